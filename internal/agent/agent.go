@@ -1,14 +1,20 @@
 package agent
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"regexp"
 	"strings"
 
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/citation"
+	"github.com/isaacphi/slop/internal/compress"
 	"github.com/isaacphi/slop/internal/config"
 	"github.com/isaacphi/slop/internal/domain"
 	"github.com/isaacphi/slop/internal/mcp"
 	"github.com/isaacphi/slop/internal/repository"
+	"github.com/isaacphi/slop/internal/trigger"
 )
 
 // "Agent" manages the interaction between the repository, llm, and function calls
@@ -19,16 +25,27 @@ type Agent struct {
 	tools      map[string]map[string]toolWithApproval // MCPServer -> Tool -> Tool Configuration
 	toolsets   map[string]config.Toolset
 	prompts    map[string]config.Prompt
+	style      config.Style
+	compressor compress.Compressor // Reused across requests so compressed history stays cached
+	oncePreset string              // Name of the preset when it was chosen for a single reply
+	rejected   map[string]string   // Reasons for rejecting pending tool calls by call ID
+	disabled   []string            // Toolsets of the preset left out because MCP runs in safe mode
 }
 
-// New creates a new Agent with the given dependencies
+// New creates a new Agent with the given dependencies. When the MCP client runs in
+// safe mode the preset's toolsets are left out, see DisabledToolsets
 func New(
 	repo repository.MessageRepository,
 	mcpClient *mcp.Client,
 	preset config.Preset,
 	toolsets map[string]config.Toolset,
 	prompts map[string]config.Prompt,
+	style config.Style,
 ) (*Agent, error) {
+	var disabled []string
+	if mcpClient.SafeMode() != "" {
+		disabled, preset.Toolsets = preset.Toolsets, nil
+	}
 	tools, err := filterAndModifyTools(mcpClient.GetTools(), preset.Toolsets, toolsets)
 
 	if err != nil {
@@ -42,41 +59,63 @@ func New(
 		tools:      tools,
 		toolsets:   toolsets,
 		prompts:    prompts,
+		style:      style,
+		disabled:   disabled,
 	}, nil
 }
 
+// DisabledToolsets returns the toolsets of the preset that were left out because the
+// MCP client runs in safe mode
+func (a *Agent) DisabledToolsets() []string {
+	return a.disabled
+}
+
 type systemMessageOpts struct {
+	threadID       uuid.UUID
 	messageContent string
+	messageRole    domain.Role
 	history        []domain.Message
 }
 
-func (a *Agent) buildSystemMessage(opts systemMessageOpts) (*domain.Message, error) {
+// buildSystemMessage assembles the system message and returns where each of its parts came from
+func (a *Agent) buildSystemMessage(ctx context.Context, opts systemMessageOpts) (*domain.Message, []string, error) {
 	var parts []string
+	var sources []string
 
 	// 1. Start with preset's system message if it exists
 	if a.preset.SystemMessage != "" {
 		parts = append(parts, a.preset.SystemMessage)
+		sources = append(sources, "preset")
 	}
 
 	// 2. Add explicitly included prompts from preset
 	for _, promptName := range a.preset.IncludePrompts {
 		if prompt, ok := a.prompts[promptName]; ok {
 			parts = append(parts, prompt.Content)
+			sources = append(sources, "prompt "+promptName)
 		} else {
-			return nil, fmt.Errorf("could not find prompt %s when building system instructions", promptName)
+			return nil, nil, fmt.Errorf("could not find prompt %s when building system instructions", promptName)
 		}
 	}
 
-	// 3. Add auto-included prompts and regex-triggered prompts
+	// 3. Add auto-included prompts and prompts triggered by a regex or condition
 	messageAndHistory := opts.messageContent
 	for _, msg := range opts.history {
 		messageAndHistory += "\n" + msg.Content
 	}
+	signals, err := a.triggerSignals(ctx, opts, messageAndHistory)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	for promptName, prompt := range a.prompts {
 		// Check auto-include
 		if prompt.IncludeInSystemMessage {
 			parts = append(parts, prompt.Content)
+			sources = append(sources, "prompt "+promptName)
+			continue
+		}
+		if prompt.SystemMessageTrigger == "" && prompt.SystemMessageCondition == "" {
 			continue
 		}
 
@@ -84,18 +123,33 @@ func (a *Agent) buildSystemMessage(opts systemMessageOpts) (*domain.Message, err
 		if prompt.SystemMessageTrigger != "" {
 			matched, err := regexp.MatchString(prompt.SystemMessageTrigger, messageAndHistory)
 			if err != nil {
-				return nil, fmt.Errorf("failed to evaluate regex trigger for prompt %s: %w", promptName, err)
+				return nil, nil, fmt.Errorf("failed to evaluate regex trigger for prompt %s: %w", promptName, err)
 			}
-			if matched {
-				parts = append(parts, prompt.Content)
+			if !matched {
+				continue
 			}
 		}
+
+		// Check the condition if one is set, both have to match when a trigger is also set
+		if prompt.SystemMessageCondition != "" {
+			condition, err := trigger.Parse(prompt.SystemMessageCondition)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to parse condition for prompt %s: %w", promptName, err)
+			}
+			if !condition.Match(signals) {
+				continue
+			}
+		}
+
+		parts = append(parts, prompt.Content)
+		sources = append(sources, "prompt "+promptName)
 	}
 
 	// 4. Add system messages from active toolsets
 	for _, toolsetName := range a.preset.Toolsets {
 		if toolset, ok := a.toolsets[toolsetName]; ok && toolset.SystemMessage != "" {
 			parts = append(parts, toolset.SystemMessage)
+			sources = append(sources, "toolset "+toolsetName)
 		}
 	}
 
@@ -103,18 +157,123 @@ func (a *Agent) buildSystemMessage(opts systemMessageOpts) (*domain.Message, err
 	for serverName := range a.tools {
 		if server, ok := a.mcpClient.Servers[serverName]; ok && server.SystemMessage != "" {
 			parts = append(parts, server.SystemMessage)
+			sources = append(sources, "server "+serverName)
+		}
+	}
+
+	// 6. Warn about tools that have been failing frequently
+	if a.preset.AnnotateFailingTools {
+		if warning := a.failingToolsMessage(ctx); warning != "" {
+			parts = append(parts, warning)
+			sources = append(sources, "failing tools")
 		}
 	}
 
+	// 7. Explain message labels when citations are enabled
+	if a.preset.Citations {
+		parts = append(parts, citation.Instruction)
+		sources = append(sources, "citations")
+	}
+
+	// 8. Keep replies in the thread's language
+	lang, err := a.replyLanguage(ctx, opts.threadID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if lang != "" {
+		parts = append(parts, languageInstruction(lang))
+		sources = append(sources, "language")
+	}
+
 	// Join all parts with double newlines
 	systemMessage := strings.Join(parts, "\n\n")
 
 	if systemMessage == "" {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	return &domain.Message{
 		Role:    domain.RoleSystem,
 		Content: systemMessage,
-	}, nil
+	}, sources, nil
+}
+
+// triggerSignals collects what prompt conditions are evaluated against. Thread tags are
+// only read when a condition uses them
+func (a *Agent) triggerSignals(ctx context.Context, opts systemMessageOpts, text string) (*trigger.Signals, error) {
+	signals := &trigger.Signals{
+		Toolsets: a.preset.Toolsets,
+		Text:     text,
+	}
+	for _, msg := range opts.history {
+		signals.Roles = append(signals.Roles, msg.Role)
+	}
+	if opts.messageRole != "" {
+		signals.Roles = append(signals.Roles, opts.messageRole)
+	}
+
+	for promptName, prompt := range a.prompts {
+		if prompt.IncludeInSystemMessage || prompt.SystemMessageCondition == "" {
+			continue
+		}
+		condition, err := trigger.Parse(prompt.SystemMessageCondition)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse condition for prompt %s: %w", promptName, err)
+		}
+		if !trigger.UsesTags(condition) {
+			continue
+		}
+		tags, err := a.repository.GetThreadTags(ctx, []uuid.UUID{opts.threadID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get thread tags: %w", err)
+		}
+		signals.Tags = tags[opts.threadID]
+		break
+	}
+	return signals, nil
+}
+
+const (
+	// Tools need this many recorded calls before they can be considered failing
+	minCallsForFailureRate = 3
+	// Tools that fail at least this often are considered failing
+	failingToolRate = 0.5
+)
+
+// failingToolsMessage lists available tools that fail frequently
+func (a *Agent) failingToolsMessage(ctx context.Context) string {
+	stats, err := a.repository.ListToolStats(ctx)
+	if err != nil {
+		slog.Warn("failed to load tool stats", "error", err)
+		return ""
+	}
+
+	var lines []string
+	for _, stat := range stats {
+		if _, ok := a.tools[stat.ServerName][stat.ToolName]; !ok {
+			continue
+		}
+		if stat.Calls() < minCallsForFailureRate || stat.FailureRate() < failingToolRate {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("- %s__%s: %d of %d calls failed, last error: %s",
+			stat.ServerName, stat.ToolName, stat.Failures, stat.Calls(), stat.LastError))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return "The following tools have been failing frequently. Prefer other tools that can do the same job when possible:\n" +
+		strings.Join(lines, "\n")
+}
+
+// Preset returns the preset the agent sends requests with
+func (a *Agent) Preset() config.Preset {
+	return a.preset
+}
+
+// ReplyOnce marks the replies of the agent as coming from a preset chosen for a single
+// turn, leaving the model a pinned thread keeps to unchanged
+func (a *Agent) ReplyOnce(presetName string) {
+	a.oncePreset = presetName
 }
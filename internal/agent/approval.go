@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/llm"
+)
+
+// sessionID identifies this slop process. Approvals remembered for the session only
+// apply to calls made by it
+var sessionID = uuid.New()
+
+// RememberApproval runs later calls of the same tools without asking for approval, in
+// the thread or for the rest of the session. pattern is a glob over the JSON encoded
+// arguments of a call, such as *"path":"/tmp/*, empty allows any arguments
+func (a *Agent) RememberApproval(ctx context.Context, threadID uuid.UUID, scope domain.ApprovalScope, calls []llm.ToolCall, pattern string) error {
+	if pattern == "" {
+		pattern = "*"
+	}
+	seen := make(map[string]bool)
+	for _, call := range calls {
+		if seen[call.Name] {
+			continue
+		}
+		seen[call.Name] = true
+
+		approval := &domain.ToolApproval{
+			Scope:           scope,
+			ThreadID:        threadID,
+			Tool:            call.Name,
+			ArgumentPattern: pattern,
+		}
+		if scope == domain.ApprovalScopeSession {
+			approval.SessionID = &sessionID
+		}
+		if err := a.repository.AddToolApproval(ctx, approval); err != nil {
+			return fmt.Errorf("failed to remember approval for %s: %w", call.Name, err)
+		}
+	}
+	return nil
+}
+
+// RejectCalls keeps calls of message from running when it is approved. Each of them
+// gets the rejection with reason as its result instead, while the other calls of the
+// message run as usual. The rejections are recorded for approvals stats
+func (a *Agent) RejectCalls(ctx context.Context, message *domain.Message, calls []llm.ToolCall, reason string) {
+	if a.rejected == nil {
+		a.rejected = make(map[string]string)
+	}
+	for _, call := range calls {
+		a.rejected[call.ID] = reason
+	}
+	a.recordRejections(ctx, message, calls, reason)
+}
+
+// RecordRejection records that all the tool calls of message were rejected with
+// reason, for approvals stats. The rejection itself is sent as a reply to message
+func (a *Agent) RecordRejection(ctx context.Context, message *domain.Message, reason string) {
+	var calls []llm.ToolCall
+	if err := json.Unmarshal([]byte(message.ToolCalls), &calls); err != nil {
+		slog.Warn("failed to parse rejected tool calls", "message", message.ID, "error", err)
+		return
+	}
+	a.recordRejections(ctx, message, calls, reason)
+}
+
+// recordRejections stores rejected calls. Like tool stats, they are best effort and
+// never fail the rejection
+func (a *Agent) recordRejections(ctx context.Context, message *domain.Message, calls []llm.ToolCall, reason string) {
+	rejections := make([]domain.ToolRejection, 0, len(calls))
+	for _, call := range calls {
+		arguments := canonicalArguments(call.Arguments)
+		hash := sha256.Sum256([]byte(arguments))
+		rejections = append(rejections, domain.ToolRejection{
+			ThreadID:      message.ThreadID,
+			MessageID:     message.ID,
+			CallID:        call.ID,
+			Tool:          call.Name,
+			Arguments:     arguments,
+			ArgumentsHash: hex.EncodeToString(hash[:]),
+			Reason:        reason,
+		})
+	}
+	if err := a.repository.AddToolRejections(ctx, rejections); err != nil {
+		slog.Warn("failed to record tool rejections", "message", message.ID, "error", err)
+	}
+}
+
+// canonicalArguments encodes arguments with their keys sorted and without spaces, so
+// the same arguments are always encoded the same way
+func canonicalArguments(arguments json.RawMessage) string {
+	var decoded any
+	if err := json.Unmarshal(arguments, &decoded); err != nil {
+		return string(arguments)
+	}
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		return string(arguments)
+	}
+	return string(encoded)
+}
+
+// rejection is the error a rejected call gets as its result
+func rejection(reason string) error {
+	if reason == "" {
+		return errors.New("tool call rejected by the user")
+	}
+	return fmt.Errorf("tool call rejected by the user: %s", reason)
+}
+
+// rememberedApproval reports whether an approval given earlier covers the call
+func rememberedApproval(approvals []domain.ToolApproval, call llm.ToolCall) bool {
+	var compact bytes.Buffer
+	arguments := string(call.Arguments)
+	if json.Compact(&compact, call.Arguments) == nil {
+		arguments = compact.String()
+	}
+	for _, approval := range approvals {
+		if approval.Tool == call.Name && matchArguments(approval.ArgumentPattern, arguments) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchArguments matches encoded arguments against a glob where * matches any text,
+// including slashes, and ? matches a single character
+func matchArguments(pattern string, arguments string) bool {
+	if pattern == "*" {
+		return true
+	}
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	matched, err := regexp.MatchString(expr.String(), arguments)
+	return err == nil && matched
+}
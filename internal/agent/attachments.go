@@ -0,0 +1,179 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"path"
+
+	"github.com/isaacphi/slop/internal/artifact"
+	"github.com/isaacphi/slop/internal/domain"
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// ToolAttachment is binary content returned by a tool, such as an image. It is
+// stored as an artifact instead of being sent to the model as text
+type ToolAttachment struct {
+	Name     string
+	MimeType string
+	Data     []byte
+}
+
+// blobResource is the shape of an embedded resource holding binary data
+type blobResource struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType"`
+	Blob     string `json:"blob"`
+}
+
+// formatToolResponse returns the text result of a tool call and any binary content
+// it returned. Binary content is replaced by a placeholder in the text
+func formatToolResponse(toolName string, resp *mcp_golang.ToolResponse) (string, []ToolAttachment, error) {
+	var attachments []ToolAttachment
+	if resp != nil {
+		formatted := *resp
+		formatted.Content = make([]*mcp_golang.Content, len(resp.Content))
+		for i, content := range resp.Content {
+			formatted.Content[i] = content
+
+			attachment, ok, err := binaryContent(toolName, len(attachments)+1, content)
+			if err != nil {
+				return "", nil, err
+			}
+			if !ok {
+				continue
+			}
+			attachments = append(attachments, attachment)
+			formatted.Content[i] = &mcp_golang.Content{
+				Type: mcp_golang.ContentTypeText,
+				TextContent: &mcp_golang.TextContent{
+					Text: fmt.Sprintf("[attachment %s, %s]", attachment.Name, attachment.MimeType),
+				},
+			}
+		}
+		resp = &formatted
+	}
+
+	resultBytes, err := json.Marshal(resp)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to format result: %w", err)
+	}
+	return string(resultBytes), attachments, nil
+}
+
+// binaryContent extracts images and binary embedded resources from tool output
+func binaryContent(toolName string, index int, content *mcp_golang.Content) (ToolAttachment, bool, error) {
+	if content == nil {
+		return ToolAttachment{}, false, nil
+	}
+
+	switch content.Type {
+	case mcp_golang.ContentTypeImage:
+		if content.ImageContent == nil {
+			return ToolAttachment{}, false, nil
+		}
+		data, err := base64.StdEncoding.DecodeString(content.ImageContent.Data)
+		if err != nil {
+			return ToolAttachment{}, false, fmt.Errorf("invalid image data from %s: %w", toolName, err)
+		}
+		return ToolAttachment{
+			Name:     attachmentName(toolName, index, "", content.ImageContent.MimeType),
+			MimeType: content.ImageContent.MimeType,
+			Data:     data,
+		}, true, nil
+
+	case mcp_golang.ContentTypeEmbeddedResource:
+		if content.EmbeddedResource == nil {
+			return ToolAttachment{}, false, nil
+		}
+		// Text resources are left in the result, only blobs are attachments
+		encoded, err := json.Marshal(content.EmbeddedResource)
+		if err != nil {
+			return ToolAttachment{}, false, nil
+		}
+		var resource blobResource
+		if err := json.Unmarshal(encoded, &resource); err != nil || resource.Blob == "" {
+			return ToolAttachment{}, false, nil
+		}
+		data, err := base64.StdEncoding.DecodeString(resource.Blob)
+		if err != nil {
+			return ToolAttachment{}, false, fmt.Errorf("invalid resource data from %s: %w", toolName, err)
+		}
+		mimeType := resource.MimeType
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		return ToolAttachment{
+			Name:     attachmentName(toolName, index, resource.URI, mimeType),
+			MimeType: mimeType,
+			Data:     data,
+		}, true, nil
+	}
+
+	return ToolAttachment{}, false, nil
+}
+
+// attachmentName uses the file name from the resource URI when there is one
+func attachmentName(toolName string, index int, uri string, mimeType string) string {
+	if name := path.Base(uri); uri != "" && name != "/" && name != "." {
+		return name
+	}
+	ext := ".bin"
+	if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+		ext = exts[0]
+	}
+	return fmt.Sprintf("%s-%d%s", toolName, index, ext)
+}
+
+// attachmentParts describes attachments as message parts that refer to their artifacts
+func attachmentParts(attachments []ToolAttachment) []domain.MessagePart {
+	parts := make([]domain.MessagePart, len(attachments))
+	for i, attachment := range attachments {
+		hash := artifact.Hash(string(attachment.Data))
+		parts[i] = domain.MessagePart{
+			Content: fmt.Sprintf("Attachment %s from a tool result, %s",
+				artifact.Reference(hash, attachment.Name, len(attachment.Data)), attachment.MimeType),
+			Artifact: hash,
+			MimeType: attachment.MimeType,
+		}
+	}
+	return parts
+}
+
+// loadAttachments reads the images in messages that can be shown to the model.
+// Nothing is loaded for presets without vision
+func (a *Agent) loadAttachments(ctx context.Context, messages ...domain.Message) map[string][]byte {
+	if !a.preset.Vision {
+		return nil
+	}
+
+	attachments := make(map[string][]byte)
+	for _, msg := range messages {
+		if msg.Parts == "" {
+			continue
+		}
+		parts, err := msg.GetParts()
+		if err != nil {
+			continue
+		}
+		for _, part := range parts {
+			// Images linked by URL are fetched by the provider
+			if !part.IsImage() || part.Artifact == "" {
+				continue
+			}
+			if _, ok := attachments[part.Artifact]; ok {
+				continue
+			}
+			_, data, err := a.repository.GetArtifact(ctx, part.Artifact)
+			if err != nil {
+				slog.Warn("failed to load image attachment", "artifact", artifact.ShortID(part.Artifact), "error", err)
+				continue
+			}
+			attachments[part.Artifact] = data
+		}
+	}
+	return attachments
+}
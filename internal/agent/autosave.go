@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// autosaveInterval is how often the text of a streaming response is saved, a crash
+// loses at most this much of it
+const autosaveInterval = 2 * time.Second
+
+// autosave keeps the text of a response saved while it streams. The text is saved to
+// an assistant message marked as incomplete, which becomes the response once it
+// finishes
+type autosave struct {
+	agent   *Agent
+	parent  *domain.Message
+	msg     *domain.Message // Message holding the text saved so far, nil until the first save
+	savedAt time.Time
+}
+
+func (a *Agent) newAutosave(parent *domain.Message) *autosave {
+	return &autosave{agent: a, parent: parent}
+}
+
+// save stores the text received so far unless it was saved less than
+// autosaveInterval ago
+func (s *autosave) save(ctx context.Context, content string) error {
+	if content == "" || time.Since(s.savedAt) < autosaveInterval {
+		return nil
+	}
+	s.savedAt = time.Now()
+
+	if s.msg != nil {
+		s.msg.Content = content
+		if err := s.agent.repository.UpdateMessageContent(ctx, s.msg); err != nil {
+			return fmt.Errorf("failed to save streamed response: %w", err)
+		}
+		return nil
+	}
+
+	msg := &domain.Message{
+		ThreadID:  s.parent.ThreadID,
+		ParentID:  &s.parent.ID,
+		Role:      domain.RoleAssistant,
+		Content:   content,
+		ModelName: s.agent.preset.Name,
+		Provider:  s.agent.preset.Provider,
+	}
+	if err := msg.SetMetadata(domain.MessageMetadata{Incomplete: true, OncePreset: s.agent.oncePreset}); err != nil {
+		return err
+	}
+	if err := s.agent.repository.AddMessageToThread(ctx, s.parent.ThreadID, msg); err != nil {
+		return fmt.Errorf("failed to save streamed response: %w", err)
+	}
+	s.msg = msg
+	return nil
+}
+
+// finish stores the finished response, replacing the text saved while it streamed
+func (s *autosave) finish(ctx context.Context, msg *domain.Message) error {
+	if s.msg == nil {
+		return s.agent.repository.AddMessageToThread(ctx, s.parent.ThreadID, msg)
+	}
+	msg.ID = s.msg.ID
+	msg.Model = s.msg.Model
+	return s.agent.repository.SaveMessage(ctx, msg)
+}
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/llm"
+	"github.com/isaacphi/slop/internal/privacy"
+)
+
+// BatchRequest prepares a saved human message to be answered through the provider's
+// batch API. The request has the same system message and history as a live request,
+// but no tools since their calls could not be run before the reply is final. It fails
+// when the thread's privacy level doesn't allow the preset
+func (a *Agent) BatchRequest(ctx context.Context, msg *domain.Message) (llm.BatchRequest, error) {
+	thread, err := a.repository.GetThread(ctx, msg.ThreadID)
+	if err != nil {
+		return llm.BatchRequest{}, fmt.Errorf("failed to get thread: %w", err)
+	}
+	if err := privacy.Check(thread, "preset", a.preset); err != nil {
+		return llm.BatchRequest{}, err
+	}
+
+	history, err := a.repository.GetMessages(ctx, msg.ThreadID, msg.ParentID, false)
+	if err != nil {
+		return llm.BatchRequest{}, fmt.Errorf("failed to get conversation history: %w", err)
+	}
+
+	systemMessage, _, err := a.buildSystemMessage(ctx, systemMessageOpts{
+		threadID:       msg.ThreadID,
+		messageContent: msg.Content,
+		messageRole:    msg.Role,
+		history:        history,
+	})
+	if err != nil {
+		return llm.BatchRequest{}, fmt.Errorf("failed to build system message: %w", err)
+	}
+
+	compacted := compactToolResults(summarizeToolCalls(history), a.preset.CompactToolResultsAfter)
+	compacted, _, err = a.compressHistory(ctx, thread, compacted)
+	if err != nil {
+		return llm.BatchRequest{}, err
+	}
+
+	request := llm.BatchRequest{
+		CustomID:      msg.ID.String(),
+		SystemMessage: systemMessage,
+		History:       compacted,
+		Content:       msg.Content,
+	}
+	if privacy.Level(thread) == domain.PrivacyRedacted {
+		request = request.Redacted()
+	}
+	return request, nil
+}
@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/events"
+	"github.com/isaacphi/slop/internal/llm"
+)
+
+// cacheTTL parses the preset's cache TTL, 0 means responses are not cached
+func (a *Agent) cacheTTL() (time.Duration, error) {
+	if a.preset.CacheTTL == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(a.preset.CacheTTL)
+	if err != nil || ttl < 0 {
+		return 0, fmt.Errorf("invalid cache TTL %q", a.preset.CacheTTL)
+	}
+	return ttl, nil
+}
+
+// cacheKey hashes everything that decides the response to a request, so only an
+// identical request gets the same key
+func cacheKey(opts llm.GenerateContentOptions) (string, error) {
+	type cachedMessage struct {
+		Role      domain.Role
+		Content   string
+		ToolCalls string
+		Parts     string
+	}
+	request := struct {
+		Provider    string
+		Model       string
+		MaxTokens   int
+		Temperature float64
+		ToolChoice  string
+		System      string
+		History     []cachedMessage
+		Content     string
+		Parts       []domain.MessagePart
+		Tools       map[string]domain.Tool
+		Attachments []string
+	}{
+		Provider:    opts.Preset.Provider,
+		Model:       opts.Preset.Name,
+		MaxTokens:   opts.Preset.MaxTokens,
+		Temperature: opts.Preset.Temperature,
+		ToolChoice:  opts.Preset.ToolChoice,
+		Content:     opts.Content,
+		Parts:       opts.ContentParts,
+		Tools:       opts.Tools,
+		Attachments: slices.Sorted(maps.Keys(opts.Attachments)),
+	}
+	if opts.SystemMessage != nil {
+		request.System = opts.SystemMessage.Content
+	}
+	for _, msg := range opts.History {
+		request.History = append(request.History, cachedMessage{
+			Role:      msg.Role,
+			Content:   msg.Content,
+			ToolCalls: msg.ToolCalls,
+			Parts:     msg.Parts,
+		})
+	}
+
+	encoded, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request for the response cache: %w", err)
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// cachedStream replays a cached response as if the provider had just sent it
+func cachedStream(response domain.CachedResponse) (llm.LLMStream, error) {
+	var toolCalls []llm.ToolCall
+	if response.ToolCalls != "" {
+		if err := json.Unmarshal([]byte(response.ToolCalls), &toolCalls); err != nil {
+			return llm.LLMStream{}, fmt.Errorf("failed to decode cached tool calls: %w", err)
+		}
+	}
+
+	eventsChan := make(chan events.Event)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(eventsChan)
+		if response.Content != "" {
+			eventsChan <- &llm.TextEvent{Content: response.Content}
+		}
+		eventsChan <- &llm.MessageCompleteEvent{
+			Content:   response.Content,
+			ToolCalls: toolCalls,
+			Model:     response.ModelVersion,
+		}
+	}()
+	return llm.LLMStream{Events: eventsChan, Done: done}, nil
+}
+
+// cacheResponse stores a response the provider sent under the key of its request
+func (a *Agent) cacheResponse(ctx context.Context, key string, ttl time.Duration, e *llm.MessageCompleteEvent) error {
+	response := &domain.CachedResponse{
+		Key:          key,
+		Provider:     a.preset.Provider,
+		ModelName:    a.preset.Name,
+		Content:      e.Content,
+		ModelVersion: e.Model,
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+	if len(e.ToolCalls) > 0 {
+		encoded, err := json.Marshal(e.ToolCalls)
+		if err != nil {
+			return fmt.Errorf("failed to encode tool calls: %w", err)
+		}
+		response.ToolCalls = string(encoded)
+	}
+	return a.repository.SaveCachedResponse(ctx, response)
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/compress"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/privacy"
+	"github.com/isaacphi/slop/internal/tokens"
+)
+
+// getCompressor returns the compressor chosen by the preset, or nil when
+// compression is off. The model compressor is kept so its cache lasts the session.
+// It fails when the privacy level of thread doesn't allow the compression model
+func (a *Agent) getCompressor(ctx context.Context, thread *domain.Thread) (compress.Compressor, error) {
+	settings := a.preset.Compression
+	switch settings.Method {
+	case "", compress.MethodNone:
+		return nil, nil
+	case compress.MethodHeuristic:
+		return compress.Heuristic{}, nil
+	case compress.MethodModel:
+		cfg := appState.FromContext(ctx).Config
+		name := settings.Model
+		if name == "" {
+			name = cfg.Internal.Model
+		}
+		preset, ok := cfg.Presets[name]
+		if !ok {
+			return nil, fmt.Errorf("compression model %q is not a configured preset", name)
+		}
+		if err := privacy.Check(thread, "compression model", preset); err != nil {
+			return nil, err
+		}
+		if privacy.Level(thread) == domain.PrivacyRedacted && !preset.Redact {
+			// Not kept, the compressor other threads share doesn't mask secrets
+			return compress.NewModel(privacy.Apply(thread, preset)), nil
+		}
+		if a.compressor == nil {
+			a.compressor = compress.NewModel(preset)
+		}
+		return a.compressor, nil
+	default:
+		return nil, fmt.Errorf("invalid compression method %q", settings.Method)
+	}
+}
+
+// compressHistory compresses the content of messages older than the preset's
+// compression keepTurns. Messages are only replaced when compression makes them
+// shorter, and a message that fails to compress is sent as it is
+func (a *Agent) compressHistory(ctx context.Context, thread *domain.Thread, history []domain.Message) ([]domain.Message, *CompressionEvent, error) {
+	compressor, err := a.getCompressor(ctx, thread)
+	if err != nil || compressor == nil {
+		return history, nil, err
+	}
+
+	settings := a.preset.Compression
+	cutoff := turnCutoff(history, settings.KeepTurns)
+	if cutoff <= 0 {
+		return history, nil, nil
+	}
+
+	tokenizer := tokens.For(a.preset.Tokenizer, a.preset.Provider, a.preset.Name)
+	stats := &CompressionEvent{Method: settings.Method}
+	shaped := make([]domain.Message, len(history))
+	copy(shaped, history)
+	for i := 0; i < cutoff; i++ {
+		msg := &shaped[i]
+		// Messages with parts are sent from their parts, not their content
+		if msg.Role == domain.RoleSystem || msg.Parts != "" || len(msg.Content) < settings.MinLength {
+			continue
+		}
+
+		compressed, err := compressor.Compress(ctx, msg.Content)
+		if err != nil {
+			slog.Warn("failed to compress message", "message", msg.ID, "error", err)
+			continue
+		}
+		if len(compressed) >= len(msg.Content) {
+			continue
+		}
+
+		stats.Messages++
+		stats.Before += tokenizer.Count(msg.Content)
+		stats.After += tokenizer.Count(compressed)
+		msg.Content = compressed
+	}
+
+	if stats.Messages == 0 {
+		return history, nil, nil
+	}
+	return shaped, stats, nil
+}
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/llm"
+	"github.com/isaacphi/slop/internal/privacy"
+)
+
+// confidencePrompt asks the model to rate its response
+const confidencePrompt = `Rate how confident you are that your previous response is correct and complete. ` +
+	`List any assumptions it relies on that the user did not state. ` +
+	`Reply with only a JSON object like {"confidence": "high", "assumptions": ["..."]} ` +
+	`where confidence is high, medium or low and assumptions may be empty.`
+
+// rateConfidence asks the model how confident it is in its response to msg and what
+// it assumed. Tools are not offered so the answer is always plain text
+func (a *Agent) rateConfidence(
+	ctx context.Context,
+	thread *domain.Thread,
+	systemMessage *domain.Message,
+	history []domain.Message,
+	msg *domain.Message,
+	response string,
+) (*domain.Confidence, error) {
+	preset := privacy.Apply(thread, a.preset)
+	preset.ToolChoice = ""
+
+	ratingHistory := slices.Concat(
+		compactToolResults(summarizeToolCalls(history), a.preset.CompactToolResultsAfter),
+		[]domain.Message{*msg, {Role: domain.RoleAssistant, Content: response}},
+	)
+
+	resp, err := llm.GenerateContent(ctx, llm.GenerateContentOptions{
+		Preset:        preset,
+		Content:       confidencePrompt,
+		SystemMessage: systemMessage,
+		History:       ratingHistory,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rate confidence: %w", err)
+	}
+
+	// Models sometimes wrap the object in a code fence or add a sentence around it
+	text := resp.TextResponse
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("confidence rating is not a JSON object: %q", text)
+	}
+	var rating struct {
+		Confidence  string   `json:"confidence"`
+		Assumptions []string `json:"assumptions"`
+	}
+	if err := json.Unmarshal([]byte(text[start:end+1]), &rating); err != nil {
+		return nil, fmt.Errorf("failed to decode confidence rating: %w", err)
+	}
+
+	level := strings.ToLower(strings.TrimSpace(rating.Confidence))
+	if level != "high" && level != "medium" && level != "low" {
+		return nil, fmt.Errorf("unknown confidence level %q", rating.Confidence)
+	}
+	return &domain.Confidence{Level: level, Assumptions: rating.Assumptions}, nil
+}
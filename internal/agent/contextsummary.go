@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/internalService"
+	"github.com/isaacphi/slop/internal/tokens"
+)
+
+// contextSummaryHeading introduces the summary in the system message
+const contextSummaryHeading = "Summary of the earlier conversation, whose messages are not repeated below:"
+
+// summarizeContext returns the summary of the older part of history and the messages
+// to send after it. The latest summary stored on a message of history stands in for
+// that message and everything before it. Once more than the preset's summarize.after
+// messages follow it, all but the last keepTurns turns are summarized along with it
+// by the internal model, and the new summary is stored on the last message it covers.
+// A summary that fails, or that the thread's privacy level doesn't allow, is only
+// logged and the messages are sent as they are
+func (a *Agent) summarizeContext(ctx context.Context, thread *domain.Thread, history []domain.Message) (string, []domain.Message, *ContextSummaryEvent) {
+	settings := a.preset.Summarize
+	if settings.After <= 0 {
+		return "", history, nil
+	}
+
+	summary, rest := "", history
+	for i := len(history) - 1; i >= 0; i-- {
+		if metadata, err := history[i].GetMetadata(); err == nil && metadata.ContextSummary != "" {
+			summary, rest = metadata.ContextSummary, history[i+1:]
+			break
+		}
+	}
+	if len(rest) <= settings.After {
+		return summary, rest, nil
+	}
+	cutoff := turnCutoff(rest, settings.KeepTurns)
+	if cutoff <= 0 {
+		return summary, rest, nil
+	}
+	covered := rest[:cutoff]
+
+	service, err := internal.NewForThread(appState.FromContext(ctx).Config, thread)
+	if err != nil {
+		slog.Warn("failed to summarize older messages", "error", err)
+		return summary, rest, nil
+	}
+	updated, err := service.SummarizeContext(ctx, summary, covered)
+	if err != nil {
+		slog.Warn("failed to summarize older messages", "error", err)
+		return summary, rest, nil
+	}
+
+	last := covered[len(covered)-1]
+	if err := a.storeContextSummary(ctx, &last, updated); err != nil {
+		slog.Warn("failed to save the summary of older messages", "message", last.ID, "error", err)
+	}
+
+	tokenizer := tokens.For(a.preset.Tokenizer, a.preset.Provider, a.preset.Name)
+	event := &ContextSummaryEvent{Messages: len(covered), Before: tokenizer.Count(summary), After: tokenizer.Count(updated)}
+	for _, msg := range covered {
+		event.Before += tokenizer.Count(msg.Content)
+	}
+	return updated, rest[cutoff:], event
+}
+
+// storeContextSummary keeps the summary on the last message it covers, so later
+// requests and other branches continuing from that message reuse it
+func (a *Agent) storeContextSummary(ctx context.Context, msg *domain.Message, summary string) error {
+	metadata, err := msg.GetMetadata()
+	if err != nil {
+		return err
+	}
+	metadata.ContextSummary = summary
+	if err := msg.SetMetadata(metadata); err != nil {
+		return err
+	}
+	return a.repository.UpdateMessageMetadata(ctx, msg)
+}
+
+// withContextSummary adds the summary of the older messages to the system message
+func withContextSummary(systemMessage *domain.Message, threadID uuid.UUID, summary string) *domain.Message {
+	block := fmt.Sprintf("%s\n\n%s", contextSummaryHeading, summary)
+	if systemMessage == nil {
+		return &domain.Message{ThreadID: threadID, Role: domain.RoleSystem, Content: block}
+	}
+	withSummary := *systemMessage
+	withSummary.Content += "\n\n" + block
+	return &withSummary
+}
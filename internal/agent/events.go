@@ -1,6 +1,10 @@
 package agent
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/isaacphi/slop/internal/domain"
 	"github.com/isaacphi/slop/internal/events"
 	"github.com/isaacphi/slop/internal/llm"
@@ -37,6 +41,123 @@ func (e NewMessageEvent) Type() events.EventType {
 	return events.EventTypeNewMessage
 }
 
+// SystemMessageEvent describes the system message sent with the next request
+type SystemMessageEvent struct {
+	Content string
+	Sources []string // Where each part of the system message came from, such as "prompt sql"
+}
+
+func (e SystemMessageEvent) Type() events.EventType {
+	return events.EventTypeSystemMessage
+}
+
+// RunStartedEvent carries the ID of the run that records the progress of a turn
+type RunStartedEvent struct {
+	RunID uuid.UUID
+}
+
+func (e RunStartedEvent) Type() events.EventType {
+	return events.EventTypeRunStarted
+}
+
+// CompressionEvent reports how much older history was compressed before a request
+type CompressionEvent struct {
+	Method   string
+	Messages int // Number of messages that were compressed
+	Before   int // Estimated tokens in those messages before compression
+	After    int // Estimated tokens in those messages after compression
+}
+
+func (e CompressionEvent) Type() events.EventType {
+	return events.EventTypeCompression
+}
+
+// Ratio returns the compressed size as a fraction of the original size
+func (e CompressionEvent) Ratio() float64 {
+	if e.Before == 0 {
+		return 1
+	}
+	return float64(e.After) / float64(e.Before)
+}
+
+// ContextSummaryEvent reports that older messages of the thread were summarized and
+// the summary is sent in their place from now on
+type ContextSummaryEvent struct {
+	Messages int // Number of messages the new summary covers besides the earlier summary
+	Before   int // Estimated tokens in those messages and the earlier summary
+	After    int // Estimated tokens in the new summary
+}
+
+func (e ContextSummaryEvent) Type() events.EventType {
+	return events.EventTypeContextSummary
+}
+
+// CacheHitEvent reports that the response was taken from the response cache instead
+// of the provider
+type CacheHitEvent struct {
+	StoredAt time.Time
+}
+
+func (e CacheHitEvent) Type() events.EventType {
+	return events.EventTypeCacheHit
+}
+
+// ReconnectEvent reports that the connection to the provider dropped mid response and
+// the request is being sent again
+type ReconnectEvent struct {
+	Attempt  int
+	Received int // Characters of the response received before the connection dropped
+	Error    error
+}
+
+func (e ReconnectEvent) Type() events.EventType {
+	return events.EventTypeReconnect
+}
+
+// FallbackEvent reports that the provider rate limited or was overloaded and the
+// request is being sent to a fallback preset instead
+type FallbackEvent struct {
+	Preset string // Name of the fallback preset
+	Model  string // Model of the fallback preset
+	Failed string // Model that could not answer
+	Error  error
+}
+
+func (e FallbackEvent) Type() events.EventType {
+	return events.EventTypeFallback
+}
+
+// ServerExitEvent reports that an MCP server exited on its own. Its tools are not
+// offered to the model until it is running again
+type ServerExitEvent struct {
+	Server     string
+	Error      error
+	Restarting bool // False once restarting the server was given up on
+}
+
+func (e ServerExitEvent) Type() events.EventType {
+	return events.EventTypeServerExit
+}
+
+func (e ServerExitEvent) String() string {
+	if e.Restarting {
+		return fmt.Sprintf("MCP server %s exited, restarting it: %v", e.Server, e.Error)
+	}
+	return fmt.Sprintf("MCP server %s could not be restarted, its tools are unavailable: %v", e.Server, e.Error)
+}
+
+// ThreadTitledEvent reports the title and summary the internal model gave a thread
+// after its first exchange
+type ThreadTitledEvent struct {
+	ThreadID uuid.UUID
+	Title    string
+	Summary  string
+}
+
+func (e ThreadTitledEvent) Type() events.EventType {
+	return events.EventTypeThreadTitled
+}
+
 // AgentStream represents an ongoing conversation stream
 type AgentStream struct {
 	Events <-chan events.Event
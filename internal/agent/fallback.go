@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/errkind"
+	"github.com/isaacphi/slop/internal/events"
+	"github.com/isaacphi/slop/internal/privacy"
+)
+
+// shouldFallBack reports whether a failed request can be sent to a fallback preset:
+// the provider was rate limiting or overloaded, and none of the response has arrived
+// yet so the user doesn't see two answers run together
+func shouldFallBack(e *events.ErrorEvent, received string) bool {
+	kind := e.Kind
+	if kind == errkind.Unknown {
+		kind = errkind.Of(e.Error)
+	}
+	return kind == errkind.RateLimit && received == ""
+}
+
+// nextFallback returns the first fallback of the preset from position from on that
+// can be used, and the position to continue from after it. Fallbacks missing from the
+// configuration or not allowed by the thread's privacy level are skipped. Threads
+// that pin their model never fall back, since the fallback replies with a different
+// model. It returns false when no fallback is left
+func (a *Agent) nextFallback(ctx context.Context, thread *domain.Thread, from int) (string, config.Preset, int, bool) {
+	if thread.PinModel {
+		return "", config.Preset{}, from, false
+	}
+	presets := appState.FromContext(ctx).Config.Presets
+	for i := from; i < len(a.preset.Fallbacks); i++ {
+		name := a.preset.Fallbacks[i]
+		preset, ok := presets[name]
+		if !ok {
+			slog.Warn("skipping fallback preset missing from the configuration", "preset", name)
+			continue
+		}
+		if err := privacy.Check(thread, "fallback preset", preset); err != nil {
+			slog.Warn("skipping fallback preset", "preset", name, "error", err)
+			continue
+		}
+		return name, privacy.Apply(thread, preset), i + 1, true
+	}
+	return "", config.Preset{}, len(a.preset.Fallbacks), false
+}
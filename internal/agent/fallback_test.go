@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+func TestNextFallback(t *testing.T) {
+	presets := map[string]config.Preset{
+		"remote": {Provider: "anthropic", Name: "claude"},
+		"local":  {Provider: "openai", Name: "llama", BaseURL: "http://localhost:8080/v1"},
+		"other":  {Provider: "openai", Name: "gpt"},
+	}
+	ctx := appState.NewContext(context.Background(), &appState.App{Config: &config.ConfigSchema{Presets: presets}})
+
+	tests := []struct {
+		name      string
+		fallbacks []string
+		thread    domain.Thread
+		from      int
+		want      string // Empty when no fallback is left
+		wantNext  int
+	}{
+		{name: "first fallback", fallbacks: []string{"remote", "other"}, want: "remote", wantNext: 1},
+		{name: "continues after tried fallbacks", fallbacks: []string{"remote", "other"}, from: 1, want: "other", wantNext: 2},
+		{name: "none left", fallbacks: []string{"remote"}, from: 1},
+		{name: "skips a missing fallback", fallbacks: []string{"gone", "remote"}, want: "remote", wantNext: 2},
+		{name: "skips a fallback the privacy level doesn't allow", fallbacks: []string{"remote", "local"}, thread: domain.Thread{Privacy: domain.PrivacyLocalOnly}, want: "local", wantNext: 2},
+		{name: "all skipped", fallbacks: []string{"gone", "remote"}, thread: domain.Thread{Privacy: domain.PrivacyLocalOnly}},
+		{name: "pinned model never falls back", fallbacks: []string{"remote"}, thread: domain.Thread{PinModel: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Agent{preset: config.Preset{Fallbacks: tt.fallbacks}}
+			name, preset, next, ok := a.nextFallback(ctx, &tt.thread, tt.from)
+			if ok != (tt.want != "") || name != tt.want {
+				t.Fatalf("nextFallback() = %q, %v, want %q", name, ok, tt.want)
+			}
+			if !ok {
+				return
+			}
+			if next != tt.wantNext {
+				t.Errorf("next = %d, want %d", next, tt.wantNext)
+			}
+			if preset.Name != presets[tt.want].Name {
+				t.Errorf("preset = %s, want %s", preset.Name, presets[tt.want].Name)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/isaacphi/slop/internal/artifact"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// compactToolResults replaces the content of tool result messages that are older
+// than keepTurns human turns with a short placeholder. Assistant messages are left
+// untouched so the model still sees what it concluded from those results.
+// keepTurns <= 0 disables compaction.
+func compactToolResults(history []domain.Message, keepTurns int) []domain.Message {
+	if keepTurns <= 0 {
+		return history
+	}
+
+	cutoff := turnCutoff(history, keepTurns)
+	if cutoff <= 0 {
+		return history
+	}
+
+	shaped := make([]domain.Message, len(history))
+	copy(shaped, history)
+	for i := 0; i < cutoff; i++ {
+		if shaped[i].Role != domain.RoleTool {
+			continue
+		}
+		placeholder := fmt.Sprintf("[tool result omitted, %s]", artifact.FormatSize(len(shaped[i].Content)))
+		if len(placeholder) < len(shaped[i].Content) {
+			shaped[i].Content = placeholder
+			// Attachments are dropped along with the rest of the result
+			shaped[i].Parts = ""
+		}
+	}
+	return shaped
+}
+
+// summarizeToolCalls leaves out the tool calls and results of turns that were
+// summarized and puts each summary before the response it belongs to
+func summarizeToolCalls(history []domain.Message) []domain.Message {
+	shaped := make([]domain.Message, 0, len(history))
+	for _, msg := range history {
+		metadata, err := msg.GetMetadata()
+		if err != nil {
+			shaped = append(shaped, msg)
+			continue
+		}
+		if metadata.Summarized {
+			continue
+		}
+		if metadata.ToolSummary != "" {
+			msg.Content = metadata.ToolSummary + "\n\n" + msg.Content
+		}
+		shaped = append(shaped, msg)
+	}
+	return shaped
+}
+
+// turnCutoff returns the index of the oldest human message that is still within
+// keepTurns turns of the end of history, or -1 if history has fewer turns
+func turnCutoff(history []domain.Message, keepTurns int) int {
+	turns := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == domain.RoleHuman {
+			turns++
+			if turns == keepTurns {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// LanguageName spells out a language code such as fr as French. Anything that
+// isn't a known code, such as a name already, is returned as it is
+func LanguageName(lang string) string {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return lang
+	}
+	if name := display.English.Tags().Name(tag); name != "" {
+		return name
+	}
+	return lang
+}
+
+// replyLanguage is the language the thread's replies are written in, its own or the
+// configured default. Empty when neither is set
+func (a *Agent) replyLanguage(ctx context.Context, threadID uuid.UUID) (string, error) {
+	if threadID != uuid.Nil {
+		thread, err := a.repository.GetThread(ctx, threadID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get thread: %w", err)
+		}
+		if thread.Language != "" {
+			return thread.Language, nil
+		}
+	}
+	return a.style.Language, nil
+}
+
+// languageInstruction tells the model to keep to lang whatever the messages are written in
+func languageInstruction(lang string) string {
+	return fmt.Sprintf("Always reply in %s, even when messages are written in another language.", LanguageName(lang))
+}
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/errkind"
+)
+
+// OverrideTools changes which tools the agent offers without changing the preset.
+// Patterns name tools as server.tool and may use wildcards, such as filesystem.* or *.
+// Disabled tools are removed first, then enabled tools are added. Tools that are not
+// in the preset's toolsets require approval
+func (a *Agent) OverrideTools(enable []string, disable []string) error {
+	for _, pattern := range slices.Concat(enable, disable) {
+		if _, err := path.Match(normalizeToolPattern(pattern), ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+		}
+	}
+
+	for server, serverTools := range a.tools {
+		for name := range serverTools {
+			if matchesTool(disable, server, name) {
+				delete(serverTools, name)
+			}
+		}
+		if len(serverTools) == 0 {
+			delete(a.tools, server)
+		}
+	}
+
+	if len(enable) == 0 {
+		return nil
+	}
+
+	// Enabled tools are resolved like a toolset that allows exactly those tools
+	adHoc := config.Toolset{Servers: make(map[string]config.MCPServerToolConfig)}
+	for server, serverTools := range a.mcpClient.GetTools() {
+		for name := range serverTools {
+			if !matchesTool(enable, server, name) {
+				continue
+			}
+			if _, ok := adHoc.Servers[server]; !ok {
+				adHoc.Servers[server] = config.MCPServerToolConfig{AllowedTools: make(map[string]config.ToolConfig)}
+			}
+			adHoc.Servers[server].AllowedTools[name] = config.ToolConfig{RequireApproval: true}
+		}
+	}
+	for _, pattern := range enable {
+		found := false
+		for server, serverConfig := range adHoc.Servers {
+			for name := range serverConfig.AllowedTools {
+				found = found || matchesTool([]string{pattern}, server, name)
+			}
+		}
+		if !found {
+			return errkind.New(errkind.ToolNotFound, fmt.Errorf("no tool matches %q", pattern))
+		}
+	}
+
+	const name = "--enable-tools"
+	added, err := filterAndModifyTools(a.mcpClient.GetTools(), []string{name}, map[string]config.Toolset{name: adHoc})
+	if err != nil {
+		return err
+	}
+	for server, serverTools := range added {
+		if _, ok := a.tools[server]; !ok {
+			a.tools[server] = make(map[string]toolWithApproval)
+		}
+		for name, tool := range serverTools {
+			// Tools the preset already offers keep their configuration
+			if _, ok := a.tools[server][name]; !ok {
+				a.tools[server][name] = tool
+			}
+		}
+	}
+	return nil
+}
+
+// matchesTool reports whether any of the patterns names the tool
+func matchesTool(patterns []string, server string, tool string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(normalizeToolPattern(pattern), server+"."+tool); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeToolPattern accepts server__tool as well as server.tool, and a bare server
+// name for all of its tools
+func normalizeToolPattern(pattern string) string {
+	pattern = strings.Replace(strings.TrimSpace(pattern), "__", ".", 1)
+	if pattern != "*" && !strings.Contains(pattern, ".") {
+		pattern += ".*"
+	}
+	return pattern
+}
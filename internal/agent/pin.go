@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// PinnedModel returns the model version of the first reply in messages that
+// recorded one. Threads that pin their model must keep getting replies from it.
+// Replies from a preset chosen for a single turn or from a fallback preset are skipped
+func PinnedModel(messages []domain.Message) string {
+	for _, msg := range messages {
+		if msg.Role != domain.RoleAssistant || msg.ModelVersion == "" {
+			continue
+		}
+		if metadata, err := msg.GetMetadata(); err == nil && (metadata.OncePreset != "" || metadata.Fallback != "") {
+			continue
+		}
+		return msg.ModelVersion
+	}
+	return ""
+}
+
+// checkPinnedModel fails when a thread that pins its model gets a reply from a
+// different model version than earlier replies in history
+func checkPinnedModel(thread *domain.Thread, history []domain.Message, model string) error {
+	if !thread.PinModel {
+		return nil
+	}
+	pinned := PinnedModel(history)
+	if pinned == "" || pinned == model {
+		return nil
+	}
+	return fmt.Errorf("thread %s is pinned to model %s but the provider replied with %s. "+
+		"Use a preset whose name is the exact version %s, or run `slop thread pin %s --off` to accept the new model",
+		thread.ID.String()[:8], pinned, model, pinned, thread.ID.String()[:8])
+}
@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/llm"
+)
+
+// reconnectDelay is how long to wait before the first reconnect, later attempts wait
+// longer
+const reconnectDelay = time.Second
+
+// continuationPrompt asks the model to carry on from the text received before the
+// connection dropped
+const continuationPrompt = "The connection dropped while you were responding and your response above was cut off. " +
+	"Continue it exactly where it stopped. Do not repeat any of it or mention the interruption."
+
+// droppedMessages are parts of the errors that SDKs and the network stack return when
+// a streaming connection closes before the response finished
+var droppedMessages = []string{
+	"connection reset",
+	"broken pipe",
+	"unexpected eof",
+	"stream error",
+	"goaway",
+	"connection closed",
+	"server closed",
+}
+
+// droppedConnection reports whether err means the connection to the provider was lost
+// mid response, as opposed to the provider rejecting the request
+func droppedConnection(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, dropped := range droppedMessages {
+		if strings.Contains(msg, dropped) {
+			return true
+		}
+	}
+	return false
+}
+
+// reconnects returns how many times a dropped response may be requested again
+func (a *Agent) reconnects() int {
+	return max(a.preset.Reconnects, 0)
+}
+
+// waitToReconnect pauses before a reconnect attempt, returning false if the request
+// ended while waiting
+func waitToReconnect(ctx context.Context, attempt int) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(reconnectDelay * time.Duration(attempt)):
+		return true
+	}
+}
+
+// continuation returns the request to send after the connection dropped. With no text
+// received the original request is sent again, otherwise the received text becomes an
+// assistant turn and the model is asked to continue it
+func continuation(opts llm.GenerateContentOptions, msg domain.Message, content string, received string) llm.GenerateContentOptions {
+	if received == "" {
+		return opts
+	}
+	msg.Content = content
+	opts.History = append(slices.Clone(opts.History), msg, domain.Message{
+		ThreadID: msg.ThreadID,
+		Role:     domain.RoleAssistant,
+		Content:  received,
+	})
+	opts.Content = continuationPrompt
+	opts.ContentParts = nil
+	return opts
+}
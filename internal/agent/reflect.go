@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/llm"
+	"github.com/isaacphi/slop/internal/privacy"
+)
+
+// reflectionPrompt asks the model to revise its draft response
+const reflectionPrompt = `Critique your previous response for mistakes, omissions and anything unclear or unnecessary. ` +
+	`Then write an improved version of it. ` +
+	`Reply with only the revised response, without the critique or any preamble. ` +
+	`If the response needs no changes, repeat it exactly.`
+
+// reviseResponse runs the reflection pass over a draft response to msg. Tools are
+// not offered so the revision is always a plain answer
+func (a *Agent) reviseResponse(
+	ctx context.Context,
+	thread *domain.Thread,
+	systemMessage *domain.Message,
+	history []domain.Message,
+	msg *domain.Message,
+	draft string,
+) (string, error) {
+	preset := privacy.Apply(thread, a.preset)
+	preset.ToolChoice = ""
+
+	reflectionHistory := slices.Concat(
+		compactToolResults(summarizeToolCalls(history), a.preset.CompactToolResultsAfter),
+		[]domain.Message{*msg, {Role: domain.RoleAssistant, Content: draft}},
+	)
+
+	resp, err := llm.GenerateContent(ctx, llm.GenerateContentOptions{
+		Preset:        preset,
+		Content:       reflectionPrompt,
+		SystemMessage: systemMessage,
+		History:       reflectionHistory,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to revise response: %w", err)
+	}
+
+	revised := strings.TrimSpace(resp.TextResponse)
+	if revised == "" {
+		return draft, nil
+	}
+	return revised, nil
+}
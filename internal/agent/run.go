@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/events"
+)
+
+// startRun records a new run for a turn in the thread
+func (a *Agent) startRun(ctx context.Context, threadID uuid.UUID) (*domain.Run, error) {
+	preset, err := json.Marshal(a.preset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode preset: %w", err)
+	}
+	run := &domain.Run{
+		ThreadID: threadID,
+		Step:     domain.RunStepGenerate,
+		Status:   domain.RunStatusRunning,
+		Preset:   string(preset),
+	}
+	if err := a.repository.CreateRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("failed to create run: %w", err)
+	}
+	return run, nil
+}
+
+// checkpoint records the message a run continues from and what it does with it next.
+// Finished tool results are only kept while the run stays on the same tool calls
+func (a *Agent) checkpoint(ctx context.Context, run *domain.Run, msg *domain.Message, step domain.RunStep) error {
+	if run.MessageID == msg.ID && run.Step == step {
+		return nil
+	}
+	run.MessageID = msg.ID
+	run.Step = step
+	run.ToolResults = ""
+	if err := a.repository.UpdateRun(ctx, run); err != nil {
+		return fmt.Errorf("failed to update run: %w", err)
+	}
+	return nil
+}
+
+// finishRun records how a run ended. It is saved even when ctx was cancelled
+func (a *Agent) finishRun(ctx context.Context, run *domain.Run, err error) {
+	switch {
+	case err == nil && run.Status == domain.RunStatusRunning:
+		run.Status = domain.RunStatusDone
+	case errors.Is(err, context.Canceled):
+		run.Status = domain.RunStatusInterrupted
+	case err != nil:
+		run.Status = domain.RunStatusFailed
+		run.Error = err.Error()
+	}
+	if updateErr := a.repository.UpdateRun(context.WithoutCancel(ctx), run); updateErr != nil {
+		slog.Warn("failed to record the end of a run", "run", run.ID, "error", updateErr)
+	}
+	if run.Status == domain.RunStatusDone {
+		if summaryErr := a.summarizeToolRun(context.WithoutCancel(ctx), run); summaryErr != nil {
+			slog.Warn("failed to summarize the tool calls of a run", "run", run.ID, "error", summaryErr)
+		}
+	}
+}
+
+// RunPreset returns the preset a run was started with
+func RunPreset(run *domain.Run) (config.Preset, error) {
+	var preset config.Preset
+	if err := json.Unmarshal([]byte(run.Preset), &preset); err != nil {
+		return preset, fmt.Errorf("failed to decode the preset of run %s: %w", run.ID.String()[:8], err)
+	}
+	return preset, nil
+}
+
+// ResumeRun continues an interrupted run from its last checkpoint. Tool calls that
+// finished before the interruption are not executed again
+func (a *Agent) ResumeRun(ctx context.Context, run *domain.Run) AgentStream {
+	return a.stream(func(eventsChan chan events.Event) error {
+		if !run.Resumable() {
+			return fmt.Errorf("run %s is %s and can't be resumed", run.ID.String()[:8], run.Status)
+		}
+		if run.MessageID == uuid.Nil {
+			return fmt.Errorf("run %s stopped before it saved any progress, send the message again instead", run.ID.String()[:8])
+		}
+		msg, err := a.repository.GetMessage(ctx, run.MessageID)
+		if err != nil {
+			return fmt.Errorf("failed to get the message run %s stopped at: %w", run.ID.String()[:8], err)
+		}
+
+		run.Status = domain.RunStatusRunning
+		run.Error = ""
+		if err := a.repository.UpdateRun(ctx, run); err != nil {
+			return fmt.Errorf("failed to update run: %w", err)
+		}
+		eventsChan <- &RunStartedEvent{RunID: run.ID}
+
+		err = a.agentLoop(ctx, run, msg, eventsChan)
+		a.finishRun(ctx, run, err)
+		return err
+	})
+}
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/tokens"
+)
+
+// slimTool shrinks a tool's schema with the toolset's limits and the tool's own
+// overrides. The original tool is left unchanged
+func slimTool(tool domain.Tool, slim config.SchemaSlimming, override config.ToolConfig) (domain.Tool, error) {
+	if override.Description != "" {
+		tool.Description = override.Description
+	}
+	tool.Description = truncateDescription(tool.Description, slim.MaxDescription)
+
+	properties := make(map[string]domain.Property, len(tool.Parameters.Properties))
+	for name, prop := range tool.Parameters.Properties {
+		required := slices.Contains(tool.Parameters.Required, name)
+		if slices.Contains(override.DropParameters, name) {
+			if required {
+				return domain.Tool{}, fmt.Errorf("cannot drop required parameter %q of tool %q", name, tool.Name)
+			}
+			continue
+		}
+		if slim.DropOptional && !required {
+			continue
+		}
+		properties[name] = slimProperty(prop, slim)
+	}
+	tool.Parameters.Properties = properties
+	return tool, nil
+}
+
+// slimProperty applies the limits to a parameter and everything nested in it
+func slimProperty(prop domain.Property, slim config.SchemaSlimming) domain.Property {
+	prop.Description = truncateDescription(prop.Description, slim.MaxDescription)
+	if slim.MaxEnum > 0 && len(prop.Enum) > slim.MaxEnum {
+		prop.Enum = nil
+	}
+	if prop.Items != nil {
+		items := slimProperty(*prop.Items, slim)
+		prop.Items = &items
+	}
+	if len(prop.Properties) > 0 {
+		nested := make(map[string]domain.Property, len(prop.Properties))
+		for name, child := range prop.Properties {
+			if slim.DropOptional && !slices.Contains(prop.Required, name) {
+				continue
+			}
+			nested[name] = slimProperty(child, slim)
+		}
+		prop.Properties = nested
+	}
+	return prop
+}
+
+// truncateDescription cuts a description to at most limit characters, 0 keeps it whole
+func truncateDescription(description string, limit int) string {
+	runes := []rune(description)
+	if limit <= 0 || len(runes) <= limit {
+		return description
+	}
+	return string(runes[:max(limit-3, 0)]) + "..."
+}
+
+// ToolCost is the estimated number of tokens a tool's schema adds to every request
+type ToolCost struct {
+	Server   string
+	Tool     string
+	Original int // Tokens for the schema as the server describes it
+	Slimmed  int // Tokens for the schema sent to the model
+}
+
+// SchemaCost estimates the tokens each tool available to preset adds to every
+// request, before and after slimming. Tools are sorted by server and name
+func SchemaCost(allTools map[string]map[string]domain.Tool, preset config.Preset, toolsets map[string]config.Toolset) ([]ToolCost, error) {
+	tools, err := filterAndModifyTools(allTools, preset.Toolsets, toolsets)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenizer := tokens.For(preset.Tokenizer, preset.Provider, preset.Name)
+	var costs []ToolCost
+	for server, serverTools := range tools {
+		for name, tool := range serverTools {
+			costs = append(costs, ToolCost{
+				Server:   server,
+				Tool:     name,
+				Original: schemaTokens(tokenizer, allTools[server][name]),
+				Slimmed:  schemaTokens(tokenizer, tool.Tool),
+			})
+		}
+	}
+	sort.Slice(costs, func(i, j int) bool {
+		if costs[i].Server != costs[j].Server {
+			return costs[i].Server < costs[j].Server
+		}
+		return costs[i].Tool < costs[j].Tool
+	})
+	return costs, nil
+}
+
+// schemaTokens estimates the tokens a tool's schema takes up in a request
+func schemaTokens(tokenizer tokens.Tokenizer, tool domain.Tool) int {
+	schema, err := json.Marshal(tool)
+	if err != nil {
+		return 0
+	}
+	return tokenizer.Count(string(schema))
+}
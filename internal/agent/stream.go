@@ -4,16 +4,49 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/artifact"
+	"github.com/isaacphi/slop/internal/citation"
 	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/errkind"
 	"github.com/isaacphi/slop/internal/events"
 	"github.com/isaacphi/slop/internal/llm"
+	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/privacy"
 )
 
+// maxArtifactPreview limits how much of a tool result saved as an artifact is still
+// sent to the model
+const maxArtifactPreview = 2000
+
 // SendMessageStream sends a message through the Agent and returns a stream of events
 // It takes a domain.Message as input and handles both new messages and tool approvals
+// Progress is recorded in a run so the turn can be resumed if it is interrupted
 func (a *Agent) SendMessageStream(ctx context.Context, msg *domain.Message) AgentStream {
+	return a.stream(func(eventsChan chan events.Event) error {
+		run, err := a.startRun(ctx, msg.ThreadID)
+		if err != nil {
+			return err
+		}
+		eventsChan <- &RunStartedEvent{RunID: run.ID}
+
+		// Start the agent loop
+		err = a.agentLoop(ctx, run, msg, eventsChan)
+		a.finishRun(ctx, run, err)
+		if err == nil {
+			a.titleThread(ctx, msg.ThreadID, eventsChan)
+		}
+		return err
+	})
+}
+
+// stream runs fn in the background and returns the events it sends, followed by its error
+func (a *Agent) stream(fn func(eventsChan chan events.Event) error) AgentStream {
 	eventsChan := make(chan events.Event)
 	done := make(chan struct{})
 
@@ -21,11 +54,10 @@ func (a *Agent) SendMessageStream(ctx context.Context, msg *domain.Message) Agen
 		defer close(done)
 		defer close(eventsChan)
 
-		// Start the agent loop
-		err := a.agentLoop(ctx, msg, eventsChan)
-		if err != nil {
+		if err := fn(eventsChan); err != nil {
 			eventsChan <- &events.ErrorEvent{
 				Error: err,
+				Kind:  errkind.Of(err),
 			}
 		}
 	}()
@@ -33,14 +65,68 @@ func (a *Agent) SendMessageStream(ctx context.Context, msg *domain.Message) Agen
 	return AgentStream{Events: eventsChan, Done: done}
 }
 
+// saveToolResults adds a tool result message replying to parent. Large results and
+// binary content returned by the tools are stored as artifacts
+func (a *Agent) saveToolResults(ctx context.Context, parent *domain.Message, output toolOutput) (*domain.Message, error) {
+	results, attachments := output.results, output.attachments
+	toolMsg := &domain.Message{
+		ThreadID: parent.ThreadID,
+		ParentID: &parent.ID,
+		Role:     domain.RoleTool,
+		Content:  results,
+	}
+	if len(output.durations) > 0 {
+		if err := toolMsg.SetMetadata(domain.MessageMetadata{ToolDurations: output.durations}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Keep large results out of the conversation
+	limit := a.preset.ToolResultArtifactSize
+	largeResult := limit > 0 && len(results) > limit
+	if largeResult {
+		toolMsg.Content = fmt.Sprintf("%s\n...\n%s",
+			results[:min(limit, maxArtifactPreview)],
+			artifact.Reference(artifact.Hash(results), "tool-result.txt", len(results)),
+		)
+	}
+
+	if len(attachments) > 0 {
+		parts := append([]domain.MessagePart{{Content: toolMsg.Content}}, attachmentParts(attachments)...)
+		if err := toolMsg.SetParts(parts); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := a.repository.AddMessageToThread(ctx, parent.ThreadID, toolMsg); err != nil {
+		return nil, fmt.Errorf("failed to add tool results to thread: %w", err)
+	}
+
+	if largeResult {
+		if _, err := artifact.Store(ctx, a.repository, toolMsg.ThreadID, &toolMsg.ID, "tool-result.txt", results); err != nil {
+			return nil, err
+		}
+	}
+	for _, attachment := range attachments {
+		if _, err := artifact.Store(ctx, a.repository, toolMsg.ThreadID, &toolMsg.ID, attachment.Name, string(attachment.Data)); err != nil {
+			return nil, err
+		}
+	}
+
+	return toolMsg, nil
+}
+
 // agentLoop handles the continuous processing of messages and tool calls
-func (a *Agent) agentLoop(ctx context.Context, initialMsg *domain.Message, eventsChan chan events.Event) error {
+func (a *Agent) agentLoop(ctx context.Context, run *domain.Run, initialMsg *domain.Message, eventsChan chan events.Event) error {
 	// Validate thread exists
 	thread, err := a.repository.GetThread(ctx, initialMsg.ThreadID)
 	if err != nil {
 		return fmt.Errorf("failed to get thread: %w", err)
 	}
 
+	// Built-in servers such as thread act on the conversation their tools are called from
+	ctx = mcp.WithThread(ctx, thread.ID)
+
 	// Use iteration instead of recursion to avoid stack overflow
 	currentMsg := initialMsg
 
@@ -70,9 +156,12 @@ func (a *Agent) agentLoop(ctx context.Context, initialMsg *domain.Message, event
 			if len(toolCalls) == 0 {
 				return fmt.Errorf("no tool calls found in message")
 			}
+			if err := a.checkpoint(ctx, run, currentMsg, domain.RunStepTools); err != nil {
+				return err
+			}
 
 			// Execute the approved tools and continue the loop
-			results, err := a.ExecuteTools(ctx, toolCalls)
+			output, err := a.executeTools(ctx, run, toolCalls)
 			if err != nil {
 				return fmt.Errorf("failed to execute tools: %w", err)
 			}
@@ -82,20 +171,14 @@ func (a *Agent) agentLoop(ctx context.Context, initialMsg *domain.Message, event
 				eventsChan <- &ToolResultEvent{
 					ToolCallID: call.ID,
 					Name:       call.Name,
-					Result:     results,
+					Result:     output.results,
 				}
 			}
 
 			// Create tool result message
-			toolMsg := &domain.Message{
-				ThreadID: currentMsg.ThreadID,
-				ParentID: &currentMsg.ID,
-				Role:     domain.RoleTool,
-				Content:  results,
-			}
-
-			if err := a.repository.AddMessageToThread(ctx, currentMsg.ThreadID, toolMsg); err != nil {
-				return fmt.Errorf("failed to add tool results to thread: %w", err)
+			toolMsg, err := a.saveToolResults(ctx, currentMsg, output)
+			if err != nil {
+				return err
 			}
 
 			// Send message created event
@@ -118,9 +201,12 @@ func (a *Agent) agentLoop(ctx context.Context, initialMsg *domain.Message, event
 					Message: currentMsg,
 				}
 			}
+			if err := a.checkpoint(ctx, run, currentMsg, domain.RunStepGenerate); err != nil {
+				return err
+			}
 
 			// Get the AI response
-			aiMsg, shouldContinue, err := a.processMessage(ctx, currentMsg, eventsChan)
+			aiMsg, shouldContinue, err := a.processMessage(ctx, run, thread, currentMsg, eventsChan)
 			if err != nil {
 				return err
 			}
@@ -141,7 +227,16 @@ func (a *Agent) agentLoop(ctx context.Context, initialMsg *domain.Message, event
 
 // processMessage generates the next AI response based on the given message
 // Returns the AI message, a boolean indicating if the loop should continue, and any error
-func (a *Agent) processMessage(ctx context.Context, msg *domain.Message, eventsChan chan events.Event) (*domain.Message, bool, error) {
+func (a *Agent) processMessage(ctx context.Context, run *domain.Run, thread *domain.Thread, msg *domain.Message, eventsChan chan events.Event) (*domain.Message, bool, error) {
+	// Nothing is sent when the thread's privacy level doesn't allow the preset
+	if err := privacy.Check(thread, "preset", a.preset); err != nil {
+		return nil, false, err
+	}
+
+	for _, exit := range a.mcpClient.Exits() {
+		eventsChan <- &ServerExitEvent{Server: exit.Server, Error: exit.Error, Restarting: exit.Restarting}
+	}
+
 	// Get conversation history for context
 	history, err := a.repository.GetMessages(ctx, msg.ThreadID, msg.ParentID, false)
 	if err != nil {
@@ -149,34 +244,146 @@ func (a *Agent) processMessage(ctx context.Context, msg *domain.Message, eventsC
 	}
 
 	// Build system message
-	systemMessage, err := a.buildSystemMessage(systemMessageOpts{
+	systemMessage, sources, err := a.buildSystemMessage(ctx, systemMessageOpts{
+		threadID:       msg.ThreadID,
 		messageContent: msg.Content,
+		messageRole:    msg.Role,
 		history:        history,
 	})
 	if err != nil {
 		return nil, false, fmt.Errorf("failed to build system message: %w", err)
 	}
 
+	// Long threads send the summary of their older messages in place of them
+	summary, recent, contextSummary := a.summarizeContext(ctx, thread, history)
+	if contextSummary != nil {
+		eventsChan <- contextSummary
+	}
+	if summary != "" {
+		systemMessage = withContextSummary(systemMessage, msg.ThreadID, summary)
+		sources = append(sources, "context summary")
+	}
+	if systemMessage != nil {
+		eventsChan <- &SystemMessageEvent{Content: systemMessage.Content, Sources: sources}
+	}
+
+	// Tool choice only applies to the first response in a turn, forcing a tool
+	// call after every tool result would never let the model finish
+	preset := privacy.Apply(thread, a.preset)
+	if msg.Role == domain.RoleTool {
+		preset.ToolChoice = ""
+	}
+
 	// Get AI response
+	compacted := compactToolResults(summarizeToolCalls(recent), a.preset.CompactToolResultsAfter)
+	compacted, compression, err := a.compressHistory(ctx, thread, compacted)
+	if err != nil {
+		return nil, false, err
+	}
+	if compression != nil {
+		eventsChan <- compression
+	}
+	content := msg.Content
+	if a.preset.Citations {
+		compacted = citation.Labelled(compacted)
+		if msg.Role == domain.RoleHuman {
+			content = citation.Label(msg.ID) + " " + content
+		}
+	}
 	generateOptions := llm.GenerateContentOptions{
-		Preset:        a.preset,
-		Content:       msg.Content,
+		Preset:        preset,
+		Content:       content,
+		ContentParts:  llm.ContentParts(*msg),
 		SystemMessage: systemMessage,
-		History:       history,
-		Tools:         flattenTools(a.tools),
+		History:       compacted,
+		Tools:         flattenTools(a.availableTools()),
+		Attachments:   a.loadAttachments(ctx, append(compacted, *msg)...),
+	}
+
+	// Limit how long the model may take to respond, tool calls are not included
+	requestCtx := ctx
+	timeout, err := a.requestTimeout()
+	if err != nil {
+		return nil, false, err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		requestCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Identical requests are answered from the response cache when the preset has one
+	ttl, err := a.cacheTTL()
+	if err != nil {
+		return nil, false, err
+	}
+	var key string
+	var cached *domain.CachedResponse
+	if ttl > 0 {
+		key, err = cacheKey(generateOptions)
+		if err != nil {
+			return nil, false, err
+		}
+		cached, err = a.repository.GetCachedResponse(ctx, key)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read the response cache: %w", err)
+		}
 	}
 
 	// Get LLM stream
-	llmStream := llm.GenerateContentStream(ctx, generateOptions)
+	var llmStream llm.LLMStream
+	start := time.Now()
+	if cached != nil {
+		if err := a.repository.RecordCacheHit(ctx, cached.ID); err != nil {
+			return nil, false, fmt.Errorf("failed to update the response cache: %w", err)
+		}
+		llmStream, err = cachedStream(*cached)
+		if err != nil {
+			return nil, false, err
+		}
+		eventsChan <- &CacheHitEvent{StoredAt: cached.UpdatedAt}
+	} else {
+		llmStream = llm.GenerateContentStream(requestCtx, generateOptions)
+	}
 
 	// Track assistant response for saving
 	var aiMsg *domain.Message
 	var toolCalls []llm.ToolCall
 
+	// With reflection on, text is held back until we know whether the response
+	// is final and gets revised
+	var heldText []events.Event
+
+	// Text received so far, saved as it streams and if the request times out
+	var partial strings.Builder
+	saver := a.newAutosave(msg)
+
+	// Text received before the connection last dropped, the reconnected response
+	// continues from it
+	var received string
+	attempts := 0
+
+	// Preset answering in place of the agent's after the provider rate limited or was
+	// overloaded, and how far down the list of fallbacks the chain got
+	used, fallback, fallbacks := preset, "", 0
+	onTimeout := func() (*domain.Message, bool, error) {
+		saved, err := a.savePartialResponse(ctx, saver, partial.String())
+		if err != nil {
+			return nil, false, err
+		}
+		if saved != nil {
+			eventsChan <- &NewMessageEvent{Message: saved}
+		}
+		return nil, false, &TimeoutError{Timeout: timeout, Partial: saved}
+	}
+
 	// Forward LLM events to agent stream
 	for {
 		select {
-		case <-ctx.Done():
+		case <-requestCtx.Done():
+			if timedOut(ctx, requestCtx) {
+				return onTimeout()
+			}
 			return nil, false, ctx.Err()
 
 		case event, ok := <-llmStream.Events:
@@ -192,14 +399,72 @@ func (a *Agent) processMessage(ctx context.Context, msg *domain.Message, eventsC
 			// Handle event and collect response data
 			switch e := event.(type) {
 			case *llm.MessageCompleteEvent:
-				// Create and save AI message
+				if a.oncePreset == "" {
+					if err := checkPinnedModel(thread, history, e.Model); err != nil {
+						return nil, false, err
+					}
+				}
+
+				// Create and save AI message, stitched onto any text received before
+				// the connection dropped
+				response := received + e.Content
 				aiMsg = &domain.Message{
-					ThreadID:  msg.ThreadID,
-					ParentID:  &msg.ID,
-					Role:      domain.RoleAssistant,
-					Content:   e.Content,
-					ModelName: a.preset.Name,
-					Provider:  a.preset.Provider,
+					ThreadID:     msg.ThreadID,
+					ParentID:     &msg.ID,
+					Role:         domain.RoleAssistant,
+					Content:      response,
+					ModelName:    used.Name,
+					Provider:     used.Provider,
+					ModelVersion: e.Model,
+					InputTokens:  e.InputTokens,
+					OutputTokens: e.OutputTokens,
+				}
+
+				// Store the response as the provider sent it, before any revision.
+				// Recovered and fallback responses were answered to a different request
+				if key != "" && cached == nil && attempts == 0 && fallback == "" {
+					if err := a.cacheResponse(ctx, key, ttl, e); err != nil {
+						slog.Warn("failed to store response in the cache", "error", err)
+					}
+				}
+
+				// Revise final responses, responses with tool calls are only steps
+				metadata := domain.MessageMetadata{OncePreset: a.oncePreset, Recovered: attempts > 0, Fallback: fallback}
+				if cached == nil {
+					metadata.Duration = time.Since(start)
+				}
+				if a.preset.Reflect && len(e.ToolCalls) == 0 {
+					revised, err := a.reviseResponse(ctx, thread, systemMessage, recent, msg, response)
+					if err != nil {
+						if ctx.Err() != nil {
+							return nil, false, ctx.Err()
+						}
+						slog.Warn("reflection failed, keeping draft response", "error", err)
+					} else {
+						if revised != response {
+							aiMsg.Content = revised
+							metadata.Draft = response
+						}
+						heldText = []events.Event{&llm.TextEvent{Content: revised}}
+					}
+				}
+				if a.preset.AppendConfidence && len(e.ToolCalls) == 0 {
+					confidence, err := a.rateConfidence(ctx, thread, systemMessage, recent, msg, aiMsg.Content)
+					if err != nil {
+						if ctx.Err() != nil {
+							return nil, false, ctx.Err()
+						}
+						slog.Warn("confidence rating failed, saving response without it", "error", err)
+					}
+					metadata.Confidence = confidence
+				}
+				if metadata.Draft != "" || metadata.Confidence != nil || metadata.OncePreset != "" || metadata.Recovered || metadata.Fallback != "" || metadata.Duration > 0 {
+					if err := aiMsg.SetMetadata(metadata); err != nil {
+						return nil, false, err
+					}
+				}
+				for _, held := range heldText {
+					eventsChan <- held
 				}
 
 				// Save tool calls
@@ -212,10 +477,17 @@ func (a *Agent) processMessage(ctx context.Context, msg *domain.Message, eventsC
 					aiMsg.ToolCalls = string(toolCallsString)
 				}
 
-				if err := a.repository.AddMessageToThread(ctx, msg.ThreadID, aiMsg); err != nil {
+				if err := saver.finish(ctx, aiMsg); err != nil {
 					return nil, false, fmt.Errorf("failed to add AI message to thread: %w", err)
 				}
 
+				// Save long code blocks so they can be listed and written to files later
+				for i, block := range artifact.ExtractCodeBlocks(aiMsg.Content) {
+					if _, err := artifact.Store(ctx, a.repository, aiMsg.ThreadID, &aiMsg.ID, block.Name(i+1), block.Content); err != nil {
+						return nil, false, err
+					}
+				}
+
 				// Send AI message event
 				eventsChan <- &NewMessageEvent{
 					Message: aiMsg,
@@ -226,10 +498,18 @@ func (a *Agent) processMessage(ctx context.Context, msg *domain.Message, eventsC
 					return aiMsg, false, nil
 				}
 
-				// Check for function calls requiring approval
+				// Check for function calls requiring approval, skipping calls the user
+				// already allowed for this thread or session
+				approvals, err := a.repository.ListToolApprovals(ctx, msg.ThreadID, sessionID)
+				if err != nil {
+					return nil, false, fmt.Errorf("failed to get tool approvals: %w", err)
+				}
 				var toolsNeedingApproval []llm.ToolCall
 
 				for _, call := range toolCalls {
+					if rememberedApproval(approvals, call) {
+						continue
+					}
 					// Find tool approval setting
 					for serverName, serverTools := range a.tools {
 						for toolName, tool := range serverTools {
@@ -244,6 +524,7 @@ func (a *Agent) processMessage(ctx context.Context, msg *domain.Message, eventsC
 
 				// If any tools need approval, emit an approval event and exit the loop
 				if len(toolsNeedingApproval) > 0 {
+					run.Status = domain.RunStatusApproval
 					eventsChan <- &ToolApprovalRequestEvent{
 						Message:   aiMsg,
 						ToolCalls: toolsNeedingApproval,
@@ -252,7 +533,10 @@ func (a *Agent) processMessage(ctx context.Context, msg *domain.Message, eventsC
 				}
 
 				// All tools are auto-approved, execute them
-				results, err := a.ExecuteTools(ctx, toolCalls)
+				if err := a.checkpoint(ctx, run, aiMsg, domain.RunStepTools); err != nil {
+					return nil, false, err
+				}
+				output, err := a.executeTools(ctx, run, toolCalls)
 				if err != nil {
 					if ctx.Err() != nil {
 						// Prioritize reporting context errors
@@ -267,20 +551,14 @@ func (a *Agent) processMessage(ctx context.Context, msg *domain.Message, eventsC
 					eventsChan <- &ToolResultEvent{
 						ToolCallID: call.ID,
 						Name:       call.Name,
-						Result:     results,
+						Result:     output.results,
 					}
 				}
 
 				// Create tool result message
-				toolMsg := &domain.Message{
-					ThreadID: msg.ThreadID,
-					ParentID: &aiMsg.ID,
-					Role:     domain.RoleTool,
-					Content:  results,
-				}
-
-				if err := a.repository.AddMessageToThread(ctx, msg.ThreadID, toolMsg); err != nil {
-					return nil, false, fmt.Errorf("failed to add tool results to thread: %w", err)
+				toolMsg, err := a.saveToolResults(ctx, aiMsg, output)
+				if err != nil {
+					return nil, false, err
 				}
 
 				// Send message created event
@@ -292,7 +570,50 @@ func (a *Agent) processMessage(ctx context.Context, msg *domain.Message, eventsC
 				return toolMsg, true, nil
 
 			case *events.ErrorEvent:
-				return nil, false, e.Error
+				if timedOut(ctx, requestCtx) {
+					return onTimeout()
+				}
+				// Reconnect and ask for the rest of a response cut off by a dropped connection
+				if cached == nil && attempts < a.reconnects() && droppedConnection(e.Error) && ctx.Err() == nil {
+					attempts++
+					received = partial.String()
+					eventsChan <- &ReconnectEvent{Attempt: attempts, Received: utf8.RuneCountInString(received), Error: e.Error}
+					if !waitToReconnect(requestCtx, attempts) {
+						if timedOut(ctx, requestCtx) {
+							return onTimeout()
+						}
+						return nil, false, ctx.Err()
+					}
+					llmStream = llm.GenerateContentStream(requestCtx, continuation(generateOptions, *msg, content, received))
+					continue
+				}
+				// Send the request to the next fallback preset when the provider is
+				// rate limiting or overloaded
+				if cached == nil && shouldFallBack(e, partial.String()) && ctx.Err() == nil {
+					if name, next, position, ok := a.nextFallback(ctx, thread, fallbacks); ok {
+						fallbacks = position
+						if msg.Role == domain.RoleTool {
+							next.ToolChoice = ""
+						}
+						eventsChan <- &FallbackEvent{Preset: name, Model: next.Name, Failed: used.Name, Error: e.Error}
+						used, fallback = next, name
+						generateOptions.Preset = next
+						llmStream = llm.GenerateContentStream(requestCtx, generateOptions)
+						continue
+					}
+				}
+				return nil, false, errkind.New(e.Kind, e.Error)
+
+			case *llm.TextEvent:
+				partial.WriteString(e.Content)
+				if err := saver.save(ctx, partial.String()); err != nil {
+					slog.Warn("failed to autosave streamed response", "error", err)
+				}
+				if a.preset.Reflect {
+					heldText = append(heldText, e)
+					continue
+				}
+				eventsChan <- e
 
 			default:
 				// Forward events to agent stream
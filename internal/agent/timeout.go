@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// TimeoutError is returned when the model did not finish responding within the preset's
+// request timeout
+type TimeoutError struct {
+	Timeout time.Duration
+	Partial *domain.Message // The output received before the timeout, nil if there was none
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("the model did not finish responding within %s", e.Timeout)
+}
+
+// requestTimeout parses the preset's request timeout, 0 means requests never time out
+func (a *Agent) requestTimeout() (time.Duration, error) {
+	if a.preset.RequestTimeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(a.preset.RequestTimeout)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid request timeout %q", a.preset.RequestTimeout)
+	}
+	return timeout, nil
+}
+
+// timedOut reports whether a request stopped because its own deadline passed rather
+// than because the caller cancelled it
+func timedOut(ctx context.Context, requestCtx context.Context) bool {
+	return ctx.Err() == nil && errors.Is(requestCtx.Err(), context.DeadlineExceeded)
+}
+
+// savePartialResponse keeps the text received before a timeout as an assistant message
+// marked as partial, in place of the text autosaved while it streamed
+func (a *Agent) savePartialResponse(ctx context.Context, saver *autosave, content string) (*domain.Message, error) {
+	if content == "" {
+		return nil, nil
+	}
+	parent := saver.parent
+	partial := &domain.Message{
+		ThreadID:  parent.ThreadID,
+		ParentID:  &parent.ID,
+		Role:      domain.RoleAssistant,
+		Content:   content,
+		ModelName: a.preset.Name,
+		Provider:  a.preset.Provider,
+	}
+	if err := partial.SetMetadata(domain.MessageMetadata{Partial: true, OncePreset: a.oncePreset}); err != nil {
+		return nil, err
+	}
+	if err := saver.finish(ctx, partial); err != nil {
+		return nil, fmt.Errorf("failed to save partial response: %w", err)
+	}
+	return partial, nil
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/events"
+	"github.com/isaacphi/slop/internal/internalService"
+)
+
+// titleThread has the internal model write a title and summary for a thread without a
+// title once its first exchange is answered. A summary that was already set is kept.
+// Failures are only logged since the response is already saved
+func (a *Agent) titleThread(ctx context.Context, threadID uuid.UUID, eventsChan chan events.Event) {
+	cfg := appState.FromContext(ctx).Config
+	if !cfg.Internal.AutoTitle {
+		return
+	}
+	thread, err := a.repository.GetThread(ctx, threadID)
+	if err != nil || thread.Title != "" {
+		return
+	}
+	messages, err := a.repository.GetMessages(ctx, threadID, nil, false)
+	if err != nil || !firstExchange(messages) {
+		return
+	}
+
+	service, err := internal.NewForThread(cfg, thread)
+	if err != nil {
+		slog.Warn("failed to title thread", "thread", threadID, "error", err)
+		return
+	}
+	title, summary, err := service.CreateThreadTitle(ctx, messages)
+	if err != nil {
+		slog.Warn("failed to title thread", "thread", threadID, "error", err)
+		return
+	}
+	if err := a.repository.SetThreadTitle(ctx, threadID, title); err != nil {
+		slog.Warn("failed to save thread title", "thread", threadID, "error", err)
+		return
+	}
+	if thread.Summary == "" && summary != "" {
+		if err := a.repository.SetThreadSummary(ctx, threadID, summary); err != nil {
+			slog.Warn("failed to save thread summary", "thread", threadID, "error", err)
+			summary = ""
+		}
+	} else {
+		summary = thread.Summary
+	}
+
+	eventsChan <- &ThreadTitledEvent{ThreadID: threadID, Title: title, Summary: summary}
+}
+
+// firstExchange reports whether messages are a single human message followed by a
+// finished response, with any tool calls made on the way
+func firstExchange(messages []domain.Message) bool {
+	if len(messages) == 0 {
+		return false
+	}
+	humans := 0
+	for _, msg := range messages {
+		if msg.Role == domain.RoleHuman {
+			humans++
+		}
+	}
+	last := messages[len(messages)-1]
+	return humans == 1 && last.Role == domain.RoleAssistant && last.ToolCalls == ""
+}
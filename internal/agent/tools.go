@@ -3,11 +3,15 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/isaacphi/slop/internal/config"
 	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/errkind"
 	"github.com/isaacphi/slop/internal/llm"
 )
 
@@ -26,6 +30,18 @@ func flattenTools(tools map[string]map[string]toolWithApproval) map[string]domai
 	return flat
 }
 
+// availableTools returns the tools of the agent without those of servers that exited
+// and are not running again yet
+func (a *Agent) availableTools() map[string]map[string]toolWithApproval {
+	available := make(map[string]map[string]toolWithApproval, len(a.tools))
+	for server, tools := range a.tools {
+		if a.mcpClient.Down(server) == nil {
+			available[server] = tools
+		}
+	}
+	return available
+}
+
 func filterAndModifyTools(allTools map[string]map[string]domain.Tool, modelToolsets []string, toolsets map[string]config.Toolset) (map[string]map[string]toolWithApproval, error) {
 	result := make(map[string]map[string]toolWithApproval)
 
@@ -35,7 +51,7 @@ func filterAndModifyTools(allTools map[string]map[string]domain.Tool, modelTools
 		for serverName, serverConfig := range toolset.Servers {
 			serverTools, exists := allTools[serverName]
 			if !exists {
-				return nil, fmt.Errorf("server %q not found", serverName)
+				return nil, errkind.New(errkind.ToolNotFound, fmt.Errorf("server %q not found", serverName))
 			}
 
 			if _, exists := result[serverName]; !exists {
@@ -45,6 +61,10 @@ func filterAndModifyTools(allTools map[string]map[string]domain.Tool, modelTools
 			// If AllowedTools is empty, include all server tools with server-level approval
 			if len(serverConfig.AllowedTools) == 0 {
 				for toolName, tool := range serverTools {
+					tool, err := slimTool(tool, toolset.Slim, config.ToolConfig{})
+					if err != nil {
+						return nil, err
+					}
 					result[serverName][toolName] = toolWithApproval{
 						Tool:            tool,
 						RequireApproval: serverConfig.RequireApproval,
@@ -57,12 +77,16 @@ func filterAndModifyTools(allTools map[string]map[string]domain.Tool, modelTools
 			for toolName, toolConfig := range serverConfig.AllowedTools {
 				tool, exists := serverTools[toolName]
 				if !exists {
-					return nil, fmt.Errorf("tool %q not found in server %q", toolName, serverName)
+					return nil, errkind.New(errkind.ToolNotFound, fmt.Errorf("tool %q not found in server %q", toolName, serverName))
 				}
 
 				if len(toolConfig.PresetParameters) > 0 {
 					tool = modifyToolWithPresets(tool, toolConfig.PresetParameters)
 				}
+				tool, err := slimTool(tool, toolset.Slim, toolConfig)
+				if err != nil {
+					return nil, err
+				}
 
 				result[serverName][toolName] = toolWithApproval{
 					Tool:            tool,
@@ -104,19 +128,59 @@ func modifyToolWithPresets(original domain.Tool, presets map[string]string) doma
 	return modified
 }
 
-// ExecuteTools executes a set of tool calls and returns the formatted results
-func (a *Agent) ExecuteTools(ctx context.Context, toolCalls []llm.ToolCall) (string, error) {
+// ExecuteTools executes a set of tool calls and returns the formatted results along
+// with any binary content the tools returned
+func (a *Agent) ExecuteTools(ctx context.Context, toolCalls []llm.ToolCall) (string, []ToolAttachment, error) {
+	output, err := a.executeTools(ctx, nil, toolCalls)
+	return output.results, output.attachments, err
+}
+
+// toolOutput is the outcome of executing the tool calls of a message
+type toolOutput struct {
+	results     string
+	attachments []ToolAttachment
+	durations   map[string]time.Duration // How long each call took by call ID, calls from before an interruption are not timed
+}
+
+// executeTools executes tool calls, recording each result in run as it finishes. Calls
+// the run already has results for are not executed again. Attachments of those calls
+// are not kept
+func (a *Agent) executeTools(ctx context.Context, run *domain.Run, toolCalls []llm.ToolCall) (toolOutput, error) {
 	// Create channels for collecting results
 	type toolResult struct {
-		call   llm.ToolCall
-		result string
-		err    error
+		call        llm.ToolCall
+		result      string
+		attachments []ToolAttachment
+		err         error
+		duration    time.Duration
+	}
+
+	var finished []domain.RunToolResult
+	if run != nil {
+		var err error
+		finished, err = run.GetToolResults()
+		if err != nil {
+			return toolOutput{}, err
+		}
+	}
+	done := make(map[string]bool)
+	for _, res := range finished {
+		done[res.CallID] = true
 	}
 
 	resultChan := make(chan toolResult, len(toolCalls))
 
 	// Execute tools concurrently
+	pending := 0
 	for _, call := range toolCalls {
+		if done[call.ID] {
+			continue
+		}
+		pending++
+		if reason, ok := a.rejected[call.ID]; ok {
+			resultChan <- toolResult{call: call, err: rejection(reason)}
+			continue
+		}
 		go func(tc llm.ToolCall) {
 			select {
 			case <-ctx.Done():
@@ -127,11 +191,14 @@ func (a *Agent) ExecuteTools(ctx context.Context, toolCalls []llm.ToolCall) (str
 				}
 				return
 			default:
-				result, err := a.executeFunction(ctx, tc, a.tools)
+				start := time.Now()
+				result, attachments, err := a.executeFunction(ctx, tc, a.tools)
 				resultChan <- toolResult{
-					call:   tc,
-					result: result,
-					err:    err,
+					call:        tc,
+					result:      result,
+					attachments: attachments,
+					err:         err,
+					duration:    time.Since(start),
 				}
 			}
 		}(call)
@@ -140,33 +207,75 @@ func (a *Agent) ExecuteTools(ctx context.Context, toolCalls []llm.ToolCall) (str
 	// Collect all results
 	var combinedResults strings.Builder
 	combinedResults.WriteString("Tool call results:\n\n")
+	output := toolOutput{durations: make(map[string]time.Duration)}
 
-	for i := 0; i < len(toolCalls); i++ {
+	// Results from before the run was interrupted come first
+	written := 0
+	for _, call := range toolCalls {
+		for _, res := range finished {
+			if res.CallID != call.ID {
+				continue
+			}
+			var err error
+			if res.Error != "" {
+				err = errors.New(res.Error)
+			}
+			writeToolResult(&combinedResults, call, res.Result, err, written == 0)
+			written++
+		}
+	}
+
+	for i := 0; i < pending; i++ {
 		select {
 		case <-ctx.Done():
-			return "", ctx.Err()
+			return toolOutput{}, ctx.Err()
 		case res := <-resultChan:
-			// Format the tool call header
-			fmt.Fprintf(&combinedResults, "Name: %s\n", res.call.Name)
-			fmt.Fprintf(&combinedResults, "ID: %s\n", res.call.ID)
-			fmt.Fprintf(&combinedResults, "Arguments: %s\n", string(res.call.Arguments))
-			fmt.Fprint(&combinedResults, "Result:\n")
+			output.attachments = append(output.attachments, res.attachments...)
+			output.durations[res.call.ID] = res.duration
+			writeToolResult(&combinedResults, res.call, res.result, res.err, written == 0)
+			written++
 
-			// Add result or error
+			// Record the result so it survives an interruption of the remaining calls
+			if run == nil || ctx.Err() != nil {
+				continue
+			}
+			recorded := domain.RunToolResult{CallID: res.call.ID, Result: res.result}
 			if res.err != nil {
-				fmt.Fprintf(&combinedResults, "Error: %v\n", res.err)
-			} else {
-				fmt.Fprintf(&combinedResults, "%s\n", res.result)
+				recorded.Error = res.err.Error()
 			}
-
-			// Add separator between results unless it's the last one
-			if i < len(toolCalls)-1 {
-				combinedResults.WriteString("\n")
+			finished = append(finished, recorded)
+			if err := run.SetToolResults(finished); err != nil {
+				return toolOutput{}, err
+			}
+			if err := a.repository.UpdateRun(ctx, run); err != nil {
+				slog.Warn("failed to record tool result", "run", run.ID, "error", err)
 			}
 		}
 	}
 
-	return combinedResults.String(), nil
+	output.results = combinedResults.String()
+	return output, nil
+}
+
+// writeToolResult formats the result of a tool call, results after the first are
+// separated by a blank line
+func writeToolResult(b *strings.Builder, call llm.ToolCall, result string, err error, first bool) {
+	if !first {
+		b.WriteString("\n")
+	}
+
+	// Format the tool call header
+	fmt.Fprintf(b, "Name: %s\n", call.Name)
+	fmt.Fprintf(b, "ID: %s\n", call.ID)
+	fmt.Fprintf(b, "Arguments: %s\n", string(call.Arguments))
+	fmt.Fprint(b, "Result:\n")
+
+	// Add result or error
+	if err != nil {
+		fmt.Fprintf(b, "Error: %v\n", err)
+	} else {
+		fmt.Fprintf(b, "%s\n", result)
+	}
 }
 
 // validateArguments checks if the provided arguments match the tool's schema
@@ -230,7 +339,7 @@ func validateArguments(args json.RawMessage, tool toolWithApproval) error {
 	return nil
 }
 
-func (a *Agent) executeFunction(ctx context.Context, toolCall llm.ToolCall, tools map[string]map[string]toolWithApproval) (string, error) {
+func (a *Agent) executeFunction(ctx context.Context, toolCall llm.ToolCall, tools map[string]map[string]toolWithApproval) (string, []ToolAttachment, error) {
 	// Find the tool
 	for serverName, serverTools := range tools {
 		for toolName, tool := range serverTools {
@@ -238,7 +347,7 @@ func (a *Agent) executeFunction(ctx context.Context, toolCall llm.ToolCall, tool
 				// Parse provided arguments
 				var providedArgs map[string]interface{}
 				if err := json.Unmarshal(toolCall.Arguments, &providedArgs); err != nil {
-					return "", fmt.Errorf("failed to parse arguments: %w", err)
+					return "", nil, fmt.Errorf("failed to parse arguments: %w", err)
 				}
 
 				// Check if any parameters were preset
@@ -275,24 +384,26 @@ func (a *Agent) executeFunction(ctx context.Context, toolCall llm.ToolCall, tool
 
 				// Validate against tool schema
 				if err := validateArguments(toolCall.Arguments, tool); err != nil {
-					return "", fmt.Errorf("argument validation failed: %w", err)
+					return "", nil, fmt.Errorf("argument validation failed: %w", err)
 				}
 
 				// Execute the function
+				start := time.Now()
 				result, err := a.mcpClient.CallTool(ctx, serverName, toolName, mergedArgs)
-				if err != nil {
-					return "", fmt.Errorf("function execution failed: %w", err)
+				if ctx.Err() == nil {
+					// Stats are best effort and never fail the tool call
+					if statErr := a.repository.RecordToolCall(ctx, serverName, toolName, time.Since(start), err); statErr != nil {
+						slog.Warn("failed to record tool call", "server", serverName, "tool", toolName, "error", statErr)
+					}
 				}
-
-				resultBytes, err := json.Marshal(result)
 				if err != nil {
-					return "", fmt.Errorf("failed to format result: %w", err)
+					return "", nil, fmt.Errorf("function execution failed: %w", err)
 				}
 
-				return string(resultBytes), nil
+				return formatToolResponse(toolName, result)
 			}
 		}
 	}
 
-	return "", fmt.Errorf("tool %s not found", toolCall.Name)
+	return "", nil, errkind.New(errkind.ToolNotFound, fmt.Errorf("tool %s not found", toolCall.Name))
 }
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/toolview"
+)
+
+// maxSummaryResultLength limits how much of each tool result a tool summary quotes
+const maxSummaryResultLength = 100
+
+// summarizeToolRun condenses the tool calls of the turn a finished run answered into a
+// summary on its final response, and marks the calls and their results so they are
+// left out of later requests. Turns with fewer calls than the preset's threshold are
+// left as they are
+func (a *Agent) summarizeToolRun(ctx context.Context, run *domain.Run) error {
+	if a.preset.SummarizeToolRuns <= 0 || run.MessageID == uuid.Nil {
+		return nil
+	}
+	history, err := a.repository.GetMessages(ctx, run.ThreadID, &run.MessageID, true)
+	if err != nil {
+		return fmt.Errorf("failed to get thread messages: %w", err)
+	}
+	if len(history) == 0 {
+		return nil
+	}
+	response := history[len(history)-1]
+	if response.Role != domain.RoleAssistant || response.ToolCalls != "" {
+		return nil
+	}
+
+	start := len(history) - 1
+	for start > 0 && history[start-1].Role != domain.RoleHuman {
+		start--
+	}
+	turn := history[start : len(history)-1]
+
+	var calls []toolview.Call
+	for i := 0; i+1 < len(turn); i++ {
+		if turn[i].Role != domain.RoleAssistant || turn[i+1].Role != domain.RoleTool {
+			continue
+		}
+		if paired, ok := toolview.Calls(turn[i], turn[i+1]); ok {
+			calls = append(calls, paired...)
+		}
+	}
+	if len(calls) < a.preset.SummarizeToolRuns {
+		return nil
+	}
+
+	for _, msg := range turn {
+		metadata, err := msg.GetMetadata()
+		if err != nil {
+			return err
+		}
+		metadata.Summarized = true
+		if err := msg.SetMetadata(metadata); err != nil {
+			return err
+		}
+		if err := a.repository.UpdateMessageMetadata(ctx, &msg); err != nil {
+			return fmt.Errorf("failed to mark message as summarized: %w", err)
+		}
+	}
+
+	metadata, err := response.GetMetadata()
+	if err != nil {
+		return err
+	}
+	metadata.ToolSummary = toolSummary(calls)
+	if err := response.SetMetadata(metadata); err != nil {
+		return err
+	}
+	if err := a.repository.UpdateMessageMetadata(ctx, &response); err != nil {
+		return fmt.Errorf("failed to save tool summary: %w", err)
+	}
+	return nil
+}
+
+// toolSummary describes each call on one line with the start of its result
+func toolSummary(calls []toolview.Call) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Tool calls made before this response, results omitted: %d]", len(calls))
+	for _, call := range calls {
+		result, _, _ := strings.Cut(strings.TrimSpace(call.Result), "\n")
+		if runes := []rune(result); len(runes) > maxSummaryResultLength {
+			result = string(runes[:maxSummaryResultLength]) + "…"
+		}
+		fmt.Fprintf(&b, "\n- %s", call.Summary())
+		if result != "" {
+			fmt.Fprintf(&b, " → %s", result)
+		}
+	}
+	return b.String()
+}
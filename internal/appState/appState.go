@@ -2,22 +2,36 @@
 package appState
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"sync"
 
 	"github.com/isaacphi/slop/internal/config"
 )
 
-// App holds the global application state
+// App holds the application state. An App is not modified after it is created, so it
+// can be shared between goroutines. Use With to change settings for a single request
 type App struct {
 	Config *config.ConfigSchema
 	Logger *slog.Logger
 	closer io.Closer // For cleanup of resources like log files
 }
 
+// Scope overrides settings for a single request or subcommand
+type Scope struct {
+	Preset      string   // Preset to use instead of the default preset
+	Mode        string   // Mode whose overrides are applied to the preset
+	Temperature *float64 // Override the preset's temperature
+	MaxTokens   *int     // Override the preset's max tokens
+	LogAttrs    []any    // Attributes added to every log line, such as a request ID
+}
+
+type contextKey struct{}
+
 var (
 	globalApp *App
 	initOnce  sync.Once
@@ -25,37 +39,120 @@ var (
 	mu        sync.RWMutex
 )
 
+// New creates an App from the configuration files and the given overrides without
+// touching the global instance
+func New(overrides *config.RuntimeOverrides) (*App, error) {
+	// Load base configuration first
+	cfg, err := config.New(overrides)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Set up logger
+	logger, closer, err := setupLogger(cfg.Log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup logger: %w", err)
+	}
+
+	return &App{
+		Config: cfg,
+		Logger: logger,
+		closer: closer,
+	}, nil
+}
+
 // Initialize creates the global app instance with the given overrides
 func Initialize(overrides *config.RuntimeOverrides) error {
 	initOnce.Do(func() {
-		// Load base configuration first
-		cfg, err := config.New(overrides)
+		app, err := New(overrides)
 		if err != nil {
-			initErr = fmt.Errorf("failed to load config: %w", err)
-			return
-		}
-
-		// Set up logger
-		logger, closer, err := setupLogger(cfg.Log)
-		if err != nil {
-			initErr = fmt.Errorf("failed to setup logger: %w", err)
+			initErr = err
 			return
 		}
 
 		mu.Lock()
-		globalApp = &App{
-			Config: cfg,
-			Logger: logger,
-			closer: closer,
-		}
+		globalApp = app
 		mu.Unlock()
 
 		// Set as default logger
-		slog.SetDefault(logger)
+		slog.SetDefault(app.Logger)
 	})
 	return initErr
 }
 
+// NewContext returns a copy of ctx that carries app
+func NewContext(ctx context.Context, app *App) context.Context {
+	return context.WithValue(ctx, contextKey{}, app)
+}
+
+// FromContext returns the App carried by ctx, or the global App when there is none
+func FromContext(ctx context.Context) *App {
+	if app, ok := ctx.Value(contextKey{}).(*App); ok {
+		return app
+	}
+	return Get()
+}
+
+// With returns a copy of the App with the scope applied. The App it was called on is
+// left unchanged
+func (a *App) With(scope Scope) (*App, error) {
+	cfg := *a.Config
+	if scope.Preset != "" {
+		if _, ok := cfg.Presets[scope.Preset]; !ok {
+			return nil, fmt.Errorf("preset %s not found in configuration", scope.Preset)
+		}
+		cfg.DefaultPreset = scope.Preset
+	}
+
+	// Modes are resolved after the preset is chosen, an explicit preset wins over the mode's
+	var mode *config.Mode
+	if scope.Mode != "" {
+		m, ok := cfg.Modes[scope.Mode]
+		if !ok {
+			return nil, fmt.Errorf("mode %s not found in configuration", scope.Mode)
+		}
+		if scope.Preset == "" && m.Preset != "" {
+			cfg.DefaultPreset = m.Preset
+		}
+		mode = &m
+	}
+
+	if mode != nil || scope.Temperature != nil || scope.MaxTokens != nil {
+		preset, ok := cfg.Presets[cfg.DefaultPreset]
+		if !ok {
+			return nil, fmt.Errorf("preset %s not found in configuration", cfg.DefaultPreset)
+		}
+		if mode != nil {
+			preset = mode.Apply(preset)
+		}
+		if scope.Temperature != nil {
+			preset.Temperature = *scope.Temperature
+		}
+		if scope.MaxTokens != nil {
+			preset.MaxTokens = *scope.MaxTokens
+		}
+		cfg.Presets = maps.Clone(cfg.Presets)
+		cfg.Presets[cfg.DefaultPreset] = preset
+	}
+
+	logger := a.Logger
+	if len(scope.LogAttrs) > 0 {
+		logger = logger.With(scope.LogAttrs...)
+	}
+
+	// The copy shares resources with the original, so it has nothing to clean up
+	return &App{Config: &cfg, Logger: logger}, nil
+}
+
+// Preset returns the name and settings of the preset in use
+func (a *App) Preset() (string, config.Preset, error) {
+	preset, ok := a.Config.Presets[a.Config.DefaultPreset]
+	if !ok {
+		return "", config.Preset{}, fmt.Errorf("preset %s not found in configuration", a.Config.DefaultPreset)
+	}
+	return a.Config.DefaultPreset, preset, nil
+}
+
 // Get returns the global app instance and panics if not initialized
 func Get() *App {
 	mu.RLock()
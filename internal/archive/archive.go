@@ -0,0 +1,514 @@
+// Package archive reads and writes portable archives of conversation history, used
+// for backups and for moving history between machines without copying the database.
+//
+// An archive is an uncompressed tar file with these entries:
+//
+//	manifest.json     {"format": "slop-archive", "version": 1, "createdAt": ..., "threads": N, "messages": N, "artifacts": N}
+//	threads.jsonl     one Thread per line
+//	messages.jsonl    one Message per line, parents before their children
+//	artifacts.jsonl   one Artifact per line
+//	blobs/<sha256>    the content of the artifacts with that hash
+//
+// Readers reject archives with a newer version than they know. Unknown fields and
+// entries are ignored so later versions can add to the format.
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	// Format identifies slop archives in the manifest
+	Format = "slop-archive"
+	// Version is the version of the format written by this build
+	Version = 1
+
+	manifestFile  = "manifest.json"
+	threadsFile   = "threads.jsonl"
+	messagesFile  = "messages.jsonl"
+	artifactsFile = "artifacts.jsonl"
+	blobsDir      = "blobs/"
+)
+
+// Manifest describes the archive
+type Manifest struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Threads   int       `json:"threads"`
+	Messages  int       `json:"messages"`
+	Artifacts int       `json:"artifacts"`
+}
+
+// Thread is a thread as stored in an archive
+type Thread struct {
+	ID         uuid.UUID  `json:"id"`
+	Title      string     `json:"title,omitempty"`
+	Summary    string     `json:"summary,omitempty"`
+	Tags       []string   `json:"tags,omitempty"`
+	PinModel   bool       `json:"pinModel,omitempty"`
+	Language   string     `json:"language,omitempty"`
+	Privacy    string     `json:"privacy,omitempty"`
+	LastReadAt *time.Time `json:"lastReadAt,omitempty"`
+	ArchivedAt *time.Time `json:"archivedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	// Message whose branch is shown by default, nil follows the newest message
+	ActiveMessageID *uuid.UUID `json:"activeMessageId,omitempty"`
+}
+
+// Message is a message as stored in an archive. Parts, tool calls and metadata are
+// kept in the JSON encoding slop stores them in
+type Message struct {
+	ID           uuid.UUID       `json:"id"`
+	ThreadID     uuid.UUID       `json:"threadId"`
+	ParentID     *uuid.UUID      `json:"parentId,omitempty"`
+	Role         domain.Role     `json:"role"`
+	Content      string          `json:"content"`
+	Parts        json.RawMessage `json:"parts,omitempty"`
+	ToolCalls    json.RawMessage `json:"toolCalls,omitempty"`
+	Metadata     json.RawMessage `json:"metadata,omitempty"`
+	ModelName    string          `json:"modelName,omitempty"`
+	Provider     string          `json:"provider,omitempty"`
+	ModelVersion string          `json:"modelVersion,omitempty"`
+	InputTokens  int             `json:"inputTokens,omitempty"`
+	OutputTokens int             `json:"outputTokens,omitempty"`
+	CreatedAt    time.Time       `json:"createdAt"`
+	UpdatedAt    time.Time       `json:"updatedAt"`
+}
+
+// Artifact is an artifact as stored in an archive, its content is in blobs/<hash>
+type Artifact struct {
+	ID        uuid.UUID  `json:"id"`
+	Hash      string     `json:"hash"`
+	ThreadID  uuid.UUID  `json:"threadId"`
+	MessageID *uuid.UUID `json:"messageId,omitempty"`
+	Name      string     `json:"name"`
+	Size      int        `json:"size"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Archive is the content of an archive file
+type Archive struct {
+	Manifest  Manifest
+	Threads   []Thread
+	Messages  []Message
+	Artifacts []Artifact
+	Blobs     map[string][]byte
+}
+
+// Write exports threads with all their messages, tags and artifacts to w
+func Write(ctx context.Context, repo repository.MessageRepository, w io.Writer, threads []*domain.Thread) (Manifest, error) {
+	ids := make([]uuid.UUID, len(threads))
+	for i, thread := range threads {
+		ids[i] = thread.ID
+	}
+	selected := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		selected[id] = true
+	}
+
+	messages, err := repo.GetThreadsMessages(ctx, ids)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to get messages: %w", err)
+	}
+	tags, err := repo.GetThreadTags(ctx, ids)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to get tags: %w", err)
+	}
+	allArtifacts, err := repo.ListArtifacts(ctx, nil)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+
+	a := &Archive{Blobs: make(map[string][]byte)}
+	for _, thread := range threads {
+		a.Threads = append(a.Threads, Thread{
+			ID:         thread.ID,
+			Title:      thread.Title,
+			Summary:    thread.Summary,
+			Tags:       tags[thread.ID],
+			PinModel:   thread.PinModel,
+			Language:   thread.Language,
+			Privacy:    thread.Privacy,
+			LastReadAt: thread.LastReadAt,
+			ArchivedAt: thread.ArchivedAt,
+			CreatedAt:  thread.CreatedAt,
+			UpdatedAt:  thread.UpdatedAt,
+
+			ActiveMessageID: thread.ActiveMessageID,
+		})
+	}
+	for _, msg := range messages {
+		a.Messages = append(a.Messages, Message{
+			ID:           msg.ID,
+			ThreadID:     msg.ThreadID,
+			ParentID:     msg.ParentID,
+			Role:         msg.Role,
+			Content:      msg.Content,
+			Parts:        rawJSON(msg.Parts),
+			ToolCalls:    rawJSON(msg.ToolCalls),
+			Metadata:     rawJSON(msg.Metadata),
+			ModelName:    msg.ModelName,
+			Provider:     msg.Provider,
+			ModelVersion: msg.ModelVersion,
+			InputTokens:  msg.InputTokens,
+			OutputTokens: msg.OutputTokens,
+			CreatedAt:    msg.CreatedAt,
+			UpdatedAt:    msg.UpdatedAt,
+		})
+	}
+	for _, artifact := range allArtifacts {
+		if !selected[artifact.ThreadID] {
+			continue
+		}
+		if _, ok := a.Blobs[artifact.Hash]; !ok {
+			_, content, err := repo.GetArtifact(ctx, artifact.Hash)
+			if err != nil {
+				return Manifest{}, fmt.Errorf("failed to get artifact %s: %w", artifact.Name, err)
+			}
+			a.Blobs[artifact.Hash] = content
+		}
+		a.Artifacts = append(a.Artifacts, Artifact{
+			ID:        artifact.ID,
+			Hash:      artifact.Hash,
+			ThreadID:  artifact.ThreadID,
+			MessageID: artifact.MessageID,
+			Name:      artifact.Name,
+			Size:      artifact.Size,
+			CreatedAt: artifact.CreatedAt,
+		})
+	}
+
+	a.Manifest = Manifest{
+		Format:    Format,
+		Version:   Version,
+		CreatedAt: time.Now().UTC(),
+		Threads:   len(a.Threads),
+		Messages:  len(a.Messages),
+		Artifacts: len(a.Artifacts),
+	}
+	return a.Manifest, a.write(w)
+}
+
+// rawJSON keeps stored JSON as it is, values that aren't valid JSON are dropped
+func rawJSON(s string) json.RawMessage {
+	if s == "" || !json.Valid([]byte(s)) {
+		return nil
+	}
+	return json.RawMessage(s)
+}
+
+func (a *Archive) write(w io.Writer) error {
+	tw := tar.NewWriter(w)
+
+	manifest, err := json.MarshalIndent(a.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(tw, manifestFile, manifest, a.Manifest.CreatedAt); err != nil {
+		return err
+	}
+
+	if err := writeLines(tw, threadsFile, a.Threads, a.Manifest.CreatedAt); err != nil {
+		return err
+	}
+	if err := writeLines(tw, messagesFile, a.Messages, a.Manifest.CreatedAt); err != nil {
+		return err
+	}
+	if err := writeLines(tw, artifactsFile, a.Artifacts, a.Manifest.CreatedAt); err != nil {
+		return err
+	}
+
+	hashes := make([]string, 0, len(a.Blobs))
+	for hash := range a.Blobs {
+		hashes = append(hashes, hash)
+	}
+	slices.Sort(hashes)
+	for _, hash := range hashes {
+		if err := writeFile(tw, blobsDir+hash, a.Blobs[hash], a.Manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+// writeLines writes each value on its own line
+func writeLines[T any](tw *tar.Writer, name string, lines []T, modTime time.Time) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, line := range lines {
+		if err := encoder.Encode(line); err != nil {
+			return fmt.Errorf("failed to encode %s: %w", name, err)
+		}
+	}
+	return writeFile(tw, name, buf.Bytes(), modTime)
+}
+
+func writeFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// Read reads an archive
+func Read(r io.Reader) (*Archive, error) {
+	a := &Archive{Blobs: make(map[string][]byte)}
+	files := make(map[string][]byte)
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("not a slop archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", header.Name, err)
+		}
+		if hash, ok := strings.CutPrefix(header.Name, blobsDir); ok {
+			a.Blobs[path.Base(hash)] = data
+			continue
+		}
+		files[header.Name] = data
+	}
+
+	manifest, ok := files[manifestFile]
+	if !ok {
+		return nil, fmt.Errorf("not a slop archive: %s is missing", manifestFile)
+	}
+	if err := json.Unmarshal(manifest, &a.Manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", manifestFile, err)
+	}
+	if a.Manifest.Format != Format {
+		return nil, fmt.Errorf("not a slop archive: format is %q", a.Manifest.Format)
+	}
+	if a.Manifest.Version > Version {
+		return nil, fmt.Errorf("archive version %d is newer than the supported version %d, upgrade slop to import it", a.Manifest.Version, Version)
+	}
+
+	if err := readLines(files[threadsFile], &a.Threads); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", threadsFile, err)
+	}
+	if err := readLines(files[messagesFile], &a.Messages); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", messagesFile, err)
+	}
+	if err := readLines(files[artifactsFile], &a.Artifacts); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", artifactsFile, err)
+	}
+	return a, nil
+}
+
+// readLines decodes one value per line into a slice
+func readLines[T any](data []byte, out *[]T) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var value T
+		if err := json.Unmarshal(scanner.Bytes(), &value); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		*out = append(*out, value)
+	}
+	return scanner.Err()
+}
+
+// OnConflict decides what happens to imported threads that already exist
+type OnConflict string
+
+const (
+	// Skip leaves existing threads unchanged
+	Skip OnConflict = "skip"
+	// Merge adds the messages, tags and artifacts the existing thread doesn't have yet
+	Merge OnConflict = "merge"
+	// Duplicate imports the thread again as a new thread with new IDs
+	Duplicate OnConflict = "duplicate"
+)
+
+// ParseOnConflict checks a conflict strategy given by the user
+func ParseOnConflict(s string) (OnConflict, error) {
+	switch OnConflict(s) {
+	case Skip, Merge, Duplicate:
+		return OnConflict(s), nil
+	}
+	return "", fmt.Errorf("invalid conflict strategy %q, use skip, merge or duplicate", s)
+}
+
+// ImportResult counts what an import did with each thread
+type ImportResult struct {
+	Imported   int
+	Skipped    int
+	Merged     int
+	Duplicated int
+}
+
+// Import adds the threads of an archive to the repository
+func Import(ctx context.Context, repo repository.MessageRepository, a *Archive, onConflict OnConflict) (ImportResult, error) {
+	var result ImportResult
+
+	ids := make([]uuid.UUID, len(a.Threads))
+	for i, thread := range a.Threads {
+		ids[i] = thread.ID
+	}
+	existing, err := repo.ExistingThreads(ctx, ids)
+	if err != nil {
+		return result, fmt.Errorf("failed to check for existing threads: %w", err)
+	}
+
+	messages := make(map[uuid.UUID][]Message)
+	for _, msg := range a.Messages {
+		messages[msg.ThreadID] = append(messages[msg.ThreadID], msg)
+	}
+	artifacts := make(map[uuid.UUID][]Artifact)
+	for _, artifact := range a.Artifacts {
+		artifacts[artifact.ThreadID] = append(artifacts[artifact.ThreadID], artifact)
+	}
+
+	for _, thread := range a.Threads {
+		threadMessages, threadArtifacts := messages[thread.ID], artifacts[thread.ID]
+
+		if existing[thread.ID] {
+			switch onConflict {
+			case Skip:
+				result.Skipped++
+				continue
+			case Merge:
+				result.Merged++
+			case Duplicate:
+				thread, threadMessages, threadArtifacts = withNewIDs(thread, threadMessages, threadArtifacts)
+				result.Duplicated++
+			}
+		} else {
+			result.Imported++
+		}
+
+		if err := importThread(ctx, repo, thread, threadMessages, threadArtifacts, a.Blobs); err != nil {
+			return result, fmt.Errorf("failed to import thread %s: %w", thread.ID.String()[:8], err)
+		}
+	}
+	return result, nil
+}
+
+// withNewIDs gives a thread and everything in it new IDs, keeping the links between them
+func withNewIDs(thread Thread, messages []Message, artifacts []Artifact) (Thread, []Message, []Artifact) {
+	thread.ID = uuid.New()
+
+	newIDs := make(map[uuid.UUID]uuid.UUID, len(messages))
+	for _, msg := range messages {
+		newIDs[msg.ID] = uuid.New()
+	}
+	remap := func(id *uuid.UUID) *uuid.UUID {
+		if id == nil {
+			return nil
+		}
+		if newID, ok := newIDs[*id]; ok {
+			return &newID
+		}
+		return nil
+	}
+
+	renamed := make([]Message, len(messages))
+	for i, msg := range messages {
+		msg.ID = newIDs[msg.ID]
+		msg.ThreadID = thread.ID
+		msg.ParentID = remap(msg.ParentID)
+		renamed[i] = msg
+	}
+	thread.ActiveMessageID = remap(thread.ActiveMessageID)
+	renamedArtifacts := make([]Artifact, len(artifacts))
+	for i, artifact := range artifacts {
+		artifact.ID = uuid.New()
+		artifact.ThreadID = thread.ID
+		artifact.MessageID = remap(artifact.MessageID)
+		renamedArtifacts[i] = artifact
+	}
+	return thread, renamed, renamedArtifacts
+}
+
+func importThread(ctx context.Context, repo repository.MessageRepository, thread Thread, messages []Message, artifacts []Artifact, blobs map[string][]byte) error {
+	t := &domain.Thread{
+		ID:         thread.ID,
+		Title:      thread.Title,
+		Summary:    thread.Summary,
+		PinModel:   thread.PinModel,
+		Language:   thread.Language,
+		Privacy:    thread.Privacy,
+		LastReadAt: thread.LastReadAt,
+		ArchivedAt: thread.ArchivedAt,
+		Model:      gorm.Model{CreatedAt: thread.CreatedAt, UpdatedAt: thread.UpdatedAt},
+
+		ActiveMessageID: thread.ActiveMessageID,
+	}
+
+	msgs := make([]domain.Message, len(messages))
+	for i, msg := range messages {
+		msgs[i] = domain.Message{
+			ID:           msg.ID,
+			ThreadID:     thread.ID,
+			ParentID:     msg.ParentID,
+			Role:         msg.Role,
+			Content:      msg.Content,
+			Parts:        string(msg.Parts),
+			ToolCalls:    string(msg.ToolCalls),
+			Metadata:     string(msg.Metadata),
+			ModelName:    msg.ModelName,
+			Provider:     msg.Provider,
+			ModelVersion: msg.ModelVersion,
+			InputTokens:  msg.InputTokens,
+			OutputTokens: msg.OutputTokens,
+			Model:        gorm.Model{CreatedAt: msg.CreatedAt, UpdatedAt: msg.UpdatedAt},
+		}
+	}
+
+	arts := make([]domain.Artifact, len(artifacts))
+	for i, artifact := range artifacts {
+		if _, ok := blobs[artifact.Hash]; !ok {
+			return fmt.Errorf("content of artifact %s is missing from the archive", artifact.Name)
+		}
+		arts[i] = domain.Artifact{
+			ID:        artifact.ID,
+			Hash:      artifact.Hash,
+			ThreadID:  thread.ID,
+			MessageID: artifact.MessageID,
+			Name:      artifact.Name,
+			Size:      artifact.Size,
+			Model:     gorm.Model{CreatedAt: artifact.CreatedAt},
+		}
+	}
+
+	return repo.ImportThread(ctx, t, msgs, thread.Tags, arts, blobs)
+}
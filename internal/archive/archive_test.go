@@ -0,0 +1,155 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+)
+
+// newTestRepo opens an empty database in a temporary directory
+func newTestRepo(t *testing.T) repository.MessageRepository {
+	t.Helper()
+	repo, err := sqlite.Initialize(filepath.Join(t.TempDir(), "slop.db"))
+	if err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return repo
+}
+
+// roundTrip exports thread from repo and reads the archive back
+func roundTrip(t *testing.T, repo repository.MessageRepository, thread *domain.Thread) *Archive {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := Write(context.Background(), repo, &buf, []*domain.Thread{thread}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	a, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	return a
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := newTestRepo(t)
+
+	thread := &domain.Thread{Title: "tokens"}
+	if err := source.CreateThread(ctx, thread); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	msg := &domain.Message{Role: domain.RoleAssistant, Content: "hi", InputTokens: 12, OutputTokens: 34}
+	if err := source.AddMessageToThread(ctx, thread.ID, msg); err != nil {
+		t.Fatalf("AddMessageToThread: %v", err)
+	}
+	if err := source.SetActiveMessage(ctx, thread.ID, &msg.ID); err != nil {
+		t.Fatalf("SetActiveMessage: %v", err)
+	}
+	if err := source.SetThreadPrivacy(ctx, thread.ID, domain.PrivacyLocalOnly); err != nil {
+		t.Fatalf("SetThreadPrivacy: %v", err)
+	}
+	thread, err := source.GetThread(ctx, thread.ID)
+	if err != nil {
+		t.Fatalf("GetThread: %v", err)
+	}
+	a := roundTrip(t, source, thread)
+
+	target := newTestRepo(t)
+	if _, err := Import(ctx, target, a, Skip); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	got, err := target.GetMessage(ctx, msg.ID)
+	if err != nil {
+		t.Fatalf("GetMessage: %v", err)
+	}
+	if got.InputTokens != 12 || got.OutputTokens != 34 {
+		t.Errorf("tokens = %d/%d, want 12/34", got.InputTokens, got.OutputTokens)
+	}
+	imported, err := target.GetThread(ctx, thread.ID)
+	if err != nil {
+		t.Fatalf("GetThread: %v", err)
+	}
+	if imported.Privacy != domain.PrivacyLocalOnly {
+		t.Errorf("privacy = %q, want %q", imported.Privacy, domain.PrivacyLocalOnly)
+	}
+	if imported.ActiveMessageID == nil || *imported.ActiveMessageID != msg.ID {
+		t.Errorf("active message = %v, want %s", imported.ActiveMessageID, msg.ID)
+	}
+
+	// A duplicate points at its own copy of the active message
+	if _, err := Import(ctx, target, a, Duplicate); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	threads, err := target.ListThreads(ctx, 0)
+	if err != nil {
+		t.Fatalf("ListThreads: %v", err)
+	}
+	if len(threads) != 2 {
+		t.Fatalf("got %d threads, want the thread and its duplicate", len(threads))
+	}
+	for _, dup := range threads {
+		if dup.ID == thread.ID {
+			continue
+		}
+		if dup.ActiveMessageID == nil {
+			t.Fatalf("duplicate has no active message")
+		}
+		active, err := target.GetMessage(ctx, *dup.ActiveMessageID)
+		if err != nil {
+			t.Fatalf("GetMessage: %v", err)
+		}
+		if active.ThreadID != dup.ID {
+			t.Errorf("active message of the duplicate is in thread %s, want %s", active.ThreadID, dup.ID)
+		}
+	}
+}
+
+func TestImportDeletedThread(t *testing.T) {
+	tests := []struct {
+		onConflict OnConflict
+	}{
+		{onConflict: Skip},
+		{onConflict: Merge},
+		{onConflict: Duplicate},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.onConflict), func(t *testing.T) {
+			ctx := context.Background()
+			repo := newTestRepo(t)
+
+			thread := &domain.Thread{Title: "deleted"}
+			if err := repo.CreateThread(ctx, thread); err != nil {
+				t.Fatalf("CreateThread: %v", err)
+			}
+			msg := &domain.Message{Role: domain.RoleHuman, Content: "hello"}
+			if err := repo.AddMessageToThread(ctx, thread.ID, msg); err != nil {
+				t.Fatalf("AddMessageToThread: %v", err)
+			}
+			a := roundTrip(t, repo, thread)
+			if err := repo.DeleteThread(ctx, thread.ID); err != nil {
+				t.Fatalf("DeleteThread: %v", err)
+			}
+
+			// A deleted thread no longer exists, so it is imported again whatever the
+			// conflict policy is
+			result, err := Import(ctx, repo, a, tt.onConflict)
+			if err != nil {
+				t.Fatalf("Import: %v", err)
+			}
+			if result != (ImportResult{Imported: 1}) {
+				t.Errorf("result = %+v, want one imported thread", result)
+			}
+			if _, err := repo.GetThread(ctx, thread.ID); err != nil {
+				t.Errorf("GetThread: %v", err)
+			}
+			if _, err := repo.GetMessage(ctx, msg.ID); err != nil {
+				t.Errorf("GetMessage: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package artifact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
+)
+
+// ShortIDLength is how many characters of the hash are shown as an artifact's ID
+const ShortIDLength = 12
+
+// MinCodeBlockLines is the smallest fenced code block that is saved as an artifact
+const MinCodeBlockLines = 10
+
+// Hash returns the content address of an artifact
+func Hash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ShortID returns the ID an artifact is shown and referenced with
+func ShortID(hash string) string {
+	return hash[:min(ShortIDLength, len(hash))]
+}
+
+// Reference is the text that stands in for an artifact inside a message
+func Reference(hash, name string, size int) string {
+	return fmt.Sprintf("[artifact %s: %s, %s]", ShortID(hash), name, FormatSize(size))
+}
+
+// Store saves content as an artifact of a message
+func Store(ctx context.Context, repo repository.MessageRepository, threadID uuid.UUID, messageID *uuid.UUID, name string, content string) (*domain.Artifact, error) {
+	a := &domain.Artifact{
+		Hash:      Hash(content),
+		ThreadID:  threadID,
+		MessageID: messageID,
+		Name:      name,
+		Size:      len(content),
+	}
+	if err := repo.AddArtifact(ctx, a, []byte(content)); err != nil {
+		return nil, fmt.Errorf("failed to store artifact %s: %w", name, err)
+	}
+	return a, nil
+}
+
+// CodeBlock is a fenced code block found in a message
+type CodeBlock struct {
+	Language string
+	Content  string
+}
+
+var codeBlockPattern = regexp.MustCompile("(?ms)^```([\\w+#.-]*)[^\\n]*\\n(.*?)^```\\s*$")
+
+// ExtractCodeBlocks returns the fenced code blocks in content that are at least
+// MinCodeBlockLines long
+func ExtractCodeBlocks(content string) []CodeBlock {
+	var blocks []CodeBlock
+	for _, match := range codeBlockPattern.FindAllStringSubmatch(content, -1) {
+		if strings.Count(match[2], "\n") < MinCodeBlockLines {
+			continue
+		}
+		blocks = append(blocks, CodeBlock{Language: strings.ToLower(match[1]), Content: match[2]})
+	}
+	return blocks
+}
+
+var extensions = map[string]string{
+	"go":         "go",
+	"python":     "py",
+	"py":         "py",
+	"javascript": "js",
+	"js":         "js",
+	"typescript": "ts",
+	"ts":         "ts",
+	"tsx":        "tsx",
+	"jsx":        "jsx",
+	"rust":       "rs",
+	"java":       "java",
+	"c":          "c",
+	"cpp":        "cpp",
+	"c++":        "cpp",
+	"ruby":       "rb",
+	"bash":       "sh",
+	"sh":         "sh",
+	"shell":      "sh",
+	"zsh":        "sh",
+	"sql":        "sql",
+	"json":       "json",
+	"yaml":       "yaml",
+	"yml":        "yaml",
+	"toml":       "toml",
+	"html":       "html",
+	"css":        "css",
+	"markdown":   "md",
+	"md":         "md",
+}
+
+// Name returns a file name for the nth code block of a message
+func (b CodeBlock) Name(n int) string {
+	ext, ok := extensions[b.Language]
+	if !ok {
+		ext = "txt"
+	}
+	return fmt.Sprintf("code-%d.%s", n, ext)
+}
+
+// FormatSize formats a byte count for display
+func FormatSize(bytes int) string {
+	switch {
+	case bytes >= 1024*1024:
+		return fmt.Sprintf("%.1fMB", float64(bytes)/(1024*1024))
+	case bytes >= 1024:
+		return fmt.Sprintf("%dKB", bytes/1024)
+	default:
+		return fmt.Sprintf("%dB", bytes)
+	}
+}
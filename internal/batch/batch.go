@@ -0,0 +1,183 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/llm"
+	"github.com/isaacphi/slop/internal/repository"
+)
+
+// Builder prepares a queued message to be answered in a batch
+type Builder func(ctx context.Context, entry domain.QueuedMessage, msg *domain.Message) (llm.BatchRequest, error)
+
+// SubmitResult summarizes a submit
+type SubmitResult struct {
+	Jobs      []domain.BatchJob
+	Remaining int // Messages left in the queue
+}
+
+// Submit sends queued messages through the batch API of their preset's provider, one
+// batch per preset. Messages that aren't due yet are skipped unless force is set. Only
+// the oldest queued message of a thread is submitted since the ones after it need its
+// reply, and messages of presets whose provider has no batch API stay queued.
+// Submitted messages are removed from the queue
+func Submit(ctx context.Context, repo repository.MessageRepository, presets map[string]config.Preset, force bool, build Builder) (SubmitResult, error) {
+	var result SubmitResult
+
+	entries, err := repo.ListQueuedMessages(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("failed to list queued messages: %w", err)
+	}
+
+	type pending struct {
+		entries  []domain.QueuedMessage
+		requests []llm.BatchRequest
+		jobs     []domain.BatchJobRequest
+	}
+	byPreset := make(map[string]*pending)
+	var order []string
+	seenThreads := make(map[uuid.UUID]bool)
+	now := time.Now()
+
+	for _, entry := range entries {
+		preset, ok := presets[entry.Preset]
+		if seenThreads[entry.ThreadID] || (!force && entry.NextAttemptAt.After(now)) || !ok || !llm.SupportsBatch(preset.Provider) {
+			seenThreads[entry.ThreadID] = true
+			result.Remaining++
+			continue
+		}
+		seenThreads[entry.ThreadID] = true
+
+		msg, err := repo.GetMessage(ctx, entry.MessageID)
+		if err != nil {
+			// The message was deleted, nothing left to send
+			if err := repo.DeleteQueuedMessage(ctx, entry.ID); err != nil {
+				return result, fmt.Errorf("failed to remove queued message: %w", err)
+			}
+			continue
+		}
+
+		request, err := build(ctx, entry, msg)
+		if err != nil {
+			return result, fmt.Errorf("failed to prepare message %s: %w", msg.ID.String()[:8], err)
+		}
+
+		p, ok := byPreset[entry.Preset]
+		if !ok {
+			p = &pending{}
+			byPreset[entry.Preset] = p
+			order = append(order, entry.Preset)
+		}
+		p.entries = append(p.entries, entry)
+		p.requests = append(p.requests, request)
+		p.jobs = append(p.jobs, domain.BatchJobRequest{ThreadID: msg.ThreadID, MessageID: msg.ID})
+	}
+
+	for _, name := range order {
+		p := byPreset[name]
+		preset := presets[name]
+
+		externalID, err := llm.SubmitBatch(ctx, preset, p.requests)
+		if err != nil {
+			return result, fmt.Errorf("failed to submit batch for preset %s: %w", name, err)
+		}
+
+		job := domain.BatchJob{
+			Preset:     name,
+			ModelName:  preset.Name,
+			Provider:   preset.Provider,
+			ExternalID: externalID,
+			Status:     domain.BatchJobSubmitted,
+		}
+		if err := job.SetRequests(p.jobs); err != nil {
+			return result, err
+		}
+		if err := repo.CreateBatchJob(ctx, &job); err != nil {
+			return result, fmt.Errorf("failed to save batch job: %w", err)
+		}
+		result.Jobs = append(result.Jobs, job)
+
+		for _, entry := range p.entries {
+			if err := repo.DeleteQueuedMessage(ctx, entry.ID); err != nil {
+				return result, fmt.Errorf("failed to remove queued message: %w", err)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Refresh checks on a submitted job and, once the provider has finished it, adds the
+// replies to their threads. Jobs that already finished are left unchanged
+func Refresh(ctx context.Context, repo repository.MessageRepository, presets map[string]config.Preset, job *domain.BatchJob) error {
+	if job.Status != domain.BatchJobSubmitted {
+		return nil
+	}
+
+	// The preset may have been removed since the job was submitted, the provider is
+	// enough to check on it
+	preset, ok := presets[job.Preset]
+	if !ok || preset.Provider != job.Provider {
+		preset = config.Preset{Provider: job.Provider, Name: job.ModelName}
+	}
+
+	status, err := llm.PollBatch(ctx, preset, job.ExternalID)
+	if err != nil {
+		return err
+	}
+	if !status.Done {
+		return nil
+	}
+
+	completed := time.Now()
+	job.CompletedAt = &completed
+	if status.Error != "" {
+		job.Status = domain.BatchJobFailed
+		job.Error = status.Error
+		return repo.UpdateBatchJob(ctx, job)
+	}
+
+	requests, err := job.GetRequests()
+	if err != nil {
+		return err
+	}
+
+	var failures []string
+	for _, request := range requests {
+		result, ok := status.Results[request.MessageID.String()]
+		switch {
+		case !ok:
+			failures = append(failures, fmt.Sprintf("message %s: no result", request.MessageID.String()[:8]))
+			continue
+		case result.Error != "":
+			failures = append(failures, fmt.Sprintf("message %s: %s", request.MessageID.String()[:8], result.Error))
+			continue
+		}
+
+		reply := &domain.Message{
+			ThreadID:     request.ThreadID,
+			ParentID:     &request.MessageID,
+			Role:         domain.RoleAssistant,
+			Content:      result.Content,
+			ModelName:    job.ModelName,
+			Provider:     job.Provider,
+			ModelVersion: result.Model,
+		}
+		if err := repo.AddMessageToThread(ctx, request.ThreadID, reply); err != nil {
+			failures = append(failures, fmt.Sprintf("message %s: failed to save reply: %v", request.MessageID.String()[:8], err))
+		}
+	}
+
+	job.Status = domain.BatchJobDone
+	if len(failures) == len(requests) && len(requests) > 0 {
+		job.Status = domain.BatchJobFailed
+	}
+	job.Error = strings.Join(failures, "; ")
+	return repo.UpdateBatchJob(ctx, job)
+}
@@ -0,0 +1,334 @@
+// Package bundle writes and reads bundles of everything a run was started with, used
+// to attach a reproducible setup to bug reports and to share setups.
+//
+// A bundle is a zip file with these entries:
+//
+//	manifest.json          {"format": "slop-bundle", "version": 1, "createdAt": ..., "runId": ..., "threadId": ..., "preset": ..., "servers": [...]}
+//	run.json               the run with the tool results it recorded
+//	config.slop.yaml       the preset of the run with the prompts and toolsets it uses, as a config file
+//	effective-config.json  the whole configuration at export time with secrets redacted
+//	thread.slop            the thread of the run as a slop archive
+//
+// Readers reject bundles with a newer version than they know. Unknown entries are
+// ignored so later versions can add to the format.
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/agent"
+	"github.com/isaacphi/slop/internal/archive"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Format identifies slop bundles in the manifest
+	Format = "slop-bundle"
+	// Version is the version of the format written by this build
+	Version = 1
+
+	manifestFile        = "manifest.json"
+	runFile             = "run.json"
+	configFile          = "config.slop.yaml"
+	effectiveConfigFile = "effective-config.json"
+	threadFile          = "thread.slop"
+)
+
+// Manifest describes the bundle
+type Manifest struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	RunID     uuid.UUID `json:"runId"`
+	ThreadID  uuid.UUID `json:"threadId"`
+	// Preset is the name the preset of the run has in config.slop.yaml
+	Preset string `json:"preset"`
+	// Servers are the MCP servers the toolsets of the preset use
+	Servers []string `json:"servers,omitempty"`
+}
+
+// Run is a run as stored in a bundle
+type Run struct {
+	ID          uuid.UUID        `json:"id"`
+	ThreadID    uuid.UUID        `json:"threadId"`
+	MessageID   uuid.UUID        `json:"messageId"`
+	Step        domain.RunStep   `json:"step"`
+	Status      domain.RunStatus `json:"status"`
+	ToolResults json.RawMessage  `json:"toolResults,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+}
+
+// Setup is the part of the configuration a run depends on, written so it can be used
+// as a config file
+type Setup struct {
+	Presets  map[string]config.Preset  `json:"presets"`
+	Prompts  map[string]config.Prompt  `json:"prompts,omitempty"`
+	Toolsets map[string]config.Toolset `json:"toolsets,omitempty"`
+}
+
+// Bundle is the content of a bundle file
+type Bundle struct {
+	Manifest Manifest
+	Run      Run
+	// Config is config.slop.yaml as written
+	Config []byte
+	// Thread is the archive of the thread of the run
+	Thread *archive.Archive
+}
+
+// PresetName is the name the preset of a run gets in its bundle
+func PresetName(runID uuid.UUID) string {
+	return "run-" + runID.String()[:8]
+}
+
+// Write exports a run with its preset, the prompts and toolsets it uses, the effective
+// configuration and its thread to w
+func Write(ctx context.Context, repo repository.MessageRepository, cfg *config.ConfigSchema, run *domain.Run, w io.Writer) (Manifest, error) {
+	preset, err := agent.RunPreset(run)
+	if err != nil {
+		return Manifest{}, err
+	}
+	thread, err := repo.GetThread(ctx, run.ThreadID)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to get the thread of run %s: %w", run.ID.String()[:8], err)
+	}
+
+	manifest := Manifest{
+		Format:    Format,
+		Version:   Version,
+		CreatedAt: time.Now().UTC(),
+		RunID:     run.ID,
+		ThreadID:  run.ThreadID,
+		Preset:    PresetName(run.ID),
+	}
+	setup := setupFor(cfg, manifest.Preset, preset)
+	servers := make(map[string]bool)
+	for _, toolset := range setup.Toolsets {
+		for name := range toolset.Servers {
+			servers[name] = true
+		}
+	}
+	manifest.Servers = slices.Sorted(maps.Keys(servers))
+
+	zw := zip.NewWriter(w)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return Manifest{}, err
+	}
+	if err := writeFile(zw, manifestFile, manifestData, manifest.CreatedAt); err != nil {
+		return Manifest{}, err
+	}
+
+	runData, err := json.MarshalIndent(Run{
+		ID:          run.ID,
+		ThreadID:    run.ThreadID,
+		MessageID:   run.MessageID,
+		Step:        run.Step,
+		Status:      run.Status,
+		ToolResults: rawJSON(run.ToolResults),
+		Error:       run.Error,
+		CreatedAt:   run.CreatedAt,
+		UpdatedAt:   run.UpdatedAt,
+	}, "", "  ")
+	if err != nil {
+		return Manifest{}, err
+	}
+	if err := writeFile(zw, runFile, runData, manifest.CreatedAt); err != nil {
+		return Manifest{}, err
+	}
+
+	configData, err := toYAML(setup)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to encode %s: %w", configFile, err)
+	}
+	if err := writeFile(zw, configFile, configData, manifest.CreatedAt); err != nil {
+		return Manifest{}, err
+	}
+
+	effective, err := cfg.Redacted()
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to encode %s: %w", effectiveConfigFile, err)
+	}
+	effectiveData, err := json.MarshalIndent(effective, "", "  ")
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to encode %s: %w", effectiveConfigFile, err)
+	}
+	if err := writeFile(zw, effectiveConfigFile, effectiveData, manifest.CreatedAt); err != nil {
+		return Manifest{}, err
+	}
+
+	var threadData bytes.Buffer
+	if _, err := archive.Write(ctx, repo, &threadData, []*domain.Thread{thread}); err != nil {
+		return Manifest{}, err
+	}
+	if err := writeFile(zw, threadFile, threadData.Bytes(), manifest.CreatedAt); err != nil {
+		return Manifest{}, err
+	}
+
+	return manifest, zw.Close()
+}
+
+// setupFor collects the preset with the toolsets it names and the prompts that can
+// end up in its system message: the ones it includes and the ones that include
+// themselves
+func setupFor(cfg *config.ConfigSchema, name string, preset config.Preset) Setup {
+	setup := Setup{
+		Presets:  map[string]config.Preset{name: preset},
+		Prompts:  make(map[string]config.Prompt),
+		Toolsets: make(map[string]config.Toolset),
+	}
+	for _, toolset := range preset.Toolsets {
+		if ts, ok := cfg.Toolsets[toolset]; ok {
+			setup.Toolsets[toolset] = ts
+		}
+	}
+	for _, prompt := range preset.IncludePrompts {
+		if p, ok := cfg.Prompts[prompt]; ok {
+			setup.Prompts[prompt] = p
+		}
+	}
+	for prompt, p := range cfg.Prompts {
+		if p.IncludeInSystemMessage || p.SystemMessageTrigger != "" || p.SystemMessageCondition != "" {
+			setup.Prompts[prompt] = p
+		}
+	}
+	return setup
+}
+
+// toYAML writes a value with the keys of its JSON encoding, which are the keys used
+// in config files
+func toYAML(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]any
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	dropNulls(values)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(values); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// dropNulls leaves out unset lists and maps, which config files leave out too
+func dropNulls(values map[string]any) {
+	for key, value := range values {
+		switch value := value.(type) {
+		case nil:
+			delete(values, key)
+		case map[string]any:
+			dropNulls(value)
+		}
+	}
+}
+
+func rawJSON(s string) json.RawMessage {
+	if s == "" {
+		return nil
+	}
+	return json.RawMessage(s)
+}
+
+func writeFile(zw *zip.Writer, name string, data []byte, modTime time.Time) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modTime,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// Read reads a bundle
+func Read(r io.ReaderAt, size int64) (*Bundle, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not a slop bundle: %w", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		files[f.Name] = data
+	}
+
+	b := &Bundle{}
+	manifest, ok := files[manifestFile]
+	if !ok {
+		return nil, fmt.Errorf("not a slop bundle: %s is missing", manifestFile)
+	}
+	if err := json.Unmarshal(manifest, &b.Manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", manifestFile, err)
+	}
+	if b.Manifest.Format != Format {
+		return nil, fmt.Errorf("not a slop bundle: format is %q", b.Manifest.Format)
+	}
+	if b.Manifest.Version > Version {
+		return nil, fmt.Errorf("bundle version %d is newer than the supported version %d, upgrade slop to import it", b.Manifest.Version, Version)
+	}
+
+	if data, ok := files[runFile]; ok {
+		if err := json.Unmarshal(data, &b.Run); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", runFile, err)
+		}
+	}
+
+	b.Config, ok = files[configFile]
+	if !ok {
+		return nil, fmt.Errorf("invalid bundle: %s is missing", configFile)
+	}
+	var setup map[string]any
+	if err := yaml.Unmarshal(b.Config, &setup); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", configFile, err)
+	}
+
+	threadData, ok := files[threadFile]
+	if !ok {
+		return nil, fmt.Errorf("invalid bundle: %s is missing", threadFile)
+	}
+	b.Thread, err = archive.Read(bytes.NewReader(threadData))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", threadFile, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,76 @@
+// Package citation lets responses refer to earlier messages of a thread. Messages sent to
+// the model are labelled with a short form of their ID and the model cites a message by
+// repeating its label:
+//
+//	as we discussed in [msg a1b2c3d4]
+package citation
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// Instruction explains the labels to the model
+const Instruction = `Earlier messages in this conversation start with a label such as [msg a1b2c3d4]. ` +
+	`When your answer builds on an earlier message cite it by writing its label, for example ` +
+	`"as we discussed in [msg a1b2c3d4]". Only cite labels that appear in the conversation ` +
+	`and do not start your own messages with a label.`
+
+// pattern matches a citation, short IDs of at least four characters are accepted
+var pattern = regexp.MustCompile(`\[msg ([0-9a-f][0-9a-f-]{3,35})\]`)
+
+// Ref is a citation found in a message's content
+type Ref struct {
+	Start int    // Byte offset of the citation
+	End   int    // Byte offset just after the citation
+	ID    string // The cited message ID or a prefix of it
+}
+
+// Label returns the label a message is sent to the model with
+func Label(id uuid.UUID) string {
+	return "[msg " + id.String()[:8] + "]"
+}
+
+// Labelled prefixes the content of human and assistant messages with their labels. Tool
+// results and system messages are left alone since they are not cited
+func Labelled(messages []domain.Message) []domain.Message {
+	labelled := make([]domain.Message, len(messages))
+	copy(labelled, messages)
+	for i, msg := range labelled {
+		if msg.Role != domain.RoleHuman && msg.Role != domain.RoleAssistant {
+			continue
+		}
+		labelled[i].Content = Label(msg.ID) + " " + msg.Content
+	}
+	return labelled
+}
+
+// Find returns the citations in content in the order they appear
+func Find(content string) []Ref {
+	var refs []Ref
+	for _, loc := range pattern.FindAllStringSubmatchIndex(content, -1) {
+		refs = append(refs, Ref{
+			Start: loc[0],
+			End:   loc[1],
+			ID:    content[loc[2]:loc[3]],
+		})
+	}
+	return refs
+}
+
+// Resolve finds the message a citation refers to. Nothing is returned when the ID
+// matches no message or more than one
+func Resolve(id string, messages []domain.Message) (domain.Message, bool) {
+	var found domain.Message
+	matches := 0
+	for _, msg := range messages {
+		if strings.HasPrefix(msg.ID.String(), id) {
+			found = msg
+			matches++
+		}
+	}
+	return found, matches == 1
+}
@@ -0,0 +1,130 @@
+package codebase
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ListFiles returns the paths of the files under root that are worth indexing,
+// relative to root with forward slashes. Inside a git repository git decides which
+// files are ignored, elsewhere the .gitignore at the root is applied
+func ListFiles(root string, exclude []string) ([]string, error) {
+	paths, err := gitFiles(root)
+	if err != nil {
+		paths, err = walkFiles(root)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := paths[:0]
+	for _, p := range paths {
+		if !excluded(p, exclude) {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// gitFiles lists tracked and untracked files that git doesn't ignore
+func gitFiles(root string) ([]string, error) {
+	cmd := exec.Command("git", "ls-files", "--cached", "--others", "--exclude-standard", "-z")
+	cmd.Dir = root
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, p := range bytes.Split(out, []byte{0}) {
+		if len(p) > 0 {
+			paths = append(paths, string(p))
+		}
+	}
+	return paths, nil
+}
+
+// walkFiles lists files under root, skipping hidden directories and paths matching the
+// patterns in root's .gitignore
+func walkFiles(root string) ([]string, error) {
+	ignore := readGitignore(filepath.Join(root, ".gitignore"))
+
+	var paths []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") || excluded(rel, ignore) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && !excluded(rel, ignore) {
+			paths = append(paths, rel)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// readGitignore reads the patterns of a .gitignore file. Negations are not supported
+func readGitignore(name string) []string {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		patterns = append(patterns, strings.TrimSuffix(line, "/"))
+	}
+	return patterns
+}
+
+// excluded reports whether a path matches any of the patterns. Patterns without a
+// slash match any path element, like in .gitignore
+func excluded(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.Contains(strings.TrimPrefix(pattern, "/"), "/") {
+			pattern = strings.TrimPrefix(pattern, "/")
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+			if strings.HasPrefix(p, pattern+"/") {
+				return true
+			}
+			continue
+		}
+		pattern = strings.TrimPrefix(pattern, "/")
+		for _, element := range strings.Split(p, "/") {
+			if ok, _ := path.Match(pattern, element); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isText reports whether content looks like text rather than binary data
+func isText(content []byte) bool {
+	return !bytes.Contains(content[:min(len(content), 8000)], []byte{0})
+}
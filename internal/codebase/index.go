@@ -0,0 +1,306 @@
+package codebase
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
+)
+
+// Index is a searchable index of the files in a directory. It is stored in the
+// repository and updated incrementally, only files that changed are read again
+type Index struct {
+	root string
+	repo repository.MessageRepository
+	cfg  config.Codebase
+	mu   sync.Mutex
+}
+
+// UpdateResult counts what an update changed
+type UpdateResult struct {
+	Added     int
+	Updated   int
+	Removed   int
+	Unchanged int
+}
+
+// SearchResult is a chunk of a file that matches a query
+type SearchResult struct {
+	Path      string
+	Chunk     int
+	StartLine int
+	EndLine   int
+	Score     float32
+	Preview   string // First non-empty line of the chunk
+}
+
+// Status describes the state of the index
+type Status struct {
+	Root        string
+	Files       int
+	Chunks      int
+	LastIndexed time.Time
+}
+
+// New opens the index of the configured root directory, the current directory if none is set
+func New(repo repository.MessageRepository, cfg config.Codebase) (*Index, error) {
+	root := cfg.Root
+	if root == "" {
+		root = "."
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve codebase root: %w", err)
+	}
+	return &Index{root: root, repo: repo, cfg: cfg}, nil
+}
+
+// Root is the absolute path of the indexed directory
+func (i *Index) Root() string {
+	return i.root
+}
+
+// Update indexes new and changed files and forgets removed ones. With rebuild, every
+// file is indexed again
+func (i *Index) Update(ctx context.Context, rebuild bool) (UpdateResult, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var result UpdateResult
+	paths, err := ListFiles(i.root, i.cfg.Exclude)
+	if err != nil {
+		return result, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	indexed, err := i.repo.ListIndexedFiles(ctx, i.root)
+	if err != nil {
+		return result, fmt.Errorf("failed to list indexed files: %w", err)
+	}
+	existing := make(map[string]domain.IndexedFile, len(indexed))
+	for _, file := range indexed {
+		existing[file.Path] = file
+	}
+
+	seen := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		info, err := os.Stat(filepath.Join(i.root, filepath.FromSlash(p)))
+		if err != nil || !info.Mode().IsRegular() || (i.cfg.MaxFileSize > 0 && info.Size() > int64(i.cfg.MaxFileSize)) {
+			continue
+		}
+
+		file, known := existing[p]
+		if known && !rebuild && file.Size == info.Size() && file.ModTime.Equal(info.ModTime()) {
+			seen[p] = true
+			result.Unchanged++
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(i.root, filepath.FromSlash(p)))
+		if err != nil || !isText(content) {
+			continue
+		}
+		seen[p] = true
+
+		file.Root = i.root
+		file.Path = p
+		file.Size = info.Size()
+		file.ModTime = info.ModTime()
+		file.IndexedAt = time.Now()
+		chunks := i.chunk(p, string(content))
+		file.Lines = strings.Count(string(content), "\n") + 1
+		if err := i.repo.SaveIndexedFile(ctx, &file, chunks); err != nil {
+			return result, fmt.Errorf("failed to index %s: %w", p, err)
+		}
+		if known {
+			result.Updated++
+		} else {
+			result.Added++
+		}
+	}
+
+	var removed []uuid.UUID
+	for p, file := range existing {
+		if !seen[p] {
+			removed = append(removed, file.ID)
+		}
+	}
+	if err := i.repo.DeleteIndexedFiles(ctx, removed); err != nil {
+		return result, fmt.Errorf("failed to remove deleted files from the index: %w", err)
+	}
+	result.Removed = len(removed)
+
+	return result, nil
+}
+
+// chunk splits a file into ranges of lines and hashes the terms of each of them. The
+// path is hashed with every chunk so queries naming a file or directory find it
+func (i *Index) chunk(p string, content string) []domain.IndexedChunk {
+	lines := strings.Split(content, "\n")
+	size := max(i.cfg.ChunkLines, 1)
+
+	var chunks []domain.IndexedChunk
+	for start := 0; start < len(lines); start += size {
+		end := min(start+size, len(lines))
+		text := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		chunks = append(chunks, domain.IndexedChunk{
+			Position:   start / size,
+			StartLine:  start + 1,
+			EndLine:    end,
+			TermVector: encodeVector(termVector(p + "\n" + text)),
+		})
+	}
+	return chunks
+}
+
+// Search returns the chunks sharing the most terms with query, best first
+func (i *Index) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	files, err := i.repo.ListIndexedFiles(ctx, i.root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexed files: %w", err)
+	}
+	paths := make(map[uuid.UUID]string, len(files))
+	for _, file := range files {
+		paths[file.ID] = file.Path
+	}
+
+	chunks, err := i.repo.ListIndexedChunks(ctx, i.root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the index: %w", err)
+	}
+
+	queryVector := termVector(query)
+	queryTerms := terms(query)
+	results := make([]SearchResult, 0, len(chunks))
+	for _, chunk := range chunks {
+		p, ok := paths[chunk.FileID]
+		if !ok {
+			continue
+		}
+		score := similarity(queryVector, decodeVector(chunk.TermVector))
+		// Naming the file is a strong hint
+		for _, term := range queryTerms {
+			if strings.Contains(strings.ToLower(path.Base(p)), term) {
+				score += 0.1
+			}
+		}
+		results = append(results, SearchResult{
+			Path:      p,
+			Chunk:     chunk.Position,
+			StartLine: chunk.StartLine,
+			EndLine:   chunk.EndLine,
+			Score:     score,
+		})
+	}
+
+	sort.SliceStable(results, func(a, b int) bool { return results[a].Score > results[b].Score })
+	results = results[:min(limit, len(results))]
+	for n := range results {
+		results[n].Preview = i.preview(results[n])
+	}
+	return results, nil
+}
+
+// preview returns the first non-empty line of a result
+func (i *Index) preview(result SearchResult) string {
+	content, err := os.ReadFile(filepath.Join(i.root, filepath.FromSlash(result.Path)))
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(string(content), "\n")
+	for n := result.StartLine - 1; n < min(result.EndLine, len(lines)); n++ {
+		if line := strings.TrimSpace(lines[n]); line != "" {
+			if len(line) > 100 {
+				line = line[:97] + "..."
+			}
+			return line
+		}
+	}
+	return ""
+}
+
+// Outline lists the declarations in an indexed file
+func (i *Index) Outline(ctx context.Context, p string) ([]OutlineEntry, error) {
+	content, err := i.read(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return outline(p, content), nil
+}
+
+// ReadChunk returns the lines of a chunk of an indexed file, numbered, along with the
+// line range it covers
+func (i *Index) ReadChunk(ctx context.Context, p string, chunk int) (string, int, int, error) {
+	content, err := i.read(ctx, p)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	lines := strings.Split(content, "\n")
+	size := max(i.cfg.ChunkLines, 1)
+	start := chunk * size
+	if chunk < 0 || start >= len(lines) {
+		return "", 0, 0, fmt.Errorf("%s has no chunk %d, it has %d", p, chunk, (len(lines)+size-1)/size)
+	}
+	end := min(start+size, len(lines))
+
+	var b strings.Builder
+	for n := start; n < end; n++ {
+		fmt.Fprintf(&b, "%d\t%s\n", n+1, lines[n])
+	}
+	return b.String(), start + 1, end, nil
+}
+
+// read returns the content of a file if it is in the index. Files outside the index,
+// such as ignored ones, can't be read
+func (i *Index) read(ctx context.Context, p string) (string, error) {
+	p = path.Clean(strings.TrimPrefix(filepath.ToSlash(p), "./"))
+	files, err := i.repo.ListIndexedFiles(ctx, i.root)
+	if err != nil {
+		return "", fmt.Errorf("failed to list indexed files: %w", err)
+	}
+	for _, file := range files {
+		if file.Path != p {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(i.root, filepath.FromSlash(p)))
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		return string(content), nil
+	}
+	return "", fmt.Errorf("%s is not in the codebase index", p)
+}
+
+// Status reports how much of the directory is indexed
+func (i *Index) Status(ctx context.Context) (Status, error) {
+	status := Status{Root: i.root}
+	files, err := i.repo.ListIndexedFiles(ctx, i.root)
+	if err != nil {
+		return status, fmt.Errorf("failed to list indexed files: %w", err)
+	}
+	chunks, err := i.repo.ListIndexedChunks(ctx, i.root)
+	if err != nil {
+		return status, fmt.Errorf("failed to read the index: %w", err)
+	}
+
+	status.Files = len(files)
+	status.Chunks = len(chunks)
+	for _, file := range files {
+		if file.IndexedAt.After(status.LastIndexed) {
+			status.LastIndexed = file.IndexedAt
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,57 @@
+package codebase
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// definitionPattern matches lines that declare something worth listing in an outline
+// across common languages: functions, types and classes
+var definitionPattern = regexp.MustCompile(`^\s*(` +
+	`func\s|type\s|` + // Go
+	`(async\s+)?def\s|class\s|` + // Python
+	`(export\s+)?(default\s+)?(async\s+)?(function|class|interface|type|enum|const\s+\w+\s*=\s*(async\s*)?\()|` + // JavaScript and TypeScript
+	`(pub(\([^)]*\))?\s+)?(fn|struct|enum|trait|impl|mod)\s|` + // Rust
+	`((public|private|protected|static|final|abstract)\s+)+[\w<>\[\], ]+\s+\w+\s*\(` + // Java and C#
+	`)`)
+
+// headingPattern matches markdown headings
+var headingPattern = regexp.MustCompile(`^#{1,6}\s`)
+
+// OutlineEntry is a declaration in a file
+type OutlineEntry struct {
+	Line int
+	Text string
+}
+
+// outline lists the declarations in a file with their line numbers. Markdown files
+// are outlined by their headings
+func outline(name string, content string) []OutlineEntry {
+	pattern := definitionPattern
+	markdown := false
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown":
+		pattern = headingPattern
+		markdown = true
+	}
+
+	var entries []OutlineEntry
+	inFence := false
+	for i, line := range strings.Split(content, "\n") {
+		// Comments in fenced code would otherwise look like headings
+		if markdown && strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || !pattern.MatchString(line) {
+			continue
+		}
+		text := strings.TrimRight(line, " \t{")
+		if len(text) > 120 {
+			text = text[:117] + "..."
+		}
+		entries = append(entries, OutlineEntry{Line: i + 1, Text: text})
+	}
+	return entries
+}
@@ -0,0 +1,158 @@
+package codebase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
+)
+
+// ServerName is the name toolsets use for the built-in codebase server
+const ServerName = "codebase"
+
+// refreshInterval is how long search results may lag behind changes to the files
+const refreshInterval = 30 * time.Second
+
+const defaultSearchLimit = 8
+
+// Server offers the index to the model as tools. The index is opened on the first
+// call and brought up to date at most every refreshInterval
+type Server struct {
+	open        func() (repository.MessageRepository, error)
+	cfg         config.Codebase
+	mu          sync.Mutex
+	index       *Index
+	lastRefresh time.Time
+}
+
+// NewServer creates the built-in server. open is called once, when a tool is first used
+func NewServer(cfg config.Codebase, open func() (repository.MessageRepository, error)) *Server {
+	return &Server{open: open, cfg: cfg}
+}
+
+// Tools describes the tools of the server
+func (s *Server) Tools() map[string]domain.Tool {
+	return map[string]domain.Tool{
+		"search_files": {
+			Name:        "search_files",
+			Description: "Search the project's files for code or text sharing words with a query, matching on words and identifier parts rather than meaning. Returns the best matching chunks with their path and chunk number and line range. Use read_chunk to read a result",
+			Parameters: domain.Parameters{
+				Type: "object",
+				Properties: map[string]domain.Property{
+					"query": {Type: "string", Description: "What to look for, such as a feature or identifier or error message"},
+					"limit": {Type: "number", Description: fmt.Sprintf("Maximum number of chunks to return, %d by default", defaultSearchLimit)},
+				},
+				Required: []string{"query"},
+			},
+		},
+		"get_file_outline": {
+			Name:        "get_file_outline",
+			Description: "List the functions and types and classes declared in a file of the project, or the headings of a markdown file, with their line numbers",
+			Parameters: domain.Parameters{
+				Type: "object",
+				Properties: map[string]domain.Property{
+					"path": {Type: "string", Description: "Path of the file relative to the project root"},
+				},
+				Required: []string{"path"},
+			},
+		},
+		"read_chunk": {
+			Name:        "read_chunk",
+			Description: "Read a chunk of a file of the project with line numbers. Chunk numbers start at 0 and come from search_files",
+			Parameters: domain.Parameters{
+				Type: "object",
+				Properties: map[string]domain.Property{
+					"path":  {Type: "string", Description: "Path of the file relative to the project root"},
+					"chunk": {Type: "number", Description: "Number of the chunk to read"},
+				},
+				Required: []string{"path", "chunk"},
+			},
+		},
+	}
+}
+
+// CallTool runs one of the server's tools
+func (s *Server) CallTool(ctx context.Context, toolName string, arguments map[string]any) (string, error) {
+	index, err := s.refresh(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	switch toolName {
+	case "search_files":
+		query, _ := arguments["query"].(string)
+		limit := defaultSearchLimit
+		if n, ok := arguments["limit"].(float64); ok && n > 0 {
+			limit = int(n)
+		}
+		results, err := index.Search(ctx, query, limit)
+		if err != nil {
+			return "", err
+		}
+		if len(results) == 0 {
+			return "No matching files", nil
+		}
+		var b strings.Builder
+		for _, r := range results {
+			fmt.Fprintf(&b, "%s chunk %d (lines %d-%d, score %.2f): %s\n", r.Path, r.Chunk, r.StartLine, r.EndLine, r.Score, r.Preview)
+		}
+		return b.String(), nil
+
+	case "get_file_outline":
+		p, _ := arguments["path"].(string)
+		entries, err := index.Outline(ctx, p)
+		if err != nil {
+			return "", err
+		}
+		if len(entries) == 0 {
+			return "No declarations found in " + p, nil
+		}
+		var b strings.Builder
+		for _, entry := range entries {
+			fmt.Fprintf(&b, "%d\t%s\n", entry.Line, entry.Text)
+		}
+		return b.String(), nil
+
+	case "read_chunk":
+		p, _ := arguments["path"].(string)
+		chunk, _ := arguments["chunk"].(float64)
+		text, start, end, err := index.ReadChunk(ctx, p, int(chunk))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s lines %d-%d:\n%s", p, start, end, text), nil
+
+	default:
+		return "", fmt.Errorf("tool %s not found in server %s", toolName, ServerName)
+	}
+}
+
+// refresh opens the index if needed and updates it when the last update is too old
+func (s *Server) refresh(ctx context.Context) (*Index, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.index == nil {
+		repo, err := s.open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open codebase index: %w", err)
+		}
+		s.index, err = New(repo, s.cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if time.Since(s.lastRefresh) > refreshInterval {
+		if _, err := s.index.Update(ctx, false); err != nil {
+			return nil, err
+		}
+		s.lastRefresh = time.Now()
+	}
+	return s.index, nil
+}
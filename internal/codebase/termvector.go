@@ -0,0 +1,111 @@
+package codebase
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// dimensions is the length of the term vectors
+const dimensions = 256
+
+// termVector hashes the words of text into a vector, a bag of words that needs no
+// provider. Words and the parts of camelCase and snake_case identifiers each add to
+// one position, so chunks score by the words they share with a query. It is lexical
+// only: synonyms and paraphrases don't match. Vectors are normalized to unit length
+func termVector(text string) []float32 {
+	vector := make([]float32, dimensions)
+	for _, term := range terms(text) {
+		h := fnv.New32a()
+		h.Write([]byte(term))
+		sum := h.Sum32()
+		// The sign spreads unrelated terms out instead of only adding up
+		sign := float32(1)
+		if sum&1 == 1 {
+			sign = -1
+		}
+		vector[(sum>>1)%dimensions] += sign
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v * v)
+	}
+	if norm == 0 {
+		return vector
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range vector {
+		vector[i] *= scale
+	}
+	return vector
+}
+
+// terms splits text into lower case words, breaking identifiers into their parts.
+// Whole identifiers are kept too so exact names match best
+func terms(text string) []string {
+	var result []string
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	for _, word := range words {
+		parts := splitIdentifier(word)
+		if len(parts) > 1 {
+			result = append(result, strings.ToLower(word))
+		}
+		for _, part := range parts {
+			if len(part) > 1 {
+				result = append(result, strings.ToLower(part))
+			}
+		}
+	}
+	return result
+}
+
+// splitIdentifier splits camelCase and snake_case identifiers into words
+func splitIdentifier(word string) []string {
+	var parts []string
+	for _, piece := range strings.Split(word, "_") {
+		runes := []rune(piece)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			lowerToUpper := unicode.IsLower(runes[i-1]) && unicode.IsUpper(runes[i])
+			acronymEnd := i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i+1])
+			if lowerToUpper || acronymEnd {
+				parts = append(parts, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			parts = append(parts, string(runes[start:]))
+		}
+	}
+	return parts
+}
+
+// similarity is the cosine similarity of two unit vectors
+func similarity(a, b []float32) float32 {
+	var dot float32
+	for i := range min(len(a), len(b)) {
+		dot += a[i] * b[i]
+	}
+	return dot
+}
+
+func encodeVector(vector []float32) []byte {
+	encoded := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(encoded[4*i:], math.Float32bits(v))
+	}
+	return encoded
+}
+
+func decodeVector(encoded []byte) []float32 {
+	vector := make([]float32, len(encoded)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(encoded[4*i:]))
+	}
+	return vector
+}
@@ -0,0 +1,165 @@
+// Package compress shrinks older conversation history before it is sent to an
+// expensive model, either with a local heuristic or by having a cheaper model
+// rewrite it
+package compress
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/llm"
+)
+
+// Compression methods a preset can choose
+const (
+	MethodNone      = "none"
+	MethodHeuristic = "heuristic"
+	MethodModel     = "model"
+)
+
+// Compressor shrinks text while keeping the information a model needs from it
+type Compressor interface {
+	Compress(ctx context.Context, text string) (string, error)
+}
+
+// fillerWords carry little meaning and are dropped from prose by the heuristic
+var fillerWords = map[string]bool{
+	"a": true, "an": true, "the": true,
+	"actually": true, "basically": true, "certainly": true, "definitely": true,
+	"essentially": true, "just": true, "literally": true, "quite": true,
+	"really": true, "simply": true, "very": true,
+}
+
+// Heuristic compresses text locally. Pretty printed JSON is compacted, and in prose
+// whitespace is collapsed, filler words are dropped and runs of repeated lines are
+// folded into one. Fenced code blocks are left untouched
+type Heuristic struct{}
+
+func (Heuristic) Compress(_ context.Context, text string) (string, error) {
+	trimmed := strings.TrimSpace(text)
+	if json.Valid([]byte(trimmed)) {
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, []byte(trimmed)); err == nil {
+			return compacted.String(), nil
+		}
+	}
+
+	var out []string
+	inCode := false
+	blank := false
+	repeats := 0
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			out = append(out, line)
+			blank, repeats = false, 0
+			continue
+		}
+		if inCode {
+			out = append(out, line)
+			continue
+		}
+
+		line = compressLine(line)
+		if line == "" {
+			if !blank && len(out) > 0 {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+
+		// Fold repeated lines such as log output into the first occurrence
+		if len(out) > 0 && strings.TrimSuffix(out[len(out)-1], fmt.Sprintf(" [x%d]", repeats+1)) == line {
+			repeats++
+			out[len(out)-1] = fmt.Sprintf("%s [x%d]", line, repeats+1)
+			continue
+		}
+		repeats = 0
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n")), nil
+}
+
+// compressLine collapses whitespace and drops filler words, keeping list markers
+// and the indentation of the line
+func compressLine(line string) string {
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	words := strings.Fields(line)
+	kept := words[:0]
+	for _, word := range words {
+		if fillerWords[strings.ToLower(word)] {
+			continue
+		}
+		kept = append(kept, word)
+	}
+	if len(kept) == 0 {
+		// Keep lines made up only of filler rather than losing them entirely
+		kept = words
+	}
+	if len(kept) == 0 {
+		return ""
+	}
+	return indent + strings.Join(kept, " ")
+}
+
+// modelPrompt asks the compression model to shorten text
+const modelPrompt = `Compress the text below so it uses as few tokens as possible. ` +
+	`Keep every fact, name, number, identifier, file path and code snippet. ` +
+	`Drop filler, repetition and pleasantries. Telegraphic style is fine. ` +
+	`Reply with only the compressed text.
+
+`
+
+// Model compresses text by asking a cheaper model to rewrite it. Results are cached
+// so history is only compressed once per session
+type Model struct {
+	preset config.Preset
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]string
+}
+
+// NewModel returns a Compressor that uses the given preset
+func NewModel(preset config.Preset) *Model {
+	return &Model{
+		preset: preset,
+		cache:  make(map[[sha256.Size]byte]string),
+	}
+}
+
+func (m *Model) Compress(ctx context.Context, text string) (string, error) {
+	key := sha256.Sum256([]byte(text))
+	m.mu.Lock()
+	cached, ok := m.cache[key]
+	m.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	preset := m.preset
+	preset.ToolChoice = ""
+	resp, err := llm.GenerateContent(ctx, llm.GenerateContentOptions{
+		Preset:  preset,
+		Content: modelPrompt + text,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to compress with %s: %w", m.preset.Name, err)
+	}
+
+	compressed := strings.TrimSpace(resp.TextResponse)
+	if compressed == "" {
+		compressed = text
+	}
+	m.mu.Lock()
+	m.cache[key] = compressed
+	m.mu.Unlock()
+	return compressed, nil
+}
@@ -4,14 +4,22 @@ import (
 	"bytes"
 	"fmt"
 	"maps"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/isaacphi/slop/internal/llm/provider"
+	"github.com/isaacphi/slop/internal/msgtemplate"
+	"github.com/isaacphi/slop/internal/outtemplate"
+	"github.com/isaacphi/slop/internal/tokens"
+	"github.com/isaacphi/slop/internal/trigger"
+	"github.com/isaacphi/slop/internal/webhook"
 	"github.com/spf13/viper"
 )
 
@@ -21,13 +29,17 @@ This configuration system implements a hierarchical config with the following pr
 (highest to lowest priority):
 
 1. Command line overrides
-2. Local project config (.slop/*.slop.{yaml,json})
-3. Global user config ($XDG_CONFIG_HOME/slop/*.slop.{yaml,json})
+2. Local project config (.slop/*.slop.{yaml,json} and .slop/prompts/*.md in the current
+   directory or the closest parent directory that has one)
+3. Global user config ($XDG_CONFIG_HOME/slop/*.slop.{yaml,json} and prompts/*.md)
 4. Default values (from defaults.slop.yaml)
 
 The system supports:
 - Multiple config files in each directory, merged alphabetically
-- Automatic merging of lists (they combine)
+- Automatic merging of lists (they combine, except lists from the defaults which are replaced).
+  A list combined with one from another file is reported in the config warnings
+  unless its file names it in mergeStrategy
+- A mergeStrategy map in a file to append to or replace lists set by earlier files
 - Deep merging of maps
 - Override of scalar values
 - Schema validation of the final config
@@ -37,6 +49,11 @@ If you have these files:
 ~/.config/slop/models.slop.yaml:  { models: ["gpt-4"] }
 ./.slop/models.slop.yaml:         { models: ["claude"] }
 The result will be: { models: ["gpt-4", "claude"] }
+
+To only use claude, the local file sets:
+mergeStrategy: { models: replace }
+and to keep both without a warning:
+mergeStrategy: { models: append }
 */
 
 // Config holds the configuration state
@@ -118,20 +135,13 @@ func (c *Config) loadConfigs() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Find global config directory
-	xdgConfig := os.Getenv("XDG_CONFIG_HOME")
-	if xdgConfig == "" {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return err
-		}
-		xdgConfig = filepath.Join(home, ".config")
+	dirs, err := configDirs()
+	if err != nil {
+		return err
 	}
-	globalDir := filepath.Join(xdgConfig, "slop")
-	localDir := ".slop"
 
 	// Load files from both locations
-	for _, dir := range []string{globalDir, localDir} {
+	for _, dir := range dirs {
 		files, err := findConfigFiles(dir)
 		if err != nil && !os.IsNotExist(err) {
 			return err
@@ -149,10 +159,69 @@ func (c *Config) loadConfigs() error {
 				return fmt.Errorf("error merging config from %s: %w", f, err)
 			}
 		}
+
+		// Markdown prompt files take precedence over prompts defined in config files
+		if err := c.loadPromptFiles(filepath.Join(dir, "prompts")); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// configDirs returns the global and local config directories in order of
+// increasing precedence
+func configDirs() ([]string, error) {
+	globalDir, err := GlobalDir()
+	if err != nil {
+		return nil, err
+	}
+	dirs := []string{globalDir}
+
+	project, err := FindProjectDir()
+	if err != nil {
+		return nil, err
+	}
+	if project != "" {
+		dirs = append(dirs, filepath.Join(project, ".slop"))
+	}
+	return dirs, nil
+}
+
+// GlobalDir returns the directory of the user's config files that apply everywhere
+func GlobalDir() (string, error) {
+	xdgConfig := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfig == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		xdgConfig = filepath.Join(home, ".config")
+	}
+	return filepath.Join(xdgConfig, "slop"), nil
+}
+
+// DefaultDBPath is where the database is kept when dbPath isn't set. Projects with a
+// .slop directory keep their own database in it, otherwise the database is shared
+// from $XDG_DATA_HOME/slop
+func DefaultDBPath() (string, error) {
+	project, err := FindProjectDir()
+	if err != nil {
+		return "", err
+	}
+	if project != "" {
+		return filepath.Join(project, ".slop", "slop.db"), nil
+	}
+	xdgData := os.Getenv("XDG_DATA_HOME")
+	if xdgData == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		xdgData = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(xdgData, "slop", "slop.db"), nil
+}
+
 // findConfigFiles returns all *.slop.{yaml,json} files in a directory
 func findConfigFiles(dir string) ([]string, error) {
 	var files []string
@@ -182,10 +251,23 @@ func (c *Config) validateConfig() (*ConfigSchema, error) {
 	}
 
 	// Set defaults from tags
-	if err := setStructuralDefaults(&schema); err != nil {
+	if err := setStructuralDefaults(&schema, c.v.IsSet); err != nil {
 		return nil, fmt.Errorf("error setting defaults: %w", err)
 	}
 
+	if schema.DBPath == "" {
+		dbPath, err := DefaultDBPath()
+		if err != nil {
+			return nil, fmt.Errorf("could not find the default database path: %w", err)
+		}
+		schema.DBPath = dbPath
+	}
+
+	// The project's preset and toolsets take the place of the configured defaults
+	if err := schema.applyProject(c.sources); err != nil {
+		return nil, err
+	}
+
 	validate := validator.New()
 	if err := validate.Struct(schema); err != nil {
 		return nil, fmt.Errorf("config validation error: %w", err)
@@ -210,6 +292,186 @@ func (c *Config) validateConfig() (*ConfigSchema, error) {
 				schema.DefaultPreset, availableModels)
 		}
 	}
+	switch schema.TimeFormat {
+	case "relative", "absolute":
+	default:
+		return nil, fmt.Errorf("invalid timeFormat %q: expected relative or absolute", schema.TimeFormat)
+	}
+	switch schema.Issues.Provider {
+	case "github", "gitlab":
+	default:
+		return nil, fmt.Errorf("invalid issues.provider %q: expected github or gitlab", schema.Issues.Provider)
+	}
+	for name, prompt := range schema.Prompts {
+		if prompt.SystemMessageCondition == "" {
+			continue
+		}
+		if _, err := trigger.Parse(prompt.SystemMessageCondition); err != nil {
+			return nil, fmt.Errorf("invalid systemMessageCondition for prompt %q: %w", name, err)
+		}
+	}
+	for name, text := range schema.Templates {
+		if _, err := msgtemplate.Parse(name, text); err != nil {
+			return nil, err
+		}
+	}
+	for name, text := range schema.OutTemplates {
+		if _, err := outtemplate.Parse(name, text); err != nil {
+			return nil, err
+		}
+	}
+	for name, server := range schema.MCPServers {
+		if server.Host != "" {
+			host, err := url.Parse(server.Host)
+			if err != nil || host.Scheme != "ssh" || host.Hostname() == "" {
+				return nil, fmt.Errorf("invalid host for MCP server %q: expected ssh://[user@]host[:port], got %q", name, server.Host)
+			}
+		}
+		if server.IdleTimeout != "" {
+			if timeout, err := time.ParseDuration(server.IdleTimeout); err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("invalid idleTimeout for MCP server %q: expected a duration such as 10m, got %q", name, server.IdleTimeout)
+			}
+		}
+		if server.PingInterval != "" {
+			if interval, err := time.ParseDuration(server.PingInterval); err != nil || interval <= 0 {
+				return nil, fmt.Errorf("invalid pingInterval for MCP server %q: expected a duration such as 30s, got %q", name, server.PingInterval)
+			}
+		}
+	}
+	for name, preset := range schema.Presets {
+		if err := tokens.Validate(preset.Tokenizer); err != nil {
+			return nil, fmt.Errorf("invalid tokenizer for preset %q: %w", name, err)
+		}
+		if preset.Summarize.After < 0 || preset.Summarize.KeepTurns < 1 {
+			return nil, fmt.Errorf("invalid summarize settings for preset %q: after must be 0 or more and keepTurns at least 1", name)
+		}
+		if preset.Retry.MaxAttempts < 1 {
+			return nil, fmt.Errorf("invalid retry.maxAttempts for preset %q: expected at least 1, got %d", name, preset.Retry.MaxAttempts)
+		}
+		for key, value := range map[string]string{"backoff": preset.Retry.Backoff, "maxBackoff": preset.Retry.MaxBackoff} {
+			if d, err := time.ParseDuration(value); err != nil || d < 0 {
+				return nil, fmt.Errorf("invalid retry.%s for preset %q: expected a duration such as 1s, got %q", key, name, value)
+			}
+		}
+		// Presets of unknown providers only fail when they are used
+		p, err := provider.Get(preset.Provider)
+		if err != nil {
+			c.warnings = append(c.warnings, fmt.Sprintf("preset %q can't be used: %v", name, err))
+		}
+		if p != nil && preset.Vision && !p.Capabilities().Vision {
+			c.warnings = append(c.warnings, fmt.Sprintf("preset %q has vision but the %s provider may not accept images", name, preset.Provider))
+		}
+		if preset.BaseURL != "" {
+			base, err := url.Parse(preset.BaseURL)
+			if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+				return nil, fmt.Errorf("invalid baseURL for preset %q: expected http(s)://host[:port], got %q", name, preset.BaseURL)
+			}
+			if p != nil && !p.Capabilities().BaseURL {
+				return nil, fmt.Errorf("baseURL for preset %q is not supported by the %s provider", name, preset.Provider)
+			}
+		}
+		for _, fallback := range preset.Fallbacks {
+			if fallback == name {
+				return nil, fmt.Errorf("preset %q lists itself as a fallback", name)
+			}
+			if _, ok := schema.Presets[fallback]; !ok {
+				return nil, fmt.Errorf("fallback %q for preset %q must be one of the configured presets", fallback, name)
+			}
+		}
+		switch preset.Compression.Method {
+		case "", "none", "heuristic", "model":
+		default:
+			return nil, fmt.Errorf("invalid compression method %q for preset %q: expected none, heuristic or model", preset.Compression.Method, name)
+		}
+		model := preset.Compression.Model
+		if preset.Compression.Method != "model" || model == "" {
+			continue
+		}
+		if _, ok := schema.Presets[model]; !ok {
+			return nil, fmt.Errorf("compression model %q for preset %q must be one of the configured presets", model, name)
+		}
+	}
+	for name, mode := range schema.Modes {
+		if _, ok := schema.Presets[mode.Preset]; mode.Preset != "" && !ok {
+			return nil, fmt.Errorf("preset %q for mode %q must be one of the configured presets", mode.Preset, name)
+		}
+		switch mode.Compression {
+		case "", "none", "heuristic", "model":
+		default:
+			return nil, fmt.Errorf("invalid compression method %q for mode %q: expected none, heuristic or model", mode.Compression, name)
+		}
+		for _, toolset := range mode.Toolsets {
+			if _, ok := schema.Toolsets[toolset]; !ok {
+				return nil, fmt.Errorf("toolset %q for mode %q must be one of the configured toolsets", toolset, name)
+			}
+		}
+	}
+	if schema.Codebase.Enabled {
+		if _, ok := schema.MCPServers["codebase"]; ok {
+			return nil, fmt.Errorf("MCP server name %q is used by the built-in codebase server, rename the server or disable codebase", "codebase")
+		}
+		if schema.Codebase.ChunkLines <= 0 {
+			return nil, fmt.Errorf("codebase chunkLines must be positive, got %d", schema.Codebase.ChunkLines)
+		}
+	}
+	if schema.Sandbox.Enabled {
+		if _, ok := schema.MCPServers["sandbox"]; ok {
+			return nil, fmt.Errorf("MCP server name %q is used by the built-in sandbox server, rename the server or disable sandbox", "sandbox")
+		}
+		switch schema.Sandbox.Runtime {
+		case "docker", "podman":
+		default:
+			return nil, fmt.Errorf("invalid sandbox runtime %q: expected docker or podman", schema.Sandbox.Runtime)
+		}
+		if timeout, err := time.ParseDuration(schema.Sandbox.Timeout); err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid sandbox timeout %q", schema.Sandbox.Timeout)
+		}
+		if len(schema.Sandbox.Languages) == 0 {
+			return nil, fmt.Errorf("sandbox needs at least one language")
+		}
+		for name, language := range schema.Sandbox.Languages {
+			if language.Image == "" || language.File == "" || len(language.Command) == 0 {
+				return nil, fmt.Errorf("sandbox language %q needs an image, a file and a command", name)
+			}
+			if strings.ContainsAny(language.File, `/\`) {
+				return nil, fmt.Errorf("file of sandbox language %q must be a name without directories, got %q", name, language.File)
+			}
+		}
+		// Running code the model wrote is never left to the model alone
+		for name, toolset := range schema.Toolsets {
+			server, ok := toolset.Servers["sandbox"]
+			if !ok {
+				continue
+			}
+			if !server.RequireApproval {
+				return nil, fmt.Errorf("toolset %q must set requireApproval on the sandbox server", name)
+			}
+			for tool, toolConfig := range server.AllowedTools {
+				if !toolConfig.RequireApproval {
+					return nil, fmt.Errorf("toolset %q must set requireApproval on sandbox tool %s", name, tool)
+				}
+			}
+		}
+	}
+	webhookPaths := make(map[string]string)
+	for name, hook := range schema.Serve.Webhooks {
+		if !strings.HasPrefix(hook.Path, "/") {
+			return nil, fmt.Errorf("path of webhook %q must start with /, got %q", name, hook.Path)
+		}
+		if other, ok := webhookPaths[hook.Path]; ok {
+			return nil, fmt.Errorf("webhooks %q and %q have the same path %q", other, name, hook.Path)
+		}
+		webhookPaths[hook.Path] = name
+		if hook.Secret == "" {
+			return nil, fmt.Errorf("webhook %q needs a secret", name)
+		}
+		if _, ok := schema.Presets[hook.Preset]; hook.Preset != "" && !ok {
+			return nil, fmt.Errorf("preset %q for webhook %q must be one of the configured presets", hook.Preset, name)
+		}
+		if _, err := webhook.ParsePrompt(name, hook.Prompt); err != nil {
+			return nil, err
+		}
+	}
 	// TODO: validate toolsets
 
 	return &schema, nil
@@ -217,11 +479,24 @@ func (c *Config) validateConfig() (*ConfigSchema, error) {
 
 // Merge settings into the main Config.v viper instance
 func (c *Config) mergeConfig(settings map[string]any, source string) error {
+	settings, strategies := extractMergeStrategies(settings)
+
 	// Combine flattening and source tracking in one pass
+	previousSources := maps.Clone(c.sources)
 	flat := c.flattenAndTrack(settings, "", source)
+	if source != "default" {
+		c.checkSettings(flat, source, previousSources)
+		c.checkStrategies(strategies, flat, source)
+		if err := c.resolveFileRefs(flat, source); err != nil {
+			return err
+		}
+	}
 
 	// Set each value in Viper
 	for key, value := range flat {
+		if list, ok := value.([]any); ok {
+			value = c.mergeList(key, list, strategies[key], source, previousSources[key])
+		}
 		c.v.Set(key, value)
 	}
 	return nil
@@ -259,11 +534,13 @@ func (c *Config) flattenAndTrack(m map[string]any, prefix string, source string)
 	return result
 }
 
-func setStructuralDefaults(schema *ConfigSchema) error {
-	return setDefaultsFromTags(reflect.ValueOf(schema).Elem())
+// setStructuralDefaults fills in the defaults of the jsonschema tags. isSet reports
+// whether the config files set a key such as toolsets.default.servers.github.requireApproval
+func setStructuralDefaults(schema *ConfigSchema, isSet func(key string) bool) error {
+	return setDefaultsFromTags(reflect.ValueOf(schema).Elem(), "", isSet)
 }
 
-func setDefaultsFromTags(v reflect.Value) error {
+func setDefaultsFromTags(v reflect.Value, prefix string, isSet func(key string) bool) error {
 	if v.Kind() != reflect.Struct {
 		return nil
 	}
@@ -277,10 +554,11 @@ func setDefaultsFromTags(v reflect.Value) error {
 
 		tag := t.Field(i).Tag.Get("jsonschema")
 		defaultVal := extractDefaultFromTag(tag)
+		key := configKey(prefix, t.Field(i))
 
 		switch field.Kind() {
 		case reflect.Struct:
-			if err := setDefaultsFromTags(field); err != nil {
+			if err := setDefaultsFromTags(field, key, isSet); err != nil {
 				return fmt.Errorf("field %s: %w", t.Field(i).Name, err)
 			}
 
@@ -293,7 +571,7 @@ func setDefaultsFromTags(v reflect.Value) error {
 				// Create a new map to store updated values
 				iter := field.MapRange()
 				for iter.Next() {
-					key := iter.Key()
+					mapKey := iter.Key()
 					val := iter.Value()
 
 					// Create a new value so we can modify it
@@ -301,13 +579,13 @@ func setDefaultsFromTags(v reflect.Value) error {
 					newVal.Set(val)
 
 					// Recursively set defaults on the new value
-					err := setDefaultsFromTags(newVal)
+					err := setDefaultsFromTags(newVal, fmt.Sprintf("%s.%v", key, mapKey.Interface()), isSet)
 					if err != nil {
 						return err
 					}
 
 					// Store the modified value back in the map
-					field.SetMapIndex(key, newVal)
+					field.SetMapIndex(mapKey, newVal)
 				}
 			}
 
@@ -319,10 +597,12 @@ func setDefaultsFromTags(v reflect.Value) error {
 		case reflect.Bool:
 			if defaultVal != "" {
 				switch defaultVal {
-				case "true":
-					field.SetBool(true)
-				case "false":
-					field.SetBool(false)
+				case "true", "false":
+					// false can't be told apart from a missing key, so only keys the config
+					// files leave out get the default
+					if !isSet(key) {
+						field.SetBool(defaultVal == "true")
+					}
 				default:
 					return fmt.Errorf("field %s: invalid boolean default value: %s (must be 'true' or 'false')",
 						t.Field(i).Name, defaultVal)
@@ -353,6 +633,18 @@ func setDefaultsFromTags(v reflect.Value) error {
 	return nil
 }
 
+// configKey returns the dotted key of a struct field in the config files
+func configKey(prefix string, field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+	if name == "" {
+		name = field.Name
+	}
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
 func extractDefaultFromTag(tag string) string {
 	for part := range strings.SplitSeq(tag, ",") {
 		if strings.HasPrefix(part, "default=") {
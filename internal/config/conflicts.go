@@ -0,0 +1,181 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Conflict is a key that is set to different values in more than one config file
+type Conflict struct {
+	Key string
+	// Values are in order of increasing precedence, so the last one is in effect
+	Values []SourceValue
+}
+
+// SourceValue is the value a single config file sets for a key
+type SourceValue struct {
+	File  string
+	Value any
+}
+
+// FindConflicts reads every global and local config file separately and
+// returns the keys that are set to different values by more than one of them.
+// Lists are combined rather than overridden so they don't conflict.
+// Keys are lowercased the same way viper reports them
+func FindConflicts() ([]Conflict, error) {
+	dirs, err := configDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]SourceValue)
+	for _, dir := range dirs {
+		files, err := findConfigFiles(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		for _, f := range files {
+			v := viper.New()
+			v.SetConfigFile(f)
+			if err := v.ReadInConfig(); err != nil {
+				return nil, fmt.Errorf("error reading config file %s: %w", f, err)
+			}
+
+			settings, _ := extractMergeStrategies(v.AllSettings())
+			tracker := &Config{sources: make(map[string]string)}
+			for key, value := range tracker.flattenAndTrack(settings, "", f) {
+				if _, isList := value.([]any); isList {
+					continue
+				}
+				values[key] = append(values[key], SourceValue{File: f, Value: value})
+			}
+		}
+	}
+
+	var conflicts []Conflict
+	for key, vals := range values {
+		if len(vals) < 2 {
+			continue
+		}
+		for _, val := range vals[1:] {
+			if !reflect.DeepEqual(val.Value, vals[0].Value) {
+				conflicts = append(conflicts, Conflict{Key: key, Values: vals})
+				break
+			}
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Key < conflicts[j].Key
+	})
+	return conflicts, nil
+}
+
+// RemoveKey deletes a dot separated key from a yaml or json config file.
+// Keys are matched case-insensitively and maps left empty are removed too.
+// Comments in yaml files are kept, json files are rewritten with sorted keys
+func RemoveKey(file, key string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", file, err)
+	}
+	path := strings.Split(key, ".")
+
+	var out []byte
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("error parsing %s: %w", file, err)
+		}
+		if len(doc.Content) == 0 || !removeYAMLKey(doc.Content[0], path) {
+			return fmt.Errorf("key %q not found in %s", key, file)
+		}
+
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(&doc); err != nil {
+			return fmt.Errorf("error encoding %s: %w", file, err)
+		}
+		out = buf.Bytes()
+
+	case ".json":
+		var m map[string]any
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("error parsing %s: %w", file, err)
+		}
+		if !removeMapKey(m, path) {
+			return fmt.Errorf("key %q not found in %s", key, file)
+		}
+
+		out, err = json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding %s: %w", file, err)
+		}
+		out = append(out, '\n')
+
+	default:
+		return fmt.Errorf("unsupported config file type: %s", file)
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, out, info.Mode().Perm())
+}
+
+// removeYAMLKey removes path from a yaml mapping node and reports whether it was found
+func removeYAMLKey(node *yaml.Node, path []string) bool {
+	if node.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if !strings.EqualFold(node.Content[i].Value, path[0]) {
+			continue
+		}
+		value := node.Content[i+1]
+		if len(path) > 1 {
+			if !removeYAMLKey(value, path[1:]) {
+				return false
+			}
+			if len(value.Content) > 0 {
+				return true
+			}
+		}
+		node.Content = append(node.Content[:i], node.Content[i+2:]...)
+		return true
+	}
+	return false
+}
+
+// removeMapKey removes path from a decoded json object and reports whether it was found
+func removeMapKey(m map[string]any, path []string) bool {
+	for k, v := range m {
+		if !strings.EqualFold(k, path[0]) {
+			continue
+		}
+		if len(path) > 1 {
+			child, ok := v.(map[string]any)
+			if !ok || !removeMapKey(child, path[1:]) {
+				return false
+			}
+			if len(child) > 0 {
+				return true
+			}
+		}
+		delete(m, k)
+		return true
+	}
+	return false
+}
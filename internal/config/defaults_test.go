@@ -0,0 +1,61 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetStructuralDefaultsBool(t *testing.T) {
+	tests := []struct {
+		name  string
+		value bool
+		set   bool
+		want  bool
+	}{
+		{name: "unset gets the default", value: false, set: false, want: true},
+		{name: "false in a config file is kept", value: false, set: true, want: false},
+		{name: "true in a config file is kept", value: true, set: true, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := ConfigSchema{Internal: Internal{AutoTitle: tt.value}}
+			isSet := func(key string) bool {
+				return tt.set && key == "internal.autoTitle"
+			}
+			if err := setStructuralDefaults(&schema, isSet); err != nil {
+				t.Fatalf("setStructuralDefaults: %v", err)
+			}
+			if schema.Internal.AutoTitle != tt.want {
+				t.Errorf("AutoTitle = %v, want %v", schema.Internal.AutoTitle, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigKey(t *testing.T) {
+	type fields struct {
+		Named    bool `mapstructure:"autoTitle"`
+		Squashed bool `mapstructure:"squashed,omitempty"`
+		Untagged bool
+	}
+	typ := reflect.TypeOf(fields{})
+
+	tests := []struct {
+		field  string
+		prefix string
+		want   string
+	}{
+		{field: "Named", prefix: "", want: "autoTitle"},
+		{field: "Named", prefix: "internal", want: "internal.autoTitle"},
+		{field: "Squashed", prefix: "presets.claude", want: "presets.claude.squashed"},
+		{field: "Untagged", prefix: "internal", want: "internal.Untagged"},
+	}
+
+	for _, tt := range tests {
+		field, _ := typ.FieldByName(tt.field)
+		if got := configKey(tt.prefix, field); got != tt.want {
+			t.Errorf("configKey(%q, %s) = %q, want %q", tt.prefix, tt.field, got, tt.want)
+		}
+	}
+}
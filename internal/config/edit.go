@@ -0,0 +1,250 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is created in a config directory that has no config files yet
+const defaultConfigFile = "config.slop.yaml"
+
+// ParseKey checks a dot separated key against the schema and returns it with the
+// casing config files use, along with the type of its value. Map keys such as preset
+// names are kept as given
+func ParseKey(key string) (string, reflect.Type, error) {
+	path := strings.Split(key, ".")
+	if slices.Contains(path, "") {
+		return "", nil, fmt.Errorf("invalid key %q", key)
+	}
+	if !isKnownKey(reflect.TypeOf(ConfigSchema{}), path) {
+		return "", nil, fmt.Errorf("unknown key %q", key)
+	}
+
+	t := reflect.TypeOf(ConfigSchema{})
+	canonical := make([]string, len(path))
+	for i, part := range path {
+		canonical[i] = part
+		switch t.Kind() {
+		case reflect.Struct:
+			for j := range t.NumField() {
+				field := t.Field(j)
+				if tag := field.Tag.Get("mapstructure"); field.IsExported() && strings.EqualFold(tag, part) {
+					canonical[i] = tag
+					t = field.Type
+					break
+				}
+			}
+		case reflect.Map:
+			t = t.Elem()
+		}
+	}
+	return strings.Join(canonical, "."), t, nil
+}
+
+// ParseValue converts a value given on the command line to the type of a key. Lists
+// are written as yaml such as [a, b], a single value is a list of one item
+func ParseValue(key string, t reflect.Type, raw string) (any, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false", key)
+		}
+		return b, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a whole number", key)
+		}
+		return n, nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", key)
+		}
+		return f, nil
+	case reflect.Slice:
+		var value any
+		if err := yaml.Unmarshal([]byte(raw), &value); err != nil {
+			return nil, fmt.Errorf("%s must be a list such as [a, b]: %w", key, err)
+		}
+		if list, ok := value.([]any); ok {
+			return list, nil
+		}
+		return []any{value}, nil
+	default:
+		return nil, fmt.Errorf("%s is a section, set one of the keys under it instead", key)
+	}
+}
+
+// LocalDir returns the .slop directory of the current project, or "" when slop isn't
+// running in a project
+func LocalDir() (string, error) {
+	project, err := FindProjectDir()
+	if err != nil || project == "" {
+		return "", err
+	}
+	return filepath.Join(project, ".slop"), nil
+}
+
+// FileFor returns the config file in dir that a key is written to: the file that sets
+// it already, or else config.slop.yaml or the first config file of the directory.
+// The file may not exist yet. An empty key picks the file to edit by hand
+func FileFor(dir, key string) (string, error) {
+	files, err := findConfigFiles(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if key != "" {
+		// Later files take precedence, so the last one that sets the key is in effect
+		for _, f := range slices.Backward(files) {
+			v := viper.New()
+			v.SetConfigFile(f)
+			if err := v.ReadInConfig(); err != nil {
+				return "", fmt.Errorf("error reading config file %s: %w", f, err)
+			}
+			if v.IsSet(key) {
+				return f, nil
+			}
+		}
+	}
+
+	preferred := filepath.Join(dir, defaultConfigFile)
+	if len(files) == 0 || slices.Contains(files, preferred) {
+		return preferred, nil
+	}
+	return files[0], nil
+}
+
+// SetKey sets a dot separated key in a yaml or json config file, creating the file
+// and any maps on the way to the key. Keys are matched case-insensitively. Comments
+// in yaml files are kept, json files are rewritten with sorted keys
+func SetKey(file, key string, value any) error {
+	data, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error reading %s: %w", file, err)
+	}
+	path := strings.Split(key, ".")
+
+	var out []byte
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("error parsing %s: %w", file, err)
+		}
+		if len(doc.Content) == 0 {
+			doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+		}
+		var valueNode yaml.Node
+		if err := valueNode.Encode(value); err != nil {
+			return fmt.Errorf("error encoding %s: %w", key, err)
+		}
+		if !setYAMLKey(doc.Content[0], path, &valueNode) {
+			return fmt.Errorf("%s in %s is not a map", key, file)
+		}
+
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(&doc); err != nil {
+			return fmt.Errorf("error encoding %s: %w", file, err)
+		}
+		out = buf.Bytes()
+
+	case ".json":
+		m := make(map[string]any)
+		if len(bytes.TrimSpace(data)) > 0 {
+			if err := json.Unmarshal(data, &m); err != nil {
+				return fmt.Errorf("error parsing %s: %w", file, err)
+			}
+		}
+		if !setMapKey(m, path, value) {
+			return fmt.Errorf("%s in %s is not a map", key, file)
+		}
+
+		out, err = json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding %s: %w", file, err)
+		}
+		out = append(out, '\n')
+
+	default:
+		return fmt.Errorf("unsupported config file type: %s", file)
+	}
+
+	perm := os.FileMode(0o644)
+	if info, err := os.Stat(file); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, out, perm)
+}
+
+// setYAMLKey sets path in a yaml mapping node and reports whether it could. Values
+// that are in the way of the path and aren't maps are left alone
+func setYAMLKey(node *yaml.Node, path []string, value *yaml.Node) bool {
+	if node.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if !strings.EqualFold(node.Content[i].Value, path[0]) {
+			continue
+		}
+		if len(path) == 1 {
+			// Keep the comments of the value that is replaced
+			value.HeadComment = node.Content[i+1].HeadComment
+			value.LineComment = node.Content[i+1].LineComment
+			node.Content[i+1] = value
+			return true
+		}
+		return setYAMLKey(node.Content[i+1], path[1:], value)
+	}
+
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: path[0]}
+	if len(path) == 1 {
+		node.Content = append(node.Content, keyNode, value)
+		return true
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	node.Content = append(node.Content, keyNode, child)
+	return setYAMLKey(child, path[1:], value)
+}
+
+// setMapKey sets path in a decoded json object and reports whether it could, like
+// setYAMLKey
+func setMapKey(m map[string]any, path []string, value any) bool {
+	key := path[0]
+	for k := range m {
+		if strings.EqualFold(k, key) {
+			key = k
+			break
+		}
+	}
+	if len(path) == 1 {
+		m[key] = value
+		return true
+	}
+	existing, found := m[key]
+	if !found {
+		existing = make(map[string]any)
+		m[key] = existing
+	}
+	child, ok := existing.(map[string]any)
+	return ok && setMapKey(child, path[1:], value)
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileRefPrefix marks a system message or prompt content that is kept in its own file
+const fileRefPrefix = "file://"
+
+// resolveFileRefs replaces system messages and prompt contents written as
+// file://path with the content of the file, so long messages can live in their own
+// markdown files:
+//
+//	presets:
+//	  coder:
+//	    systemMessage: file://prompts/coder.md
+//
+// Relative paths are relative to the directory of the config file that sets them.
+// The file is recorded as the source of the setting, and a missing file is an error
+func (c *Config) resolveFileRefs(flat map[string]any, source string) error {
+	for key, value := range flat {
+		ref, ok := value.(string)
+		if !ok || !isFileRefKey(key) {
+			continue
+		}
+		path, ok := strings.CutPrefix(strings.TrimSpace(ref), fileRefPrefix)
+		if !ok {
+			continue
+		}
+		if path == "" {
+			return fmt.Errorf("%s has an empty file reference", key)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(source), path)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading %s for %s: %w", path, key, err)
+		}
+		flat[key] = strings.TrimSpace(strings.ReplaceAll(string(data), "\r\n", "\n"))
+		c.sources[key] = fmt.Sprintf("%s via %s", path, source)
+	}
+	return nil
+}
+
+// isFileRefKey reports whether a flattened key may refer to a file: any system
+// message, and the content of prompts
+func isFileRefKey(key string) bool {
+	if strings.HasSuffix(key, ".systemmessage") {
+		return true
+	}
+	return strings.HasPrefix(key, "prompts.") && strings.HasSuffix(key, ".content")
+}
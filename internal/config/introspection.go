@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
@@ -82,6 +83,16 @@ func (s *ConfigSchema) printValue(v reflect.Value, key, fullKey string, includeS
 	}
 }
 
+// Source returns the config file that set a key such as dbPath, or an empty string if
+// the key has its default value
+func (s *ConfigSchema) Source(key string) string {
+	source := s.sources[strings.ToLower(key)]
+	if source == "default" {
+		return ""
+	}
+	return source
+}
+
 func (s *ConfigSchema) printSourceInfo(key string, includeSources bool) {
 	if !includeSources {
 		return
@@ -94,8 +105,36 @@ func (s *ConfigSchema) printSourceInfo(key string, includeSources bool) {
 	}
 }
 
+// Redacted returns the configuration as JSON values keyed like the config files, with
+// the same values hidden as when it is printed
+func (s *ConfigSchema) Redacted() (map[string]any, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]any
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	redact(values)
+	return values, nil
+}
+
+func redact(values map[string]any) {
+	for key, value := range values {
+		if nested, ok := value.(map[string]any); ok {
+			redact(nested)
+			continue
+		}
+		if value != nil && value != "" && isSecretKey(key) {
+			values[key] = "[REDACTED]"
+		}
+	}
+}
+
 func isSecretKey(key string) bool {
 	return strings.Contains(strings.ToLower(key), "key") ||
 		strings.Contains(strings.ToLower(key), "secret") ||
-		strings.Contains(strings.ToLower(key), "password")
+		strings.Contains(strings.ToLower(key), "password") ||
+		strings.ToLower(key) == "token"
 }
@@ -4,27 +4,63 @@ import "encoding/json"
 
 // Key bindings
 const (
-	KeyActionQuit        = "quit"
-	KeyActionToggleHelp  = "toggleHelp"
-	KeyActionSwitchChat  = "switchToChat"
-	KeyActionSwitchHome  = "switchToHome"
-	KeyActionExitInput   = "exitInput"
-	KeyActionInputMode   = "inputMode"
-	KeyActionScrollDown  = "scrollDown"
-	KeyActionScrollUp    = "scrollUp"
-	KeyActionSendMessage = "sendMessage"
+	KeyActionQuit           = "quit"
+	KeyActionToggleHelp     = "toggleHelp"
+	KeyActionSwitchChat     = "switchToChat"
+	KeyActionSwitchHome     = "switchToHome"
+	KeyActionExitInput      = "exitInput"
+	KeyActionInputMode      = "inputMode"
+	KeyActionScrollDown     = "scrollDown"
+	KeyActionScrollUp       = "scrollUp"
+	KeyActionSendMessage    = "sendMessage"
+	KeyActionSearch         = "search"
+	KeyActionNextMatch      = "nextMatch"
+	KeyActionPrevMatch      = "prevMatch"
+	KeyActionPauseStream    = "pauseStream"
+	KeyActionFollow         = "toggleFollow"
+	KeyActionFocusPane      = "focusPane"
+	KeyActionGrowSplit      = "growSplit"
+	KeyActionShrinkSplit    = "shrinkSplit"
+	KeyActionOpenThread     = "openThread"
+	KeyActionNextCitation   = "nextCitation"
+	KeyActionPrevCitation   = "prevCitation"
+	KeyActionFollowCitation = "followCitation"
+	KeyActionAttachFile     = "attachFile"
+	KeyActionToggleTools    = "toggleTools"
+	KeyActionNewThread      = "newThread"
+	KeyActionDeleteThread   = "deleteThread"
+	KeyActionToggleDiff     = "toggleDiff"
+	KeyActionToggleInfo     = "toggleInfo"
 )
 
 type KeyMap struct {
-	Quit         []string `mapstructure:"quit" json:"quit" jsonschema:"description=Exit the application,default=q"`
-	ToggleHelp   []string `mapstructure:"toggleHelp" json:"toggleHelp" jsonschema:"description=Toggle help display,default=?"`
-	SwitchToChat []string `mapstructure:"switchToChat" json:"switchToChat" jsonschema:"description=Switch to chat screen,default=c"`
-	SwitchToHome []string `mapstructure:"switchToHome" json:"switchToHome" jsonschema:"description=Switch to home screen,default=h"`
-	ExitInput    []string `mapstructure:"exitInput" json:"exitInput" jsonschema:"description=Exit input mode,default=esc"`
-	InputMode    []string `mapstructure:"inputMode" json:"inputMode" jsonschema:"description=Enter input mode,default=i"`
-	ScrollDown   []string `mapstructure:"scrollDown" json:"scrollDown" jsonschema:"description=Scroll down in chat,default=j,down"`
-	ScrollUp     []string `mapstructure:"scrollUp" json:"scrollUp" jsonschema:"description=Scroll up in chat,default=k,up"`
-	SendMessage  []string `mapstructure:"sendMessage" json:"sendMessage" jsonschema:"description=Send a message,default=enter"`
+	Quit           []string `mapstructure:"quit" json:"quit" jsonschema:"description=Exit the application,default=q"`
+	ToggleHelp     []string `mapstructure:"toggleHelp" json:"toggleHelp" jsonschema:"description=Show or hide the help overlay listing the keys of the current screen,default=?"`
+	SwitchToChat   []string `mapstructure:"switchToChat" json:"switchToChat" jsonschema:"description=Switch to chat screen,default=c"`
+	SwitchToHome   []string `mapstructure:"switchToHome" json:"switchToHome" jsonschema:"description=Switch to home screen,default=h"`
+	ExitInput      []string `mapstructure:"exitInput" json:"exitInput" jsonschema:"description=Exit input mode,default=esc"`
+	InputMode      []string `mapstructure:"inputMode" json:"inputMode" jsonschema:"description=Enter input mode,default=i"`
+	ScrollDown     []string `mapstructure:"scrollDown" json:"scrollDown" jsonschema:"description=Scroll down in chat,default=j,down"`
+	ScrollUp       []string `mapstructure:"scrollUp" json:"scrollUp" jsonschema:"description=Scroll up in chat,default=k,up"`
+	SendMessage    []string `mapstructure:"sendMessage" json:"sendMessage" jsonschema:"description=Send a message,default=enter"`
+	Search         []string `mapstructure:"search" json:"search" jsonschema:"description=Search the chat,default=/"`
+	NextMatch      []string `mapstructure:"nextMatch" json:"nextMatch" jsonschema:"description=Jump to the next search match,default=n"`
+	PrevMatch      []string `mapstructure:"prevMatch" json:"prevMatch" jsonschema:"description=Jump to the previous search match,default=N"`
+	PauseStream    []string `mapstructure:"pauseStream" json:"pauseStream" jsonschema:"description=Pause or resume a streaming response,default=p"`
+	ToggleFollow   []string `mapstructure:"toggleFollow" json:"toggleFollow" jsonschema:"description=Toggle scrolling to new content as it arrives,default=f"`
+	FocusPane      []string `mapstructure:"focusPane" json:"focusPane" jsonschema:"description=Move focus between the thread list and the chat in split view,default=tab"`
+	GrowSplit      []string `mapstructure:"growSplit" json:"growSplit" jsonschema:"description=Widen the thread list in split view,default=>"`
+	ShrinkSplit    []string `mapstructure:"shrinkSplit" json:"shrinkSplit" jsonschema:"description=Narrow the thread list in split view,default=<"`
+	OpenThread     []string `mapstructure:"openThread" json:"openThread" jsonschema:"description=Open the selected thread from the thread list,default=enter"`
+	NextCitation   []string `mapstructure:"nextCitation" json:"nextCitation" jsonschema:"description=Select the next reference to an earlier message,default=]"`
+	PrevCitation   []string `mapstructure:"prevCitation" json:"prevCitation" jsonschema:"description=Select the previous reference to an earlier message,default=["`
+	FollowCitation []string `mapstructure:"followCitation" json:"followCitation" jsonschema:"description=Jump to the message the selected reference cites,default=g"`
+	AttachFile     []string `mapstructure:"attachFile" json:"attachFile" jsonschema:"description=Pick a file to attach to the message being typed,default=ctrl+o"`
+	ToggleTools    []string `mapstructure:"toggleTools" json:"toggleTools" jsonschema:"description=Expand or collapse the arguments and results of tool calls,default=t"`
+	NewThread      []string `mapstructure:"newThread" json:"newThread" jsonschema:"description=Start a new thread from the thread list,default=n"`
+	DeleteThread   []string `mapstructure:"deleteThread" json:"deleteThread" jsonschema:"description=Delete the selected thread from the thread list after confirming,default=d"`
+	ToggleDiff     []string `mapstructure:"toggleDiff" json:"toggleDiff" jsonschema:"description=Show what regenerated responses changed from the responses they replaced,default=D"`
+	ToggleInfo     []string `mapstructure:"toggleInfo" json:"toggleInfo" jsonschema:"description=Show or hide the panel with the preset and toolsets and context usage and details of the thread next to the chat,default=I"`
 
 	keyCache map[string][]string
 }
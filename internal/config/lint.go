@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// LintIssue is a part of the configuration that is probably a mistake
+type LintIssue struct {
+	Key     string // Config key the issue is about, such as prompts.sql
+	Message string
+}
+
+// Lint finds dead references and unused definitions: prompts that are never included,
+// toolsets no preset uses, MCP servers in no toolset, presets identical to another one
+// and references to prompts, toolsets, servers or presets that do not exist
+func (s *ConfigSchema) Lint() []LintIssue {
+	var issues []LintIssue
+	add := func(key string, format string, args ...any) {
+		issues = append(issues, LintIssue{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	presetNames := sortedKeys(s.Presets)
+	usedPrompts := make(map[string]bool)
+	usedToolsets := make(map[string]bool)
+	for _, name := range presetNames {
+		preset := s.Presets[name]
+		for _, prompt := range preset.IncludePrompts {
+			usedPrompts[prompt] = true
+			if _, ok := s.Prompts[prompt]; !ok {
+				add("presets."+name+".includePrompts", "prompt %q does not exist", prompt)
+			}
+		}
+		for _, toolset := range preset.Toolsets {
+			usedToolsets[toolset] = true
+			if _, ok := s.Toolsets[toolset]; !ok {
+				add("presets."+name+".toolsets", "toolset %q does not exist", toolset)
+			}
+		}
+	}
+	if _, ok := s.Presets[s.Internal.Model]; s.Internal.Model != "" && !ok {
+		add("internal.model", "preset %q does not exist", s.Internal.Model)
+	}
+
+	// Presets with the same definition as an earlier one
+	for i, name := range presetNames {
+		for _, other := range presetNames[:i] {
+			if reflect.DeepEqual(s.Presets[name], s.Presets[other]) {
+				add("presets."+name, "identical to preset %q", other)
+				break
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(s.Prompts) {
+		prompt := s.Prompts[name]
+		automatic := prompt.IncludeInSystemMessage || prompt.SystemMessageTrigger != "" || prompt.SystemMessageCondition != ""
+		if !automatic && !usedPrompts[name] {
+			add("prompts."+name, "never included, no preset lists it in includePrompts and it has no trigger or condition")
+		}
+	}
+
+	usedServers := make(map[string]bool)
+	for _, name := range sortedKeys(s.Toolsets) {
+		if !usedToolsets[name] {
+			add("toolsets."+name, "not used by any preset")
+		}
+		for _, server := range sortedKeys(s.Toolsets[name].Servers) {
+			usedServers[server] = true
+			if _, ok := s.MCPServers[server]; !ok {
+				add("toolsets."+name+".servers."+server, "MCP server %q does not exist", server)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(s.MCPServers) {
+		if !usedServers[name] {
+			add("mcpServers."+name, "not in any toolset, its tools are only reachable with slop mcp call")
+		}
+	}
+
+	return issues
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
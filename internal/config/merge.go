@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// mergeStrategyKey holds the strategies a config file uses for its lists, as a map
+// from the dot separated key of a list to a strategy. It isn't part of the schema
+const mergeStrategyKey = "mergestrategy"
+
+const (
+	// MergeAppend adds the items of a list to the ones set by earlier files
+	MergeAppend = "append"
+	// MergeReplace discards the items set by earlier files
+	MergeReplace = "replace"
+)
+
+// extractMergeStrategies removes the merge strategies from the settings of a file
+// and returns them by flattened, lowercased key
+func extractMergeStrategies(settings map[string]any) (map[string]any, map[string]any) {
+	raw, ok := settings[mergeStrategyKey]
+	if !ok {
+		return settings, nil
+	}
+	settings = maps.Clone(settings)
+	delete(settings, mergeStrategyKey)
+
+	strategies := make(map[string]any)
+	if m, ok := raw.(map[string]any); ok {
+		tracker := &Config{sources: make(map[string]string)}
+		for key, strategy := range tracker.flattenAndTrack(m, "", "") {
+			strategies[strings.ToLower(key)] = strategy
+		}
+	}
+	return settings, strategies
+}
+
+// checkStrategies records warnings for merge strategies that are invalid or that
+// don't name a list set by the same file
+func (c *Config) checkStrategies(strategies map[string]any, flat map[string]any, source string) {
+	keys := make([]string, 0, len(strategies))
+	for key := range strategies {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		strategy := strategies[key]
+		if strategy != MergeAppend && strategy != MergeReplace {
+			c.warnings = append(c.warnings, fmt.Sprintf("invalid merge strategy %v for %q in %s, must be %s or %s", strategy, key, source, MergeAppend, MergeReplace))
+			continue
+		}
+		if _, ok := flat[key].([]any); !ok {
+			c.warnings = append(c.warnings, fmt.Sprintf("merge strategy for %q in %s doesn't match a list set in the same file", key, source))
+		}
+	}
+}
+
+// mergeList combines a list from source with the value set by earlier files and
+// records in the sources which files it came from. Lists from config files are
+// appended to ones from other config files unless the strategy is replace, with a
+// warning when the file has no strategy for the list. Lists from the defaults are
+// always replaced
+func (c *Config) mergeList(key string, list []any, strategy any, source string, previousSource string) []any {
+	if previousSource == "" || previousSource == "default" || previousSource == source {
+		return list
+	}
+	if strategy == MergeReplace {
+		c.sources[key] = fmt.Sprintf("%s, replacing %s", source, previousSource)
+		return list
+	}
+	if strategy == nil {
+		c.warnings = append(c.warnings, fmt.Sprintf("list %q in %s is appended to the one from %s, set mergeStrategy to %s or %s for it to choose",
+			key, source, previousSource, MergeAppend, MergeReplace))
+	}
+
+	merged := toList(c.v.Get(key))
+	for _, item := range list {
+		if !containsItem(merged, item) {
+			merged = append(merged, item)
+		}
+	}
+	c.sources[key] = previousSource + " + " + source
+	return merged
+}
+
+// toList converts a list stored in viper back to []any
+func toList(value any) []any {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice {
+		return nil
+	}
+	list := make([]any, v.Len())
+	for i := range list {
+		list[i] = v.Index(i).Interface()
+	}
+	return list
+}
+
+func containsItem(list []any, item any) bool {
+	for _, existing := range list {
+		if reflect.DeepEqual(existing, item) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestMergeConfigLists(t *testing.T) {
+	const key = "presets.claude.toolsets"
+
+	tests := []struct {
+		name        string
+		firstSource string
+		first       []any
+		second      []any
+		strategy    string // Strategy of the second file, empty for none
+		want        []any
+		wantSource  string
+		wantWarning bool
+	}{
+		{
+			name:        "appended with a warning without a strategy",
+			firstSource: "global.slop.yaml",
+			first:       []any{"files"},
+			second:      []any{"git"},
+			want:        []any{"files", "git"},
+			wantSource:  "global.slop.yaml + local.slop.yaml",
+			wantWarning: true,
+		},
+		{
+			name:        "appended without a warning with append",
+			firstSource: "global.slop.yaml",
+			first:       []any{"files"},
+			second:      []any{"git", "files"},
+			strategy:    MergeAppend,
+			want:        []any{"files", "git"},
+			wantSource:  "global.slop.yaml + local.slop.yaml",
+		},
+		{
+			name:        "replaced with replace",
+			firstSource: "global.slop.yaml",
+			first:       []any{"files"},
+			second:      []any{"git"},
+			strategy:    MergeReplace,
+			want:        []any{"git"},
+			wantSource:  "local.slop.yaml, replacing global.slop.yaml",
+		},
+		{
+			name:        "lists from the defaults are replaced",
+			firstSource: "default",
+			first:       []any{"files"},
+			second:      []any{"git"},
+			want:        []any{"git"},
+			wantSource:  "local.slop.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{v: viper.New(), sources: make(map[string]string)}
+			first := map[string]any{"presets": map[string]any{"claude": map[string]any{"toolsets": tt.first}}}
+			if err := c.mergeConfig(first, tt.firstSource); err != nil {
+				t.Fatalf("mergeConfig: %v", err)
+			}
+
+			second := map[string]any{"presets": map[string]any{"claude": map[string]any{"toolsets": tt.second}}}
+			if tt.strategy != "" {
+				second[mergeStrategyKey] = map[string]any{key: tt.strategy}
+			}
+			if err := c.mergeConfig(second, "local.slop.yaml"); err != nil {
+				t.Fatalf("mergeConfig: %v", err)
+			}
+
+			if got := toList(c.v.Get(key)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %v, want %v", key, got, tt.want)
+			}
+			if got := c.sources[key]; got != tt.wantSource {
+				t.Errorf("source = %q, want %q", got, tt.wantSource)
+			}
+			warned := strings.Contains(strings.Join(c.warnings, "\n"), "mergeStrategy")
+			if warned != tt.wantWarning {
+				t.Errorf("warnings = %q, want a mergeStrategy warning: %v", c.warnings, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestCheckStrategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy any
+		key      string
+		want     string
+	}{
+		{name: "valid", strategy: MergeReplace, key: "presets.claude.toolsets"},
+		{name: "invalid strategy", strategy: "prepend", key: "presets.claude.toolsets", want: "invalid merge strategy"},
+		{name: "key without a list", strategy: MergeAppend, key: "presets.claude.model", want: "doesn't match a list"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := map[string]any{
+				"presets":        map[string]any{"claude": map[string]any{"toolsets": []any{"git"}}},
+				mergeStrategyKey: map[string]any{tt.key: tt.strategy},
+			}
+			settings, strategies := extractMergeStrategies(settings)
+			if _, ok := settings[mergeStrategyKey]; ok {
+				t.Fatalf("merge strategies were left in the settings")
+			}
+
+			c := &Config{sources: make(map[string]string)}
+			flat := c.flattenAndTrack(settings, "", "local.slop.yaml")
+			c.checkStrategies(strategies, flat, "local.slop.yaml")
+
+			warnings := strings.Join(c.warnings, "\n")
+			if tt.want == "" && warnings != "" {
+				t.Errorf("unexpected warnings %q", warnings)
+			}
+			if !strings.Contains(warnings, tt.want) {
+				t.Errorf("warnings = %q, want one containing %q", warnings, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package config
+
+import "slices"
+
+// Apply returns preset with the mode's overrides applied
+func (m Mode) Apply(preset Preset) Preset {
+	if m.Temperature != nil {
+		preset.Temperature = *m.Temperature
+	}
+	if m.MaxTokens > 0 {
+		preset.MaxTokens = m.MaxTokens
+	}
+	if m.CompactToolResultsAfter != nil {
+		preset.CompactToolResultsAfter = *m.CompactToolResultsAfter
+	}
+	if m.Compression != "" {
+		preset.Compression.Method = m.Compression
+	}
+	for _, toolset := range m.Toolsets {
+		if !slices.Contains(preset.Toolsets, toolset) {
+			preset.Toolsets = append(slices.Clone(preset.Toolsets), toolset)
+		}
+	}
+	return preset
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// FindProjectDir returns the closest directory from the current one up that has a
+// .slop directory, or "" outside of a project. The search stops below the home
+// directory so a .slop directory there only applies when slop runs from home itself
+func FindProjectDir() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	home, _ := os.UserHomeDir()
+	for {
+		if info, err := os.Stat(filepath.Join(dir, ".slop")); err == nil && info.IsDir() {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir || parent == home {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// ProjectDir returns the directory of the project whose config is in use, or "" when
+// slop isn't running in a project
+func (s *ConfigSchema) ProjectDir() string {
+	return s.projectDir
+}
+
+// ProjectName returns the name of the project whose config is in use, or "" when slop
+// isn't running in a project
+func (s *ConfigSchema) ProjectName() string {
+	if s.projectDir == "" {
+		return ""
+	}
+	if s.Project.Name != "" {
+		return s.Project.Name
+	}
+	return filepath.Base(s.projectDir)
+}
+
+// applyProject finds the project directory and makes the project's preset the default
+// and adds its toolsets to every preset
+func (s *ConfigSchema) applyProject(sources map[string]string) error {
+	dir, err := FindProjectDir()
+	if err != nil {
+		return fmt.Errorf("could not find the project directory: %w", err)
+	}
+	s.projectDir = dir
+
+	if s.Project.Preset != "" {
+		if _, ok := s.Presets[s.Project.Preset]; !ok {
+			return fmt.Errorf("project preset %q must be one of the configured presets", s.Project.Preset)
+		}
+		s.DefaultPreset = s.Project.Preset
+		sources["defaultpreset"] = sources["project.preset"]
+	}
+
+	for _, toolset := range s.Project.Toolsets {
+		if _, ok := s.Toolsets[toolset]; !ok {
+			return fmt.Errorf("project toolset %q must be one of the configured toolsets", toolset)
+		}
+	}
+	for name, preset := range s.Presets {
+		for _, toolset := range s.Project.Toolsets {
+			if !slices.Contains(preset.Toolsets, toolset) {
+				preset.Toolsets = append(slices.Clone(preset.Toolsets), toolset)
+			}
+		}
+		s.Presets[name] = preset
+	}
+	return nil
+}
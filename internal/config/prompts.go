@@ -0,0 +1,91 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// frontMatterDelimiter opens and closes the optional yaml front matter of a prompt file
+const frontMatterDelimiter = "---"
+
+// loadPromptFiles registers every *.md file in dir as a named prompt. The file name
+// without its extension is the prompt name and the markdown body is its content.
+// An optional yaml front matter block sets the other prompt options:
+//
+//	---
+//	includeInSystemMessage: false
+//	systemMessageTrigger: "(?i)sql|database"
+//	systemMessageCondition: "toolset:postgres and not tag:scratch"
+//	---
+//	Prompt content...
+func (c *Config) loadPromptFiles(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("error reading prompt file %s: %w", f, err)
+		}
+
+		prompt, err := parsePromptFile(data)
+		if err != nil {
+			return fmt.Errorf("error parsing prompt file %s: %w", f, err)
+		}
+
+		name := strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))
+		settings := map[string]any{
+			"prompts": map[string]any{
+				name: prompt,
+			},
+		}
+		if err := c.mergeConfig(settings, f); err != nil {
+			return fmt.Errorf("error merging prompt from %s: %w", f, err)
+		}
+	}
+	return nil
+}
+
+// parsePromptFile splits a prompt file into its front matter settings and content
+func parsePromptFile(data []byte) (map[string]any, error) {
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+	settings := make(map[string]any)
+
+	if rest, ok := strings.CutPrefix(content, frontMatterDelimiter+"\n"); ok {
+		frontMatter, body, found := strings.Cut(rest, "\n"+frontMatterDelimiter)
+		if !found {
+			return nil, fmt.Errorf("front matter is not closed with %q", frontMatterDelimiter)
+		}
+
+		v := viper.New()
+		v.SetConfigType("yaml")
+		if err := v.ReadConfig(bytes.NewBufferString(frontMatter)); err != nil {
+			return nil, fmt.Errorf("invalid front matter: %w", err)
+		}
+		// Keys are lowercased to match the settings viper reads from config files
+		for _, key := range []string{"includeInSystemMessage", "systemMessageTrigger", "systemMessageCondition"} {
+			if v.IsSet(key) {
+				settings[strings.ToLower(key)] = v.Get(key)
+			}
+		}
+
+		// Drop the rest of the closing delimiter line
+		if _, after, ok := strings.Cut(body, "\n"); ok {
+			content = after
+		} else {
+			content = ""
+		}
+	}
+
+	settings["content"] = strings.TrimSpace(content)
+	return settings, nil
+}
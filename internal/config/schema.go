@@ -9,41 +9,137 @@ package config
 type ConfigSchema struct {
 	Presets       map[string]Preset    `mapstructure:"presets" json:"presets" jsonschema:"description=Available model configurations"`
 	DefaultPreset string               `mapstructure:"defaultPreset" json:"defaultPreset" jsonschema:"description=Default preset for new chats,default=claude"`
-	DBPath        string               `mapstructure:"dbPath" json:"dbPath" jsonschema:"description=Path to the database file,default=.slop/slop.db"`
+	Modes         map[string]Mode      `mapstructure:"modes" json:"modes" jsonschema:"description=Named bundles of preset overrides chosen with --mode such as fast or quality"`
+	DBPath        string               `mapstructure:"dbPath" json:"dbPath" jsonschema:"description=Path to the database file. Defaults to .slop/slop.db in projects with a .slop directory and $XDG_DATA_HOME/slop/slop.db everywhere else"`
 	Internal      Internal             `mapstructure:"internal" json:"internal" jsonschema:"description=Internal configuration settings"`
 	MCPServers    map[string]MCPServer `mapstructure:"mcpServers" json:"mcpServers" jsonschema:"description=MCP server configurations"`
 	Log           Log                  `mapstructure:"log" json:"log" jsonschema:"description=Logging configuration"`
 	Toolsets      map[string]Toolset   `mapstructure:"toolsets" json:"toolsets" jsonschema:"description=Configurations for sets of MCP Servers and tools. Leave empty to allow all servers and all tools."`
 	Prompts       map[string]Prompt    `mapstructure:"prompts" json:"prompts" jsonschema:"Reusable prompt configuration"`
+	Templates     map[string]string    `mapstructure:"messageTemplates" json:"messageTemplates" jsonschema:"description=Named message texts with {{.name}} placeholders filled in by msg send --template and --var"`
+	OutTemplates  map[string]string    `mapstructure:"outputTemplates" json:"outputTemplates" jsonschema:"description=Named Go templates for the --format-template option of thread ls and thread view. Each runs once per thread or message and the fields are listed in the help of the commands"`
 	KeyMap        KeyMap               `mapstructure:"keyMap" json:"keyMap" jsonschema:"description=Custom keybindings for the TUI"`
+	Theme         Theme                `mapstructure:"theme" json:"theme" jsonschema:"description=Colors and styles for the TUI"`
+	Codebase      Codebase             `mapstructure:"codebase" json:"codebase" jsonschema:"description=Index of the project's files offered to the model as the built-in codebase server"`
+	Sandbox       Sandbox              `mapstructure:"sandbox" json:"sandbox" jsonschema:"description=Containers the built-in sandbox server runs code written by the model in"`
+	Serve         Serve                `mapstructure:"serve" json:"serve" jsonschema:"description=HTTP server started by slop serve"`
+	VimMode       bool                 `mapstructure:"vimMode" json:"vimMode" jsonschema:"description=Edit the TUI input with vim style normal and insert and visual modes. Escape from normal mode leaves input mode,default=false"`
+	Style         Style                `mapstructure:"style" json:"style" jsonschema:"description=How replies are written"`
+	MergeStrategy map[string]string    `mapstructure:"mergeStrategy" json:"mergeStrategy" jsonschema:"description=How lists in this file combine with lists set by earlier files. Maps the dot separated key of a list to append or replace. Lists are appended by default with a warning unless they are named here"`
+	Project       Project              `mapstructure:"project" json:"project" jsonschema:"description=Defaults for the project whose .slop directory is in use. Set them in a config file of the project"`
+	RenderMath    bool                 `mapstructure:"renderMath" json:"renderMath" jsonschema:"description=Show LaTeX math in responses as Unicode in the terminal and typeset it with KaTeX in HTML exports"`
+	TimeFormat    string               `mapstructure:"timeFormat" json:"timeFormat" jsonschema:"description=How thread view and the TUI show when messages were written: relative such as 2m ago or absolute. Exports always show absolute times,default=relative,enum=relative,enum=absolute"`
+	Issues        Issues               `mapstructure:"issues" json:"issues" jsonschema:"description=Issue tracker that thread to-issue creates issues in"`
 
 	// Internal fields for printing
-	sources  map[string]string
-	warnings []string
+	sources    map[string]string
+	warnings   []string
+	projectDir string
 }
 
 // LLM presets
 type Preset struct {
-	Provider       string   `mapstructure:"provider" json:"provider" jsonschema:"description=The AI provider to use"`
-	Name           string   `mapstructure:"name" json:"name" jsonschema:"description=Model name for the provider"`
-	MaxTokens      int      `mapstructure:"maxTokens" json:"maxTokens" jsonschema:"description=Maximum tokens to use in requests,default=1000"`
-	Temperature    float64  `mapstructure:"temperature" json:"temperature" jsonschema:"description=Temperature setting for the model,default=0.7"`
-	Toolsets       []string `mapstructure:"toolsets" json:"toolsets" jsonschema:"description=Toolsets to use for this model preset"`
-	SystemMessage  string   `mapstructure:"systemMessage" json:"systemMessage" jsonschema:"description=Base system message for all conversations using this preset"`
-	IncludePrompts []string `mapstructure:"includePrompts" json:"includePrompts" jsonschema:"description=Names of prompts to include in the system message,default=false"`
+	Provider                string      `mapstructure:"provider" json:"provider" jsonschema:"description=The AI provider to use: openai or anthropic or googleai or ollama"`
+	Name                    string      `mapstructure:"name" json:"name" jsonschema:"description=Model name for the provider"`
+	BaseURL                 string      `mapstructure:"baseURL" json:"baseURL" jsonschema:"description=Address of the provider's API. Point an openai preset at any OpenAI compatible server such as https://openrouter.ai/api/v1 or http://localhost:1234/v1 for LM Studio. Empty uses the provider's default"`
+	APIKeyEnv               string      `mapstructure:"apiKeyEnv" json:"apiKeyEnv" jsonschema:"description=Environment variable holding the API key such as OPENROUTER_API_KEY. Empty uses the provider's usual variable. Presets with a baseURL then send a placeholder instead so the provider's key never reaches other servers"`
+	MaxTokens               int         `mapstructure:"maxTokens" json:"maxTokens" jsonschema:"description=Maximum tokens to use in requests,default=1000"`
+	ContextWindow           int         `mapstructure:"contextWindow" json:"contextWindow" jsonschema:"description=Number of tokens the model accepts in a single request. Used to warn when a conversation gets close to the limit. 0 if unknown"`
+	Temperature             float64     `mapstructure:"temperature" json:"temperature" jsonschema:"description=Temperature setting for the model,default=0.7"`
+	Toolsets                []string    `mapstructure:"toolsets" json:"toolsets" jsonschema:"description=Toolsets to use for this model preset"`
+	SystemMessage           string      `mapstructure:"systemMessage" json:"systemMessage" jsonschema:"description=Base system message for all conversations using this preset. file://PATH reads it from a file relative to the config file"`
+	IncludePrompts          []string    `mapstructure:"includePrompts" json:"includePrompts" jsonschema:"description=Names of prompts to include in the system message,default=false"`
+	Pricing                 Pricing     `mapstructure:"pricing" json:"pricing" jsonschema:"description=Price of the model's tokens used to estimate the cost of responses"`
+	CompactToolResultsAfter int         `mapstructure:"compactToolResultsAfter" json:"compactToolResultsAfter" jsonschema:"description=Replace tool results older than this many turns with a short placeholder. 0 sends all tool results verbatim"`
+	SummarizeToolRuns       int         `mapstructure:"summarizeToolRuns" json:"summarizeToolRuns" jsonschema:"description=Once a turn that made at least this many tool calls finishes send later requests a summary of what the calls did instead of every call and result. 0 always sends them all"`
+	ToolChoice              string      `mapstructure:"toolChoice" json:"toolChoice" jsonschema:"description=Whether the model may call tools: auto or none or required or the server__tool name of a tool it must call,default=auto"`
+	AnnotateFailingTools    bool        `mapstructure:"annotateFailingTools" json:"annotateFailingTools" jsonschema:"description=Tell the model which of its tools have been failing frequently so it prefers healthier alternatives,default=false"`
+	ToolResultArtifactSize  int         `mapstructure:"toolResultArtifactSize" json:"toolResultArtifactSize" jsonschema:"description=Save tool results larger than this many bytes as artifacts and only send the model a preview. 0 always sends the full result"`
+	Vision                  bool        `mapstructure:"vision" json:"vision" jsonschema:"description=Send images attached with --image or returned by tools to the model. Only enable for models that accept image input,default=false"`
+	Reflect                 bool        `mapstructure:"reflect" json:"reflect" jsonschema:"description=Ask the model to critique and revise each final response before it is saved. The original draft is kept in the message metadata,default=false"`
+	AppendConfidence        bool        `mapstructure:"appendConfidence" json:"appendConfidence" jsonschema:"description=Ask the model to rate its confidence in each final response and note its assumptions. The rating is kept in the message metadata and shown below the response,default=false"`
+	Citations               bool        `mapstructure:"citations" json:"citations" jsonschema:"description=Label earlier messages with their IDs so the model can cite them as [msg a1b2c3d4]. Citations can be followed in the TUI and become footnotes in exports,default=false"`
+	Redact                  bool        `mapstructure:"redact" json:"redact" jsonschema:"description=Mask secrets such as API keys and passwords and tokens and private keys and email addresses in everything sent to the provider. Stored messages keep them. Threads with the redacted privacy level always mask them,default=false"`
+	RequestTimeout          string      `mapstructure:"requestTimeout" json:"requestTimeout" jsonschema:"description=Give up on a response that has not finished after this long such as 120s or 5m. Output received so far is saved. Empty waits as long as the provider keeps responding"`
+	Reconnects              int         `mapstructure:"reconnects" json:"reconnects" jsonschema:"description=How many times to reconnect when the connection to the provider drops mid response. The model is asked to continue from the text already received. A negative number never reconnects,default=2"`
+	Fallbacks               []string    `mapstructure:"fallbacks" json:"fallbacks" jsonschema:"description=Presets to send the request to in turn when the provider is rate limiting or overloaded. The tools and system message of this preset are kept. Threads that pin their model never fall back. Presets missing from the configuration or not allowed by the privacy level of the thread are skipped"`
+	CacheTTL                string      `mapstructure:"cacheTTL" json:"cacheTTL" jsonschema:"description=Reuse the response to an identical request for this long such as 24h instead of calling the provider again. Empty disables the response cache"`
+	Tokenizer               string      `mapstructure:"tokenizer" json:"tokenizer" jsonschema:"description=How tokens are counted for budgets and estimates: openai or anthropic or generic. Empty picks the tokenizer of the provider"`
+	HTTP                    HTTP        `mapstructure:"http" json:"http" jsonschema:"description=HTTP client settings for requests to the provider"`
+	Retry                   Retry       `mapstructure:"retry" json:"retry" jsonschema:"description=Send a request again when the provider fails with a temporary error before any of the response arrived"`
+	Compression             Compression `mapstructure:"compression" json:"compression" jsonschema:"description=Shrink older conversation history before it is sent to the model"`
+	Summarize               Summarize   `mapstructure:"summarize" json:"summarize" jsonschema:"description=Replace the older part of long threads with a summary written by the internal model"`
+}
+
+// Token prices for a preset in US dollars. Zero prices leave out cost estimates
+type Pricing struct {
+	InputPerMillion  float64 `mapstructure:"inputPerMillion" json:"inputPerMillion" jsonschema:"description=Price of a million input tokens"`
+	OutputPerMillion float64 `mapstructure:"outputPerMillion" json:"outputPerMillion" jsonschema:"description=Price of a million output tokens"`
+}
+
+// Prompt compression settings for a preset
+type Compression struct {
+	Method    string `mapstructure:"method" json:"method" jsonschema:"description=How to compress older history: none or heuristic to drop filler words and repeated lines locally or model to have a cheaper preset rewrite it,default=none,enum=none,enum=heuristic,enum=model"`
+	Model     string `mapstructure:"model" json:"model" jsonschema:"description=Preset used by the model method. Defaults to internal.model"`
+	KeepTurns int    `mapstructure:"keepTurns" json:"keepTurns" jsonschema:"description=Number of most recent turns that are always sent uncompressed,default=2"`
+	MinLength int    `mapstructure:"minLength" json:"minLength" jsonschema:"description=Only compress messages with at least this many characters,default=500"`
+}
+
+// Rolling summary settings for a preset. Summaries are stored with the thread and
+// extended as it grows, so each message is only summarized once
+type Summarize struct {
+	After     int `mapstructure:"after" json:"after" jsonschema:"description=Summarize older messages once more than this many would be sent with a request. 0 always sends every message"`
+	KeepTurns int `mapstructure:"keepTurns" json:"keepTurns" jsonschema:"description=Number of most recent turns that are always sent as they are,default=4"`
+}
+
+// HTTP client settings for a preset. Empty values fall back to the environment
+type HTTP struct {
+	Proxy    string `mapstructure:"proxy" json:"proxy" jsonschema:"description=Proxy URL for requests to the provider. Defaults to the HTTPS_PROXY and NO_PROXY environment variables"`
+	CABundle string `mapstructure:"caBundle" json:"caBundle" jsonschema:"description=Path to a PEM file of CA certificates to trust in addition to the system pool"`
+	Timeout  string `mapstructure:"timeout" json:"timeout" jsonschema:"description=How long to wait for the provider to start responding such as 30s or 5m. Streaming responses are not cut off once started"`
+}
+
+// Retry settings for a preset. Requests are only retried before any of the response
+// arrived, dropped responses are handled by reconnects
+type Retry struct {
+	MaxAttempts int    `mapstructure:"maxAttempts" json:"maxAttempts" jsonschema:"description=How many times to send a request that keeps failing with a temporary error. 1 never retries,default=3"`
+	Backoff     string `mapstructure:"backoff" json:"backoff" jsonschema:"description=How long to wait before the first retry such as 500ms or 2s. Each later retry waits twice as long,default=1s"`
+	MaxBackoff  string `mapstructure:"maxBackoff" json:"maxBackoff" jsonschema:"description=Longest wait between two attempts,default=30s"`
+	StatusCodes []int  `mapstructure:"statusCodes" json:"statusCodes" jsonschema:"description=HTTP status codes worth retrying. Timeouts are always retried. Empty retries 408 and 429 and 500 and 502 and 503 and 504 and 529"`
+}
+
+// Mode is a named bundle of overrides applied to the selected preset. Empty fields
+// keep the preset's value
+type Mode struct {
+	Preset                  string   `mapstructure:"preset" json:"preset" jsonschema:"description=Preset to use when none is chosen with --model"`
+	Temperature             *float64 `mapstructure:"temperature" json:"temperature" jsonschema:"description=Temperature to use instead of the preset's"`
+	MaxTokens               int      `mapstructure:"maxTokens" json:"maxTokens" jsonschema:"description=Maximum tokens to use instead of the preset's"`
+	CompactToolResultsAfter *int     `mapstructure:"compactToolResultsAfter" json:"compactToolResultsAfter" jsonschema:"description=Replace tool results older than this many turns with a placeholder instead of following the preset. 0 sends all tool results verbatim"`
+	Compression             string   `mapstructure:"compression" json:"compression" jsonschema:"description=Compression method for older history to use instead of the preset's: none or heuristic or model"`
+	Toolsets                []string `mapstructure:"toolsets" json:"toolsets" jsonschema:"description=Toolsets to add to the preset's"`
 }
 
 // Prompts
 type Prompt struct {
-	Content                string `mapstructure:"content" json:"content" jsonschema:"description=The text content of the prompt"`
+	Content                string `mapstructure:"content" json:"content" jsonschema:"description=The text content of the prompt. file://PATH reads it from a file relative to the config file"`
 	IncludeInSystemMessage bool   `mapstructure:"includeInSystemMessage" json:"includeInSystemMessage" jsonschema:"description=If true, this prompt will be automatically included in all system messages"`
 	SystemMessageTrigger   string `mapstructure:"systemMessageTrigger" json:"systemMessageTrigger" jsonschema:"description=Regex pattern - if matched in user message or history, this prompt will be included in the system message"`
+	SystemMessageCondition string `mapstructure:"systemMessageCondition" json:"systemMessageCondition" jsonschema:"description=Condition on the conversation that includes this prompt in the system message when true. Terms such as toolset:NAME file:go tag:NAME role:tool last:tool and match:REGEX are combined with and/or/not and parentheses. When a trigger is also set both have to match"`
 }
 
 // Toolsets
 type Toolset struct {
 	Servers       map[string]MCPServerToolConfig `mapstructure:"servers" json:"servers"`
-	SystemMessage string                         `mapstructure:"systemMessage" json:"systemMessage" jsonschema:"description=System message to include when this toolset is used"`
+	SystemMessage string                         `mapstructure:"systemMessage" json:"systemMessage" jsonschema:"description=System message to include when this toolset is used. file://PATH reads it from a file relative to the config file"`
+	Slim          SchemaSlimming                 `mapstructure:"slim" json:"slim" jsonschema:"description=Shrink the schemas of every tool in this toolset to use fewer tokens per request"`
+}
+
+// SchemaSlimming limits how much of a tool schema is sent to the model. Zero values
+// keep the schema as the server describes it
+type SchemaSlimming struct {
+	MaxDescription int  `mapstructure:"maxDescription" json:"maxDescription" jsonschema:"description=Cut tool and parameter descriptions to this many characters. 0 keeps them whole"`
+	MaxEnum        int  `mapstructure:"maxEnum" json:"maxEnum" jsonschema:"description=Drop the list of allowed values of parameters with more than this many. 0 keeps every list"`
+	DropOptional   bool `mapstructure:"dropOptional" json:"dropOptional" jsonschema:"description=Leave out every parameter the tool does not require,default=false"`
 }
 
 type MCPServerToolConfig struct {
@@ -54,12 +150,20 @@ type MCPServerToolConfig struct {
 type ToolConfig struct {
 	RequireApproval  bool              `mapstructure:"requireApproval" json:"requireApproval" jsonschema:"description=Whether tools need explicit approval,default=true"`
 	PresetParameters map[string]string `mapstructure:"presetParameters" json:"presetParameters" jsonschema:"description=Pre-configured parameters for this tool. Uses partial function application to send fewer parameters to the LLM."`
+	Description      string            `mapstructure:"description" json:"description" jsonschema:"description=Shorter description to send instead of the one from the server"`
+	DropParameters   []string          `mapstructure:"dropParameters" json:"dropParameters" jsonschema:"description=Optional parameters to leave out of the schema sent to the model"`
 }
 
 // Internal configuration settings
 type Internal struct {
-	Model         string `mapstructure:"model" json:"model" jsonschema:"description=Default model to use for internal llm calls such as summaries,default=claude"`
-	SummaryPrompt string `mapstructure:"summaryPrompt" json:"summaryPrompt" jsonschema:"description=Prompt used for generating conversation summaries"`
+	Model            string `mapstructure:"model" json:"model" jsonschema:"description=Default model to use for internal llm calls such as summaries,default=claude"`
+	SummaryPrompt    string `mapstructure:"summaryPrompt" json:"summaryPrompt" jsonschema:"description=Prompt used for generating conversation summaries"`
+	TranscriptPrompt string `mapstructure:"transcriptPrompt" json:"transcriptPrompt" jsonschema:"description=Prompt used to split a pasted conversation into turns when it has no speaker labels"`
+	PrunePrompt      string `mapstructure:"prunePrompt" json:"prunePrompt" jsonschema:"description=Prompt used to suggest which branches of a thread are safe to prune"`
+	AutoTitle        bool   `mapstructure:"autoTitle" json:"autoTitle" jsonschema:"description=Give threads without a title a title and summary written by the internal model after their first exchange,default=true"`
+	TitlePrompt      string `mapstructure:"titlePrompt" json:"titlePrompt" jsonschema:"description=Prompt used to write the title and summary of a thread. The answer is the title on the first line followed by the summary"`
+	ContextPrompt    string `mapstructure:"contextPrompt" json:"contextPrompt" jsonschema:"description=Prompt used to summarize the older part of long threads for presets with summarize.after set. The summary is sent in place of those messages"`
+	IssuePrompt      string `mapstructure:"issuePrompt" json:"issuePrompt" jsonschema:"description=Prompt used by thread to-issue to turn a thread into an issue. The answer is the title on the first line followed by the body in Markdown"`
 }
 
 // MCP server configuration
@@ -67,7 +171,76 @@ type MCPServer struct {
 	Command       string            `mapstructure:"command" json:"command" jsonschema:"description=Command to run the MCP server"`
 	Args          []string          `mapstructure:"args" json:"args" jsonschema:"description=Command line arguments for the MCP server"`
 	Env           map[string]string `mapstructure:"env" json:"env" jsonschema:"description=Environment variables for the MCP server"`
-	SystemMessage string            `mapstructure:"systemMessage" json:"systemMessage" jsonschema:"description=System message to include when any of this server's tools are used"`
+	Host          string            `mapstructure:"host" json:"host" jsonschema:"description=Remote host to run the command on over SSH such as ssh://user@devbox:22. The command and its environment are run on the host with stdio forwarded back. Empty runs the command locally"`
+	SystemMessage string            `mapstructure:"systemMessage" json:"systemMessage" jsonschema:"description=System message to include when any of this server's tools are used. file://PATH reads it from a file relative to the config file"`
+	IdleTimeout   string            `mapstructure:"idleTimeout" json:"idleTimeout" jsonschema:"description=Stop the server when none of its tools has been called for this long such as 10m. It is started again the next time one of its tools is called. Empty keeps it running"`
+	PingInterval  string            `mapstructure:"pingInterval" json:"pingInterval" jsonschema:"description=How often to check that the server still answers such as 30s. A server that does not answer a ping is restarted. Empty never pings it"`
+}
+
+// Workspace index served by the built-in codebase server
+type Codebase struct {
+	Enabled     bool     `mapstructure:"enabled" json:"enabled" jsonschema:"description=Index the project and offer the codebase server to toolsets"`
+	Root        string   `mapstructure:"root" json:"root" jsonschema:"description=Directory to index. Defaults to the current directory"`
+	ChunkLines  int      `mapstructure:"chunkLines" json:"chunkLines" jsonschema:"description=Number of lines in each searchable chunk of a file,default=60"`
+	MaxFileSize int      `mapstructure:"maxFileSize" json:"maxFileSize" jsonschema:"description=Skip files larger than this many bytes,default=262144"`
+	Exclude     []string `mapstructure:"exclude" json:"exclude" jsonschema:"description=Glob patterns of paths to leave out in addition to those ignored by git"`
+}
+
+// Disposable containers the built-in sandbox server runs code in
+type Sandbox struct {
+	Enabled   bool                       `mapstructure:"enabled" json:"enabled" jsonschema:"description=Offer the sandbox server to toolsets. Its tools always need approval,default=false"`
+	Runtime   string                     `mapstructure:"runtime" json:"runtime" jsonschema:"description=Container runtime that runs the code,default=docker,enum=docker,enum=podman"`
+	CPUs      string                     `mapstructure:"cpus" json:"cpus" jsonschema:"description=CPUs each run may use such as 0.5 or 2,default=1"`
+	Memory    string                     `mapstructure:"memory" json:"memory" jsonschema:"description=Memory each run may use such as 256m or 1g,default=512m"`
+	Timeout   string                     `mapstructure:"timeout" json:"timeout" jsonschema:"description=Stop a run that takes longer than this such as 30s or 2m,default=30s"`
+	Network   bool                       `mapstructure:"network" json:"network" jsonschema:"description=Let the code reach the network. Runs are offline otherwise,default=false"`
+	Languages map[string]SandboxLanguage `mapstructure:"languages" json:"languages" jsonschema:"description=Languages the model can write code in by name"`
+}
+
+// How code in one language is run in the sandbox
+type SandboxLanguage struct {
+	Image   string   `mapstructure:"image" json:"image" jsonschema:"description=Container image the code runs in such as python:3.12-slim"`
+	File    string   `mapstructure:"file" json:"file" jsonschema:"description=Name of the file the code is written to in the working directory such as main.py"`
+	Command []string `mapstructure:"command" json:"command" jsonschema:"description=Command that runs the file from the working directory such as python main.py"`
+}
+
+// Defaults for a project, found from the .slop directory in the current directory or
+// the closest parent directory that has one
+type Project struct {
+	Name     string   `mapstructure:"name" json:"name" jsonschema:"description=Name shown by slop config and in the TUI status bar. Defaults to the name of the project directory"`
+	Preset   string   `mapstructure:"preset" json:"preset" jsonschema:"description=Preset used in the project in place of defaultPreset"`
+	Toolsets []string `mapstructure:"toolsets" json:"toolsets" jsonschema:"description=Toolsets added to every preset in the project such as git and filesystem"`
+}
+
+// How replies are written
+type Style struct {
+	Language string `mapstructure:"language" json:"language" jsonschema:"description=Language replies are written in regardless of the language of the message. A code such as fr or a name such as Brazilian Portuguese. Threads can override it with slop thread set-lang. Empty replies in whatever language fits"`
+}
+
+// HTTP server settings
+type Serve struct {
+	Address  string             `mapstructure:"address" json:"address" jsonschema:"description=Address the server listens on,default=127.0.0.1:7878"`
+	Webhooks map[string]Webhook `mapstructure:"webhooks" json:"webhooks" jsonschema:"description=Inbound webhooks that start a conversation with the payload they receive"`
+	Token    string             `mapstructure:"token" json:"token" jsonschema:"description=Bearer token required by the /api endpoints. Leave empty to allow any local client"`
+}
+
+// Issue tracker settings for thread to-issue
+type Issues struct {
+	Provider string   `mapstructure:"provider" json:"provider" jsonschema:"description=Issue tracker API to use,default=github,enum=github,enum=gitlab"`
+	Repo     string   `mapstructure:"repo" json:"repo" jsonschema:"description=Repository issues are created in as owner/name when --repo isn't given. GitLab accepts nested groups such as group/subgroup/name"`
+	BaseURL  string   `mapstructure:"baseURL" json:"baseURL" jsonschema:"description=Address of the API for GitHub Enterprise or a self-hosted GitLab such as https://gitlab.example.com/api/v4. Empty uses https://api.github.com or https://gitlab.com/api/v4"`
+	Token    string   `mapstructure:"token" json:"token" jsonschema:"description=API token allowed to create issues. Leave empty to read it from tokenEnv"`
+	TokenEnv string   `mapstructure:"tokenEnv" json:"tokenEnv" jsonschema:"description=Environment variable holding the API token when token is empty. Empty uses GITHUB_TOKEN or GITLAB_TOKEN"`
+	Labels   []string `mapstructure:"labels" json:"labels" jsonschema:"description=Labels added to every issue"`
+}
+
+// An inbound webhook. A POST to its path starts a new thread with the rendered prompt
+type Webhook struct {
+	Path      string `mapstructure:"path" json:"path" jsonschema:"description=URL path the webhook listens on such as /hooks/ci"`
+	Secret    string `mapstructure:"secret" json:"secret" jsonschema:"description=Shared secret. Requests must carry the hex sha256 HMAC of their body in an X-Slop-Signature or X-Hub-Signature-256 header or the secret itself as an Authorization Bearer token"`
+	Preset    string `mapstructure:"preset" json:"preset" jsonschema:"description=Preset that answers the webhook. Defaults to defaultPreset"`
+	Prompt    string `mapstructure:"prompt" json:"prompt" jsonschema:"description=Template of the message sent to the model. The JSON payload is the template data such as {{.alert.name}} and {{json .}} prints the whole payload. Empty sends the payload as is"`
+	DeliverTo string `mapstructure:"deliverTo" json:"deliverTo" jsonschema:"description=URL the result is POSTed to as JSON when the run finishes. Signed like inbound requests"`
 }
 
 // Logging configuration
@@ -75,3 +248,19 @@ type Log struct {
 	LogLevel string `mapstructure:"logLevel" json:"logLevel" jsonschema:"description=Log level (DEBUG, INFO, WARN, ERROR),default=INFO,enum=DEBUG,enum=INFO,enum=WARN,enum=ERROR"`
 	LogFile  string `mapstructure:"logFile" json:"logFile" jsonschema:"description=Log file path, empty for stdout,default="`
 }
+
+// TUI theme. Any color left empty falls back to the value from the named built-in theme
+type Theme struct {
+	Name       string `mapstructure:"name" json:"name" jsonschema:"description=Built-in theme to start from,default=dark,enum=dark,enum=light,enum=solarized"`
+	Accent     string `mapstructure:"accent" json:"accent" jsonschema:"description=Accent color used for titles and highlights"`
+	AccentText string `mapstructure:"accentText" json:"accentText" jsonschema:"description=Text color used on top of the accent color"`
+	Border     string `mapstructure:"border" json:"border" jsonschema:"description=Border color for panels and inputs"`
+	Text       string `mapstructure:"text" json:"text" jsonschema:"description=Default text color"`
+	Muted      string `mapstructure:"muted" json:"muted" jsonschema:"description=Color for secondary text"`
+	Human      string `mapstructure:"human" json:"human" jsonschema:"description=Color for your messages"`
+	Assistant  string `mapstructure:"assistant" json:"assistant" jsonschema:"description=Color for assistant messages"`
+	Tool       string `mapstructure:"tool" json:"tool" jsonschema:"description=Color for tool calls and results"`
+	System     string `mapstructure:"system" json:"system" jsonschema:"description=Color for system messages"`
+	Warning    string `mapstructure:"warning" json:"warning" jsonschema:"description=Color for values getting close to a limit"`
+	Danger     string `mapstructure:"danger" json:"danger" jsonschema:"description=Color for values at or over a limit"`
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// deprecatedKeys maps keys that are no longer used to the keys that replaced them
+var deprecatedKeys = map[string]string{
+	"models": "presets",
+}
+
+// Warnings returns problems found while loading the configuration, such as
+// unknown keys, deprecated keys and values overridden by another file
+func (s *ConfigSchema) Warnings() []string {
+	return s.warnings
+}
+
+// checkSettings records warnings for flattened settings from a config file before
+// they are merged. previousSources are the sources of settings merged so far
+func (c *Config) checkSettings(flat map[string]any, source string, previousSources map[string]string) {
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		path := strings.Split(key, ".")
+
+		if replacement, ok := deprecatedKeys[strings.ToLower(path[0])]; ok {
+			c.warnings = append(c.warnings, fmt.Sprintf("deprecated key %q in %s, use %q instead", key, source, replacement))
+			continue
+		}
+
+		if !isKnownKey(reflect.TypeOf(ConfigSchema{}), path) {
+			c.warnings = append(c.warnings, fmt.Sprintf("unknown key %q in %s", key, source))
+			continue
+		}
+
+		previous, ok := previousSources[key]
+		if !ok || previous == "default" || previous == source {
+			continue
+		}
+		// Lists are combined, or replaced on purpose with a merge strategy
+		if _, isList := flat[key].([]any); isList {
+			continue
+		}
+		if !reflect.DeepEqual(c.v.Get(key), flat[key]) {
+			c.warnings = append(c.warnings, fmt.Sprintf("%q from %s is overridden by %s", key, previous, source))
+		}
+	}
+}
+
+// isKnownKey reports whether a flattened key path exists in the schema type t.
+// Map keys are free form, and lists are treated as single values
+func isKnownKey(t reflect.Type, path []string) bool {
+	if len(path) == 0 {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := range t.NumField() {
+			field := t.Field(i)
+			tag := field.Tag.Get("mapstructure")
+			if field.IsExported() && tag != "" && strings.EqualFold(tag, path[0]) {
+				return isKnownKey(field.Type, path[1:])
+			}
+		}
+		return false
+
+	case reflect.Map:
+		return isKnownKey(t.Elem(), path[1:])
+
+	default:
+		return false
+	}
+}
@@ -0,0 +1,121 @@
+// Package document keeps the outline of a long document the model writes section by
+// section over several turns, and assembles the document from it
+package document
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/artifact"
+	"github.com/isaacphi/slop/internal/repository"
+)
+
+// ArtifactName is the name of the artifacts holding the outline of a thread. Every
+// change stores a new version, the newest is the current outline
+const ArtifactName = "outline.json"
+
+// Status is how far a section has come
+type Status string
+
+const (
+	StatusPlanned  Status = "planned"  // Outlined but not written yet
+	StatusDraft    Status = "draft"    // Written, waiting for review
+	StatusApproved Status = "approved" // Reviewed and part of the exported document
+)
+
+// Outline is the structure of a document and the sections written so far
+type Outline struct {
+	Title    string    `json:"title"`
+	Sections []Section `json:"sections"`
+}
+
+// Section is one part of the document
+type Section struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Summary string `json:"summary,omitempty"` // What the section is meant to cover
+	Content string `json:"content,omitempty"`
+	Status  Status `json:"status"`
+}
+
+// Section returns the section with an ID, nil if there is none
+func (o *Outline) Section(id string) *Section {
+	for i := range o.Sections {
+		if o.Sections[i].ID == id {
+			return &o.Sections[i]
+		}
+	}
+	return nil
+}
+
+// Load returns the current outline of a thread, nil if the thread has none
+func Load(ctx context.Context, repo repository.MessageRepository, threadID uuid.UUID) (*Outline, error) {
+	artifacts, err := repo.ListArtifacts(ctx, &threadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	for _, a := range artifacts {
+		if a.Name != ArtifactName {
+			continue
+		}
+		_, content, err := repo.GetArtifact(ctx, a.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the outline: %w", err)
+		}
+		var outline Outline
+		if err := json.Unmarshal(content, &outline); err != nil {
+			return nil, fmt.Errorf("failed to decode the outline: %w", err)
+		}
+		return &outline, nil
+	}
+	return nil, nil
+}
+
+// Save stores a new version of the outline of a thread
+func Save(ctx context.Context, repo repository.MessageRepository, threadID uuid.UUID, outline *Outline) error {
+	encoded, err := json.MarshalIndent(outline, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the outline: %w", err)
+	}
+	_, err = artifact.Store(ctx, repo, threadID, nil, ArtifactName, string(encoded))
+	return err
+}
+
+// Assemble joins the sections into a markdown document in outline order. Only
+// approved sections are included unless drafts is set, in which case every written
+// section is
+func (o *Outline) Assemble(drafts bool) string {
+	var b strings.Builder
+	if o.Title != "" {
+		fmt.Fprintf(&b, "# %s\n\n", o.Title)
+	}
+	for _, section := range o.Sections {
+		if section.Content == "" || (section.Status != StatusApproved && !drafts) {
+			continue
+		}
+		fmt.Fprintf(&b, "## %s\n\n%s\n\n", section.Title, strings.TrimSpace(section.Content))
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// Describe summarizes the outline for the model, one line per section without the
+// contents
+func (o *Outline) Describe() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Document %q with %d sections:\n", o.Title, len(o.Sections))
+	for _, section := range o.Sections {
+		fmt.Fprintf(&b, "- %s %q (%s", section.ID, section.Title, section.Status)
+		if section.Content != "" {
+			fmt.Fprintf(&b, ", %d words", len(strings.Fields(section.Content)))
+		}
+		b.WriteString(")")
+		if section.Summary != "" {
+			fmt.Fprintf(&b, ": %s", section.Summary)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
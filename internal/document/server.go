@@ -0,0 +1,218 @@
+package document
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/repository"
+)
+
+// ServerName is the name toolsets use for the built-in document server
+const ServerName = "document"
+
+// Server offers tools to plan a document and write it section by section. The outline
+// belongs to the thread a call was made in. The repository is opened on the first call
+type Server struct {
+	open func() (repository.MessageRepository, error)
+	mu   sync.Mutex
+	repo repository.MessageRepository
+}
+
+// NewServer creates the built-in server. open is called once, when a tool is first used
+func NewServer(open func() (repository.MessageRepository, error)) *Server {
+	return &Server{open: open}
+}
+
+// Tools describes the tools of the server
+func (s *Server) Tools() map[string]domain.Tool {
+	return map[string]domain.Tool{
+		"get_outline": {
+			Name:        "get_outline",
+			Description: "Show the outline of the document being written in this conversation with the status of each section. Give a section ID to read what has been written for it",
+			Parameters: domain.Parameters{
+				Type: "object",
+				Properties: map[string]domain.Property{
+					"section": {Type: "string", Description: "ID of a section to read"},
+				},
+			},
+		},
+		"set_outline": {
+			Name:        "set_outline",
+			Description: "Plan or restructure the document. The sections given replace the outline in this order. Sections that keep their ID keep what was written for them and sections left out are dropped",
+			Parameters: domain.Parameters{
+				Type: "object",
+				Properties: map[string]domain.Property{
+					"title": {Type: "string", Description: "Title of the document"},
+					"sections": {
+						Type:        "array",
+						Description: "Sections of the document in order",
+						Items: &domain.Property{
+							Type: "object",
+							Properties: map[string]domain.Property{
+								"id":      {Type: "string", Description: "Short stable identifier such as intro or methods. Made from the title when left out"},
+								"title":   {Type: "string", Description: "Heading of the section"},
+								"summary": {Type: "string", Description: "What the section will cover"},
+							},
+							Required: []string{"title"},
+						},
+					},
+				},
+				Required: []string{"sections"},
+			},
+		},
+		"write_section": {
+			Name:        "write_section",
+			Description: "Write or rewrite the content of a section of the outline in markdown without its heading. The section becomes a draft until the user approves it",
+			Parameters: domain.Parameters{
+				Type: "object",
+				Properties: map[string]domain.Property{
+					"id":      {Type: "string", Description: "ID of the section"},
+					"content": {Type: "string", Description: "Content of the section"},
+				},
+				Required: []string{"id", "content"},
+			},
+		},
+	}
+}
+
+// CallTool runs one of the server's tools
+func (s *Server) CallTool(ctx context.Context, toolName string, arguments map[string]any) (string, error) {
+	threadID, ok := mcp.ThreadFrom(ctx)
+	if !ok {
+		return "", fmt.Errorf("%s can only be called from a conversation", toolName)
+	}
+	repo, err := s.repository()
+	if err != nil {
+		return "", err
+	}
+
+	// Calls of one conversation could otherwise overwrite each other's changes
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	outline, err := Load(ctx, repo, threadID)
+	if err != nil {
+		return "", err
+	}
+
+	switch toolName {
+	case "get_outline":
+		if outline == nil {
+			return "There is no outline yet, plan the document with set_outline", nil
+		}
+		id, _ := arguments["section"].(string)
+		if id == "" {
+			return outline.Describe(), nil
+		}
+		section := outline.Section(id)
+		if section == nil {
+			return "", fmt.Errorf("there is no section %q", id)
+		}
+		if section.Content == "" {
+			return fmt.Sprintf("Section %s has not been written yet", id), nil
+		}
+		return section.Content, nil
+
+	case "set_outline":
+		updated, err := restructure(outline, arguments)
+		if err != nil {
+			return "", err
+		}
+		if err := Save(ctx, repo, threadID, updated); err != nil {
+			return "", err
+		}
+		return "Outline saved. " + updated.Describe(), nil
+
+	case "write_section":
+		if outline == nil {
+			return "", fmt.Errorf("plan the document with set_outline first")
+		}
+		id, _ := arguments["id"].(string)
+		content, _ := arguments["content"].(string)
+		section := outline.Section(id)
+		if section == nil {
+			return "", fmt.Errorf("there is no section %q, add it with set_outline first", id)
+		}
+		if strings.TrimSpace(content) == "" {
+			return "", fmt.Errorf("content is required")
+		}
+		section.Content = content
+		section.Status = StatusDraft
+		if err := Save(ctx, repo, threadID, outline); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Section %s saved as a draft with %d words", id, len(strings.Fields(content))), nil
+	}
+	return "", fmt.Errorf("tool %s not found in server %s", toolName, ServerName)
+}
+
+// restructure builds the outline given to set_outline, keeping the content and
+// status of sections that are still in it
+func restructure(current *Outline, arguments map[string]any) (*Outline, error) {
+	items, _ := arguments["sections"].([]any)
+	if len(items) == 0 {
+		return nil, fmt.Errorf("give at least one section")
+	}
+
+	updated := &Outline{}
+	if current != nil {
+		updated.Title = current.Title
+	}
+	if title, _ := arguments["title"].(string); title != "" {
+		updated.Title = title
+	}
+	for i, item := range items {
+		fields, _ := item.(map[string]any)
+		title, _ := fields["title"].(string)
+		id, _ := fields["id"].(string)
+		summary, _ := fields["summary"].(string)
+		if strings.TrimSpace(title) == "" {
+			return nil, fmt.Errorf("every section needs a title")
+		}
+		if id == "" {
+			id = slug(title)
+		}
+		if id == "" {
+			id = fmt.Sprintf("section-%d", i+1)
+		}
+		if updated.Section(id) != nil {
+			return nil, fmt.Errorf("section ID %q is used twice", id)
+		}
+
+		section := Section{ID: id, Title: title, Summary: summary, Status: StatusPlanned}
+		if current != nil {
+			if existing := current.Section(id); existing != nil {
+				section.Content = existing.Content
+				section.Status = existing.Status
+			}
+		}
+		updated.Sections = append(updated.Sections, section)
+	}
+	return updated, nil
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug makes a section ID from a title, such as related-work from Related Work
+func slug(title string) string {
+	return strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(title), "-"), "-")
+}
+
+func (s *Server) repository() (repository.MessageRepository, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.repo == nil {
+		repo, err := s.open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the database: %w", err)
+		}
+		s.repo = repo
+	}
+	return s.repo, nil
+}
@@ -1,6 +1,11 @@
 package domain
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -15,12 +20,56 @@ const (
 )
 
 type Thread struct {
-	ID       uuid.UUID `gorm:"type:uuid;primary_key"`
-	Summary  string    `gorm:"type:text"`
-	Messages []Message `gorm:"foreignKey:ThreadID"`
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key"`
+	Title      string     `gorm:"type:text"` // Short name shown in lists of threads
+	Summary    string     `gorm:"type:text"`
+	Messages   []Message  `gorm:"foreignKey:ThreadID"`
+	LastReadAt *time.Time // When the thread was last viewed, nil if never
+	PinModel   bool       // Fail instead of continuing with a different model version than earlier replies
+	Language   string     // Language replies are written in, empty for the configured default
+	Privacy    string     // What may leave the machine: normal, local-only or redacted. Empty is normal
+	ArchivedAt *time.Time // When the thread was archived, nil if it is active
+	// Message whose branch is shown and continued by default, nil follows the newest message
+	ActiveMessageID *uuid.UUID `gorm:"type:uuid"`
 	gorm.Model
 }
 
+// Privacy levels of a thread
+const (
+	// PrivacyNormal sends messages to the provider of any preset
+	PrivacyNormal = "normal"
+	// PrivacyLocalOnly only sends messages to presets that run on this machine
+	PrivacyLocalOnly = "local-only"
+	// PrivacyRedacted masks secrets in everything sent, whatever the preset
+	PrivacyRedacted = "redacted"
+)
+
+// ThreadTag labels a thread so it can be found and managed along with others
+type ThreadTag struct {
+	ThreadID  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Tag       string    `gorm:"type:text;primaryKey;index"`
+	CreatedAt time.Time
+}
+
+// Label returns the title of the thread, or its summary when it has no title
+func (t Thread) Label() string {
+	if t.Title != "" {
+		return t.Title
+	}
+	return t.Summary
+}
+
+// IsUnread reports whether the latest assistant message in messages arrived after
+// the thread was last viewed
+func (t Thread) IsUnread(messages []Message) bool {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == RoleAssistant {
+			return t.LastReadAt == nil || messages[i].CreatedAt.After(*t.LastReadAt)
+		}
+	}
+	return false
+}
+
 type Message struct {
 	ID       uuid.UUID `gorm:"type:uuid;primary_key"`
 	ThreadID uuid.UUID `gorm:"type:uuid;index"`
@@ -30,11 +79,418 @@ type Message struct {
 	Parent   *Message   `gorm:"foreignKey:ParentID"`
 	Children []Message  `gorm:"foreignKey:ParentID"`
 
-	Role      Role   `gorm:"type:text"`
-	Content   string `gorm:"type:text"`
-	ToolCalls string `gorm:"type:text"`
-	ModelName string `gorm:"type:text"`
-	Provider  string `gorm:"type:text"`
+	Role         Role   `gorm:"type:text"`
+	Content      string `gorm:"type:text"`
+	Parts        string `gorm:"type:text"` // JSON encoded []MessagePart when the message was composed from several parts
+	ToolCalls    string `gorm:"type:text"`
+	ModelName    string `gorm:"type:text"`
+	Provider     string `gorm:"type:text"`
+	ModelVersion string `gorm:"type:text"` // Exact model version reported by the provider, ModelName is the requested model
+	Metadata     string `gorm:"type:text"` // JSON encoded MessageMetadata
+	// Tokens the provider reported for the request and the response, 0 when unknown
+	InputTokens  int
+	OutputTokens int
+	// Files sent with the message, saved along with it. Their contents are parts of the message
+	Attachments []Attachment `gorm:"foreignKey:MessageID"`
+	gorm.Model
+}
+
+// MessageMetadata holds details about how a message was produced that are kept for
+// inspection but never sent to the model
+type MessageMetadata struct {
+	Draft   string `json:"draft,omitempty"`   // Response before the reflection pass revised it
+	Partial bool   `json:"partial,omitempty"` // Response was cut off by a timeout before it finished
+	// Connection to the provider dropped mid response and the rest was requested again
+	Recovered bool `json:"recovered,omitempty"`
+	// Response was saved while it streamed and the process stopped before it finished
+	Incomplete bool `json:"incomplete,omitempty"`
+	// Time each tool call of a tool result message took, by call ID
+	ToolDurations map[string]time.Duration `json:"toolDurations,omitempty"`
+	Confidence    *Confidence              `json:"confidence,omitempty"` // The model's rating of its own response
+	// Summary of the tool calls made in the turn before this response. The tool calls
+	// and results themselves are marked Summarized and left out of later requests
+	ToolSummary string `json:"toolSummary,omitempty"`
+	Summarized  bool   `json:"summarized,omitempty"`
+	// Preset chosen for this reply only with --once-model. Such replies don't decide
+	// the model a pinned thread keeps to
+	OncePreset string `json:"oncePreset,omitempty"`
+	// Preset that answered instead of the thread's preset because the provider was
+	// rate limiting or overloaded. Such replies don't decide the model a pinned thread
+	// keeps to either
+	Fallback string `json:"fallback,omitempty"`
+	// Summary of the conversation up to and including this message, written by the
+	// internal model to be sent in place of those messages in later requests
+	ContextSummary string `json:"contextSummary,omitempty"`
+	// Time the provider took to generate the response, from sending the request until
+	// it completed. Not set for responses from the cache
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// Confidence is how sure the model is of a response and what it assumed to give it
+type Confidence struct {
+	Level       string   `json:"level"` // high, medium or low
+	Assumptions []string `json:"assumptions,omitempty"`
+}
+
+// Footer describes the rating on one line to show below the response
+func (c Confidence) Footer() string {
+	footer := "Confidence: " + c.Level
+	if len(c.Assumptions) > 0 {
+		footer += " · Assumes: " + strings.Join(c.Assumptions, "; ")
+	}
+	return footer
+}
+
+// SetMetadata stores metadata on the message
+func (m *Message) SetMetadata(metadata MessageMetadata) error {
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode message metadata: %w", err)
+	}
+	m.Metadata = string(encoded)
+	return nil
+}
+
+// GetMetadata returns the metadata of the message
+func (m Message) GetMetadata() (MessageMetadata, error) {
+	var metadata MessageMetadata
+	if m.Metadata == "" {
+		return metadata, nil
+	}
+	if err := json.Unmarshal([]byte(m.Metadata), &metadata); err != nil {
+		return metadata, fmt.Errorf("failed to decode message metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// MessagePart is one part of a message composed from several parts
+type MessagePart struct {
+	Source  string `json:"source,omitempty"` // File the part was read from, if any
+	Content string `json:"content"`
+	// Parts for binary content such as images have a text description as their content
+	// and refer to the artifact holding the data
+	Artifact string `json:"artifact,omitempty"` // Hash of the artifact
+	MimeType string `json:"mimeType,omitempty"`
+	URL      string `json:"url,omitempty"` // Address of an image the provider fetches itself, instead of an artifact
+}
+
+// IsImage reports whether the part is an image attachment
+func (p MessagePart) IsImage() bool {
+	return (p.Artifact != "" || p.URL != "") && strings.HasPrefix(p.MimeType, "image/")
+}
+
+// PartSeparator joins message parts into the message content
+const PartSeparator = "\n\n"
+
+// SetParts stores parts on the message and sets its content to the joined parts
+func (m *Message) SetParts(parts []MessagePart) error {
+	encoded, err := json.Marshal(parts)
+	if err != nil {
+		return fmt.Errorf("failed to encode message parts: %w", err)
+	}
+	contents := make([]string, len(parts))
+	for i, part := range parts {
+		contents[i] = part.Content
+	}
+	m.Parts = string(encoded)
+	m.Content = strings.Join(contents, PartSeparator)
+	return nil
+}
+
+// GetParts returns the parts of the message. Messages that weren't composed from
+// parts have a single part with their content
+func (m Message) GetParts() ([]MessagePart, error) {
+	if m.Parts == "" {
+		return []MessagePart{{Content: m.Content}}, nil
+	}
+	var parts []MessagePart
+	if err := json.Unmarshal([]byte(m.Parts), &parts); err != nil {
+		return nil, fmt.Errorf("failed to decode message parts: %w", err)
+	}
+	return parts, nil
+}
+
+// QueuedMessage is a human message that has been saved to a thread but could not be
+// sent because the provider was unreachable
+type QueuedMessage struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key"`
+	ThreadID      uuid.UUID `gorm:"type:uuid;index"`
+	MessageID     uuid.UUID `gorm:"type:uuid;index"`
+	Preset        string    `gorm:"type:text"`
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string `gorm:"type:text"`
+	gorm.Model
+}
+
+// Evaluation is a score given to a thread by the internal model against a rubric
+type Evaluation struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
+	ThreadID  uuid.UUID `gorm:"type:uuid;index"`
+	Rubric    string    `gorm:"type:text"`
+	Scores    string    `gorm:"type:text"` // JSON encoded score for each criterion
+	Score     float64   // Overall score normalized to 0-1
+	ModelName string    `gorm:"type:text"`
+	Provider  string    `gorm:"type:text"`
+	gorm.Model
+}
+
+// ToolStat tracks how reliable an MCP tool has been across all threads
+type ToolStat struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key"`
+	ServerName     string    `gorm:"type:text;uniqueIndex:idx_tool_stats_server_tool"`
+	ToolName       string    `gorm:"type:text;uniqueIndex:idx_tool_stats_server_tool"`
+	Successes      int
+	Failures       int
+	TotalLatencyMs int64
+	LastError      string `gorm:"type:text"`
+	LastErrorAt    *time.Time
+	gorm.Model
+}
+
+// Calls returns the number of recorded calls
+func (s ToolStat) Calls() int {
+	return s.Successes + s.Failures
+}
+
+// FailureRate returns the fraction of calls that failed
+func (s ToolStat) FailureRate() float64 {
+	if s.Calls() == 0 {
+		return 0
+	}
+	return float64(s.Failures) / float64(s.Calls())
+}
+
+// AverageLatency returns the mean duration of a call
+func (s ToolStat) AverageLatency() time.Duration {
+	if s.Calls() == 0 {
+		return 0
+	}
+	return time.Duration(s.TotalLatencyMs/int64(s.Calls())) * time.Millisecond
+}
+
+// Attachment records a file sent with a message. The file's contents are a part of
+// the message, the attachment keeps where they came from
+type Attachment struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
+	MessageID uuid.UUID `gorm:"type:uuid;index"`
+	Name      string    `gorm:"type:text"` // Path of the file as it was given
+	Size      int
+	Hash      string `gorm:"type:text"` // Hex encoded sha256 of the contents
+	gorm.Model
+}
+
+// Artifact is a piece of large generated content, such as a code block or a long tool
+// result, referenced from a message. The content itself is stored once per hash in
+// ArtifactBlob so identical content is only kept once
+type Artifact struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key"`
+	Hash      string     `gorm:"type:text;index"` // Hex encoded sha256 of the content
+	ThreadID  uuid.UUID  `gorm:"type:uuid;index"`
+	MessageID *uuid.UUID `gorm:"type:uuid;index"`
+	Name      string     `gorm:"type:text"`
+	Size      int
+	gorm.Model
+}
+
+// ArtifactBlob holds the content of every artifact with the same hash
+type ArtifactBlob struct {
+	Hash      string `gorm:"type:text;primaryKey"`
+	Content   []byte
+	CreatedAt time.Time
+}
+
+// RunStatus is the state of an agent run
+type RunStatus string
+
+const (
+	RunStatusRunning     RunStatus = "running"     // In progress, or the process stopped without recording why
+	RunStatusInterrupted RunStatus = "interrupted" // Cancelled before it finished
+	RunStatusApproval    RunStatus = "approval"    // Stopped to wait for tool calls to be approved
+	RunStatusFailed      RunStatus = "failed"
+	RunStatusDone        RunStatus = "done"
+)
+
+// RunStep is what a run does next with its current message
+type RunStep string
+
+const (
+	RunStepGenerate RunStep = "generate" // Get a response to the current message
+	RunStepTools    RunStep = "tools"    // Execute the tool calls of the current message
+)
+
+// Run records the progress of the agent loop for one turn so a turn that was
+// interrupted can be resumed instead of started over
+type Run struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key"`
+	ThreadID    uuid.UUID `gorm:"type:uuid;index"`
+	MessageID   uuid.UUID `gorm:"type:uuid"` // Message the loop continues from
+	Step        RunStep   `gorm:"type:text"`
+	Status      RunStatus `gorm:"type:text;index"`
+	Preset      string    `gorm:"type:text"` // JSON encoded preset the run was started with
+	ToolResults string    `gorm:"type:text"` // JSON encoded []RunToolResult of the tool calls of MessageID that already finished
+	Error       string    `gorm:"type:text"`
+	gorm.Model
+}
+
+// RunToolResult is the outcome of a tool call that finished before a run was interrupted
+type RunToolResult struct {
+	CallID string `json:"callId"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Resumable reports whether the run can be continued
+func (r Run) Resumable() bool {
+	return r.Status == RunStatusRunning || r.Status == RunStatusInterrupted || r.Status == RunStatusFailed
+}
+
+// SetToolResults stores the finished tool calls of the run
+func (r *Run) SetToolResults(results []RunToolResult) error {
+	if len(results) == 0 {
+		r.ToolResults = ""
+		return nil
+	}
+	encoded, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("failed to encode tool results: %w", err)
+	}
+	r.ToolResults = string(encoded)
+	return nil
+}
+
+// GetToolResults returns the finished tool calls of the run
+func (r Run) GetToolResults() ([]RunToolResult, error) {
+	var results []RunToolResult
+	if r.ToolResults == "" {
+		return results, nil
+	}
+	if err := json.Unmarshal([]byte(r.ToolResults), &results); err != nil {
+		return nil, fmt.Errorf("failed to decode tool results: %w", err)
+	}
+	return results, nil
+}
+
+// BatchJobStatus is the state of a batch submitted to a provider
+type BatchJobStatus string
+
+const (
+	BatchJobSubmitted BatchJobStatus = "submitted" // Waiting for the provider to finish
+	BatchJobDone      BatchJobStatus = "done"      // Results were added to their threads
+	BatchJobFailed    BatchJobStatus = "failed"
+)
+
+// BatchJob is a set of queued messages submitted to a provider's batch API. Replies
+// are added to their threads once the provider finishes the batch
+type BatchJob struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primary_key"`
+	Preset      string         `gorm:"type:text"`
+	ModelName   string         `gorm:"type:text"`
+	Provider    string         `gorm:"type:text"`
+	ExternalID  string         `gorm:"type:text"` // ID of the batch at the provider
+	Status      BatchJobStatus `gorm:"type:text;index"`
+	Requests    string         `gorm:"type:text"` // JSON encoded []BatchJobRequest
+	Error       string         `gorm:"type:text"`
+	CompletedAt *time.Time
+	gorm.Model
+}
+
+// BatchJobRequest is a message in a batch that gets a reply
+type BatchJobRequest struct {
+	ThreadID  uuid.UUID `json:"threadId"`
+	MessageID uuid.UUID `json:"messageId"`
+}
+
+// SetRequests stores the messages of the batch
+func (b *BatchJob) SetRequests(requests []BatchJobRequest) error {
+	encoded, err := json.Marshal(requests)
+	if err != nil {
+		return fmt.Errorf("failed to encode batch requests: %w", err)
+	}
+	b.Requests = string(encoded)
+	return nil
+}
+
+// GetRequests returns the messages of the batch
+func (b BatchJob) GetRequests() ([]BatchJobRequest, error) {
+	var requests []BatchJobRequest
+	if b.Requests == "" {
+		return requests, nil
+	}
+	if err := json.Unmarshal([]byte(b.Requests), &requests); err != nil {
+		return nil, fmt.Errorf("failed to decode batch requests: %w", err)
+	}
+	return requests, nil
+}
+
+// IndexedFile is a file in the workspace index
+type IndexedFile struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
+	Root      string    `gorm:"type:text;index"` // Absolute path of the indexed directory
+	Path      string    `gorm:"type:text"`       // Relative to Root with forward slashes
+	Size      int64
+	ModTime   time.Time
+	Lines     int
+	IndexedAt time.Time
+}
+
+// IndexedChunk is a range of lines of an indexed file and the hashed vector of its
+// terms used to find it by the words it shares with a query
+type IndexedChunk struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key"`
+	FileID     uuid.UUID `gorm:"type:uuid;index"`
+	Position   int       // Position of the chunk in the file, starting at 0
+	StartLine  int       // First line of the chunk, starting at 1
+	EndLine    int       // Last line of the chunk, inclusive
+	TermVector []byte    // Little endian float32 vector made by hashing the terms of the chunk
+}
+
+// CachedResponse is a model response stored so an identical request can be answered
+// without calling the provider again
+type CachedResponse struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key"`
+	Key          string    `gorm:"type:text;uniqueIndex"` // Hash of everything sent in the request
+	Provider     string    `gorm:"type:text"`
+	ModelName    string    `gorm:"type:text"`
+	Content      string    `gorm:"type:text"`
+	ToolCalls    string    `gorm:"type:text"`
+	ModelVersion string    `gorm:"type:text"` // Model version reported by the provider
+	Hits         int       // Number of times the response was reused
+	Stores       int       // Number of times the response was requested from the provider
+	ExpiresAt    time.Time `gorm:"index"`
+	gorm.Model
+}
+
+// ApprovalScope is how widely a remembered tool approval applies
+type ApprovalScope string
+
+const (
+	ApprovalScopeThread  ApprovalScope = "thread"  // Later calls in the same thread
+	ApprovalScopeSession ApprovalScope = "session" // Later calls made by the same slop process
+)
+
+// ToolApproval is a remembered decision to run a tool without asking again
+type ToolApproval struct {
+	ID              uuid.UUID     `gorm:"type:uuid;primary_key"`
+	Scope           ApprovalScope `gorm:"type:text"`
+	ThreadID        uuid.UUID     `gorm:"type:uuid;index"` // Thread the approval was given in
+	SessionID       *uuid.UUID    `gorm:"type:uuid;index"` // Process the approval applies to, nil for thread approvals
+	Tool            string        `gorm:"type:text"`       // server__tool name as called by the model
+	ArgumentPattern string        `gorm:"type:text"`       // Glob matched against the JSON encoded arguments, * allows any
+	gorm.Model
+}
+
+// ToolRejection records a tool call the user rejected, kept to find out which tools
+// and arguments get rejected and why
+type ToolRejection struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key"`
+	ThreadID      uuid.UUID `gorm:"type:uuid;index"` // Thread the call was made in
+	MessageID     uuid.UUID `gorm:"type:uuid"`       // Assistant message that made the call
+	CallID        string    `gorm:"type:text"`
+	Tool          string    `gorm:"type:text;index"` // server__tool name as called by the model
+	Arguments     string    `gorm:"type:text"`       // JSON encoded arguments with their keys sorted
+	ArgumentsHash string    `gorm:"type:text"`       // SHA-256 of Arguments, the same for calls with the same arguments
+	Reason        string    `gorm:"type:text"`       // Reason the user gave, empty if none
 	gorm.Model
 }
 
@@ -51,3 +507,87 @@ func (m *Message) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	return
 }
+
+func (q *QueuedMessage) BeforeCreate(tx *gorm.DB) (err error) {
+	if q.ID == uuid.Nil {
+		q.ID = uuid.New()
+	}
+	return
+}
+
+func (e *Evaluation) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return
+}
+
+func (s *ToolStat) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return
+}
+
+func (a *Attachment) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return
+}
+
+func (a *Artifact) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return
+}
+
+func (r *Run) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return
+}
+
+func (b *BatchJob) BeforeCreate(tx *gorm.DB) (err error) {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return
+}
+
+func (f *IndexedFile) BeforeCreate(tx *gorm.DB) (err error) {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return
+}
+
+func (c *IndexedChunk) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return
+}
+
+func (c *CachedResponse) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return
+}
+
+func (a *ToolApproval) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return
+}
+
+func (r *ToolRejection) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,118 @@
+// Package errkind sorts errors into categories that the CLI and TUI can give
+// targeted advice for
+package errkind
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Kind is the category of an error
+type Kind string
+
+const (
+	Unknown          Kind = ""
+	RateLimit        Kind = "provider-rate-limit"
+	Auth             Kind = "provider-auth"
+	ToolNotFound     Kind = "tool-not-found"
+	ApprovalRequired Kind = "approval-required"
+	DBBusy           Kind = "db-busy"
+	Privacy          Kind = "privacy"
+	ServerDown       Kind = "mcp-server-down"
+)
+
+// Error is an error of a known kind
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns err marked as being of kind. A nil err stays nil
+func New(kind Kind, err error) error {
+	if err == nil || kind == Unknown {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+var (
+	rateLimitPattern = regexp.MustCompile(`\b429\b|rate[ _-]?limit|too many requests|quota exceeded|resource[ _]exhausted|overloaded`)
+	authPattern      = regexp.MustCompile(`\b401\b|\b403\b|unauthorized|authentication|invalid[ _-]?(x-)?api[ _-]?key|api key not valid|missing the \w+ api key|permission[ _]denied`)
+	dbBusyPattern    = regexp.MustCompile(`database (table )?is locked|sqlite_busy|database is busy`)
+)
+
+// Of returns the kind of err. Errors created with New keep their kind through
+// wrapping. Provider and database errors are recognized from their messages since
+// the SDKs and drivers don't export typed errors for them
+func Of(err error) Kind {
+	if err == nil {
+		return Unknown
+	}
+	var kindErr *Error
+	if errors.As(err, &kindErr) {
+		return kindErr.Kind
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case dbBusyPattern.MatchString(msg):
+		return DBBusy
+	case rateLimitPattern.MatchString(msg):
+		return RateLimit
+	case authPattern.MatchString(msg):
+		return Auth
+	}
+	return Unknown
+}
+
+// Title returns a short name for the kind, used as the heading of error banners
+func (k Kind) Title() string {
+	switch k {
+	case RateLimit:
+		return "Rate limited"
+	case Auth:
+		return "Authentication failed"
+	case ToolNotFound:
+		return "Tool not found"
+	case ApprovalRequired:
+		return "Approval required"
+	case DBBusy:
+		return "Database busy"
+	case Privacy:
+		return "Not allowed by privacy level"
+	case ServerDown:
+		return "MCP server not running"
+	}
+	return "Error"
+}
+
+// Advice returns what the user can do about errors of the kind, empty if there is
+// nothing specific to suggest
+func (k Kind) Advice() string {
+	switch k {
+	case RateLimit:
+		return "The provider is limiting requests. Wait a minute and try again, switch to another preset with -m, or list presets to fall back to in the preset's fallbacks"
+	case Auth:
+		return "Check the API key for the provider is set in its environment variable, such as ANTHROPIC_API_KEY or OPENAI_API_KEY, and is still valid"
+	case ToolNotFound:
+		return "A tool named in the config or called by the model is not available. Check the preset's toolsets and list the tools each MCP server provides with `slop mcp`"
+	case ApprovalRequired:
+		return "Tool calls need approval but there was no terminal to ask on. Approve them with `slop msg send --approve` or resume the run with `slop run resume`"
+	case DBBusy:
+		return "Another slop process is writing to the database. Wait for it to finish and try again"
+	case Privacy:
+		return "The thread's privacy level keeps its messages on this machine. Reply with a local preset such as an ollama one using -m, or change the level with `slop thread privacy`"
+	case ServerDown:
+		return "An MCP server exited and its tools can't be called until it is running again. It is restarted on its own, check its command and arguments with `slop mcp` if it keeps exiting"
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+	internal "github.com/isaacphi/slop/internal/internalService"
+	"github.com/spf13/viper"
+)
+
+// Criterion is a single question the judge scores a thread on
+type Criterion struct {
+	Name        string `mapstructure:"name" json:"name"`
+	Description string `mapstructure:"description" json:"description"`
+}
+
+// Rubric is a set of criteria the judge scores a thread against
+type Rubric struct {
+	Name     string      `mapstructure:"name" json:"name"`
+	MaxScore int         `mapstructure:"maxScore" json:"maxScore"`
+	Criteria []Criterion `mapstructure:"criteria" json:"criteria"`
+}
+
+// Score is the judge's score for a single criterion
+type Score struct {
+	Criterion string `json:"criterion"`
+	Score     int    `json:"score"`
+	Reason    string `json:"reason"`
+}
+
+// maxToolResultLength limits how much of each tool result is shown to the judge
+const maxToolResultLength = 2000
+
+// DefaultRubric is used when no rubric file is given
+func DefaultRubric() Rubric {
+	return Rubric{
+		Name:     "default",
+		MaxScore: 5,
+		Criteria: []Criterion{
+			{Name: "instructions", Description: "Did the assistant follow the user's instructions?"},
+			{Name: "toolUse", Description: "Were the tool calls necessary and well chosen, without redundant or missing calls?"},
+			{Name: "grounded", Description: "Was the final answer grounded in the conversation and tool results rather than invented?"},
+		},
+	}
+}
+
+// LoadRubric reads a rubric from a yaml or json file
+func LoadRubric(path string) (Rubric, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return Rubric{}, fmt.Errorf("error reading rubric %s: %w", path, err)
+	}
+
+	var rubric Rubric
+	if err := v.Unmarshal(&rubric); err != nil {
+		return Rubric{}, fmt.Errorf("error parsing rubric %s: %w", path, err)
+	}
+
+	if rubric.Name == "" {
+		rubric.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if rubric.MaxScore <= 0 {
+		rubric.MaxScore = 5
+	}
+	if len(rubric.Criteria) == 0 {
+		return Rubric{}, fmt.Errorf("rubric %s has no criteria", path)
+	}
+	for _, c := range rubric.Criteria {
+		if c.Name == "" || c.Description == "" {
+			return Rubric{}, fmt.Errorf("rubric %s: every criterion needs a name and description", path)
+		}
+	}
+
+	return rubric, nil
+}
+
+// Evaluate asks the internal model to score a thread against a rubric
+func Evaluate(ctx context.Context, svc *internal.InternalService, threadID uuid.UUID, messages []domain.Message, rubric Rubric) (*domain.Evaluation, []Score, error) {
+	if len(messages) == 0 {
+		return nil, nil, fmt.Errorf("thread has no messages")
+	}
+	last := messages[len(messages)-1]
+	if last.Role == domain.RoleAssistant && last.ToolCalls != "" {
+		return nil, nil, fmt.Errorf("thread has pending tool calls, finish the run before evaluating it")
+	}
+
+	response, err := svc.GenerateOneOff(ctx, buildPrompt(messages, rubric))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scores, err := parseScores(response, rubric)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scoresJSON, err := json.Marshal(scores)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode scores: %w", err)
+	}
+
+	total := 0
+	for _, s := range scores {
+		total += s.Score
+	}
+
+	preset := svc.Preset()
+	evaluation := &domain.Evaluation{
+		ThreadID:  threadID,
+		Rubric:    rubric.Name,
+		Scores:    string(scoresJSON),
+		Score:     float64(total) / float64(rubric.MaxScore*len(rubric.Criteria)),
+		ModelName: preset.Name,
+		Provider:  preset.Provider,
+	}
+
+	return evaluation, scores, nil
+}
+
+func buildPrompt(messages []domain.Message, rubric Rubric) string {
+	var b strings.Builder
+
+	b.WriteString("You are evaluating a conversation between a user and an AI assistant that can call tools.\n")
+	fmt.Fprintf(&b, "Score the assistant on each criterion below with an integer from 0 to %d, where %d is best.\n\n", rubric.MaxScore, rubric.MaxScore)
+
+	b.WriteString("Criteria:\n")
+	for _, c := range rubric.Criteria {
+		fmt.Fprintf(&b, "- %s: %s\n", c.Name, c.Description)
+	}
+
+	b.WriteString("\nRespond with only a JSON array and no other text, in this format:\n")
+	b.WriteString(`[{"criterion": "<name>", "score": <integer>, "reason": "<one sentence>"}]`)
+	b.WriteString("\n\nConversation:\n\n")
+
+	for _, msg := range messages {
+		content := msg.Content
+		if msg.Role == domain.RoleTool && len(content) > maxToolResultLength {
+			content = content[:maxToolResultLength] + "\n[truncated]"
+		}
+		fmt.Fprintf(&b, "%s: %s\n", msg.Role, content)
+		if msg.ToolCalls != "" {
+			fmt.Fprintf(&b, "%s tool calls: %s\n", msg.Role, msg.ToolCalls)
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+func parseScores(response string, rubric Rubric) ([]Score, error) {
+	// Models often wrap JSON in a code block or add text around it
+	start := strings.Index(response, "[")
+	end := strings.LastIndex(response, "]")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("judge response did not contain scores: %s", response)
+	}
+
+	var scores []Score
+	if err := json.Unmarshal([]byte(response[start:end+1]), &scores); err != nil {
+		return nil, fmt.Errorf("failed to parse judge response: %w", err)
+	}
+
+	byName := make(map[string]Score)
+	for _, s := range scores {
+		byName[s.Criterion] = s
+	}
+
+	// Return scores in rubric order and make sure every criterion was scored
+	result := make([]Score, 0, len(rubric.Criteria))
+	for _, c := range rubric.Criteria {
+		s, ok := byName[c.Name]
+		if !ok {
+			return nil, fmt.Errorf("judge did not score criterion %q", c.Name)
+		}
+		s.Score = max(0, min(s.Score, rubric.MaxScore))
+		result = append(result, s)
+	}
+
+	return result, nil
+}
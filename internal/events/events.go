@@ -1,5 +1,7 @@
 package events
 
+import "github.com/isaacphi/slop/internal/errkind"
+
 // EventType defines the type of streaming event
 type EventType int
 
@@ -14,6 +16,16 @@ const (
 	EventTypeNewMessage
 	EventTypeError
 	EventTypeMessageComplete
+	EventTypeSystemMessage
+	EventTypeRunStarted
+	EventTypeCompression
+	EventTypeCacheHit
+	EventTypeReconnect
+	EventTypeThreadTitled
+	EventTypeFallback
+	EventTypeRetry
+	EventTypeContextSummary
+	EventTypeServerExit
 )
 
 // Event is the interface for all streaming events
@@ -24,6 +36,7 @@ type Event interface {
 // ErrorEvent represents an error during processing
 type ErrorEvent struct {
 	Error error
+	Kind  errkind.Kind // Category of the error so UIs can suggest a fix, empty if unknown
 }
 
 func (e ErrorEvent) Type() EventType {
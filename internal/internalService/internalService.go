@@ -2,11 +2,14 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/isaacphi/slop/internal/config"
 	"github.com/isaacphi/slop/internal/domain"
 	"github.com/isaacphi/slop/internal/llm"
+	"github.com/isaacphi/slop/internal/privacy"
 )
 
 // InternalService is used for LLM calls within the application itself
@@ -28,6 +31,26 @@ func NewInternalService(cfg *config.ConfigSchema) (*InternalService, error) {
 	}, nil
 }
 
+// NewForThread returns the service for work on the messages of thread, such as
+// summaries and titles. It fails when the thread's privacy level doesn't allow sending
+// them to the internal model, and masks secrets in them for redacted threads
+func NewForThread(cfg *config.ConfigSchema, thread *domain.Thread) (*InternalService, error) {
+	s, err := NewInternalService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := privacy.Check(thread, "internal model", s.preset); err != nil {
+		return nil, err
+	}
+	s.preset = privacy.Apply(thread, s.preset)
+	return s, nil
+}
+
+// Preset returns the preset used for internal calls
+func (s *InternalService) Preset() config.Preset {
+	return s.preset
+}
+
 // GenerateOneOff makes a single call to the LLM without storing any context or history
 func (s *InternalService) GenerateOneOff(ctx context.Context, prompt string) (string, error) {
 	opts := llm.GenerateContentOptions{
@@ -55,3 +78,139 @@ func (s *InternalService) CreateThreadSummary(ctx context.Context, messages []do
 
 	return s.GenerateOneOff(ctx, prompt)
 }
+
+// maxContextToolResult limits how much of each tool result is given to the model
+// writing a context summary
+const maxContextToolResult = 500
+
+// SummarizeContext writes a summary of messages that the conversation can continue
+// from, combined with the summary of the messages before them when there is one
+func (s *InternalService) SummarizeContext(ctx context.Context, previous string, messages []domain.Message) (string, error) {
+	var b strings.Builder
+	b.WriteString(s.cfg.ContextPrompt + "\n\n")
+	if previous != "" {
+		fmt.Fprintf(&b, "Earlier summary:\n%s\n\nNew messages:\n", previous)
+	}
+	for _, msg := range messages {
+		content := msg.Content
+		if msg.Role == domain.RoleTool {
+			if runes := []rune(content); len(runes) > maxContextToolResult {
+				content = string(runes[:maxContextToolResult]) + "…"
+			}
+		}
+		if msg.ToolCalls != "" {
+			content += "\n[tool calls: " + msg.ToolCalls + "]"
+		}
+		fmt.Fprintf(&b, "%s: %s\n", msg.Role, content)
+	}
+
+	summary, err := s.GenerateOneOff(ctx, b.String())
+	if err != nil {
+		return "", err
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", fmt.Errorf("internal model did not answer with a summary")
+	}
+	return summary, nil
+}
+
+// maxTitleLength limits generated titles, models sometimes answer with a sentence
+const maxTitleLength = 80
+
+// CreateThreadTitle writes a title and a summary for a thread using the internal model
+func (s *InternalService) CreateThreadTitle(ctx context.Context, messages []domain.Message) (title, summary string, err error) {
+	prompt := s.cfg.TitlePrompt + "\n\n"
+	for _, msg := range messages {
+		prompt += fmt.Sprintf("%s: %s\n", msg.Role, msg.Content)
+	}
+
+	response, err := s.GenerateOneOff(ctx, prompt)
+	if err != nil {
+		return "", "", err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(response, "\n") {
+		if line = strings.Trim(strings.TrimSpace(line), `"*#`); line != "" {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	if len(lines) == 0 {
+		return "", "", fmt.Errorf("internal model did not answer with a title")
+	}
+	title = strings.TrimSuffix(lines[0], ".")
+	if len([]rune(title)) > maxTitleLength {
+		title = string([]rune(title)[:maxTitleLength])
+	}
+	return title, strings.Join(lines[1:], " "), nil
+}
+
+// WriteIssue turns a thread into the title and Markdown body of an issue using the
+// internal model
+func (s *InternalService) WriteIssue(ctx context.Context, messages []domain.Message) (title, body string, err error) {
+	var b strings.Builder
+	b.WriteString(s.cfg.IssuePrompt + "\n\n")
+	for _, msg := range messages {
+		content := msg.Content
+		if msg.Role == domain.RoleTool {
+			if runes := []rune(content); len(runes) > maxContextToolResult {
+				content = string(runes[:maxContextToolResult]) + "…"
+			}
+		}
+		fmt.Fprintf(&b, "%s: %s\n", msg.Role, content)
+	}
+
+	response, err := s.GenerateOneOff(ctx, b.String())
+	if err != nil {
+		return "", "", err
+	}
+	title, body, _ = strings.Cut(strings.TrimSpace(response), "\n")
+	title = strings.TrimSpace(strings.Trim(strings.TrimSpace(title), `"*#`))
+	title = strings.TrimPrefix(title, "Title: ")
+	if title == "" {
+		return "", "", fmt.Errorf("internal model did not answer with an issue")
+	}
+	return title, strings.TrimSpace(body), nil
+}
+
+// SplitTranscript uses the internal model to divide a conversation copied from a chat
+// interface into user and assistant messages
+func (s *InternalService) SplitTranscript(ctx context.Context, text string) ([]domain.Message, error) {
+	response, err := s.GenerateOneOff(ctx, s.cfg.TranscriptPrompt+"\n\n"+text)
+	if err != nil {
+		return nil, err
+	}
+
+	// Models often wrap JSON in a code block or add text around it
+	start := strings.Index(response, "[")
+	end := strings.LastIndex(response, "]")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("internal model did not answer with turns: %s", response)
+	}
+	var turns []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &turns); err != nil {
+		return nil, fmt.Errorf("failed to parse turns: %w", err)
+	}
+
+	messages := make([]domain.Message, 0, len(turns))
+	for _, turn := range turns {
+		role := domain.RoleHuman
+		if turn.Role == "assistant" {
+			role = domain.RoleAssistant
+		}
+		if content := strings.TrimSpace(turn.Content); content != "" {
+			messages = append(messages, domain.Message{Role: role, Content: content})
+		}
+	}
+	return messages, nil
+}
+
+// SuggestPrune asks the internal model which of the described branches of a thread
+// are safe to delete
+func (s *InternalService) SuggestPrune(ctx context.Context, branches string) (string, error) {
+	return s.GenerateOneOff(ctx, s.cfg.PrunePrompt+"\n\n"+branches)
+}
@@ -0,0 +1,152 @@
+// Package issue creates issues in GitHub or GitLab repositories through their REST APIs
+package issue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/slop/internal/config"
+)
+
+// Issue is an issue to create
+type Issue struct {
+	Title  string
+	Body   string // Markdown
+	Labels []string
+}
+
+// defaults are the API address and token variable of each provider
+var defaults = map[string]struct {
+	baseURL  string
+	tokenEnv string
+}{
+	"github": {baseURL: "https://api.github.com", tokenEnv: "GITHUB_TOKEN"},
+	"gitlab": {baseURL: "https://gitlab.com/api/v4", tokenEnv: "GITLAB_TOKEN"},
+}
+
+// Tracker creates issues with the settings of the issues config
+type Tracker struct {
+	provider string
+	baseURL  string
+	token    string
+}
+
+// New reads the address and token of the configured provider. It fails when there is
+// no token since every provider needs one to create issues
+func New(cfg config.Issues) (*Tracker, error) {
+	d, ok := defaults[cfg.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported issue provider %q, must be github or gitlab", cfg.Provider)
+	}
+	t := &Tracker{provider: cfg.Provider, baseURL: d.baseURL, token: cfg.Token}
+	if cfg.BaseURL != "" {
+		t.baseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	}
+	if t.token == "" {
+		env := cfg.TokenEnv
+		if env == "" {
+			env = d.tokenEnv
+		}
+		t.token = os.Getenv(env)
+		if t.token == "" {
+			return nil, fmt.Errorf("no %s token: set issues.token or the %s environment variable", cfg.Provider, env)
+		}
+	}
+	return t, nil
+}
+
+// Create opens the issue in repo, given as owner/name, and returns its web address
+func (t *Tracker) Create(ctx context.Context, repo string, issue Issue) (string, error) {
+	if !strings.Contains(strings.Trim(repo, "/"), "/") {
+		return "", fmt.Errorf("invalid repository %q, expected owner/name", repo)
+	}
+	repo = strings.Trim(repo, "/")
+
+	var (
+		endpoint string
+		payload  map[string]any
+		header   string
+		value    string
+	)
+	switch t.provider {
+	case "github":
+		endpoint = fmt.Sprintf("%s/repos/%s/issues", t.baseURL, repo)
+		payload = map[string]any{"title": issue.Title, "body": issue.Body}
+		if len(issue.Labels) > 0 {
+			payload["labels"] = issue.Labels
+		}
+		header, value = "Authorization", "Bearer "+t.token
+	case "gitlab":
+		// GitLab takes the URL encoded path of a project in place of its ID
+		endpoint = fmt.Sprintf("%s/projects/%s/issues", t.baseURL, url.PathEscape(repo))
+		payload = map[string]any{"title": issue.Title, "description": issue.Body}
+		if len(issue.Labels) > 0 {
+			payload["labels"] = strings.Join(issue.Labels, ",")
+		}
+		header, value = "PRIVATE-TOKEN", t.token
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode issue: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set(header, value)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to create issue in %s: %w", repo, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("failed to create issue in %s: %s: %s", repo, resp.Status, apiError(respBody))
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"` // GitHub
+		WebURL  string `json:"web_url"`  // GitLab
+	}
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if created.HTMLURL != "" {
+		return created.HTMLURL, nil
+	}
+	return created.WebURL, nil
+}
+
+// apiError returns the message of an API error response, or the response itself
+func apiError(body []byte) string {
+	var e struct {
+		Message any `json:"message"`
+		Error   any `json:"error"`
+	}
+	if json.Unmarshal(body, &e) == nil {
+		if e.Message != nil {
+			return fmt.Sprint(e.Message)
+		}
+		if e.Error != nil {
+			return fmt.Sprint(e.Error)
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
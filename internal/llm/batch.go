@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/llm/provider"
+)
+
+// BatchRequest is a single request in a batch. Batched requests are plain text, tools
+// are not offered since there is no chance to run them before the reply is final
+type BatchRequest struct {
+	CustomID      string // Identifies the result of this request
+	SystemMessage *domain.Message
+	History       []domain.Message
+	Content       string
+}
+
+// SupportsBatch reports whether provider has a batch API that slop can submit to
+func SupportsBatch(name string) bool {
+	p, err := provider.Get(name)
+	if err != nil {
+		return false
+	}
+	_, ok := p.(provider.Batcher)
+	return ok
+}
+
+// batcher returns the batch API of the preset's provider and the options to call it with
+func batcher(preset config.Preset) (provider.Batcher, provider.Options, error) {
+	p, err := provider.Get(preset.Provider)
+	if err != nil {
+		return nil, provider.Options{}, err
+	}
+	b, ok := p.(provider.Batcher)
+	if !ok {
+		return nil, provider.Options{}, fmt.Errorf("provider %s does not support batches", preset.Provider)
+	}
+	opts, err := providerOptions(preset, p)
+	if err != nil {
+		return nil, provider.Options{}, err
+	}
+	return b, opts, nil
+}
+
+// SubmitBatch submits requests to the preset's provider to be answered within a day
+// at a lower price, and returns the provider's ID for the batch
+func SubmitBatch(ctx context.Context, preset config.Preset, requests []BatchRequest) (string, error) {
+	b, opts, err := batcher(preset)
+	if err != nil {
+		return "", err
+	}
+
+	batch := make([]provider.BatchRequest, len(requests))
+	for i, request := range requests {
+		if preset.Redact {
+			request = request.Redacted()
+		}
+		batch[i] = provider.BatchRequest{
+			CustomID: request.CustomID,
+			System:   systemContent(request),
+			Messages: batchMessages(request),
+		}
+	}
+	params := provider.BatchParams{MaxTokens: preset.MaxTokens, Temperature: preset.Temperature}
+	return b.SubmitBatch(ctx, opts, params, batch)
+}
+
+// PollBatch checks on a submitted batch and downloads its results once it is done
+func PollBatch(ctx context.Context, preset config.Preset, batchID string) (provider.BatchStatus, error) {
+	b, opts, err := batcher(preset)
+	if err != nil {
+		return provider.BatchStatus{}, err
+	}
+	return b.PollBatch(ctx, opts, batchID)
+}
+
+// batchMessages converts the history and content of a request to alternating user and
+// assistant messages. Tool calls and their results are left out
+func batchMessages(request BatchRequest) []provider.BatchMessage {
+	var messages []provider.BatchMessage
+	add := func(role, content string) {
+		if content == "" {
+			return
+		}
+		// Consecutive messages from the same role are merged so roles alternate
+		if n := len(messages); n > 0 && messages[n-1].Role == role {
+			messages[n-1].Content += "\n\n" + content
+			return
+		}
+		messages = append(messages, provider.BatchMessage{Role: role, Content: content})
+	}
+
+	for _, msg := range request.History {
+		switch msg.Role {
+		case domain.RoleHuman:
+			add("user", msg.Content)
+		case domain.RoleAssistant:
+			add("assistant", msg.Content)
+		}
+	}
+	add("user", request.Content)
+	return messages
+}
+
+func systemContent(request BatchRequest) string {
+	if request.SystemMessage == nil {
+		return ""
+	}
+	return request.SystemMessage.Content
+}
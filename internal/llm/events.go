@@ -1,6 +1,10 @@
 package llm
 
-import "github.com/isaacphi/slop/internal/events"
+import (
+	"time"
+
+	"github.com/isaacphi/slop/internal/events"
+)
 
 // TextEvent represents a chunk of text from the LLM
 type TextEvent struct {
@@ -29,6 +33,19 @@ func (e ToolCallStartEvent) Type() events.EventType {
 	return events.EventTypeToolCallStart
 }
 
+// RetryEvent reports that a request failed with a temporary error before any of the
+// response arrived and is about to be sent again
+type RetryEvent struct {
+	Attempt     int // The attempt about to be made, starting at 2
+	MaxAttempts int
+	Delay       time.Duration // Wait before the attempt
+	Error       error
+}
+
+func (e RetryEvent) Type() events.EventType {
+	return events.EventTypeRetry
+}
+
 // LLMStream represents an ongoing LLM response stream
 type LLMStream struct {
 	Events <-chan events.Event
@@ -39,6 +56,10 @@ type LLMStream struct {
 type MessageCompleteEvent struct {
 	Content   string
 	ToolCalls []ToolCall
+	Model     string // Model version reported by the provider
+	// Tokens the provider reports for the request and the response, 0 if it reported none
+	InputTokens  int
+	OutputTokens int
 }
 
 func (e MessageCompleteEvent) Type() events.EventType {
@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/isaacphi/slop/internal/config"
+)
+
+// newHTTPClient builds an HTTP client from a preset's HTTP settings.
+// It returns nil when nothing is configured so providers keep their default clients
+func newHTTPClient(cfg config.HTTP) (*http.Client, error) {
+	if cfg.Proxy == "" && cfg.CABundle == "" && cfg.Timeout == "" {
+		return nil, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", cfg.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if cfg.CABundle != "" {
+		pem, err := os.ReadFile(cfg.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.CABundle)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	if cfg.Timeout != "" {
+		// Only limit the wait for the response headers, a streamed response can
+		// legitimately take much longer than this to finish
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout %q: %w", cfg.Timeout, err)
+		}
+		transport.ResponseHeaderTimeout = timeout
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
@@ -5,19 +5,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 
 	"github.com/isaacphi/slop/internal/config"
 	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/errkind"
 	"github.com/isaacphi/slop/internal/events"
+	"github.com/isaacphi/slop/internal/llm/provider"
 	"github.com/tmc/langchaingo/llms"
-	"github.com/tmc/langchaingo/llms/anthropic"
-	"github.com/tmc/langchaingo/llms/googleai"
-	"github.com/tmc/langchaingo/llms/openai"
 )
 
 type MessageResponse struct {
 	TextResponse string
 	ToolCalls    []ToolCall
+	Model        string // Model version reported by the provider
 }
 
 type ToolCall struct {
@@ -35,38 +36,67 @@ type FunctionCallChunk struct {
 	ArgumentsJson string `json:"arguments"`
 }
 
+// createLLMClient creates a client for the preset with its registered provider
 func createLLMClient(preset config.Preset) (llms.Model, error) {
-	var llm llms.Model
-	var err error
-
-	switch preset.Provider {
-	case "openai":
-		llm, err = openai.New(
-			openai.WithModel(preset.Name),
-		)
-	case "anthropic":
-		llm, err = anthropic.New(
-			anthropic.WithModel(preset.Name),
-		)
-	case "googleai":
-		genaiKey := os.Getenv("GEMINI_API_KEY")
-		ctx := context.Background()
-		llm, err = googleai.New(
-			ctx,
-			googleai.WithDefaultModel(preset.Name),
-			googleai.WithAPIKey(genaiKey),
-		)
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", preset.Provider)
+	p, err := provider.Get(preset.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := providerOptions(preset, p)
+	if err != nil {
+		return nil, err
 	}
+	llm, err := p.New(opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create %s client: %w", preset.Provider, err)
 	}
-
 	return llm, nil
 }
 
-func buildMessageHistory(systemMessage *domain.Message, messages []domain.Message) []llms.MessageContent {
+// providerOptions resolves the settings of a preset that its provider is called with
+func providerOptions(preset config.Preset, p provider.Provider) (provider.Options, error) {
+	httpClient, err := newHTTPClient(preset.HTTP)
+	if err != nil {
+		return provider.Options{}, fmt.Errorf("invalid http configuration for %s: %w", preset.Provider, err)
+	}
+	key, err := apiKey(preset, p.Capabilities().KeyEnv)
+	if err != nil {
+		return provider.Options{}, err
+	}
+	return provider.Options{
+		Model:      preset.Name,
+		BaseURL:    preset.BaseURL,
+		APIKey:     key,
+		HTTPClient: httpClient,
+	}, nil
+}
+
+// localAPIKey is sent to servers at a custom base URL when no key is configured.
+// Local servers such as vLLM or LM Studio don't check it but the clients require one
+const localAPIKey = "unused"
+
+// apiKey reads the API key from the variable named by the preset's apiKeyEnv, or from
+// the provider's usual variable. Keys from the usual variable are never sent to a
+// custom base URL, so a gateway can't receive the key of the provider
+func apiKey(preset config.Preset, usualEnv string) (string, error) {
+	if preset.APIKeyEnv != "" {
+		key := os.Getenv(preset.APIKeyEnv)
+		if key == "" {
+			return "", fmt.Errorf("missing the API key for %s, set it in the %s environment variable", preset.Name, preset.APIKeyEnv)
+		}
+		return key, nil
+	}
+	if preset.BaseURL != "" {
+		return localAPIKey, nil
+	}
+	if usualEnv == "" {
+		return "", nil
+	}
+	return os.Getenv(usualEnv), nil
+}
+
+func buildMessageHistory(systemMessage *domain.Message, messages []domain.Message, attachments map[string][]byte, vision bool) []llms.MessageContent {
 	var history []llms.MessageContent
 	if systemMessage != nil {
 		history = append(history, llms.TextParts(llms.ChatMessageTypeSystem, systemMessage.Content))
@@ -79,11 +109,56 @@ func buildMessageHistory(systemMessage *domain.Message, messages []domain.Messag
 		} else {
 			role = llms.ChatMessageTypeHuman
 		}
-		history = append(history, llms.TextParts(role, msg.Content))
+		history = append(history, llms.MessageContent{
+			Role:  role,
+			Parts: convertParts(ContentParts(msg), attachments, vision),
+		})
 	}
 	return history
 }
 
+// ContentParts returns the parts a message is sent as. Messages composed from
+// several parts keep their boundaries, everything else is sent as a single part
+func ContentParts(msg domain.Message) []domain.MessagePart {
+	if msg.Parts == "" {
+		return []domain.MessagePart{{Content: msg.Content}}
+	}
+	parts, err := msg.GetParts()
+	if err != nil {
+		return []domain.MessagePart{{Content: msg.Content}}
+	}
+	return parts
+}
+
+// convertParts maps message parts to provider parts. Attachments are sent with
+// their data when it is in attachments, otherwise only their description is sent.
+// Images linked by URL are sent as links when the model accepts images
+func convertParts(parts []domain.MessagePart, attachments map[string][]byte, vision bool) []llms.ContentPart {
+	result := make([]llms.ContentPart, 0, len(parts))
+	for _, part := range parts {
+		if part.URL != "" {
+			if !vision {
+				result = append(result, llms.TextPart(part.Content+" This image can't be shown to you."))
+				continue
+			}
+			result = append(result, llms.TextPart(part.Content), llms.ImageURLPart(part.URL))
+			continue
+		}
+		if part.Artifact == "" {
+			result = append(result, llms.TextPart(part.Content))
+			continue
+		}
+
+		data, ok := attachments[part.Artifact]
+		if !ok {
+			result = append(result, llms.TextPart(part.Content+" This attachment can't be shown to you."))
+			continue
+		}
+		result = append(result, llms.TextPart(part.Content), llms.BinaryPart(part.MimeType, data))
+	}
+	return result
+}
+
 func getTools(tools map[string]domain.Tool) []llms.Tool {
 	var result []llms.Tool
 	for name, tool := range tools {
@@ -102,6 +177,30 @@ func getTools(tools map[string]domain.Tool) []llms.Tool {
 	return result
 }
 
+// toolChoiceOption maps a tool choice setting to the provider option. It returns nil
+// for auto so providers keep their default behavior
+func toolChoiceOption(choice string, tools map[string]domain.Tool) (llms.CallOption, error) {
+	switch choice {
+	case "", "auto":
+		return nil, nil
+	case "none", "required":
+		return llms.WithToolChoice(choice), nil
+	}
+
+	if _, ok := tools[choice]; !ok {
+		names := make([]string, 0, len(tools))
+		for name := range tools {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("invalid tool choice %q, must be auto, none, required or one of the available tools: %v", choice, names)
+	}
+	return llms.WithToolChoice(llms.ToolChoice{
+		Type:     "function",
+		Function: &llms.FunctionReference{Name: choice},
+	}), nil
+}
+
 func convertParameters(params domain.Parameters) map[string]any {
 	properties := make(map[string]any)
 
@@ -152,9 +251,25 @@ func convertProperty(prop domain.Property) map[string]any {
 type GenerateContentOptions struct {
 	Preset        config.Preset
 	Content       string
+	ContentParts  []domain.MessagePart // Sent instead of Content when set
 	SystemMessage *domain.Message
 	History       []domain.Message
 	Tools         map[string]domain.Tool
+	// Data of the attachments that can be shown to the model, such as images, by artifact
+	// hash. Images linked by URL are sent when the preset has vision
+	Attachments map[string][]byte
+}
+
+// humanMessage returns the new human turn
+func (opts GenerateContentOptions) humanMessage() llms.MessageContent {
+	parts := opts.ContentParts
+	if len(parts) == 0 {
+		parts = []domain.MessagePart{{Content: opts.Content}}
+	}
+	return llms.MessageContent{
+		Role:  llms.ChatMessageTypeHuman,
+		Parts: convertParts(parts, opts.Attachments, opts.Preset.Vision),
+	}
 }
 
 // GenerateContentStream returns a stream of events from the LLM
@@ -177,61 +292,32 @@ func GenerateContentStream(
 		default:
 		}
 
+		if opts.Preset.Redact {
+			opts = opts.redacted()
+		}
+
 		llmClient, err := createLLMClient(opts.Preset)
 		if err != nil {
-			eventsChan <- &events.ErrorEvent{Error: fmt.Errorf("failed to create LLM client: %w", err)}
+			eventsChan <- &events.ErrorEvent{Error: fmt.Errorf("failed to create LLM client: %w", err), Kind: errkind.Of(err)}
 			return
 		}
 
-		// Map to track parsers for different function calls
-		var toolCallParsers = make(map[string]*IncrementalJsonParser)
-		var functionId *string
-		var functionName string
+		toolCalls := newStreamedCalls(opts.Tools)
+		// Once any of the response arrived a failed request isn't retried here, the
+		// agent reconnects and asks for the rest instead
+		received := false
 
 		// In the streaming callback
 		streamCallback := func(ctx context.Context, chunk []byte) error {
+			received = true
 			// Try to parse as function call first
-			var fcall []struct {
-				Function FunctionCallChunk `json:"function"`
-				Id       *string           `json:"id,omitempty"`
-			}
+			var fcall []toolCallChunk
 			if err := json.Unmarshal(chunk, &fcall); err == nil && len(fcall) > 0 {
-				// This is a function call chunk
-				if fcall[0].Function.Name != functionName {
-					functionName = fcall[0].Function.Name
-					eventsChan <- &ToolCallStartEvent{FunctionName: functionName}
-				}
-
-				// OpenAI only returns the function call ID once so we perist it
-				if fcall[0].Id != nil {
-					functionId = fcall[0].Id
-				}
-				if functionId == nil {
-					return nil
-				}
-
-				// Get or create a parser for this function call
-				parser, exists := toolCallParsers[*functionId]
-				if !exists {
-					tool, exists := opts.Tools[functionName]
-					if !exists {
-						return fmt.Errorf("tool not found: %s", functionName)
-					}
-					parser = NewIncrementalJsonParser(&tool.Parameters)
-					toolCallParsers[*functionId] = parser
-				}
-
-				// Add the chunk to the parser and get updates
-				updates, err := parser.ProcessChunk(fcall[0].Function.ArgumentsJson)
-				if err != nil {
-					return fmt.Errorf("json parse error: %w", err)
-				}
-
-				// Emit events for each update
+				updates, err := toolCalls.process(fcall)
 				for _, update := range updates {
-					eventsChan <- &update
+					eventsChan <- update
 				}
-				return nil
+				return err
 			}
 
 			// Regular text chunk
@@ -250,39 +336,46 @@ func GenerateContentStream(
 			callOptions = append(callOptions, llms.WithTools(langchainTools))
 		}
 
+		toolChoice, err := toolChoiceOption(opts.Preset.ToolChoice, opts.Tools)
+		if err != nil {
+			eventsChan <- &events.ErrorEvent{Error: err}
+			return
+		}
+		if toolChoice != nil {
+			callOptions = append(callOptions, toolChoice)
+		}
+
 		if opts.SystemMessage != nil && opts.SystemMessage.Role != domain.RoleSystem {
 			eventsChan <- &events.ErrorEvent{Error: fmt.Errorf("system message is of type %v", opts.SystemMessage.Role)}
 			return
 		}
 
-		msgs := buildMessageHistory(opts.SystemMessage, opts.History)
-		msgs = append(msgs, llms.TextParts(llms.ChatMessageTypeHuman, opts.Content))
-
-		resp, err := llmClient.GenerateContent(ctx, msgs, callOptions...)
+		msgs := buildMessageHistory(opts.SystemMessage, opts.History, opts.Attachments, opts.Preset.Vision)
+		msgs = append(msgs, opts.humanMessage())
+
+		var resp *llms.ContentResponse
+		err = withRetries(ctx, newRetryPolicy(opts.Preset.Retry), func() (bool, error) {
+			var err error
+			resp, err = llmClient.GenerateContent(ctx, msgs, callOptions...)
+			return !received, err
+		}, func(e RetryEvent) {
+			eventsChan <- &e
+		})
 		if err != nil {
-			eventsChan <- &events.ErrorEvent{Error: fmt.Errorf("streaming message failed: %w", err)}
+			eventsChan <- &events.ErrorEvent{Error: fmt.Errorf("streaming message failed: %w", err), Kind: errkind.Of(err)}
 			return
 		}
 
 		// Send complete response with tool calls
 		if len(resp.Choices) > 0 {
-			toolCalls := make([]ToolCall, 0)
-			for _, choice := range resp.Choices {
-				if len(choice.ToolCalls) > 0 {
-					for _, tc := range choice.ToolCalls {
-						toolCalls = append(toolCalls, ToolCall{
-							ID:        tc.ID,
-							Name:      tc.FunctionCall.Name,
-							Arguments: json.RawMessage(tc.FunctionCall.Arguments),
-						})
-					}
-				}
-			}
-
 			// TODO: can there be text content in other choices? Might need to combine them
+			input, output := tokenUsage(resp.Choices)
 			eventsChan <- &MessageCompleteEvent{
-				Content:   resp.Choices[0].Content,
-				ToolCalls: toolCalls,
+				Content:      resp.Choices[0].Content,
+				ToolCalls:    responseToolCalls(resp.Choices, toolCalls.order()),
+				Model:        responseModel(resp.Choices[0], opts.Preset),
+				InputTokens:  input,
+				OutputTokens: output,
 			}
 		}
 	}()
@@ -295,6 +388,9 @@ func GenerateContent(
 	ctx context.Context,
 	opts GenerateContentOptions,
 ) (MessageResponse, error) {
+	if opts.Preset.Redact {
+		opts = opts.redacted()
+	}
 	llmClient, err := createLLMClient(opts.Preset)
 	if err != nil {
 		return MessageResponse{}, fmt.Errorf("failed to create LLM client: %w", err)
@@ -310,13 +406,26 @@ func GenerateContent(
 		callOptions = append(callOptions, llms.WithTools(langchainTools))
 	}
 
+	toolChoice, err := toolChoiceOption(opts.Preset.ToolChoice, opts.Tools)
+	if err != nil {
+		return MessageResponse{}, err
+	}
+	if toolChoice != nil {
+		callOptions = append(callOptions, toolChoice)
+	}
+
 	if opts.SystemMessage != nil && opts.SystemMessage.Role != domain.RoleSystem {
 		return MessageResponse{}, fmt.Errorf("system message is of type %v", opts.SystemMessage.Role)
 	}
-	msgs := buildMessageHistory(opts.SystemMessage, opts.History)
-	msgs = append(msgs, llms.TextParts(llms.ChatMessageTypeHuman, opts.Content))
-
-	resp, err := llmClient.GenerateContent(ctx, msgs, callOptions...)
+	msgs := buildMessageHistory(opts.SystemMessage, opts.History, opts.Attachments, opts.Preset.Vision)
+	msgs = append(msgs, opts.humanMessage())
+
+	var resp *llms.ContentResponse
+	err = withRetries(ctx, newRetryPolicy(opts.Preset.Retry), func() (bool, error) {
+		var err error
+		resp, err = llmClient.GenerateContent(ctx, msgs, callOptions...)
+		return true, err
+	}, nil)
 	if err != nil {
 		return MessageResponse{}, fmt.Errorf("sending message failed: %w", err)
 	}
@@ -325,21 +434,52 @@ func GenerateContent(
 		return MessageResponse{}, fmt.Errorf("no response choices returned")
 	}
 
-	toolCalls := make([]ToolCall, 0)
-	for _, choice := range resp.Choices {
-		if len(choice.ToolCalls) > 0 {
-			for _, tc := range choice.ToolCalls {
-				toolCalls = append(toolCalls, ToolCall{
-					ID:        tc.ID,
-					Name:      tc.FunctionCall.Name,
-					Arguments: json.RawMessage(tc.FunctionCall.Arguments),
-				})
-			}
-		}
-	}
-
 	return MessageResponse{
 		TextResponse: resp.Choices[0].Content,
-		ToolCalls:    toolCalls,
+		ToolCalls:    responseToolCalls(resp.Choices, nil),
+		Model:        responseModel(resp.Choices[0], opts.Preset),
 	}, nil
 }
+
+// responseModel returns the model version the provider reports in its response.
+// Providers that don't report one are assumed to have used the requested model
+func responseModel(choice *llms.ContentChoice, preset config.Preset) string {
+	for _, key := range []string{"model", "Model"} {
+		if model, ok := choice.GenerationInfo[key].(string); ok && model != "" {
+			return model
+		}
+	}
+	return preset.Name
+}
+
+// Keys of the token counts in the generation info of each provider
+var (
+	inputTokenKeys  = []string{"PromptTokens", "InputTokens", "input_tokens", "prompt_eval_count"}
+	outputTokenKeys = []string{"CompletionTokens", "OutputTokens", "output_tokens", "eval_count"}
+)
+
+// tokenUsage returns the input and output tokens the provider reports for a response.
+// Providers repeat the counts of the whole response on each choice
+func tokenUsage(choices []*llms.ContentChoice) (input int, output int) {
+	for _, choice := range choices {
+		input = max(input, generationCount(choice.GenerationInfo, inputTokenKeys))
+		output = max(output, generationCount(choice.GenerationInfo, outputTokenKeys))
+	}
+	return input, output
+}
+
+func generationCount(info map[string]any, keys []string) int {
+	for _, key := range keys {
+		switch n := info[key].(type) {
+		case int:
+			return n
+		case int32:
+			return int(n)
+		case int64:
+			return int(n)
+		case float64:
+			return int(n)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,37 @@
+// Package anthropic registers the Anthropic provider
+package anthropic
+
+import (
+	"github.com/isaacphi/slop/internal/llm/provider"
+	"github.com/isaacphi/slop/internal/tokens"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/anthropic"
+)
+
+func init() {
+	provider.Register("anthropic", claude{})
+}
+
+type claude struct{}
+
+func (claude) New(opts provider.Options) (llms.Model, error) {
+	clientOpts := []anthropic.Option{anthropic.WithModel(opts.Model)}
+	if opts.BaseURL != "" {
+		clientOpts = append(clientOpts, anthropic.WithBaseURL(opts.BaseURL))
+	}
+	if opts.APIKey != "" {
+		clientOpts = append(clientOpts, anthropic.WithToken(opts.APIKey))
+	}
+	if opts.HTTPClient != nil {
+		clientOpts = append(clientOpts, anthropic.WithHTTPClient(opts.HTTPClient))
+	}
+	return anthropic.New(clientOpts...)
+}
+
+func (claude) Capabilities() provider.Capabilities {
+	return provider.Capabilities{KeyEnv: "ANTHROPIC_API_KEY", BaseURL: true, Vision: true, Endpoint: "https://api.anthropic.com"}
+}
+
+func (claude) Tokenizer() string {
+	return tokens.Anthropic
+}
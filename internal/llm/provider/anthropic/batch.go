@@ -0,0 +1,134 @@
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/llm/provider"
+)
+
+const (
+	batchAPI     = "https://api.anthropic.com/v1"
+	batchVersion = "2023-06-01"
+)
+
+func batchClient(opts provider.Options) (*provider.BatchAPI, error) {
+	if opts.APIKey == "" {
+		return nil, fmt.Errorf("missing the Anthropic API key, set it in the ANTHROPIC_API_KEY environment variable")
+	}
+	return provider.NewBatchAPI(opts, batchAPI, map[string]string{"x-api-key": opts.APIKey, "anthropic-version": batchVersion}), nil
+}
+
+func (claude) SubmitBatch(ctx context.Context, opts provider.Options, batchParams provider.BatchParams, requests []provider.BatchRequest) (string, error) {
+	client, err := batchClient(opts)
+	if err != nil {
+		return "", err
+	}
+
+	type params struct {
+		Model       string                  `json:"model"`
+		MaxTokens   int                     `json:"max_tokens"`
+		Temperature float64                 `json:"temperature"`
+		System      string                  `json:"system,omitempty"`
+		Messages    []provider.BatchMessage `json:"messages"`
+	}
+	type request struct {
+		CustomID string `json:"custom_id"`
+		Params   params `json:"params"`
+	}
+
+	body := struct {
+		Requests []request `json:"requests"`
+	}{}
+	for _, r := range requests {
+		body.Requests = append(body.Requests, request{
+			CustomID: r.CustomID,
+			Params: params{
+				Model:       opts.Model,
+				MaxTokens:   batchParams.MaxTokens,
+				Temperature: batchParams.Temperature,
+				System:      r.System,
+				Messages:    r.Messages,
+			},
+		})
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		ID string `json:"id"`
+	}
+	if _, err := client.Do(ctx, http.MethodPost, client.Base+"/messages/batches", "application/json", bytes.NewReader(data), &resp); err != nil {
+		return "", fmt.Errorf("failed to submit batch: %w", err)
+	}
+	return resp.ID, nil
+}
+
+func (claude) PollBatch(ctx context.Context, opts provider.Options, batchID string) (provider.BatchStatus, error) {
+	client, err := batchClient(opts)
+	if err != nil {
+		return provider.BatchStatus{}, err
+	}
+
+	var batch struct {
+		ProcessingStatus string `json:"processing_status"`
+		ResultsURL       string `json:"results_url"`
+	}
+	if _, err := client.Do(ctx, http.MethodGet, client.Base+"/messages/batches/"+batchID, "", nil, &batch); err != nil {
+		return provider.BatchStatus{}, fmt.Errorf("failed to check batch: %w", err)
+	}
+	if batch.ProcessingStatus != "ended" {
+		return provider.BatchStatus{}, nil
+	}
+
+	data, err := client.Do(ctx, http.MethodGet, batch.ResultsURL, "", nil, nil)
+	if err != nil {
+		return provider.BatchStatus{}, fmt.Errorf("failed to download batch results: %w", err)
+	}
+
+	status := provider.BatchStatus{Done: true, Results: make(map[string]provider.BatchResult)}
+	err = provider.EachLine(data, func(line []byte) error {
+		var entry struct {
+			CustomID string `json:"custom_id"`
+			Result   struct {
+				Type    string `json:"type"`
+				Message struct {
+					Model   string `json:"model"`
+					Content []struct {
+						Type string `json:"type"`
+						Text string `json:"text"`
+					} `json:"content"`
+				} `json:"message"`
+				Error json.RawMessage `json:"error"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+
+		result := provider.BatchResult{Model: entry.Result.Message.Model}
+		switch entry.Result.Type {
+		case "succeeded":
+			var text []string
+			for _, block := range entry.Result.Message.Content {
+				if block.Type == "text" {
+					text = append(text, block.Text)
+				}
+			}
+			result.Content = strings.Join(text, "")
+		case "errored":
+			result.Error = string(entry.Result.Error)
+		default:
+			result.Error = "request " + entry.Result.Type
+		}
+		status.Results[entry.CustomID] = result
+		return nil
+	})
+	return status, err
+}
@@ -0,0 +1,125 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Batcher is implemented by providers with a batch API that slop can submit requests
+// to, to be answered within a day at a lower price
+type Batcher interface {
+	// SubmitBatch submits requests and returns the provider's ID for the batch
+	SubmitBatch(ctx context.Context, opts Options, params BatchParams, requests []BatchRequest) (string, error)
+	// PollBatch checks on a submitted batch and downloads its results once it is done
+	PollBatch(ctx context.Context, opts Options, batchID string) (BatchStatus, error)
+}
+
+// BatchParams are the settings of the preset every request of a batch is sent with
+type BatchParams struct {
+	MaxTokens   int
+	Temperature float64
+}
+
+// BatchMessage is a chat message in a batched request, from the user or the assistant
+type BatchMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// BatchRequest is a single request in a batch
+type BatchRequest struct {
+	CustomID string // Identifies the result of this request
+	System   string // System prompt, empty for none
+	Messages []BatchMessage
+}
+
+// BatchResult is the reply to one request of a batch
+type BatchResult struct {
+	Content string
+	Model   string
+	Error   string // Why the request failed, empty if it succeeded
+}
+
+// BatchStatus is the progress of a submitted batch. Results are only set once it is done
+type BatchStatus struct {
+	Done    bool
+	Error   string // Why the whole batch failed, empty if it didn't
+	Results map[string]BatchResult
+}
+
+// BatchAPI makes authenticated requests to a provider's batch API
+type BatchAPI struct {
+	http    *http.Client
+	Base    string // URL the API paths are relative to
+	headers map[string]string
+}
+
+// NewBatchAPI creates a client for the batch API at base, or at the base URL of opts
+// when it has one
+func NewBatchAPI(opts Options, base string, headers map[string]string) *BatchAPI {
+	api := &BatchAPI{http: opts.HTTPClient, Base: base, headers: headers}
+	if api.http == nil {
+		api.http = http.DefaultClient
+	}
+	if opts.BaseURL != "" {
+		api.Base = strings.TrimSuffix(opts.BaseURL, "/")
+	}
+	return api
+}
+
+// Do sends a request and decodes a JSON response into out, or returns the raw body
+// when out is nil
+func (c *BatchAPI) Do(ctx context.Context, method, url, contentType string, body io.Reader, out any) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("API returned unexpected status code: %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return data, nil
+}
+
+// EachLine calls fn with every non-empty line of a JSON lines document
+func EachLine(data []byte, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return fmt.Errorf("failed to read batch results: %w", err)
+		}
+	}
+	return scanner.Err()
+}
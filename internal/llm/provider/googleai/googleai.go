@@ -0,0 +1,36 @@
+// Package googleai registers the Google AI provider for Gemini models
+package googleai
+
+import (
+	"context"
+
+	"github.com/isaacphi/slop/internal/llm/provider"
+	"github.com/isaacphi/slop/internal/tokens"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/googleai"
+)
+
+func init() {
+	provider.Register("googleai", gemini{})
+}
+
+type gemini struct{}
+
+func (gemini) New(opts provider.Options) (llms.Model, error) {
+	clientOpts := []googleai.Option{
+		googleai.WithDefaultModel(opts.Model),
+		googleai.WithAPIKey(opts.APIKey),
+	}
+	if opts.HTTPClient != nil {
+		clientOpts = append(clientOpts, googleai.WithHTTPClient(opts.HTTPClient))
+	}
+	return googleai.New(context.Background(), clientOpts...)
+}
+
+func (gemini) Capabilities() provider.Capabilities {
+	return provider.Capabilities{KeyEnv: "GEMINI_API_KEY", Vision: true, Endpoint: "https://generativelanguage.googleapis.com"}
+}
+
+func (gemini) Tokenizer() string {
+	return tokens.Generic
+}
@@ -0,0 +1,36 @@
+// Package ollama registers the provider for models served by Ollama
+package ollama
+
+import (
+	"github.com/isaacphi/slop/internal/llm/provider"
+	"github.com/isaacphi/slop/internal/tokens"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/ollama"
+)
+
+func init() {
+	provider.Register("ollama", server{})
+}
+
+type server struct{}
+
+// New creates a client for the server at the base URL. Without one the client uses
+// OLLAMA_HOST, or the local default port
+func (server) New(opts provider.Options) (llms.Model, error) {
+	clientOpts := []ollama.Option{ollama.WithModel(opts.Model)}
+	if opts.BaseURL != "" {
+		clientOpts = append(clientOpts, ollama.WithServerURL(opts.BaseURL))
+	}
+	if opts.HTTPClient != nil {
+		clientOpts = append(clientOpts, ollama.WithHTTPClient(opts.HTTPClient))
+	}
+	return ollama.New(clientOpts...)
+}
+
+func (server) Capabilities() provider.Capabilities {
+	return provider.Capabilities{BaseURL: true, Local: true, Endpoint: "http://localhost:11434"}
+}
+
+func (server) Tokenizer() string {
+	return tokens.Generic
+}
@@ -0,0 +1,162 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/isaacphi/slop/internal/llm/provider"
+)
+
+const batchAPI = "https://api.openai.com/v1"
+
+func batchClient(opts provider.Options) (*provider.BatchAPI, error) {
+	if opts.APIKey == "" {
+		return nil, fmt.Errorf("missing the OpenAI API key, set it in the OPENAI_API_KEY environment variable")
+	}
+	return provider.NewBatchAPI(opts, batchAPI, map[string]string{"Authorization": "Bearer " + opts.APIKey}), nil
+}
+
+func (openAI) SubmitBatch(ctx context.Context, opts provider.Options, params provider.BatchParams, requests []provider.BatchRequest) (string, error) {
+	client, err := batchClient(opts)
+	if err != nil {
+		return "", err
+	}
+
+	// Requests are uploaded as a JSON lines file that the batch refers to
+	var lines bytes.Buffer
+	encoder := json.NewEncoder(&lines)
+	for _, r := range requests {
+		messages := r.Messages
+		if r.System != "" {
+			messages = append([]provider.BatchMessage{{Role: "system", Content: r.System}}, messages...)
+		}
+		line := map[string]any{
+			"custom_id": r.CustomID,
+			"method":    http.MethodPost,
+			"url":       "/v1/chat/completions",
+			"body": map[string]any{
+				"model":       opts.Model,
+				"max_tokens":  params.MaxTokens,
+				"temperature": params.Temperature,
+				"messages":    messages,
+			},
+		}
+		if err := encoder.Encode(line); err != nil {
+			return "", err
+		}
+	}
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	if err := writer.WriteField("purpose", "batch"); err != nil {
+		return "", err
+	}
+	file, err := writer.CreateFormFile("file", "slop-batch.jsonl")
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.Write(lines.Bytes()); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	var uploaded struct {
+		ID string `json:"id"`
+	}
+	if _, err := client.Do(ctx, http.MethodPost, client.Base+"/files", writer.FormDataContentType(), &form, &uploaded); err != nil {
+		return "", fmt.Errorf("failed to upload batch requests: %w", err)
+	}
+
+	data, err := json.Marshal(map[string]string{
+		"input_file_id":     uploaded.ID,
+		"endpoint":          "/v1/chat/completions",
+		"completion_window": "24h",
+	})
+	if err != nil {
+		return "", err
+	}
+	var batch struct {
+		ID string `json:"id"`
+	}
+	if _, err := client.Do(ctx, http.MethodPost, client.Base+"/batches", "application/json", bytes.NewReader(data), &batch); err != nil {
+		return "", fmt.Errorf("failed to submit batch: %w", err)
+	}
+	return batch.ID, nil
+}
+
+func (openAI) PollBatch(ctx context.Context, opts provider.Options, batchID string) (provider.BatchStatus, error) {
+	client, err := batchClient(opts)
+	if err != nil {
+		return provider.BatchStatus{}, err
+	}
+
+	var batch struct {
+		Status       string `json:"status"`
+		OutputFileID string `json:"output_file_id"`
+		ErrorFileID  string `json:"error_file_id"`
+	}
+	if _, err := client.Do(ctx, http.MethodGet, client.Base+"/batches/"+batchID, "", nil, &batch); err != nil {
+		return provider.BatchStatus{}, fmt.Errorf("failed to check batch: %w", err)
+	}
+	switch batch.Status {
+	case "completed":
+	case "failed", "expired", "cancelled":
+		return provider.BatchStatus{Done: true, Error: "batch " + batch.Status}, nil
+	default:
+		return provider.BatchStatus{}, nil
+	}
+
+	status := provider.BatchStatus{Done: true, Results: make(map[string]provider.BatchResult)}
+	// Successful and failed requests are written to separate files
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		data, err := client.Do(ctx, http.MethodGet, client.Base+"/files/"+fileID+"/content", "", nil, nil)
+		if err != nil {
+			return provider.BatchStatus{}, fmt.Errorf("failed to download batch results: %w", err)
+		}
+		err = provider.EachLine(data, func(line []byte) error {
+			var entry struct {
+				CustomID string `json:"custom_id"`
+				Response struct {
+					StatusCode int `json:"status_code"`
+					Body       struct {
+						Model   string `json:"model"`
+						Choices []struct {
+							Message struct {
+								Content string `json:"content"`
+							} `json:"message"`
+						} `json:"choices"`
+					} `json:"body"`
+				} `json:"response"`
+				Error json.RawMessage `json:"error"`
+			}
+			if err := json.Unmarshal(line, &entry); err != nil {
+				return err
+			}
+
+			result := provider.BatchResult{Model: entry.Response.Body.Model}
+			switch {
+			case len(entry.Error) > 0 && string(entry.Error) != "null":
+				result.Error = string(entry.Error)
+			case entry.Response.StatusCode != http.StatusOK:
+				result.Error = fmt.Sprintf("request failed with status code %d", entry.Response.StatusCode)
+			case len(entry.Response.Body.Choices) > 0:
+				result.Content = entry.Response.Body.Choices[0].Message.Content
+			}
+			status.Results[entry.CustomID] = result
+			return nil
+		})
+		if err != nil {
+			return provider.BatchStatus{}, err
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,38 @@
+// Package openai registers the OpenAI provider, which also serves any OpenAI
+// compatible server through the preset's baseURL
+package openai
+
+import (
+	"github.com/isaacphi/slop/internal/llm/provider"
+	"github.com/isaacphi/slop/internal/tokens"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+func init() {
+	provider.Register("openai", openAI{})
+}
+
+type openAI struct{}
+
+func (openAI) New(opts provider.Options) (llms.Model, error) {
+	clientOpts := []openai.Option{openai.WithModel(opts.Model)}
+	if opts.BaseURL != "" {
+		clientOpts = append(clientOpts, openai.WithBaseURL(opts.BaseURL))
+	}
+	if opts.APIKey != "" {
+		clientOpts = append(clientOpts, openai.WithToken(opts.APIKey))
+	}
+	if opts.HTTPClient != nil {
+		clientOpts = append(clientOpts, openai.WithHTTPClient(opts.HTTPClient))
+	}
+	return openai.New(clientOpts...)
+}
+
+func (openAI) Capabilities() provider.Capabilities {
+	return provider.Capabilities{KeyEnv: "OPENAI_API_KEY", BaseURL: true, Vision: true, Endpoint: "https://api.openai.com"}
+}
+
+func (openAI) Tokenizer() string {
+	return tokens.OpenAI
+}
@@ -0,0 +1,83 @@
+// Package provider is the registry of the model providers presets can use. Each
+// provider is a package that registers itself when it is imported, so adding one
+// doesn't touch the code that sends requests
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Options are the settings of a preset a client is created with
+type Options struct {
+	Model      string
+	BaseURL    string       // Address of the provider's API, empty for its default
+	APIKey     string       // Key to authenticate with, empty when the provider needs none
+	HTTPClient *http.Client // Client with the preset's proxy and CA settings, nil for the default
+}
+
+// Capabilities describes what a provider supports
+type Capabilities struct {
+	KeyEnv   string // Environment variable usually holding the API key, empty if the provider needs none
+	BaseURL  bool   // The client can be pointed at another server with the preset's baseURL
+	Vision   bool   // Models of the provider can be sent images
+	Local    bool   // Without a baseURL requests go to a server on this machine
+	Endpoint string // Address requests go to without a baseURL
+}
+
+// Provider creates clients for the models of one provider. Providers with a batch
+// API also implement Batcher
+type Provider interface {
+	New(opts Options) (llms.Model, error)
+	Capabilities() Capabilities
+	// Tokenizer names the tokenizer of package tokens that estimates the provider's
+	// tokens best
+	Tokenizer() string
+}
+
+var (
+	mu        sync.RWMutex
+	providers = make(map[string]Provider)
+)
+
+// Register makes a provider available to presets under name. Providers register in
+// an init function, registering a name twice panics
+func Register(name string, p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := providers[name]; ok {
+		panic(fmt.Sprintf("provider %s is registered twice", name))
+	}
+	providers[name] = p
+}
+
+// Get returns the provider registered under name
+func Get(name string) (Provider, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider %q, must be one of %v", name, namesLocked())
+	}
+	return p, nil
+}
+
+// Names lists the registered providers in alphabetical order
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return namesLocked()
+}
+
+func namesLocked() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
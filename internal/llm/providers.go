@@ -0,0 +1,9 @@
+package llm
+
+// Providers register themselves with package provider when they are imported
+import (
+	_ "github.com/isaacphi/slop/internal/llm/provider/anthropic"
+	_ "github.com/isaacphi/slop/internal/llm/provider/googleai"
+	_ "github.com/isaacphi/slop/internal/llm/provider/ollama"
+	_ "github.com/isaacphi/slop/internal/llm/provider/openai"
+)
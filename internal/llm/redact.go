@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/redact"
+)
+
+// redacted returns the options with secrets masked in all the text that is sent.
+// Images and other attachments are sent as they are
+func (opts GenerateContentOptions) redacted() GenerateContentOptions {
+	opts.Content = redact.Text(opts.Content)
+	opts.ContentParts = redactParts(opts.ContentParts)
+	opts.SystemMessage, opts.History = redactMessages(opts.SystemMessage, opts.History)
+	return opts
+}
+
+// Redacted returns the request with secrets masked in all of its text
+func (r BatchRequest) Redacted() BatchRequest {
+	r.Content = redact.Text(r.Content)
+	r.SystemMessage, r.History = redactMessages(r.SystemMessage, r.History)
+	return r
+}
+
+// redactMessages masks secrets in copies of a system message and history
+func redactMessages(systemMessage *domain.Message, history []domain.Message) (*domain.Message, []domain.Message) {
+	if systemMessage != nil {
+		masked := *systemMessage
+		masked.Content = redact.Text(masked.Content)
+		systemMessage = &masked
+	}
+	masked := make([]domain.Message, len(history))
+	for i, msg := range history {
+		msg.Content = redact.Text(msg.Content)
+		if msg.ToolCalls != "" {
+			msg.ToolCalls = redactToolCalls(msg.ToolCalls)
+		}
+		if msg.Parts != "" {
+			parts, err := msg.GetParts()
+			if err != nil || msg.SetParts(redactParts(parts)) != nil {
+				// Sent from the masked content instead
+				msg.Parts = ""
+			}
+		}
+		masked[i] = msg
+	}
+	return systemMessage, masked
+}
+
+// redactToolCalls masks secrets in the arguments of encoded tool calls. Arguments
+// that are no longer JSON once masked are sent as a JSON string
+func redactToolCalls(encoded string) string {
+	var calls []ToolCall
+	if err := json.Unmarshal([]byte(encoded), &calls); err != nil {
+		return redact.Text(encoded)
+	}
+	for i, call := range calls {
+		masked := redact.Text(string(call.Arguments))
+		if !json.Valid([]byte(masked)) {
+			masked = strconv.Quote(masked)
+		}
+		calls[i].Arguments = json.RawMessage(masked)
+	}
+	redacted, err := json.Marshal(calls)
+	if err != nil {
+		return redact.Text(encoded)
+	}
+	return string(redacted)
+}
+
+func redactParts(parts []domain.MessagePart) []domain.MessagePart {
+	if parts == nil {
+		return nil
+	}
+	masked := make([]domain.MessagePart, len(parts))
+	for i, part := range parts {
+		part.Content = redact.Text(part.Content)
+		masked[i] = part
+	}
+	return masked
+}
@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRedactToolCalls(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		want    string
+	}{
+		{
+			name:    "secret in the arguments is masked",
+			encoded: `[{"id":"call_0","name":"http","arguments":{"url":"https://x.dev","token":"abc123"}}]`,
+			want:    `[{"id":"call_0","name":"http","arguments":{"url":"https://x.dev","token":"[REDACTED secret]"}}]`,
+		},
+		{
+			name:    "arguments that are no longer json become a string",
+			encoded: `[{"id":"call_0","name":"run","arguments":{"n":1,"password":5}}]`,
+			want:    `[{"id":"call_0","name":"run","arguments":"{\"n\":1,\"password\":[REDACTED secret]}"}]`,
+		},
+		{
+			name:    "calls without secrets are unchanged",
+			encoded: `[{"id":"call_0","name":"read","arguments":{"path":"main.go"}}]`,
+			want:    `[{"id":"call_0","name":"read","arguments":{"path":"main.go"}}]`,
+		},
+		{
+			name:    "invalid json is masked as text",
+			encoded: `not json password=hunter2`,
+			want:    `not json password=[REDACTED secret]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactToolCalls(tt.encoded)
+			if got != tt.want {
+				t.Errorf("redactToolCalls() = %s, want %s", got, tt.want)
+			}
+			if tt.encoded[0] == '[' && !json.Valid([]byte(got)) {
+				t.Errorf("redactToolCalls() = %s is not valid JSON", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/slop/internal/config"
+)
+
+// defaultRetryStatusCodes are the status codes retried when a preset lists none
+var defaultRetryStatusCodes = []int{408, 429, 500, 502, 503, 504, 529}
+
+var (
+	// statusCodePattern finds the status code in the errors the provider SDKs return,
+	// such as "status code: 503" or "429 Too Many Requests"
+	statusCodePattern = regexp.MustCompile(`(?i)status(?:[ _]?code)?\W{0,3}(\d{3})\b|\b(\d{3}) (?:too many requests|internal server error|bad gateway|service unavailable|gateway timeout|request timeout)`)
+	// statusMessages are the reasons providers give without a status code
+	statusMessages = map[string]int{
+		"too many requests":     429,
+		"rate limit":            429,
+		"overloaded":            529,
+		"internal server error": 500,
+		"bad gateway":           502,
+		"service unavailable":   503,
+		"gateway timeout":       504,
+	}
+)
+
+// retryPolicy is how often and how patiently the requests of a preset are retried
+type retryPolicy struct {
+	attempts    int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	statusCodes []int
+}
+
+// newRetryPolicy reads the retry settings of a preset. Presets that didn't go through
+// config defaults, such as ones built in code, are never retried
+func newRetryPolicy(cfg config.Retry) retryPolicy {
+	p := retryPolicy{
+		attempts:    max(cfg.MaxAttempts, 1),
+		backoff:     time.Second,
+		maxBackoff:  30 * time.Second,
+		statusCodes: cfg.StatusCodes,
+	}
+	if d, err := time.ParseDuration(cfg.Backoff); err == nil {
+		p.backoff = d
+	}
+	if d, err := time.ParseDuration(cfg.MaxBackoff); err == nil {
+		p.maxBackoff = d
+	}
+	if len(p.statusCodes) == 0 {
+		p.statusCodes = defaultRetryStatusCodes
+	}
+	return p
+}
+
+// retryable reports whether a failed request is worth sending again: it timed out
+// or the provider answered with one of the retried status codes. Requests whose
+// context ended are never retried
+func (p retryPolicy) retryable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if timeout(err) {
+		return true
+	}
+	code := statusCode(err)
+	return code != 0 && slices.Contains(p.statusCodes, code)
+}
+
+// delay is how long to wait before an attempt, doubling from the backoff up to the
+// max backoff. Up to a tenth is added at random so clients don't retry in lockstep
+func (p retryPolicy) delay(attempt int) time.Duration {
+	d := p.backoff
+	for i := 2; i < attempt && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, p.maxBackoff)
+	if d > 0 {
+		d += rand.N(d/10 + 1)
+	}
+	return d
+}
+
+// withRetries sends a request until it succeeds, fails in a way that isn't worth
+// retrying, or runs out of attempts. send also reports whether the request may be sent
+// again at all, which streams can't once part of the response arrived. onRetry is told
+// about each retry before the wait and may be nil. The last error is returned
+func withRetries(ctx context.Context, p retryPolicy, send func() (bool, error), onRetry func(RetryEvent)) error {
+	for attempt := 1; ; attempt++ {
+		again, err := send()
+		if err == nil || !again || attempt >= p.attempts || !p.retryable(ctx, err) {
+			return err
+		}
+
+		wait := p.delay(attempt + 1)
+		if onRetry != nil {
+			onRetry(RetryEvent{Attempt: attempt + 1, MaxAttempts: p.attempts, Delay: wait, Error: err})
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// timeout reports whether err is a network or client timeout
+func timeout(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "timeout") || strings.Contains(msg, "timed out")
+}
+
+// statusCode returns the HTTP status code in a provider error, 0 if it has none
+func statusCode(err error) int {
+	msg := err.Error()
+	if m := statusCodePattern.FindStringSubmatch(msg); m != nil {
+		code := m[1]
+		if code == "" {
+			code = m[2]
+		}
+		if n, err := strconv.Atoi(code); err == nil {
+			return n
+		}
+	}
+	lower := strings.ToLower(msg)
+	for reason, code := range statusMessages {
+		if strings.Contains(lower, reason) {
+			return code
+		}
+	}
+	return 0
+}
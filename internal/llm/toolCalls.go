@@ -0,0 +1,231 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/errkind"
+	"github.com/isaacphi/slop/internal/events"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// toolCallChunk is one entry of a streamed tool call chunk. Providers send the ID and
+// name once, when the call starts, and then only pieces of the arguments. The index
+// tells the calls of a response apart when it is given
+type toolCallChunk struct {
+	Index    *int              `json:"index,omitempty"`
+	ID       *string           `json:"id,omitempty"`
+	Function FunctionCallChunk `json:"function"`
+}
+
+// streamedCall is the state of one tool call being streamed
+type streamedCall struct {
+	index   int
+	id      string
+	name    string
+	started bool                   // Whether the ToolCallStartEvent was sent
+	parser  *IncrementalJsonParser // nil until the tool is known or after the arguments failed to parse
+}
+
+// streamedCalls assembles the tool calls of a streamed response. Calls are tracked by
+// their stream index and ID, so chunks of calls that are interleaved or repeated
+// don't end up in the wrong call
+type streamedCalls struct {
+	tools   map[string]domain.Tool
+	byIndex map[int]*streamedCall
+	byID    map[string]*streamedCall
+	last    *streamedCall // The call that chunks without an index or ID continue
+	next    int           // Index given to calls the provider didn't index
+}
+
+func newStreamedCalls(tools map[string]domain.Tool) *streamedCalls {
+	return &streamedCalls{
+		tools:   tools,
+		byIndex: make(map[int]*streamedCall),
+		byID:    make(map[string]*streamedCall),
+	}
+}
+
+// process handles a tool call chunk and returns the events it results in
+func (s *streamedCalls) process(chunk []toolCallChunk) ([]events.Event, error) {
+	var out []events.Event
+	for _, c := range chunk {
+		call := s.call(c)
+		if call == nil {
+			// Arguments of a call whose ID hasn't arrived yet
+			continue
+		}
+		if c.Function.Name != "" && call.name == "" {
+			call.name = c.Function.Name
+		}
+		if call.name == "" {
+			continue
+		}
+		if !call.started {
+			call.started = true
+			out = append(out, &ToolCallStartEvent{FunctionName: call.name})
+			tool, ok := s.tools[call.name]
+			if !ok {
+				return out, errkind.New(errkind.ToolNotFound, fmt.Errorf("tool not found: %s", call.name))
+			}
+			call.parser = NewIncrementalJsonParser(&tool.Parameters)
+		}
+		if call.parser == nil || c.Function.ArgumentsJson == "" {
+			continue
+		}
+
+		updates, err := call.parser.ProcessChunk(c.Function.ArgumentsJson)
+		if err != nil {
+			// The updates only show the arguments as they arrive, the complete response
+			// still has them. Stop showing them rather than failing the response
+			slog.Debug("failed to parse streamed tool call arguments", "tool", call.name, "id", call.id, "error", err)
+			call.parser = nil
+			continue
+		}
+		for _, update := range updates {
+			out = append(out, &update)
+		}
+	}
+	return out, nil
+}
+
+// call returns the call a chunk entry belongs to, starting a new one when the entry
+// starts a call. It is nil for entries that can't be placed yet
+func (s *streamedCalls) call(c toolCallChunk) *streamedCall {
+	id := ""
+	if c.ID != nil {
+		id = *c.ID
+	}
+
+	var call *streamedCall
+	switch {
+	case id != "" && s.byID[id] != nil:
+		call = s.byID[id]
+	case c.Index != nil:
+		call = s.byIndex[*c.Index]
+		if call != nil && id != "" && call.id != "" {
+			// A new call sent with an index already in use, as some servers send every
+			// call with index 0. Later chunks with the index continue the new call
+			call = &streamedCall{index: s.free(nil)}
+			s.byIndex[call.index] = call
+			s.byIndex[*c.Index] = call
+		}
+	case id != "":
+		// A new call without an index
+	default:
+		call = s.last
+	}
+
+	if call == nil {
+		if id == "" && c.Index == nil {
+			return nil
+		}
+		call = &streamedCall{index: s.free(c.Index)}
+		s.byIndex[call.index] = call
+	}
+	if id != "" && call.id == "" {
+		call.id = id
+		s.byID[id] = call
+	}
+	s.last = call
+	return call
+}
+
+// free returns index when no call has it yet, or the next index no call has
+func (s *streamedCalls) free(index *int) int {
+	if index != nil {
+		if _, taken := s.byIndex[*index]; !taken {
+			s.next = max(s.next, *index+1)
+			return *index
+		}
+	}
+	for {
+		if _, taken := s.byIndex[s.next]; !taken {
+			return s.next
+		}
+		s.next++
+	}
+}
+
+// order returns the stream index of each call by ID
+func (s *streamedCalls) order() map[string]int {
+	order := make(map[string]int, len(s.byID))
+	for id, call := range s.byID {
+		order[id] = call.index
+	}
+	return order
+}
+
+// responseToolCalls collects the tool calls of a response in a deterministic order:
+// by stream index when order has the call, then in the order of the response. Calls
+// repeated with the same ID, name and arguments are kept once. Calls that share an ID
+// but differ, or that have no ID, get one of their own so their results can be told
+// apart
+func responseToolCalls(choices []*llms.ContentChoice, order map[string]int) []ToolCall {
+	toolCalls := make([]ToolCall, 0)
+	for _, choice := range choices {
+		for _, tc := range choice.ToolCalls {
+			if tc.FunctionCall == nil {
+				continue
+			}
+			toolCalls = append(toolCalls, ToolCall{
+				ID:        tc.ID,
+				Name:      tc.FunctionCall.Name,
+				Arguments: json.RawMessage(tc.FunctionCall.Arguments),
+			})
+		}
+	}
+
+	sort.SliceStable(toolCalls, func(i, j int) bool {
+		a, aStreamed := order[toolCalls[i].ID]
+		b, bStreamed := order[toolCalls[j].ID]
+		if aStreamed != bStreamed {
+			return aStreamed
+		}
+		return aStreamed && a < b
+	})
+
+	unique := make([]ToolCall, 0, len(toolCalls))
+	ids := make(map[string]bool)
+	for i, call := range toolCalls {
+		if call.ID == "" {
+			call.ID = fmt.Sprintf("call_%d", i+1)
+		}
+		if ids[call.ID] {
+			if duplicateToolCall(unique, call) {
+				continue
+			}
+			base := call.ID
+			for n := 2; ids[call.ID]; n++ {
+				call.ID = fmt.Sprintf("%s_%d", base, n)
+			}
+		}
+		ids[call.ID] = true
+		unique = append(unique, call)
+	}
+	return unique
+}
+
+// duplicateToolCall reports whether calls has a call with the same ID, name and
+// arguments as call
+func duplicateToolCall(calls []ToolCall, call ToolCall) bool {
+	for _, c := range calls {
+		if c.ID == call.ID && c.Name == call.Name && sameArguments(c.Arguments, call.Arguments) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameArguments compares encoded arguments ignoring spacing
+func sameArguments(a, b json.RawMessage) bool {
+	var compactA, compactB bytes.Buffer
+	if json.Compact(&compactA, a) != nil || json.Compact(&compactB, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(compactA.Bytes(), compactB.Bytes())
+}
@@ -0,0 +1,184 @@
+package llm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestStreamedCalls(t *testing.T) {
+	index := func(i int) *int { return &i }
+	id := func(s string) *string { return &s }
+	tools := map[string]domain.Tool{
+		"read": {Name: "read", Parameters: domain.Parameters{Type: "object", Properties: map[string]domain.Property{"path": {Type: "string"}}}},
+	}
+
+	tests := []struct {
+		name       string
+		chunks     [][]toolCallChunk
+		wantOrder  map[string]int    // Stream index of each call by ID
+		wantArgs   map[string]string // Arguments streamed to each call by ID
+		wantStarts int
+	}{
+		{
+			name: "indexed calls",
+			chunks: [][]toolCallChunk{
+				{{Index: index(0), ID: id("a"), Function: FunctionCallChunk{Name: "read"}}},
+				{{Index: index(1), ID: id("b"), Function: FunctionCallChunk{Name: "read"}}},
+				{{Index: index(0), Function: FunctionCallChunk{ArgumentsJson: `{"path":"a.go"}`}}},
+				{{Index: index(1), Function: FunctionCallChunk{ArgumentsJson: `{"path":"b.go"}`}}},
+			},
+			wantOrder:  map[string]int{"a": 0, "b": 1},
+			wantArgs:   map[string]string{"a": `{"path":"a.go"}`, "b": `{"path":"b.go"}`},
+			wantStarts: 2,
+		},
+		{
+			name: "index 0 reused for every call",
+			chunks: [][]toolCallChunk{
+				{{Index: index(0), ID: id("a"), Function: FunctionCallChunk{Name: "read"}}},
+				{{Index: index(0), Function: FunctionCallChunk{ArgumentsJson: `{"path":"a.go"}`}}},
+				{{Index: index(0), ID: id("b"), Function: FunctionCallChunk{Name: "read"}}},
+				{{Index: index(0), Function: FunctionCallChunk{ArgumentsJson: `{"path":"b.go"}`}}},
+			},
+			wantOrder:  map[string]int{"a": 0, "b": 1},
+			wantArgs:   map[string]string{"a": `{"path":"a.go"}`, "b": `{"path":"b.go"}`},
+			wantStarts: 2,
+		},
+		{
+			name: "ID arriving after its arguments",
+			chunks: [][]toolCallChunk{
+				{{Index: index(0), Function: FunctionCallChunk{ArgumentsJson: `{"path":"a.go"}`}}},
+				{{Index: index(0), ID: id("a"), Function: FunctionCallChunk{Name: "read"}}},
+			},
+			wantOrder:  map[string]int{"a": 0},
+			wantArgs:   map[string]string{"a": ""},
+			wantStarts: 1,
+		},
+		{
+			name: "calls without an index continue by ID and then the last call",
+			chunks: [][]toolCallChunk{
+				{{ID: id("a"), Function: FunctionCallChunk{Name: "read"}}},
+				{{ID: id("b"), Function: FunctionCallChunk{Name: "read"}}},
+				{{ID: id("a"), Function: FunctionCallChunk{ArgumentsJson: `{"path":"a.go"}`}}},
+				{{ID: id("b"), Function: FunctionCallChunk{ArgumentsJson: `{"path":`}}},
+				{{Function: FunctionCallChunk{ArgumentsJson: `"b.go"}`}}},
+			},
+			wantOrder:  map[string]int{"a": 0, "b": 1},
+			wantArgs:   map[string]string{"a": `{"path":"a.go"}`, "b": `{"path":"b.go"}`},
+			wantStarts: 2,
+		},
+		{
+			name: "arguments before any call are dropped",
+			chunks: [][]toolCallChunk{
+				{{Function: FunctionCallChunk{ArgumentsJson: `{"path":"x.go"}`}}},
+				{{Index: index(0), ID: id("a"), Function: FunctionCallChunk{Name: "read"}}},
+			},
+			wantOrder:  map[string]int{"a": 0},
+			wantArgs:   map[string]string{"a": ""},
+			wantStarts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStreamedCalls(tools)
+			starts := 0
+			for _, chunk := range tt.chunks {
+				out, err := s.process(chunk)
+				if err != nil {
+					t.Fatalf("process: %v", err)
+				}
+				for _, event := range out {
+					if _, ok := event.(*ToolCallStartEvent); ok {
+						starts++
+					}
+				}
+			}
+
+			if got := s.order(); !reflect.DeepEqual(got, tt.wantOrder) {
+				t.Errorf("order() = %v, want %v", got, tt.wantOrder)
+			}
+			if starts != tt.wantStarts {
+				t.Errorf("%d calls started, want %d", starts, tt.wantStarts)
+			}
+			for callID, want := range tt.wantArgs {
+				call := s.byID[callID]
+				if call == nil || call.parser == nil {
+					t.Errorf("call %s has no parser", callID)
+					continue
+				}
+				if got := call.parser.completeString.String(); got != want {
+					t.Errorf("arguments of %s = %q, want %q", callID, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestResponseToolCalls(t *testing.T) {
+	type call struct{ id, name, args string }
+	choices := func(calls ...call) []*llms.ContentChoice {
+		choice := &llms.ContentChoice{}
+		for _, c := range calls {
+			choice.ToolCalls = append(choice.ToolCalls, llms.ToolCall{
+				ID:           c.id,
+				FunctionCall: &llms.FunctionCall{Name: c.name, Arguments: c.args},
+			})
+		}
+		return []*llms.ContentChoice{choice}
+	}
+
+	tests := []struct {
+		name    string
+		choices []*llms.ContentChoice
+		order   map[string]int
+		want    []call
+	}{
+		{
+			name:    "ordered by stream index",
+			choices: choices(call{"b", "read", `{}`}, call{"a", "read", `{}`}),
+			order:   map[string]int{"a": 0, "b": 1},
+			want:    []call{{"a", "read", `{}`}, {"b", "read", `{}`}},
+		},
+		{
+			name:    "streamed calls come before the rest",
+			choices: choices(call{"x", "read", `{}`}, call{"a", "read", `{}`}),
+			order:   map[string]int{"a": 0},
+			want:    []call{{"a", "read", `{}`}, {"x", "read", `{}`}},
+		},
+		{
+			name:    "repeated call is kept once",
+			choices: choices(call{"a", "read", `{"path": "a.go"}`}, call{"a", "read", `{"path":"a.go"}`}),
+			want:    []call{{"a", "read", `{"path": "a.go"}`}},
+		},
+		{
+			name:    "duplicate ID with different arguments gets its own",
+			choices: choices(call{"a", "read", `{"path":"a.go"}`}, call{"a", "read", `{"path":"b.go"}`}),
+			want:    []call{{"a", "read", `{"path":"a.go"}`}, {"a_2", "read", `{"path":"b.go"}`}},
+		},
+		{
+			name:    "calls without an ID are numbered",
+			choices: choices(call{"", "read", `{"path":"a.go"}`}, call{"", "read", `{"path":"b.go"}`}),
+			want:    []call{{"call_1", "read", `{"path":"a.go"}`}, {"call_2", "read", `{"path":"b.go"}`}},
+		},
+		{
+			name:    "generated ID taken by the provider",
+			choices: choices(call{"", "read", `{"path":"a.go"}`}, call{"call_1", "read", `{"path":"b.go"}`}),
+			want:    []call{{"call_1", "read", `{"path":"a.go"}`}, {"call_1_2", "read", `{"path":"b.go"}`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []call
+			for _, tc := range responseToolCalls(tt.choices, tt.order) {
+				got = append(got, call{tc.ID, tc.Name, string(tc.Arguments)})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("responseToolCalls() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
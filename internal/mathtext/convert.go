@@ -0,0 +1,233 @@
+package mathtext
+
+import (
+	"strings"
+	"unicode"
+)
+
+// symbols maps commands to the characters they stand for
+var symbols = map[string]string{
+	// Greek letters
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ε", "varepsilon": "ε",
+	"zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ", "iota": "ι", "kappa": "κ",
+	"lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ", "pi": "π", "varpi": "ϖ", "rho": "ρ",
+	"varrho": "ϱ", "sigma": "σ", "varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "φ",
+	"varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ", "Pi": "Π",
+	"Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+
+	// Operators and relations
+	"times": "×", "cdot": "·", "cdotp": "·", "div": "÷", "pm": "±", "mp": "∓", "ast": "∗",
+	"star": "⋆", "circ": "∘", "bullet": "•", "oplus": "⊕", "otimes": "⊗",
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠", "ll": "≪", "gg": "≫",
+	"approx": "≈", "equiv": "≡", "sim": "∼", "simeq": "≃", "cong": "≅", "propto": "∝",
+	"in": "∈", "notin": "∉", "ni": "∋", "subset": "⊂", "subseteq": "⊆", "supset": "⊃",
+	"supseteq": "⊇", "cup": "∪", "cap": "∩", "setminus": "∖", "emptyset": "∅", "varnothing": "∅",
+	"forall": "∀", "exists": "∃", "nexists": "∄", "neg": "¬", "lnot": "¬", "land": "∧",
+	"wedge": "∧", "lor": "∨", "vee": "∨", "perp": "⊥", "parallel": "∥", "mid": "|",
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "gets": "←", "leftrightarrow": "↔",
+	"Rightarrow": "⇒", "Leftarrow": "⇐", "Leftrightarrow": "⇔", "implies": "⟹", "iff": "⟺",
+	"mapsto": "↦", "uparrow": "↑", "downarrow": "↓", "longrightarrow": "⟶",
+
+	// Big operators
+	"sum": "∑", "prod": "∏", "coprod": "∐", "int": "∫", "iint": "∬", "iiint": "∭",
+	"oint": "∮", "bigcup": "⋃", "bigcap": "⋂",
+
+	// Other symbols
+	"infty": "∞", "partial": "∂", "nabla": "∇", "hbar": "ℏ", "ell": "ℓ", "Re": "ℜ", "Im": "ℑ",
+	"aleph": "ℵ", "prime": "′", "degree": "°", "angle": "∠", "triangle": "△",
+	"ldots": "…", "dots": "…", "cdots": "⋯", "vdots": "⋮", "ddots": "⋱",
+	"langle": "⟨", "rangle": "⟩", "lceil": "⌈", "rceil": "⌉", "lfloor": "⌊", "rfloor": "⌋",
+	"vert": "|", "lvert": "|", "rvert": "|", "Vert": "‖", "lVert": "‖", "rVert": "‖", "|": "‖",
+
+	// Escaped characters and spacing
+	"{": "{", "}": "}", "_": "_", "%": "%", "$": "$", "&": "&", "#": "#",
+	",": " ", ";": " ", ":": " ", "!": "", " ": " ", "quad": "  ", "qquad": "    ",
+	"\\": "\n",
+}
+
+// ignored are commands that only size or space their argument
+var ignored = map[string]bool{
+	"left": true, "right": true, "big": true, "Big": true, "bigg": true, "Bigg": true,
+	"bigl": true, "bigr": true, "Bigl": true, "Bigr": true, "displaystyle": true,
+	"textstyle": true, "limits": true, "nolimits": true,
+}
+
+// textCommands show their argument as it is
+var textCommands = map[string]bool{
+	"text": true, "textrm": true, "textbf": true, "textit": true, "mathrm": true,
+	"mathbf": true, "mathit": true, "mathsf": true, "mathtt": true, "operatorname": true,
+	"boldsymbol": true, "mbox": true, "hat": true, "bar": true, "vec": true, "tilde": true,
+	"overline": true, "underline": true, "dot": true,
+}
+
+var doubleStruck = map[rune]rune{
+	'C': 'ℂ', 'H': 'ℍ', 'N': 'ℕ', 'P': 'ℙ', 'Q': 'ℚ', 'R': 'ℝ', 'Z': 'ℤ',
+}
+
+var superscripts = map[rune]rune{
+	'0': '⁰', '1': '¹', '2': '²', '3': '³', '4': '⁴', '5': '⁵', '6': '⁶', '7': '⁷', '8': '⁸',
+	'9': '⁹', '+': '⁺', '-': '⁻', '=': '⁼', '(': '⁽', ')': '⁾', 'a': 'ᵃ', 'b': 'ᵇ', 'c': 'ᶜ',
+	'd': 'ᵈ', 'e': 'ᵉ', 'f': 'ᶠ', 'g': 'ᵍ', 'h': 'ʰ', 'i': 'ⁱ', 'j': 'ʲ', 'k': 'ᵏ', 'l': 'ˡ',
+	'm': 'ᵐ', 'n': 'ⁿ', 'o': 'ᵒ', 'p': 'ᵖ', 'r': 'ʳ', 's': 'ˢ', 't': 'ᵗ', 'u': 'ᵘ', 'v': 'ᵛ',
+	'w': 'ʷ', 'x': 'ˣ', 'y': 'ʸ', 'z': 'ᶻ', 'T': 'ᵀ', '′': '′', '∗': '*',
+}
+
+var subscripts = map[rune]rune{
+	'0': '₀', '1': '₁', '2': '₂', '3': '₃', '4': '₄', '5': '₅', '6': '₆', '7': '₇', '8': '₈',
+	'9': '₉', '+': '₊', '-': '₋', '=': '₌', '(': '₍', ')': '₎', 'a': 'ₐ', 'e': 'ₑ', 'h': 'ₕ',
+	'i': 'ᵢ', 'j': 'ⱼ', 'k': 'ₖ', 'l': 'ₗ', 'm': 'ₘ', 'n': 'ₙ', 'o': 'ₒ', 'p': 'ₚ', 'r': 'ᵣ',
+	's': 'ₛ', 't': 'ₜ', 'u': 'ᵤ', 'v': 'ᵥ', 'x': 'ₓ',
+}
+
+// Convert approximates a LaTeX expression, without its delimiters, with Unicode
+func Convert(expr string) string {
+	c := &converter{src: []rune(expr)}
+	return c.convertUntil(0)
+}
+
+type converter struct {
+	src []rune
+	pos int
+}
+
+// convertUntil converts until the closing rune, or to the end when closing is 0
+func (c *converter) convertUntil(closing rune) string {
+	var b strings.Builder
+	for c.pos < len(c.src) {
+		r := c.src[c.pos]
+		if closing != 0 && r == closing {
+			c.pos++
+			break
+		}
+		c.pos++
+		switch r {
+		case '\\':
+			b.WriteString(c.command())
+		case '{':
+			b.WriteString(c.convertUntil('}'))
+		case '^':
+			b.WriteString(script(c.argument(), superscripts, "^"))
+		case '_':
+			b.WriteString(script(c.argument(), subscripts, "_"))
+		case '&':
+			// Alignment points of multi-line equations
+		case '~':
+			b.WriteRune(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// command converts the command starting after a backslash
+func (c *converter) command() string {
+	name := c.commandName()
+	switch {
+	case name == "":
+		return ""
+	case ignored[name]:
+		// \left( keeps the delimiter, \left. has none
+		if c.pos < len(c.src) && c.src[c.pos] == '.' {
+			c.pos++
+		}
+		return ""
+	case name == "frac" || name == "dfrac" || name == "tfrac":
+		num, den := c.argument(), c.argument()
+		return group(num) + "/" + group(den)
+	case name == "sqrt":
+		if c.pos < len(c.src) && c.src[c.pos] == '[' {
+			c.pos++
+			c.convertUntil(']')
+		}
+		return "√" + group(c.argument())
+	case name == "mathbb":
+		arg := c.argument()
+		var b strings.Builder
+		for _, r := range arg {
+			if ds, ok := doubleStruck[r]; ok {
+				r = ds
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	case name == "begin" || name == "end":
+		// Environments such as aligned or cases are flattened to their lines
+		c.argument()
+		return ""
+	case textCommands[name]:
+		return c.argument()
+	}
+	if symbol, ok := symbols[name]; ok {
+		return symbol
+	}
+	// Functions such as \sin or \log are written as their names
+	return name
+}
+
+// commandName reads the letters of a command name, or the single character of a
+// command such as \{ or \,
+func (c *converter) commandName() string {
+	if c.pos >= len(c.src) {
+		return ""
+	}
+	start := c.pos
+	for c.pos < len(c.src) && unicode.IsLetter(c.src[c.pos]) {
+		c.pos++
+	}
+	if c.pos == start {
+		c.pos++
+	}
+	return string(c.src[start:c.pos])
+}
+
+// argument converts the next group, command or character
+func (c *converter) argument() string {
+	for c.pos < len(c.src) && c.src[c.pos] == ' ' {
+		c.pos++
+	}
+	if c.pos >= len(c.src) {
+		return ""
+	}
+	r := c.src[c.pos]
+	c.pos++
+	switch r {
+	case '{':
+		return c.convertUntil('}')
+	case '\\':
+		return c.command()
+	}
+	return string(r)
+}
+
+// script writes text as superscript or subscript characters, or with a ^ or _ marker
+// when some of them have no such character
+func script(text string, chars map[rune]rune, marker string) string {
+	var b strings.Builder
+	for _, r := range text {
+		s, ok := chars[r]
+		if !ok {
+			return marker + group(text)
+		}
+		b.WriteRune(s)
+	}
+	return b.String()
+}
+
+// group wraps text in parentheses unless it reads as a single term
+func group(text string) string {
+	if len([]rune(text)) <= 1 || isTerm(text) {
+		return text
+	}
+	return "(" + text + ")"
+}
+
+func isTerm(text string) bool {
+	for _, r := range text {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+// Package mathtext finds LaTeX math in responses and approximates it with Unicode so
+// it reads well in a terminal
+package mathtext
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// $$...$$ and \[...\] hold display math, \(...\) holds inline math
+	displayDollars  = regexp.MustCompile(`(?s)\$\$(.+?)\$\$`)
+	displayBrackets = regexp.MustCompile(`(?s)\\\[(.+?)\\\]`)
+	inlineParens    = regexp.MustCompile(`\\\((.+?)\\\)`)
+	// $...$ only counts as math when the dollars hug the expression, so prices such
+	// as "$5 and $10" are left alone
+	inlineDollars = regexp.MustCompile(`\$([^\s$](?:[^$\n]*?[^\s$\\])?)\$`)
+)
+
+// Contains reports whether text has math outside of code
+func Contains(text string) bool {
+	found := false
+	outsideCode(text, func(prose string) string {
+		if !found {
+			found = displayDollars.MatchString(prose) || displayBrackets.MatchString(prose) ||
+				inlineParens.MatchString(prose) || len(inlineMath(prose)) > 0
+		}
+		return prose
+	})
+	return found
+}
+
+// ToUnicode replaces the math in text with Unicode approximations, such as α² + β²
+// for $\alpha^2 + \beta^2$. Code blocks and code spans are left unchanged
+func ToUnicode(text string) string {
+	return outsideCode(text, func(prose string) string {
+		for _, pattern := range []*regexp.Regexp{displayDollars, displayBrackets, inlineParens} {
+			prose = pattern.ReplaceAllStringFunc(prose, func(match string) string {
+				return strings.TrimSpace(Convert(pattern.FindStringSubmatch(match)[1]))
+			})
+		}
+
+		var b strings.Builder
+		pos := 0
+		for _, loc := range inlineMath(prose) {
+			b.WriteString(prose[pos:loc[0]])
+			b.WriteString(Convert(prose[loc[2]:loc[3]]))
+			pos = loc[1]
+		}
+		b.WriteString(prose[pos:])
+		return b.String()
+	})
+}
+
+// Delimit rewrites inline $...$ math as \(...\), so renderers such as KaTeX don't
+// have to guess which dollar signs are prices
+func Delimit(text string) string {
+	return outsideCode(text, func(prose string) string {
+		var b strings.Builder
+		pos := 0
+		for _, loc := range inlineMath(prose) {
+			b.WriteString(prose[pos:loc[0]])
+			b.WriteString(`\(` + prose[loc[2]:loc[3]] + `\)`)
+			pos = loc[1]
+		}
+		b.WriteString(prose[pos:])
+		return b.String()
+	})
+}
+
+// inlineMath finds $...$ expressions, skipping ones followed by a digit such as the
+// first dollar of "$5-$10"
+func inlineMath(prose string) [][]int {
+	var found [][]int
+	for _, loc := range inlineDollars.FindAllStringSubmatchIndex(prose, -1) {
+		if loc[1] < len(prose) && unicode.IsDigit(rune(prose[loc[1]])) {
+			continue
+		}
+		found = append(found, loc)
+	}
+	return found
+}
+
+// outsideCode applies convert to the parts of markdown text that aren't fenced code
+// blocks or inline code spans
+func outsideCode(text string, convert func(string) string) string {
+	var b strings.Builder
+	var prose strings.Builder
+	flush := func() {
+		b.WriteString(convertSpans(prose.String(), convert))
+		prose.Reset()
+	}
+
+	inFence := false
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if !inFence {
+				flush()
+			}
+			inFence = !inFence
+			b.WriteString(line)
+			continue
+		}
+		if inFence {
+			b.WriteString(line)
+		} else {
+			prose.WriteString(line)
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// convertSpans applies convert between `code spans`
+func convertSpans(text string, convert func(string) string) string {
+	pieces := strings.Split(text, "`")
+	// An unmatched backtick doesn't start a code span
+	for i := range pieces {
+		if i%2 == 0 || i == len(pieces)-1 {
+			pieces[i] = convert(pieces[i])
+		}
+	}
+	return strings.Join(pieces, "`")
+}
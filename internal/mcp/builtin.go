@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// Builtin is a server implemented by slop itself instead of an external process. Its
+// tools are offered and called like those of any other server
+type Builtin interface {
+	Tools() map[string]domain.Tool
+	CallTool(ctx context.Context, toolName string, arguments map[string]any) (string, error)
+}
+
+var (
+	builtinsMu sync.RWMutex
+	builtins   = make(map[string]Builtin)
+)
+
+// RegisterBuiltin makes a built-in server available to every client created after it.
+// A configured server with the same name takes precedence
+func RegisterBuiltin(name string, server Builtin) {
+	builtinsMu.Lock()
+	defer builtinsMu.Unlock()
+	builtins[name] = server
+}
+
+// IsBuiltin reports whether name is a registered built-in server
+func IsBuiltin(name string) bool {
+	builtinsMu.RLock()
+	defer builtinsMu.RUnlock()
+	_, ok := builtins[name]
+	return ok
+}
+
+type threadKey struct{}
+
+// WithThread returns a context for tool calls made in a thread, so built-in servers
+// can act on the conversation they were called from
+func WithThread(ctx context.Context, threadID uuid.UUID) context.Context {
+	return context.WithValue(ctx, threadKey{}, threadID)
+}
+
+// ThreadFrom returns the thread a tool call was made in. ok is false for calls made
+// outside of a conversation, such as with slop mcp call
+func ThreadFrom(ctx context.Context) (threadID uuid.UUID, ok bool) {
+	threadID, ok = ctx.Value(threadKey{}).(uuid.UUID)
+	return threadID, ok
+}
+
+// registeredBuiltins returns the built-in servers that aren't shadowed by a configured one
+func (c *Client) registeredBuiltins() map[string]Builtin {
+	builtinsMu.RLock()
+	defer builtinsMu.RUnlock()
+
+	result := make(map[string]Builtin, len(builtins))
+	for name, server := range builtins {
+		if _, configured := c.Servers[name]; !configured {
+			result[name] = server
+		}
+	}
+	return result
+}
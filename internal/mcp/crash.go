@@ -0,0 +1,144 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"time"
+
+	"github.com/isaacphi/slop/internal/errkind"
+)
+
+const (
+	// restartDelay is how long after a server exits it is first restarted. The delay
+	// doubles after each failed restart, up to maxRestartDelay
+	restartDelay    = time.Second
+	maxRestartDelay = 30 * time.Second
+	// maxRestarts is how many restarts are tried before the server is left stopped
+	maxRestarts = 5
+	// restartTimeout is how long a restarted server has to start and list its tools
+	restartTimeout = 30 * time.Second
+)
+
+// ServerExit reports an MCP server that exited on its own, or that could not be
+// restarted after it did
+type ServerExit struct {
+	Server     string
+	Error      error
+	Restarting bool // False once restarting the server was given up on
+}
+
+// outage is why a server that exited on its own is not running
+type outage struct {
+	err        error
+	restarting bool
+}
+
+// watch starts waiting for the process of a server to exit. It must be called with
+// mu held, right after the process is registered in commands
+func (c *Client) watch(name string, cmd *exec.Cmd) {
+	exited := make(chan struct{})
+	c.exited[name] = exited
+	go func() {
+		err := cmd.Wait()
+		close(exited)
+		c.exit(name, cmd, err)
+	}()
+}
+
+// exit marks a server whose process exited as down and restarts it, unless the
+// process was stopped on purpose for being idle, by a reload or on shutdown
+func (c *Client) exit(name string, cmd *exec.Cmd, err error) {
+	c.mu.Lock()
+	if !c.initialized || c.commands[name] != cmd {
+		c.mu.Unlock()
+		return
+	}
+	if err == nil {
+		err = errors.New("exit status 0")
+	}
+	delete(c.clients, name)
+	delete(c.commands, name)
+	delete(c.calls, name)
+	delete(c.exited, name)
+	c.down[name] = outage{err: err, restarting: true}
+	c.exits = append(c.exits, ServerExit{Server: name, Error: err, Restarting: true})
+	stop := c.stop
+	c.mu.Unlock()
+
+	slog.Warn("MCP server exited, restarting it", "server", name, "error", err)
+	c.restart(name, stop)
+}
+
+// restart starts a server that exited again, waiting longer after each failed
+// attempt. It stops when the server was reloaded or removed in the meantime
+func (c *Client) restart(name string, stop <-chan struct{}) {
+	delay := restartDelay
+	var err error
+	for attempt := 1; attempt <= maxRestarts; attempt++ {
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+
+		c.mu.RLock()
+		server, configured := c.Servers[name]
+		_, down := c.down[name]
+		c.mu.RUnlock()
+		if !configured || !down {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), restartTimeout)
+		_, err = c.Reload(ctx, name, server)
+		cancel()
+		if err == nil {
+			slog.Info("restarted MCP server", "server", name, "attempt", attempt)
+			return
+		}
+		slog.Warn("failed to restart MCP server", "server", name, "attempt", attempt, "error", err)
+		delay = min(2*delay, maxRestartDelay)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if o, down := c.down[name]; down && c.initialized {
+		o.restarting = false
+		c.down[name] = o
+		c.exits = append(c.exits, ServerExit{Server: name, Error: err, Restarting: false})
+		slog.Warn("gave up restarting MCP server", "server", name, "attempts", maxRestarts)
+	}
+}
+
+// Down returns why a server is not running after it exited on its own, or nil when
+// it is running or was stopped on purpose. Its tools can't be called until it is
+// running again
+func (c *Client) Down(name string) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if o, down := c.down[name]; down {
+		return c.downError(name, o)
+	}
+	return nil
+}
+
+// Exits returns the servers that exited, or that restarting was given up on, since
+// the last call
+func (c *Client) Exits() []ServerExit {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	exits := c.exits
+	c.exits = nil
+	return exits
+}
+
+// downError is the error tool calls against a server that exited fail with
+func (c *Client) downError(name string, o outage) error {
+	if o.restarting {
+		return errkind.New(errkind.ServerDown, fmt.Errorf("MCP server %s exited (%v) and is being restarted", name, o.err))
+	}
+	return errkind.New(errkind.ServerDown, fmt.Errorf("MCP server %s exited (%v) and could not be restarted", name, o.err))
+}
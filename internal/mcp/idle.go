@@ -0,0 +1,176 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	// monitorInterval is how often servers are checked for idleness and due pings
+	monitorInterval = time.Second
+	// pingTimeout is how long a server has to answer a ping before it is restarted
+	pingTimeout = 10 * time.Second
+)
+
+// monitor stops servers that have been idle for longer than their idleTimeout and
+// restarts servers that don't answer pings, until stop is closed
+func (c *Client) monitor(stop <-chan struct{}) {
+	ticker := time.NewTicker(monitorInterval)
+	defer ticker.Stop()
+	pinged := make(map[string]time.Time)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			c.stopIdle(now)
+			for _, name := range c.duePings(now, pinged) {
+				go c.ping(name, stop)
+			}
+		}
+	}
+}
+
+// stopIdle stops the servers with no calls in flight that haven't been used for their
+// idleTimeout. Their tools stay registered so the model can still call them
+func (c *Client) stopIdle(now time.Time) {
+	var idle []*exec.Cmd
+	c.mu.Lock()
+	for name, server := range c.Servers {
+		timeout := duration(server.IdleTimeout)
+		if _, running := c.clients[name]; !running || timeout == 0 {
+			continue
+		}
+		if c.busy[name] > 0 || now.Sub(c.used[name]) < timeout {
+			continue
+		}
+		idle = append(idle, c.commands[name])
+		delete(c.clients, name)
+		delete(c.commands, name)
+		delete(c.calls, name)
+		delete(c.exited, name)
+		slog.Info("stopped idle MCP server", "server", name, "idleTimeout", timeout)
+	}
+	c.mu.Unlock()
+
+	// The processes are waited for by watch
+	for _, cmd := range idle {
+		if cmd != nil && cmd.Process != nil {
+			_ = cmd.Process.Kill()
+		}
+	}
+}
+
+// duePings returns the running servers whose pingInterval has passed since they were
+// last pinged, leaving out servers that haven't answered the last ping yet
+func (c *Client) duePings(now time.Time, pinged map[string]time.Time) []string {
+	var due []string
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, server := range c.Servers {
+		interval := duration(server.PingInterval)
+		if _, running := c.clients[name]; !running || interval == 0 || c.pinging[name] {
+			continue
+		}
+		if last, ok := pinged[name]; !ok {
+			// The first ping is one interval after the server is first seen
+			pinged[name] = now
+			continue
+		} else if now.Sub(last) < interval {
+			continue
+		}
+		pinged[name] = now
+		c.pinging[name] = true
+		due = append(due, name)
+	}
+	return due
+}
+
+// ping checks that a server still answers and restarts it when it doesn't
+func (c *Client) ping(name string, stop <-chan struct{}) {
+	c.mu.RLock()
+	client := c.clients[name]
+	c.mu.RUnlock()
+
+	var err error
+	if client != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+		err = client.Ping(ctx)
+		cancel()
+	}
+
+	c.mu.Lock()
+	c.pinging[name] = false
+	server, configured := c.Servers[name]
+	// Skip servers that were stopped or reloaded while the ping was waiting
+	current := c.clients[name] == client
+	c.mu.Unlock()
+
+	select {
+	case <-stop:
+		return
+	default:
+	}
+	if err == nil || !configured || !current {
+		return
+	}
+
+	slog.Warn("MCP server did not answer a ping, restarting it", "server", name, "error", err)
+	if _, err := c.Reload(context.Background(), name, server); err != nil {
+		slog.Warn("failed to restart MCP server", "server", name, "error", err)
+	}
+}
+
+// stopped reports whether a configured server is not running because it was idle
+func (c *Client) stopped(name string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, configured := c.Servers[name]
+	_, running := c.clients[name]
+	_, down := c.down[name]
+	return c.initialized && configured && !running && !down
+}
+
+// wake starts a server stopped for being idle again, keeping the tools it had
+func (c *Client) wake(ctx context.Context, name string) error {
+	c.wakeMu.Lock()
+	defer c.wakeMu.Unlock()
+	if !c.stopped(name) {
+		// Another call started it first
+		return nil
+	}
+
+	c.mu.RLock()
+	server := c.Servers[name]
+	c.mu.RUnlock()
+
+	client, cmd, err := launchServer(ctx, name, server)
+	if err != nil {
+		return fmt.Errorf("failed to start idle server %s: %w", name, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.initialized {
+		_ = cmd.Process.Kill()
+		return fmt.Errorf("server %s not found", name)
+	}
+	c.clients[name] = client
+	c.commands[name] = cmd
+	c.calls[name] = &sync.WaitGroup{}
+	c.used[name] = time.Now()
+	c.watch(name, cmd)
+	slog.Info("started idle MCP server again", "server", name)
+	return nil
+}
+
+// duration parses a validated duration from the config, empty is 0
+func duration(s string) time.Duration {
+	d, _ := time.ParseDuration(s)
+	return d
+}
@@ -7,9 +7,11 @@ import (
 	"runtime/debug"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/isaacphi/slop/internal/config"
 	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/errkind"
 	mcp_golang "github.com/metoro-io/mcp-golang"
 	"github.com/metoro-io/mcp-golang/transport/stdio"
 	"github.com/pkg/errors"
@@ -21,6 +23,16 @@ type Client struct {
 	clients     map[string]*mcp_golang.Client
 	commands    map[string]*exec.Cmd
 	tools       map[string]map[string]domain.Tool
+	calls       map[string]*sync.WaitGroup // In-flight tool calls for each server
+	busy        map[string]int             // Number of in-flight tool calls for each server
+	used        map[string]time.Time       // When each server was started or last finished a tool call
+	pinging     map[string]bool            // Servers with a ping waiting for an answer
+	exited      map[string]chan struct{}   // Closed when the process of each running server exits
+	down        map[string]outage          // Servers that exited on their own
+	exits       []ServerExit               // Exits not yet returned by Exits
+	stop        chan struct{}              // Closed on shutdown to stop the monitor
+	wakeMu      sync.Mutex                 // Serializes restarting servers stopped for being idle
+	safeMode    string                     // Why the client runs without its servers, empty when it doesn't
 	mu          sync.RWMutex
 	initialized bool
 }
@@ -32,6 +44,12 @@ func New(servers map[string]config.MCPServer) *Client {
 		clients:  make(map[string]*mcp_golang.Client),
 		commands: make(map[string]*exec.Cmd),
 		tools:    make(map[string]map[string]domain.Tool),
+		calls:    make(map[string]*sync.WaitGroup),
+		busy:     make(map[string]int),
+		used:     make(map[string]time.Time),
+		pinging:  make(map[string]bool),
+		exited:   make(map[string]chan struct{}),
+		down:     make(map[string]outage),
 	}
 }
 
@@ -73,37 +91,54 @@ func (c *Client) Initialize(ctx context.Context) error {
 
 	c.mu.Lock()
 	c.initialized = true
+	c.stop = make(chan struct{})
+	go c.monitor(c.stop)
 	c.mu.Unlock()
 	return nil
 }
 
 // startServer starts a single server and establishes its client connection
 func (c *Client) startServer(ctx context.Context, name string, server config.MCPServer) error {
+	client, cmd, err := launchServer(ctx, name, server)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.clients[name] = client
+	c.commands[name] = cmd
+	c.calls[name] = &sync.WaitGroup{}
+	c.used[name] = time.Now()
+	c.watch(name, cmd)
+	c.mu.Unlock()
+
+	return nil
+}
+
+// launchServer starts a server process and initializes a client connected to it
+func launchServer(ctx context.Context, name string, server config.MCPServer) (*mcp_golang.Client, *exec.Cmd, error) {
 	parts := strings.Split(name, "__")
 	if len(parts) != 1 {
-		return fmt.Errorf("invalid server name format, can't contain '__', got '%s'", name)
+		return nil, nil, fmt.Errorf("invalid server name format, can't contain '__', got '%s'", name)
 	}
 
-	cmd := exec.Command(server.Command, server.Args...)
-
-	if server.Env != nil {
-		for k, v := range server.Env {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
-		}
+	cmd, err := serverCommand(server)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
-		return errors.Wrap(err, "failed to get stdin pipe")
+		return nil, nil, errors.Wrap(err, "failed to get stdin pipe")
 	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return errors.Wrap(err, "failed to get stdout pipe")
+		return nil, nil, errors.Wrap(err, "failed to get stdout pipe")
 	}
 
 	if err := cmd.Start(); err != nil {
-		return errors.Wrap(err, "failed to start server")
+		return nil, nil, errors.Wrap(err, "failed to start server")
 	}
 
 	transport := stdio.NewStdioServerTransportWithIO(stdout, stdin)
@@ -112,19 +147,15 @@ func (c *Client) startServer(ctx context.Context, name string, server config.MCP
 	// Initialize with client name and version
 	info, ok := debug.ReadBuildInfo()
 	if !ok {
-		return fmt.Errorf("no build info available")
+		_ = cmd.Process.Kill()
+		return nil, nil, fmt.Errorf("no build info available")
 	}
 	if _, err := client.Initialize(ctx, "slop", info.Main.Version); err != nil {
 		_ = cmd.Process.Kill()
-		return errors.Wrap(err, "failed to initialize client")
+		return nil, nil, errors.Wrap(err, "failed to initialize client")
 	}
 
-	c.mu.Lock()
-	c.clients[name] = client
-	c.commands[name] = cmd
-	c.mu.Unlock()
-
-	return nil
+	return client, cmd, nil
 }
 
 func (c *Client) buildToolRegistry(ctx context.Context) error {
@@ -134,33 +165,46 @@ func (c *Client) buildToolRegistry(ctx context.Context) error {
 	c.tools = make(map[string]map[string]domain.Tool)
 
 	for serverName, client := range c.clients {
-		response, err := client.ListTools(ctx, nil)
+		tools, err := listServerTools(ctx, client)
 		if err != nil {
 			return errors.Wrapf(err, "failed to list tools for server %s", serverName)
 		}
+		c.tools[serverName] = tools
+	}
+	for serverName, server := range c.registeredBuiltins() {
+		c.tools[serverName] = server.Tools()
+	}
 
-		c.tools[serverName] = make(map[string]domain.Tool)
+	return nil
+}
 
-		for _, mcpTool := range response.Tools {
-			description := ""
-			if mcpTool.Description != nil {
-				description = *mcpTool.Description
-			}
+// listServerTools fetches the tools a server provides
+func listServerTools(ctx context.Context, client *mcp_golang.Client) (map[string]domain.Tool, error) {
+	response, err := client.ListTools(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
 
-			var params domain.Parameters
-			if schema, ok := mcpTool.InputSchema.(map[string]interface{}); ok {
-				params = parseSchema(schema)
-			}
+	tools := make(map[string]domain.Tool)
+	for _, mcpTool := range response.Tools {
+		description := ""
+		if mcpTool.Description != nil {
+			description = *mcpTool.Description
+		}
 
-			c.tools[serverName][mcpTool.Name] = domain.Tool{
-				Name:        mcpTool.Name,
-				Description: description,
-				Parameters:  params,
-			}
+		var params domain.Parameters
+		if schema, ok := mcpTool.InputSchema.(map[string]interface{}); ok {
+			params = parseSchema(schema)
+		}
+
+		tools[mcpTool.Name] = domain.Tool{
+			Name:        mcpTool.Name,
+			Description: description,
+			Parameters:  params,
 		}
 	}
 
-	return nil
+	return tools, nil
 }
 
 func parseSchema(schema map[string]interface{}) domain.Parameters {
@@ -246,17 +290,87 @@ func parseProperty(propMap map[string]interface{}) domain.Property {
 	return property
 }
 
-// CallTool calls a tool on a specific server
+// CallTool calls a tool on a specific server. Calls against a server that exited fail
+// right away, including calls that were waiting for it when it exited
 func (c *Client) CallTool(ctx context.Context, serverName string, toolName string, arguments interface{}) (*mcp_golang.ToolResponse, error) {
-	c.mu.RLock()
-	client, exists := c.clients[serverName]
-	c.mu.RUnlock()
+	if err := c.Down(serverName); err != nil {
+		return nil, err
+	}
+	client, calls, exited, exists := c.acquire(serverName)
+	if !exists && c.stopped(serverName) {
+		// Servers stopped for being idle are started again when they are needed
+		if err := c.wake(ctx, serverName); err != nil {
+			return nil, err
+		}
+		client, calls, exited, exists = c.acquire(serverName)
+	}
 
 	if !exists {
+		if server, ok := c.registeredBuiltins()[serverName]; ok {
+			args, _ := arguments.(map[string]any)
+			text, err := server.CallTool(ctx, toolName, args)
+			if err != nil {
+				return nil, err
+			}
+			return &mcp_golang.ToolResponse{Content: []*mcp_golang.Content{{
+				Type:        mcp_golang.ContentTypeText,
+				TextContent: &mcp_golang.TextContent{Text: text},
+			}}}, nil
+		}
 		return nil, fmt.Errorf("server %s not found", serverName)
 	}
+	defer c.release(serverName, calls)
+
+	type result struct {
+		response *mcp_golang.ToolResponse
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		response, err := client.CallTool(ctx, toolName, arguments)
+		done <- result{response, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.response, r.err
+	case <-exited:
+		// The connection never answers calls once the process is gone
+		select {
+		case r := <-done:
+			return r.response, r.err
+		default:
+		}
+		if err := c.Down(serverName); err != nil {
+			return nil, err
+		}
+		return nil, errkind.New(errkind.ServerDown, fmt.Errorf("MCP server %s stopped during the tool call", serverName))
+	}
+}
+
+// acquire returns the connection to a running server, and a channel closed when its
+// process exits, and registers a call on it so neither a reload nor the idle timeout
+// stops the server mid call
+func (c *Client) acquire(serverName string) (*mcp_golang.Client, *sync.WaitGroup, <-chan struct{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	client, exists := c.clients[serverName]
+	if !exists {
+		return nil, nil, nil, false
+	}
+	calls := c.calls[serverName]
+	calls.Add(1)
+	c.busy[serverName]++
+	return client, calls, c.exited[serverName], true
+}
 
-	return client.CallTool(ctx, toolName, arguments)
+// release ends a call registered by acquire
+func (c *Client) release(serverName string, calls *sync.WaitGroup) {
+	c.mu.Lock()
+	c.busy[serverName]--
+	c.used[serverName] = time.Now()
+	c.mu.Unlock()
+	calls.Done()
 }
 
 func (c *Client) GetTools() map[string]map[string]domain.Tool {
@@ -282,6 +396,7 @@ func (c *Client) Shutdown() {
 	if !c.initialized {
 		return
 	}
+	close(c.stop)
 
 	var wg sync.WaitGroup
 	errs := make(chan error, len(c.commands))
@@ -304,5 +419,12 @@ func (c *Client) Shutdown() {
 	c.commands = make(map[string]*exec.Cmd)
 	c.clients = make(map[string]*mcp_golang.Client)
 	c.tools = make(map[string]map[string]domain.Tool)
+	c.calls = make(map[string]*sync.WaitGroup)
+	c.busy = make(map[string]int)
+	c.used = make(map[string]time.Time)
+	c.pinging = make(map[string]bool)
+	c.exited = make(map[string]chan struct{})
+	c.down = make(map[string]outage)
+	c.exits = nil
 	c.initialized = false
 }
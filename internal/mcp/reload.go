@@ -0,0 +1,106 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// reloadGracePeriod is how long a reload waits for in-flight tool calls on the old
+// server before stopping it anyway
+const reloadGracePeriod = 30 * time.Second
+
+// ReloadResult describes how a server's tools changed during a reload
+type ReloadResult struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+}
+
+// Reload restarts a single server with the given configuration and re-registers its
+// tools. The new server is started before the old one is stopped, so other servers
+// and tool calls already running against the old server are not interrupted.
+// If the new server fails to start, the old one keeps running. Reloading a server
+// that exited brings its tools back
+func (c *Client) Reload(ctx context.Context, name string, server config.MCPServer) (ReloadResult, error) {
+	client, cmd, err := launchServer(ctx, name, server)
+	if err != nil {
+		return ReloadResult{}, fmt.Errorf("server %s failed: %w", name, err)
+	}
+
+	tools, err := listServerTools(ctx, client)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		return ReloadResult{}, fmt.Errorf("failed to list tools for server %s: %w", name, err)
+	}
+
+	c.mu.Lock()
+	if !c.initialized {
+		c.mu.Unlock()
+		_ = cmd.Process.Kill()
+		return ReloadResult{}, fmt.Errorf("server %s can't be reloaded after shutdown", name)
+	}
+	oldCmd := c.commands[name]
+	oldCalls := c.calls[name]
+	oldTools := c.tools[name]
+	oldExited := c.exited[name]
+
+	c.Servers[name] = server
+	c.clients[name] = client
+	c.commands[name] = cmd
+	c.calls[name] = &sync.WaitGroup{}
+	c.tools[name] = tools
+	c.used[name] = time.Now()
+	delete(c.down, name)
+	c.watch(name, cmd)
+	c.mu.Unlock()
+
+	// Let in-flight calls on the old server finish before stopping it
+	if oldCalls != nil {
+		done := make(chan struct{})
+		go func() {
+			oldCalls.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(reloadGracePeriod):
+		case <-ctx.Done():
+		}
+	}
+	if oldCmd != nil && oldCmd.Process != nil {
+		_ = oldCmd.Process.Kill()
+		if oldExited != nil {
+			<-oldExited
+		}
+	}
+
+	return diffTools(oldTools, tools), nil
+}
+
+func diffTools(oldTools, newTools map[string]domain.Tool) ReloadResult {
+	var result ReloadResult
+	for name, tool := range newTools {
+		oldTool, ok := oldTools[name]
+		if !ok {
+			result.Added = append(result.Added, name)
+		} else if !reflect.DeepEqual(oldTool, tool) {
+			result.Changed = append(result.Changed, name)
+		}
+	}
+	for name := range oldTools {
+		if _, ok := newTools[name]; !ok {
+			result.Removed = append(result.Removed, name)
+		}
+	}
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+	sort.Strings(result.Changed)
+	return result
+}
@@ -0,0 +1,124 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+const (
+	// safeModeAfter is how many times in a row the servers may fail to start before
+	// clients start without them
+	safeModeAfter = 3
+	// failuresFile counts the failed starts in a row, next to the database
+	failuresFile = "mcp-failures"
+)
+
+var (
+	safeModeMu sync.RWMutex
+	// noServers makes every client started with Start run without its servers
+	noServers bool
+	// stateDir is where failed starts are counted, empty to not count them
+	stateDir string
+)
+
+// ConfigureSafeMode sets up Start for the whole process. With disable set clients
+// never start their servers. Failed starts are counted in dir so that clients start
+// without servers once they keep failing
+func ConfigureSafeMode(disable bool, dir string) {
+	safeModeMu.Lock()
+	defer safeModeMu.Unlock()
+	noServers = disable
+	stateDir = dir
+}
+
+// Start initializes the client for running the agent. When the servers were disabled
+// with --no-mcp, or have failed to start several times in a row, the client runs in
+// safe mode instead: no servers are started and no tools are offered, not even those
+// of built-in servers. SafeMode tells why. Use Initialize to always start the servers
+func (c *Client) Start(ctx context.Context) error {
+	safeModeMu.RLock()
+	disabled, dir := noServers, stateDir
+	safeModeMu.RUnlock()
+
+	if disabled {
+		c.enterSafeMode("MCP servers were disabled with --no-mcp")
+		return nil
+	}
+
+	err := c.Initialize(ctx)
+	if err == nil {
+		resetFailures(dir)
+		return nil
+	}
+
+	failures := recordFailure(dir)
+	if failures < safeModeAfter {
+		return fmt.Errorf("%w. Start without MCP servers with --no-mcp", err)
+	}
+	c.enterSafeMode(fmt.Sprintf("MCP servers failed to start %d times in a row: %v", failures, err))
+	return nil
+}
+
+// SafeMode returns why the client runs without its servers, empty when it doesn't
+func (c *Client) SafeMode() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.safeMode
+}
+
+// enterSafeMode stops any server that did start and leaves the client with no servers
+// and no tools
+func (c *Client) enterSafeMode(reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, cmd := range c.commands {
+		if cmd != nil && cmd.Process != nil {
+			_ = cmd.Process.Kill()
+		}
+	}
+	c.Servers = make(map[string]config.MCPServer)
+	c.clients = make(map[string]*mcp_golang.Client)
+	c.commands = make(map[string]*exec.Cmd)
+	c.tools = make(map[string]map[string]domain.Tool)
+	c.calls = make(map[string]*sync.WaitGroup)
+	c.safeMode = reason
+	c.initialized = true
+	c.stop = make(chan struct{})
+}
+
+// recordFailure counts a failed start and returns the number of failed starts in a row
+func recordFailure(dir string) int {
+	if dir == "" {
+		return 1
+	}
+	path := filepath.Join(dir, failuresFile)
+	data, _ := os.ReadFile(path)
+	failures, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	failures++
+	if err := os.WriteFile(path, []byte(strconv.Itoa(failures)), 0o644); err != nil {
+		slog.Warn("failed to record MCP start failure", "path", path, "error", err)
+	}
+	return failures
+}
+
+// resetFailures forgets earlier failed starts after the servers started
+func resetFailures(dir string) {
+	if dir == "" {
+		return
+	}
+	if err := os.Remove(filepath.Join(dir, failuresFile)); err != nil && !os.IsNotExist(err) {
+		slog.Warn("failed to reset MCP start failures", "error", err)
+	}
+}
@@ -0,0 +1,77 @@
+package mcp
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/config"
+)
+
+// serverCommand builds the command that runs a server. Servers with a host are run
+// remotely through ssh, whose stdio is connected to the remote process
+func serverCommand(server config.MCPServer) (*exec.Cmd, error) {
+	if server.Host == "" {
+		cmd := exec.Command(server.Command, server.Args...)
+		for k, v := range server.Env {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+		}
+		return cmd, nil
+	}
+
+	host, err := url.Parse(server.Host)
+	if err != nil || host.Scheme != "ssh" || host.Hostname() == "" {
+		return nil, fmt.Errorf("invalid host %q, expected ssh://[user@]host[:port]", server.Host)
+	}
+
+	target := host.Hostname()
+	if host.User != nil && host.User.Username() != "" {
+		target = host.User.Username() + "@" + target
+	}
+
+	// BatchMode fails instead of prompting for a password, the prompt would otherwise
+	// be read from the MCP transport
+	args := []string{"-T", "-o", "BatchMode=yes"}
+	if port := host.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+	args = append(args, target, "--", remoteCommand(server))
+
+	return exec.Command("ssh", args...), nil
+}
+
+// remoteCommand quotes the server command for the remote shell. The environment is
+// set with env since ssh does not forward it
+func remoteCommand(server config.MCPServer) string {
+	var words []string
+	if len(server.Env) > 0 {
+		keys := make([]string, 0, len(server.Env))
+		for k := range server.Env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		words = append(words, "env")
+		for _, k := range keys {
+			words = append(words, shellQuote(k+"="+server.Env[k]))
+		}
+	}
+
+	words = append(words, shellQuote(server.Command))
+	for _, arg := range server.Args {
+		words = append(words, shellQuote(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+// shellQuote quotes a word for a POSIX shell
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-./=:,@+%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,114 @@
+// Package msgtemplate fills in the message templates of the config. Templates are Go
+// templates whose placeholders name variables, such as
+//
+//	Title: {{.title}}
+//	Steps: {{.steps}}
+package msgtemplate
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Template is a parsed message template
+type Template struct {
+	name string
+	tmpl *template.Template
+}
+
+// Parse parses the text of a template
+func Parse(name string, text string) (*Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message template %q: %w", name, err)
+	}
+	return &Template{name: name, tmpl: tmpl}, nil
+}
+
+// Variables lists the variables the template uses in the order they first appear
+func (t *Template) Variables() []string {
+	var names []string
+	seen := make(map[string]bool)
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			if name := n.Ident[0]; !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		}
+	}
+	walk(t.tmpl.Tree.Root)
+	return names
+}
+
+// Missing lists the variables of the template that vars has no value for
+func (t *Template) Missing(vars map[string]string) []string {
+	var missing []string
+	for _, name := range t.Variables() {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// Render fills in the template. Every variable it uses must have a value
+func (t *Template) Render(vars map[string]string) (string, error) {
+	if missing := t.Missing(vars); len(missing) > 0 {
+		return "", fmt.Errorf("message template %q needs values for: %s", t.name, strings.Join(missing, ", "))
+	}
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("failed to render message template %q: %w", t.name, err)
+	}
+	return b.String(), nil
+}
+
+// ParseVars parses name=value pairs as given to --var
+func ParseVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid variable %q: expected name=value", pair)
+		}
+		vars[strings.TrimSpace(name)] = value
+	}
+	return vars, nil
+}
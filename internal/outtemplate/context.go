@@ -0,0 +1,112 @@
+package outtemplate
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// Thread is what a template sees for each thread listed by thread ls
+type Thread struct {
+	ID        string    // Full ID
+	ShortID   string    // First 8 characters of the ID
+	Title     string    // Title written by the internal model or set with thread title
+	Summary   string    // Summary written by the internal model
+	Label     string    // Title or summary, whichever is set
+	Preview   string    // Label, or the first message when the thread has no label
+	CreatedAt time.Time // When the thread was started
+	Archived  bool      // Whether the thread is archived
+	Tags      []string  // Tags of the thread
+	Messages  int       // Number of messages in the thread
+	Unread    bool      // Whether the thread has replies that weren't viewed
+}
+
+// NewThread describes a thread with its messages and tags
+func NewThread(thread *domain.Thread, messages []domain.Message, tags []string) Thread {
+	t := Thread{
+		ID:        thread.ID.String(),
+		ShortID:   thread.ID.String()[:8],
+		Title:     thread.Title,
+		Summary:   thread.Summary,
+		Label:     thread.Label(),
+		Preview:   thread.Label(),
+		CreatedAt: thread.CreatedAt,
+		Archived:  thread.ArchivedAt != nil,
+		Tags:      tags,
+		Messages:  len(messages),
+		Unread:    thread.IsUnread(messages),
+	}
+	if t.Tags == nil {
+		t.Tags = []string{}
+	}
+	if t.Preview == "" {
+		for _, msg := range messages {
+			if msg.Role == domain.RoleHuman {
+				t.Preview = msg.Content
+				break
+			}
+		}
+	}
+	return t
+}
+
+// Message is what a template sees for each message shown by thread view
+type Message struct {
+	ID           string        // Full ID
+	ShortID      string        // First 8 characters of the ID
+	ParentID     string        // Full ID of the message this one answers, empty for the first
+	Role         string        // human, assistant or tool
+	Content      string        // Text of the message
+	Model        string        // Model that wrote a response, empty for other messages
+	Provider     string        // Provider of the model
+	ModelVersion string        // Model version the provider reported
+	CreatedAt    time.Time     // When the message was written
+	Duration     time.Duration // Time a response took to generate, 0 if unknown
+	InputTokens  int           // Tokens of the request that a response answered
+	OutputTokens int           // Tokens of the response
+	ToolCalls    []ToolCall    // Tools the response called
+}
+
+// ToolCall is a tool called by a response
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string // JSON encoded arguments
+}
+
+// NewMessage describes a message
+func NewMessage(msg domain.Message) Message {
+	m := Message{
+		ID:           msg.ID.String(),
+		ShortID:      msg.ID.String()[:8],
+		Role:         string(msg.Role),
+		Content:      msg.Content,
+		Model:        msg.ModelName,
+		Provider:     msg.Provider,
+		ModelVersion: msg.ModelVersion,
+		CreatedAt:    msg.CreatedAt,
+		InputTokens:  msg.InputTokens,
+		OutputTokens: msg.OutputTokens,
+		ToolCalls:    []ToolCall{},
+	}
+	if msg.ParentID != nil {
+		m.ParentID = msg.ParentID.String()
+	}
+	if metadata, err := msg.GetMetadata(); err == nil {
+		m.Duration = metadata.Duration
+	}
+	if msg.ToolCalls != "" {
+		var calls []struct {
+			ID        string          `json:"id"`
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal([]byte(msg.ToolCalls), &calls); err == nil {
+			for _, call := range calls {
+				m.ToolCalls = append(m.ToolCalls, ToolCall{ID: call.ID, Name: call.Name, Arguments: string(call.Arguments)})
+			}
+		}
+	}
+	return m
+}
@@ -0,0 +1,86 @@
+// Package outtemplate shapes the output of CLI commands with Go templates given with
+// --format-template, such as
+//
+//	{{.Role}}: {{.Content}}
+//
+// A template runs once per thread or message with a Thread or Message as its data.
+// Templates can also be named in the outputTemplates config and used by name
+package outtemplate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/isaacphi/slop/internal/timestamp"
+)
+
+// Template is a parsed output template
+type Template struct {
+	tmpl *template.Template
+}
+
+// funcs are the functions templates can call besides the built-in ones
+var funcs = template.FuncMap{
+	// join joins a list such as {{join .Tags ","}}
+	"join": func(list []string, sep string) string { return strings.Join(list, sep) },
+	// truncate shortens text to n characters such as {{.Content | truncate 80}}
+	"truncate": func(n int, s string) string {
+		runes := []rune(s)
+		if n < 4 || len(runes) <= n {
+			return s
+		}
+		return string(runes[:n-3]) + "..."
+	},
+	// oneline puts text on a single line such as {{.Content | oneline}}
+	"oneline": func(s string) string { return strings.Join(strings.Fields(s), " ") },
+	// json encodes a value such as {{json .}}
+	"json": func(v any) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+	// date formats a time with a Go layout such as {{.CreatedAt | date "2006-01-02"}}
+	"date": func(layout string, t time.Time) string { return t.Local().Format(layout) },
+	// ago shows how long ago a time was such as {{ago .CreatedAt}}
+	"ago": func(t time.Time) string { return timestamp.Ago(t, time.Now()) },
+	// elapsed shows a duration for reading such as {{elapsed .Duration}}
+	"elapsed": timestamp.Elapsed,
+}
+
+// Parse parses the text of a template
+func Parse(name string, text string) (*Template, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output template %q: %w", name, err)
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// Resolve returns the template named value in templates, or parses value as the text
+// of a template when no template has that name. Names are matched case-insensitively
+// since config keys are
+func Resolve(templates map[string]string, value string) (*Template, error) {
+	for name, text := range templates {
+		if strings.EqualFold(name, value) {
+			return Parse(name, text)
+		}
+	}
+	return Parse("--format-template", value)
+}
+
+// Execute writes the template filled in with data, ending with a newline
+func (t *Template) Execute(w io.Writer, data any) error {
+	var out strings.Builder
+	if err := t.tmpl.Execute(&out, data); err != nil {
+		return err
+	}
+	text := out.String()
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	_, err := io.WriteString(w, text)
+	return err
+}
@@ -0,0 +1,74 @@
+// Package privacy enforces the privacy level of threads: local-only threads are only
+// sent to presets that run on this machine and redacted threads have secrets masked
+// in everything sent
+package privacy
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/errkind"
+	"github.com/isaacphi/slop/internal/llm/provider"
+)
+
+// Levels are the privacy levels a thread can have
+var Levels = []string{domain.PrivacyNormal, domain.PrivacyLocalOnly, domain.PrivacyRedacted}
+
+// Valid reports whether level is one of Levels
+func Valid(level string) bool {
+	return slices.Contains(Levels, level)
+}
+
+// Level returns the privacy level of thread, normal for threads that never had one
+func Level(thread *domain.Thread) string {
+	if thread == nil || thread.Privacy == "" {
+		return domain.PrivacyNormal
+	}
+	return thread.Privacy
+}
+
+// Local reports whether a preset's requests stay on this machine: it points at a
+// loopback address, or it has no base URL and its provider serves models locally
+func Local(preset config.Preset) bool {
+	if preset.BaseURL == "" {
+		p, err := provider.Get(preset.Provider)
+		return err == nil && p.Capabilities().Local
+	}
+	u, err := url.Parse(preset.BaseURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Check returns an error when the privacy level of thread doesn't allow sending its
+// messages with preset. use names what the preset is for in the error, such as
+// "preset" or "internal model"
+func Check(thread *domain.Thread, use string, preset config.Preset) error {
+	if Level(thread) != domain.PrivacyLocalOnly || Local(preset) {
+		return nil
+	}
+	where := preset.Provider
+	if preset.BaseURL != "" {
+		where = preset.BaseURL
+	}
+	return errkind.New(errkind.Privacy, fmt.Errorf("thread %s is local-only but the %s %s sends requests to %s, off this machine",
+		thread.ID.String()[:8], use, preset.Name, where))
+}
+
+// Apply returns preset with redaction turned on when thread is redacted
+func Apply(thread *domain.Thread, preset config.Preset) config.Preset {
+	if Level(thread) == domain.PrivacyRedacted {
+		preset.Redact = true
+	}
+	return preset
+}
@@ -0,0 +1,226 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
+)
+
+const (
+	baseBackoff = 30 * time.Second
+	maxBackoff  = time.Hour
+
+	probeTimeout = 5 * time.Second
+)
+
+// probeInterval is how often Wait checks whether an unreachable provider is back
+var probeInterval = 15 * time.Second
+
+// Sender sends a previously saved human message through the agent
+type Sender func(ctx context.Context, entry domain.QueuedMessage, msg *domain.Message) error
+
+// FlushResult summarizes a flush
+type FlushResult struct {
+	Sent      int
+	Failed    int
+	Remaining int
+	Errors    []error // Errors for messages that failed permanently
+}
+
+// IsOfflineError reports whether err means the provider could not be reached: its
+// name didn't resolve, the connection was refused or the network is unreachable.
+// Timeouts and other failures of a request that reached the provider aren't offline
+func IsOfflineError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENETUNREACH) || errors.Is(err, syscall.EHOSTUNREACH) {
+		return true
+	}
+
+	// Provider SDKs don't always wrap the underlying error, so fall back to the message
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{
+		"no such host",
+		"connection refused",
+		"network is unreachable",
+		"host is unreachable",
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reachable reports whether a connection can be opened to the host of address
+func Reachable(ctx context.Context, address string) bool {
+	u, err := url.Parse(address)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// Wait waits until the next retry is due at until, or until one of the addresses
+// that can't be reached when it starts can be reached again. It reports whether
+// connectivity returned, so queued messages can be sent before they are due
+func Wait(ctx context.Context, until time.Time, addresses []string, reachable func(context.Context, string) bool) (bool, error) {
+	var offline []string
+	for _, address := range addresses {
+		if !reachable(ctx, address) {
+			offline = append(offline, address)
+		}
+	}
+
+	for {
+		delay := time.Until(until)
+		if delay <= 0 {
+			return false, nil
+		}
+		if len(offline) > 0 {
+			delay = min(delay, probeInterval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		for _, address := range offline {
+			if reachable(ctx, address) {
+				return true, nil
+			}
+		}
+	}
+}
+
+// NextAttempt returns when a message that has failed attempts times should be retried.
+// The delay doubles with every attempt up to a maximum of one hour
+func NextAttempt(attempts int) time.Time {
+	delay := baseBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return time.Now().Add(min(delay, maxBackoff))
+}
+
+// Enqueue records a saved human message as waiting to be sent
+func Enqueue(ctx context.Context, repo repository.MessageRepository, msg *domain.Message, preset string, cause error) error {
+	entry := &domain.QueuedMessage{
+		ThreadID:      msg.ThreadID,
+		MessageID:     msg.ID,
+		Preset:        preset,
+		Attempts:      1,
+		NextAttemptAt: NextAttempt(1),
+	}
+	if cause != nil {
+		entry.LastError = cause.Error()
+	}
+	if err := repo.EnqueueMessage(ctx, entry); err != nil {
+		return fmt.Errorf("failed to queue message: %w", err)
+	}
+	return nil
+}
+
+// Flush tries to send queued messages, oldest first. Messages that aren't due yet are
+// skipped unless force is set. If a send fails because the provider is unreachable, the
+// remaining messages in that thread are left queued so they are sent in order.
+// If threadID is nil, all threads are flushed.
+func Flush(ctx context.Context, repo repository.MessageRepository, threadID *uuid.UUID, force bool, send Sender) (FlushResult, error) {
+	var result FlushResult
+
+	entries, err := repo.ListQueuedMessages(ctx, threadID)
+	if err != nil {
+		return result, fmt.Errorf("failed to list queued messages: %w", err)
+	}
+
+	blockedThreads := make(map[uuid.UUID]bool)
+	now := time.Now()
+
+	for _, entry := range entries {
+		if blockedThreads[entry.ThreadID] || (!force && entry.NextAttemptAt.After(now)) {
+			// Keep later messages in the thread behind this one
+			blockedThreads[entry.ThreadID] = true
+			result.Remaining++
+			continue
+		}
+
+		msg, err := repo.GetMessage(ctx, entry.MessageID)
+		if err != nil {
+			// The message was deleted, nothing left to send
+			if err := repo.DeleteQueuedMessage(ctx, entry.ID); err != nil {
+				return result, fmt.Errorf("failed to remove queued message: %w", err)
+			}
+			continue
+		}
+
+		sendErr := send(ctx, entry, msg)
+		if sendErr == nil {
+			if err := repo.DeleteQueuedMessage(ctx, entry.ID); err != nil {
+				return result, fmt.Errorf("failed to remove queued message: %w", err)
+			}
+			result.Sent++
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		entry.Attempts++
+		entry.NextAttemptAt = NextAttempt(entry.Attempts)
+		entry.LastError = sendErr.Error()
+		if err := repo.UpdateQueuedMessage(ctx, &entry); err != nil {
+			return result, fmt.Errorf("failed to update queued message: %w", err)
+		}
+
+		if IsOfflineError(sendErr) {
+			blockedThreads[entry.ThreadID] = true
+			result.Remaining++
+		} else {
+			// The provider rejected the request, retrying later won't help
+			if err := repo.DeleteQueuedMessage(ctx, entry.ID); err != nil {
+				return result, fmt.Errorf("failed to remove queued message: %w", err)
+			}
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Errorf("message %s: %w", entry.MessageID.String()[:8], sendErr))
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,222 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+)
+
+func TestNextAttempt(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: 30 * time.Second},
+		{attempts: 1, want: 30 * time.Second},
+		{attempts: 2, want: time.Minute},
+		{attempts: 3, want: 2 * time.Minute},
+		{attempts: 5, want: 8 * time.Minute},
+		{attempts: 8, want: time.Hour},
+		{attempts: 100, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d attempts", tt.attempts), func(t *testing.T) {
+			before := time.Now()
+			got := NextAttempt(tt.attempts)
+			if got.Before(before.Add(tt.want)) || got.After(time.Now().Add(tt.want)) {
+				t.Errorf("NextAttempt(%d) is %s from now, want %s", tt.attempts, got.Sub(before).Round(time.Second), tt.want)
+			}
+		})
+	}
+}
+
+func TestIsOfflineError(t *testing.T) {
+	dial := func(err error) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", err)}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "dns failure", err: fmt.Errorf("post: %w", &net.DNSError{Err: "no such host", Name: "api.anthropic.com"}), want: true},
+		{name: "connection refused", err: dial(syscall.ECONNREFUSED), want: true},
+		{name: "network unreachable", err: dial(syscall.ENETUNREACH), want: true},
+		{name: "host unreachable", err: dial(syscall.EHOSTUNREACH), want: true},
+		{name: "unwrapped message", err: errors.New("Post \"https://api.openai.com\": dial tcp: lookup api.openai.com: no such host"), want: true},
+		{name: "timeout", err: dial(syscall.ETIMEDOUT), want: false},
+		{name: "i/o timeout message", err: errors.New("read tcp 10.0.0.2:5000: i/o timeout"), want: false},
+		{name: "rejected request", err: errors.New("API returned unexpected status code: 400"), want: false},
+		{name: "canceled", err: fmt.Errorf("dial: %w", context.Canceled), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsOfflineError(tt.err); got != tt.want {
+				t.Errorf("IsOfflineError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWait(t *testing.T) {
+	defer func(interval time.Duration) { probeInterval = interval }(probeInterval)
+	probeInterval = 10 * time.Millisecond
+
+	tests := []struct {
+		name       string
+		until      time.Duration
+		online     map[string]bool // Reachability of each address when the wait starts
+		returnsAt  int             // Probe after which unreachable addresses come back, 0 for never
+		wantOnline bool
+	}{
+		{name: "waits for the retry when nothing is unreachable", until: 30 * time.Millisecond, online: map[string]bool{"a": true}},
+		{name: "waits for the retry when nothing comes back", until: 30 * time.Millisecond, online: map[string]bool{"a": false}},
+		{name: "returns early when connectivity returns", until: time.Minute, online: map[string]bool{"a": true, "b": false}, returnsAt: 2, wantOnline: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probes := 0
+			reachable := func(ctx context.Context, address string) bool {
+				probes++
+				return tt.online[address] || (tt.returnsAt > 0 && probes > len(tt.online)+tt.returnsAt)
+			}
+			addresses := make([]string, 0, len(tt.online))
+			for address := range tt.online {
+				addresses = append(addresses, address)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			online, err := Wait(ctx, time.Now().Add(tt.until), addresses, reachable)
+			if err != nil {
+				t.Fatalf("Wait: %v", err)
+			}
+			if online != tt.wantOnline {
+				t.Errorf("Wait() = %v, want %v", online, tt.wantOnline)
+			}
+		})
+	}
+}
+
+func TestWaitCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	unreachable := func(context.Context, string) bool { return false }
+	if _, err := Wait(ctx, time.Now().Add(time.Minute), []string{"a"}, unreachable); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	offline := &net.DNSError{Err: "no such host", Name: "api.anthropic.com"}
+	rejected := errors.New("API returned unexpected status code: 400")
+
+	tests := []struct {
+		name          string
+		force         bool
+		due           bool
+		sendErr       error // Error sending the first message of the first thread
+		wantSent      int
+		wantFailed    int
+		wantRemaining int
+		wantAttempts  int // Attempts of the first message afterwards, 0 if it was removed
+	}{
+		{name: "all sent", force: true, wantSent: 3},
+		{name: "offline keeps the thread queued in order", force: true, sendErr: offline, wantSent: 1, wantRemaining: 2, wantAttempts: 2},
+		{name: "rejected is removed and the thread continues", force: true, sendErr: rejected, wantSent: 2, wantFailed: 1},
+		{name: "messages that aren't due are skipped", wantRemaining: 3, wantAttempts: 1},
+		{name: "due messages are sent", due: true, wantSent: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo, err := sqlite.Initialize(filepath.Join(t.TempDir(), "slop.db"))
+			if err != nil {
+				t.Fatalf("Initialize: %v", err)
+			}
+
+			// Two messages queued in one thread and one in another
+			var first *domain.Message
+			for _, contents := range [][]string{{"first", "second"}, {"other"}} {
+				thread := &domain.Thread{}
+				if err := repo.CreateThread(ctx, thread); err != nil {
+					t.Fatalf("CreateThread: %v", err)
+				}
+				for _, content := range contents {
+					msg := &domain.Message{Role: domain.RoleHuman, Content: content}
+					if err := repo.AddMessageToThread(ctx, thread.ID, msg); err != nil {
+						t.Fatalf("AddMessageToThread: %v", err)
+					}
+					if err := Enqueue(ctx, repo, msg, "claude", offline); err != nil {
+						t.Fatalf("Enqueue: %v", err)
+					}
+					if first == nil {
+						first = msg
+					}
+				}
+			}
+			if tt.due {
+				entries, _ := repo.ListQueuedMessages(ctx, nil)
+				for _, entry := range entries {
+					entry.NextAttemptAt = time.Now().Add(-time.Second)
+					if err := repo.UpdateQueuedMessage(ctx, &entry); err != nil {
+						t.Fatalf("UpdateQueuedMessage: %v", err)
+					}
+				}
+			}
+
+			var sent []string
+			send := func(ctx context.Context, entry domain.QueuedMessage, msg *domain.Message) error {
+				if msg.ID == first.ID && tt.sendErr != nil {
+					return tt.sendErr
+				}
+				sent = append(sent, msg.Content)
+				return nil
+			}
+
+			result, err := Flush(ctx, repo, nil, tt.force, send)
+			if err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+			if result.Sent != tt.wantSent || result.Failed != tt.wantFailed || result.Remaining != tt.wantRemaining {
+				t.Errorf("Flush() sent %d failed %d remaining %d, want %d %d %d (sent %v)",
+					result.Sent, result.Failed, result.Remaining, tt.wantSent, tt.wantFailed, tt.wantRemaining, sent)
+			}
+
+			entries, err := repo.ListQueuedMessages(ctx, nil)
+			if err != nil {
+				t.Fatalf("ListQueuedMessages: %v", err)
+			}
+			attempts := 0
+			for _, entry := range entries {
+				if entry.MessageID == first.ID {
+					attempts = entry.Attempts
+					if want := NextAttempt(attempts); entry.NextAttemptAt.After(want) || entry.NextAttemptAt.Before(want.Add(-time.Minute)) {
+						t.Errorf("next attempt at %s, want about %s", entry.NextAttemptAt, want)
+					}
+				}
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if len(entries) != tt.wantRemaining {
+				t.Errorf("%d messages still queued, want %d", len(entries), tt.wantRemaining)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+// Package redact masks secrets and personal data in text before it is sent to a
+// provider. Only what is sent is masked, stored messages keep their content
+package redact
+
+import (
+	"regexp"
+)
+
+// rule masks the matches of a pattern. Patterns with groups keep the text of the
+// first group before the mask and of the second after it, such as the name in
+// password=..., and mask the rest of the match
+type rule struct {
+	label   string
+	pattern *regexp.Regexp
+}
+
+// rules are applied in order, so more specific patterns such as private keys come
+// before the general ones that could match part of them. Masks leave quotes in place
+// so JSON such as tool call arguments stays valid
+var rules = []rule{
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{"api key", regexp.MustCompile(`\b(?:sk-ant-[A-Za-z0-9_-]{20,}|sk-(?:proj-)?[A-Za-z0-9_-]{20,}|gh[pousr]_[A-Za-z0-9]{30,}|github_pat_[A-Za-z0-9_]{22,}|glpat-[A-Za-z0-9_-]{20,}|xox[abprs]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_-]{35})`)},
+	{"aws key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{"token", regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9._~+/-]{16,}=*`)},
+	{"secret", regexp.MustCompile(`(?i)(\b(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key)["']?\s*[:=]\s*["']?)[^\s"',;\[}]+`)},
+	{"credentials", regexp.MustCompile(`(\b[a-z][a-z0-9+.-]*://[^\s:/@"]+:)[^\s@/"]+(@)`)},
+	{"email", regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+}
+
+// Text returns text with secrets such as API keys, tokens, passwords and private keys,
+// and email addresses, replaced by a label such as [REDACTED api key]
+func Text(text string) string {
+	for _, r := range rules {
+		mask := "[REDACTED " + r.label + "]"
+		if r.pattern.NumSubexp() == 0 {
+			text = r.pattern.ReplaceAllLiteralString(text, mask)
+			continue
+		}
+		text = r.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			m := r.pattern.FindStringSubmatch(match)
+			masked := m[1] + mask
+			if len(m) > 2 {
+				masked += m[2]
+			}
+			return masked
+		})
+	}
+	return text
+}
@@ -2,11 +2,22 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/isaacphi/slop/internal/domain"
 )
 
+// ThreadFilter selects threads for bulk operations
+type ThreadFilter struct {
+	// Only threads without messages since this time, nil for any
+	InactiveSince *time.Time
+	// Only threads with this tag, empty for any
+	Tag string
+	// Select archived threads instead of active ones
+	Archived bool
+}
+
 type MessageRepository interface {
 	// Threads
 	CreateThread(ctx context.Context, thread *domain.Thread) error
@@ -16,6 +27,38 @@ type MessageRepository interface {
 	GetThreadByPartialID(ctx context.Context, partialID string) (*domain.Thread, error)
 	DeleteThread(ctx context.Context, id uuid.UUID) error
 	SetThreadSummary(ctx context.Context, threadId uuid.UUID, summary string) error
+	SetThreadTitle(ctx context.Context, threadID uuid.UUID, title string) error
+	MarkThreadRead(ctx context.Context, threadID uuid.UUID) error
+	SetThreadPinModel(ctx context.Context, threadID uuid.UUID, pin bool) error
+	// Set the language replies in the thread are written in, empty for the configured default
+	SetThreadLanguage(ctx context.Context, threadID uuid.UUID, language string) error
+	// Set what the thread's messages may be sent to, one of the domain privacy levels
+	SetThreadPrivacy(ctx context.Context, threadID uuid.UUID, privacy string) error
+	// Set the message whose branch GetMessages follows by default, nil follows the newest message
+	SetActiveMessage(ctx context.Context, threadID uuid.UUID, messageID *uuid.UUID) error
+
+	// Bulk thread operations
+	// Find threads matching filter, newest first
+	FindThreads(ctx context.Context, filter ThreadFilter) ([]*domain.Thread, error)
+	DeleteThreads(ctx context.Context, ids []uuid.UUID) error
+	SetThreadsArchived(ctx context.Context, ids []uuid.UUID, archived bool) error
+	// Count messages in each of the threads
+	CountMessages(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]int, error)
+	// Get every message of the threads, including all branches, oldest first
+	GetThreadsMessages(ctx context.Context, ids []uuid.UUID) ([]domain.Message, error)
+
+	// Thread tags
+	AddThreadTags(ctx context.Context, threadID uuid.UUID, tags []string) error
+	RemoveThreadTags(ctx context.Context, threadID uuid.UUID, tags []string) error
+	// Get the tags of each of the threads, sorted by name
+	GetThreadTags(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]string, error)
+
+	// Import
+	// Get which of the threads exist, deleted threads don't count
+	ExistingThreads(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]bool, error)
+	// Insert a thread with its messages, tags and artifacts, keeping their IDs and timestamps.
+	// Rows that already exist are left unchanged and what is left of a deleted thread with the same ID is removed
+	ImportThread(ctx context.Context, thread *domain.Thread, messages []domain.Message, tags []string, artifacts []domain.Artifact, blobs map[string][]byte) error
 
 	// Messages
 	// Get messages in thread up to and including message with ID messageID getFutureMessages also fetches child messages.
@@ -24,4 +67,85 @@ type MessageRepository interface {
 	FindMessageByPartialID(ctx context.Context, threadID uuid.UUID, partialID string) (*domain.Message, error)
 	DeleteLastMessages(ctx context.Context, threadID uuid.UUID, count int) error
 	AddMessageToThread(ctx context.Context, threadID uuid.UUID, msg *domain.Message) error
+	// Save the metadata of a message, leaving the rest of it unchanged
+	UpdateMessageMetadata(ctx context.Context, msg *domain.Message) error
+	// Save the content of a message, leaving the rest of it unchanged
+	UpdateMessageContent(ctx context.Context, msg *domain.Message) error
+	// Save every field of a message that is already stored, leaving its attachments unchanged
+	SaveMessage(ctx context.Context, msg *domain.Message) error
+	// Delete messages by ID, such as the messages of a branch that is pruned
+	DeleteMessages(ctx context.Context, ids []uuid.UUID) error
+	// Get messages across all threads created in the half open interval [start, end)
+	GetMessagesInRange(ctx context.Context, start time.Time, end time.Time) ([]domain.Message, error)
+
+	// Queue
+	// List queued messages, oldest first. If threadID is nil, list queued messages for all threads
+	EnqueueMessage(ctx context.Context, entry *domain.QueuedMessage) error
+	ListQueuedMessages(ctx context.Context, threadID *uuid.UUID) ([]domain.QueuedMessage, error)
+	UpdateQueuedMessage(ctx context.Context, entry *domain.QueuedMessage) error
+	DeleteQueuedMessage(ctx context.Context, id uuid.UUID) error
+
+	// Evaluations
+	// List evaluations, newest first. If threadID is nil, list evaluations for all threads
+	AddEvaluation(ctx context.Context, evaluation *domain.Evaluation) error
+	ListEvaluations(ctx context.Context, threadID *uuid.UUID) ([]domain.Evaluation, error)
+
+	// Tool stats
+	// Record the outcome of a tool call. callErr is nil for successful calls
+	RecordToolCall(ctx context.Context, serverName string, toolName string, latency time.Duration, callErr error) error
+	ListToolStats(ctx context.Context) ([]domain.ToolStat, error)
+
+	// Runs
+	// List runs, newest first. If threadID is nil, list runs for all threads. limit <= 0 lists all runs
+	CreateRun(ctx context.Context, run *domain.Run) error
+	UpdateRun(ctx context.Context, run *domain.Run) error
+	GetRunByPartialID(ctx context.Context, partialID string) (*domain.Run, error)
+	ListRuns(ctx context.Context, threadID *uuid.UUID, limit int) ([]domain.Run, error)
+
+	// Tool approvals
+	AddToolApproval(ctx context.Context, approval *domain.ToolApproval) error
+	// List the approvals given for the thread and the approvals given for the session
+	ListToolApprovals(ctx context.Context, threadID uuid.UUID, sessionID uuid.UUID) ([]domain.ToolApproval, error)
+
+	// Tool rejections
+	// List rejections made at or after since, oldest first
+	AddToolRejections(ctx context.Context, rejections []domain.ToolRejection) error
+	ListToolRejections(ctx context.Context, since time.Time) ([]domain.ToolRejection, error)
+
+	// Batch jobs
+	// List batch jobs, newest first. If pending is set, only list jobs still waiting for the provider
+	CreateBatchJob(ctx context.Context, job *domain.BatchJob) error
+	UpdateBatchJob(ctx context.Context, job *domain.BatchJob) error
+	GetBatchJobByPartialID(ctx context.Context, partialID string) (*domain.BatchJob, error)
+	ListBatchJobs(ctx context.Context, pending bool) ([]domain.BatchJob, error)
+
+	// Workspace index
+	// List the files indexed under root
+	ListIndexedFiles(ctx context.Context, root string) ([]domain.IndexedFile, error)
+	// Store a file and its chunks, replacing the chunks it had before
+	SaveIndexedFile(ctx context.Context, file *domain.IndexedFile, chunks []domain.IndexedChunk) error
+	DeleteIndexedFiles(ctx context.Context, ids []uuid.UUID) error
+	// Get the chunks of every file indexed under root
+	ListIndexedChunks(ctx context.Context, root string) ([]domain.IndexedChunk, error)
+
+	// Response cache
+	// Get the unexpired response stored under key, nil if there is none
+	GetCachedResponse(ctx context.Context, key string) (*domain.CachedResponse, error)
+	RecordCacheHit(ctx context.Context, id uuid.UUID) error
+	// Store a response under its key, replacing any response stored before
+	SaveCachedResponse(ctx context.Context, response *domain.CachedResponse) error
+	// List stored responses without their content, including expired ones
+	ListCachedResponses(ctx context.Context) ([]domain.CachedResponse, error)
+
+	// Artifacts
+	// Store an artifact and its content. Content is only stored once per hash
+	AddArtifact(ctx context.Context, artifact *domain.Artifact, content []byte) error
+	// List artifacts, newest first. If threadID is nil, list artifacts for all threads
+	ListArtifacts(ctx context.Context, threadID *uuid.UUID) ([]domain.Artifact, error)
+	// Get an artifact and its content by a prefix of its hash
+	GetArtifact(ctx context.Context, hashPrefix string) (*domain.Artifact, []byte, error)
+
+	// Attachments
+	// Get the files sent with messages, by message ID
+	GetAttachments(ctx context.Context, messageIDs []uuid.UUID) (map[uuid.UUID][]domain.Attachment, error)
 }
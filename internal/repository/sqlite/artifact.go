@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *messageRepo) AddArtifact(ctx context.Context, artifact *domain.Artifact, content []byte) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		blob := domain.ArtifactBlob{Hash: artifact.Hash, Content: content}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&blob).Error; err != nil {
+			return err
+		}
+		return tx.Create(artifact).Error
+	})
+}
+
+func (r *messageRepo) ListArtifacts(ctx context.Context, threadID *uuid.UUID) ([]domain.Artifact, error) {
+	var artifacts []domain.Artifact
+	query := r.db.WithContext(ctx).Order("created_at DESC")
+
+	if threadID != nil {
+		query = query.Where("thread_id = ?", *threadID)
+	}
+
+	if err := query.Find(&artifacts).Error; err != nil {
+		return nil, err
+	}
+	return artifacts, nil
+}
+
+func (r *messageRepo) GetArtifact(ctx context.Context, hashPrefix string) (*domain.Artifact, []byte, error) {
+	hashPrefix = strings.ToLower(hashPrefix)
+
+	var hashes []string
+	if err := r.db.WithContext(ctx).
+		Model(&domain.Artifact{}).
+		Where("hash LIKE ?", hashPrefix+"%").
+		Distinct().
+		Pluck("hash", &hashes).Error; err != nil {
+		return nil, nil, err
+	}
+	switch len(hashes) {
+	case 0:
+		return nil, nil, fmt.Errorf("artifact not found")
+	case 1:
+	default:
+		return nil, nil, fmt.Errorf("artifact ID %s is ambiguous, use more characters", hashPrefix)
+	}
+
+	var artifact domain.Artifact
+	if err := r.db.WithContext(ctx).
+		Where("hash = ?", hashes[0]).
+		Order("created_at DESC").
+		First(&artifact).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var blob domain.ArtifactBlob
+	if err := r.db.WithContext(ctx).First(&blob, "hash = ?", hashes[0]).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, fmt.Errorf("artifact content not found")
+		}
+		return nil, nil, err
+	}
+
+	return &artifact, blob.Content, nil
+}
@@ -0,0 +1,27 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+func (r *messageRepo) GetAttachments(ctx context.Context, messageIDs []uuid.UUID) (map[uuid.UUID][]domain.Attachment, error) {
+	result := make(map[uuid.UUID][]domain.Attachment)
+	if len(messageIDs) == 0 {
+		return result, nil
+	}
+
+	var attachments []domain.Attachment
+	if err := r.db.WithContext(ctx).
+		Where("message_id IN ?", messageIDs).
+		Order("created_at ASC").
+		Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		result[attachment.MessageID] = append(result[attachment.MessageID], attachment)
+	}
+	return result, nil
+}
@@ -0,0 +1,53 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"gorm.io/gorm"
+)
+
+func (r *messageRepo) CreateBatchJob(ctx context.Context, job *domain.BatchJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+func (r *messageRepo) UpdateBatchJob(ctx context.Context, job *domain.BatchJob) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.BatchJob{}).
+		Where("id = ?", job.ID).
+		Updates(map[string]any{
+			"status":       job.Status,
+			"error":        job.Error,
+			"completed_at": job.CompletedAt,
+		}).Error
+}
+
+func (r *messageRepo) GetBatchJobByPartialID(ctx context.Context, partialID string) (*domain.BatchJob, error) {
+	var job domain.BatchJob
+	if err := r.db.WithContext(ctx).
+		Where("LOWER(CAST(id AS TEXT)) LIKE ?", strings.ToLower(partialID)+"%").
+		Order("created_at DESC").
+		First(&job).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("batch job not found")
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *messageRepo) ListBatchJobs(ctx context.Context, pending bool) ([]domain.BatchJob, error) {
+	var jobs []domain.BatchJob
+	query := r.db.WithContext(ctx).Order("created_at DESC")
+
+	if pending {
+		query = query.Where("status = ?", domain.BatchJobSubmitted)
+	}
+
+	if err := query.Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+	"gorm.io/gorm"
+)
+
+func (r *messageRepo) GetCachedResponse(ctx context.Context, key string) (*domain.CachedResponse, error) {
+	// Misses are expected, Find doesn't log them as errors the way First does
+	var responses []domain.CachedResponse
+	if err := r.db.WithContext(ctx).
+		Where("key = ? AND expires_at > ?", key, time.Now()).
+		Limit(1).
+		Find(&responses).Error; err != nil {
+		return nil, err
+	}
+	if len(responses) == 0 {
+		return nil, nil
+	}
+	return &responses[0], nil
+}
+
+func (r *messageRepo) RecordCacheHit(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.CachedResponse{}).
+		Where("id = ?", id).
+		Update("hits", gorm.Expr("hits + 1")).Error
+}
+
+func (r *messageRepo) SaveCachedResponse(ctx context.Context, response *domain.CachedResponse) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var found []domain.CachedResponse
+		if err := tx.Where("key = ?", response.Key).Limit(1).Find(&found).Error; err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			response.Stores = 1
+			return tx.Create(response).Error
+		}
+		existing := found[0]
+
+		response.ID = existing.ID
+		response.Hits = existing.Hits
+		response.Stores = existing.Stores + 1
+		return tx.Model(&existing).Updates(map[string]any{
+			"provider":      response.Provider,
+			"model_name":    response.ModelName,
+			"content":       response.Content,
+			"tool_calls":    response.ToolCalls,
+			"model_version": response.ModelVersion,
+			"stores":        response.Stores,
+			"expires_at":    response.ExpiresAt,
+		}).Error
+	})
+}
+
+func (r *messageRepo) ListCachedResponses(ctx context.Context) ([]domain.CachedResponse, error) {
+	var responses []domain.CachedResponse
+	if err := r.db.WithContext(ctx).
+		Omit("content", "tool_calls").
+		Order("provider ASC, model_name ASC").
+		Find(&responses).Error; err != nil {
+		return nil, err
+	}
+	return responses, nil
+}
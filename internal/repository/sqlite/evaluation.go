@@ -0,0 +1,26 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+func (r *messageRepo) AddEvaluation(ctx context.Context, evaluation *domain.Evaluation) error {
+	return r.db.WithContext(ctx).Create(evaluation).Error
+}
+
+func (r *messageRepo) ListEvaluations(ctx context.Context, threadID *uuid.UUID) ([]domain.Evaluation, error) {
+	var evaluations []domain.Evaluation
+	query := r.db.WithContext(ctx).Order("created_at DESC")
+
+	if threadID != nil {
+		query = query.Where("thread_id = ?", *threadID)
+	}
+
+	if err := query.Find(&evaluations).Error; err != nil {
+		return nil, err
+	}
+	return evaluations, nil
+}
@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *messageRepo) ExistingThreads(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+	existing := make(map[uuid.UUID]bool)
+	if len(ids) == 0 {
+		return existing, nil
+	}
+
+	var found []uuid.UUID
+	if err := r.db.WithContext(ctx).
+		Model(&domain.Thread{}).
+		Where("id IN ?", ids).
+		Pluck("id", &found).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range found {
+		existing[id] = true
+	}
+	return existing, nil
+}
+
+func (r *messageRepo) ImportThread(ctx context.Context, thread *domain.Thread, messages []domain.Message, tags []string, artifacts []domain.Artifact, blobs map[string][]byte) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		skipExisting := func() *gorm.DB {
+			return tx.Clauses(clause.OnConflict{DoNothing: true})
+		}
+
+		// A deleted thread with the same ID would keep the imported rows hidden, so
+		// remove what is left of it first
+		deleted := func() *gorm.DB {
+			return tx.Unscoped().Where("deleted_at IS NOT NULL")
+		}
+		if err := deleted().Where("thread_id = ?", thread.ID).Delete(&domain.Message{}).Error; err != nil {
+			return err
+		}
+		if err := deleted().Where("thread_id = ?", thread.ID).Delete(&domain.Artifact{}).Error; err != nil {
+			return err
+		}
+		if err := deleted().Where("id = ?", thread.ID).Delete(&domain.Thread{}).Error; err != nil {
+			return err
+		}
+
+		if err := skipExisting().Omit(clause.Associations).Create(thread).Error; err != nil {
+			return err
+		}
+		if len(messages) > 0 {
+			if err := skipExisting().Omit(clause.Associations).Create(&messages).Error; err != nil {
+				return err
+			}
+		}
+		if len(tags) > 0 {
+			rows := make([]domain.ThreadTag, len(tags))
+			for i, tag := range tags {
+				rows[i] = domain.ThreadTag{ThreadID: thread.ID, Tag: tag}
+			}
+			if err := skipExisting().Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+		for _, artifact := range artifacts {
+			if content, ok := blobs[artifact.Hash]; ok {
+				blob := domain.ArtifactBlob{Hash: artifact.Hash, Content: content}
+				if err := skipExisting().Create(&blob).Error; err != nil {
+					return err
+				}
+			}
+			if err := skipExisting().Create(&artifact).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+	"gorm.io/gorm"
+)
+
+func (r *messageRepo) ListIndexedFiles(ctx context.Context, root string) ([]domain.IndexedFile, error) {
+	var files []domain.IndexedFile
+	if err := r.db.WithContext(ctx).
+		Where("root = ?", root).
+		Order("path").
+		Find(&files).Error; err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (r *messageRepo) SaveIndexedFile(ctx context.Context, file *domain.IndexedFile, chunks []domain.IndexedChunk) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if file.ID == uuid.Nil {
+			if err := tx.Create(file).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Save(file).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("file_id = ?", file.ID).Delete(&domain.IndexedChunk{}).Error; err != nil {
+			return err
+		}
+		for i := range chunks {
+			chunks[i].FileID = file.ID
+		}
+		if len(chunks) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(chunks, 100).Error
+	})
+}
+
+func (r *messageRepo) DeleteIndexedFiles(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("file_id IN ?", ids).Delete(&domain.IndexedChunk{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&domain.IndexedFile{}).Error
+	})
+}
+
+func (r *messageRepo) ListIndexedChunks(ctx context.Context, root string) ([]domain.IndexedChunk, error) {
+	var chunks []domain.IndexedChunk
+	if err := r.db.WithContext(ctx).
+		Joins("JOIN indexed_files ON indexed_files.id = indexed_chunks.file_id").
+		Where("indexed_files.root = ?", root).
+		Order("indexed_chunks.file_id, indexed_chunks.position").
+		Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
@@ -2,6 +2,8 @@ package sqlite
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/isaacphi/slop/internal/domain"
 	"github.com/isaacphi/slop/internal/repository"
@@ -11,15 +13,52 @@ import (
 
 // Initialize creates a new SQLite message repository with the given database path
 func Initialize(dbPath string) (repository.MessageRepository, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
 	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if err := renameColumns(db); err != nil {
+		return nil, fmt.Errorf("failed to rename columns: %w", err)
+	}
+
 	// Run migrations
-	if err := db.AutoMigrate(&domain.Thread{}, &domain.Message{}); err != nil {
+	if err := db.AutoMigrate(&domain.Thread{}, &domain.Message{}, &domain.QueuedMessage{}, &domain.Evaluation{}, &domain.ToolStat{}, &domain.Artifact{}, &domain.ArtifactBlob{}, &domain.ThreadTag{}, &domain.Run{}, &domain.BatchJob{}, &domain.IndexedFile{}, &domain.IndexedChunk{}, &domain.CachedResponse{}, &domain.ToolApproval{}, &domain.ToolRejection{}, &domain.Attachment{}); err != nil {
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	if err := migrateIndexes(db); err != nil {
+		return nil, fmt.Errorf("failed to create indexes: %w", err)
+	}
+
 	return NewMessageRepository(db), nil
 }
+
+// renameColumns renames columns of existing databases so AutoMigrate keeps their data
+func renameColumns(db *gorm.DB) error {
+	// Chunks were indexed with term vectors stored as embeddings
+	if db.Migrator().HasColumn(&domain.IndexedChunk{}, "embedding") {
+		return db.Migrator().RenameColumn(&domain.IndexedChunk{}, "embedding", "term_vector")
+	}
+	return nil
+}
+
+// migrateIndexes creates indexes that can't be declared with struct tags because they
+// cover columns of the embedded gorm.Model
+func migrateIndexes(db *gorm.DB) error {
+	indexes := []string{
+		// Finding the newest child of a message when following a branch
+		"CREATE INDEX IF NOT EXISTS idx_messages_parent_created ON messages (parent_id, created_at)",
+		// Finding the newest message of a thread
+		"CREATE INDEX IF NOT EXISTS idx_messages_thread_created ON messages (thread_id, created_at)",
+	}
+	for _, index := range indexes {
+		if err := db.Exec(index).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,13 +3,13 @@ package sqlite
 import (
 	"context"
 	"fmt"
-	"sort"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/isaacphi/slop/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func (r *messageRepo) AddMessageToThread(ctx context.Context, threadID uuid.UUID, msg *domain.Message) error {
@@ -17,6 +17,25 @@ func (r *messageRepo) AddMessageToThread(ctx context.Context, threadID uuid.UUID
 	return r.db.WithContext(ctx).Create(msg).Error
 }
 
+func (r *messageRepo) UpdateMessageMetadata(ctx context.Context, msg *domain.Message) error {
+	return r.db.WithContext(ctx).Model(msg).Update("metadata", msg.Metadata).Error
+}
+
+func (r *messageRepo) UpdateMessageContent(ctx context.Context, msg *domain.Message) error {
+	return r.db.WithContext(ctx).Model(msg).Update("content", msg.Content).Error
+}
+
+func (r *messageRepo) SaveMessage(ctx context.Context, msg *domain.Message) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(msg).Error
+}
+
+func (r *messageRepo) DeleteMessages(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Where("id IN ?", ids).Delete(&domain.Message{}).Error
+}
+
 func (r *messageRepo) GetMessage(ctx context.Context, messageID uuid.UUID) (*domain.Message, error) {
 	var msg domain.Message
 	if err := r.db.WithContext(ctx).
@@ -30,89 +49,95 @@ func (r *messageRepo) GetMessage(ctx context.Context, messageID uuid.UUID) (*dom
 	return &msg, nil
 }
 
-func (r *messageRepo) GetMessages(ctx context.Context, threadID uuid.UUID, messageID *uuid.UUID, getFutureMessages bool) ([]domain.Message, error) {
-	var messages []domain.Message
-
-	// Load all messages with their relationships
-	if err := r.db.WithContext(ctx).
-		Where("thread_id = ?", threadID).
-		Preload("Parent").
-		Preload("Children").
-		Find(&messages).Error; err != nil {
-		return nil, err
-	}
+// ancestorsQuery walks up from a message to the root of its thread
+const ancestorsQuery = `
+WITH RECURSIVE ancestors(id, parent_id) AS (
+	SELECT id, parent_id FROM messages WHERE id = ? AND thread_id = ? AND deleted_at IS NULL
+	UNION ALL
+	SELECT m.id, m.parent_id FROM messages m JOIN ancestors a ON m.id = a.parent_id
+	WHERE m.deleted_at IS NULL
+)
+SELECT id FROM ancestors`
+
+// newestPathQuery walks down from a message, following the newest child of each
+// message. Messages are returned from the starting message down
+const newestPathQuery = `
+WITH RECURSIVE path(id, depth) AS (
+	SELECT id, 0 FROM messages WHERE id = ? AND thread_id = ? AND deleted_at IS NULL
+	UNION ALL
+	SELECT m.id, p.depth + 1 FROM path p JOIN messages m ON m.id = (
+		SELECT c.id FROM messages c
+		WHERE c.parent_id = p.id AND c.deleted_at IS NULL
+		ORDER BY c.created_at DESC
+		LIMIT 1
+	)
+)
+SELECT id FROM path ORDER BY depth`
 
-	// Build a map for easier lookup
-	messageMap := make(map[uuid.UUID]*domain.Message)
-	for i := range messages {
-		messageMap[messages[i].ID] = &messages[i]
-	}
+func (r *messageRepo) GetMessages(ctx context.Context, threadID uuid.UUID, messageID *uuid.UUID, getFutureMessages bool) ([]domain.Message, error) {
+	db := r.db.WithContext(ctx)
 
 	// Find our starting message
-	var startMessage *domain.Message
+	var start *uuid.UUID
 	if messageID == nil {
-		var newest time.Time
-		for i := range messages {
-			if messages[i].CreatedAt.After(newest) {
-				newest = messages[i].CreatedAt
-				startMessage = &messages[i]
+		var thread domain.Thread
+		if err := db.Select("active_message_id").First(&thread, "id = ?", threadID).Error; err != nil && err != gorm.ErrRecordNotFound {
+			return nil, err
+		}
+		if thread.ActiveMessageID != nil {
+			// Follow the active branch to its newest message
+			var path []uuid.UUID
+			if err := db.Raw(newestPathQuery, *thread.ActiveMessageID, threadID).Scan(&path).Error; err != nil {
+				return nil, err
+			}
+			if len(path) > 0 {
+				start = &path[len(path)-1]
 			}
 		}
-	} else {
-		var exists bool
-		startMessage, exists = messageMap[*messageID]
-		if !exists {
-			return nil, fmt.Errorf("message %s not found", messageID)
+		if start == nil {
+			var newest []uuid.UUID
+			if err := db.Model(&domain.Message{}).
+				Where("thread_id = ?", threadID).
+				Order("created_at DESC").
+				Limit(1).
+				Pluck("id", &newest).Error; err != nil {
+				return nil, err
+			}
+			if len(newest) == 0 {
+				return []domain.Message{}, nil
+			}
+			start = &newest[0]
 		}
+	} else {
+		start = messageID
 	}
 
-	// Collect messages in the branch
-	branchMessages := make(map[uuid.UUID]domain.Message)
-
-	// Work backwards to collect all parents
-	current := startMessage
-	for current != nil {
-		branchMessages[current.ID] = *current
-		if current.ParentID == nil {
-			break
-		}
-		current = messageMap[*current.ParentID]
+	// Collect the IDs of the messages in the branch, working backwards to collect all
+	// parents and forwards to get the newest child path
+	var ids []uuid.UUID
+	if err := db.Raw(ancestorsQuery, *start, threadID).Scan(&ids).Error; err != nil {
+		return nil, err
 	}
-
-	// Work forwards from startMessage to get the newest child path
-	current = startMessage
-	for getFutureMessages && current != nil {
-		// If no children, we're done
-		if len(current.Children) == 0 {
-			break
-		}
-
-		// Find newest child
-		var newestChild *domain.Message
-		var newestTime time.Time
-		for i := range current.Children {
-			child := &current.Children[i]
-			if child.CreatedAt.After(newestTime) {
-				newestTime = child.CreatedAt
-				newestChild = child
-			}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("message %s not found", start)
+	}
+	if getFutureMessages {
+		var path []uuid.UUID
+		if err := db.Raw(newestPathQuery, *start, threadID).Scan(&path).Error; err != nil {
+			return nil, err
 		}
-
-		// Add newest child to our branch and continue with that child
-		branchMessages[newestChild.ID] = *newestChild
-		current = newestChild
+		// The starting message is already in ids
+		ids = append(ids, path[1:]...)
 	}
 
-	// Convert map to slice and sort by creation time
-	result := make([]domain.Message, 0, len(branchMessages))
-	for _, msg := range branchMessages {
-		result = append(result, msg)
+	var messages []domain.Message
+	if err := db.
+		Where("id IN ?", ids).
+		Order("created_at ASC").
+		Find(&messages).Error; err != nil {
+		return nil, err
 	}
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].CreatedAt.Before(result[j].CreatedAt)
-	})
-
-	return result, nil
+	return messages, nil
 }
 
 func (r *messageRepo) DeleteLastMessages(ctx context.Context, threadID uuid.UUID, count int) error {
@@ -156,3 +181,14 @@ func (r *messageRepo) FindMessageByPartialID(ctx context.Context, threadID uuid.
 
 	return &message, nil
 }
+
+func (r *messageRepo) GetMessagesInRange(ctx context.Context, start time.Time, end time.Time) ([]domain.Message, error) {
+	var messages []domain.Message
+	if err := r.db.WithContext(ctx).
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Order("created_at ASC").
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
@@ -0,0 +1,119 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+func TestGetMessages(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	db := repo.(*messageRepo).db
+
+	// root - a - b1 - c1
+	//          \ b2
+	// b2 is the newest child of a, c1 the newest message of the thread
+	thread := &domain.Thread{}
+	if err := repo.CreateThread(ctx, thread); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	created := time.Now().Add(-time.Hour)
+	ids := make(map[string]uuid.UUID)
+	add := func(name, parent string) {
+		msg := &domain.Message{Role: domain.RoleHuman, Content: name}
+		msg.CreatedAt = created.Add(time.Duration(len(ids)) * time.Minute)
+		if parent != "" {
+			parentID := ids[parent]
+			msg.ParentID = &parentID
+		}
+		if err := repo.AddMessageToThread(ctx, thread.ID, msg); err != nil {
+			t.Fatalf("AddMessageToThread: %v", err)
+		}
+		ids[name] = msg.ID
+	}
+	add("root", "")
+	add("a", "root")
+	add("b1", "a")
+	add("b2", "a")
+	add("c1", "b1")
+
+	other := &domain.Thread{}
+	if err := repo.CreateThread(ctx, other); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		threadID uuid.UUID
+		from     string // Message to start from, empty for the thread's current branch
+		future   bool
+		active   string // Active message of the thread, empty for none
+		deleted  string // Message deleted before reading, empty for none
+		want     string
+		wantErr  string
+	}{
+		{name: "newest message without an active one", want: "root a b1 c1"},
+		{name: "ancestors only", from: "a", want: "root a"},
+		{name: "future follows the newest child", from: "a", future: true, want: "root a b2"},
+		{name: "future of an older branch", from: "b1", future: true, want: "root a b1 c1"},
+		{name: "active message is followed to its newest descendant", active: "a", want: "root a b2"},
+		{name: "active leaf", active: "c1", want: "root a b1 c1"},
+		{name: "deleted messages are left out", from: "b1", future: true, deleted: "c1", want: "root a b1"},
+		{name: "newest message skips deleted ones", deleted: "c1", want: "root a b2"},
+		{name: "message of another thread", threadID: other.ID, from: "a", wantErr: "not found"},
+		{name: "empty thread", threadID: other.ID, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			threadID := thread.ID
+			if tt.threadID != uuid.Nil {
+				threadID = tt.threadID
+			}
+
+			var active *uuid.UUID
+			if tt.active != "" {
+				id := ids[tt.active]
+				active = &id
+			}
+			if err := db.Model(&domain.Thread{}).Where("id = ?", thread.ID).Update("active_message_id", active).Error; err != nil {
+				t.Fatalf("setting the active message: %v", err)
+			}
+			if tt.deleted != "" {
+				if err := repo.DeleteMessages(ctx, []uuid.UUID{ids[tt.deleted]}); err != nil {
+					t.Fatalf("DeleteMessages: %v", err)
+				}
+				defer db.Unscoped().Model(&domain.Message{}).Where("id = ?", ids[tt.deleted]).Update("deleted_at", nil)
+			}
+
+			var from *uuid.UUID
+			if tt.from != "" {
+				id := ids[tt.from]
+				from = &id
+			}
+			messages, err := repo.GetMessages(ctx, threadID, from, tt.future)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetMessages() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetMessages: %v", err)
+			}
+
+			var got []string
+			for _, msg := range messages {
+				got = append(got, msg.Content)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("GetMessages() = %q, want %q", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+func (r *messageRepo) EnqueueMessage(ctx context.Context, entry *domain.QueuedMessage) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *messageRepo) ListQueuedMessages(ctx context.Context, threadID *uuid.UUID) ([]domain.QueuedMessage, error) {
+	var entries []domain.QueuedMessage
+	query := r.db.WithContext(ctx).Order("created_at ASC")
+
+	if threadID != nil {
+		query = query.Where("thread_id = ?", *threadID)
+	}
+
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *messageRepo) UpdateQueuedMessage(ctx context.Context, entry *domain.QueuedMessage) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.QueuedMessage{}).
+		Where("id = ?", entry.ID).
+		Updates(map[string]any{
+			"attempts":        entry.Attempts,
+			"next_attempt_at": entry.NextAttemptAt,
+			"last_error":      entry.LastError,
+		}).Error
+}
+
+func (r *messageRepo) DeleteQueuedMessage(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&domain.QueuedMessage{}, id).Error
+}
@@ -0,0 +1,59 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+	"gorm.io/gorm"
+)
+
+func (r *messageRepo) CreateRun(ctx context.Context, run *domain.Run) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+func (r *messageRepo) UpdateRun(ctx context.Context, run *domain.Run) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.Run{}).
+		Where("id = ?", run.ID).
+		Updates(map[string]any{
+			"message_id":   run.MessageID,
+			"step":         run.Step,
+			"status":       run.Status,
+			"tool_results": run.ToolResults,
+			"error":        run.Error,
+		}).Error
+}
+
+func (r *messageRepo) GetRunByPartialID(ctx context.Context, partialID string) (*domain.Run, error) {
+	var run domain.Run
+	if err := r.db.WithContext(ctx).
+		Where("LOWER(CAST(id AS TEXT)) LIKE ?", strings.ToLower(partialID)+"%").
+		Order("created_at DESC").
+		First(&run).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("run not found")
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *messageRepo) ListRuns(ctx context.Context, threadID *uuid.UUID, limit int) ([]domain.Run, error) {
+	var runs []domain.Run
+	query := r.db.WithContext(ctx).Order("created_at DESC")
+
+	if threadID != nil {
+		query = query.Where("thread_id = ?", *threadID)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if err := query.Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
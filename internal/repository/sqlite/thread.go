@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
 	"gorm.io/gorm"
 )
 
@@ -28,19 +30,44 @@ func (r *messageRepo) GetThread(ctx context.Context, id uuid.UUID) (*domain.Thre
 }
 
 func (r *messageRepo) DeleteThread(ctx context.Context, id uuid.UUID) error {
+	return r.DeleteThreads(ctx, []uuid.UUID{id})
+}
+
+func (r *messageRepo) DeleteThreads(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
 	// Start a transaction to ensure all related records are deleted
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// Delete all messages associated with the thread
-		if err := tx.Where("thread_id = ?", id).Delete(&domain.Message{}).Error; err != nil {
+		// Delete all messages associated with the threads
+		if err := tx.Where("thread_id IN ?", ids).Delete(&domain.Message{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("thread_id IN ?", ids).Delete(&domain.QueuedMessage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("thread_id IN ?", ids).Delete(&domain.Evaluation{}).Error; err != nil {
 			return err
 		}
-		return tx.Delete(&domain.Thread{}, id).Error
+		if err := tx.Where("thread_id IN ?", ids).Delete(&domain.Artifact{}).Error; err != nil {
+			return err
+		}
+		// Threads share the blobs of artifacts with the same content, so only
+		// remove the ones no remaining artifact uses
+		if err := tx.Where("hash NOT IN (?)", tx.Model(&domain.Artifact{}).Select("hash")).Delete(&domain.ArtifactBlob{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("thread_id IN ?", ids).Delete(&domain.ThreadTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.Thread{}, ids).Error
 	})
 }
 
+// ListThreads lists active threads, archived threads are left out
 func (r *messageRepo) ListThreads(ctx context.Context, limit int) ([]*domain.Thread, error) {
 	var threads []*domain.Thread
-	query := r.db.WithContext(ctx).Order("created_at DESC")
+	query := r.db.WithContext(ctx).Where("archived_at IS NULL").Order("created_at DESC")
 
 	if limit > 0 {
 		query = query.Limit(limit)
@@ -87,3 +114,101 @@ func (r *messageRepo) GetThreadByPartialID(ctx context.Context, partialID string
 func (r *messageRepo) SetThreadSummary(ctx context.Context, threadId uuid.UUID, summary string) error {
 	return r.db.WithContext(ctx).Model(&domain.Thread{}).Where("id = ?", threadId).Update("summary", summary).Error
 }
+
+func (r *messageRepo) SetThreadTitle(ctx context.Context, threadID uuid.UUID, title string) error {
+	return r.db.WithContext(ctx).Model(&domain.Thread{}).Where("id = ?", threadID).Update("title", title).Error
+}
+
+func (r *messageRepo) SetThreadPinModel(ctx context.Context, threadID uuid.UUID, pin bool) error {
+	return r.db.WithContext(ctx).Model(&domain.Thread{}).Where("id = ?", threadID).Update("pin_model", pin).Error
+}
+
+func (r *messageRepo) SetThreadLanguage(ctx context.Context, threadID uuid.UUID, language string) error {
+	return r.db.WithContext(ctx).Model(&domain.Thread{}).Where("id = ?", threadID).Update("language", language).Error
+}
+
+func (r *messageRepo) SetThreadPrivacy(ctx context.Context, threadID uuid.UUID, privacy string) error {
+	return r.db.WithContext(ctx).Model(&domain.Thread{}).Where("id = ?", threadID).Update("privacy", privacy).Error
+}
+
+func (r *messageRepo) SetActiveMessage(ctx context.Context, threadID uuid.UUID, messageID *uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&domain.Thread{}).Where("id = ?", threadID).Update("active_message_id", messageID).Error
+}
+
+func (r *messageRepo) FindThreads(ctx context.Context, filter repository.ThreadFilter) ([]*domain.Thread, error) {
+	var threads []*domain.Thread
+	query := r.db.WithContext(ctx).Order("threads.created_at DESC")
+
+	if filter.Archived {
+		query = query.Where("threads.archived_at IS NOT NULL")
+	} else {
+		query = query.Where("threads.archived_at IS NULL")
+	}
+
+	if filter.Tag != "" {
+		tagged := r.db.Model(&domain.ThreadTag{}).Select("thread_id").Where("tag = ?", filter.Tag)
+		query = query.Where("threads.id IN (?)", tagged)
+	}
+
+	if filter.InactiveSince != nil {
+		recent := r.db.Model(&domain.Message{}).
+			Select("1").
+			Where("messages.thread_id = threads.id AND messages.created_at >= ?", *filter.InactiveSince)
+		query = query.
+			Where("threads.created_at < ?", *filter.InactiveSince).
+			Where("NOT EXISTS (?)", recent)
+	}
+
+	if err := query.Find(&threads).Error; err != nil {
+		return nil, err
+	}
+	return threads, nil
+}
+
+func (r *messageRepo) SetThreadsArchived(ctx context.Context, ids []uuid.UUID, archived bool) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var archivedAt *time.Time
+	if archived {
+		now := time.Now()
+		archivedAt = &now
+	}
+	return r.db.WithContext(ctx).Model(&domain.Thread{}).Where("id IN ?", ids).Update("archived_at", archivedAt).Error
+}
+
+func (r *messageRepo) CountMessages(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]int, error) {
+	var rows []struct {
+		ThreadID uuid.UUID
+		Count    int
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&domain.Message{}).
+		Select("thread_id, COUNT(*) AS count").
+		Where("thread_id IN ?", ids).
+		Group("thread_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		counts[row.ThreadID] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *messageRepo) GetThreadsMessages(ctx context.Context, ids []uuid.UUID) ([]domain.Message, error) {
+	var messages []domain.Message
+	if err := r.db.WithContext(ctx).
+		Where("thread_id IN ?", ids).
+		Order("created_at ASC").
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (r *messageRepo) MarkThreadRead(ctx context.Context, threadID uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&domain.Thread{}).Where("id = ?", threadID).Update("last_read_at", time.Now()).Error
+}
@@ -0,0 +1,40 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+	"gorm.io/gorm/clause"
+)
+
+func (r *messageRepo) AddThreadTags(ctx context.Context, threadID uuid.UUID, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	rows := make([]domain.ThreadTag, len(tags))
+	for i, tag := range tags {
+		rows[i] = domain.ThreadTag{ThreadID: threadID, Tag: tag}
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+func (r *messageRepo) RemoveThreadTags(ctx context.Context, threadID uuid.UUID, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Where("thread_id = ? AND tag IN ?", threadID, tags).Delete(&domain.ThreadTag{}).Error
+}
+
+func (r *messageRepo) GetThreadTags(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]string, error) {
+	var rows []domain.ThreadTag
+	if err := r.db.WithContext(ctx).Where("thread_id IN ?", ids).Order("tag ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	tags := make(map[uuid.UUID][]string)
+	for _, row := range rows {
+		tags[row.ThreadID] = append(tags[row.ThreadID], row.Tag)
+	}
+	return tags, nil
+}
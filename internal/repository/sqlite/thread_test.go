@@ -0,0 +1,69 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
+)
+
+// newTestRepo opens an empty database in a temporary directory
+func newTestRepo(t *testing.T) repository.MessageRepository {
+	t.Helper()
+	repo, err := Initialize(filepath.Join(t.TempDir(), "slop.db"))
+	if err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return repo
+}
+
+func TestDeleteThreadBlobs(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	// The shared blob is used by both threads, the own blob only by the deleted one
+	deleted, kept := &domain.Thread{}, &domain.Thread{}
+	for _, thread := range []*domain.Thread{deleted, kept} {
+		if err := repo.CreateThread(ctx, thread); err != nil {
+			t.Fatalf("CreateThread: %v", err)
+		}
+	}
+	artifacts := []struct {
+		thread *domain.Thread
+		hash   string
+	}{
+		{thread: deleted, hash: "shared"},
+		{thread: deleted, hash: "own"},
+		{thread: kept, hash: "shared"},
+	}
+	for _, a := range artifacts {
+		artifact := &domain.Artifact{ThreadID: a.thread.ID, Hash: a.hash, Name: a.hash}
+		if err := repo.AddArtifact(ctx, artifact, []byte(a.hash)); err != nil {
+			t.Fatalf("AddArtifact: %v", err)
+		}
+	}
+
+	if err := repo.DeleteThread(ctx, deleted.ID); err != nil {
+		t.Fatalf("DeleteThread: %v", err)
+	}
+
+	tests := []struct {
+		hash     string
+		wantKept bool
+	}{
+		{hash: "shared", wantKept: true},
+		{hash: "own", wantKept: false},
+	}
+	db := repo.(*messageRepo).db
+	for _, tt := range tests {
+		var count int64
+		if err := db.Model(&domain.ArtifactBlob{}).Where("hash = ?", tt.hash).Count(&count).Error; err != nil {
+			t.Fatalf("counting blobs: %v", err)
+		}
+		if kept := count > 0; kept != tt.wantKept {
+			t.Errorf("blob %s kept = %v, want %v", tt.hash, kept, tt.wantKept)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+func (r *messageRepo) AddToolApproval(ctx context.Context, approval *domain.ToolApproval) error {
+	return r.db.WithContext(ctx).Create(approval).Error
+}
+
+func (r *messageRepo) ListToolApprovals(ctx context.Context, threadID uuid.UUID, sessionID uuid.UUID) ([]domain.ToolApproval, error) {
+	var approvals []domain.ToolApproval
+	if err := r.db.WithContext(ctx).
+		Where("(scope = ? AND thread_id = ?) OR (scope = ? AND session_id = ?)",
+			domain.ApprovalScopeThread, threadID, domain.ApprovalScopeSession, sessionID).
+		Order("created_at ASC").
+		Find(&approvals).Error; err != nil {
+		return nil, err
+	}
+	return approvals, nil
+}
@@ -0,0 +1,26 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+func (r *messageRepo) AddToolRejections(ctx context.Context, rejections []domain.ToolRejection) error {
+	if len(rejections) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&rejections).Error
+}
+
+func (r *messageRepo) ListToolRejections(ctx context.Context, since time.Time) ([]domain.ToolRejection, error) {
+	var rejections []domain.ToolRejection
+	if err := r.db.WithContext(ctx).
+		Where("created_at >= ?", since).
+		Order("created_at ASC").
+		Find(&rejections).Error; err != nil {
+		return nil, err
+	}
+	return rejections, nil
+}
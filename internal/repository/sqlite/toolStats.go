@@ -0,0 +1,45 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"gorm.io/gorm"
+)
+
+func (r *messageRepo) RecordToolCall(ctx context.Context, serverName string, toolName string, latency time.Duration, callErr error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var stat domain.ToolStat
+		if err := tx.
+			Where(domain.ToolStat{ServerName: serverName, ToolName: toolName}).
+			FirstOrCreate(&stat).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]any{
+			"total_latency_ms": gorm.Expr("total_latency_ms + ?", latency.Milliseconds()),
+		}
+		if callErr == nil {
+			updates["successes"] = gorm.Expr("successes + 1")
+		} else {
+			updates["failures"] = gorm.Expr("failures + 1")
+			updates["last_error"] = callErr.Error()
+			updates["last_error_at"] = time.Now()
+		}
+
+		return tx.Model(&domain.ToolStat{}).
+			Where("id = ?", stat.ID).
+			Updates(updates).Error
+	})
+}
+
+func (r *messageRepo) ListToolStats(ctx context.Context) ([]domain.ToolStat, error) {
+	var stats []domain.ToolStat
+	if err := r.db.WithContext(ctx).
+		Order("server_name ASC, tool_name ASC").
+		Find(&stats).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,163 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/config"
+)
+
+const (
+	// maxOutput limits how much of stdout and of stderr is returned
+	maxOutput = 32 * 1024
+	// pidsLimit stops fork bombs from taking over the host
+	pidsLimit = 256
+	// workDir is where the code is mounted in the container
+	workDir = "/sandbox"
+)
+
+// Result is how a run ended
+type Result struct {
+	ExitCode int
+	Stdout   string
+	Stderr   string
+	TimedOut bool
+	Timeout  time.Duration
+	Duration time.Duration
+}
+
+// String describes the result for the model
+func (r Result) String() string {
+	var b strings.Builder
+	if r.TimedOut {
+		fmt.Fprintf(&b, "Stopped after %s, the time limit\n", r.Timeout)
+	} else {
+		fmt.Fprintf(&b, "Exit code %d after %s\n", r.ExitCode, r.Duration.Round(time.Millisecond))
+	}
+	fmt.Fprintf(&b, "\nstdout:\n%s\n", orNone(r.Stdout))
+	fmt.Fprintf(&b, "\nstderr:\n%s", orNone(r.Stderr))
+	return b.String()
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(empty)"
+	}
+	return s
+}
+
+// Run writes code to the language's file and runs it in a new container with the
+// limits of cfg. The container has no capabilities, sees the code read only and is
+// removed when the run ends or times out
+func Run(ctx context.Context, cfg config.Sandbox, language config.SandboxLanguage, code string, stdin string) (Result, error) {
+	timeout, err := time.ParseDuration(cfg.Timeout)
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid sandbox timeout %q", cfg.Timeout)
+	}
+
+	dir, err := os.MkdirTemp("", "slop-sandbox-")
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	// The container may run as another user than the one that owns the directory
+	if err := os.Chmod(dir, 0755); err != nil {
+		return Result{}, fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, language.File), []byte(code), 0644); err != nil {
+		return Result{}, fmt.Errorf("failed to write code: %w", err)
+	}
+
+	name := "slop-sandbox-" + uuid.NewString()[:8]
+	args := []string{
+		"run", "--rm", "-i",
+		"--name", name,
+		"--cpus", cfg.CPUs,
+		"--memory", cfg.Memory,
+		"--pids-limit", fmt.Sprint(pidsLimit),
+		"--cap-drop", "ALL",
+		"--security-opt", "no-new-privileges",
+		"--read-only",
+		"--tmpfs", "/tmp",
+		"-v", dir + ":" + workDir + ":ro",
+		"-w", workDir,
+	}
+	if !cfg.Network {
+		args = append(args, "--network", "none")
+	}
+	args = append(args, language.Image)
+	args = append(args, language.Command...)
+
+	var stdout, stderr limitedBuffer
+	cmd := exec.Command(cfg.Runtime, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return Result{}, fmt.Errorf("failed to start %s, is it installed? %w", cfg.Runtime, err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	// Killing the runtime's client would leave the container running, so the
+	// container itself is removed when the run is stopped
+	result := Result{Timeout: timeout}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err = <-done:
+	case <-timer.C:
+		result.TimedOut = true
+		_ = exec.Command(cfg.Runtime, "rm", "-f", name).Run()
+		err = <-done
+	case <-ctx.Done():
+		_ = exec.Command(cfg.Runtime, "rm", "-f", name).Run()
+		<-done
+		return Result{}, ctx.Err()
+	}
+	result.Duration = time.Since(start)
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	default:
+		return Result{}, fmt.Errorf("failed to run code with %s: %w", cfg.Runtime, err)
+	}
+	return result, nil
+}
+
+// limitedBuffer keeps the first maxOutput bytes written to it
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := maxOutput - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		b.buf.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return b.buf.String() + fmt.Sprintf("\n... output cut off after %d bytes", maxOutput)
+	}
+	return b.buf.String()
+}
@@ -0,0 +1,77 @@
+// Package sandbox runs code written by the model in disposable containers
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// ServerName is the name toolsets use for the built-in sandbox server
+const ServerName = "sandbox"
+
+// Server offers the sandbox to the model as tools
+type Server struct {
+	cfg config.Sandbox
+}
+
+// NewServer creates the built-in server
+func NewServer(cfg config.Sandbox) *Server {
+	return &Server{cfg: cfg}
+}
+
+// Tools describes the tools of the server
+func (s *Server) Tools() map[string]domain.Tool {
+	languages := slices.Sorted(maps.Keys(s.cfg.Languages))
+	network := "It has no network access"
+	if s.cfg.Network {
+		network = "It can reach the network"
+	}
+	return map[string]domain.Tool{
+		"run_code": {
+			Name: "run_code",
+			Description: fmt.Sprintf("Run a program in a new container that is thrown away afterwards and return its exit code with what it printed to stdout and stderr. "+
+				"The program may use %s CPUs and %s of memory and is stopped after %s. %s and can't read or keep files between runs",
+				s.cfg.CPUs, s.cfg.Memory, s.cfg.Timeout, network),
+			Parameters: domain.Parameters{
+				Type: "object",
+				Properties: map[string]domain.Property{
+					"language": {Type: "string", Description: "Language the program is written in", Enum: languages},
+					"code":     {Type: "string", Description: "Source code of the program"},
+					"stdin":    {Type: "string", Description: "Text passed to the program on standard input"},
+				},
+				Required: []string{"language", "code"},
+			},
+		},
+	}
+}
+
+// CallTool runs one of the server's tools
+func (s *Server) CallTool(ctx context.Context, toolName string, arguments map[string]any) (string, error) {
+	switch toolName {
+	case "run_code":
+		name, _ := arguments["language"].(string)
+		code, _ := arguments["code"].(string)
+		stdin, _ := arguments["stdin"].(string)
+		language, ok := s.cfg.Languages[name]
+		if !ok {
+			return "", fmt.Errorf("unknown language %q, must be one of %s", name, strings.Join(slices.Sorted(maps.Keys(s.cfg.Languages)), ", "))
+		}
+		if strings.TrimSpace(code) == "" {
+			return "", fmt.Errorf("code is required")
+		}
+		result, err := Run(ctx, s.cfg, language, code, stdin)
+		if err != nil {
+			return "", err
+		}
+		return result.String(), nil
+
+	default:
+		return "", fmt.Errorf("tool %s not found in server %s", toolName, ServerName)
+	}
+}
@@ -0,0 +1,57 @@
+package textdiff
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// PreviousAnswers finds the answer each regenerated answer replaced. messages are all
+// the messages of a thread including every branch. An answer is an assistant message
+// without tool calls, and it replaced the latest earlier answer to the same human
+// message. Answers that are the first to their human message are left out
+func PreviousAnswers(messages []domain.Message) map[uuid.UUID]domain.Message {
+	byID := make(map[uuid.UUID]domain.Message, len(messages))
+	for _, msg := range messages {
+		byID[msg.ID] = msg
+	}
+
+	// Answers by the human message that started their turn, in the order they were made
+	turns := make(map[uuid.UUID][]domain.Message)
+	for _, msg := range messages {
+		if msg.Role != domain.RoleAssistant || msg.ToolCalls != "" {
+			continue
+		}
+		if human, ok := turnStart(byID, msg); ok {
+			turns[human] = append(turns[human], msg)
+		}
+	}
+
+	previous := make(map[uuid.UUID]domain.Message)
+	for _, answers := range turns {
+		sort.SliceStable(answers, func(i, j int) bool {
+			return answers[i].CreatedAt.Before(answers[j].CreatedAt)
+		})
+		for i := 1; i < len(answers); i++ {
+			previous[answers[i].ID] = answers[i-1]
+		}
+	}
+	return previous
+}
+
+// turnStart finds the human message a message answers, following its parents
+// through any tool calls and results
+func turnStart(byID map[uuid.UUID]domain.Message, msg domain.Message) (uuid.UUID, bool) {
+	for msg.ParentID != nil {
+		parent, ok := byID[*msg.ParentID]
+		if !ok {
+			return uuid.Nil, false
+		}
+		if parent.Role == domain.RoleHuman {
+			return parent.ID, true
+		}
+		msg = parent
+	}
+	return uuid.Nil, false
+}
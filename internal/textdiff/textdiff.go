@@ -0,0 +1,204 @@
+// Package textdiff compares texts word by word, such as a regenerated response with
+// the response it replaced
+package textdiff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxCells limits the size of the table used to compare the parts of two texts that
+// differ. Larger changes are shown as the whole of one text replacing the other
+const maxCells = 4_000_000
+
+// Kind is what happened to a run of text
+type Kind int
+
+const (
+	Equal Kind = iota
+	Delete
+	Insert
+)
+
+// Op is a run of text that is kept, removed or added
+type Op struct {
+	Kind Kind
+	Text string
+}
+
+// Words compares old and new word by word. Whitespace is kept with the words so the
+// text of the ops put together gives back either text
+func Words(old, new string) []Op {
+	a, b := tokenize(old), tokenize(new)
+
+	// Common ends are compared cheaply, the table is only needed for the middle
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []Op
+	ops = appendOp(ops, Equal, a[:prefix]...)
+	ops = append(ops, middle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	ops = appendOp(ops, Equal, a[len(a)-suffix:]...)
+	return merge(ops)
+}
+
+// Changed reports whether the ops change anything
+func Changed(ops []Op) bool {
+	for _, op := range ops {
+		if op.Kind != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats counts the words removed and added
+func Stats(ops []Op) (removed, added int) {
+	for _, op := range ops {
+		switch op.Kind {
+		case Delete:
+			removed += len(strings.Fields(op.Text))
+		case Insert:
+			added += len(strings.Fields(op.Text))
+		}
+	}
+	return removed, added
+}
+
+// middle compares the differing middle of two texts with the longest common
+// subsequence of their tokens
+func middle(a, b []string) []Op {
+	if len(a) == 0 || len(b) == 0 || len(a)*len(b) > maxCells {
+		return appendOp(appendOp(nil, Delete, a...), Insert, b...)
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []Op
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = appendOp(ops, Equal, a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = appendOp(ops, Delete, a[i])
+			i++
+		default:
+			ops = appendOp(ops, Insert, b[j])
+			j++
+		}
+	}
+	ops = appendOp(ops, Delete, a[i:]...)
+	return appendOp(ops, Insert, b[j:]...)
+}
+
+// tokenize splits text into words, runs of whitespace and single punctuation marks
+func tokenize(text string) []string {
+	var tokens []string
+	start := 0
+	class := func(r rune) int {
+		switch {
+		case unicode.IsSpace(r):
+			return 0
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			return 1
+		}
+		return 2
+	}
+	runes := []rune(text)
+	for i := 1; i <= len(runes); i++ {
+		if i == len(runes) || class(runes[i]) != class(runes[start]) || class(runes[i]) == 2 {
+			tokens = append(tokens, string(runes[start:i]))
+			start = i
+		}
+	}
+	return tokens
+}
+
+// appendOp adds tokens to the ops, extending the last op when it is of the same kind
+func appendOp(ops []Op, kind Kind, tokens ...string) []Op {
+	for _, token := range tokens {
+		if n := len(ops); n > 0 && ops[n-1].Kind == kind {
+			ops[n-1].Text += token
+			continue
+		}
+		ops = append(ops, Op{Kind: kind, Text: token})
+	}
+	return ops
+}
+
+// merge cleans up the ops so whitespace kept between two changes joins them, which
+// reads better than many small changes
+func merge(ops []Op) []Op {
+	var merged []Op
+	for i := 0; i < len(ops); i++ {
+		op := ops[i]
+		n := len(merged)
+		if op.Kind == Equal && strings.TrimSpace(op.Text) == "" && n > 0 && merged[n-1].Kind != Equal && i+1 < len(ops) && ops[i+1].Kind != Equal && replaces(merged, ops[i+1]) {
+			// Fold the whitespace into both sides of the surrounding change
+			merged = appendChange(merged, Delete, op.Text)
+			merged = appendChange(merged, Insert, op.Text)
+			continue
+		}
+		if op.Kind != Equal {
+			merged = appendChange(merged, op.Kind, op.Text)
+			continue
+		}
+		merged = append(merged, op)
+	}
+	return merged
+}
+
+// replaces reports whether the run of changes at the end of ops together with next
+// both removes and adds text. Whitespace between pure additions or pure removals is
+// left alone
+func replaces(ops []Op, next Op) bool {
+	kinds := map[Kind]bool{next.Kind: true}
+	for i := len(ops) - 1; i >= 0 && ops[i].Kind != Equal; i-- {
+		kinds[ops[i].Kind] = true
+	}
+	return kinds[Delete] && kinds[Insert]
+}
+
+// appendChange adds a removal or addition to the run of changes at the end of ops,
+// keeping removals before additions
+func appendChange(ops []Op, kind Kind, text string) []Op {
+	// Find the run of changes at the end
+	start := len(ops)
+	for start > 0 && ops[start-1].Kind != Equal {
+		start--
+	}
+	for i := start; i < len(ops); i++ {
+		if ops[i].Kind == kind {
+			ops[i].Text += text
+			return ops
+		}
+	}
+	if kind == Delete && start < len(ops) {
+		// An addition is already there, the removal goes before it
+		ops = append(ops[:start], append([]Op{{Kind: Delete, Text: text}}, ops[start:]...)...)
+		return ops
+	}
+	return append(ops, Op{Kind: kind, Text: text})
+}
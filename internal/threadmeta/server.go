@@ -0,0 +1,131 @@
+// Package threadmeta is the built-in server that lets the model title and tag the
+// thread it is talking in
+package threadmeta
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/repository"
+)
+
+// ServerName is the name toolsets use for the built-in thread server
+const ServerName = "thread"
+
+const (
+	maxTitleLength = 80
+	maxTags        = 5
+)
+
+// Server offers tools that change the thread a call was made in. The repository is
+// opened on the first call
+type Server struct {
+	open func() (repository.MessageRepository, error)
+	mu   sync.Mutex
+	repo repository.MessageRepository
+}
+
+// NewServer creates the built-in server. open is called once, when a tool is first used
+func NewServer(open func() (repository.MessageRepository, error)) *Server {
+	return &Server{open: open}
+}
+
+// Tools describes the tools of the server
+func (s *Server) Tools() map[string]domain.Tool {
+	return map[string]domain.Tool{
+		"set_thread_metadata": {
+			Name:        "set_thread_metadata",
+			Description: "Give the current conversation a short title and tags so it is easy to find in the list of threads. Call it once the topic is clear or when it changes. Tags are added to the ones the thread already has",
+			Parameters: domain.Parameters{
+				Type: "object",
+				Properties: map[string]domain.Property{
+					"title": {Type: "string", Description: "Title of the conversation in less than 8 words"},
+					"tags": {
+						Type:        "array",
+						Description: fmt.Sprintf("Up to %d short lowercase tags such as go or travel", maxTags),
+						Items:       &domain.Property{Type: "string"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// CallTool runs one of the server's tools
+func (s *Server) CallTool(ctx context.Context, toolName string, arguments map[string]any) (string, error) {
+	if toolName != "set_thread_metadata" {
+		return "", fmt.Errorf("tool %s not found in server %s", toolName, ServerName)
+	}
+	threadID, ok := mcp.ThreadFrom(ctx)
+	if !ok {
+		return "", fmt.Errorf("%s can only be called from a conversation", toolName)
+	}
+
+	title, _ := arguments["title"].(string)
+	title = strings.Join(strings.Fields(title), " ")
+	if len([]rune(title)) > maxTitleLength {
+		title = string([]rune(title)[:maxTitleLength])
+	}
+	tags := normalizeTags(arguments["tags"])
+	if title == "" && len(tags) == 0 {
+		return "", fmt.Errorf("give a title or at least one tag")
+	}
+
+	repo, err := s.repository()
+	if err != nil {
+		return "", err
+	}
+
+	var done []string
+	if title != "" {
+		if err := repo.SetThreadTitle(ctx, threadID, title); err != nil {
+			return "", fmt.Errorf("failed to set the title: %w", err)
+		}
+		done = append(done, fmt.Sprintf("title set to %q", title))
+	}
+	if len(tags) > 0 {
+		if err := repo.AddThreadTags(ctx, threadID, tags); err != nil {
+			return "", fmt.Errorf("failed to add tags: %w", err)
+		}
+		done = append(done, "tagged "+strings.Join(tags, ", "))
+	}
+	return "Thread " + strings.Join(done, " and "), nil
+}
+
+// normalizeTags turns the tags argument into distinct lowercase tags joined with
+// hyphens, keeping at most maxTags
+func normalizeTags(arg any) []string {
+	values, _ := arg.([]any)
+	var tags []string
+	for _, value := range values {
+		text, _ := value.(string)
+		tag := strings.Join(strings.Fields(strings.ToLower(text)), "-")
+		if tag == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		tags = append(tags, tag)
+		if len(tags) == maxTags {
+			break
+		}
+	}
+	return tags
+}
+
+func (s *Server) repository() (repository.MessageRepository, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.repo == nil {
+		repo, err := s.open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the database: %w", err)
+		}
+		s.repo = repo
+	}
+	return s.repo, nil
+}
@@ -0,0 +1,67 @@
+// Package timestamp formats when messages were written and how long responses took
+// to generate, the same way in the CLI, the TUI and exports
+package timestamp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// Time formats set by the timeFormat config option
+const (
+	Relative = "relative"
+	Absolute = "absolute"
+)
+
+// absoluteLayout shows times to the minute in the local time zone
+const absoluteLayout = "2006-01-02 15:04"
+
+// Format returns t in the given format: relative such as "2m ago", or absolute
+func Format(t time.Time, format string) string {
+	if format == Relative {
+		return Ago(t, time.Now())
+	}
+	return t.Local().Format(absoluteLayout)
+}
+
+// Ago returns how long before now t was, such as "just now", "2m ago" or "3d ago".
+// Times more than a month ago are shown as a date
+func Ago(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d/(24*time.Hour)))
+	}
+	return t.Local().Format(time.DateOnly)
+}
+
+// Elapsed returns a generation time rounded for reading, such as "850ms", "4.2s" or
+// "1m5s"
+func Elapsed(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	case d < time.Minute:
+		return d.Round(100 * time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+// Label describes when a message was written and, for responses, how long it took to
+// generate, such as "2m ago · 4.2s"
+func Label(msg domain.Message, format string) string {
+	parts := []string{Format(msg.CreatedAt, format)}
+	if metadata, err := msg.GetMetadata(); err == nil && metadata.Duration > 0 {
+		parts = append(parts, Elapsed(metadata.Duration))
+	}
+	return strings.Join(parts, " · ")
+}
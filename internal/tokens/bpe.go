@@ -0,0 +1,93 @@
+package tokens
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// downloadTimeout bounds how long counting tokens can wait for an encoding
+const downloadTimeout = 10 * time.Second
+
+func init() {
+	tiktoken.SetBpeLoader(cachedLoader{})
+}
+
+// cachedLoader downloads tiktoken encodings once and keeps them in the user's cache
+// directory. Unlike the default loader it gives up on slow downloads
+type cachedLoader struct{}
+
+func (cachedLoader) LoadTiktokenBpe(url string) (map[string]int, error) {
+	contents, err := readEncoding(url)
+	if err != nil {
+		return nil, err
+	}
+
+	ranks := make(map[string]int)
+	for _, line := range bytes.Split(contents, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		token, rank, ok := bytes.Cut(line, []byte(" "))
+		if !ok {
+			return nil, fmt.Errorf("invalid encoding line %q", line)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(string(token))
+		if err != nil {
+			return nil, fmt.Errorf("invalid encoding token: %w", err)
+		}
+		n, err := strconv.Atoi(string(rank))
+		if err != nil {
+			return nil, fmt.Errorf("invalid encoding rank: %w", err)
+		}
+		ranks[string(decoded)] = n
+	}
+	return ranks, nil
+}
+
+// readEncoding reads an encoding from the cache, downloading it if it isn't there yet
+func readEncoding(url string) ([]byte, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return downloadEncoding(url)
+	}
+	cachePath := filepath.Join(cacheDir, "slop", "tiktoken", path.Base(url))
+	if contents, err := os.ReadFile(cachePath); err == nil {
+		return contents, nil
+	}
+
+	contents, err := downloadEncoding(url)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err == nil {
+		// Written under a temporary name so other processes never read half a file
+		tmpPath := cachePath + ".tmp" + strconv.Itoa(os.Getpid())
+		if os.WriteFile(tmpPath, contents, 0644) == nil {
+			os.Rename(tmpPath, cachePath)
+		}
+	}
+	return contents, nil
+}
+
+func downloadEncoding(url string) ([]byte, error) {
+	client := &http.Client{Timeout: downloadTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download encoding: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download encoding: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
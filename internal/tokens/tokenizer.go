@@ -0,0 +1,119 @@
+package tokens
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/isaacphi/slop/internal/llm/provider"
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// Names of the tokenizers a preset can choose
+const (
+	Generic   = "generic"   // Four characters per token, for providers without a better estimate
+	OpenAI    = "openai"    // The model's tiktoken encoding
+	Anthropic = "anthropic" // Calibrated character estimate for Claude models
+)
+
+// Names lists the tokenizers a preset can choose
+var Names = []string{Generic, OpenAI, Anthropic}
+
+// Tokenizer counts the tokens text takes up for one family of models
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// Validate checks that name is a tokenizer, empty picks one for the provider
+func Validate(name string) error {
+	if name != "" && !slices.Contains(Names, name) {
+		return fmt.Errorf("unknown tokenizer %q, must be one of %v", name, Names)
+	}
+	return nil
+}
+
+var (
+	tokenizersMu sync.Mutex
+	tokenizers   = make(map[string]Tokenizer)
+)
+
+// For returns the tokenizer a preset counts with. name overrides the tokenizer the
+// provider picks. Tokenizers are shared so encodings are only loaded once
+func For(name string, providerName string, model string) Tokenizer {
+	if name == "" {
+		if p, err := provider.Get(providerName); err == nil {
+			name = p.Tokenizer()
+		}
+	}
+	switch name {
+	case OpenAI:
+		key := name + "/" + model
+		tokenizersMu.Lock()
+		defer tokenizersMu.Unlock()
+		if t, ok := tokenizers[key]; ok {
+			return t
+		}
+		t := &tiktokenTokenizer{model: model}
+		tokenizers[key] = t
+		return t
+	case Anthropic:
+		return anthropicTokenizer{}
+	}
+	return genericTokenizer{}
+}
+
+// CountMessages returns the number of tokens messages with the given contents take up
+// in a request
+func CountMessages(t Tokenizer, contents ...string) int {
+	total := 0
+	for _, content := range contents {
+		total += t.Count(content) + messageOverhead
+	}
+	return total
+}
+
+type genericTokenizer struct{}
+
+func (genericTokenizer) Count(text string) int {
+	return Estimate(text)
+}
+
+// anthropicCharsPerToken is the average length of a Claude token in tenths of a
+// character. Anthropic doesn't publish its tokenizer, Claude models average about 3.5
+// characters per token on English text and code
+const anthropicCharsPerToken = 35
+
+type anthropicTokenizer struct{}
+
+func (anthropicTokenizer) Count(text string) int {
+	n := utf8.RuneCountInString(text) * 10
+	return (n + anthropicCharsPerToken - 1) / anthropicCharsPerToken
+}
+
+// tiktokenTokenizer counts with the encoding of an OpenAI model. The encoding is
+// downloaded the first time it is used, the generic estimate is used if that fails
+type tiktokenTokenizer struct {
+	model    string
+	once     sync.Once
+	encoding *tiktoken.Tiktoken
+}
+
+func (t *tiktokenTokenizer) Count(text string) int {
+	t.once.Do(func() {
+		encoding, err := tiktoken.EncodingForModel(t.model)
+		if err != nil {
+			encoding, err = tiktoken.GetEncoding(tiktoken.MODEL_CL100K_BASE)
+		}
+		if err != nil {
+			slog.Warn("failed to load tokenizer, estimating tokens instead", "model", t.model, "error", err)
+			return
+		}
+		t.encoding = encoding
+	})
+	if t.encoding == nil {
+		return Estimate(text)
+	}
+	return len(t.encoding.EncodeOrdinary(text))
+}
@@ -0,0 +1,37 @@
+// Package tokens counts how many tokens text takes up in a request, for budgeting
+// the context sent to a model before the provider reports real counts
+package tokens
+
+import (
+	"unicode/utf8"
+
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+const (
+	// Most tokenizers average about four characters of English text per token
+	charsPerToken = 4
+	// Each message costs a few tokens for its role and separators
+	messageOverhead = 4
+)
+
+// Estimate returns the approximate number of tokens in text
+func Estimate(text string) int {
+	n := utf8.RuneCountInString(text)
+	return (n + charsPerToken - 1) / charsPerToken
+}
+
+// EstimateMessages returns the approximate number of tokens messages take up in a request
+func EstimateMessages(messages []domain.Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += Estimate(msg.Content) + Estimate(msg.ToolCalls) + messageOverhead
+	}
+	return total
+}
+
+// EstimateMessage returns the approximate number of tokens a single message with
+// the given content takes up in a request
+func EstimateMessage(content string) int {
+	return Estimate(content) + messageOverhead
+}
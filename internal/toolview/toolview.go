@@ -0,0 +1,189 @@
+package toolview
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/llm"
+)
+
+const maxArgumentLength = 24
+
+// Call is a tool call together with its result, as shown in thread views
+type Call struct {
+	ID        string
+	Server    string
+	Tool      string
+	Arguments json.RawMessage
+	Result    string
+	Failed    bool
+	Duration  time.Duration // Zero if the call was not timed
+}
+
+// Calls pairs the tool calls of an assistant message with their results in the tool
+// message that replies to it. Calls are in the order the model made them. ok is false
+// if the results could not be matched to the calls
+func Calls(assistant domain.Message, tool domain.Message) ([]Call, bool) {
+	var toolCalls []llm.ToolCall
+	if assistant.ToolCalls == "" || json.Unmarshal([]byte(assistant.ToolCalls), &toolCalls) != nil || len(toolCalls) == 0 {
+		return nil, false
+	}
+	metadata, err := tool.GetMetadata()
+	if err != nil {
+		return nil, false
+	}
+
+	// Results are written in the order the calls finished, each starting with a header
+	// naming the call
+	type located struct {
+		call  int
+		start int // Offset of the header
+		body  int // Offset of the result after the header
+	}
+	var found []located
+	for i, call := range toolCalls {
+		header := fmt.Sprintf("Name: %s\nID: %s\nArguments: %s\nResult:\n", call.Name, call.ID, string(call.Arguments))
+		start := strings.Index(tool.Content, header)
+		if start < 0 {
+			return nil, false
+		}
+		found = append(found, located{call: i, start: start, body: start + len(header)})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].start < found[j].start })
+
+	calls := make([]Call, len(toolCalls))
+	for i, loc := range found {
+		end := len(tool.Content)
+		if i+1 < len(found) {
+			end = found[i+1].start
+		}
+		result := strings.TrimSuffix(strings.TrimSuffix(tool.Content[loc.body:end], "\n"), "\n")
+
+		toolCall := toolCalls[loc.call]
+		server, name, ok := strings.Cut(toolCall.Name, "__")
+		if !ok {
+			server, name = "", toolCall.Name
+		}
+		calls[loc.call] = Call{
+			ID:        toolCall.ID,
+			Server:    server,
+			Tool:      name,
+			Arguments: toolCall.Arguments,
+			Result:    strings.TrimPrefix(result, "Error: "),
+			Failed:    strings.HasPrefix(result, "Error: "),
+			Duration:  metadata.ToolDurations[toolCall.ID],
+		}
+	}
+	return calls, true
+}
+
+// Name is the server and tool of the call, such as filesystem.read_file
+func (c Call) Name() string {
+	if c.Server == "" {
+		return c.Tool
+	}
+	return c.Server + "." + c.Tool
+}
+
+// Summary describes the call on one line, such as
+// "✓ filesystem.read_file(path=…/notes.md) 1.2s"
+func (c Call) Summary() string {
+	mark := "✓"
+	if c.Failed {
+		mark = "✗"
+	}
+	summary := fmt.Sprintf("%s %s(%s)", mark, c.Name(), shortArguments(c.Arguments))
+	if c.Duration > 0 {
+		summary += " " + formatDuration(c.Duration)
+	}
+	return summary
+}
+
+// Details shows the full arguments and result of the call, indented under its summary
+func (c Call) Details() string {
+	var b strings.Builder
+	arguments := string(c.Arguments)
+	var indented bytes.Buffer
+	if json.Indent(&indented, c.Arguments, "    ", "  ") == nil {
+		arguments = indented.String()
+	}
+	fmt.Fprintf(&b, "  Arguments:\n    %s\n", arguments)
+	if c.Failed {
+		b.WriteString("  Error:\n")
+	} else {
+		b.WriteString("  Result:\n")
+	}
+	for _, line := range strings.Split(c.Result, "\n") {
+		fmt.Fprintf(&b, "    %s\n", line)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Pending describes a call that hasn't run yet on one line, such as
+// "filesystem.read_file(path=…/notes.md)"
+func Pending(call llm.ToolCall) string {
+	name := strings.Replace(call.Name, "__", ".", 1)
+	return fmt.Sprintf("%s(%s)", name, shortArguments(call.Arguments))
+}
+
+// Render shows calls as one line summaries, followed by their details if expanded
+func Render(calls []Call, expanded bool) string {
+	lines := make([]string, 0, len(calls))
+	for _, call := range calls {
+		lines = append(lines, call.Summary())
+		if expanded {
+			lines = append(lines, call.Details())
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// shortArguments lists the arguments as name=value, shortening long values
+func shortArguments(raw json.RawMessage) string {
+	var arguments map[string]any
+	if err := json.Unmarshal(raw, &arguments); err != nil {
+		return shorten(string(raw))
+	}
+
+	names := make([]string, 0, len(arguments))
+	for name := range arguments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		var value string
+		switch v := arguments[name].(type) {
+		case string:
+			value = v
+		default:
+			encoded, _ := json.Marshal(v)
+			value = string(encoded)
+		}
+		parts = append(parts, name+"="+shorten(value))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// shorten keeps the end of long values, which for paths is the most telling part
+func shorten(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	runes := []rune(value)
+	if len(runes) <= maxArgumentLength {
+		return value
+	}
+	return "…" + string(runes[len(runes)-maxArgumentLength+1:])
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
@@ -0,0 +1,92 @@
+// Package transcript splits conversations copied out of chat interfaces into the
+// messages of a thread
+package transcript
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// ErrNoSpeakers is returned when a transcript has no speaker labels to split it on
+var ErrNoSpeakers = errors.New("no speaker labels found")
+
+// speakers maps the labels chat interfaces put before each turn to the role they stand for
+var speakers = map[string]domain.Role{
+	"user":      domain.RoleHuman,
+	"human":     domain.RoleHuman,
+	"you":       domain.RoleHuman,
+	"me":        domain.RoleHuman,
+	"prompt":    domain.RoleHuman,
+	"assistant": domain.RoleAssistant,
+	"ai":        domain.RoleAssistant,
+	"bot":       domain.RoleAssistant,
+	"model":     domain.RoleAssistant,
+	"response":  domain.RoleAssistant,
+	"chatgpt":   domain.RoleAssistant,
+	"gpt":       domain.RoleAssistant,
+	"claude":    domain.RoleAssistant,
+	"gemini":    domain.RoleAssistant,
+}
+
+// labelPattern matches a line starting a turn such as "User: hi", "**Claude:**",
+// "## Assistant" or "ChatGPT said:". The label is the first group and text following
+// it on the same line is the second
+var labelPattern = regexp.MustCompile(`^(?:#{1,6}\s*)?(?:\*\*|__)?([A-Za-z][A-Za-z ]{0,15}?)(?:\s+said)?(?:\*\*|__)?\s*(?::(?:\*\*|__)?\s*(.*))?$`)
+
+// Split divides a transcript into messages at lines starting with a speaker label.
+// Text before the first label is dropped and consecutive turns of the same speaker
+// are joined
+func Split(text string) ([]domain.Message, error) {
+	var messages []domain.Message
+	var current *strings.Builder
+	flush := func() {
+		if current == nil {
+			return
+		}
+		messages[len(messages)-1].Content = strings.TrimSpace(current.String())
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if role, rest, ok := parseLabel(line); ok {
+			flush()
+			if len(messages) > 0 && messages[len(messages)-1].Role == role {
+				current.WriteString("\n\n" + rest)
+				continue
+			}
+			messages = append(messages, domain.Message{Role: role})
+			current = &strings.Builder{}
+			current.WriteString(rest)
+			continue
+		}
+		if current != nil {
+			current.WriteString("\n" + line)
+		}
+	}
+	flush()
+
+	if len(messages) == 0 {
+		return nil, ErrNoSpeakers
+	}
+	// Labels with nothing after them, such as a trailing "User:", aren't turns
+	kept := messages[:0]
+	for _, msg := range messages {
+		if msg.Content != "" {
+			kept = append(kept, msg)
+		}
+	}
+	return kept, nil
+}
+
+// parseLabel reports whether line starts a turn, returning its role and the text after
+// the label
+func parseLabel(line string) (domain.Role, string, bool) {
+	match := labelPattern.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return "", "", false
+	}
+	role, ok := speakers[strings.ToLower(strings.TrimSpace(match[1]))]
+	return role, match[2], ok
+}
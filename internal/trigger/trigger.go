@@ -0,0 +1,311 @@
+// Package trigger parses the conditions that decide whether a prompt is added to the
+// system message. A condition combines terms with and, or, not and parentheses:
+//
+//	toolset:github and (file:go or file:*.sql) and not tag:scratch
+//
+// Terms are written as key:value, values containing spaces or parentheses are quoted:
+//
+//	toolset:NAME   a toolset of the preset is active
+//	file:EXT       a file with this extension is mentioned, such as go or .go
+//	file:GLOB      a file whose name matches the glob is mentioned, such as *_test.go
+//	tag:NAME       the thread has this tag
+//	role:ROLE      a message in the conversation has this role
+//	last:ROLE      the message being answered has this role, such as tool
+//	match:REGEX    the regex matches the message or its history
+package trigger
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// Signals are the facts about a conversation that conditions are evaluated against
+type Signals struct {
+	Toolsets []string      // Toolsets active in the preset
+	Tags     []string      // Tags of the thread
+	Roles    []domain.Role // Roles of the history and the message being answered, oldest first
+	Text     string        // The message and its history
+	files    []string
+}
+
+// Condition is a parsed trigger condition
+type Condition interface {
+	Match(s *Signals) bool
+}
+
+// UsesTags reports whether evaluating the condition needs the thread's tags
+func UsesTags(c Condition) bool {
+	switch c := c.(type) {
+	case term:
+		return c.key == "tag"
+	case not:
+		return UsesTags(c.inner)
+	case and:
+		return slices.ContainsFunc(c, UsesTags)
+	case or:
+		return slices.ContainsFunc(c, UsesTags)
+	}
+	return false
+}
+
+type term struct {
+	key   string
+	value string
+	regex *regexp.Regexp
+}
+
+type not struct{ inner Condition }
+
+type and []Condition
+
+type or []Condition
+
+func (n not) Match(s *Signals) bool { return !n.inner.Match(s) }
+
+func (a and) Match(s *Signals) bool {
+	for _, c := range a {
+		if !c.Match(s) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o or) Match(s *Signals) bool {
+	for _, c := range o {
+		if c.Match(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t term) Match(s *Signals) bool {
+	switch t.key {
+	case "toolset":
+		return slices.Contains(s.Toolsets, t.value)
+	case "tag":
+		return slices.Contains(s.Tags, t.value)
+	case "role":
+		return slices.Contains(s.Roles, domain.Role(t.value))
+	case "last":
+		return len(s.Roles) > 0 && s.Roles[len(s.Roles)-1] == domain.Role(t.value)
+	case "match":
+		return t.regex.MatchString(s.Text)
+	case "file":
+		return slices.ContainsFunc(s.referencedFiles(), t.matchFile)
+	}
+	return false
+}
+
+// matchFile matches a file name against an extension or a glob
+func (t term) matchFile(name string) bool {
+	if strings.ContainsAny(t.value, "*?[") {
+		matched, _ := path.Match(t.value, name)
+		return matched
+	}
+	return strings.EqualFold(path.Ext(name), "."+strings.TrimPrefix(t.value, "."))
+}
+
+// filePattern finds words that look like file names, the extension has to start with a letter
+// so version numbers and decimals are skipped
+var filePattern = regexp.MustCompile(`[\w~./-]*\w\.[A-Za-z][A-Za-z0-9]{0,9}\b`)
+
+// referencedFiles returns the base names of the files mentioned in the text
+func (s *Signals) referencedFiles() []string {
+	if s.files == nil {
+		s.files = []string{}
+		for _, match := range filePattern.FindAllString(s.Text, -1) {
+			s.files = append(s.files, path.Base(match))
+		}
+	}
+	return s.files
+}
+
+var roles = []domain.Role{domain.RoleHuman, domain.RoleAssistant, domain.RoleTool, domain.RoleSystem}
+
+// Parse parses a condition
+func Parse(input string) (Condition, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("condition is empty")
+	}
+
+	p := &parser{tokens: tokens}
+	c, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	return c, nil
+}
+
+type token struct {
+	text   string
+	quoted bool // Quoted values are never keywords or parentheses
+}
+
+// tokenize splits a condition into parentheses, keywords and key:value terms
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	r := []rune(input)
+	for i := 0; i < len(r); {
+		switch {
+		case unicode.IsSpace(r[i]):
+			i++
+		case r[i] == '(' || r[i] == ')':
+			tokens = append(tokens, token{text: string(r[i])})
+			i++
+		default:
+			var b strings.Builder
+			quoted := false
+			for i < len(r) && !unicode.IsSpace(r[i]) && r[i] != '(' && r[i] != ')' {
+				if r[i] != '"' {
+					b.WriteRune(r[i])
+					i++
+					continue
+				}
+				quoted = true
+				i++
+				for ; i < len(r) && r[i] != '"'; i++ {
+					if r[i] == '\\' && i+1 < len(r) && r[i+1] == '"' {
+						i++
+					}
+					b.WriteRune(r[i])
+				}
+				if i == len(r) {
+					return nil, fmt.Errorf("unterminated quote in %q", input)
+				}
+				i++
+			}
+			tokens = append(tokens, token{text: b.String(), quoted: quoted})
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// keyword reports whether the next token is the given keyword or parenthesis
+func (p *parser) keyword(word string) bool {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].quoted {
+		return false
+	}
+	return strings.EqualFold(p.tokens[p.pos].text, word)
+}
+
+func (p *parser) parseOr() (Condition, error) {
+	c, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	terms := or{c}
+	for p.keyword("or") {
+		p.pos++
+		c, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, c)
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+func (p *parser) parseAnd() (Condition, error) {
+	c, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	terms := and{c}
+	for p.keyword("and") {
+		p.pos++
+		c, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, c)
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+func (p *parser) parseUnary() (Condition, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("condition ends unexpectedly")
+	}
+
+	switch {
+	case p.keyword("not"):
+		p.pos++
+		c, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return not{c}, nil
+
+	case p.keyword("("):
+		p.pos++
+		c, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword(")") {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return c, nil
+	}
+
+	tok := p.tokens[p.pos]
+	p.pos++
+	return parseTerm(tok.text)
+}
+
+// parseTerm parses and checks a key:value term
+func parseTerm(text string) (Condition, error) {
+	key, value, ok := strings.Cut(text, ":")
+	if !ok || value == "" {
+		return nil, fmt.Errorf("expected a key:value term but found %q", text)
+	}
+
+	t := term{key: strings.ToLower(key), value: value}
+	switch t.key {
+	case "toolset", "tag":
+	case "file":
+		if _, err := path.Match(value, ""); err != nil {
+			return nil, fmt.Errorf("invalid file pattern %q: %w", value, err)
+		}
+	case "role", "last":
+		if !slices.Contains(roles, domain.Role(value)) {
+			return nil, fmt.Errorf("unknown role %q, expected one of %v", value, roles)
+		}
+	case "match":
+		regex, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", value, err)
+		}
+		t.regex = regex
+	default:
+		return nil, fmt.Errorf("unknown condition %q, expected toolset, file, tag, role, last or match", key)
+	}
+	return t, nil
+}
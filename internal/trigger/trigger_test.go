@@ -0,0 +1,114 @@
+package trigger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+func TestMatch(t *testing.T) {
+	signals := Signals{
+		Toolsets: []string{"github", "files"},
+		Tags:     []string{"review", "needs work"},
+		Roles:    []domain.Role{domain.RoleHuman, domain.RoleAssistant, domain.RoleTool},
+		Text:     "Look at internal/store/user_test.go and schema.SQL, this is version 1.2",
+	}
+
+	tests := []struct {
+		condition string
+		want      bool
+	}{
+		{condition: "toolset:github", want: true},
+		{condition: "toolset:slack", want: false},
+		{condition: "tag:review", want: true},
+		{condition: `tag:"needs work"`, want: true},
+		{condition: "role:assistant", want: true},
+		{condition: "role:system", want: false},
+		{condition: "last:tool", want: true},
+		{condition: "last:human", want: false},
+		{condition: "file:go", want: true},
+		{condition: "file:.sql", want: true},
+		{condition: "file:*_test.go", want: true},
+		{condition: "file:user.go", want: false},
+		{condition: "file:2", want: false},
+		{condition: `match:"version \d"`, want: true},
+		{condition: `match:"(?i)LOOK"`, want: true},
+		{condition: "toolset:github and tag:review", want: true},
+		{condition: "toolset:github and tag:scratch", want: false},
+		{condition: "toolset:slack or tag:review", want: true},
+		{condition: "not tag:scratch", want: true},
+		{condition: "not not tag:scratch", want: false},
+		{condition: "toolset:slack and tag:review or last:tool", want: true},
+		{condition: "toolset:slack and (tag:review or last:tool)", want: false},
+		{condition: "toolset:github AND (file:go OR file:*.sql) AND NOT tag:scratch", want: true},
+		{condition: `tag:"and"`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			c, err := Parse(tt.condition)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.condition, err)
+			}
+			s := signals
+			if got := c.Match(&s); got != tt.want {
+				t.Errorf("Parse(%q).Match() = %v, want %v", tt.condition, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		condition string
+		want      string
+	}{
+		{condition: "", want: "condition is empty"},
+		{condition: "   ", want: "condition is empty"},
+		{condition: "tag:a and", want: "condition ends unexpectedly"},
+		{condition: "not", want: "condition ends unexpectedly"},
+		{condition: "(tag:a or tag:b", want: "missing closing parenthesis"},
+		{condition: "tag:a)", want: `unexpected ")"`},
+		{condition: "tag:a tag:b", want: `unexpected "tag:b"`},
+		{condition: "github", want: "expected a key:value term"},
+		{condition: "tag:", want: "expected a key:value term"},
+		{condition: "color:red", want: `unknown condition "color"`},
+		{condition: "role:user", want: `unknown role "user"`},
+		{condition: "match:(?i)look", want: "expected a key:value term"},
+		{condition: "match:[a-", want: "invalid regex"},
+		{condition: "file:[a-", want: "invalid file pattern"},
+		{condition: `tag:"open`, want: "unterminated quote"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			_, err := Parse(tt.condition)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse(%q) error = %v, want one containing %q", tt.condition, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsesTags(t *testing.T) {
+	tests := []struct {
+		condition string
+		want      bool
+	}{
+		{condition: "tag:review", want: true},
+		{condition: "toolset:github", want: false},
+		{condition: "toolset:github and (file:go or not tag:scratch)", want: true},
+		{condition: "toolset:github or last:tool", want: false},
+	}
+
+	for _, tt := range tests {
+		c, err := Parse(tt.condition)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.condition, err)
+		}
+		if got := UsesTags(c); got != tt.want {
+			t.Errorf("UsesTags(%q) = %v, want %v", tt.condition, got, tt.want)
+		}
+	}
+}
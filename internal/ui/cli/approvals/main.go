@@ -0,0 +1,10 @@
+package approvals
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var ApprovalsCmd = &cobra.Command{
+	Use:   "approvals",
+	Short: "Inspect tool call approvals and rejections",
+}
@@ -0,0 +1,203 @@
+package approvals
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+// maxArgumentsLength limits how much of the arguments of a call are shown in the table
+const maxArgumentsLength = 60
+
+var (
+	daysFlag int
+	topFlag  int
+)
+
+// toolRejections are the rejections of one tool
+type toolRejections struct {
+	tool      string
+	count     int
+	reasons   map[string]int // Rejections by reason, without the ones given no reason
+	arguments map[string]int // Rejections by hash of the arguments
+	threads   map[string]bool
+	last      time.Time
+}
+
+// repeatedArguments are arguments of a tool rejected more than once
+type repeatedArguments struct {
+	tool      string
+	hash      string
+	arguments string
+	count     int
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [tool]",
+	Short: "Summarize rejected tool calls",
+	Long:  "Show how often each tool had its calls rejected, the reasons given most often and the arguments rejected more than once. Tools that are rejected a lot are candidates for tighter toolsets or clearer prompts. The tool is given as server__tool. E.g. slop approvals stats --days 7",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+
+		if daysFlag <= 0 {
+			return fmt.Errorf("--days must be positive")
+		}
+		if topFlag < 0 {
+			return fmt.Errorf("--top can't be negative")
+		}
+
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		since := time.Date(now.Year(), now.Month(), now.Day()-daysFlag+1, 0, 0, 0, 0, time.Local)
+		rejections, err := repo.ListToolRejections(cmd.Context(), since)
+		if err != nil {
+			return fmt.Errorf("failed to list tool rejections: %w", err)
+		}
+
+		tools := make(map[string]*toolRejections)
+		examples := make(map[string]domain.ToolRejection)
+		for _, rejection := range rejections {
+			if len(args) > 0 && rejection.Tool != args[0] {
+				continue
+			}
+			stats, ok := tools[rejection.Tool]
+			if !ok {
+				stats = &toolRejections{
+					tool:      rejection.Tool,
+					reasons:   make(map[string]int),
+					arguments: make(map[string]int),
+					threads:   make(map[string]bool),
+				}
+				tools[rejection.Tool] = stats
+			}
+			stats.count++
+			stats.threads[rejection.ThreadID.String()] = true
+			stats.arguments[rejection.ArgumentsHash]++
+			if reason := normalizeReason(rejection.Reason); reason != "" {
+				stats.reasons[reason]++
+			}
+			if rejection.CreatedAt.After(stats.last) {
+				stats.last = rejection.CreatedAt
+			}
+			examples[rejection.ArgumentsHash] = rejection
+		}
+
+		if len(tools) == 0 {
+			fmt.Printf("No rejected tool calls in the last %d days\n", daysFlag)
+			return nil
+		}
+
+		// Most rejected tools first
+		sorted := make([]*toolRejections, 0, len(tools))
+		for _, stats := range tools {
+			sorted = append(sorted, stats)
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			if sorted[i].count != sorted[j].count {
+				return sorted[i].count > sorted[j].count
+			}
+			return sorted[i].tool < sorted[j].tool
+		})
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Tool\tRejected\tWith Reason\tThreads\tLast")
+		var repeated []repeatedArguments
+		for _, stats := range sorted {
+			withReason := 0
+			for _, n := range stats.reasons {
+				withReason += n
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n",
+				stats.tool,
+				stats.count,
+				withReason,
+				len(stats.threads),
+				stats.last.Local().Format("2006-01-02 15:04"),
+			)
+			for hash, n := range stats.arguments {
+				if n > 1 {
+					repeated = append(repeated, repeatedArguments{tool: stats.tool, hash: hash, arguments: examples[hash].Arguments, count: n})
+				}
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if topFlag > 0 {
+			for _, stats := range sorted {
+				reasons := topReasons(stats.reasons, topFlag)
+				if len(reasons) == 0 {
+					continue
+				}
+				fmt.Printf("\nTop reasons for %s:\n", stats.tool)
+				for _, reason := range reasons {
+					fmt.Printf("  %d× %s\n", stats.reasons[reason], reason)
+				}
+			}
+		}
+
+		if len(repeated) > 0 {
+			sort.Slice(repeated, func(i, j int) bool {
+				if repeated[i].count != repeated[j].count {
+					return repeated[i].count > repeated[j].count
+				}
+				return repeated[i].tool < repeated[j].tool
+			})
+			fmt.Println("\nArguments rejected more than once:")
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "Tool\tHash\tRejected\tArguments")
+			for _, r := range repeated {
+				arguments := r.arguments
+				if len(arguments) > maxArgumentsLength {
+					arguments = arguments[:maxArgumentsLength-3] + "..."
+				}
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", r.tool, r.hash[:12], r.count, arguments)
+			}
+			return w.Flush()
+		}
+		return nil
+	},
+}
+
+// normalizeReason makes reasons that only differ in case or spacing count as the same
+func normalizeReason(reason string) string {
+	return strings.ToLower(strings.Join(strings.Fields(reason), " "))
+}
+
+// topReasons returns the n reasons given most often, ties in alphabetical order
+func topReasons(reasons map[string]int, n int) []string {
+	sorted := make([]string, 0, len(reasons))
+	for reason := range reasons {
+		sorted = append(sorted, reason)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if reasons[sorted[i]] != reasons[sorted[j]] {
+			return reasons[sorted[i]] > reasons[sorted[j]]
+		}
+		return sorted[i] < sorted[j]
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+func init() {
+	statsCmd.Flags().IntVar(&daysFlag, "days", 30, "Number of days to include, counting today")
+	statsCmd.Flags().IntVar(&topFlag, "top", 3, "Number of reasons to show for each tool, 0 to hide them")
+	ApprovalsCmd.AddCommand(statsCmd)
+}
@@ -0,0 +1,80 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/archive"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var ExportCmd = &cobra.Command{
+	Use:   "export [thread_id...] archive.slop",
+	Short: "Export conversation history to a portable archive",
+	Long: `Export threads with their messages, tags and artifacts to an archive that can be imported
+on another machine with slop import. Use --all to export every thread, including archived
+threads. Pass - as the archive to write to stdout.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		path, ids := args[len(args)-1], args[:len(args)-1]
+		if allFlag == (len(ids) > 0) {
+			return fmt.Errorf("give the threads to export or use --all")
+		}
+
+		var threads []*domain.Thread
+		if allFlag {
+			for _, archived := range []bool{false, true} {
+				found, err := repo.FindThreads(cmd.Context(), repository.ThreadFilter{Archived: archived})
+				if err != nil {
+					return fmt.Errorf("failed to find threads: %w", err)
+				}
+				threads = append(threads, found...)
+			}
+		} else {
+			for _, id := range ids {
+				thread, err := repo.GetThreadByPartialID(cmd.Context(), id)
+				if err != nil {
+					return fmt.Errorf("failed to find thread %s: %w", id, err)
+				}
+				threads = append(threads, thread)
+			}
+		}
+
+		var w io.Writer = os.Stdout
+		if path != "-" {
+			f, err := os.Create(path)
+			if err != nil {
+				return fmt.Errorf("failed to create archive: %w", err)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		manifest, err := archive.Write(cmd.Context(), repo, w, threads)
+		if err != nil {
+			return err
+		}
+
+		if path != "-" {
+			output.Printf("Exported %d threads with %d messages and %d artifacts to %s\n",
+				manifest.Threads, manifest.Messages, manifest.Artifacts, path)
+		}
+		return nil
+	},
+}
+
+func init() {
+	ExportCmd.Flags().BoolVar(&allFlag, "all", false, "Export every thread")
+}
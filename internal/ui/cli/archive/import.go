@@ -0,0 +1,76 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/archive"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var ImportCmd = &cobra.Command{
+	Use:   "import archive.slop",
+	Short: "Import conversation history from an archive",
+	Long: `Import the threads of an archive written by slop export. Threads that already exist are
+handled with --on-conflict:
+
+  skip       leave the existing thread unchanged
+  merge      add the messages, tags and artifacts the existing thread doesn't have yet
+  duplicate  import the thread again as a new thread
+
+Pass - as the archive to read from stdin.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		onConflict, err := archive.ParseOnConflict(onConflictFlag)
+		if err != nil {
+			return err
+		}
+
+		var r io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open archive: %w", err)
+			}
+			defer f.Close()
+			r = f
+		}
+
+		a, err := archive.Read(r)
+		if err != nil {
+			return err
+		}
+
+		result, err := archive.Import(cmd.Context(), repo, a, onConflict)
+		if err != nil {
+			return err
+		}
+
+		output.Printf("Imported %d threads", result.Imported)
+		if result.Merged > 0 {
+			output.Printf(", merged %d", result.Merged)
+		}
+		if result.Duplicated > 0 {
+			output.Printf(", duplicated %d", result.Duplicated)
+		}
+		if result.Skipped > 0 {
+			output.Printf(", skipped %d that already exist", result.Skipped)
+		}
+		output.Println()
+		return nil
+	},
+}
+
+func init() {
+	ImportCmd.Flags().StringVar(&onConflictFlag, "on-conflict", string(archive.Skip), "What to do with threads that already exist: skip, merge or duplicate")
+}
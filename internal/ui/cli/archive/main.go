@@ -0,0 +1,6 @@
+package archive
+
+var (
+	allFlag        bool
+	onConflictFlag string
+)
@@ -0,0 +1,65 @@
+package artifact
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/artifact"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "ls [thread_id]",
+	Short: "List artifacts",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		var threadID *uuid.UUID
+		if len(args) > 0 {
+			thread, err := repo.GetThreadByPartialID(cmd.Context(), args[0])
+			if err != nil {
+				return fmt.Errorf("failed to find thread: %w", err)
+			}
+			threadID = &thread.ID
+		}
+
+		artifacts, err := repo.ListArtifacts(cmd.Context(), threadID)
+		if err != nil {
+			return fmt.Errorf("failed to list artifacts: %w", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tThread\tMessage\tCreated\tSize\tName")
+		for _, a := range artifacts {
+			messageID := ""
+			if a.MessageID != nil {
+				messageID = a.MessageID.String()[:8]
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				artifact.ShortID(a.Hash),
+				a.ThreadID.String()[:8],
+				messageID,
+				a.CreatedAt.Format(time.RFC822),
+				artifact.FormatSize(a.Size),
+				a.Name,
+			)
+		}
+		w.Flush()
+
+		return nil
+	},
+}
+
+func init() {
+	ArtifactCmd.AddCommand(listCmd)
+}
@@ -0,0 +1,10 @@
+package artifact
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var ArtifactCmd = &cobra.Command{
+	Use:   "artifact",
+	Short: "Manage saved code blocks and large tool results",
+}
@@ -0,0 +1,47 @@
+package artifact
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+var forceFlag bool
+
+var saveCmd = &cobra.Command{
+	Use:   "save [artifact_id] [path]",
+	Short: "Write an artifact to a file",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		a, content, err := repo.GetArtifact(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to get artifact: %w", err)
+		}
+
+		path := args[1]
+		if _, err := os.Stat(path); err == nil && !forceFlag {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+		}
+
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return fmt.Errorf("failed to write artifact: %w", err)
+		}
+
+		fmt.Printf("Saved %s to %s\n", a.Name, path)
+		return nil
+	},
+}
+
+func init() {
+	saveCmd.Flags().BoolVarP(&forceFlag, "force", "f", false, "Overwrite the file if it exists")
+	ArtifactCmd.AddCommand(saveCmd)
+}
@@ -0,0 +1,37 @@
+package artifact
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+var showCmd = &cobra.Command{
+	Use:   "show [artifact_id]",
+	Short: "Print an artifact's content",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		_, content, err := repo.GetArtifact(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to get artifact: %w", err)
+		}
+
+		if _, err := os.Stdout.Write(content); err != nil {
+			return fmt.Errorf("failed to write artifact: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	ArtifactCmd.AddCommand(showCmd)
+}
@@ -0,0 +1,55 @@
+package bundle
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/bundle"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export <run_id> [bundle.zip]",
+	Short: "Export a run with its setup to a bundle",
+	Long: `Export a run with its preset, the prompts and toolsets it uses, the effective configuration and its
+thread to a zip file. Values of keys that look like secrets, such as API keys, are redacted. The bundle is
+written to run-<id>.zip unless a path is given.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		run, err := repo.GetRunByPartialID(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		path := bundle.PresetName(run.ID) + ".zip"
+		if len(args) > 1 {
+			path = args[1]
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create bundle: %w", err)
+		}
+		defer f.Close()
+
+		manifest, err := bundle.Write(cmd.Context(), repo, cfg, run, f)
+		if err != nil {
+			os.Remove(path)
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+
+		output.Printf("Exported run %s of thread %s to %s\n", run.ID.String()[:8], manifest.ThreadID.String()[:8], path)
+		return nil
+	},
+}
@@ -0,0 +1,125 @@
+package bundle
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/archive"
+	"github.com/isaacphi/slop/internal/bundle"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	onConflictFlag string
+	configDirFlag  string
+	forceFlag      bool
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <bundle.zip>",
+	Short: "Recreate the setup of a run from a bundle",
+	Long: `Import the thread of a bundle and write the preset, prompts and toolsets of its run to a config file
+named bundle-<id>.slop.yaml. The file goes to the .slop directory of the project, or to the global config
+directory outside a project. The preset is named run-<id>, continue the thread with it using
+slop msg send -t <thread_id> -m run-<id>.
+
+Prompts and toolsets in the bundle are merged with configured ones of the same names like any other
+config file. MCP servers the toolsets use are not part of the bundle and have to be configured separately.
+Threads that already exist are handled with --on-conflict as in slop import.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		onConflict, err := archive.ParseOnConflict(onConflictFlag)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open bundle: %w", err)
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to open bundle: %w", err)
+		}
+		b, err := bundle.Read(f, info.Size())
+		if err != nil {
+			return err
+		}
+
+		dir := configDirFlag
+		if dir == "" {
+			dir, err = defaultConfigDir(cfg)
+			if err != nil {
+				return err
+			}
+		}
+		path := filepath.Join(dir, "bundle-"+b.Manifest.RunID.String()[:8]+".slop.yaml")
+		if _, err := os.Stat(path); err == nil && !forceFlag {
+			return fmt.Errorf("%s already exists, use --force to replace it", path)
+		} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		result, err := archive.Import(cmd.Context(), repo, b.Thread, onConflict)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create config directory: %w", err)
+		}
+		header := fmt.Sprintf("# Imported from %s, the setup of run %s\n", filepath.Base(args[0]), b.Manifest.RunID.String()[:8])
+		if err := os.WriteFile(path, append([]byte(header), b.Config...), 0644); err != nil {
+			return fmt.Errorf("failed to write config: %w", err)
+		}
+
+		threadID := b.Manifest.ThreadID.String()[:8]
+		switch {
+		case result.Skipped > 0:
+			output.Printf("Thread %s already exists and was left unchanged\n", threadID)
+		case result.Merged > 0:
+			output.Printf("Merged thread %s\n", threadID)
+		case result.Duplicated > 0:
+			output.Printf("Thread %s already exists and was imported again as a new thread\n", threadID)
+		default:
+			output.Printf("Imported thread %s\n", threadID)
+		}
+		output.Printf("Wrote preset %s to %s\n", b.Manifest.Preset, path)
+
+		for _, server := range b.Manifest.Servers {
+			if _, ok := cfg.MCPServers[server]; !ok && !mcp.IsBuiltin(server) {
+				output.Noticef("The toolsets use the MCP server %s which isn't configured\n", server)
+			}
+		}
+		return nil
+	},
+}
+
+// defaultConfigDir is the .slop directory of the project, or the global config
+// directory outside a project
+func defaultConfigDir(cfg *config.ConfigSchema) (string, error) {
+	if project := cfg.ProjectDir(); project != "" {
+		return filepath.Join(project, ".slop"), nil
+	}
+	return config.GlobalDir()
+}
+
+func init() {
+	importCmd.Flags().StringVar(&onConflictFlag, "on-conflict", string(archive.Skip), "What to do with the thread if it already exists: skip, merge or duplicate")
+	importCmd.Flags().StringVar(&configDirFlag, "config-dir", "", "Directory to write the config file to")
+	importCmd.Flags().BoolVar(&forceFlag, "force", false, "Replace the config file of an earlier import of the same bundle")
+}
@@ -0,0 +1,17 @@
+package bundle
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var BundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Export and import the setup of a run",
+	Long: `A bundle captures what a run was started with: its preset, the prompts and toolsets the preset uses,
+the effective configuration with secrets redacted and the thread of the run. Attach one to a bug report
+or share it to let someone else reproduce the run.`,
+}
+
+func init() {
+	BundleCmd.AddCommand(exportCmd, importCmd)
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+// cacheRow sums up the cached responses of a model
+type cacheRow struct {
+	model   string
+	entries int
+	live    int
+	hits    int
+	stores  int
+}
+
+// hitRate is the fraction of requests answered from the cache
+func (r cacheRow) hitRate() float64 {
+	if r.hits+r.stores == 0 {
+		return 0
+	}
+	return float64(r.hits) / float64(r.hits+r.stores)
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show how often cached responses were reused",
+	Long:  "Show how many responses are cached for each model and how many requests they answered. Responses are only cached for presets with a cacheTTL",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		responses, err := repo.ListCachedResponses(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to read the response cache: %w", err)
+		}
+		if len(responses) == 0 {
+			fmt.Println("The response cache is empty")
+			return nil
+		}
+
+		// Responses are sorted by model
+		var rows []cacheRow
+		total := cacheRow{model: "total"}
+		now := time.Now()
+		for _, response := range responses {
+			model := response.Provider + "/" + response.ModelName
+			if len(rows) == 0 || rows[len(rows)-1].model != model {
+				rows = append(rows, cacheRow{model: model})
+			}
+			for _, row := range []*cacheRow{&rows[len(rows)-1], &total} {
+				row.entries++
+				if response.ExpiresAt.After(now) {
+					row.live++
+				}
+				row.hits += response.Hits
+				row.stores += response.Stores
+			}
+		}
+		if len(rows) > 1 {
+			rows = append(rows, total)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Model\tResponses\tUnexpired\tHits\tMisses\tHit Rate")
+		for _, row := range rows {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%.0f%%\n", row.model, row.entries, row.live, row.hits, row.stores, row.hitRate()*100)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	CacheCmd.AddCommand(statsCmd)
+}
@@ -1,21 +1,103 @@
 package chat
 
 import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/agent"
 	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
 	"github.com/isaacphi/slop/internal/ui/tui"
+	"github.com/isaacphi/slop/internal/ui/tui/screens/chat"
+	"github.com/isaacphi/slop/internal/ui/tui/theme"
 	"github.com/spf13/cobra"
 )
 
 var (
+	modeFlag string
+
 	ChatCmd = &cobra.Command{
 		Use:   "chat",
 		Short: "Start interactive chat",
 		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			config := appState.Get().Config
-			tui.StartTUI(&config.KeyMap)
+			app, err := appState.Get().With(appState.Scope{Mode: modeFlag})
+			if err != nil {
+				return err
+			}
+			config := app.Config
+
+			t, err := theme.New(config.Theme)
+			if err != nil {
+				return fmt.Errorf("invalid theme configuration: %w", err)
+			}
+
+			repo, err := sqlite.Initialize(config.DBPath)
+			if err != nil {
+				return err
+			}
+
+			mcpClient := mcp.New(config.MCPServers)
+			if err := mcpClient.Start(context.Background()); err != nil {
+				return fmt.Errorf("failed to initialize MCP client: %w", err)
+			}
+			defer mcpClient.Shutdown()
+
+			presetName, preset, err := app.Preset()
+			if err != nil {
+				return err
+			}
+			agentService, err := agent.New(repo, mcpClient, preset, config.Toolsets, config.Prompts, config.Style)
+			if err != nil {
+				return fmt.Errorf("could not initialize MCP agent: %w", err)
+			}
+
+			crashDir := filepath.Dir(config.DBPath)
+			resume, err := offerResume(crashDir)
+			if err != nil {
+				return err
+			}
 
-			return nil
+			return tui.StartTUI(&config.KeyMap, t, config.Warnings(), repo, agentService, presetName, preset, config.Toolsets, config.VimMode, config.RenderMath, config.TimeFormat, config.ProjectName(), crashDir, resume)
 		},
 	}
 )
+
+// offerResume asks whether to go back to where the chat was when it last crashed
+func offerResume(crashDir string) (*chat.Session, error) {
+	crashed, err := tui.LoadCrashedSession(crashDir)
+	if err != nil || crashed == nil {
+		return nil, err
+	}
+
+	what := "your draft"
+	if crashed.ThreadID != uuid.Nil {
+		what = "thread " + crashed.ThreadID.String()[:8]
+		if crashed.Draft != "" {
+			what += " with your draft"
+		}
+	}
+	output.Noticef("The chat crashed at %s, the report is in %s.\nResume %s? [Y/n]: ",
+		crashed.CrashedAt.Format(time.DateTime), crashed.Report, what)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "", "y", "yes":
+		return &crashed.Session, nil
+	}
+	return nil, nil
+}
+
+func init() {
+	ChatCmd.Flags().StringVar(&modeFlag, "mode", "", "Apply a bundle of overrides from the modes config, such as fast or quality")
+}
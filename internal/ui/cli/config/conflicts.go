@@ -0,0 +1,105 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var interactiveFlag bool
+
+var conflictsCmd = &cobra.Command{
+	Use:   "conflicts",
+	Short: "Show keys set to different values in more than one config file",
+	Long: `Show keys set to different values in more than one config file, with the value from each file.
+The last file listed is the one in effect. With --interactive, choose the value to keep for each key
+and the key is removed from the other files.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conflicts, err := config.FindConflicts()
+		if err != nil {
+			return fmt.Errorf("failed to find conflicts: %w", err)
+		}
+		if len(conflicts) == 0 {
+			fmt.Println("No conflicting configuration values")
+			return nil
+		}
+
+		reader := bufio.NewReader(os.Stdin)
+		for _, conflict := range conflicts {
+			fmt.Println(conflict.Key)
+			for i, val := range conflict.Values {
+				active := ""
+				if i == len(conflict.Values)-1 {
+					active = " (active)"
+				}
+				fmt.Printf("  %d) %s: %s%s\n", i+1, val.File, formatValue(val.Value), active)
+			}
+
+			if interactiveFlag {
+				if err := resolveConflict(reader, conflict); err != nil {
+					return err
+				}
+			}
+			fmt.Println()
+		}
+		return nil
+	},
+}
+
+// resolveConflict asks which value to keep and removes the key from the other files
+func resolveConflict(reader *bufio.Reader, conflict config.Conflict) error {
+	for {
+		fmt.Printf("Keep which value? [1-%d, enter to skip] ", len(conflict.Values))
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+
+		input = strings.TrimSpace(input)
+		if input == "" {
+			fmt.Println("Skipped")
+			return nil
+		}
+
+		choice, err := strconv.Atoi(input)
+		if err != nil || choice < 1 || choice > len(conflict.Values) {
+			fmt.Printf("Please enter a number between 1 and %d\n", len(conflict.Values))
+			continue
+		}
+
+		keep := conflict.Values[choice-1].File
+		for _, val := range conflict.Values {
+			if val.File == keep {
+				continue
+			}
+			if err := config.RemoveKey(val.File, conflict.Key); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", conflict.Key, err)
+			}
+			fmt.Printf("Removed %s from %s\n", conflict.Key, val.File)
+		}
+		return nil
+	}
+}
+
+func formatValue(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(b)
+}
+
+func init() {
+	conflictsCmd.Flags().BoolVarP(&interactiveFlag, "interactive", "i", false, "Choose which value to keep for each conflicting key")
+	ConfigCmd.AddCommand(conflictsCmd)
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var editGlobalFlag bool
+
+var editCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open a config file in your editor and check it when it is saved",
+	Long: `Open the project's config file in $VISUAL or $EDITOR, or the global one with --global. The file is
+config.slop.yaml, or the first config file of the directory when there is no config.slop.yaml.
+
+Once the editor exits the configuration is loaded with the saved file. When it doesn't load the error
+is shown and the file can be edited again, or put back the way it was.`,
+	Args: cobra.NoArgs,
+	// Editing is the way to fix a configuration that doesn't load, so it isn't loaded first
+	PersistentPreRunE: skipConfig,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := targetDir(editGlobalFlag)
+		if err != nil {
+			return err
+		}
+		file, err := config.FileFor(dir, "")
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+
+		original, err := os.ReadFile(file)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error reading %s: %w", file, err)
+		}
+		existed := err == nil
+
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			if err := runEditor(file); err != nil {
+				return err
+			}
+			edited, err := os.ReadFile(file)
+			if os.IsNotExist(err) || (err == nil && bytes.Equal(edited, original)) {
+				output.Println("No changes")
+				return nil
+			}
+
+			cfg, err := config.New(nil)
+			if err == nil {
+				printWarnings(cfg, file)
+				output.Printf("Saved %s\n", file)
+				return nil
+			}
+
+			output.Noticef("The configuration doesn't load: %v\nEdit %s again? [Y/n]: ", err, file)
+			input, inputErr := reader.ReadString('\n')
+			if answer := strings.ToLower(strings.TrimSpace(input)); inputErr != nil || answer == "n" || answer == "no" {
+				if restoreErr := restore(file, original, existed); restoreErr != nil {
+					return fmt.Errorf("%w, and %s could not be restored: %v", err, file, restoreErr)
+				}
+				return fmt.Errorf("%s was not changed: %w", file, err)
+			}
+		}
+	},
+}
+
+// runEditor opens a file in $VISUAL or $EDITOR, or vi when neither is set, and waits
+// for it to exit. The variables may include arguments such as "code --wait"
+func runEditor(file string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	fields := strings.Fields(editor)
+	c := exec.Command(fields[0], append(fields[1:], file)...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("failed to run %s: %w", editor, err)
+	}
+	return nil
+}
+
+// printWarnings shows the configuration warnings about a file, such as unknown keys
+func printWarnings(cfg *config.ConfigSchema, file string) {
+	for _, warning := range cfg.Warnings() {
+		if strings.Contains(warning, file) {
+			output.Noticef("warning: %s\n", warning)
+		}
+	}
+}
+
+func init() {
+	editCmd.Flags().BoolVarP(&editGlobalFlag, "global", "g", false, "Edit the global config file instead of the project's")
+	ConfigCmd.AddCommand(editCmd)
+}
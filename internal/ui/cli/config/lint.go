@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Find unused definitions and dead references in the configuration",
+	Long: `Find prompts that are never included, toolsets not used by any preset, MCP servers not in any
+toolset, presets identical to another preset, and references to prompts, toolsets, servers or presets
+that do not exist.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		issues := appState.Get().Config.Lint()
+		if len(issues) == 0 {
+			fmt.Println("No configuration issues")
+			return nil
+		}
+
+		for _, issue := range issues {
+			fmt.Printf("- %s: %s\n", issue.Key, issue.Message)
+		}
+		return nil
+	},
+}
+
+func init() {
+	ConfigCmd.AddCommand(lintCmd)
+}
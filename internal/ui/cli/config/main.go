@@ -1,6 +1,9 @@
 package config
 
 import (
+	"fmt"
+	"path/filepath"
+
 	"github.com/isaacphi/slop/internal/appState"
 	"github.com/spf13/cobra"
 )
@@ -11,8 +14,8 @@ var (
 
 	ConfigCmd = &cobra.Command{
 		Use:   "config [prefix]",
-		Short: "View configuration",
-		Long:  "Read configuration. If prefix is included, only show configuration under that path. E.g. slop config models.openai",
+		Short: "View and change configuration",
+		Long:  "Read configuration. If prefix is included, only show configuration under that path. E.g. slop config models.openai. The project whose .slop directory is in use is shown first",
 		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg := appState.Get().Config
@@ -21,6 +24,9 @@ var (
 				prefixFilter = args[0]
 			}
 
+			if project := cfg.ProjectName(); project != "" && prefixFilter == "" {
+				fmt.Printf("# project %s (%s)\n", project, filepath.Join(cfg.ProjectDir(), ".slop"))
+			}
 			cfg.PrintConfig(includeSources, prefixFilter)
 
 			return nil
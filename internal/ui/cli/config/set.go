@@ -0,0 +1,118 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var setGlobalFlag bool
+
+var setCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a configuration value in a config file",
+	Long: `Set a key such as presets.default.temperature in the project's .slop directory, or in the global
+config directory with --global. The key is written to the file that sets it already, otherwise to
+config.slop.yaml. Lists are written as yaml such as [a, b].
+
+The key must exist in the configuration schema and the value must have its type. The file is left
+as it was when the configuration doesn't load with the new value.`,
+	Args: cobra.ExactArgs(2),
+	// Setting a value can fix a configuration that doesn't load, so it isn't loaded first
+	PersistentPreRunE: skipConfig,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, t, err := config.ParseKey(args[0])
+		if err != nil {
+			return err
+		}
+		value, err := config.ParseValue(key, t, args[1])
+		if err != nil {
+			return err
+		}
+
+		dir, err := targetDir(setGlobalFlag)
+		if err != nil {
+			return err
+		}
+		file, err := config.FileFor(dir, key)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := applyChange(file, func() error {
+			return config.SetKey(file, key, value)
+		})
+		if err != nil {
+			return err
+		}
+		output.Printf("Set %s in %s\n", key, file)
+		if source := cfg.Source(key); source != "" && source != file {
+			output.Noticef("%s is still overridden by %s\n", key, source)
+		}
+		return nil
+	},
+}
+
+// skipConfig runs instead of the root command's setup for commands that change config
+// files, which must work when the configuration doesn't load
+func skipConfig(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+// targetDir is the config directory a change goes to: the project's .slop directory,
+// or the global one
+func targetDir(global bool) (string, error) {
+	if global {
+		return config.GlobalDir()
+	}
+	dir, err := config.LocalDir()
+	if err != nil {
+		return "", err
+	}
+	if dir == "" {
+		return "", errors.New("not in a project with a .slop directory, use --global to change the global config")
+	}
+	return dir, nil
+}
+
+// applyChange changes a config file and loads the configuration with it. The file is
+// put back the way it was when the configuration doesn't load
+func applyChange(file string, change func() error) (*config.ConfigSchema, error) {
+	original, readErr := os.ReadFile(file)
+	if readErr != nil && !os.IsNotExist(readErr) {
+		return nil, fmt.Errorf("error reading %s: %w", file, readErr)
+	}
+	if err := change(); err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.New(nil)
+	if err != nil {
+		if restoreErr := restore(file, original, readErr == nil); restoreErr != nil {
+			return nil, fmt.Errorf("%w, and %s could not be restored: %v", err, file, restoreErr)
+		}
+		return nil, fmt.Errorf("%s was not changed: %w", file, err)
+	}
+	return cfg, nil
+}
+
+// restore writes back the content a config file had, or removes it if it didn't exist
+func restore(file string, original []byte, existed bool) error {
+	if !existed {
+		return os.Remove(file)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, original, info.Mode().Perm())
+}
+
+func init() {
+	setCmd.Flags().BoolVarP(&setGlobalFlag, "global", "g", false, "Write to the global config directory instead of the project's")
+	ConfigCmd.AddCommand(setCmd)
+}
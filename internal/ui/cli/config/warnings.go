@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/spf13/cobra"
+)
+
+var warningsCmd = &cobra.Command{
+	Use:   "warnings",
+	Short: "Show configuration warnings",
+	Long:  "Show unknown keys, deprecated keys and values overridden by other config files, along with the files they come from.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		warnings := appState.Get().Config.Warnings()
+		if len(warnings) == 0 {
+			fmt.Println("No configuration warnings")
+			return nil
+		}
+
+		for _, w := range warnings {
+			fmt.Printf("- %s\n", w)
+		}
+		return nil
+	},
+}
+
+func init() {
+	ConfigCmd.AddCommand(warningsCmd)
+}
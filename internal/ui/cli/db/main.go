@@ -0,0 +1,14 @@
+package db
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var DBCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the database file",
+}
+
+func init() {
+	DBCmd.AddCommand(moveCmd)
+}
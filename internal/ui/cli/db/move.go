@@ -0,0 +1,106 @@
+package db
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+// sidecarSuffixes are the files SQLite keeps next to a database while it is in use
+var sidecarSuffixes = []string{"-wal", "-shm", "-journal"}
+
+var moveCmd = &cobra.Command{
+	Use:   "move <path>",
+	Short: "Move the database to a new location",
+	Long: `Move the database from its current location to path. Run it from the project whose
+database should be moved. The default location is .slop/slop.db in projects with a
+.slop directory and $XDG_DATA_HOME/slop/slop.db everywhere else. Set dbPath in your
+config to keep using a database moved anywhere else.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+
+		from, err := filepath.Abs(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+		to, err := filepath.Abs(args[0])
+		if err != nil {
+			return err
+		}
+		if from == to {
+			return fmt.Errorf("the database is already at %s", to)
+		}
+		if _, err := os.Stat(from); err != nil {
+			return fmt.Errorf("no database at %s: %w", from, err)
+		}
+		if _, err := os.Stat(to); err == nil {
+			return fmt.Errorf("%s already exists", to)
+		}
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+
+		if err := moveFile(from, to); err != nil {
+			return err
+		}
+		for _, suffix := range sidecarSuffixes {
+			if _, err := os.Stat(from + suffix); err != nil {
+				continue
+			}
+			if err := moveFile(from+suffix, to+suffix); err != nil {
+				return err
+			}
+		}
+		output.Printf("Moved database from %s to %s\n", from, to)
+
+		defaultPath, err := config.DefaultDBPath()
+		if err != nil {
+			return err
+		}
+		defaultPath, err = filepath.Abs(defaultPath)
+		if err != nil {
+			return err
+		}
+		switch source := cfg.Source("dbPath"); {
+		case source != "":
+			output.Noticef("Update dbPath in %s to %s\n", source, to)
+		case to != defaultPath:
+			output.Noticef("Set dbPath: %s in your config to keep using it\n", to)
+		}
+		return nil
+	},
+}
+
+// moveFile renames a file, copying it when it moves to another file system
+func moveFile(from string, to string) error {
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(from)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", from, err)
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", to, err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(to)
+		return fmt.Errorf("failed to copy %s: %w", from, err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(to)
+		return fmt.Errorf("failed to copy %s: %w", from, err)
+	}
+	return os.Remove(from)
+}
@@ -0,0 +1,75 @@
+package doc
+
+import (
+	"fmt"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/document"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+var (
+	allFlag    bool
+	revokeFlag bool
+)
+
+var approveCmd = &cobra.Command{
+	Use:   "approve [thread_id] [section_id...]",
+	Short: "Approve written sections so they are exported",
+	Long:  "Approve sections of a document. A section goes back to draft when the model rewrites it. E.g. slop doc approve 1a2b intro methods",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+		thread, outline, err := loadOutline(cmd.Context(), repo, args[0])
+		if err != nil {
+			return err
+		}
+
+		ids := args[1:]
+		if allFlag {
+			if len(ids) > 0 {
+				return fmt.Errorf("cannot combine --all with section IDs")
+			}
+			for _, section := range outline.Sections {
+				if section.Content != "" {
+					ids = append(ids, section.ID)
+				}
+			}
+		}
+		if len(ids) == 0 {
+			return fmt.Errorf("name the sections to approve or use --all")
+		}
+
+		status := document.StatusApproved
+		if revokeFlag {
+			status = document.StatusDraft
+		}
+		for _, id := range ids {
+			section := outline.Section(id)
+			if section == nil {
+				return fmt.Errorf("there is no section %q", id)
+			}
+			if section.Content == "" {
+				return fmt.Errorf("section %q has not been written yet", id)
+			}
+			section.Status = status
+		}
+		if err := document.Save(cmd.Context(), repo, thread.ID, outline); err != nil {
+			return err
+		}
+
+		fmt.Printf("Marked %d sections as %s\n", len(ids), status)
+		return nil
+	},
+}
+
+func init() {
+	approveCmd.Flags().BoolVar(&allFlag, "all", false, "Approve every written section")
+	approveCmd.Flags().BoolVar(&revokeFlag, "revoke", false, "Put the sections back to draft instead")
+	DocCmd.AddCommand(approveCmd)
+}
@@ -0,0 +1,66 @@
+package doc
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/document"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	outputFlag string
+	draftsFlag bool
+	forceFlag  bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [thread_id]",
+	Short: "Assemble the approved sections of a document into markdown",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+		_, outline, err := loadOutline(cmd.Context(), repo, args[0])
+		if err != nil {
+			return err
+		}
+
+		var missing int
+		for _, section := range outline.Sections {
+			if section.Status != document.StatusApproved && (section.Content == "" || !draftsFlag) {
+				missing++
+			}
+		}
+		if missing > 0 {
+			output.Noticef("%d of %d sections are left out because they are not approved\n", missing, len(outline.Sections))
+		}
+
+		content := outline.Assemble(draftsFlag)
+		if outputFlag == "" {
+			fmt.Print(content)
+			return nil
+		}
+		if _, err := os.Stat(outputFlag); err == nil && !forceFlag {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", outputFlag)
+		}
+		if err := os.WriteFile(outputFlag, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write document: %w", err)
+		}
+		fmt.Printf("Exported %s to %s\n", outline.Title, outputFlag)
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&outputFlag, "output", "o", "", "Write the document to this file instead of stdout")
+	exportCmd.Flags().BoolVar(&draftsFlag, "drafts", false, "Also include sections that are written but not approved")
+	exportCmd.Flags().BoolVarP(&forceFlag, "force", "f", false, "Overwrite the output file if it exists")
+	DocCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,35 @@
+package doc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/isaacphi/slop/internal/document"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
+	"github.com/spf13/cobra"
+)
+
+var DocCmd = &cobra.Command{
+	Use:   "doc",
+	Short: "Review and export documents written in document mode",
+	Long: `Documents are written over several turns with --mode doc. The model keeps an outline
+of sections and writes them one at a time. Review the sections with doc show, approve
+the ones you are happy with and assemble the approved sections with doc export.`,
+}
+
+// loadOutline finds a thread by a prefix of its ID and returns it with its outline
+func loadOutline(ctx context.Context, repo repository.MessageRepository, threadID string) (*domain.Thread, *document.Outline, error) {
+	thread, err := repo.GetThreadByPartialID(ctx, threadID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find thread: %w", err)
+	}
+	outline, err := document.Load(ctx, repo, thread.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if outline == nil {
+		return nil, nil, fmt.Errorf("thread %s has no document, start one with slop msg send --mode doc", thread.ID.String()[:8])
+	}
+	return thread, outline, nil
+}
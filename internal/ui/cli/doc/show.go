@@ -0,0 +1,53 @@
+package doc
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+var sectionFlag string
+
+var showCmd = &cobra.Command{
+	Use:   "show [thread_id]",
+	Short: "Show the outline of a document and the status of its sections",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+		_, outline, err := loadOutline(cmd.Context(), repo, args[0])
+		if err != nil {
+			return err
+		}
+
+		if sectionFlag != "" {
+			section := outline.Section(sectionFlag)
+			if section == nil {
+				return fmt.Errorf("there is no section %q", sectionFlag)
+			}
+			fmt.Printf("## %s (%s)\n\n%s\n", section.Title, section.Status, strings.TrimSpace(section.Content))
+			return nil
+		}
+
+		fmt.Println(outline.Title)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tStatus\tWords\tTitle")
+		for _, section := range outline.Sections {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", section.ID, section.Status, len(strings.Fields(section.Content)), section.Title)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	showCmd.Flags().StringVarP(&sectionFlag, "section", "s", "", "Print the content of this section")
+	DocCmd.AddCommand(showCmd)
+}
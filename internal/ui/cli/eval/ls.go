@@ -0,0 +1,59 @@
+package eval
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "ls [thread_id]",
+	Short: "List stored evaluations",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		var threadID *uuid.UUID
+		if len(args) > 0 {
+			thread, err := repo.GetThreadByPartialID(cmd.Context(), args[0])
+			if err != nil {
+				return fmt.Errorf("failed to find thread: %w", err)
+			}
+			threadID = &thread.ID
+		}
+
+		evaluations, err := repo.ListEvaluations(cmd.Context(), threadID)
+		if err != nil {
+			return fmt.Errorf("failed to list evaluations: %w", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Thread\tCreated\tRubric\tJudge\tScore")
+		for _, e := range evaluations {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%.0f%%\n",
+				e.ThreadID.String()[:8],
+				e.CreatedAt.Format(time.RFC822),
+				e.Rubric,
+				e.ModelName,
+				e.Score*100,
+			)
+		}
+		w.Flush()
+
+		return nil
+	},
+}
+
+func init() {
+	EvalCmd.AddCommand(listCmd)
+}
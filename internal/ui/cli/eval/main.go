@@ -0,0 +1,10 @@
+package eval
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var EvalCmd = &cobra.Command{
+	Use:   "eval",
+	Short: "Evaluate agent runs with the internal model",
+}
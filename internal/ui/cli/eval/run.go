@@ -0,0 +1,76 @@
+package eval
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/eval"
+	"github.com/isaacphi/slop/internal/internalService"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+var rubricFlag string
+
+var runCmd = &cobra.Command{
+	Use:   "run [thread_id]",
+	Short: "Score a completed agent run",
+	Long:  "Have the internal model score a thread against a rubric, and store the scores so they can be compared across prompt and config changes.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		rubric := eval.DefaultRubric()
+		if rubricFlag != "" {
+			rubric, err = eval.LoadRubric(rubricFlag)
+			if err != nil {
+				return err
+			}
+		}
+
+		thread, err := repo.GetThreadByPartialID(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to find thread: %w", err)
+		}
+
+		messages, err := repo.GetMessages(cmd.Context(), thread.ID, nil, false)
+		if err != nil {
+			return fmt.Errorf("failed to get thread messages: %w", err)
+		}
+
+		internalService, err := internal.NewForThread(cfg, thread)
+		if err != nil {
+			return fmt.Errorf("failed to initialize internal service: %w", err)
+		}
+
+		evaluation, scores, err := eval.Evaluate(cmd.Context(), internalService, thread.ID, messages, rubric)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate thread: %w", err)
+		}
+
+		if err := repo.AddEvaluation(cmd.Context(), evaluation); err != nil {
+			return fmt.Errorf("failed to save evaluation: %w", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Criterion\tScore\tReason")
+		for _, s := range scores {
+			fmt.Fprintf(w, "%s\t%d/%d\t%s\n", s.Criterion, s.Score, rubric.MaxScore, s.Reason)
+		}
+		w.Flush()
+		fmt.Printf("\nOverall: %.0f%%\n", evaluation.Score*100)
+
+		return nil
+	},
+}
+
+func init() {
+	runCmd.Flags().StringVar(&rubricFlag, "rubric", "", "Rubric file (yaml or json) with name, maxScore and criteria")
+	EvalCmd.AddCommand(runCmd)
+}
@@ -0,0 +1,19 @@
+package index
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	rebuildFlag bool
+	limitFlag   int
+)
+
+var IndexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Manage the index of project files used by the codebase server",
+	Long: `Manage the index of project files that the built-in codebase server searches. Add the
+codebase toolset to a preset and set codebase.enabled to offer its tools to the model.
+The index is kept up to date as the tools are used, so updating it by hand is only
+needed to build it ahead of time or after changing codebase.chunkLines.`,
+}
@@ -0,0 +1,52 @@
+package index
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/codebase"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search the index the way the search_files tool does",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+		index, err := codebase.New(repo, cfg.Codebase)
+		if err != nil {
+			return err
+		}
+
+		results, err := index.Search(cmd.Context(), strings.Join(args, " "), limitFlag)
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Println("No matching files, build the index with `slop index update`")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Score\tPath\tChunk\tLines\tPreview")
+		for _, r := range results {
+			fmt.Fprintf(w, "%.2f\t%s\t%d\t%d-%d\t%s\n", r.Score, r.Path, r.Chunk, r.StartLine, r.EndLine, r.Preview)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+func init() {
+	searchCmd.Flags().IntVarP(&limitFlag, "limit", "l", 10, "Maximum number of chunks to show")
+	IndexCmd.AddCommand(searchCmd)
+}
@@ -0,0 +1,47 @@
+package index
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/codebase"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show how much of the project is indexed",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+		index, err := codebase.New(repo, cfg.Codebase)
+		if err != nil {
+			return err
+		}
+
+		status, err := index.Status(cmd.Context())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Root: %s\n", status.Root)
+		fmt.Printf("Files: %d\n", status.Files)
+		fmt.Printf("Chunks: %d\n", status.Chunks)
+		if !status.LastIndexed.IsZero() {
+			fmt.Printf("Last indexed: %s\n", status.LastIndexed.Format(time.RFC822))
+		}
+		if !cfg.Codebase.Enabled {
+			fmt.Println("The codebase server is disabled, set codebase.enabled to offer it to the model")
+		}
+		return nil
+	},
+}
+
+func init() {
+	IndexCmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,40 @@
+package index
+
+import (
+	"fmt"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/codebase"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Index new and changed files",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+		index, err := codebase.New(repo, cfg.Codebase)
+		if err != nil {
+			return err
+		}
+
+		result, err := index.Update(cmd.Context(), rebuildFlag)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Indexed %s: %d added, %d updated, %d removed, %d unchanged\n",
+			index.Root(), result.Added, result.Updated, result.Removed, result.Unchanged)
+		return nil
+	},
+}
+
+func init() {
+	updateCmd.Flags().BoolVar(&rebuildFlag, "rebuild", false, "Index every file again, even if it didn't change")
+	IndexCmd.AddCommand(updateCmd)
+}
@@ -0,0 +1,10 @@
+package job
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var JobCmd = &cobra.Command{
+	Use:   "job",
+	Short: "Manage batches submitted to providers",
+}
@@ -0,0 +1,133 @@
+package job
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/batch"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	waitFlag     bool
+	intervalFlag time.Duration
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status [job]",
+	Short: "Show batch jobs and add finished replies to their threads",
+	Long: `Check on batches submitted with ` + "`slop queue flush --batch`" + `. Batches the provider has
+finished have their replies added to their threads.
+
+Give a job ID to show its messages. With --wait, keep checking until every batch is finished.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		for {
+			var jobs []domain.BatchJob
+			if len(args) > 0 {
+				job, err := repo.GetBatchJobByPartialID(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				jobs = []domain.BatchJob{*job}
+			} else {
+				jobs, err = repo.ListBatchJobs(ctx, false)
+				if err != nil {
+					return fmt.Errorf("failed to list batch jobs: %w", err)
+				}
+			}
+
+			pending := 0
+			for i := range jobs {
+				if err := batch.Refresh(ctx, repo, cfg.Presets, &jobs[i]); err != nil {
+					return fmt.Errorf("failed to check batch %s: %w", jobs[i].ID.String()[:8], err)
+				}
+				if jobs[i].Status == domain.BatchJobSubmitted {
+					pending++
+				}
+			}
+
+			if !waitFlag || pending == 0 {
+				return printJobs(jobs, len(args) > 0)
+			}
+
+			output.Noticef("%d batches still processing, checking again in %s\n", pending, intervalFlag)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(intervalFlag):
+			}
+		}
+	},
+}
+
+// printJobs prints a table of jobs, and the messages of each job if details is set
+func printJobs(jobs []domain.BatchJob, details bool) error {
+	if len(jobs) == 0 {
+		fmt.Println("No batch jobs")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Job\tPreset\tProvider\tSubmitted\tMessages\tStatus\tError")
+	for _, job := range jobs {
+		requests, err := job.GetRequests()
+		if err != nil {
+			return err
+		}
+		jobError := job.Error
+		if len(jobError) > 50 && !details {
+			jobError = jobError[:47] + "..."
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			job.ID.String()[:8],
+			job.Preset,
+			job.Provider,
+			job.CreatedAt.Format(time.RFC822),
+			len(requests),
+			job.Status,
+			jobError,
+		)
+	}
+	w.Flush()
+
+	if !details {
+		return nil
+	}
+	for _, job := range jobs {
+		requests, err := job.GetRequests()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\nProvider batch: %s\n", job.ExternalID)
+		if job.CompletedAt != nil {
+			fmt.Printf("Completed: %s\n", job.CompletedAt.Format(time.RFC822))
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Thread\tMessage")
+		for _, request := range requests {
+			fmt.Fprintf(w, "%s\t%s\n", request.ThreadID.String()[:8], request.MessageID.String()[:8])
+		}
+		w.Flush()
+	}
+	return nil
+}
+
+func init() {
+	statusCmd.Flags().BoolVarP(&waitFlag, "wait", "w", false, "Keep checking until every batch is finished")
+	statusCmd.Flags().DurationVar(&intervalFlag, "interval", time.Minute, "How often to check with --wait")
+	JobCmd.AddCommand(statusCmd)
+}
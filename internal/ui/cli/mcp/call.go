@@ -0,0 +1,200 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	argsFlag        string
+	interactiveFlag bool
+)
+
+var callCmd = &cobra.Command{
+	Use:   "call [server] [tool]",
+	Short: "Call an MCP tool directly",
+	Long:  "Call a tool on an MCP server without involving an LLM. Pass arguments as JSON with --args, or use --interactive to be prompted for each parameter.",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		serverName, toolName := args[0], args[1]
+
+		if argsFlag != "" && interactiveFlag {
+			return fmt.Errorf("cannot specify both --args and --interactive")
+		}
+
+		// Only start the server being called, built-in servers need none started
+		servers := make(map[string]config.MCPServer)
+		if server, ok := cfg.MCPServers[serverName]; ok {
+			servers[serverName] = server
+		} else if !mcp.IsBuiltin(serverName) {
+			return fmt.Errorf("server %s not found in configuration", serverName)
+		}
+
+		client := mcp.New(servers)
+		if err := client.Initialize(context.Background()); err != nil {
+			return fmt.Errorf("failed to initialize MCP client: %w", err)
+		}
+		defer client.Shutdown()
+
+		tool, ok := client.GetTools()[serverName][toolName]
+		if !ok {
+			return fmt.Errorf("tool %s not found in server %s", toolName, serverName)
+		}
+
+		toolArgs := make(map[string]any)
+		if argsFlag != "" {
+			if err := json.Unmarshal([]byte(argsFlag), &toolArgs); err != nil {
+				return fmt.Errorf("failed to parse --args: %w", err)
+			}
+		} else if interactiveFlag {
+			var err error
+			toolArgs, err = promptForArguments(tool)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, required := range tool.Parameters.Required {
+			if _, exists := toolArgs[required]; !exists {
+				return fmt.Errorf("missing required parameter: %s", required)
+			}
+		}
+
+		if output.Verbose() {
+			encoded, _ := json.Marshal(toolArgs)
+			output.Verbosef("[calling %s__%s with %s]\n", serverName, toolName, encoded)
+		}
+		start := time.Now()
+		result, err := client.CallTool(cmd.Context(), serverName, toolName, toolArgs)
+		if err != nil {
+			return fmt.Errorf("tool call failed: %w", err)
+		}
+		output.Verbosef("[finished after %s]\n", time.Since(start).Round(time.Millisecond))
+
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format result: %w", err)
+		}
+		fmt.Println(string(out))
+
+		return nil
+	},
+}
+
+// promptForArguments asks the user for a value for each parameter in the tool schema
+func promptForArguments(tool domain.Tool) (map[string]any, error) {
+	reader := bufio.NewReader(os.Stdin)
+	result := make(map[string]any)
+
+	required := make(map[string]bool)
+	for _, name := range tool.Parameters.Required {
+		required[name] = true
+	}
+
+	// Sort parameter names for a predictable prompt order, required first
+	names := make([]string, 0, len(tool.Parameters.Properties))
+	for name := range tool.Parameters.Properties {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if required[names[i]] != required[names[j]] {
+			return required[names[i]]
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		prop := tool.Parameters.Properties[name]
+
+		if prop.Description != "" {
+			output.Noticef("# %s\n", prop.Description)
+		}
+		label := fmt.Sprintf("%s (%s", name, prop.Type)
+		if required[name] {
+			label += ", required"
+		}
+		if len(prop.Enum) > 0 {
+			label += ", one of: " + strings.Join(prop.Enum, "|")
+		}
+		if prop.Default != nil {
+			label += fmt.Sprintf(", default: %v", prop.Default)
+		}
+		label += ")"
+
+		for {
+			output.Noticef("%s: ", label)
+			input, err := reader.ReadString('\n')
+			if err != nil {
+				return nil, fmt.Errorf("failed to read input: %w", err)
+			}
+			input = strings.TrimSpace(input)
+
+			if input == "" {
+				if required[name] {
+					output.Noticef("A value is required\n")
+					continue
+				}
+				break
+			}
+
+			value, err := parseValue(input, prop)
+			if err != nil {
+				output.Noticef("Invalid value: %v\n", err)
+				continue
+			}
+			result[name] = value
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// parseValue converts user input to the type expected by the property schema
+func parseValue(input string, prop domain.Property) (any, error) {
+	switch prop.Type {
+	case "number":
+		return strconv.ParseFloat(input, 64)
+	case "integer":
+		return strconv.ParseInt(input, 10, 64)
+	case "boolean":
+		return strconv.ParseBool(input)
+	case "array", "object":
+		var value any
+		if err := json.Unmarshal([]byte(input), &value); err != nil {
+			return nil, fmt.Errorf("expected JSON %s: %w", prop.Type, err)
+		}
+		return value, nil
+	default:
+		if len(prop.Enum) > 0 {
+			for _, enum := range prop.Enum {
+				if input == enum {
+					return input, nil
+				}
+			}
+			return nil, fmt.Errorf("must be one of: %v", prop.Enum)
+		}
+		return input, nil
+	}
+}
+
+func init() {
+	callCmd.Flags().StringVar(&argsFlag, "args", "", "Tool arguments as a JSON object")
+	callCmd.Flags().BoolVarP(&interactiveFlag, "interactive", "i", false, "Prompt for each parameter based on the tool schema")
+	MCPCmd.AddCommand(callCmd)
+}
@@ -0,0 +1,93 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/isaacphi/slop/internal/agent"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var costCmd = &cobra.Command{
+	Use:   "cost [preset]",
+	Short: "Show how many tokens tool schemas add to each request",
+	Long: `Estimate how many tokens the schemas of the tools available to each preset add to every
+request, as the servers describe them and after the slim settings of the preset's toolsets.
+
+Give a preset, or use --verbose, to see the cost of each tool.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+
+		names := make([]string, 0, len(cfg.Presets))
+		for name := range cfg.Presets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(args) > 0 {
+			if _, ok := cfg.Presets[args[0]]; !ok {
+				return fmt.Errorf("preset %s not found in configuration", args[0])
+			}
+			names = args
+		}
+
+		client := mcp.New(cfg.MCPServers)
+		if err := client.Initialize(cmd.Context()); err != nil {
+			return fmt.Errorf("failed to initialize MCP client: %w", err)
+		}
+		defer client.Shutdown()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Preset\tTools\tOriginal\tSlimmed\tSaved")
+		details := make(map[string][]agent.ToolCost)
+		for _, name := range names {
+			costs, err := agent.SchemaCost(client.GetTools(), cfg.Presets[name], cfg.Toolsets)
+			if err != nil {
+				return fmt.Errorf("failed to get tools for preset %s: %w", name, err)
+			}
+			details[name] = costs
+
+			var original, slimmed int
+			for _, cost := range costs {
+				original += cost.Original
+				slimmed += cost.Slimmed
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n", name, len(costs), original, slimmed, savedPercent(original, slimmed))
+		}
+		w.Flush()
+
+		if len(args) == 0 && !output.Verbose() {
+			return nil
+		}
+		for _, name := range names {
+			if len(details[name]) == 0 {
+				continue
+			}
+			fmt.Printf("\n%s:\n", name)
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "Server\tTool\tOriginal\tSlimmed\tSaved")
+			for _, cost := range details[name] {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", cost.Server, cost.Tool, cost.Original, cost.Slimmed, savedPercent(cost.Original, cost.Slimmed))
+			}
+			w.Flush()
+		}
+		return nil
+	},
+}
+
+// savedPercent formats how much smaller slimmed is than original
+func savedPercent(original, slimmed int) string {
+	if original == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", float64(original-slimmed)/float64(original)*100)
+}
+
+func init() {
+	MCPCmd.AddCommand(costCmd)
+}
@@ -3,9 +3,11 @@ package mcp
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/isaacphi/slop/internal/appState"
 	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
 	"github.com/spf13/cobra"
 )
 
@@ -25,6 +27,21 @@ var (
 			}
 			defer client.Shutdown()
 
+			// Quiet mode only lists tool names
+			if output.Quiet() {
+				var names []string
+				for serverName, tools := range client.GetTools() {
+					for toolName := range tools {
+						names = append(names, serverName+"__"+toolName)
+					}
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					fmt.Println(name)
+				}
+				return nil
+			}
+
 			client.PrintTools()
 
 			return nil
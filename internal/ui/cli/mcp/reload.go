@@ -0,0 +1,102 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+// reloadTimeout bounds how long a reload may take, the server waits for tool calls
+// running on the old server before stopping it
+const reloadTimeout = time.Minute
+
+var addressFlag string
+
+var reloadCmd = &cobra.Command{
+	Use:   "reload [server]",
+	Short: "Restart an MCP server in a running slop serve",
+	Long: `Ask a running slop serve to restart an MCP server with the current configuration and show how its tools changed.
+
+The new server is started before the old one is stopped and other servers keep running. Tool calls already running on the old server are given time to finish. In slop pipe, send {"type": "reload-mcp", "server": "<name>"} instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		serverName := args[0]
+
+		address := cfg.Serve.Address
+		if addressFlag != "" {
+			address = addressFlag
+		}
+		if !strings.Contains(address, "://") {
+			address = "http://" + address
+		}
+		endpoint := fmt.Sprintf("%s/api/mcp/%s/reload", strings.TrimSuffix(address, "/"), url.PathEscape(serverName))
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), reloadTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader([]byte("{}")))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if cfg.Serve.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+cfg.Serve.Token)
+		}
+
+		start := time.Now()
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to reach slop serve at %s, is it running? %w", address, err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			var apiErr struct {
+				Error string `json:"error"`
+			}
+			if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+				return fmt.Errorf("failed to reload server: %s", apiErr.Error)
+			}
+			return fmt.Errorf("failed to reload server: %s", resp.Status)
+		}
+
+		var result mcp.ReloadResult
+		if err := json.Unmarshal(body, &result); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		output.Printf("Reloaded %s in %s\n", serverName, time.Since(start).Round(time.Millisecond))
+		for _, change := range []struct {
+			label string
+			tools []string
+		}{{"added", result.Added}, {"removed", result.Removed}, {"changed", result.Changed}} {
+			for _, name := range change.tools {
+				if output.Quiet() {
+					fmt.Println(name)
+					continue
+				}
+				fmt.Printf("  %s %s\n", change.label, name)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	reloadCmd.Flags().StringVar(&addressFlag, "address", "", "Address of slop serve, defaults to serve.address")
+	MCPCmd.AddCommand(reloadCmd)
+}
@@ -0,0 +1,69 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+// maxErrorLength limits how much of the last error is shown in the table
+const maxErrorLength = 60
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [server]",
+	Short: "Show tool call statistics",
+	Long:  "Show success and failure counts, average latency and the last error for every tool the agent has called.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		stats, err := repo.ListToolStats(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to list tool stats: %w", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Server\tTool\tCalls\tFailed\tAvg Latency\tLast Error")
+		for _, stat := range stats {
+			if len(args) > 0 && stat.ServerName != args[0] {
+				continue
+			}
+
+			lastError := ""
+			if stat.LastErrorAt != nil {
+				lastError = strings.ReplaceAll(stat.LastError, "\n", " ")
+				if len(lastError) > maxErrorLength {
+					lastError = lastError[:maxErrorLength-3] + "..."
+				}
+				lastError = fmt.Sprintf("%s (%s)", lastError, stat.LastErrorAt.Format(time.RFC822))
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d (%.0f%%)\t%s\t%s\n",
+				stat.ServerName,
+				stat.ToolName,
+				stat.Calls(),
+				stat.Failures,
+				stat.FailureRate()*100,
+				stat.AverageLatency(),
+				lastError,
+			)
+		}
+		w.Flush()
+
+		return nil
+	},
+}
+
+func init() {
+	MCPCmd.AddCommand(statsCmd)
+}
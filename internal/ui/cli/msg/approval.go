@@ -0,0 +1,154 @@
+package msg
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/llm"
+	"github.com/isaacphi/slop/internal/toolview"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+)
+
+// maxListedCalls limits how many calls of each tool are listed before asking for approval
+const maxListedCalls = 5
+
+// callGroup is the pending calls of one tool
+type callGroup struct {
+	name  string
+	calls []llm.ToolCall
+}
+
+// groupCalls groups calls by tool in the order each tool was first called
+func groupCalls(calls []llm.ToolCall) []callGroup {
+	var groups []callGroup
+	index := make(map[string]int)
+	for _, call := range calls {
+		i, ok := index[call.Name]
+		if !ok {
+			i = len(groups)
+			index[call.Name] = i
+			groups = append(groups, callGroup{name: call.Name})
+		}
+		groups[i].calls = append(groups[i].calls, call)
+	}
+	return groups
+}
+
+// printPendingCalls lists the calls waiting for approval grouped by tool
+func printPendingCalls(calls []llm.ToolCall) {
+	output.Noticef("\n\nTool calls waiting for approval:\n")
+	for _, group := range groupCalls(calls) {
+		output.Noticef("  %s x%d\n", strings.Replace(group.name, "__", ".", 1), len(group.calls))
+		for i, call := range group.calls {
+			if i == maxListedCalls {
+				output.Noticef("    … and %d more\n", len(group.calls)-i)
+				break
+			}
+			output.Noticef("    %s\n", toolview.Pending(call))
+		}
+	}
+}
+
+// chooseCalls asks which calls of each tool to approve and returns the ones that
+// were rejected. A whole tool can be approved or rejected at once, or just the calls
+// with an argument starting with a prefix such as a directory
+func chooseCalls(reader *bufio.Reader, calls []llm.ToolCall) ([]llm.ToolCall, error) {
+	var rejected []llm.ToolCall
+	for _, group := range groupCalls(calls) {
+		name := strings.Replace(group.name, "__", ".", 1)
+		if len(group.calls) == 1 {
+			ok, err := askYes(reader, fmt.Sprintf("Approve %s? [y/N]: ", toolview.Pending(group.calls[0])))
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				rejected = append(rejected, group.calls[0])
+			}
+			continue
+		}
+
+		output.Noticef("%s, %d calls: [a]pprove all, [R]eject all, approve matching a [p]refix, decide [o]ne by one: ", name, len(group.calls))
+		answer, err := readAnswer(reader)
+		if err != nil {
+			return nil, err
+		}
+		switch answer {
+		case "a", "all":
+		case "p", "prefix":
+			output.Noticef("Approve calls with an argument starting with: ")
+			prefix, err := reader.ReadString('\n')
+			if err != nil {
+				return nil, fmt.Errorf("failed to read prefix: %w", err)
+			}
+			prefix = strings.TrimSpace(prefix)
+			approved := 0
+			for _, call := range group.calls {
+				if prefix != "" && hasArgumentPrefix(call, prefix) {
+					approved++
+				} else {
+					rejected = append(rejected, call)
+				}
+			}
+			output.Noticef("Approved %d of %d %s calls\n", approved, len(group.calls), name)
+		case "o", "one":
+			for _, call := range group.calls {
+				ok, err := askYes(reader, fmt.Sprintf("  Approve %s? [y/N]: ", toolview.Pending(call)))
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					rejected = append(rejected, call)
+				}
+			}
+		default:
+			rejected = append(rejected, group.calls...)
+		}
+	}
+	return rejected, nil
+}
+
+// hasArgumentPrefix reports whether any string argument of the call, including ones
+// nested in objects and lists, starts with prefix
+func hasArgumentPrefix(call llm.ToolCall, prefix string) bool {
+	var arguments any
+	if err := json.Unmarshal(call.Arguments, &arguments); err != nil {
+		return false
+	}
+	var match func(value any) bool
+	match = func(value any) bool {
+		switch v := value.(type) {
+		case string:
+			return strings.HasPrefix(v, prefix)
+		case map[string]any:
+			for _, item := range v {
+				if match(item) {
+					return true
+				}
+			}
+		case []any:
+			for _, item := range v {
+				if match(item) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return match(arguments)
+}
+
+func askYes(reader *bufio.Reader, question string) (bool, error) {
+	output.Noticef("%s", question)
+	answer, err := readAnswer(reader)
+	return answer == "y" || answer == "yes", err
+}
+
+func readAnswer(reader *bufio.Reader) (string, error) {
+	answer, err := reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	return strings.TrimSpace(strings.ToLower(answer)), nil
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/isaacphi/slop/internal/appState"
 	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
 	"github.com/spf13/cobra"
 )
 
@@ -41,7 +42,7 @@ var deleteCmd = &cobra.Command{
 			return fmt.Errorf("failed to delete messages: %w", err)
 		}
 
-		fmt.Println("Last message pair deleted successfully")
+		output.Println("Last message pair deleted successfully")
 		return nil
 	},
 }
@@ -0,0 +1,74 @@
+package msg
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"github.com/isaacphi/slop/internal/artifact"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+const (
+	// maxFileSize limits each file attached with --file
+	maxFileSize = 256 * 1024
+	// maxFilesSize limits all files attached to one message together
+	maxFilesSize = 1024 * 1024
+	// binarySniffSize is how much of a file is checked for NUL bytes
+	binarySniffSize = 8000
+)
+
+// readFiles reads the files attached with --file. Each file becomes a part of the
+// message headed by its name, and an attachment recording where the part came from
+func readFiles(paths []string) ([]domain.MessagePart, []domain.Attachment, error) {
+	var parts []domain.MessagePart
+	var attachments []domain.Attachment
+	total := 0
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+		if info.IsDir() {
+			return nil, nil, fmt.Errorf("cannot attach %s, it is a directory", path)
+		}
+		if info.Size() > maxFileSize {
+			return nil, nil, fmt.Errorf("cannot attach %s, it is %s and files can be at most %s",
+				path, artifact.FormatSize(int(info.Size())), artifact.FormatSize(maxFileSize))
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+		if isBinary(data) {
+			return nil, nil, fmt.Errorf("cannot attach %s, it is not a text file", path)
+		}
+		total += len(data)
+		if total > maxFilesSize {
+			return nil, nil, fmt.Errorf("cannot attach %s, the files add up to more than %s", path, artifact.FormatSize(maxFilesSize))
+		}
+
+		parts = append(parts, domain.MessagePart{
+			Source:  path,
+			Content: fmt.Sprintf("--- File: %s ---\n%s\n--- End of %s ---", path, bytes.TrimRight(data, "\n"), path),
+		})
+		attachments = append(attachments, domain.Attachment{
+			Name: path,
+			Size: len(data),
+			Hash: artifact.Hash(string(data)),
+		})
+	}
+	return parts, attachments, nil
+}
+
+// isBinary guesses whether data is not text the way git does, by looking for a NUL
+// byte near the start. Text that isn't valid UTF-8 is treated as binary too
+func isBinary(data []byte) bool {
+	sniff := data
+	if len(sniff) > binarySniffSize {
+		sniff = sniff[:binarySniffSize]
+	}
+	return bytes.IndexByte(sniff, 0) >= 0 || !utf8.Valid(data)
+}
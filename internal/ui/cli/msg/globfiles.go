@@ -0,0 +1,273 @@
+package msg
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/isaacphi/slop/internal/artifact"
+	"github.com/isaacphi/slop/internal/codebase"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/tokens"
+)
+
+const (
+	// defaultFilesBudget is how many tokens the files matched with --files may take up
+	// when the preset doesn't say how large its context window is
+	defaultFilesBudget = 16000
+	// chunkLines is how many lines of a file go into each chunk
+	chunkLines = 200
+)
+
+// Orders of the files matched with --files
+const (
+	filesByRecent    = "recent"
+	filesByRelevance = "relevance"
+)
+
+// fileChunk is a range of lines of a file matched with --files
+type fileChunk struct {
+	path    string
+	modTime time.Time
+	start   int // First line of the chunk, from 1
+	end     int // Last line of the chunk
+	content string
+	score   int // How many words of the message the chunk or its path contains
+	tokens  int
+}
+
+// globResult is what --files gathered
+type globResult struct {
+	parts       []domain.MessagePart
+	attachments []domain.Attachment
+	files       int // Files matching the patterns
+	left        int // Matching files left out in whole or in part to fit the budget
+	skipped     int // Matching files that aren't text
+	tokens      int // Tokens taken up by the parts
+}
+
+// filesBudget is how many tokens the files matched with --files may take up. Without
+// a budget set with --files-budget, they may fill half of the preset's context window
+// so there is room left for the conversation and the response
+func filesBudget(flag int, preset config.Preset) int {
+	switch {
+	case flag > 0:
+		return flag
+	case preset.ContextWindow > 0:
+		return preset.ContextWindow / 2
+	}
+	return defaultFilesBudget
+}
+
+// gatherFiles reads the files under the working directory matching the --files
+// patterns, which may use ** for any number of directories, and splits them into
+// chunks. The chunks that fit in budget tokens are picked, most recently modified or
+// most relevant to message first, and become parts of the message headed by their
+// path in the order of the files
+func gatherFiles(patterns []string, order string, message string, budget int, tokenizer tokens.Tokenizer) (globResult, error) {
+	if order != filesByRecent && order != filesByRelevance {
+		return globResult{}, fmt.Errorf("invalid --files-by %q, must be %s or %s", order, filesByRecent, filesByRelevance)
+	}
+
+	paths, err := codebase.ListFiles(".", nil)
+	if err != nil {
+		return globResult{}, fmt.Errorf("failed to list files: %w", err)
+	}
+	var matched []string
+	for _, p := range paths {
+		for _, pattern := range patterns {
+			if matchGlob(strings.TrimPrefix(filepath.ToSlash(pattern), "./"), p) {
+				matched = append(matched, p)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return globResult{}, fmt.Errorf("no files match %s", strings.Join(patterns, ", "))
+	}
+
+	result := globResult{files: len(matched)}
+	words := keywords(message)
+	var chunks []fileChunk
+	for _, p := range matched {
+		info, err := os.Stat(p)
+		if err != nil {
+			return globResult{}, fmt.Errorf("failed to read file %s: %w", p, err)
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return globResult{}, fmt.Errorf("failed to read file %s: %w", p, err)
+		}
+		if isBinary(data) {
+			result.skipped++
+			continue
+		}
+		for _, chunk := range splitFile(p, string(data)) {
+			chunk.modTime = info.ModTime()
+			chunk.score = relevance(words, p, chunk.content)
+			chunk.tokens = tokenizer.Count(chunk.content) + tokenizer.Count(chunkHeader(chunk, 0))
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	sort.SliceStable(chunks, func(i, j int) bool {
+		a, b := chunks[i], chunks[j]
+		if order == filesByRelevance && a.score != b.score {
+			return a.score > b.score
+		}
+		if !a.modTime.Equal(b.modTime) {
+			return a.modTime.After(b.modTime)
+		}
+		if a.path != b.path {
+			return a.path < b.path
+		}
+		return a.start < b.start
+	})
+
+	// Smaller chunks further down may still fit once a larger one doesn't
+	lines := make(map[string]int)
+	var picked []fileChunk
+	left := make(map[string]bool)
+	for _, chunk := range chunks {
+		lines[chunk.path] = max(lines[chunk.path], chunk.end)
+		if result.tokens+chunk.tokens > budget {
+			left[chunk.path] = true
+			continue
+		}
+		result.tokens += chunk.tokens
+		picked = append(picked, chunk)
+	}
+	result.left = len(left)
+
+	// Read the picked chunks in the order of the files
+	sort.Slice(picked, func(i, j int) bool {
+		if picked[i].path != picked[j].path {
+			return picked[i].path < picked[j].path
+		}
+		return picked[i].start < picked[j].start
+	})
+	sizes := make(map[string]int)
+	var files []string
+	contents := make(map[string]*strings.Builder)
+	for _, chunk := range picked {
+		total := 0
+		if left[chunk.path] {
+			total = lines[chunk.path]
+		}
+		result.parts = append(result.parts, domain.MessagePart{
+			Source:  chunk.path,
+			Content: fmt.Sprintf("%s\n%s\n--- End of %s ---", chunkHeader(chunk, total), strings.TrimRight(chunk.content, "\n"), chunk.path),
+		})
+		if contents[chunk.path] == nil {
+			contents[chunk.path] = &strings.Builder{}
+			files = append(files, chunk.path)
+		}
+		contents[chunk.path].WriteString(chunk.content)
+		sizes[chunk.path] += len(chunk.content)
+	}
+	for _, p := range files {
+		result.attachments = append(result.attachments, domain.Attachment{
+			Name: p,
+			Size: sizes[p],
+			Hash: artifact.Hash(contents[p].String()),
+		})
+	}
+	return result, nil
+}
+
+// splitFile splits the content of a file into chunks of chunkLines lines
+func splitFile(p string, content string) []fileChunk {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return []fileChunk{{path: p, start: 1, end: 1}}
+	}
+	var chunks []fileChunk
+	for start := 0; start < len(lines); start += chunkLines {
+		end := min(start+chunkLines, len(lines))
+		chunks = append(chunks, fileChunk{
+			path:    p,
+			start:   start + 1,
+			end:     end,
+			content: strings.Join(lines[start:end], ""),
+		})
+	}
+	return chunks
+}
+
+// chunkHeader heads a chunk with its path, and with its lines out of total when only
+// part of the file is sent. total is 0 when the whole file is sent
+func chunkHeader(chunk fileChunk, total int) string {
+	if total == 0 {
+		return fmt.Sprintf("--- File: %s ---", chunk.path)
+	}
+	return fmt.Sprintf("--- File: %s (lines %d-%d of %d) ---", chunk.path, chunk.start, chunk.end, total)
+}
+
+// keywords are the distinct words of a message worth looking for in files
+func keywords(message string) []string {
+	seen := make(map[string]bool)
+	var words []string
+	fields := strings.FieldsFunc(strings.ToLower(message), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	for _, word := range fields {
+		if len([]rune(word)) < 3 || seen[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	return words
+}
+
+// relevance counts the keywords found in a chunk. Keywords in the path count twice
+func relevance(words []string, p string, content string) int {
+	p = strings.ToLower(p)
+	content = strings.ToLower(content)
+	score := 0
+	for _, word := range words {
+		if strings.Contains(p, word) {
+			score += 2
+		}
+		if strings.Contains(content, word) {
+			score++
+		}
+	}
+	return score
+}
+
+// matchGlob reports whether a slash separated path matches pattern. A ** element
+// matches any number of directories, other elements match like path.Match
+func matchGlob(pattern string, p string) bool {
+	return matchElements(strings.Split(pattern, "/"), strings.Split(p, "/"))
+}
+
+func matchElements(pattern []string, elements []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(elements); i++ {
+				if matchElements(pattern[1:], elements[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elements) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], elements[0]); !ok {
+			return false
+		}
+		pattern, elements = pattern[1:], elements[1:]
+	}
+	return len(elements) == 0
+}
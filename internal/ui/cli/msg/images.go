@@ -0,0 +1,116 @@
+package msg
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/artifact"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
+)
+
+// maxImageSize limits each image attached with --image, providers refuse larger ones
+const maxImageSize = 20 * 1024 * 1024
+
+// image is an image attached with --image. Local images are stored as artifacts once
+// the thread of the message is known
+type image struct {
+	part domain.MessagePart
+	data []byte // Contents of a local image, nil for images linked by URL
+	name string
+}
+
+// readImages reads the images attached with --image, which are paths of local files or
+// http(s) URLs the provider fetches itself
+func readImages(sources []string) ([]image, error) {
+	var images []image
+	for _, source := range sources {
+		if u, err := url.Parse(source); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+			images = append(images, image{
+				name: source,
+				part: domain.MessagePart{
+					Content:  fmt.Sprintf("Image %s", source),
+					URL:      source,
+					MimeType: imageType(path.Ext(u.Path), nil),
+				},
+			})
+			continue
+		}
+
+		info, err := os.Stat(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image %s: %w", source, err)
+		}
+		if info.Size() > maxImageSize {
+			return nil, fmt.Errorf("cannot attach %s, it is %s and images can be at most %s",
+				source, artifact.FormatSize(int(info.Size())), artifact.FormatSize(maxImageSize))
+		}
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image %s: %w", source, err)
+		}
+		mimeType := imageType(filepath.Ext(source), data)
+		if !strings.HasPrefix(mimeType, "image/") {
+			return nil, fmt.Errorf("cannot attach %s, it is %s and not an image", source, mimeType)
+		}
+
+		hash := artifact.Hash(string(data))
+		images = append(images, image{
+			name: source,
+			data: data,
+			part: domain.MessagePart{
+				Source:   source,
+				Content:  fmt.Sprintf("Image %s", artifact.Reference(hash, filepath.Base(source), len(data))),
+				Artifact: hash,
+				MimeType: mimeType,
+			},
+		})
+	}
+	return images, nil
+}
+
+// imageType finds the mime type of an image from its extension, or from its data when
+// the extension is unknown. Images linked by URL without a known extension are
+// assumed to be images of any type
+func imageType(ext string, data []byte) string {
+	if mimeType := mime.TypeByExtension(ext); mimeType != "" {
+		mimeType, _, _ = strings.Cut(mimeType, ";")
+		return mimeType
+	}
+	if data == nil {
+		return "image/*"
+	}
+	mimeType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	return mimeType
+}
+
+// imageAttachments records the images as attachments of their message
+func imageAttachments(images []image) []domain.Attachment {
+	attachments := make([]domain.Attachment, len(images))
+	for i, img := range images {
+		attachments[i] = domain.Attachment{Name: img.name, Size: len(img.data), Hash: img.part.Artifact}
+	}
+	return attachments
+}
+
+// storeImages saves local images as artifacts of the thread so they can be sent with
+// later requests
+func storeImages(ctx context.Context, repo repository.MessageRepository, threadID uuid.UUID, images []image) error {
+	for _, img := range images {
+		if img.data == nil {
+			continue
+		}
+		if _, err := artifact.Store(ctx, repo, threadID, nil, filepath.Base(img.name), string(img.data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
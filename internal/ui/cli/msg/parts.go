@@ -0,0 +1,86 @@
+package msg
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// collectParts builds the parts of a multi-part message from the message arguments,
+// --part flags and piped stdin, in that order. A --part value that names a file is
+// replaced with the file's contents. Piped stdin is split into one part per block
+// when a separator is given. It returns nil when no parts were requested
+func collectParts(args []string, partValues []string, separator string) ([]domain.MessagePart, error) {
+	if len(partValues) == 0 && separator == "" {
+		return nil, nil
+	}
+
+	var parts []domain.MessagePart
+	if len(args) > 0 {
+		parts = append(parts, domain.MessagePart{Content: strings.Join(args, " ")})
+	}
+
+	for _, value := range partValues {
+		info, err := os.Stat(value)
+		if err != nil || info.IsDir() {
+			parts = append(parts, domain.MessagePart{Content: value})
+			continue
+		}
+		data, err := os.ReadFile(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read part %s: %w", value, err)
+		}
+		parts = append(parts, domain.MessagePart{Source: value, Content: strings.TrimSpace(string(data))})
+	}
+
+	stat, _ := os.Stdin.Stat()
+	if (stat.Mode() & os.ModeCharDevice) == 0 {
+		bytes, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read piped input: %w", err)
+		}
+		for _, block := range splitBlocks(string(bytes), separator) {
+			parts = append(parts, domain.MessagePart{Content: block})
+		}
+	}
+
+	// Drop empty parts so stray separators don't produce blank turns
+	nonEmpty := parts[:0]
+	for _, part := range parts {
+		if strings.TrimSpace(part.Content) != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return nonEmpty, nil
+}
+
+// splitBlocks splits input on lines that consist only of separator
+func splitBlocks(input string, separator string) []string {
+	if separator == "" {
+		return []string{strings.TrimSpace(input)}
+	}
+
+	var blocks []string
+	var current []string
+	for _, line := range strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == separator {
+			blocks = append(blocks, strings.TrimSpace(strings.Join(current, "\n")))
+			current = nil
+			continue
+		}
+		current = append(current, line)
+	}
+	return append(blocks, strings.TrimSpace(strings.Join(current, "\n")))
+}
+
+// joinParts returns the content of a message composed from parts
+func joinParts(parts []domain.MessagePart) string {
+	contents := make([]string, len(parts))
+	for i, part := range parts {
+		contents[i] = part.Content
+	}
+	return strings.Join(contents, domain.PartSeparator)
+}
@@ -0,0 +1,64 @@
+package msg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/agent"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/queue"
+	"github.com/isaacphi/slop/internal/repository"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+)
+
+// sendOrQueue sends a message, queueing human messages instead of failing when the
+// provider can't be reached. Messages sent to a thread that still has queued messages
+// are queued behind them so the thread stays in order.
+func sendOrQueue(ctx context.Context, repo repository.MessageRepository, agentService *agent.Agent, msg *domain.Message, presetName string) error {
+	if msg.Role != domain.RoleHuman {
+		return sendMessage(ctx, agentService, msg)
+	}
+
+	// Try to send anything already waiting in this thread first
+	result, err := queue.Flush(ctx, repo, &msg.ThreadID, true, func(ctx context.Context, entry domain.QueuedMessage, queued *domain.Message) error {
+		output.Printf("Sending queued message %s\n", queued.ID.String()[:8])
+		return sendMessage(ctx, agentService, queued)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to flush queued messages: %w", err)
+	}
+	for _, err := range result.Errors {
+		output.Noticef("Queued message failed: %v\n", err)
+	}
+	if result.Remaining > 0 {
+		if msg.ID == uuid.Nil {
+			if err := repo.AddMessageToThread(ctx, msg.ThreadID, msg); err != nil {
+				return fmt.Errorf("failed to add message to thread: %w", err)
+			}
+		}
+		if err := queue.Enqueue(ctx, repo, msg, presetName, fmt.Errorf("earlier messages in this thread are still queued")); err != nil {
+			return err
+		}
+		output.Noticef("Provider unreachable, message %s queued. Run `slop queue flush` to retry\n", msg.ID.String()[:8])
+		return nil
+	}
+
+	sendErr := sendMessage(ctx, agentService, msg)
+	if sendErr == nil || msg.ID == uuid.Nil || !queue.IsOfflineError(sendErr) {
+		return sendErr
+	}
+
+	// Only queue the message if nothing was generated for it yet, otherwise
+	// resending it would create a duplicate branch
+	branch, err := repo.GetMessages(ctx, msg.ThreadID, &msg.ID, true)
+	if err != nil || len(branch) == 0 || branch[len(branch)-1].ID != msg.ID {
+		return sendErr
+	}
+
+	if err := queue.Enqueue(ctx, repo, msg, presetName, sendErr); err != nil {
+		return err
+	}
+	output.Noticef("Provider unreachable, message %s queued. Run `slop queue flush` to retry\n", msg.ID.String()[:8])
+	return nil
+}
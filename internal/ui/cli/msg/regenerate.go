@@ -0,0 +1,121 @@
+package msg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/agent"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	regenerateThreadFlag  string
+	regenerateMessageFlag string
+	regenerateModelFlag   string
+	regenerateNoDiffFlag  bool
+)
+
+var regenerateCmd = &cobra.Command{
+	Use:   "regenerate",
+	Short: "Answer the message before a response again as a new branch",
+	Long: `Send the human message that a response answers again and stream the new answer. The new answer is a
+sibling branch of the response, which is kept, and the thread switches to it. Use --model to answer with a
+different preset.
+
+The response is the last one of the thread unless --message names another. The thread is the most recent
+one unless --thread names another.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg := appState.Get().Config
+
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("failed to initialize repository: %w", err)
+		}
+
+		var thread *domain.Thread
+		if regenerateThreadFlag != "" {
+			thread, err = repo.GetThreadByPartialID(ctx, regenerateThreadFlag)
+		} else {
+			thread, err = repo.GetMostRecentThread(ctx)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to find thread: %w", err)
+		}
+
+		var previous domain.Message
+		if regenerateMessageFlag != "" {
+			msg, err := repo.FindMessageByPartialID(ctx, thread.ID, regenerateMessageFlag)
+			if err != nil {
+				return fmt.Errorf("failed to find message: %w", err)
+			}
+			if msg.Role != domain.RoleAssistant {
+				return fmt.Errorf("message %s is not a response, only responses can be regenerated", msg.ID.String()[:8])
+			}
+			previous = *msg
+		} else {
+			messages, err := repo.GetMessages(ctx, thread.ID, nil, false)
+			if err != nil {
+				return fmt.Errorf("failed to get thread messages: %w", err)
+			}
+			found := false
+			for i := len(messages) - 1; i >= 0 && !found; i-- {
+				if messages[i].Role == domain.RoleAssistant {
+					previous, found = messages[i], true
+				}
+			}
+			if !found {
+				return fmt.Errorf("thread %s has no response to regenerate", thread.ID.String()[:8])
+			}
+		}
+
+		scoped, err := appState.Get().With(appState.Scope{Preset: regenerateModelFlag})
+		if err != nil {
+			return err
+		}
+		presetName, preset, err := scoped.Preset()
+		if err != nil {
+			return err
+		}
+
+		mcpClient := mcp.New(cfg.MCPServers)
+		if err := mcpClient.Start(context.Background()); err != nil {
+			return fmt.Errorf("failed to initialize MCP client: %w", err)
+		}
+		defer mcpClient.Shutdown()
+		if reason := mcpClient.SafeMode(); reason != "" {
+			output.Noticef("Safe mode: %s\n", reason)
+		}
+
+		agentService, err := agent.New(repo, mcpClient, preset, cfg.Toolsets, cfg.Prompts, cfg.Style)
+		if err != nil {
+			return fmt.Errorf("could not initialize MCP agent: %w", err)
+		}
+		if disabled := agentService.DisabledToolsets(); len(disabled) > 0 {
+			output.Noticef("Toolsets disabled in safe mode: %s\n", strings.Join(disabled, ", "))
+		}
+
+		if err := regenerateResponse(ctx, repo, agentService, previous, presetName, !regenerateNoDiffFlag); err != nil {
+			return err
+		}
+		if err := repo.MarkThreadRead(ctx, thread.ID); err != nil {
+			return fmt.Errorf("failed to mark thread as read: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	regenerateCmd.Flags().StringVarP(&regenerateThreadFlag, "thread", "t", "", "Thread of the response, defaults to the most recent thread")
+	regenerateCmd.Flags().StringVar(&regenerateMessageFlag, "message", "", "Response to regenerate, defaults to the last response of the thread")
+	regenerateCmd.Flags().StringVarP(&regenerateModelFlag, "model", "m", "", "Answer with this preset instead of the default one")
+	regenerateCmd.Flags().BoolVar(&regenerateNoDiffFlag, "no-diff", false, "Don't show what changed from the response")
+	MsgCmd.AddCommand(regenerateCmd)
+}
@@ -0,0 +1,107 @@
+package msg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/agent"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
+	"github.com/isaacphi/slop/internal/textdiff"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+)
+
+// retryResponse answers the human message of the last response in a thread again and
+// shows what changed from the response it replaces. The old response is kept as a
+// branch and the thread switches to the new one
+func retryResponse(ctx context.Context, repo repository.MessageRepository, agentService *agent.Agent, threadID uuid.UUID, presetName string, showDiff bool) error {
+	messages, err := repo.GetMessages(ctx, threadID, nil, false)
+	if err != nil {
+		return fmt.Errorf("failed to get thread messages: %w", err)
+	}
+	if len(messages) == 0 {
+		return fmt.Errorf("thread %s has no response to retry", threadID.String()[:8])
+	}
+	previous := messages[len(messages)-1]
+	if previous.Role != domain.RoleAssistant || previous.ToolCalls != "" {
+		return fmt.Errorf("the last message of thread %s is not a finished response, there is nothing to retry", threadID.String()[:8])
+	}
+	return regenerateResponse(ctx, repo, agentService, previous, presetName, showDiff)
+}
+
+// regenerateResponse answers the human message that started the turn of an assistant
+// message again. The new response becomes a sibling branch of the old one, which is
+// kept, and the thread switches to it. With showDiff a finished response is compared
+// to the new one
+func regenerateResponse(ctx context.Context, repo repository.MessageRepository, agentService *agent.Agent, previous domain.Message, presetName string, showDiff bool) error {
+	threadID := previous.ThreadID
+	history, err := repo.GetMessages(ctx, threadID, &previous.ID, false)
+	if err != nil {
+		return fmt.Errorf("failed to get thread messages: %w", err)
+	}
+
+	// The turn is answered again from its start, including any tool calls
+	var human *domain.Message
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == domain.RoleHuman {
+			human = &history[i]
+			break
+		}
+	}
+	if human == nil {
+		return fmt.Errorf("thread %s has no message to answer again", threadID.String()[:8])
+	}
+
+	if err := repo.SetActiveMessage(ctx, threadID, nil); err != nil {
+		return fmt.Errorf("failed to switch to the new response: %w", err)
+	}
+	if err := sendOrQueue(ctx, repo, agentService, human, presetName); err != nil {
+		return timeoutHint(err, threadID)
+	}
+	if !showDiff || previous.ToolCalls != "" {
+		return nil
+	}
+
+	messages, err := repo.GetMessages(ctx, threadID, nil, false)
+	if err != nil {
+		return fmt.Errorf("failed to get thread messages: %w", err)
+	}
+	latest := messages[len(messages)-1]
+	if latest.ID == previous.ID || latest.Role != domain.RoleAssistant || latest.ToolCalls != "" {
+		// Nothing new was answered, such as when the message was queued
+		return nil
+	}
+	printAnswerDiff(previous, latest)
+	return nil
+}
+
+// printAnswerDiff shows the words a new response removed and added compared to the
+// response it replaced
+func printAnswerDiff(previous, latest domain.Message) {
+	ops := textdiff.Words(previous.Content, latest.Content)
+	if !textdiff.Changed(ops) {
+		output.Printf("\nSame as the previous response %s\n", previous.ID.String()[:8])
+		return
+	}
+
+	removed, added := textdiff.Stats(ops)
+	deleted := lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Strikethrough(true)
+	inserted := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	var b strings.Builder
+	for _, op := range ops {
+		// The markers keep the changes readable when colors are off
+		switch op.Kind {
+		case textdiff.Delete:
+			b.WriteString(deleted.Render("[-" + op.Text + "-]"))
+		case textdiff.Insert:
+			b.WriteString(inserted.Render("{+" + op.Text + "+}"))
+		default:
+			b.WriteString(op.Text)
+		}
+	}
+	output.Printf("\nChanges from the previous response %s (%d words removed, %d added):\n%s\n",
+		previous.ID.String()[:8], removed, added, b.String())
+}
@@ -3,25 +3,35 @@ package msg
 import (
 	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/isaacphi/slop/internal/agent"
 	"github.com/isaacphi/slop/internal/appState"
 	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/errkind"
 	"github.com/isaacphi/slop/internal/events"
 	"github.com/isaacphi/slop/internal/llm"
 	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/privacy"
+	"github.com/isaacphi/slop/internal/queue"
 	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/tokens"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/isaacphi/slop/internal/usage"
 	"github.com/spf13/cobra"
 )
 
 var (
 	continueFlag    bool
 	modelFlag       string
+	onceModelFlag   string
 	threadFlag      string
 	parentFlag      string
 	noStreamFlag    bool
@@ -29,6 +39,28 @@ var (
 	temperatureFlag float64
 	approveFlag     bool
 	rejectFlag      bool
+	partFlag        []string
+	fileFlag        []string
+	filesFlag       []string
+	filesByFlag     string
+	filesBudgetFlag int
+	retryFlag       bool
+	noDiffFlag      bool
+	imageFlag       []string
+	separatorFlag   string
+	toolChoiceFlag  string
+	timeoutFlag     time.Duration
+	modeFlag        string
+	noCacheFlag     bool
+	privacyFlag     string
+
+	// Tools offered for this message only
+	enableToolsFlag  []string
+	disableToolsFlag []string
+
+	// Message composed from a template of the config
+	templateFlag string
+	varFlag      []string
 )
 
 var sendCmd = &cobra.Command{
@@ -47,19 +79,29 @@ var sendCmd = &cobra.Command{
 
 		// Initialize MCP client
 		mcpClient := mcp.New(cfg.MCPServers)
-		if err := mcpClient.Initialize(context.Background()); err != nil {
+		if err := mcpClient.Start(context.Background()); err != nil {
 			return fmt.Errorf("failed to initialize MCP client: %w", err)
 		}
 		defer mcpClient.Shutdown()
+		if reason := mcpClient.SafeMode(); reason != "" {
+			output.Noticef("Safe mode: %s\n", reason)
+		}
 
-		// Get model configuration
-		preset := cfg.Presets[cfg.DefaultPreset]
-		if modelFlag != "" {
-			var ok bool
-			preset, ok = cfg.Presets[modelFlag]
-			if !ok {
-				return fmt.Errorf("model %s not found in configuration", modelFlag)
-			}
+		// Get model configuration, the mode's overrides apply to the chosen preset
+		if modelFlag != "" && onceModelFlag != "" {
+			return fmt.Errorf("cannot specify --model and --once-model")
+		}
+		presetFlag := modelFlag
+		if onceModelFlag != "" {
+			presetFlag = onceModelFlag
+		}
+		scoped, err := appState.Get().With(appState.Scope{Preset: presetFlag, Mode: modeFlag})
+		if err != nil {
+			return err
+		}
+		presetName, preset, err := scoped.Preset()
+		if err != nil {
+			return err
 		}
 		if maxTokensFlag > 0 {
 			preset.MaxTokens = maxTokensFlag
@@ -67,12 +109,30 @@ var sendCmd = &cobra.Command{
 		if temperatureFlag > 0 {
 			preset.Temperature = temperatureFlag
 		}
+		if toolChoiceFlag != "" {
+			preset.ToolChoice = toolChoiceFlag
+		}
+		if timeoutFlag > 0 {
+			preset.RequestTimeout = timeoutFlag.String()
+		}
+		if noCacheFlag {
+			preset.CacheTTL = ""
+		}
 
 		// Initialize Agent
-		agentService, err := agent.New(repo, mcpClient, preset, cfg.Toolsets, cfg.Prompts)
+		agentService, err := agent.New(repo, mcpClient, preset, cfg.Toolsets, cfg.Prompts, cfg.Style)
 		if err != nil {
 			return fmt.Errorf("could not initialize MCP agent: %w", err)
 		}
+		if disabled := agentService.DisabledToolsets(); len(disabled) > 0 {
+			output.Noticef("Toolsets disabled in safe mode: %s\n", strings.Join(disabled, ", "))
+		}
+		if err := agentService.OverrideTools(enableToolsFlag, disableToolsFlag); err != nil {
+			return fmt.Errorf("failed to override tools: %w", err)
+		}
+		if onceModelFlag != "" {
+			agentService.ReplyOnce(presetName)
+		}
 
 		// Check for conflicting flags
 		if continueFlag && threadFlag != "" {
@@ -83,9 +143,61 @@ var sendCmd = &cobra.Command{
 			return fmt.Errorf("cannot specify both --approve and --reject")
 		}
 
+		if privacyFlag != "" {
+			if continueFlag || threadFlag != "" {
+				return fmt.Errorf("--privacy only applies to new threads, use slop thread privacy for existing ones")
+			}
+			if !privacy.Valid(privacyFlag) {
+				return fmt.Errorf("invalid privacy level %q, must be one of %s", privacyFlag, strings.Join(privacy.Levels, ", "))
+			}
+		}
+
+		// Answer the last message again instead of sending a new one
+		if retryFlag {
+			if len(args) > 0 || len(partFlag) > 0 || templateFlag != "" || len(fileFlag) > 0 || len(filesFlag) > 0 || len(imageFlag) > 0 {
+				return fmt.Errorf("cannot combine --retry with a message")
+			}
+			if parentFlag != "" || approveFlag || rejectFlag {
+				return fmt.Errorf("cannot combine --retry with --parent, --approve or --reject")
+			}
+			var thread *domain.Thread
+			switch {
+			case continueFlag:
+				thread, err = repo.GetMostRecentThread(ctx)
+			case threadFlag != "":
+				thread, err = repo.GetThreadByPartialID(ctx, threadFlag)
+			default:
+				return fmt.Errorf("--retry needs --thread or --continue")
+			}
+			if err != nil {
+				return err
+			}
+			if err := retryResponse(ctx, repo, agentService, thread.ID, presetName, !noDiffFlag); err != nil {
+				return err
+			}
+			if err := repo.MarkThreadRead(ctx, thread.ID); err != nil {
+				return fmt.Errorf("failed to mark thread as read: %w", err)
+			}
+			return nil
+		}
+
 		// Get the message content
 		var messageContent string
-		if len(args) > 0 {
+		parts, err := collectParts(args, partFlag, separatorFlag)
+		if err != nil {
+			return err
+		}
+		if templateFlag != "" {
+			if len(args) > 0 || len(parts) > 0 {
+				return fmt.Errorf("cannot combine --template with a message or --part")
+			}
+			messageContent, err = renderTemplate(cfg.Templates, templateFlag, varFlag)
+			if err != nil {
+				return err
+			}
+		} else if len(parts) > 0 {
+			messageContent = joinParts(parts)
+		} else if len(args) > 0 {
 			messageContent = strings.Join(args, " ")
 		} else {
 			// Check for piped input
@@ -99,6 +211,54 @@ var sendCmd = &cobra.Command{
 			}
 		}
 
+		// Files and images follow the rest of the message as parts of their own
+		var attachments []domain.Attachment
+		var images []image
+		if len(fileFlag) > 0 || len(filesFlag) > 0 || len(imageFlag) > 0 {
+			if approveFlag || rejectFlag {
+				return fmt.Errorf("cannot attach files or images with --approve or --reject")
+			}
+			if len(imageFlag) > 0 && !preset.Vision {
+				return fmt.Errorf("preset %s does not accept images, set vision: true on it if its model does", presetName)
+			}
+			fileParts, fileAttachments, err := readFiles(fileFlag)
+			if err != nil {
+				return err
+			}
+			var matched globResult
+			if len(filesFlag) > 0 {
+				budget := filesBudget(filesBudgetFlag, preset)
+				tokenizer := tokens.For(preset.Tokenizer, preset.Provider, preset.Name)
+				matched, err = gatherFiles(filesFlag, filesByFlag, messageContent, budget, tokenizer)
+				if err != nil {
+					return err
+				}
+				output.Verbosef("Attached %d of %d files matching --files, about %d tokens\n",
+					len(matched.attachments), matched.files, matched.tokens)
+				if matched.left > 0 {
+					output.Noticef("Left out all or part of %d files matching --files to fit in %d tokens, set a larger --files-budget to send more\n",
+						matched.left, budget)
+				}
+				if matched.skipped > 0 {
+					output.Noticef("Skipped %d files matching --files that are not text\n", matched.skipped)
+				}
+			}
+			images, err = readImages(imageFlag)
+			if err != nil {
+				return err
+			}
+			if len(parts) == 0 && messageContent != "" {
+				parts = []domain.MessagePart{{Content: messageContent}}
+			}
+			parts = append(parts, fileParts...)
+			parts = append(parts, matched.parts...)
+			for _, img := range images {
+				parts = append(parts, img.part)
+			}
+			attachments = append(append(fileAttachments, matched.attachments...), imageAttachments(images)...)
+			messageContent = joinParts(parts)
+		}
+
 		// Get thread ID
 		var threadID uuid.UUID
 		var msg *domain.Message
@@ -140,6 +300,7 @@ var sendCmd = &cobra.Command{
 						Role:     domain.RoleHuman,
 						Content:  fmt.Sprintf("Tool call rejected: %s", messageContent),
 					}
+					agentService.RecordRejection(ctx, parentMsg, messageContent)
 				} else {
 					return fmt.Errorf("parent message has pending tool calls; must use --approve or --reject")
 				}
@@ -190,6 +351,7 @@ var sendCmd = &cobra.Command{
 								Role:     domain.RoleHuman,
 								Content:  fmt.Sprintf("Tool call rejected: %s", messageContent),
 							}
+							agentService.RecordRejection(ctx, &lastMsg, messageContent)
 						} else {
 							return fmt.Errorf("last message has pending tool calls; must use --approve or --reject")
 						}
@@ -249,6 +411,7 @@ var sendCmd = &cobra.Command{
 								Role:     domain.RoleHuman,
 								Content:  fmt.Sprintf("Tool call rejected: %s", messageContent),
 							}
+							agentService.RecordRejection(ctx, &lastMsg, messageContent)
 						} else {
 							return fmt.Errorf("last message has pending tool calls; must use --approve or --reject")
 						}
@@ -282,7 +445,7 @@ var sendCmd = &cobra.Command{
 					return fmt.Errorf("no message provided")
 				}
 
-				thread := &domain.Thread{}
+				thread := &domain.Thread{Privacy: privacyFlag}
 				if err := repo.CreateThread(ctx, thread); err != nil {
 					return fmt.Errorf("failed to create thread: %w", err)
 				}
@@ -296,15 +459,46 @@ var sendCmd = &cobra.Command{
 			}
 		}
 
+		// Keep part boundaries on new messages composed from parts
+		if len(parts) > 0 && msg.ID == uuid.Nil && msg.Role == domain.RoleHuman && msg.Content == messageContent {
+			if err := msg.SetParts(parts); err != nil {
+				return err
+			}
+			msg.Attachments = attachments
+			if err := storeImages(ctx, repo, threadID, images); err != nil {
+				return err
+			}
+		}
+
 		// Send the message
-		if err := sendMessage(ctx, agentService, msg); err != nil {
-			return err
+		if err := sendOrQueue(ctx, repo, agentService, msg, presetName); err != nil {
+			return timeoutHint(err, threadID)
+		}
+
+		// The reply was just printed so there is nothing left unread
+		if err := repo.MarkThreadRead(ctx, threadID); err != nil {
+			return fmt.Errorf("failed to mark thread as read: %w", err)
 		}
 
 		return nil
 	},
 }
 
+// timeoutHint explains how to recover from a request that timed out
+func timeoutHint(err error, threadID uuid.UUID) error {
+	var timeoutErr *agent.TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		return err
+	}
+
+	retry := "Retry with a longer --timeout or raise requestTimeout in the preset"
+	if timeoutErr.Partial == nil {
+		return fmt.Errorf("%w. Nothing was received before the timeout. %s", err, retry)
+	}
+	return fmt.Errorf("%w. The partial response was saved as message %s. %s, or ask for the rest with `slop msg send -t %s continue`",
+		err, timeoutErr.Partial.ID.String()[:8], retry, threadID.String()[:8])
+}
+
 // getLastUserMessageID returns the ID of the last human message in the thread
 // to be used as the parent ID for new messages
 func getLastUserMessageID(messages []domain.Message) *uuid.UUID {
@@ -316,29 +510,103 @@ func getLastUserMessageID(messages []domain.Message) *uuid.UUID {
 	return nil
 }
 
+// statusInterval is how often the status line is redrawn while a response streams
+const statusInterval = 250 * time.Millisecond
+
 // processStream handles the common logic for processing events from an agent stream
 func processStream(ctx context.Context, agentService *agent.Agent, stream agent.AgentStream) error {
 	var jsonKey string
 
+	// Timings shown in verbose mode
+	start := time.Now()
+	var firstToken time.Time
+
+	// Running estimate of the response's size and cost, shown when it has a status line
+	var estimate *usage.Estimate
+	var lastStatus time.Time
+	clearStatus := func() {
+		if estimate != nil {
+			output.ClearStatus()
+			estimate = nil
+		}
+	}
+	defer clearStatus()
+
+	// The run is named when the turn stops early so it can be resumed
+	var runID uuid.UUID
+	resumeHint := func() {
+		if runID != uuid.Nil {
+			output.Noticef("Resume this turn with `slop run resume %s`\n", runID.String()[:8])
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
-			fmt.Println("\nRequest cancelled")
+			output.Println("\nRequest cancelled")
+			resumeHint()
 			return ctx.Err()
 
 		case event, ok := <-stream.Events:
 			if !ok {
 				// Stream closed
-				fmt.Println()
+				output.Println()
 				return nil
 			}
 
 			switch e := event.(type) {
+			case *agent.RunStartedEvent:
+				runID = e.RunID
+				output.Verbosef("[run %s]\n", runID.String()[:8])
+
+			case *agent.SystemMessageEvent:
+				output.Verbosef("[system message: %d characters from %s]\n", len(e.Content), strings.Join(e.Sources, ", "))
+
+			case *agent.CacheHitEvent:
+				output.Verbosef("[response from cache, stored %s]\n", e.StoredAt.Format(time.DateTime))
+
+			case *agent.ReconnectEvent:
+				output.Verbosef("\n[connection dropped after %d characters, reconnecting (attempt %d): %v]\n", e.Received, e.Attempt, e.Error)
+
+			case *llm.RetryEvent:
+				output.Noticef("\n[retrying (%d/%d) in %s: %v]\n", e.Attempt, e.MaxAttempts, e.Delay.Round(time.Millisecond), e.Error)
+
+			case *agent.ServerExitEvent:
+				output.Noticef("\n[%s]\n", e)
+
+			case *agent.FallbackEvent:
+				output.Noticef("\n[%s could not answer, falling back to preset %s (%s): %v]\n", e.Failed, e.Preset, e.Model, e.Error)
+
+			case *agent.ThreadTitledEvent:
+				output.Verbosef("[thread titled %q]\n", e.Title)
+
+			case *agent.ContextSummaryEvent:
+				output.Verbosef("[summarized %d older messages: %d to %d tokens]\n", e.Messages, e.Before, e.After)
+
+			case *agent.CompressionEvent:
+				output.Verbosef("[compressed %d older messages with %s: %d to %d tokens, ratio %.2f]\n", e.Messages, e.Method, e.Before, e.After, e.Ratio())
+
 			case *llm.TextEvent:
-				fmt.Print(e.Content)
+				if firstToken.IsZero() {
+					firstToken = time.Now()
+				}
+				// Quiet mode prints the final answer once it is complete
+				if !output.Quiet() {
+					fmt.Print(e.Content)
+				}
+				if output.StatusEnabled() {
+					if estimate == nil {
+						estimate = usage.NewEstimate(agentService.Preset())
+					}
+					estimate.Add(e.Content)
+					if time.Since(lastStatus) >= statusInterval {
+						output.Status(estimate.String())
+						lastStatus = time.Now()
+					}
+				}
 
 			case *llm.ToolCallStartEvent:
-				fmt.Printf("\n\n[Requesting function call: %s]", e.FunctionName)
+				output.Printf("\n\n[Requesting function call: %s]", e.FunctionName)
 
 			case *llm.MessageCompleteEvent:
 				// The message is complete with all metadata
@@ -349,10 +617,29 @@ func processStream(ctx context.Context, agentService *agent.Agent, stream agent.
 				return handleToolApproval(ctx, agentService, e.Message, e.ToolCalls)
 
 			case *agent.ToolResultEvent:
-				fmt.Printf("%s\n", e.Result)
+				output.Printf("%s\n", e.Result)
+				status := "ok"
+				if e.Error != nil {
+					status = e.Error.Error()
+				}
+				output.Verbosef("[%s finished after %s: %s]\n", e.Name, time.Since(start).Round(time.Millisecond), status)
+				start = time.Now()
 
 			case *agent.NewMessageEvent:
-				// Update thread state if needed
+				if e.Message.Role != domain.RoleAssistant {
+					break
+				}
+				clearStatus()
+				printResponseDetails(e.Message, start, firstToken)
+				if output.Quiet() && e.Message.ToolCalls == "" {
+					fmt.Println(e.Message.Content)
+				}
+				if metadata, err := e.Message.GetMetadata(); err == nil && metadata.Confidence != nil {
+					output.Println()
+					output.Footer(metadata.Confidence.Footer())
+				}
+				start = time.Now()
+				firstToken = time.Time{}
 
 			case *llm.JsonUpdateEvent:
 				if jsonKey != e.Key {
@@ -362,7 +649,11 @@ func processStream(ctx context.Context, agentService *agent.Agent, stream agent.
 				fmt.Print(e.ValueChunk)
 
 			case *events.ErrorEvent:
-				return e.Error
+				// Messages that fail because the provider is unreachable are queued instead
+				if !queue.IsOfflineError(e.Error) {
+					resumeHint()
+				}
+				return errkind.New(e.Kind, e.Error)
 			}
 
 		case <-stream.Done:
@@ -371,19 +662,91 @@ func processStream(ctx context.Context, agentService *agent.Agent, stream agent.
 	}
 }
 
+// printResponseDetails shows the timings and tool calls of a response in verbose mode
+func printResponseDetails(msg *domain.Message, start time.Time, firstToken time.Time) {
+	if !output.Verbose() {
+		return
+	}
+
+	details := fmt.Sprintf("response in %s", time.Since(start).Round(time.Millisecond))
+	if !firstToken.IsZero() {
+		details += fmt.Sprintf(", first token after %s", firstToken.Sub(start).Round(time.Millisecond))
+	}
+	model := msg.ModelName
+	if msg.ModelVersion != "" {
+		model = msg.ModelVersion
+	}
+	output.Verbosef("\n[%s, model %s]\n", details, model)
+
+	if msg.ToolCalls == "" {
+		return
+	}
+	var toolCalls []llm.ToolCall
+	if err := json.Unmarshal([]byte(msg.ToolCalls), &toolCalls); err != nil {
+		return
+	}
+	for _, call := range toolCalls {
+		output.Verbosef("[tool call %s %s: %s]\n", call.ID, call.Name, call.Arguments)
+	}
+}
+
 // Helper function to handle tool approval
 func handleToolApproval(ctx context.Context, agentService *agent.Agent, message *domain.Message, toolCalls []llm.ToolCall) error {
-	// Prompt for approval
-	fmt.Print("\n\nApprove tool execution? [y/N] ")
+	// Prompt for approval. Calls of several tools or several calls of one tool can
+	// be approved by tool
+	question := "\n\nApprove tool execution? [y]es, [N]o, always for this [t]hread, always for this [s]ession: "
+	if len(toolCalls) > 1 {
+		printPendingCalls(toolCalls)
+		question = "Approve tool execution? [y]es to all, [N]o, always for this [t]hread, always for this [s]ession, [c]hoose by tool: "
+	}
+	output.Noticef("%s", question)
 	reader := bufio.NewReader(os.Stdin)
 	response, err := reader.ReadString('\n')
 	if err != nil {
-		return fmt.Errorf("failed to read approval: %w", err)
+		return errkind.New(errkind.ApprovalRequired, fmt.Errorf("failed to read approval: %w", err))
 	}
 
 	response = strings.TrimSpace(strings.ToLower(response))
+	var scope domain.ApprovalScope
+	switch response {
+	case "t", "thread":
+		scope = domain.ApprovalScopeThread
+	case "s", "session":
+		scope = domain.ApprovalScopeSession
+	}
+	if scope != "" {
+		output.Noticef("Only allow arguments matching (a glob over the JSON arguments, press Enter to allow any): ")
+		pattern, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read pattern: %w", err)
+		}
+		if err := agentService.RememberApproval(ctx, message.ThreadID, scope, toolCalls, strings.TrimSpace(pattern)); err != nil {
+			return err
+		}
+		response = "y"
+	}
+
+	if (response == "c" || response == "choose") && len(toolCalls) > 1 {
+		rejected, err := chooseCalls(reader, toolCalls)
+		if err != nil {
+			return errkind.New(errkind.ApprovalRequired, err)
+		}
+		response = "n"
+		if len(rejected) < len(toolCalls) {
+			response = "y"
+			if len(rejected) > 0 {
+				output.Noticef("Enter rejection reason for the other calls (optional, press Enter to skip): ")
+				reason, err := reader.ReadString('\n')
+				if err != nil {
+					return fmt.Errorf("failed to read reason: %w", err)
+				}
+				agentService.RejectCalls(ctx, message, rejected, strings.TrimSpace(reason))
+			}
+		}
+	}
+
 	if response == "y" || response == "yes" {
-		fmt.Println()
+		output.Println()
 		// Execute tools by calling SendMessageStream with the assistant message
 		// This is considered an approval
 		stream := agentService.SendMessageStream(ctx, message)
@@ -392,14 +755,15 @@ func handleToolApproval(ctx context.Context, agentService *agent.Agent, message
 		return processStream(ctx, agentService, stream)
 	} else {
 		// Optional rejection reason
-		fmt.Print("Enter rejection reason (optional, press Enter to skip): ")
+		output.Noticef("Enter rejection reason (optional, press Enter to skip): ")
 		reason, err := reader.ReadString('\n')
-		fmt.Println()
+		output.Println()
 
 		if err != nil {
 			return fmt.Errorf("failed to read reason: %w", err)
 		}
 		reason = strings.TrimSpace(reason)
+		agentService.RecordRejection(ctx, message, reason)
 
 		// Create a tool rejection message
 		rejectionMsg := &domain.Message{
@@ -431,10 +795,29 @@ func init() {
 	sendCmd.Flags().StringVarP(&parentFlag, "parent", "p", "", "Create alternative response by using specified message's parent")
 	sendCmd.Flags().BoolVarP(&continueFlag, "continue", "c", false, "Continue the most recent thread")
 	sendCmd.Flags().StringVarP(&modelFlag, "model", "m", "", "Specify the model to use")
+	sendCmd.Flags().StringVar(&onceModelFlag, "once-model", "", "Reply with this preset for this message only. Pinned threads keep their model for later replies")
+	sendCmd.Flags().StringVar(&modeFlag, "mode", "", "Apply a bundle of overrides from the modes config, such as fast or quality")
 	sendCmd.Flags().BoolVarP(&noStreamFlag, "no-stream", "n", false, "Disable streaming of responses")
 	sendCmd.Flags().IntVar(&maxTokensFlag, "max-tokens", 0, "Override maximum length")
 	sendCmd.Flags().Float64Var(&temperatureFlag, "temperature", 0, "Override temperature")
+	sendCmd.Flags().StringVar(&toolChoiceFlag, "tool-choice", "", "Override tool choice: auto, none, required or a server__tool name")
+	sendCmd.Flags().StringSliceVar(&enableToolsFlag, "enable-tools", nil, "Offer these tools for this message only, such as filesystem.read_file,web.* (tools outside the preset need approval)")
+	sendCmd.Flags().StringSliceVar(&disableToolsFlag, "disable-tools", nil, "Don't offer these tools for this message, '*' disables all of them")
+	sendCmd.Flags().StringVar(&privacyFlag, "privacy", "", "Privacy level of a new thread: normal, local-only or redacted")
+	sendCmd.Flags().BoolVar(&noCacheFlag, "no-cache", false, "Always ask the provider, even if the response cache has an answer")
 	sendCmd.Flags().BoolVarP(&approveFlag, "approve", "a", false, "Approve pending tool calls")
 	sendCmd.Flags().BoolVarP(&rejectFlag, "reject", "r", false, "Reject pending tool calls")
+	sendCmd.Flags().BoolVar(&retryFlag, "retry", false, "Answer the last message of the thread again and show what changed from the last response. Use with --thread or --continue")
+	sendCmd.Flags().BoolVar(&noDiffFlag, "no-diff", false, "Don't show what changed from the last response with --retry")
+	sendCmd.Flags().DurationVar(&timeoutFlag, "timeout", 0, "Give up on a response that has not finished after this long, such as 120s. Partial output is saved")
+	sendCmd.Flags().StringArrayVar(&partFlag, "part", nil, "Add a message part from a file or text. Repeat to send several parts as one turn")
+	sendCmd.Flags().StringArrayVarP(&fileFlag, "file", "f", nil, "Attach a text file, sent to the model after the message with its name. Repeat to attach several")
+	sendCmd.Flags().StringArrayVar(&filesFlag, "files", nil, "Attach the files matching a glob such as 'src/**/*.go', split to fit the context budget. Repeat to add patterns")
+	sendCmd.Flags().StringVar(&filesByFlag, "files-by", filesByRecent, "Which files matching --files come first when they don't all fit: recent or relevance to the message")
+	sendCmd.Flags().IntVar(&filesBudgetFlag, "files-budget", 0, "Tokens the files matching --files may take up, defaults to half the preset's context window")
+	sendCmd.Flags().StringArrayVar(&imageFlag, "image", nil, "Attach an image file or http(s) URL for presets with vision. Repeat to attach several")
+	sendCmd.Flags().StringVar(&templateFlag, "template", "", "Compose the message from a template in messageTemplates")
+	sendCmd.Flags().StringArrayVar(&varFlag, "var", nil, "Set a template variable as name=value. Missing variables are asked for")
+	sendCmd.Flags().StringVar(&separatorFlag, "stdin-separator", "", "Split piped input into a separate part at every line matching this separator")
 	MsgCmd.AddCommand(sendCmd)
 }
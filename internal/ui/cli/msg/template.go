@@ -0,0 +1,50 @@
+package msg
+
+import (
+	"bufio"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/msgtemplate"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+)
+
+// renderTemplate fills in a message template from the config with the --var values,
+// asking for the variables that were not given when stdin is a terminal
+func renderTemplate(templates map[string]string, name string, pairs []string) (string, error) {
+	text, ok := templates[name]
+	if !ok {
+		available := slices.Sorted(maps.Keys(templates))
+		if len(available) == 0 {
+			return "", fmt.Errorf("message template %q not found, none are configured in messageTemplates", name)
+		}
+		return "", fmt.Errorf("message template %q not found, available templates: %s", name, strings.Join(available, ", "))
+	}
+
+	tmpl, err := msgtemplate.Parse(name, text)
+	if err != nil {
+		return "", err
+	}
+	vars, err := msgtemplate.ParseVars(pairs)
+	if err != nil {
+		return "", err
+	}
+
+	missing := tmpl.Missing(vars)
+	if stat, _ := os.Stdin.Stat(); len(missing) > 0 && (stat.Mode()&os.ModeCharDevice) != 0 {
+		reader := bufio.NewReader(os.Stdin)
+		for _, variable := range missing {
+			output.Noticef("%s: ", variable)
+			input, err := reader.ReadString('\n')
+			if err != nil {
+				return "", fmt.Errorf("failed to read input: %w", err)
+			}
+			vars[variable] = strings.TrimSpace(input)
+		}
+	}
+
+	return tmpl.Render(vars)
+}
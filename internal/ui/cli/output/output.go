@@ -0,0 +1,77 @@
+package output
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Level controls how much the CLI prints besides the results of a command
+type Level int
+
+const (
+	// LevelQuiet prints only results, such as the text of the final answer
+	LevelQuiet Level = iota - 1
+	// LevelNormal prints results and status messages
+	LevelNormal
+	// LevelVerbose also prints details such as tool calls, timings and the system message
+	LevelVerbose
+)
+
+var level = LevelNormal
+
+// SetLevel sets the output level for the rest of the command
+func SetLevel(l Level) {
+	level = l
+}
+
+// Quiet reports whether only results are printed
+func Quiet() bool {
+	return level <= LevelQuiet
+}
+
+// Verbose reports whether details are printed
+func Verbose() bool {
+	return level >= LevelVerbose
+}
+
+// Printf prints a status message, which is left out in quiet mode
+func Printf(format string, a ...any) {
+	if !Quiet() {
+		fmt.Printf(format, a...)
+	}
+}
+
+// Println prints a status message, which is left out in quiet mode
+func Println(a ...any) {
+	if !Quiet() {
+		fmt.Println(a...)
+	}
+}
+
+// Footer prints a dimmed line below a response, which is left out in quiet mode. It
+// is only dimmed when stdout is a terminal
+func Footer(text string) {
+	if !Quiet() {
+		fmt.Println(lipgloss.NewStyle().Faint(true).Render(text))
+	}
+}
+
+// Noticef prints something the user has to see at every level, such as a question
+// or a failure. In quiet mode it goes to stderr so stdout only holds results
+func Noticef(format string, a ...any) {
+	if Quiet() {
+		fmt.Fprintf(os.Stderr, format, a...)
+		return
+	}
+	fmt.Printf(format, a...)
+}
+
+// Verbosef prints details that are only shown in verbose mode. They go to stderr so
+// stdout is the same at the normal and verbose levels
+func Verbosef(format string, a ...any) {
+	if Verbose() {
+		fmt.Fprintf(os.Stderr, format, a...)
+	}
+}
@@ -0,0 +1,31 @@
+package output
+
+import (
+	"fmt"
+	"os"
+)
+
+// StatusEnabled reports whether a live status line can be shown on stderr. It needs
+// stderr to be a terminal that the response is not being streamed to, so it is only
+// shown in quiet mode or when stdout is redirected
+func StatusEnabled() bool {
+	if !isTerminal(os.Stderr) {
+		return false
+	}
+	return Quiet() || !isTerminal(os.Stdout)
+}
+
+// Status replaces the status line on stderr with text
+func Status(text string) {
+	fmt.Fprintf(os.Stderr, "\r\x1b[K%s", text)
+}
+
+// ClearStatus removes the status line from stderr
+func ClearStatus() {
+	fmt.Fprint(os.Stderr, "\r\x1b[K")
+}
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,383 @@
+package pipe
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/agent"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/errkind"
+	"github.com/isaacphi/slop/internal/events"
+	"github.com/isaacphi/slop/internal/llm"
+	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/repository"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+var (
+	modelFlag  string
+	modeFlag   string
+	threadFlag string
+)
+
+var PipeCmd = &cobra.Command{
+	Use:   "pipe",
+	Short: "Drive slop with JSON lines on stdin and stdout",
+	Long: `Read commands as JSON lines from stdin and write events as JSON lines to stdout.
+
+Commands:
+  {"type": "send", "content": "...", "model": "<preset>"}
+  {"type": "approve", "remember": "thread|session", "arguments": "<glob>"}
+  {"type": "reject", "reason": "..."}
+  {"type": "switch-thread", "thread": "<id>"}
+  {"type": "reload-mcp", "server": "<name>"}
+
+Every command produces a stream of events ending with a "done" or "error" event.
+An optional "id" field on a command is echoed back on its events.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		app := appState.FromContext(ctx)
+		cfg := app.Config
+
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("failed to initialize repository: %w", err)
+		}
+
+		mcpClient := mcp.New(cfg.MCPServers)
+		if err := mcpClient.Start(context.Background()); err != nil {
+			return fmt.Errorf("failed to initialize MCP client: %w", err)
+		}
+		defer mcpClient.Shutdown()
+		if reason := mcpClient.SafeMode(); reason != "" {
+			fmt.Fprintf(os.Stderr, "Safe mode, tools are not offered: %s\n", reason)
+		}
+
+		s := &session{
+			app:       app,
+			repo:      repo,
+			mcpClient: mcpClient,
+			out:       newEncoder(os.Stdout),
+		}
+
+		if err := s.setPreset(modelFlag); err != nil {
+			return err
+		}
+		if threadFlag != "" {
+			if err := s.switchThread(ctx, threadFlag); err != nil {
+				return err
+			}
+		}
+
+		if err := s.out.write(Event{Type: EventReady, ThreadID: s.threadIDString()}); err != nil {
+			return err
+		}
+
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+
+			var command Command
+			if err := json.Unmarshal([]byte(line), &command); err != nil {
+				s.out.write(Event{Type: EventError, Error: fmt.Sprintf("invalid command: %v", err)})
+				continue
+			}
+
+			if err := s.handle(ctx, command); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				s.out.write(Event{Type: EventError, ID: command.ID, ThreadID: s.threadIDString(), Error: err.Error(), Kind: string(errkind.Of(err))})
+				continue
+			}
+			s.out.write(Event{Type: EventDone, ID: command.ID, ThreadID: s.threadIDString()})
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+
+		return nil
+	},
+}
+
+// session holds the state shared between commands
+type session struct {
+	app       *appState.App // Scoped to the preset selected by the last command
+	repo      repository.MessageRepository
+	mcpClient *mcp.Client
+	agent     *agent.Agent
+	preset    string
+	threadID  *uuid.UUID
+	out       *encoder
+}
+
+func (s *session) handle(ctx context.Context, command Command) error {
+	switch command.Type {
+	case CommandSend:
+		if command.Content == "" {
+			return fmt.Errorf("no message provided")
+		}
+		if command.Model != "" {
+			if err := s.setPreset(command.Model); err != nil {
+				return err
+			}
+		}
+		return s.send(ctx, command)
+
+	case CommandApprove:
+		pending, err := s.pendingToolCalls(ctx)
+		if err != nil {
+			return err
+		}
+		if command.Remember != "" {
+			if err := s.rememberApproval(ctx, pending, command); err != nil {
+				return err
+			}
+		}
+		return s.stream(ctx, command, pending)
+
+	case CommandReject:
+		pending, err := s.pendingToolCalls(ctx)
+		if err != nil {
+			return err
+		}
+		s.agent.RecordRejection(ctx, pending, command.Reason)
+		return s.stream(ctx, command, &domain.Message{
+			ThreadID: pending.ThreadID,
+			ParentID: &pending.ID,
+			Role:     domain.RoleHuman,
+			Content:  fmt.Sprintf("Tool call rejected: %s", command.Reason),
+		})
+
+	case CommandSwitchThread:
+		if command.Thread == "" {
+			s.threadID = nil
+			return nil
+		}
+		return s.switchThread(ctx, command.Thread)
+
+	case CommandReloadMCP:
+		return s.reloadServer(ctx, command)
+
+	default:
+		return fmt.Errorf("unknown command type %q", command.Type)
+	}
+}
+
+// setPreset switches to the named preset, or the default preset when name is empty.
+// The --mode overrides are applied to it
+func (s *session) setPreset(name string) error {
+	scoped, err := s.app.With(appState.Scope{Preset: name, Mode: modeFlag})
+	if err != nil {
+		return err
+	}
+	name, preset, err := scoped.Preset()
+	if err != nil {
+		return err
+	}
+	agentService, err := agent.New(s.repo, s.mcpClient, preset, scoped.Config.Toolsets, scoped.Config.Prompts, scoped.Config.Style)
+	if err != nil {
+		return fmt.Errorf("could not initialize MCP agent: %w", err)
+	}
+	s.app = scoped
+	s.agent = agentService
+	s.preset = name
+	return nil
+}
+
+// reloadServer restarts one MCP server with its latest configuration and refreshes
+// the agent's tools. Other servers keep running
+func (s *session) reloadServer(ctx context.Context, command Command) error {
+	if command.Server == "" {
+		return fmt.Errorf("no server provided")
+	}
+
+	// Read the config again so edits since startup are picked up
+	cfg, err := config.New(nil)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	server, ok := cfg.MCPServers[command.Server]
+	if !ok {
+		return fmt.Errorf("server %s not found in configuration", command.Server)
+	}
+
+	result, err := s.mcpClient.Reload(ctx, command.Server, server)
+	if err != nil {
+		return err
+	}
+	if err := s.setPreset(s.preset); err != nil {
+		return err
+	}
+
+	return s.out.write(Event{
+		Type:   EventMCPReloaded,
+		ID:     command.ID,
+		Name:   command.Server,
+		Reload: &result,
+	})
+}
+
+func (s *session) switchThread(ctx context.Context, partialID string) error {
+	thread, err := s.repo.GetThreadByPartialID(ctx, partialID)
+	if err != nil {
+		return fmt.Errorf("failed to find thread: %w", err)
+	}
+	s.threadID = &thread.ID
+	return nil
+}
+
+func (s *session) threadIDString() string {
+	if s.threadID == nil {
+		return ""
+	}
+	return s.threadID.String()
+}
+
+func (s *session) send(ctx context.Context, command Command) error {
+	msg := &domain.Message{
+		Role:    domain.RoleHuman,
+		Content: command.Content,
+	}
+
+	if s.threadID == nil {
+		thread := &domain.Thread{}
+		if err := s.repo.CreateThread(ctx, thread); err != nil {
+			return fmt.Errorf("failed to create thread: %w", err)
+		}
+		s.threadID = &thread.ID
+		s.out.write(Event{Type: EventThread, ID: command.ID, ThreadID: s.threadIDString()})
+	} else {
+		messages, err := s.repo.GetMessages(ctx, *s.threadID, nil, false)
+		if err != nil {
+			return fmt.Errorf("failed to get thread messages: %w", err)
+		}
+		if len(messages) > 0 {
+			lastMsg := messages[len(messages)-1]
+			if lastMsg.Role == domain.RoleAssistant && lastMsg.ToolCalls != "" {
+				return fmt.Errorf("thread has pending tool calls, send approve or reject first")
+			}
+			msg.ParentID = &lastMsg.ID
+		}
+	}
+	msg.ThreadID = *s.threadID
+
+	return s.stream(ctx, command, msg)
+}
+
+// pendingToolCalls returns the last message of the current thread if it is waiting for tool approval
+func (s *session) pendingToolCalls(ctx context.Context) (*domain.Message, error) {
+	if s.threadID == nil {
+		return nil, fmt.Errorf("no thread selected")
+	}
+	messages, err := s.repo.GetMessages(ctx, *s.threadID, nil, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get thread messages: %w", err)
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("thread has no pending tool calls")
+	}
+	lastMsg := messages[len(messages)-1]
+	if lastMsg.Role != domain.RoleAssistant || lastMsg.ToolCalls == "" {
+		return nil, fmt.Errorf("thread has no pending tool calls")
+	}
+	return &lastMsg, nil
+}
+
+// rememberApproval allows the pending tools to run without approval from now on
+func (s *session) rememberApproval(ctx context.Context, pending *domain.Message, command Command) error {
+	scope := domain.ApprovalScope(command.Remember)
+	if scope != domain.ApprovalScopeThread && scope != domain.ApprovalScopeSession {
+		return fmt.Errorf("invalid remember %q: expected thread or session", command.Remember)
+	}
+	var toolCalls []llm.ToolCall
+	if err := json.Unmarshal([]byte(pending.ToolCalls), &toolCalls); err != nil {
+		return fmt.Errorf("failed to parse tool calls: %w", err)
+	}
+	return s.agent.RememberApproval(ctx, pending.ThreadID, scope, toolCalls, command.Arguments)
+}
+
+// stream sends a message through the agent and writes its events until the agent stops.
+// Tool approval requests end the stream, the caller answers them with approve or reject
+func (s *session) stream(ctx context.Context, command Command, msg *domain.Message) error {
+	stream := s.agent.SendMessageStream(ctx, msg)
+	threadID := msg.ThreadID.String()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case event, ok := <-stream.Events:
+			if !ok {
+				return nil
+			}
+
+			var out Event
+			switch e := event.(type) {
+			case *llm.TextEvent:
+				out = Event{Type: EventText, Content: e.Content}
+
+			case *llm.ToolCallStartEvent:
+				out = Event{Type: EventToolCallStart, Name: e.FunctionName}
+
+			case *llm.RetryEvent:
+				out = Event{Type: EventRetry, Attempt: e.Attempt, MaxAttempts: e.MaxAttempts, Error: e.Error.Error()}
+
+			case *agent.ServerExitEvent:
+				out = Event{Type: EventMCPExited, Name: e.Server, Error: e.Error.Error(), Restarting: e.Restarting, Kind: string(errkind.ServerDown)}
+
+			case *agent.ToolApprovalRequestEvent:
+				out = Event{Type: EventToolApproval, MessageID: e.Message.ID.String(), ToolCalls: e.ToolCalls}
+
+			case *agent.ToolResultEvent:
+				out = Event{Type: EventToolResult, ToolCallID: e.ToolCallID, Name: e.Name, Content: e.Result}
+				if e.Error != nil {
+					out.Error = e.Error.Error()
+				}
+
+			case *agent.NewMessageEvent:
+				out = Event{
+					Type:      EventMessage,
+					MessageID: e.Message.ID.String(),
+					Role:      string(e.Message.Role),
+					Content:   e.Message.Content,
+				}
+
+			case *events.ErrorEvent:
+				return errkind.New(e.Kind, e.Error)
+
+			default:
+				continue
+			}
+
+			out.ID = command.ID
+			out.ThreadID = threadID
+			if err := s.out.write(out); err != nil {
+				return fmt.Errorf("failed to write event: %w", err)
+			}
+
+		case <-stream.Done:
+			return nil
+		}
+	}
+}
+
+func init() {
+	PipeCmd.Flags().StringVarP(&modelFlag, "model", "m", "", "Preset to start with")
+	PipeCmd.Flags().StringVar(&modeFlag, "mode", "", "Apply a bundle of overrides from the modes config to every preset used, such as fast or quality")
+	PipeCmd.Flags().StringVarP(&threadFlag, "thread", "t", "", "Thread to start in, a new thread is created on the first send otherwise")
+}
@@ -0,0 +1,97 @@
+package pipe
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/isaacphi/slop/internal/llm"
+	"github.com/isaacphi/slop/internal/mcp"
+)
+
+// Command types read from stdin
+const (
+	CommandSend         = "send"
+	CommandApprove      = "approve"
+	CommandReject       = "reject"
+	CommandSwitchThread = "switch-thread"
+	CommandReloadMCP    = "reload-mcp"
+)
+
+// Event types written to stdout
+const (
+	EventReady         = "ready"
+	EventThread        = "thread"
+	EventMessage       = "message"
+	EventText          = "text"
+	EventToolCallStart = "tool_call_start"
+	EventToolApproval  = "tool_approval"
+	EventToolResult    = "tool_result"
+	EventMCPReloaded   = "mcp_reloaded"
+	EventMCPExited     = "mcp_exited"
+	EventRetry         = "retry"
+	EventDone          = "done"
+	EventError         = "error"
+)
+
+// Command is a single line of input
+//
+//	{"type": "send", "content": "hello", "model": "claude"}
+//	{"type": "approve"}
+//	{"type": "approve", "remember": "thread", "arguments": "*\"path\":\"/tmp/*"}
+//	{"type": "reject", "reason": "not that file"}
+//	{"type": "switch-thread", "thread": "1a2b3c4d"}
+//	{"type": "reload-mcp", "server": "filesystem"}
+type Command struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`      // Echoed back on every event caused by this command
+	Content string `json:"content,omitempty"` // send
+	Model   string `json:"model,omitempty"`   // send, switches the preset for this and later messages
+	Reason  string `json:"reason,omitempty"`  // reject
+	Thread  string `json:"thread,omitempty"`  // switch-thread, an empty thread starts a new one on the next send
+	Server  string `json:"server,omitempty"`  // reload-mcp
+
+	// approve, allow later calls of the same tools without approval in the thread or
+	// session, optionally only when their JSON encoded arguments match the Arguments glob
+	Remember  string `json:"remember,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// Event is a single line of output
+type Event struct {
+	Type       string            `json:"type"`
+	ID         string            `json:"id,omitempty"`
+	ThreadID   string            `json:"threadId,omitempty"`
+	MessageID  string            `json:"messageId,omitempty"`
+	Role       string            `json:"role,omitempty"`
+	Content    string            `json:"content,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	ToolCallID string            `json:"toolCallId,omitempty"`
+	ToolCalls  []llm.ToolCall    `json:"toolCalls,omitempty"`
+	Reload     *mcp.ReloadResult `json:"reload,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Kind       string            `json:"kind,omitempty"` // error, category such as provider-rate-limit
+
+	// retry, the attempt about to be made out of the most that will be
+	Attempt     int `json:"attempt,omitempty"`
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+
+	// mcp_exited, whether the server named by Name is being restarted
+	Restarting bool `json:"restarting,omitempty"`
+}
+
+// encoder writes events as JSON lines
+type encoder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newEncoder(w io.Writer) *encoder {
+	return &encoder{enc: json.NewEncoder(w)}
+}
+
+func (e *encoder) write(event Event) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Encode(event)
+}
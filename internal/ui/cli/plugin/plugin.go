@@ -0,0 +1,235 @@
+// Package plugin runs slop-<name> executables found on PATH as slop <name>, the way
+// git runs git-<name>
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+// prefix starts the name of every plugin executable
+const prefix = "slop-"
+
+// contextVersion is bumped when the context sent to plugins changes incompatibly
+const contextVersion = 1
+
+// Context is what a plugin is told about slop, as JSON on its stdin
+type Context struct {
+	Version    int                  `json:"version"`
+	Args       []string             `json:"args"`
+	ProjectDir string               `json:"projectDir,omitempty"`
+	Config     *config.ConfigSchema `json:"config"`
+	Thread     *Thread              `json:"thread,omitempty"` // The most recent thread, nil if there are none
+}
+
+// Thread is the most recent thread with the messages of its active branch
+type Thread struct {
+	ID        string    `json:"id"`
+	Summary   string    `json:"summary,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	Language  string    `json:"language,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	Messages  []Message `json:"messages"`
+}
+
+// Message is one message of the thread
+type Message struct {
+	ID        string    `json:"id"`
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Model     string    `json:"model,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Commands returns a command for each plugin on PATH whose name isn't taken by a
+// command of root. The first executable on PATH with a name wins
+func Commands(root *cobra.Command) []*cobra.Command {
+	taken := make(map[string]bool)
+	for _, cmd := range root.Commands() {
+		taken[cmd.Name()] = true
+		for _, alias := range cmd.Aliases {
+			taken[alias] = true
+		}
+	}
+	taken["help"] = true
+
+	found := make(map[string]string)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := pluginName(entry.Name())
+			if !ok || taken[name] || found[name] != "" {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if isExecutable(path) {
+				found[name] = path
+			}
+		}
+	}
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	commands := make([]*cobra.Command, 0, len(names))
+	for _, name := range names {
+		commands = append(commands, command(name, found[name]))
+	}
+	return commands
+}
+
+// pluginName returns the command name of a plugin executable's file name
+func pluginName(file string) (string, bool) {
+	if !strings.HasPrefix(file, prefix) {
+		return "", false
+	}
+	name := strings.TrimPrefix(file, prefix)
+	if runtime.GOOS == "windows" {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return name, name != "" && !strings.ContainsAny(name, ". ")
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode()&0o111 != 0
+}
+
+func command(name, path string) *cobra.Command {
+	return &cobra.Command{
+		Use:   name,
+		Short: fmt.Sprintf("Plugin %s", path),
+		Long: fmt.Sprintf("Runs %s with the rest of the command line. The plugin gets the config and the most recent thread as JSON on stdin, "+
+			"unless input is piped to slop which is passed on instead, and in the SLOP_* environment variables.", path),
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(cmd.Context(), path, args)
+		},
+	}
+}
+
+func run(ctx context.Context, path string, args []string) error {
+	cfg := appState.Get().Config
+	pluginCtx := Context{
+		Version:    contextVersion,
+		Args:       args,
+		ProjectDir: cfg.ProjectDir(),
+		Config:     cfg,
+	}
+	thread, err := latestThread(ctx, cfg.DBPath)
+	if err != nil {
+		return err
+	}
+	pluginCtx.Thread = thread
+
+	plugin := exec.CommandContext(ctx, path, args...)
+	plugin.Stdout = os.Stdout
+	plugin.Stderr = os.Stderr
+	plugin.Env = append(os.Environ(), environment(cfg, thread)...)
+
+	if stat, _ := os.Stdin.Stat(); stat.Mode()&os.ModeCharDevice == 0 {
+		plugin.Stdin = os.Stdin
+	} else {
+		encoded, err := json.Marshal(pluginCtx)
+		if err != nil {
+			return fmt.Errorf("failed to encode the plugin context: %w", err)
+		}
+		plugin.Stdin = bytes.NewReader(encoded)
+	}
+
+	err = plugin.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// The plugin reported its own failure, exit with its code like git does
+		_ = appState.Cleanup()
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run plugin %s: %w", path, err)
+	}
+	return nil
+}
+
+// environment describes slop to a plugin in SLOP_* variables
+func environment(cfg *config.ConfigSchema, thread *Thread) []string {
+	env := []string{
+		"SLOP_DB_PATH=" + cfg.DBPath,
+		"SLOP_PRESET=" + cfg.DefaultPreset,
+		"SLOP_PROJECT_DIR=" + cfg.ProjectDir(),
+	}
+	if self, err := os.Executable(); err == nil {
+		env = append(env, "SLOP_BIN="+self)
+	}
+	if thread != nil {
+		env = append(env, "SLOP_THREAD_ID="+thread.ID)
+	}
+	return env
+}
+
+// latestThread reads the most recent thread, nil if there are none
+func latestThread(ctx context.Context, dbPath string) (*Thread, error) {
+	repo, err := sqlite.Initialize(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := repo.GetMostRecentThread(ctx)
+	if err != nil || latest == nil {
+		return nil, nil
+	}
+	messages, err := repo.GetMessages(ctx, latest.ID, nil, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get thread messages: %w", err)
+	}
+	tags, err := repo.GetThreadTags(ctx, []uuid.UUID{latest.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	thread := &Thread{
+		ID:        latest.ID.String(),
+		Summary:   latest.Summary,
+		Tags:      tags[latest.ID],
+		Language:  latest.Language,
+		CreatedAt: latest.CreatedAt,
+		Messages:  make([]Message, 0, len(messages)),
+	}
+	for _, msg := range messages {
+		if msg.Role == domain.RoleSystem {
+			continue
+		}
+		thread.Messages = append(thread.Messages, Message{
+			ID:        msg.ID.String(),
+			Role:      string(msg.Role),
+			Content:   msg.Content,
+			Model:     msg.ModelName,
+			CreatedAt: msg.CreatedAt,
+		})
+	}
+	return thread, nil
+}
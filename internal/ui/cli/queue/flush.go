@@ -0,0 +1,227 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/slop/internal/agent"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/batch"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/errkind"
+	"github.com/isaacphi/slop/internal/events"
+	"github.com/isaacphi/slop/internal/llm"
+	"github.com/isaacphi/slop/internal/llm/provider"
+	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/queue"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	waitFlag  bool
+	dueFlag   bool
+	batchFlag bool
+)
+
+var flushCmd = &cobra.Command{
+	Use:   "flush",
+	Short: "Send queued messages",
+	Long: `Send queued messages in order. With --wait, keep retrying with exponential backoff until the queue is empty,
+and send everything as soon as an unreachable provider can be reached again.
+
+With --batch, submit them through the provider's batch API instead, which is cheaper but can
+take up to a day. Only Anthropic and OpenAI presets are submitted, and only the oldest queued
+message of each thread. Use ` + "`slop job status`" + ` to check on submitted batches and add the
+replies to their threads.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg := appState.Get().Config
+
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("failed to initialize repository: %w", err)
+		}
+
+		mcpClient := mcp.New(cfg.MCPServers)
+		if err := mcpClient.Start(context.Background()); err != nil {
+			return fmt.Errorf("failed to initialize MCP client: %w", err)
+		}
+		defer mcpClient.Shutdown()
+		if reason := mcpClient.SafeMode(); reason != "" {
+			output.Noticef("Safe mode: %s\n", reason)
+		}
+
+		// Queued messages are sent with the preset they were originally sent with
+		agents := make(map[string]*agent.Agent)
+		getAgent := func(name string) (*agent.Agent, error) {
+			if agentService, ok := agents[name]; ok {
+				return agentService, nil
+			}
+			preset, exists := cfg.Presets[name]
+			if !exists {
+				return nil, fmt.Errorf("model %s not found in configuration", name)
+			}
+			agentService, err := agent.New(repo, mcpClient, preset, cfg.Toolsets, cfg.Prompts, cfg.Style)
+			if err != nil {
+				return nil, fmt.Errorf("could not initialize MCP agent: %w", err)
+			}
+			if disabled := agentService.DisabledToolsets(); len(disabled) > 0 {
+				output.Noticef("Toolsets of preset %s disabled in safe mode: %s\n", name, strings.Join(disabled, ", "))
+			}
+			agents[name] = agentService
+			return agentService, nil
+		}
+
+		force := !dueFlag
+
+		if batchFlag {
+			build := func(ctx context.Context, entry domain.QueuedMessage, msg *domain.Message) (llm.BatchRequest, error) {
+				agentService, err := getAgent(entry.Preset)
+				if err != nil {
+					return llm.BatchRequest{}, err
+				}
+				return agentService.BatchRequest(ctx, msg)
+			}
+			result, err := batch.Submit(ctx, repo, cfg.Presets, force, build)
+			for _, job := range result.Jobs {
+				requests, _ := job.GetRequests()
+				fmt.Printf("Submitted batch %s with %d messages for preset %s\n", job.ID.String()[:8], len(requests), job.Preset)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Submitted %d batches, still queued %d\n", len(result.Jobs), result.Remaining)
+			return nil
+		}
+
+		send := func(ctx context.Context, entry domain.QueuedMessage, msg *domain.Message) error {
+			agentService, err := getAgent(entry.Preset)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("[thread %s] %s\n", msg.ThreadID.String()[:8], msg.Content)
+			return printStream(ctx, agentService.SendMessageStream(ctx, msg))
+		}
+
+		for {
+			result, err := queue.Flush(ctx, repo, nil, force, send)
+			if err != nil {
+				return err
+			}
+			for _, err := range result.Errors {
+				fmt.Printf("Failed: %v\n", err)
+			}
+			fmt.Printf("Sent %d, failed %d, still queued %d\n", result.Sent, result.Failed, result.Remaining)
+
+			if !waitFlag || result.Remaining == 0 {
+				return nil
+			}
+
+			// Wait until the next queued message is due
+			entries, err := repo.ListQueuedMessages(ctx, nil)
+			if err != nil {
+				return fmt.Errorf("failed to list queued messages: %w", err)
+			}
+			next := time.Now().Add(time.Minute)
+			for _, entry := range entries {
+				if entry.NextAttemptAt.Before(next) {
+					next = entry.NextAttemptAt
+				}
+			}
+			fmt.Printf("Retrying at %s\n", next.Format(time.Kitchen))
+
+			online, err := queue.Wait(ctx, next, endpoints(cfg.Presets, entries), queue.Reachable)
+			if err != nil {
+				return err
+			}
+			// Once connectivity returns everything queued is sent, otherwise only
+			// the messages that are due
+			force = online
+			if online {
+				fmt.Println("Provider reachable again")
+			}
+		}
+	},
+}
+
+// endpoints returns the addresses of the providers queued messages are sent to
+func endpoints(presets map[string]config.Preset, entries []domain.QueuedMessage) []string {
+	seen := make(map[string]bool)
+	var addresses []string
+	for _, entry := range entries {
+		preset, ok := presets[entry.Preset]
+		if !ok {
+			continue
+		}
+		address := preset.BaseURL
+		if address == "" {
+			p, err := provider.Get(preset.Provider)
+			if err != nil {
+				continue
+			}
+			address = p.Capabilities().Endpoint
+		}
+		if address != "" && !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// printStream prints the response to a queued message. Tool calls that need approval
+// are left pending so they can be handled with `slop msg send --approve`
+func printStream(ctx context.Context, stream agent.AgentStream) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case event, ok := <-stream.Events:
+			if !ok {
+				fmt.Println()
+				return nil
+			}
+
+			switch e := event.(type) {
+			case *llm.TextEvent:
+				fmt.Print(e.Content)
+
+			case *llm.ToolCallStartEvent:
+				fmt.Printf("\n\n[Requesting function call: %s]", e.FunctionName)
+
+			case *agent.ServerExitEvent:
+				fmt.Printf("\n[%s]\n", e)
+
+			case *llm.RetryEvent:
+				fmt.Printf("\n[retrying (%d/%d) in %s: %v]\n", e.Attempt, e.MaxAttempts, e.Delay.Round(time.Millisecond), e.Error)
+
+			case *agent.ToolApprovalRequestEvent:
+				fmt.Printf("\n\nTool calls need approval, use `slop msg send -t %s --approve`\n", e.Message.ThreadID.String()[:8])
+
+			case *agent.ToolResultEvent:
+				fmt.Printf("%s\n", e.Result)
+
+			case *events.ErrorEvent:
+				return errkind.New(e.Kind, e.Error)
+			}
+
+		case <-stream.Done:
+			return nil
+		}
+	}
+}
+
+func init() {
+	flushCmd.Flags().BoolVarP(&waitFlag, "wait", "w", false, "Keep retrying until all queued messages are sent")
+	flushCmd.Flags().BoolVar(&dueFlag, "due", false, "Only send messages whose retry time has passed")
+	flushCmd.Flags().BoolVar(&batchFlag, "batch", false, "Submit messages through the provider's batch API at a lower price")
+	QueueCmd.AddCommand(flushCmd)
+}
@@ -0,0 +1,60 @@
+package queue
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List queued messages",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		entries, err := repo.ListQueuedMessages(cmd.Context(), nil)
+		if err != nil {
+			return fmt.Errorf("failed to list queued messages: %w", err)
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("No queued messages")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Thread\tMessage\tPreset\tAttempts\tNext Attempt\tLast Error")
+
+		for _, entry := range entries {
+			lastError := entry.LastError
+			if len(lastError) > 50 {
+				lastError = lastError[:47] + "..."
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n",
+				entry.ThreadID.String()[:8],
+				entry.MessageID.String()[:8],
+				entry.Preset,
+				entry.Attempts,
+				entry.NextAttemptAt.Format(time.RFC822),
+				lastError,
+			)
+		}
+		w.Flush()
+
+		return nil
+	},
+}
+
+func init() {
+	QueueCmd.AddCommand(listCmd)
+}
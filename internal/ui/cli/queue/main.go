@@ -0,0 +1,10 @@
+package queue
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var QueueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Manage messages queued while the provider was unreachable",
+}
@@ -4,20 +4,50 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/codebase"
 	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/document"
+	"github.com/isaacphi/slop/internal/errkind"
+	mcpClient "github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/repository"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/sandbox"
+	"github.com/isaacphi/slop/internal/threadmeta"
+	"github.com/isaacphi/slop/internal/ui/cli/approvals"
+	archiveCmd "github.com/isaacphi/slop/internal/ui/cli/archive"
+	"github.com/isaacphi/slop/internal/ui/cli/artifact"
+	bundleCmd "github.com/isaacphi/slop/internal/ui/cli/bundle"
+	"github.com/isaacphi/slop/internal/ui/cli/cache"
 	"github.com/isaacphi/slop/internal/ui/cli/chat"
 	configCmd "github.com/isaacphi/slop/internal/ui/cli/config"
+	"github.com/isaacphi/slop/internal/ui/cli/db"
+	"github.com/isaacphi/slop/internal/ui/cli/doc"
+	"github.com/isaacphi/slop/internal/ui/cli/eval"
+	"github.com/isaacphi/slop/internal/ui/cli/index"
+	"github.com/isaacphi/slop/internal/ui/cli/job"
 	"github.com/isaacphi/slop/internal/ui/cli/mcp"
 	"github.com/isaacphi/slop/internal/ui/cli/msg"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/isaacphi/slop/internal/ui/cli/pipe"
+	"github.com/isaacphi/slop/internal/ui/cli/plugin"
+	"github.com/isaacphi/slop/internal/ui/cli/queue"
+	"github.com/isaacphi/slop/internal/ui/cli/run"
+	"github.com/isaacphi/slop/internal/ui/cli/serve"
 	"github.com/isaacphi/slop/internal/ui/cli/thread"
+	"github.com/isaacphi/slop/internal/ui/cli/tune"
+	"github.com/isaacphi/slop/internal/ui/cli/usage"
 	"github.com/spf13/cobra"
 )
 
 var (
 	logLevel string
 	logFile  string
+	quiet    bool
+	verbose  bool
+	noMCP    bool
 )
 
 var rootCmd = &cobra.Command{
@@ -34,8 +64,14 @@ func Execute() {
 	// Set up the root command to use this context
 	rootCmd.SetContext(ctx)
 
+	// slop-<name> executables on PATH extend slop with their own commands
+	rootCmd.AddCommand(plugin.Commands(rootCmd)...)
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		if advice := errkind.Of(err).Advice(); advice != "" {
+			fmt.Fprintf(os.Stderr, "Hint: %s\n", advice)
+		}
 		os.Exit(1)
 	}
 }
@@ -44,17 +80,65 @@ func init() {
 	// Add global flags for logging
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "Set logging level (DEBUG, INFO, WARN, ERROR)")
 	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Log file path (defaults to stdout)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only print results, such as the text of the final answer")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Also print tool call details, timings and a summary of the system message")
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "verbose")
+	rootCmd.PersistentFlags().BoolVar(&noMCP, "no-mcp", false, "Don't start MCP servers and offer no tools, for when a server keeps slop from starting")
 
 	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		switch {
+		case quiet:
+			output.SetLevel(output.LevelQuiet)
+		case verbose:
+			output.SetLevel(output.LevelVerbose)
+		}
+
 		// Initialize app with logging overrides
 		overrides := &config.RuntimeOverrides{}
 		if logLevel != "" {
 			overrides.LogLevel = &logLevel
+		} else if quiet {
+			// Logs go to stdout by default and would mix with the results
+			errorLevel := "ERROR"
+			overrides.LogLevel = &errorLevel
 		}
 		if logFile != "" {
 			overrides.LogFile = &logFile
 		}
-		return appState.Initialize(overrides)
+		if err := appState.Initialize(overrides); err != nil {
+			return err
+		}
+
+		// Commands that run the agent start without MCP servers with --no-mcp, and by
+		// themselves once the servers keep failing to start
+		mcpClient.ConfigureSafeMode(noMCP, filepath.Dir(appState.Get().Config.DBPath))
+
+		// The codebase server is built in, toolsets use it like any MCP server
+		if cfg := appState.Get().Config; cfg.Codebase.Enabled {
+			mcpClient.RegisterBuiltin(codebase.ServerName, codebase.NewServer(cfg.Codebase, func() (repository.MessageRepository, error) {
+				return sqlite.Initialize(cfg.DBPath)
+			}))
+		}
+
+		// The thread server lets the model title and tag its conversation
+		mcpClient.RegisterBuiltin(threadmeta.ServerName, threadmeta.NewServer(func() (repository.MessageRepository, error) {
+			return sqlite.Initialize(appState.Get().Config.DBPath)
+		}))
+
+		// The document server keeps the outline of documents written in document mode
+		mcpClient.RegisterBuiltin(document.ServerName, document.NewServer(func() (repository.MessageRepository, error) {
+			return sqlite.Initialize(appState.Get().Config.DBPath)
+		}))
+
+		// The sandbox server runs code the model writes in containers, only when enabled
+		if cfg := appState.Get().Config; cfg.Sandbox.Enabled {
+			mcpClient.RegisterBuiltin(sandbox.ServerName, sandbox.NewServer(cfg.Sandbox))
+		}
+
+		// Commands that serve several requests read the App from the context so
+		// each request can be given its own scope
+		cmd.SetContext(appState.NewContext(cmd.Context(), appState.Get()))
+		return nil
 	}
 
 	rootCmd.PersistentPostRunE = func(cmd *cobra.Command, args []string) error {
@@ -66,9 +150,26 @@ func init() {
 
 	rootCmd.AddCommand(
 		configCmd.ConfigCmd,
+		db.DBCmd,
 		msg.MsgCmd,
 		thread.ThreadCmd,
 		mcp.MCPCmd,
+		approvals.ApprovalsCmd,
 		chat.ChatCmd,
+		usage.UsageCmd,
+		queue.QueueCmd,
+		job.JobCmd,
+		index.IndexCmd,
+		cache.CacheCmd,
+		run.RunCmd,
+		eval.EvalCmd,
+		pipe.PipeCmd,
+		serve.ServeCmd,
+		artifact.ArtifactCmd,
+		doc.DocCmd,
+		tune.TuneCmd,
+		archiveCmd.ExportCmd,
+		archiveCmd.ImportCmd,
+		bundleCmd.BundleCmd,
 	)
 }
@@ -0,0 +1,81 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+var (
+	threadFlag string
+	limitFlag  int
+)
+
+var listCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List recent runs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		var threadID *uuid.UUID
+		if threadFlag != "" {
+			thread, err := repo.GetThreadByPartialID(cmd.Context(), threadFlag)
+			if err != nil {
+				return fmt.Errorf("failed to find thread: %w", err)
+			}
+			threadID = &thread.ID
+		}
+
+		runs, err := repo.ListRuns(cmd.Context(), threadID, limitFlag)
+		if err != nil {
+			return fmt.Errorf("failed to list runs: %w", err)
+		}
+
+		if len(runs) == 0 {
+			fmt.Println("No runs")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Run\tThread\tStarted\tStatus\tStep\tError")
+
+		for _, run := range runs {
+			step := ""
+			if run.Resumable() {
+				step = string(run.Step)
+			}
+			runError := run.Error
+			if len(runError) > 50 {
+				runError = runError[:47] + "..."
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				run.ID.String()[:8],
+				run.ThreadID.String()[:8],
+				run.CreatedAt.Format(time.RFC822),
+				run.Status,
+				step,
+				runError,
+			)
+		}
+		w.Flush()
+
+		return nil
+	},
+}
+
+func init() {
+	listCmd.Flags().StringVarP(&threadFlag, "thread", "t", "", "Only list runs of this thread")
+	listCmd.Flags().IntVarP(&limitFlag, "limit", "l", 20, "Maximum number of runs to list, 0 for all")
+	RunCmd.AddCommand(listCmd)
+}
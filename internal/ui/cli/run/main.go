@@ -0,0 +1,12 @@
+package run
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var RunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Inspect and resume agent runs",
+	Long: `Every message sent to the agent starts a run that records the progress of the turn, such as which
+tool calls already finished. A run that was interrupted can be resumed instead of starting the turn over.`,
+}
@@ -0,0 +1,123 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/slop/internal/agent"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/errkind"
+	"github.com/isaacphi/slop/internal/events"
+	"github.com/isaacphi/slop/internal/llm"
+	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume <run_id>",
+	Short: "Continue an interrupted run",
+	Long: `Continue an interrupted run from where it stopped, with the preset it was started with. Tool calls
+that finished before the interruption are not executed again, and a response that was being generated
+is requested again.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg := appState.Get().Config
+
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("failed to initialize repository: %w", err)
+		}
+
+		run, err := repo.GetRunByPartialID(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		if !run.Resumable() {
+			return fmt.Errorf("run %s is %s and can't be resumed", run.ID.String()[:8], run.Status)
+		}
+		preset, err := agent.RunPreset(run)
+		if err != nil {
+			return err
+		}
+
+		mcpClient := mcp.New(cfg.MCPServers)
+		if err := mcpClient.Start(context.Background()); err != nil {
+			return fmt.Errorf("failed to initialize MCP client: %w", err)
+		}
+		defer mcpClient.Shutdown()
+		if reason := mcpClient.SafeMode(); reason != "" {
+			output.Noticef("Safe mode: %s\n", reason)
+		}
+
+		agentService, err := agent.New(repo, mcpClient, preset, cfg.Toolsets, cfg.Prompts, cfg.Style)
+		if err != nil {
+			return fmt.Errorf("could not initialize MCP agent: %w", err)
+		}
+		if disabled := agentService.DisabledToolsets(); len(disabled) > 0 {
+			output.Noticef("Toolsets disabled in safe mode: %s\n", strings.Join(disabled, ", "))
+		}
+
+		output.Noticef("Resuming run %s in thread %s at step %s\n", run.ID.String()[:8], run.ThreadID.String()[:8], run.Step)
+		return printStream(ctx, agentService.ResumeRun(ctx, run))
+	},
+}
+
+// printStream prints the rest of a resumed run. Tool calls that need approval are left
+// pending so they can be handled with `slop msg send --approve`
+func printStream(ctx context.Context, stream agent.AgentStream) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case event, ok := <-stream.Events:
+			if !ok {
+				output.Println()
+				return nil
+			}
+
+			switch e := event.(type) {
+			case *llm.TextEvent:
+				if !output.Quiet() {
+					fmt.Print(e.Content)
+				}
+
+			case *llm.ToolCallStartEvent:
+				output.Printf("\n\n[Requesting function call: %s]", e.FunctionName)
+
+			case *agent.ServerExitEvent:
+				output.Noticef("\n[%s]\n", e)
+
+			case *llm.RetryEvent:
+				output.Noticef("\n[retrying (%d/%d) in %s: %v]\n", e.Attempt, e.MaxAttempts, e.Delay.Round(time.Millisecond), e.Error)
+
+			case *agent.ToolApprovalRequestEvent:
+				output.Noticef("\n\nTool calls need approval, use `slop msg send -t %s --approve`\n", e.Message.ThreadID.String()[:8])
+
+			case *agent.ToolResultEvent:
+				output.Printf("%s\n", e.Result)
+
+			case *agent.NewMessageEvent:
+				if output.Quiet() && e.Message.ToolCalls == "" && e.Message.Role == domain.RoleAssistant {
+					fmt.Println(e.Message.Content)
+				}
+
+			case *events.ErrorEvent:
+				return errkind.New(e.Kind, e.Error)
+			}
+
+		case <-stream.Done:
+			return nil
+		}
+	}
+}
+
+func init() {
+	RunCmd.AddCommand(resumeCmd)
+}
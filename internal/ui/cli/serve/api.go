@@ -0,0 +1,458 @@
+package serve
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/agent"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/events"
+	"github.com/isaacphi/slop/internal/llm"
+)
+
+// defaultThreadLimit is how many threads are listed when the request doesn't say
+const defaultThreadLimit = 50
+
+// apiThread is a thread as the API returns it
+type apiThread struct {
+	ID        string       `json:"id"`
+	Title     string       `json:"title,omitempty"`
+	Summary   string       `json:"summary,omitempty"`
+	Tags      []string     `json:"tags,omitempty"`
+	CreatedAt time.Time    `json:"createdAt"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+	Messages  []apiMessage `json:"messages,omitempty"`
+}
+
+// apiMessage is a message as the API returns it
+type apiMessage struct {
+	ID        string          `json:"id"`
+	ParentID  string          `json:"parentId,omitempty"`
+	Role      domain.Role     `json:"role"`
+	Content   string          `json:"content"`
+	ToolCalls json.RawMessage `json:"toolCalls,omitempty"`
+	Model     string          `json:"model,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+func newAPIMessage(msg domain.Message) apiMessage {
+	m := apiMessage{
+		ID:        msg.ID.String(),
+		Role:      msg.Role,
+		Content:   msg.Content,
+		Model:     msg.ModelName,
+		CreatedAt: msg.CreatedAt,
+	}
+	if msg.ParentID != nil {
+		m.ParentID = msg.ParentID.String()
+	}
+	if msg.ToolCalls != "" {
+		m.ToolCalls = json.RawMessage(msg.ToolCalls)
+	}
+	return m
+}
+
+func (s *server) apiRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/threads", s.api(s.handleListThreads))
+	mux.HandleFunc("POST /api/threads", s.api(s.handleCreateThread))
+	mux.HandleFunc("GET /api/threads/{id}", s.api(s.handleGetThread))
+	mux.HandleFunc("POST /api/threads/{id}/messages", s.api(s.handleSendMessage))
+	mux.HandleFunc("POST /api/threads/{id}/approve", s.api(s.handleApprove))
+	mux.HandleFunc("POST /api/threads/{id}/reject", s.api(s.handleReject))
+	mux.HandleFunc("POST /api/mcp/{server}/reload", s.api(s.handleReloadServer))
+}
+
+// api checks the token of API requests and that requests with a body send JSON. A
+// web page can't send a JSON body to another origin without asking first, so other
+// sites can't drive the local server from a browser
+func (s *server) api(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token := s.app.Config.Serve.Token; token != "" {
+			given, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid token"))
+				return
+			}
+		}
+		if r.Method == http.MethodPost {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("requests must send application/json"))
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// handleListThreads lists the most recent threads, limited by the limit parameter
+func (s *server) handleListThreads(w http.ResponseWriter, r *http.Request) {
+	limit := defaultThreadLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", value))
+			return
+		}
+		limit = n
+	}
+	threads, err := s.repo.ListThreads(r.Context(), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to list threads: %w", err))
+		return
+	}
+
+	ids := make([]uuid.UUID, len(threads))
+	for i, thread := range threads {
+		ids[i] = thread.ID
+	}
+	tags, err := s.repo.GetThreadTags(r.Context(), ids)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to get tags: %w", err))
+		return
+	}
+
+	result := make([]apiThread, len(threads))
+	for i, thread := range threads {
+		result[i] = apiThread{
+			ID:        thread.ID.String(),
+			Title:     thread.Title,
+			Summary:   thread.Summary,
+			Tags:      tags[thread.ID],
+			CreatedAt: thread.CreatedAt,
+			UpdatedAt: thread.UpdatedAt,
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleCreateThread creates an empty thread
+func (s *server) handleCreateThread(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Title   string `json:"title"`
+		Summary string `json:"summary"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	thread := &domain.Thread{Title: body.Title, Summary: body.Summary}
+	if err := s.repo.CreateThread(r.Context(), thread); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to create thread: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, apiThread{
+		ID:        thread.ID.String(),
+		Title:     thread.Title,
+		Summary:   thread.Summary,
+		CreatedAt: thread.CreatedAt,
+		UpdatedAt: thread.UpdatedAt,
+	})
+}
+
+// handleGetThread returns a thread with the messages of its active branch
+func (s *server) handleGetThread(w http.ResponseWriter, r *http.Request) {
+	thread, messages, ok := s.threadMessages(w, r)
+	if !ok {
+		return
+	}
+	tags, err := s.repo.GetThreadTags(r.Context(), []uuid.UUID{thread.ID})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to get tags: %w", err))
+		return
+	}
+	result := apiThread{
+		ID:        thread.ID.String(),
+		Title:     thread.Title,
+		Summary:   thread.Summary,
+		Tags:      tags[thread.ID],
+		CreatedAt: thread.CreatedAt,
+		UpdatedAt: thread.UpdatedAt,
+		Messages:  make([]apiMessage, len(messages)),
+	}
+	for i, msg := range messages {
+		result.Messages[i] = newAPIMessage(msg)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleSendMessage adds a message to the end of a thread and streams the reply
+func (s *server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Content string `json:"content"`
+		Preset  string `json:"preset"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if strings.TrimSpace(body.Content) == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("content is required"))
+		return
+	}
+	thread, messages, ok := s.threadMessages(w, r)
+	if !ok {
+		return
+	}
+	if pendingCalls(messages) != nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("the last message has pending tool calls, approve or reject them first"))
+		return
+	}
+
+	msg := &domain.Message{
+		ThreadID: thread.ID,
+		Role:     domain.RoleHuman,
+		Content:  body.Content,
+	}
+	if len(messages) > 0 {
+		msg.ParentID = &messages[len(messages)-1].ID
+	}
+	agentService, err := s.newAgent(body.Preset)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.streamReply(w, r, agentService, msg)
+}
+
+// handleApprove runs the pending tool calls of a thread and streams the reply. Calls
+// listed in reject get the reason as their result instead
+func (s *server) handleApprove(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Reject []string `json:"reject"` // IDs of calls not to run
+		Reason string   `json:"reason"`
+		Preset string   `json:"preset"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	_, messages, ok := s.threadMessages(w, r)
+	if !ok {
+		return
+	}
+	calls := pendingCalls(messages)
+	if calls == nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("the thread has no pending tool calls"))
+		return
+	}
+
+	agentService, err := s.newAgent(body.Preset)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var rejected []llm.ToolCall
+	for _, id := range body.Reject {
+		found := false
+		for _, call := range calls {
+			if call.ID == id {
+				rejected = append(rejected, call)
+				found = true
+			}
+		}
+		if !found {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("tool call %q is not pending", id))
+			return
+		}
+	}
+	last := messages[len(messages)-1]
+	agentService.RejectCalls(r.Context(), &last, rejected, body.Reason)
+	s.streamReply(w, r, agentService, &last)
+}
+
+// handleReject rejects all pending tool calls of a thread with a reason and streams
+// the reply to the rejection
+func (s *server) handleReject(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Reason string `json:"reason"`
+		Preset string `json:"preset"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	thread, messages, ok := s.threadMessages(w, r)
+	if !ok {
+		return
+	}
+	if pendingCalls(messages) == nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("the thread has no pending tool calls"))
+		return
+	}
+
+	agentService, err := s.newAgent(body.Preset)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	last := messages[len(messages)-1]
+	agentService.RecordRejection(r.Context(), &last, body.Reason)
+	s.streamReply(w, r, agentService, &domain.Message{
+		ThreadID: thread.ID,
+		ParentID: &last.ID,
+		Role:     domain.RoleHuman,
+		Content:  fmt.Sprintf("Tool call rejected: %s", body.Reason),
+	})
+}
+
+// streamReply sends msg through the agent and writes its events as server-sent events
+// until the turn ends. The turn is cancelled if the client disconnects
+func (s *server) streamReply(w http.ResponseWriter, r *http.Request, agentService *agent.Agent, msg *domain.Message) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	stop := context.AfterFunc(s.ctx, cancel)
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	stream := agentService.SendMessageStream(ctx, msg)
+	for event := range stream.Events {
+		name, data := sseEvent(event)
+		if name == "" {
+			continue
+		}
+		writeSSE(w, name, data)
+		flusher.Flush()
+	}
+	writeSSE(w, "done", struct{}{})
+	flusher.Flush()
+}
+
+// sseEvent names an agent event and gives the data sent with it. Events clients have
+// no use for get an empty name
+func sseEvent(event events.Event) (string, any) {
+	switch e := event.(type) {
+	case *agent.RunStartedEvent:
+		return "run", map[string]string{"runId": e.RunID.String()}
+	case *llm.TextEvent:
+		return "text", map[string]string{"content": e.Content}
+	case *agent.NewMessageEvent:
+		return "message", newAPIMessage(*e.Message)
+	case *agent.ToolApprovalRequestEvent:
+		return "approval", map[string]any{"messageId": e.Message.ID.String(), "toolCalls": e.ToolCalls}
+	case *agent.ToolResultEvent:
+		return "tool_result", map[string]string{"toolCallId": e.ToolCallID, "name": e.Name}
+	case *agent.ReconnectEvent:
+		return "reconnect", map[string]int{"attempt": e.Attempt, "received": e.Received}
+	case *llm.RetryEvent:
+		return "retry", map[string]any{"attempt": e.Attempt, "maxAttempts": e.MaxAttempts, "error": e.Error.Error()}
+	case *agent.ServerExitEvent:
+		return "mcp_exited", map[string]any{"server": e.Server, "error": e.Error.Error(), "restarting": e.Restarting}
+	case *agent.FallbackEvent:
+		return "fallback", map[string]string{"preset": e.Preset, "model": e.Model, "failed": e.Failed}
+	case *events.ErrorEvent:
+		return "error", map[string]string{"error": e.Error.Error(), "kind": string(e.Kind)}
+	}
+	return "", nil
+}
+
+func writeSSE(w io.Writer, name string, data any) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		encoded, _ = json.Marshal(map[string]string{"error": err.Error()})
+		name = "error"
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, encoded)
+}
+
+// handleReloadServer restarts an MCP server with its latest configuration. Other
+// servers keep running and replies started later get the server's new tools
+func (s *server) handleReloadServer(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("server")
+
+	// Read the config again so edits since startup are picked up
+	cfg, err := config.New(nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to load configuration: %w", err))
+		return
+	}
+	server, ok := cfg.MCPServers[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("server %s not found in configuration", name))
+		return
+	}
+
+	result, err := s.mcpClient.Reload(r.Context(), name, server)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// newAgent creates an agent for a preset, or the default preset when it is empty
+func (s *server) newAgent(presetName string) (*agent.Agent, error) {
+	scoped, err := s.app.With(appState.Scope{Preset: presetName})
+	if err != nil {
+		return nil, err
+	}
+	_, preset, err := scoped.Preset()
+	if err != nil {
+		return nil, err
+	}
+	agentService, err := agent.New(s.repo, s.mcpClient, preset, scoped.Config.Toolsets, scoped.Config.Prompts, scoped.Config.Style)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize MCP agent: %w", err)
+	}
+	return agentService, nil
+}
+
+// threadMessages finds the thread of the request and the messages of its active branch
+func (s *server) threadMessages(w http.ResponseWriter, r *http.Request) (*domain.Thread, []domain.Message, bool) {
+	thread, err := s.repo.GetThreadByPartialID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return nil, nil, false
+	}
+	messages, err := s.repo.GetMessages(r.Context(), thread.ID, nil, false)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to get thread messages: %w", err))
+		return nil, nil, false
+	}
+	return thread, messages, true
+}
+
+// pendingCalls returns the tool calls of the last message waiting for approval, nil
+// if there are none
+func pendingCalls(messages []domain.Message) []llm.ToolCall {
+	if len(messages) == 0 {
+		return nil
+	}
+	last := messages[len(messages)-1]
+	if last.Role != domain.RoleAssistant || last.ToolCalls == "" {
+		return nil
+	}
+	var calls []llm.ToolCall
+	if err := json.Unmarshal([]byte(last.ToolCalls), &calls); err != nil || len(calls) == 0 {
+		return nil
+	}
+	return calls
+}
+
+// readJSON decodes the request body into v, writing an error if it can't. An empty
+// body leaves v as it is
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(io.LimitReader(r.Body, maxPayloadSize)).Decode(v)
+	if err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/repository"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var addressFlag string
+
+var ServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run an HTTP server with an API for conversations and webhooks",
+	Long: `Listen for the webhooks configured in serve.webhooks. Each POST to a webhook's path
+starts a run in a new thread with the webhook's prompt and answers with the ID of
+the run. The result is POSTed to the webhook's deliverTo URL when the run stops.
+
+The /api endpoints let other programs hold conversations. Replies are streamed as
+server-sent events named run, text, message, approval, tool_result, reconnect,
+retry, fallback, error and done. POSTs must send JSON, and when serve.token is set
+every /api request needs the header Authorization: Bearer <token>.
+
+Endpoints:
+  POST <webhook path>                Start a run with the payload
+  GET  /runs/<id>                    Status and last response of a run
+  GET  /api/threads?limit=<n>        Recent threads
+  POST /api/threads                  Create a thread {"summary"}
+  GET  /api/threads/<id>             A thread with its messages
+  POST /api/threads/<id>/messages    Send a message and stream the reply {"content", "preset"}
+  POST /api/threads/<id>/approve     Run pending tool calls and stream the reply {"reject", "reason"}
+  POST /api/threads/<id>/reject      Reject pending tool calls and stream the reply {"reason"}
+  POST /api/mcp/<server>/reload      Restart an MCP server with its current configuration`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		app := appState.FromContext(ctx)
+		cfg := app.Config
+
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return fmt.Errorf("failed to initialize repository: %w", err)
+		}
+
+		mcpClient := mcp.New(cfg.MCPServers)
+		if err := mcpClient.Start(context.Background()); err != nil {
+			return fmt.Errorf("failed to initialize MCP client: %w", err)
+		}
+		defer mcpClient.Shutdown()
+		if reason := mcpClient.SafeMode(); reason != "" {
+			output.Noticef("Safe mode, tools are not offered: %s\n", reason)
+		}
+
+		s := &server{
+			ctx:       ctx,
+			app:       app,
+			repo:      repo,
+			mcpClient: mcpClient,
+		}
+
+		address := cfg.Serve.Address
+		if addressFlag != "" {
+			address = addressFlag
+		}
+		httpServer := &http.Server{Addr: address, Handler: logRequests(s.routes())}
+		go func() {
+			<-ctx.Done()
+			httpServer.Shutdown(context.Background())
+		}()
+
+		output.Noticef("Listening on http://%s with %d webhooks\n", address, len(cfg.Serve.Webhooks))
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+
+		// Runs are cancelled with ctx, wait for them to record how they ended
+		s.runs.Wait()
+		return nil
+	},
+}
+
+// server holds the state shared between requests
+type server struct {
+	ctx       context.Context // Cancelled when the server stops, runs outlive their request
+	app       *appState.App
+	repo      repository.MessageRepository
+	mcpClient *mcp.Client
+	runs      sync.WaitGroup
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	for name, hook := range s.app.Config.Serve.Webhooks {
+		mux.HandleFunc("POST "+hook.Path, s.handleWebhook(name, hook))
+	}
+	mux.HandleFunc("GET /runs/{id}", s.handleRun)
+	s.apiRoutes(mux)
+	return mux
+}
+
+// logRequests is the middleware that records every request in the log
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slog.Info("request", "method", r.Method, "path", r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func init() {
+	ServeCmd.Flags().StringVar(&addressFlag, "address", "", "Address to listen on, overriding serve.address")
+}
@@ -0,0 +1,170 @@
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/agent"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/events"
+	"github.com/isaacphi/slop/internal/webhook"
+)
+
+// maxPayloadSize limits the size of webhook payloads
+const maxPayloadSize = 1 << 20
+
+// handleWebhook starts a run in a new thread with the rendered prompt of a webhook.
+// It answers once the run has started, the run continues in the background
+func (s *server) handleWebhook(name string, hook config.Webhook) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadSize))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("failed to read payload: %w", err))
+			return
+		}
+		if !webhook.Verify(hook.Secret, r.Header, payload) {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid signature"))
+			return
+		}
+
+		content := string(payload)
+		if hook.Prompt != "" {
+			prompt, err := webhook.ParsePrompt(name, hook.Prompt)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			if content, err = prompt.Render(payload); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		agentService, err := s.newAgent(hook.Preset)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		thread := &domain.Thread{}
+		if err := s.repo.CreateThread(r.Context(), thread); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to create thread: %w", err))
+			return
+		}
+		stream := agentService.SendMessageStream(s.ctx, &domain.Message{
+			ThreadID: thread.ID,
+			Role:     domain.RoleHuman,
+			Content:  content,
+		})
+
+		// Wait for the run to be recorded so its ID can be returned
+		var runID uuid.UUID
+		for runID == uuid.Nil {
+			event, ok := <-stream.Events
+			if !ok {
+				writeError(w, http.StatusInternalServerError, fmt.Errorf("run stopped before it started"))
+				return
+			}
+			switch e := event.(type) {
+			case *agent.RunStartedEvent:
+				runID = e.RunID
+			case *events.ErrorEvent:
+				writeError(w, http.StatusInternalServerError, e.Error)
+				return
+			}
+		}
+
+		s.runs.Add(1)
+		go func() {
+			defer s.runs.Done()
+			s.finishWebhookRun(name, hook, runID, stream)
+		}()
+
+		writeJSON(w, http.StatusAccepted, map[string]string{
+			"runId":    runID.String(),
+			"threadId": thread.ID.String(),
+		})
+	}
+}
+
+// finishWebhookRun drains the events of a run and delivers its result
+func (s *server) finishWebhookRun(name string, hook config.Webhook, runID uuid.UUID, stream agent.AgentStream) {
+	for range stream.Events {
+	}
+
+	// Delivery is still attempted when the server is stopping
+	ctx := context.WithoutCancel(s.ctx)
+	result, err := s.runResult(ctx, runID)
+	if err != nil {
+		slog.Error("failed to get webhook run result", "webhook", name, "run", runID, "error", err)
+		return
+	}
+	result.Webhook = name
+	slog.Info("webhook run finished", "webhook", name, "run", runID, "status", result.Status)
+
+	if hook.DeliverTo == "" {
+		return
+	}
+	if err := webhook.Deliver(ctx, hook.DeliverTo, hook.Secret, result); err != nil {
+		slog.Error("failed to deliver webhook result", "webhook", name, "run", runID, "error", err)
+	}
+}
+
+// handleRun reports the status and last response of a run
+func (s *server) handleRun(w http.ResponseWriter, r *http.Request) {
+	run, err := s.repo.GetRunByPartialID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	result, err := s.runResult(r.Context(), run.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// runResult describes a run with the last response of the model in its thread
+func (s *server) runResult(ctx context.Context, runID uuid.UUID) (webhook.Result, error) {
+	run, err := s.repo.GetRunByPartialID(ctx, runID.String())
+	if err != nil {
+		return webhook.Result{}, err
+	}
+	result := webhook.Result{
+		RunID:    run.ID.String(),
+		ThreadID: run.ThreadID.String(),
+		Status:   string(run.Status),
+		Error:    run.Error,
+	}
+
+	messages, err := s.repo.GetMessages(ctx, run.ThreadID, nil, false)
+	if err != nil {
+		return result, fmt.Errorf("failed to get thread messages: %w", err)
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == domain.RoleAssistant {
+			result.Response = messages[i].Content
+			break
+		}
+	}
+	return result, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to write response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
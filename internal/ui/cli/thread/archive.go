@@ -0,0 +1,76 @@
+package thread
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive [thread_id]",
+	Short: "Archive threads to hide them from thread ls",
+	Long: `Archive a thread, or use --older-than or --tag to archive several threads at once. Archived threads
+are kept but no longer listed by thread ls. Use --restore to bring them back.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		action, done := "Archive", "Archived"
+		if restoreFlag {
+			action, done = "Restore", "Restored"
+		}
+
+		var threads []*domain.Thread
+		if len(args) > 0 {
+			thread, err := repo.GetThreadByPartialID(cmd.Context(), args[0])
+			if err != nil {
+				return fmt.Errorf("failed to find thread: %w", err)
+			}
+			threads = []*domain.Thread{thread}
+		} else {
+			if !isBulk() {
+				return fmt.Errorf("give a thread ID or select threads with --older-than or --tag")
+			}
+			// Restoring selects from the archived threads
+			threads, err = selectThreads(cmd.Context(), repo, restoreFlag)
+			if err != nil {
+				return err
+			}
+			if len(threads) == 0 {
+				output.Println("No threads matched")
+				return nil
+			}
+			if err := printSelection(cmd.Context(), repo, strings.ToLower(action), threads); err != nil {
+				return err
+			}
+			if dryRunFlag {
+				return nil
+			}
+			if ok, err := confirm(fmt.Sprintf("%s these %d threads?", action, len(threads))); err != nil || !ok {
+				return err
+			}
+		}
+
+		if err := repo.SetThreadsArchived(cmd.Context(), threadIDs(threads), !restoreFlag); err != nil {
+			return fmt.Errorf("failed to %s threads: %w", strings.ToLower(action), err)
+		}
+		output.Printf("%s %d threads\n", done, len(threads))
+		return nil
+	},
+}
+
+func init() {
+	archiveCmd.Flags().BoolVarP(&forceFlag, "force", "f", false, "Archive without confirmation")
+	archiveCmd.Flags().BoolVar(&restoreFlag, "restore", false, "Restore archived threads instead")
+	addSelectionFlags(archiveCmd)
+	ThreadCmd.AddCommand(archiveCmd)
+}
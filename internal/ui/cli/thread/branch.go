@@ -0,0 +1,119 @@
+package thread
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+// maxBranchPreview limits how much of each branch's last message is listed
+const maxBranchPreview = 60
+
+var branchCmd = &cobra.Command{
+	Use:   "branch [thread_id]",
+	Short: "List the branches of a thread or choose the active one",
+	Long: `List the branches of a thread, marking the active one with *. The active branch is the one
+shown by thread view and the TUI, and the one new messages continue from with --continue.
+
+Use --use with the ID of any message to make its branch active. New replies to that message keep
+the branch active. Use --reset to go back to following the newest message in the thread.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		thread, err := repo.GetThreadByPartialID(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to find thread: %w", err)
+		}
+
+		switch {
+		case useFlag != "" && resetFlag:
+			return fmt.Errorf("cannot specify --use and --reset")
+
+		case useFlag != "":
+			msg, err := repo.FindMessageByPartialID(cmd.Context(), thread.ID, useFlag)
+			if err != nil {
+				return fmt.Errorf("failed to find message: %w", err)
+			}
+			if err := repo.SetActiveMessage(cmd.Context(), thread.ID, &msg.ID); err != nil {
+				return fmt.Errorf("failed to update thread: %w", err)
+			}
+			output.Printf("Thread %s now continues from message %s\n", thread.ID.String()[:8], msg.ID.String()[:8])
+			return nil
+
+		case resetFlag:
+			if err := repo.SetActiveMessage(cmd.Context(), thread.ID, nil); err != nil {
+				return fmt.Errorf("failed to update thread: %w", err)
+			}
+			output.Printf("Thread %s now follows its newest message\n", thread.ID.String()[:8])
+			return nil
+		}
+
+		messages, err := repo.GetThreadsMessages(cmd.Context(), []uuid.UUID{thread.ID})
+		if err != nil {
+			return fmt.Errorf("failed to get thread messages: %w", err)
+		}
+		active, err := repo.GetMessages(cmd.Context(), thread.ID, nil, false)
+		if err != nil {
+			return fmt.Errorf("failed to get thread messages: %w", err)
+		}
+		var activeTip uuid.UUID
+		if len(active) > 0 {
+			activeTip = active[len(active)-1].ID
+		}
+
+		// A branch ends at each message nothing replies to
+		hasReply := make(map[uuid.UUID]bool)
+		for _, msg := range messages {
+			if msg.ParentID != nil {
+				hasReply[*msg.ParentID] = true
+			}
+		}
+		for _, msg := range messages {
+			if hasReply[msg.ID] {
+				continue
+			}
+			marker := " "
+			if msg.ID == activeTip {
+				marker = "*"
+			}
+			output.Printf("%s %s  %s  %s\n",
+				marker,
+				msg.ID.String()[:8],
+				msg.CreatedAt.Format(time.RFC822),
+				branchPreview(msg),
+			)
+		}
+		return nil
+	},
+}
+
+// branchPreview describes the last message of a branch on a single line
+func branchPreview(msg domain.Message) string {
+	role := "You"
+	if msg.Role == domain.RoleAssistant {
+		role = "Slop"
+	}
+	content := []rune(strings.Join(strings.Fields(msg.Content), " "))
+	if len(content) > maxBranchPreview {
+		return fmt.Sprintf("%s: %s...", role, string(content[:maxBranchPreview]))
+	}
+	return fmt.Sprintf("%s: %s", role, string(content))
+}
+
+func init() {
+	branchCmd.Flags().StringVar(&useFlag, "use", "", "ID of the message whose branch becomes active")
+	branchCmd.Flags().BoolVar(&resetFlag, "reset", false, "Follow the newest message in the thread again")
+	ThreadCmd.AddCommand(branchCmd)
+}
@@ -0,0 +1,130 @@
+package thread
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+// addSelectionFlags adds the flags used to select threads for a bulk operation
+func addSelectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&olderThanFlag, "older-than", "", "Select threads without messages in this long, such as 90d, 2w or 12h")
+	cmd.Flags().StringVar(&tagFlag, "tag", "", "Select threads with this tag")
+	cmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Show the selected threads without changing anything")
+}
+
+// isBulk reports whether any selection flags were given
+func isBulk() bool {
+	return olderThanFlag != "" || tagFlag != "" || allFlag
+}
+
+// selectThreads finds the threads selected by the selection flags
+func selectThreads(ctx context.Context, repo repository.MessageRepository, archived bool) ([]*domain.Thread, error) {
+	filter := repository.ThreadFilter{Tag: tagFlag, Archived: archived}
+	if olderThanFlag != "" {
+		age, err := parseAge(olderThanFlag)
+		if err != nil {
+			return nil, err
+		}
+		cutoff := time.Now().Add(-age)
+		filter.InactiveSince = &cutoff
+	}
+
+	threads, err := repo.FindThreads(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find threads: %w", err)
+	}
+	return threads, nil
+}
+
+// parseAge parses a duration that may also be given in days or weeks
+func parseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	age, err := time.ParseDuration(s)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q, use a number followed by d, w, h or m", s)
+	}
+	return age, nil
+}
+
+// threadIDs returns the IDs of threads
+func threadIDs(threads []*domain.Thread) []uuid.UUID {
+	ids := make([]uuid.UUID, len(threads))
+	for i, thread := range threads {
+		ids[i] = thread.ID
+	}
+	return ids
+}
+
+// printSelection summarizes the threads a bulk operation will act on
+func printSelection(ctx context.Context, repo repository.MessageRepository, action string, threads []*domain.Thread) error {
+	counts, err := repo.CountMessages(ctx, threadIDs(threads))
+	if err != nil {
+		return fmt.Errorf("failed to count messages: %w", err)
+	}
+
+	// Quiet mode only lists the selected threads
+	if output.Quiet() {
+		for _, thread := range threads {
+			fmt.Println(thread.ID.String()[:8])
+		}
+		return nil
+	}
+
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	fmt.Printf("About to %s %d threads with %d messages:\n", action, len(threads), total)
+	for _, thread := range threads {
+		label := thread.Label()
+		if len(label) > 50 {
+			label = label[:47] + "..."
+		}
+		fmt.Printf("  %s  %s  %d messages  %s\n",
+			thread.ID.String()[:8],
+			thread.CreatedAt.Format(time.RFC822),
+			counts[thread.ID],
+			label,
+		)
+	}
+	return nil
+}
+
+// confirm asks before a bulk operation, unless it was forced
+func confirm(question string) (bool, error) {
+	if forceFlag {
+		return true, nil
+	}
+
+	output.Noticef("\n%s [y/N] ", question)
+	var response string
+	if _, err := fmt.Scanln(&response); err != nil {
+		return false, fmt.Errorf("failed to read input: %w", err)
+	}
+
+	response = strings.ToLower(strings.TrimSpace(response))
+	if response != "y" && response != "yes" {
+		output.Noticef("Operation cancelled\n")
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,234 @@
+package thread
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/citation"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/timestamp"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+// exportedThread is the file written for each exported thread
+type exportedThread struct {
+	ID         uuid.UUID         `json:"id"`
+	Title      string            `json:"title,omitempty"`
+	Summary    string            `json:"summary,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	ArchivedAt *time.Time        `json:"archivedAt,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	Messages   []exportedMessage `json:"messages"`
+}
+
+type exportedMessage struct {
+	ID           uuid.UUID            `json:"id"`
+	ParentID     *uuid.UUID           `json:"parentId,omitempty"`
+	Role         domain.Role          `json:"role"`
+	Content      string               `json:"content"`
+	Parts        []domain.MessagePart `json:"parts,omitempty"`
+	ToolCalls    json.RawMessage      `json:"toolCalls,omitempty"`
+	ModelName    string               `json:"modelName,omitempty"`
+	Provider     string               `json:"provider,omitempty"`
+	ModelVersion string               `json:"modelVersion,omitempty"`
+	CreatedAt    time.Time            `json:"createdAt"`
+	DurationMs   int64                `json:"durationMs,omitempty"` // Time the response took to generate
+	Footnotes    []footnote           `json:"footnotes,omitempty"`
+}
+
+// Stamp describes when the message was written and how long it took to generate
+func (m exportedMessage) Stamp() string {
+	stamp := timestamp.Format(m.CreatedAt, timestamp.Absolute)
+	if m.DurationMs > 0 {
+		stamp += " · " + timestamp.Elapsed(time.Duration(m.DurationMs)*time.Millisecond)
+	}
+	return stamp
+}
+
+// footnote resolves a citation of an earlier message. Footnotes are numbered across
+// the whole thread
+type footnote struct {
+	Number    int        `json:"number"`
+	Ref       string     `json:"ref"`
+	MessageID *uuid.UUID `json:"messageId,omitempty"` // Not set when the cited message is not in the thread
+	Excerpt   string     `json:"excerpt,omitempty"`
+}
+
+// maxExcerpt limits how much of a cited message is quoted in its footnote
+const maxExcerpt = 80
+
+var exportCmd = &cobra.Command{
+	Use:   "export [thread_id]",
+	Short: "Export threads as JSON or HTML files",
+	Long: `Export a thread, or use --all, --older-than or --tag to export several threads at once. Each thread
+is written to <thread_id>.json in the --out directory, or <thread_id>.html with --format html.
+HTML exports typeset LaTeX math with KaTeX unless renderMath is turned off.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		if formatFlag != "json" && formatFlag != "html" {
+			return fmt.Errorf("unsupported format %q, must be json or html", formatFlag)
+		}
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		var threads []*domain.Thread
+		if len(args) > 0 {
+			thread, err := repo.GetThreadByPartialID(cmd.Context(), args[0])
+			if err != nil {
+				return fmt.Errorf("failed to find thread: %w", err)
+			}
+			threads = []*domain.Thread{thread}
+		} else {
+			if !isBulk() {
+				return fmt.Errorf("give a thread ID or select threads with --all, --older-than or --tag")
+			}
+			threads, err = selectThreads(cmd.Context(), repo, archivedFlag)
+			if err != nil {
+				return err
+			}
+			if len(threads) == 0 {
+				output.Println("No threads matched")
+				return nil
+			}
+			if dryRunFlag {
+				return printSelection(cmd.Context(), repo, "export", threads)
+			}
+		}
+
+		ids := threadIDs(threads)
+		messages, err := repo.GetThreadsMessages(cmd.Context(), ids)
+		if err != nil {
+			return fmt.Errorf("failed to get messages: %w", err)
+		}
+		tags, err := repo.GetThreadTags(cmd.Context(), ids)
+		if err != nil {
+			return fmt.Errorf("failed to get tags: %w", err)
+		}
+
+		threadMessages := make(map[uuid.UUID][]domain.Message)
+		for _, msg := range messages {
+			threadMessages[msg.ThreadID] = append(threadMessages[msg.ThreadID], msg)
+		}
+		byThread := make(map[uuid.UUID][]exportedMessage)
+		for threadID, msgs := range threadMessages {
+			exported, err := exportMessages(msgs)
+			if err != nil {
+				return err
+			}
+			byThread[threadID] = exported
+		}
+
+		if err := os.MkdirAll(outFlag, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		for _, thread := range threads {
+			exported := exportedThread{
+				ID:         thread.ID,
+				Title:      thread.Title,
+				Summary:    thread.Summary,
+				CreatedAt:  thread.CreatedAt,
+				ArchivedAt: thread.ArchivedAt,
+				Tags:       tags[thread.ID],
+				Messages:   byThread[thread.ID],
+			}
+			var data []byte
+			if formatFlag == "html" {
+				data, err = renderHTML(exported, cfg.RenderMath)
+			} else {
+				data, err = json.MarshalIndent(exported, "", "  ")
+			}
+			if err != nil {
+				return fmt.Errorf("failed to encode thread %s: %w", thread.ID.String()[:8], err)
+			}
+			path := filepath.Join(outFlag, thread.ID.String()+"."+formatFlag)
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", path, err)
+			}
+		}
+
+		output.Printf("Exported %d threads to %s\n", len(threads), outFlag)
+		return nil
+	},
+}
+
+// exportMessages converts the messages of a thread and turns their citations into footnotes
+func exportMessages(messages []domain.Message) ([]exportedMessage, error) {
+	exported := make([]exportedMessage, len(messages))
+	number := 0
+	for i, msg := range messages {
+		var err error
+		exported[i], err = exportMessage(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ref := range citation.Find(msg.Content) {
+			number++
+			note := footnote{Number: number, Ref: msg.Content[ref.Start:ref.End]}
+			if cited, ok := citation.Resolve(ref.ID, messages); ok {
+				note.MessageID = &cited.ID
+				note.Excerpt = excerpt(cited.Content)
+			}
+			exported[i].Footnotes = append(exported[i].Footnotes, note)
+		}
+	}
+	return exported, nil
+}
+
+// excerpt returns the start of the first line of content
+func excerpt(content string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+	if len(line) > maxExcerpt {
+		line = line[:maxExcerpt-3] + "..."
+	}
+	return line
+}
+
+// exportMessage converts a stored message to its exported form
+func exportMessage(msg domain.Message) (exportedMessage, error) {
+	exported := exportedMessage{
+		ID:           msg.ID,
+		ParentID:     msg.ParentID,
+		Role:         msg.Role,
+		Content:      msg.Content,
+		ModelName:    msg.ModelName,
+		Provider:     msg.Provider,
+		ModelVersion: msg.ModelVersion,
+		CreatedAt:    msg.CreatedAt,
+	}
+	if msg.Parts != "" {
+		parts, err := msg.GetParts()
+		if err != nil {
+			return exportedMessage{}, err
+		}
+		exported.Parts = parts
+	}
+	if msg.ToolCalls != "" && json.Valid([]byte(msg.ToolCalls)) {
+		exported.ToolCalls = json.RawMessage(msg.ToolCalls)
+	}
+	if metadata, err := msg.GetMetadata(); err == nil {
+		exported.DurationMs = metadata.Duration.Milliseconds()
+	}
+	return exported, nil
+}
+
+func init() {
+	exportCmd.Flags().BoolVar(&allFlag, "all", false, "Export all threads")
+	exportCmd.Flags().BoolVar(&archivedFlag, "archived", false, "Select archived threads instead of active ones")
+	exportCmd.Flags().StringVarP(&outFlag, "out", "o", "", "Directory to write the exported threads to")
+	exportCmd.Flags().StringVar(&formatFlag, "format", "json", "File format (json, html)")
+	exportCmd.MarkFlagRequired("out")
+	addSelectionFlags(exportCmd)
+	ThreadCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,37 @@
+package thread
+
+import (
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/outtemplate"
+	"github.com/spf13/cobra"
+)
+
+// threadFields documents what --format-template sees for each thread
+const threadFields = `Fields: .ID .ShortID .Title .Summary .Label .Preview .CreatedAt .Archived .Tags
+.Messages (the number of messages) and .Unread`
+
+// messageFields documents what --format-template sees for each message
+const messageFields = `Fields: .ID .ShortID .ParentID .Role .Content .Model .Provider .ModelVersion
+.CreatedAt .Duration .InputTokens .OutputTokens and .ToolCalls with .ID .Name and
+.Arguments`
+
+// templateHelp explains --format-template after the fields of a command
+const templateHelp = `Functions: join truncate oneline json date ago and elapsed, such as
+{{join .Tags ","}}, {{.Content | oneline | truncate 80}}, {{.CreatedAt | date "2006-01-02"}}
+or {{json .}}. Templates in the outputTemplates config can be used by name, such as
+--format-template jsonl. Each result is printed on its own line.`
+
+// addFormatTemplateFlag adds --format-template to a command that prints one of what
+// to describe per thread or message
+func addFormatTemplateFlag(cmd *cobra.Command, what string) {
+	cmd.Flags().StringVar(&formatTemplateFlag, "format-template", "", "Print each "+what+" with a Go template such as '{{.Role}}: {{.Content}}', or the name of one in outputTemplates")
+}
+
+// formatTemplate returns the template given with --format-template, nil when the
+// output isn't templated
+func formatTemplate(cfg *config.ConfigSchema) (*outtemplate.Template, error) {
+	if formatTemplateFlag == "" {
+		return nil, nil
+	}
+	return outtemplate.Resolve(cfg.OutTemplates, formatTemplateFlag)
+}
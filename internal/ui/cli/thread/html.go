@@ -0,0 +1,95 @@
+package thread
+
+import (
+	"bytes"
+	"html/template"
+	"slices"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/mathtext"
+)
+
+// katexVersion is the KaTeX release loaded by HTML exports that contain math
+const katexVersion = "0.16.11"
+
+var htmlExport = template.Must(template.New("thread").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{with or .Thread.Title .Thread.Summary}}{{.}}{{else}}Thread {{.Thread.ID}}{{end}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; }
+.meta { color: #666; font-size: 0.875rem; }
+.message { margin: 1.5rem 0; }
+.role { font-weight: bold; }
+.content { white-space: pre-wrap; }
+.human .role { color: #2563eb; }
+.assistant .role { color: #16a34a; }
+.tool, .system { color: #555; }
+.footnotes { font-size: 0.875rem; color: #555; }
+</style>
+{{- if .Math}}
+<link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/katex@{{.KaTeX}}/dist/katex.min.css">
+<script defer src="https://cdn.jsdelivr.net/npm/katex@{{.KaTeX}}/dist/katex.min.js"></script>
+<script defer src="https://cdn.jsdelivr.net/npm/katex@{{.KaTeX}}/dist/contrib/auto-render.min.js"
+  onload="renderMathInElement(document.body, {delimiters: [
+    {left: '$$', right: '$$', display: true},
+    {left: '\\[', right: '\\]', display: true},
+    {left: '\\(', right: '\\)', display: false}
+  ], ignoredClasses: ['meta', 'footnotes']})"></script>
+{{- end}}
+</head>
+<body>
+<h1>{{with or .Thread.Title .Thread.Summary}}{{.}}{{else}}Thread {{.Thread.ID}}{{end}}</h1>
+<p class="meta">Created {{.Thread.CreatedAt.Format "2006-01-02 15:04"}}{{range .Thread.Tags}} · {{.}}{{end}}</p>
+{{- range .Thread.Messages}}
+<div class="message {{.Role}}" id="{{.ID}}">
+<div class="role">{{.Role}} <span class="meta">{{with .ModelName}}{{.}} · {{end}}{{.Stamp}}</span></div>
+{{- if .Parts}}
+{{- range .Parts}}
+<div class="content">{{.Content}}</div>
+{{- end}}
+{{- else}}
+<div class="content">{{.Content}}</div>
+{{- end}}
+{{- if .Footnotes}}
+<ol class="footnotes">
+{{- range .Footnotes}}
+<li value="{{.Number}}">{{.Ref}}{{if .MessageID}}: <a href="#{{.MessageID}}">{{.Excerpt}}</a>{{end}}</li>
+{{- end}}
+</ol>
+{{- end}}
+</div>
+{{- end}}
+</body>
+</html>
+`))
+
+// renderHTML writes a thread as a standalone page. KaTeX is only loaded when
+// renderMath is set and the thread contains math
+func renderHTML(thread exportedThread, renderMath bool) ([]byte, error) {
+	hasMath := false
+	if renderMath {
+		for _, msg := range thread.Messages {
+			if msg.Role != domain.RoleTool && mathtext.Contains(msg.Content) {
+				hasMath = true
+			}
+		}
+	}
+	if hasMath {
+		thread.Messages = slices.Clone(thread.Messages)
+		for i, msg := range thread.Messages {
+			if msg.Role != domain.RoleTool {
+				thread.Messages[i].Content = mathtext.Delimit(msg.Content)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	err := htmlExport.Execute(&buf, struct {
+		Thread exportedThread
+		Math   bool
+		KaTeX  string
+	}{thread, hasMath, katexVersion})
+	return buf.Bytes(), err
+}
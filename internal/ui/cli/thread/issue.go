@@ -0,0 +1,98 @@
+package thread
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/internalService"
+	"github.com/isaacphi/slop/internal/issue"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var toIssueCmd = &cobra.Command{
+	Use:   "to-issue <thread_id>",
+	Short: "Turn a thread into a GitHub or GitLab issue",
+	Long: `Have the internal model write an issue from a thread, show it and create it in
+the repository once confirmed. The issues config sets the tracker, the default
+repository and the API token. E.g. slop thread to-issue 1a2b3c4d --repo owner/name`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		ctx := cmd.Context()
+
+		repoName := repoFlag
+		if repoName == "" {
+			repoName = cfg.Issues.Repo
+		}
+		if repoName == "" {
+			return fmt.Errorf("no repository: pass --repo owner/name or set issues.repo")
+		}
+		// Check the token before spending a call to the internal model
+		var tracker *issue.Tracker
+		if !dryRunFlag {
+			var err error
+			if tracker, err = issue.New(cfg.Issues); err != nil {
+				return err
+			}
+		}
+
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+		thread, err := repo.GetThreadByPartialID(ctx, args[0])
+		if err != nil {
+			return fmt.Errorf("failed to find thread: %w", err)
+		}
+		messages, err := repo.GetMessages(ctx, thread.ID, nil, false)
+		if err != nil {
+			return fmt.Errorf("failed to get thread messages: %w", err)
+		}
+		if len(messages) == 0 {
+			return fmt.Errorf("thread %s has no messages", thread.ID.String()[:8])
+		}
+
+		internal, err := internal.NewForThread(cfg, thread)
+		if err != nil {
+			return fmt.Errorf("failed to initialize internal service: %w", err)
+		}
+		title, body, err := internal.WriteIssue(ctx, messages)
+		if err != nil {
+			return fmt.Errorf("failed to write issue: %w", err)
+		}
+		labels := slices.Concat(cfg.Issues.Labels, labelFlag)
+
+		output.Printf("Title: %s\n", title)
+		if len(labels) > 0 {
+			output.Printf("Labels: %s\n", strings.Join(labels, ", "))
+		}
+		output.Printf("\n%s\n", body)
+		if dryRunFlag {
+			return nil
+		}
+
+		ok, err := confirm(fmt.Sprintf("Create this issue in %s?", repoName))
+		if err != nil || !ok {
+			return err
+		}
+		url, err := tracker.Create(ctx, repoName, issue.Issue{Title: title, Body: body, Labels: labels})
+		if err != nil {
+			return err
+		}
+		output.Noticef("Created issue ")
+		fmt.Println(url)
+		return nil
+	},
+}
+
+func init() {
+	toIssueCmd.Flags().StringVar(&repoFlag, "repo", "", "Repository to create the issue in as owner/name (defaults to issues.repo)")
+	toIssueCmd.Flags().StringSliceVar(&labelFlag, "label", nil, "Label to add besides issues.labels, can be repeated")
+	toIssueCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Only show the issue without creating it")
+	toIssueCmd.Flags().BoolVarP(&forceFlag, "force", "f", false, "Create the issue without confirmation")
+	ThreadCmd.AddCommand(toIssueCmd)
+}
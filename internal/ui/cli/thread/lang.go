@@ -0,0 +1,60 @@
+package thread
+
+import (
+	"fmt"
+
+	"github.com/isaacphi/slop/internal/agent"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var setLangCmd = &cobra.Command{
+	Use:   "set-lang [thread_id] [language]",
+	Short: "Set the language replies in a thread are written in",
+	Long: `Set the language replies in a thread are written in, whatever language the messages are in.
+The language is a code such as fr or a name such as Brazilian Portuguese. Threads without a
+language of their own use style.language from the config. Use --off to go back to it.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if offFlag {
+			return cobra.ExactArgs(1)(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		thread, err := repo.GetThreadByPartialID(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to find thread: %w", err)
+		}
+
+		lang := ""
+		if !offFlag {
+			lang = args[1]
+		}
+		if err := repo.SetThreadLanguage(cmd.Context(), thread.ID, lang); err != nil {
+			return fmt.Errorf("failed to update thread: %w", err)
+		}
+
+		switch {
+		case lang != "":
+			output.Printf("Replies in thread %s will be in %s\n", thread.ID.String()[:8], agent.LanguageName(lang))
+		case cfg.Style.Language != "":
+			output.Printf("Replies in thread %s will be in the default language, %s\n", thread.ID.String()[:8], agent.LanguageName(cfg.Style.Language))
+		default:
+			output.Printf("Replies in thread %s are no longer kept to one language\n", thread.ID.String()[:8])
+		}
+		return nil
+	},
+}
+
+func init() {
+	setLangCmd.Flags().BoolVar(&offFlag, "off", false, "Use the default language from the config")
+	ThreadCmd.AddCommand(setLangCmd)
+}
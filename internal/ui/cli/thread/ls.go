@@ -3,31 +3,61 @@ package thread
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/outtemplate"
+	"github.com/isaacphi/slop/internal/repository"
 	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
 	"github.com/spf13/cobra"
 )
 
 var listCmd = &cobra.Command{
 	Use:   "ls",
 	Short: "List conversation threads",
+	Long: `List conversation threads. Scripts can shape the output with --format-template, which
+prints each thread with a Go template.
+
+` + threadFields + `
+
+` + templateHelp,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg := appState.Get().Config
+		tmpl, err := formatTemplate(cfg)
+		if err != nil {
+			return err
+		}
 		repo, err := sqlite.Initialize(cfg.DBPath)
 		if err != nil {
 			return err
 		}
 
-		threads, err := repo.ListThreads(cmd.Context(), limitFlag)
+		var threads []*domain.Thread
+		if archivedFlag {
+			threads, err = repo.FindThreads(cmd.Context(), repository.ThreadFilter{Archived: true})
+			if limitFlag > 0 && len(threads) > limitFlag {
+				threads = threads[:limitFlag]
+			}
+		} else {
+			threads, err = repo.ListThreads(cmd.Context(), limitFlag)
+		}
 		if err != nil {
 			return fmt.Errorf("failed to list threads: %w", err)
 		}
 
+		tags, err := repo.GetThreadTags(cmd.Context(), threadIDs(threads))
+		if err != nil {
+			return fmt.Errorf("failed to get tags: %w", err)
+		}
+
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "ID\tCreated\tMessages\tPreview")
+		if !output.Quiet() && tmpl == nil {
+			fmt.Fprintln(w, "ID\tCreated\tMessages\tUnread\tTags\tPreview")
+		}
 
 		for _, thread := range threads {
 			messages, err := repo.GetMessages(cmd.Context(), thread.ID, nil, false)
@@ -35,9 +65,35 @@ var listCmd = &cobra.Command{
 				return fmt.Errorf("failed to get messages: %w", err)
 			}
 
+			unread := thread.IsUnread(messages)
+			if unreadFlag && !unread {
+				continue
+			}
+			if tmpl != nil {
+				if err := tmpl.Execute(os.Stdout, outtemplate.NewThread(thread, messages, tags[thread.ID])); err != nil {
+					return fmt.Errorf("failed to format thread %s: %w", thread.ID.String()[:8], err)
+				}
+				continue
+			}
+			unreadStr := ""
+			if unread {
+				unreadStr = "*"
+			}
+
+			// Quiet mode only lists IDs so they can be passed to other commands
+			if output.Quiet() {
+				fmt.Fprintln(w, thread.ID.String()[:8])
+				continue
+			}
+
+			id := thread.ID.String()[:8]
+			if output.Verbose() {
+				id = thread.ID.String()
+			}
+
 			preview := "[empty]"
-			if thread.Summary != "" {
-				preview = thread.Summary
+			if thread.Label() != "" {
+				preview = thread.Label()
 			} else {
 				for _, msg := range messages {
 					if msg.Role == "human" {
@@ -50,10 +106,12 @@ var listCmd = &cobra.Command{
 				preview = preview[:47] + "..."
 			}
 
-			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n",
-				thread.ID.String()[:8],
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n",
+				id,
 				thread.CreatedAt.Format(time.RFC822),
 				len(messages),
+				unreadStr,
+				strings.Join(tags[thread.ID], ","),
 				preview,
 			)
 		}
@@ -65,5 +123,8 @@ var listCmd = &cobra.Command{
 
 func init() {
 	listCmd.Flags().IntVarP(&limitFlag, "limit", "n", 0, "Limit the number of threads to show (0 for all)")
+	listCmd.Flags().BoolVar(&archivedFlag, "archived", false, "List archived threads instead")
+	listCmd.Flags().BoolVar(&unreadFlag, "unread", false, "Only show threads with replies you haven't viewed")
+	addFormatTemplateFlag(listCmd, "thread")
 	ThreadCmd.AddCommand(listCmd)
 }
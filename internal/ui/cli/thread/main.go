@@ -5,8 +5,38 @@ import (
 )
 
 var (
-	limitFlag int
-	forceFlag bool
+	limitFlag    int
+	forceFlag    bool
+	unreadFlag   bool
+	draftsFlag   bool
+	offFlag      bool
+	archivedFlag bool
+	useFlag      string
+	resetFlag    bool
+	expandFlag   bool
+	modelFlag    bool
+
+	// Bulk operations
+	olderThanFlag string
+	tagFlag       string
+	dryRunFlag    bool
+	allFlag       bool
+	outFlag       string
+	formatFlag    string
+	restoreFlag   bool
+	removeFlag    bool
+
+	// Output
+	formatTemplateFlag string
+
+	// Pruning
+	deleteFlag   []int
+	compressFlag []int
+	suggestFlag  bool
+
+	// Issues
+	repoFlag  string
+	labelFlag []string
 )
 
 var ThreadCmd = &cobra.Command{
@@ -0,0 +1,95 @@
+package thread
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/internalService"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/transcript"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var pasteCmd = &cobra.Command{
+	Use:   "paste",
+	Short: "Create a thread from a conversation copied from a chat interface",
+	Long: `Read a conversation from stdin and create a thread from it that can be continued with
+msg send or chat. The conversation is split into turns at speaker labels such as
+"User:", "Assistant:", "**Claude:**" or "ChatGPT said:". Conversations without
+labels are split by the internal model, use --model to always split with it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		if stat, _ := os.Stdin.Stat(); stat.Mode()&os.ModeCharDevice != 0 {
+			output.Noticef("Paste the conversation, then press Ctrl-D\n")
+		}
+		text, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read conversation: %w", err)
+		}
+		if strings.TrimSpace(string(text)) == "" {
+			return fmt.Errorf("no conversation to paste")
+		}
+
+		var messages []domain.Message
+		if !modelFlag {
+			messages, err = transcript.Split(string(text))
+			if err != nil && !errors.Is(err, transcript.ErrNoSpeakers) {
+				return err
+			}
+		}
+		if len(messages) == 0 {
+			output.Verbosef("Splitting the conversation with the internal model\n")
+			internal, err := internal.NewInternalService(cfg)
+			if err != nil {
+				return fmt.Errorf("failed to initialize internal service: %w", err)
+			}
+			messages, err = internal.SplitTranscript(cmd.Context(), string(text))
+			if err != nil {
+				return fmt.Errorf("failed to split conversation: %w", err)
+			}
+			if len(messages) == 0 {
+				return fmt.Errorf("no turns found in the conversation")
+			}
+		}
+
+		thread := &domain.Thread{}
+		if err := repo.CreateThread(cmd.Context(), thread); err != nil {
+			return fmt.Errorf("failed to create thread: %w", err)
+		}
+		// Spread the messages out so they keep their order when sorted by time
+		start := time.Now().Add(-time.Duration(len(messages)) * time.Millisecond)
+		var parentID *uuid.UUID
+		for i := range messages {
+			msg := &messages[i]
+			msg.ParentID = parentID
+			msg.CreatedAt = start.Add(time.Duration(i) * time.Millisecond)
+			if err := repo.AddMessageToThread(cmd.Context(), thread.ID, msg); err != nil {
+				return fmt.Errorf("failed to add message: %w", err)
+			}
+			parentID = &msg.ID
+		}
+
+		output.Printf("Created thread %s with %d messages\n", thread.ID.String()[:8], len(messages))
+		output.Noticef("Continue it with: slop msg send --thread %s\n", thread.ID.String()[:8])
+		return nil
+	},
+}
+
+func init() {
+	ThreadCmd.AddCommand(pasteCmd)
+	pasteCmd.Flags().BoolVar(&modelFlag, "model", false, "Split the conversation with the internal model instead of at speaker labels")
+}
@@ -0,0 +1,57 @@
+package thread
+
+import (
+	"fmt"
+
+	"github.com/isaacphi/slop/internal/agent"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var pinCmd = &cobra.Command{
+	Use:   "pin [thread_id]",
+	Short: "Pin a thread to the model version that has been replying",
+	Long: `Pin a thread to the exact model version that has been replying in it. If the provider replies
+with a different version, for example after silently upgrading an alias, sending fails instead of
+continuing with the new model. Threads without replies are pinned to the version of their first reply.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		thread, err := repo.GetThreadByPartialID(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to find thread: %w", err)
+		}
+
+		if err := repo.SetThreadPinModel(cmd.Context(), thread.ID, !offFlag); err != nil {
+			return fmt.Errorf("failed to update thread: %w", err)
+		}
+
+		if offFlag {
+			output.Printf("Thread %s is no longer pinned to a model\n", thread.ID.String()[:8])
+			return nil
+		}
+
+		messages, err := repo.GetMessages(cmd.Context(), thread.ID, nil, false)
+		if err != nil {
+			return fmt.Errorf("failed to get messages: %w", err)
+		}
+		if model := agent.PinnedModel(messages); model != "" {
+			output.Printf("Thread %s pinned to model %s\n", thread.ID.String()[:8], model)
+		} else {
+			output.Printf("Thread %s will be pinned to the model of its first reply\n", thread.ID.String()[:8])
+		}
+		return nil
+	},
+}
+
+func init() {
+	pinCmd.Flags().BoolVar(&offFlag, "off", false, "Unpin the thread")
+	ThreadCmd.AddCommand(pinCmd)
+}
@@ -0,0 +1,54 @@
+package thread
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/privacy"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var privacyCmd = &cobra.Command{
+	Use:   "privacy [thread_id] [level]",
+	Short: "Show or set what a thread may send off this machine",
+	Long: `Show or set the privacy level of a thread. Leave [level] blank to show the current level.
+  normal      messages are sent to the thread's preset as they are
+  local-only  messages are only sent to local providers such as ollama, sending fails otherwise
+  redacted    secrets and email addresses are masked in everything sent for the thread
+The level also applies to summaries, titles, compression and fallbacks of the thread.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		thread, err := repo.GetThreadByPartialID(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to find thread: %w", err)
+		}
+
+		if len(args) == 1 {
+			output.Printf("Thread %s is %s\n", thread.ID.String()[:8], privacy.Level(thread))
+			return nil
+		}
+
+		level := args[1]
+		if !privacy.Valid(level) {
+			return fmt.Errorf("invalid privacy level %q, must be one of %s", level, strings.Join(privacy.Levels, ", "))
+		}
+		if err := repo.SetThreadPrivacy(cmd.Context(), thread.ID, level); err != nil {
+			return fmt.Errorf("failed to update thread: %w", err)
+		}
+		output.Printf("Thread %s is now %s\n", thread.ID.String()[:8], level)
+		return nil
+	},
+}
+
+func init() {
+	ThreadCmd.AddCommand(privacyCmd)
+}
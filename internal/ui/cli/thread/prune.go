@@ -0,0 +1,282 @@
+package thread
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/artifact"
+	"github.com/isaacphi/slop/internal/compress"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/internalService"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+// prunableBranch is the part of a branch that no other branch shares, from the
+// message after the fork it split off at down to its last message
+type prunableBranch struct {
+	messages []domain.Message // Oldest first
+	fork     *uuid.UUID       // Message the branch split off at, nil if it starts the thread
+	active   bool
+}
+
+// last is the message the branch ends at
+func (b prunableBranch) last() domain.Message {
+	return b.messages[len(b.messages)-1]
+}
+
+// size is the number of bytes of content in the branch
+func (b prunableBranch) size() int {
+	total := 0
+	for _, msg := range b.messages {
+		total += len(msg.Content) + len(msg.ToolCalls)
+	}
+	return total
+}
+
+// deadEnd reports whether the branch stops without a final response, such as a
+// message that was never answered or a tool call that was never finished
+func (b prunableBranch) deadEnd() bool {
+	last := b.last()
+	return last.Role != domain.RoleAssistant || last.ToolCalls != ""
+}
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune [thread_id]",
+	Short: "Delete or compress branches of a thread",
+	Long: `List the branches of a thread with their sizes and dead ends, then choose branches to
+delete or compress. Only the messages a branch doesn't share with other branches are
+affected. The active branch can be compressed but not deleted.
+
+Branches are chosen at a prompt, or with --delete and --compress. Use --suggest to have
+the internal model point out what is safe to remove.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		thread, err := repo.GetThreadByPartialID(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to find thread: %w", err)
+		}
+		messages, err := repo.GetThreadsMessages(cmd.Context(), []uuid.UUID{thread.ID})
+		if err != nil {
+			return fmt.Errorf("failed to get thread messages: %w", err)
+		}
+		active, err := repo.GetMessages(cmd.Context(), thread.ID, nil, false)
+		if err != nil {
+			return fmt.Errorf("failed to get thread messages: %w", err)
+		}
+		branches := prunableBranches(messages, active)
+		if len(branches) == 0 {
+			output.Println("Thread has no messages")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tLast\tMessages\tSize\tForked At\tPreview")
+		for i, branch := range branches {
+			number := strconv.Itoa(i + 1)
+			if branch.active {
+				number += "*"
+			}
+			fork := "start"
+			if branch.fork != nil {
+				fork = branch.fork.String()[:8]
+			}
+			preview := branchPreview(branch.last())
+			if branch.deadEnd() {
+				preview = "(dead end) " + preview
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n",
+				number,
+				branch.last().ID.String()[:8],
+				len(branch.messages),
+				artifact.FormatSize(branch.size()),
+				fork,
+				preview,
+			)
+		}
+		w.Flush()
+
+		if suggestFlag {
+			internal, err := internal.NewForThread(cfg, thread)
+			if err != nil {
+				return fmt.Errorf("failed to initialize internal service: %w", err)
+			}
+			suggestion, err := internal.SuggestPrune(cmd.Context(), describeBranches(branches))
+			if err != nil {
+				return fmt.Errorf("failed to get suggestion: %w", err)
+			}
+			output.Printf("\nSuggestion:\n%s\n", strings.TrimSpace(suggestion))
+		}
+
+		toDelete, toCompress := deleteFlag, compressFlag
+		if len(toDelete) == 0 && len(toCompress) == 0 {
+			if stat, _ := os.Stdin.Stat(); stat.Mode()&os.ModeCharDevice == 0 {
+				return nil
+			}
+			reader := bufio.NewReader(os.Stdin)
+			if toDelete, err = askBranches(reader, "Branches to delete"); err != nil {
+				return err
+			}
+			if toCompress, err = askBranches(reader, "Branches to compress"); err != nil {
+				return err
+			}
+			// Branches were just chosen at the prompt, there is no need to confirm them
+			forceFlag = true
+		}
+		if len(toDelete) == 0 && len(toCompress) == 0 {
+			return nil
+		}
+
+		for _, n := range slices.Concat(toDelete, toCompress) {
+			if n < 1 || n > len(branches) {
+				return fmt.Errorf("no branch %d, choose from 1 to %d", n, len(branches))
+			}
+		}
+		for _, n := range toDelete {
+			if branches[n-1].active {
+				return fmt.Errorf("branch %d is the active branch, make another branch active with thread branch --use first", n)
+			}
+		}
+		if ok, err := confirm(fmt.Sprintf("Delete %d and compress %d branches?", len(toDelete), len(toCompress))); err != nil || !ok {
+			return err
+		}
+
+		var ids []uuid.UUID
+		for _, n := range toDelete {
+			for _, msg := range branches[n-1].messages {
+				ids = append(ids, msg.ID)
+			}
+		}
+		if err := repo.DeleteMessages(cmd.Context(), ids); err != nil {
+			return fmt.Errorf("failed to delete branches: %w", err)
+		}
+
+		saved := 0
+		for _, n := range toCompress {
+			if slices.Contains(toDelete, n) {
+				continue
+			}
+			for _, msg := range branches[n-1].messages {
+				// Messages with parts are sent from their parts, not their content
+				if msg.Role == domain.RoleSystem || msg.Parts != "" {
+					continue
+				}
+				compressed, err := compress.Heuristic{}.Compress(cmd.Context(), msg.Content)
+				if err != nil || len(compressed) >= len(msg.Content) {
+					continue
+				}
+				saved += len(msg.Content) - len(compressed)
+				msg.Content = compressed
+				if err := repo.UpdateMessageContent(cmd.Context(), &msg); err != nil {
+					return fmt.Errorf("failed to compress message %s: %w", msg.ID.String()[:8], err)
+				}
+			}
+		}
+
+		output.Printf("Deleted %d messages and saved %s by compressing\n", len(ids), artifact.FormatSize(saved))
+		return nil
+	},
+}
+
+// prunableBranches splits the messages of a thread into one branch for each message
+// nothing replies to. active is the branch shown by default
+func prunableBranches(messages []domain.Message, active []domain.Message) []prunableBranch {
+	byID := make(map[uuid.UUID]domain.Message)
+	replies := make(map[uuid.UUID]int)
+	for _, msg := range messages {
+		byID[msg.ID] = msg
+		if msg.ParentID != nil {
+			replies[*msg.ParentID]++
+		}
+	}
+	onActive := make(map[uuid.UUID]bool)
+	for _, msg := range active {
+		onActive[msg.ID] = true
+	}
+
+	var branches []prunableBranch
+	for _, msg := range messages {
+		if replies[msg.ID] > 0 {
+			continue
+		}
+		branch := prunableBranch{messages: []domain.Message{msg}}
+		for current := msg; current.ParentID != nil; {
+			parent, ok := byID[*current.ParentID]
+			if !ok {
+				break
+			}
+			if replies[parent.ID] > 1 {
+				branch.fork = &parent.ID
+				break
+			}
+			branch.messages = append([]domain.Message{parent}, branch.messages...)
+			current = parent
+		}
+		for _, m := range branch.messages {
+			if onActive[m.ID] {
+				branch.active = true
+				break
+			}
+		}
+		branches = append(branches, branch)
+	}
+	return branches
+}
+
+// describeBranches lists the branches for the internal model with how each starts and ends
+func describeBranches(branches []prunableBranch) string {
+	var b strings.Builder
+	for i, branch := range branches {
+		fmt.Fprintf(&b, "Branch %d: %d messages, %s", i+1, len(branch.messages), artifact.FormatSize(branch.size()))
+		if branch.active {
+			b.WriteString(", active so it can't be deleted")
+		}
+		if branch.deadEnd() {
+			b.WriteString(", dead end")
+		}
+		fmt.Fprintf(&b, "\n  Starts: %s\n  Ends: %s\n", branchPreview(branch.messages[0]), branchPreview(branch.last()))
+	}
+	return b.String()
+}
+
+// askBranches reads the numbers of branches separated by commas or spaces
+func askBranches(reader *bufio.Reader, question string) ([]int, error) {
+	output.Noticef("%s (numbers, Enter for none): ", question)
+	line, err := reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	var numbers []int
+	for _, field := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' }) {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid branch number %q", field)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+func init() {
+	pruneCmd.Flags().IntSliceVar(&deleteFlag, "delete", nil, "Numbers of the branches to delete")
+	pruneCmd.Flags().IntSliceVar(&compressFlag, "compress", nil, "Numbers of the branches to compress")
+	pruneCmd.Flags().BoolVar(&suggestFlag, "suggest", false, "Ask the internal model which branches are safe to remove")
+	pruneCmd.Flags().BoolVarP(&forceFlag, "force", "f", false, "Prune without confirmation")
+	ThreadCmd.AddCommand(pruneCmd)
+}
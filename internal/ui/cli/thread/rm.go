@@ -7,13 +7,15 @@ import (
 
 	"github.com/isaacphi/slop/internal/appState"
 	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
 	"github.com/spf13/cobra"
 )
 
 var deleteCmd = &cobra.Command{
 	Use:   "rm [thread_id]",
 	Short: "Delete a thread and all its messages",
-	Args:  cobra.ExactArgs(1),
+	Long:  "Delete a thread and all its messages. Use --older-than or --tag instead of a thread ID to delete several threads at once.",
+	Args:  cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg := appState.Get().Config
 		repo, err := sqlite.Initialize(cfg.DBPath)
@@ -21,6 +23,36 @@ var deleteCmd = &cobra.Command{
 			return err
 		}
 
+		if len(args) == 0 {
+			if !isBulk() {
+				return fmt.Errorf("give a thread ID or select threads with --older-than or --tag")
+			}
+
+			threads, err := selectThreads(cmd.Context(), repo, archivedFlag)
+			if err != nil {
+				return err
+			}
+			if len(threads) == 0 {
+				output.Println("No threads matched")
+				return nil
+			}
+			if err := printSelection(cmd.Context(), repo, "delete", threads); err != nil {
+				return err
+			}
+			if dryRunFlag {
+				return nil
+			}
+			if ok, err := confirm(fmt.Sprintf("Delete these %d threads?", len(threads))); err != nil || !ok {
+				return err
+			}
+
+			if err := repo.DeleteThreads(cmd.Context(), threadIDs(threads)); err != nil {
+				return fmt.Errorf("failed to delete threads: %w", err)
+			}
+			output.Printf("Deleted %d threads\n", len(threads))
+			return nil
+		}
+
 		// Find thread by partial ID
 		thread, err := repo.GetThreadByPartialID(cmd.Context(), args[0])
 		if err != nil {
@@ -34,8 +66,8 @@ var deleteCmd = &cobra.Command{
 		}
 
 		preview := "[empty]"
-		if thread.Summary != "" {
-			preview = thread.Summary
+		if thread.Label() != "" {
+			preview = thread.Label()
 		} else {
 			for _, msg := range messages {
 				if msg.Role == "human" {
@@ -48,13 +80,13 @@ var deleteCmd = &cobra.Command{
 			preview = preview[:47] + "..."
 		}
 
-		fmt.Printf("About to delete thread %s:\n", thread.ID.String()[:8])
-		fmt.Printf("Created: %s\n", thread.CreatedAt.Format(time.RFC822))
-		fmt.Printf("Messages: %d\n", len(messages))
-		fmt.Printf("Preview: %s\n", preview)
+		output.Printf("About to delete thread %s:\n", thread.ID.String()[:8])
+		output.Printf("Created: %s\n", thread.CreatedAt.Format(time.RFC822))
+		output.Printf("Messages: %d\n", len(messages))
+		output.Printf("Preview: %s\n", preview)
 
 		if !forceFlag {
-			fmt.Print("\nAre you sure you want to delete this thread? [y/N] ")
+			output.Noticef("\nAre you sure you want to delete this thread %s? [y/N] ", thread.ID.String()[:8])
 			var response string
 			_, err := fmt.Scanln(&response)
 			if err != nil {
@@ -63,7 +95,7 @@ var deleteCmd = &cobra.Command{
 
 			response = strings.ToLower(strings.TrimSpace(response))
 			if response != "y" && response != "yes" {
-				fmt.Println("Operation cancelled")
+				output.Noticef("Operation cancelled\n")
 				return nil
 			}
 		}
@@ -72,12 +104,14 @@ var deleteCmd = &cobra.Command{
 			return fmt.Errorf("failed to delete thread: %w", err)
 		}
 
-		fmt.Println("Thread deleted successfully")
+		output.Println("Thread deleted successfully")
 		return nil
 	},
 }
 
 func init() {
 	deleteCmd.Flags().BoolVarP(&forceFlag, "force", "f", false, "Delete without confirmation")
+	deleteCmd.Flags().BoolVar(&archivedFlag, "archived", false, "Select archived threads instead of active ones")
+	addSelectionFlags(deleteCmd)
 	ThreadCmd.AddCommand(deleteCmd)
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/isaacphi/slop/internal/appState"
 	"github.com/isaacphi/slop/internal/internalService"
 	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
 	"github.com/spf13/cobra"
 )
 
@@ -38,7 +39,7 @@ var summaryCmd = &cobra.Command{
 			if err != nil {
 				return fmt.Errorf("failed to get thread messages: %w", err)
 			}
-			internal, err := internal.NewInternalService(cfg)
+			internal, err := internal.NewForThread(cfg, thread)
 			if err != nil {
 				return fmt.Errorf("failed to initialize internal service: %w", err)
 			}
@@ -51,7 +52,7 @@ var summaryCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to set thread summary: %w", err)
 		}
-		fmt.Println("Thread summary updated successfully")
+		output.Println("Thread summary updated successfully")
 		return nil
 	},
 }
@@ -0,0 +1,62 @@
+package thread
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag [thread_id] [tags...]",
+	Short: "Add or remove tags on a thread",
+	Long:  "Add tags to a thread, or remove them with --remove. With only a thread ID, the thread's tags are shown.",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		thread, err := repo.GetThreadByPartialID(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to find thread: %w", err)
+		}
+
+		tags := args[1:]
+		for _, tag := range tags {
+			if strings.TrimSpace(tag) == "" {
+				return fmt.Errorf("tags can't be empty")
+			}
+		}
+
+		if removeFlag {
+			err = repo.RemoveThreadTags(cmd.Context(), thread.ID, tags)
+		} else {
+			err = repo.AddThreadTags(cmd.Context(), thread.ID, tags)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update tags: %w", err)
+		}
+
+		current, err := repo.GetThreadTags(cmd.Context(), threadIDs([]*domain.Thread{thread}))
+		if err != nil {
+			return fmt.Errorf("failed to get tags: %w", err)
+		}
+		if len(current[thread.ID]) == 0 {
+			fmt.Printf("Thread %s has no tags\n", thread.ID.String()[:8])
+			return nil
+		}
+		fmt.Printf("Thread %s tags: %s\n", thread.ID.String()[:8], strings.Join(current[thread.ID], ", "))
+		return nil
+	},
+}
+
+func init() {
+	tagCmd.Flags().BoolVar(&removeFlag, "remove", false, "Remove the tags instead of adding them")
+	ThreadCmd.AddCommand(tagCmd)
+}
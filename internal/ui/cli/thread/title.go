@@ -0,0 +1,68 @@
+package thread
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/internalService"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/spf13/cobra"
+)
+
+var titleCmd = &cobra.Command{
+	Use:   "title [thread_id] [title]",
+	Short: "Set the title of a thread",
+	Long: `Set the title shown for a thread in lists. Leave [title] blank to have the internal model write a
+title, along with a summary when the thread has none. New threads get one after their first exchange
+unless internal.autoTitle is off`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		thread, err := repo.GetThreadByPartialID(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to find thread: %w", err)
+		}
+
+		title := strings.Join(args[1:], " ")
+		if title == "" {
+			messages, err := repo.GetMessages(cmd.Context(), thread.ID, nil, false)
+			if err != nil {
+				return fmt.Errorf("failed to get thread messages: %w", err)
+			}
+			if len(messages) == 0 {
+				return fmt.Errorf("thread %s has no messages to title", thread.ID.String()[:8])
+			}
+			internal, err := internal.NewForThread(cfg, thread)
+			if err != nil {
+				return fmt.Errorf("failed to initialize internal service: %w", err)
+			}
+			var summary string
+			title, summary, err = internal.CreateThreadTitle(cmd.Context(), messages)
+			if err != nil {
+				return fmt.Errorf("failed to generate title: %w", err)
+			}
+			if thread.Summary == "" && summary != "" {
+				if err := repo.SetThreadSummary(cmd.Context(), thread.ID, summary); err != nil {
+					return fmt.Errorf("failed to set thread summary: %w", err)
+				}
+			}
+		}
+
+		if err := repo.SetThreadTitle(cmd.Context(), thread.ID, title); err != nil {
+			return fmt.Errorf("failed to set thread title: %w", err)
+		}
+		output.Printf("Thread %s is titled %q\n", thread.ID.String()[:8], title)
+		return nil
+	},
+}
+
+func init() {
+	ThreadCmd.AddCommand(titleCmd)
+}
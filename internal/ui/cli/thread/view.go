@@ -1,21 +1,46 @@
 package thread
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/agent"
 	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/artifact"
+	"github.com/isaacphi/slop/internal/config"
 	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/llm"
+	"github.com/isaacphi/slop/internal/mathtext"
+	"github.com/isaacphi/slop/internal/outtemplate"
+	"github.com/isaacphi/slop/internal/privacy"
 	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/timestamp"
+	"github.com/isaacphi/slop/internal/toolview"
+	"github.com/isaacphi/slop/internal/ui/cli/output"
+	"github.com/isaacphi/slop/internal/usage"
 	"github.com/spf13/cobra"
 )
 
 var viewCmd = &cobra.Command{
 	Use:   "view [thread_id]",
 	Short: "View messages in a thread",
-	Args:  cobra.ExactArgs(1),
+	Long: `View the messages in a thread. Scripts can shape the output with --format-template, which
+prints each message with a Go template instead of the thread's details and messages.
+
+` + messageFields + `
+
+` + templateHelp,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg := appState.Get().Config
+		tmpl, err := formatTemplate(cfg)
+		if err != nil {
+			return err
+		}
 		repo, err := sqlite.Initialize(cfg.DBPath)
 		if err != nil {
 			return err
@@ -31,28 +56,199 @@ var viewCmd = &cobra.Command{
 			return fmt.Errorf("failed to get thread messages: %w", err)
 		}
 
-		fmt.Printf("Thread %s (created %s)\n\n",
+		if limitFlag > 0 && len(messages) > limitFlag {
+			messages = messages[len(messages)-limitFlag:]
+		}
+		if cfg.RenderMath {
+			for i, msg := range messages {
+				if msg.Role == domain.RoleAssistant {
+					messages[i].Content = mathtext.ToUnicode(msg.Content)
+				}
+			}
+		}
+
+		if tmpl != nil {
+			for _, msg := range messages {
+				if err := tmpl.Execute(os.Stdout, outtemplate.NewMessage(msg)); err != nil {
+					return fmt.Errorf("failed to format message %s: %w", msg.ID.String()[:8], err)
+				}
+			}
+			return repo.MarkThreadRead(cmd.Context(), thread.ID)
+		}
+
+		// Quiet mode only prints the latest reply
+		if output.Quiet() {
+			for i := len(messages) - 1; i >= 0; i-- {
+				if messages[i].Role == domain.RoleAssistant {
+					fmt.Println(messages[i].Content)
+					break
+				}
+			}
+			return repo.MarkThreadRead(cmd.Context(), thread.ID)
+		}
+
+		fmt.Printf("Thread %s (created %s)\n",
 			thread.ID.String()[:8],
 			thread.CreatedAt.Format(time.RFC822),
 		)
+		if thread.PinModel {
+			if model := agent.PinnedModel(messages); model != "" {
+				fmt.Printf("Pinned to model %s\n", model)
+			} else {
+				fmt.Println("Pinned to the model of its first reply")
+			}
+		}
+		if thread.Language != "" {
+			fmt.Printf("Replies in %s\n", agent.LanguageName(thread.Language))
+		}
+		if level := privacy.Level(thread); level != domain.PrivacyNormal {
+			fmt.Printf("Privacy %s\n", level)
+		}
+		var total usage.Tokens
+		for _, msg := range messages {
+			total.Add(msg, cfg.Presets)
+		}
+		if total.InputTokens+total.OutputTokens > 0 {
+			fmt.Printf("Used %s\n", total)
+		}
+		fmt.Println()
 
-		if limitFlag > 0 && len(messages) > limitFlag {
-			messages = messages[len(messages)-limitFlag:]
+		queued, err := repo.ListQueuedMessages(cmd.Context(), &thread.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get queued messages: %w", err)
+		}
+		pending := make(map[uuid.UUID]bool)
+		for _, entry := range queued {
+			pending[entry.MessageID] = true
+		}
+
+		byID := make(map[uuid.UUID]domain.Message, len(messages))
+		ids := make([]uuid.UUID, len(messages))
+		for i, msg := range messages {
+			byID[msg.ID] = msg
+			ids[i] = msg.ID
+		}
+		attachments, err := repo.GetAttachments(cmd.Context(), ids)
+		if err != nil {
+			return fmt.Errorf("failed to get attachments: %w", err)
 		}
 
 		for _, msg := range messages {
 			roleStr := "You"
-			if msg.Role == domain.RoleAssistant {
+			switch msg.Role {
+			case domain.RoleAssistant:
 				roleStr = "Slop"
+			case domain.RoleTool:
+				roleStr = "Tools"
+			}
+			if pending[msg.ID] {
+				roleStr += " (pending)"
+			}
+
+			metadata, err := msg.GetMetadata()
+			if err != nil {
+				return err
+			}
+			if metadata.Draft != "" {
+				if draftsFlag {
+					fmt.Printf("%s - %s (draft): %s\n", msg.ID.String()[:8], roleStr, metadata.Draft)
+				}
+				roleStr += " (revised)"
+			}
+			if metadata.Partial {
+				roleStr += " (timed out)"
+			}
+			if metadata.Recovered {
+				roleStr += " (reconnected)"
+			}
+			if metadata.Incomplete {
+				roleStr += " (incomplete)"
+			}
+			roleStr += " · " + timestamp.Label(msg, cfg.TimeFormat)
+
+			printMessageDetails(msg, attachments[msg.ID], cfg.Presets)
+
+			// Tool results are summarized a line per call unless expanded
+			if msg.Role == domain.RoleTool && msg.ParentID != nil {
+				if calls, ok := toolview.Calls(byID[*msg.ParentID], msg); ok {
+					fmt.Printf("%s - %s:\n%s\n", msg.ID.String()[:8], roleStr, toolview.Render(calls, expandFlag))
+					continue
+				}
+			}
+
+			if msg.Parts == "" {
+				fmt.Printf("%s - %s: %s\n", msg.ID.String()[:8], roleStr, msg.Content)
+				if metadata.Confidence != nil {
+					output.Footer(metadata.Confidence.Footer())
+				}
+				continue
+			}
+
+			parts, err := msg.GetParts()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s - %s:\n", msg.ID.String()[:8], roleStr)
+			for i, part := range parts {
+				label := fmt.Sprintf("[part %d/%d]", i+1, len(parts))
+				if part.Source != "" {
+					label = fmt.Sprintf("[part %d/%d: %s]", i+1, len(parts), part.Source)
+				}
+				fmt.Printf("%s\n%s\n", label, part.Content)
 			}
-			fmt.Printf("%s - %s: %s\n", msg.ID.String()[:8], roleStr, msg.Content)
+		}
+
+		if err := repo.MarkThreadRead(cmd.Context(), thread.ID); err != nil {
+			return fmt.Errorf("failed to mark thread as read: %w", err)
 		}
 
 		return nil
 	},
 }
 
+// printMessageDetails shows when and how a message was produced in verbose mode
+func printMessageDetails(msg domain.Message, attachments []domain.Attachment, presets map[string]config.Preset) {
+	if !output.Verbose() {
+		return
+	}
+
+	details := []string{msg.CreatedAt.Format(time.RFC3339)}
+	if msg.ModelName != "" {
+		model := fmt.Sprintf("%s %s", msg.Provider, msg.ModelName)
+		if msg.ModelVersion != "" && msg.ModelVersion != msg.ModelName {
+			model += fmt.Sprintf(" (%s)", msg.ModelVersion)
+		}
+		if metadata, err := msg.GetMetadata(); err == nil && metadata.OncePreset != "" {
+			model += fmt.Sprintf(" for this reply only with preset %s", metadata.OncePreset)
+		}
+		details = append(details, model)
+	}
+	if msg.InputTokens+msg.OutputTokens > 0 {
+		tokens := usage.Tokens{InputTokens: msg.InputTokens, OutputTokens: msg.OutputTokens, Cost: usage.MessageCost(msg, presets)}
+		details = append(details, tokens.String())
+	}
+	if msg.ToolCalls != "" {
+		var toolCalls []llm.ToolCall
+		if err := json.Unmarshal([]byte(msg.ToolCalls), &toolCalls); err == nil {
+			for _, call := range toolCalls {
+				details = append(details, fmt.Sprintf("tool call %s %s", call.Name, call.Arguments))
+			}
+		}
+	}
+	for _, attachment := range attachments {
+		if attachment.Hash == "" {
+			details = append(details, "linked "+attachment.Name)
+			continue
+		}
+		details = append(details, fmt.Sprintf("attached %s %s", attachment.Name, artifact.FormatSize(attachment.Size)))
+	}
+	output.Verbosef("[%s: %s]\n", msg.ID.String()[:8], strings.Join(details, ", "))
+}
+
 func init() {
 	viewCmd.Flags().IntVarP(&limitFlag, "limit", "n", 0, "Limit the number of messages to show (0 for all)")
+	viewCmd.Flags().BoolVar(&draftsFlag, "drafts", false, "Also show the drafts of responses revised by the reflection pass")
+	viewCmd.Flags().BoolVarP(&expandFlag, "expand", "x", false, "Show the full arguments and results of tool calls")
+	addFormatTemplateFlag(viewCmd, "message")
 	ThreadCmd.AddCommand(viewCmd)
 }
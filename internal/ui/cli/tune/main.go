@@ -0,0 +1,211 @@
+package tune
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/llm"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/spf13/cobra"
+)
+
+var (
+	presetFlag       string
+	promptFlag       string
+	temperaturesFlag []float64
+	maxTokensFlag    []int
+	repeatsFlag      int
+)
+
+// cell is one combination of generation settings in the sweep
+type cell struct {
+	temperature float64
+	maxTokens   int
+	thread      *domain.Thread
+	outputs     []string
+	latency     time.Duration
+	errors      int
+}
+
+var TuneCmd = &cobra.Command{
+	Use:   "tune",
+	Short: "Compare generation settings for a preset",
+	Long: `Send the same prompt with every combination of the given temperatures and max tokens, repeated
+--repeats times. The outputs of each combination are stored in their own thread, and a table
+comparing them is printed when the sweep finishes.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		presetName := cfg.DefaultPreset
+		if presetFlag != "" {
+			presetName = presetFlag
+		}
+		preset, ok := cfg.Presets[presetName]
+		if !ok {
+			return fmt.Errorf("model %s not found in configuration", presetName)
+		}
+
+		if repeatsFlag < 1 {
+			return fmt.Errorf("--repeats must be at least 1")
+		}
+
+		prompt, err := os.ReadFile(promptFlag)
+		if err != nil {
+			return fmt.Errorf("failed to read prompt: %w", err)
+		}
+		content := strings.TrimSpace(string(prompt))
+		if content == "" {
+			return fmt.Errorf("prompt %s is empty", promptFlag)
+		}
+
+		temperatures := temperaturesFlag
+		if len(temperatures) == 0 {
+			temperatures = []float64{preset.Temperature}
+		}
+		maxTokens := maxTokensFlag
+		if len(maxTokens) == 0 {
+			maxTokens = []int{preset.MaxTokens}
+		}
+
+		var systemMessage *domain.Message
+		if preset.SystemMessage != "" {
+			systemMessage = &domain.Message{Role: domain.RoleSystem, Content: preset.SystemMessage}
+		}
+
+		var cells []*cell
+		for _, temperature := range temperatures {
+			for _, tokens := range maxTokens {
+				cells = append(cells, &cell{temperature: temperature, maxTokens: tokens})
+			}
+		}
+
+		for i, c := range cells {
+			c.thread = &domain.Thread{}
+			if err := repo.CreateThread(cmd.Context(), c.thread); err != nil {
+				return fmt.Errorf("failed to create thread: %w", err)
+			}
+			summary := fmt.Sprintf("tune %s temperature=%g maxTokens=%d", presetName, c.temperature, c.maxTokens)
+			if err := repo.SetThreadSummary(cmd.Context(), c.thread.ID, summary); err != nil {
+				return fmt.Errorf("failed to set thread summary: %w", err)
+			}
+
+			cellPreset := preset
+			cellPreset.Temperature = c.temperature
+			cellPreset.MaxTokens = c.maxTokens
+
+			// Each repeat is generated without history so the runs are independent,
+			// they are chained in the thread so all of them show up in thread view
+			var previous *domain.Message
+			for repeat := range repeatsFlag {
+				fmt.Fprintf(os.Stderr, "[%d/%d] temperature=%g maxTokens=%d run %d/%d\n",
+					i+1, len(cells), c.temperature, c.maxTokens, repeat+1, repeatsFlag)
+
+				humanMsg := &domain.Message{Role: domain.RoleHuman, Content: content}
+				if previous != nil {
+					humanMsg.ParentID = &previous.ID
+				}
+				if err := repo.AddMessageToThread(cmd.Context(), c.thread.ID, humanMsg); err != nil {
+					return fmt.Errorf("failed to add message to thread: %w", err)
+				}
+
+				resp, latency, err := generate(cmd.Context(), cellPreset, systemMessage, content)
+				output := resp.TextResponse
+				if err != nil {
+					if cmd.Context().Err() != nil {
+						return cmd.Context().Err()
+					}
+					c.errors++
+					output = fmt.Sprintf("[error: %s]", err)
+				} else {
+					c.outputs = append(c.outputs, output)
+					c.latency += latency
+				}
+
+				aiMsg := &domain.Message{
+					Role:         domain.RoleAssistant,
+					ParentID:     &humanMsg.ID,
+					Content:      output,
+					ModelName:    cellPreset.Name,
+					Provider:     cellPreset.Provider,
+					ModelVersion: resp.Model,
+				}
+				if err := repo.AddMessageToThread(cmd.Context(), c.thread.ID, aiMsg); err != nil {
+					return fmt.Errorf("failed to add message to thread: %w", err)
+				}
+				previous = aiMsg
+			}
+		}
+
+		printTable(cells)
+		return nil
+	},
+}
+
+// generate sends the prompt with the given settings and times the response
+func generate(ctx context.Context, preset config.Preset, systemMessage *domain.Message, content string) (llm.MessageResponse, time.Duration, error) {
+	start := time.Now()
+	resp, err := llm.GenerateContent(ctx, llm.GenerateContentOptions{
+		Preset:        preset,
+		Content:       content,
+		SystemMessage: systemMessage,
+	})
+	if err != nil {
+		return llm.MessageResponse{}, 0, err
+	}
+	return resp, time.Since(start), nil
+}
+
+// printTable compares the outputs of each cell
+func printTable(cells []*cell) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Temperature\tMax Tokens\tThread\tRuns\tErrors\tDistinct\tAvg Length\tAvg Latency")
+
+	for _, c := range cells {
+		distinct := make(map[string]bool)
+		totalLength := 0
+		for _, output := range c.outputs {
+			distinct[output] = true
+			totalLength += len(output)
+		}
+
+		avgLength := "-"
+		avgLatency := "-"
+		if len(c.outputs) > 0 {
+			avgLength = fmt.Sprintf("%d", totalLength/len(c.outputs))
+			avgLatency = (c.latency / time.Duration(len(c.outputs))).Round(time.Millisecond).String()
+		}
+
+		fmt.Fprintf(w, "%g\t%d\t%s\t%d\t%d\t%d\t%s\t%s\n",
+			c.temperature,
+			c.maxTokens,
+			c.thread.ID.String()[:8],
+			len(c.outputs)+c.errors,
+			c.errors,
+			len(distinct),
+			avgLength,
+			avgLatency,
+		)
+	}
+	w.Flush()
+}
+
+func init() {
+	TuneCmd.Flags().StringVar(&presetFlag, "preset", "", "Preset to tune (defaults to the default preset)")
+	TuneCmd.Flags().StringVar(&promptFlag, "prompt", "", "File containing the prompt to send")
+	TuneCmd.Flags().Float64SliceVar(&temperaturesFlag, "temperatures", nil, "Comma separated temperatures to try (defaults to the preset's temperature)")
+	TuneCmd.Flags().IntSliceVar(&maxTokensFlag, "max-tokens", nil, "Comma separated max token limits to try (defaults to the preset's max tokens)")
+	TuneCmd.Flags().IntVar(&repeatsFlag, "repeats", 1, "Number of times to send the prompt with each combination")
+	TuneCmd.MarkFlagRequired("prompt")
+}
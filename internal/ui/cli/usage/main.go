@@ -0,0 +1,10 @@
+package usage
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var UsageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report on slop usage",
+}
@@ -0,0 +1,93 @@
+package usage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	monthFlag  string
+	formatFlag string
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Export a monthly usage report",
+	Long:  "Aggregate messages, tool executions, tokens and their cost for a month, broken down by preset. E.g. slop usage report --month 2025-05 --format csv",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+
+		if formatFlag != "csv" && formatFlag != "json" {
+			return fmt.Errorf("unsupported format %q, must be csv or json", formatFlag)
+		}
+
+		month := monthFlag
+		if month == "" {
+			month = time.Now().Format("2006-01")
+		}
+		start, end, err := usage.MonthRange(month)
+		if err != nil {
+			return err
+		}
+
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		messages, err := repo.GetMessagesInRange(cmd.Context(), start, end)
+		if err != nil {
+			return fmt.Errorf("failed to get messages: %w", err)
+		}
+
+		report := usage.BuildReport(month, messages, cfg.Presets)
+
+		if formatFlag == "json" {
+			out, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to format report: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		w := csv.NewWriter(os.Stdout)
+		if err := w.Write([]string{"month", "preset", "provider", "model", "threads", "messages", "toolExecutions", "inputTokens", "outputTokens", "cost"}); err != nil {
+			return err
+		}
+		for _, row := range append(report.Presets, report.Totals) {
+			if err := w.Write([]string{
+				report.Month,
+				row.Preset,
+				row.Provider,
+				row.Model,
+				strconv.Itoa(row.Threads),
+				strconv.Itoa(row.Messages),
+				strconv.Itoa(row.ToolExecutions),
+				strconv.Itoa(row.InputTokens),
+				strconv.Itoa(row.OutputTokens),
+				strconv.FormatFloat(row.Cost, 'f', 4, 64),
+			}); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	},
+}
+
+func init() {
+	reportCmd.Flags().StringVar(&monthFlag, "month", "", "Month to report on in YYYY-MM format (defaults to the current month)")
+	reportCmd.Flags().StringVar(&formatFlag, "format", "csv", "Output format (csv, json)")
+	UsageCmd.AddCommand(reportCmd)
+}
@@ -0,0 +1,114 @@
+package usage
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/appState"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository/sqlite"
+	"github.com/isaacphi/slop/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	byFlag   string
+	daysFlag int
+)
+
+var tokensCmd = &cobra.Command{
+	Use:   "tokens",
+	Short: "Show tokens used and their cost",
+	Long:  "Add up the tokens providers reported for replies and what they cost at the presets' prices, by day, thread or message. E.g. slop usage tokens --by thread --days 7",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := appState.Get().Config
+
+		if byFlag != "day" && byFlag != "thread" && byFlag != "message" {
+			return fmt.Errorf("unsupported grouping %q, must be day, thread or message", byFlag)
+		}
+		if daysFlag <= 0 {
+			return fmt.Errorf("--days must be positive")
+		}
+
+		repo, err := sqlite.Initialize(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		start := time.Date(now.Year(), now.Month(), now.Day()-daysFlag+1, 0, 0, 0, 0, time.Local)
+		messages, err := repo.GetMessagesInRange(cmd.Context(), start, now.Add(time.Second))
+		if err != nil {
+			return fmt.Errorf("failed to get messages: %w", err)
+		}
+
+		// Rows are kept in the order they were first seen, which is by time
+		var keys []string
+		rows := make(map[string]*usage.Tokens)
+		threads := make(map[string]uuid.UUID)
+		var total usage.Tokens
+		for _, msg := range messages {
+			if msg.Role != domain.RoleAssistant {
+				continue
+			}
+			var key string
+			switch byFlag {
+			case "day":
+				key = msg.CreatedAt.Local().Format(time.DateOnly)
+			case "thread":
+				key = msg.ThreadID.String()[:8]
+				threads[key] = msg.ThreadID
+			case "message":
+				key = fmt.Sprintf("%s %s", msg.CreatedAt.Local().Format("2006-01-02 15:04"), msg.ID.String()[:8])
+			}
+			row, ok := rows[key]
+			if !ok {
+				row = &usage.Tokens{}
+				rows[key] = row
+				keys = append(keys, key)
+			}
+			row.Add(msg, cfg.Presets)
+			total.Add(msg, cfg.Presets)
+		}
+		if byFlag == "thread" {
+			// Most expensive threads first
+			sort.SliceStable(keys, func(i, j int) bool {
+				return rows[keys[i]].Cost > rows[keys[j]].Cost
+			})
+		}
+
+		if len(keys) == 0 {
+			fmt.Printf("No replies in the last %d days\n", daysFlag)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "%s\tREPLIES\tINPUT\tOUTPUT\tCOST\n", map[string]string{"day": "DAY", "thread": "THREAD", "message": "MESSAGE"}[byFlag])
+		for _, key := range keys {
+			label := key
+			if byFlag == "thread" {
+				if thread, err := repo.GetThread(cmd.Context(), threads[key]); err == nil && thread.Label() != "" {
+					label = fmt.Sprintf("%s %s", key, thread.Label())
+				}
+			}
+			writeTokensRow(w, label, *rows[key])
+		}
+		writeTokensRow(w, "total", total)
+		return w.Flush()
+	},
+}
+
+func writeTokensRow(w *tabwriter.Writer, label string, t usage.Tokens) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t$%.4f\n", label, t.Messages, t.InputTokens, t.OutputTokens, t.Cost)
+}
+
+func init() {
+	tokensCmd.Flags().StringVar(&byFlag, "by", "day", "Group by day, thread or message")
+	tokensCmd.Flags().IntVar(&daysFlag, "days", 30, "Number of days to include, counting today")
+	UsageCmd.AddCommand(tokensCmd)
+}
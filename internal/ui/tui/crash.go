@@ -0,0 +1,180 @@
+package tui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/ui/tui/screens/chat"
+)
+
+const (
+	// maxCrashEvents is how many of the latest events a crash report lists
+	maxCrashEvents = 50
+	// sessionFile holds the chat session of a TUI that crashed, until it is resumed
+	sessionFile = "tui-session.json"
+)
+
+// CrashedSession is the chat session left behind by a TUI that crashed
+type CrashedSession struct {
+	chat.Session
+	Report    string    `json:"report"` // Path of the crash report
+	CrashedAt time.Time `json:"crashedAt"`
+}
+
+// LoadCrashedSession returns the session saved in dir when the TUI last crashed, nil
+// if it didn't. The session is removed so it is only offered once
+func LoadCrashedSession(dir string) (*CrashedSession, error) {
+	path := filepath.Join(dir, sessionFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the crashed session: %w", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return nil, fmt.Errorf("failed to remove the crashed session: %w", err)
+	}
+
+	var session CrashedSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to decode the crashed session: %w", err)
+	}
+	return &session, nil
+}
+
+// crashGuard runs the TUI model and recovers from panics in it. A panic ends the
+// program normally so the terminal is restored, and is recorded with the latest
+// events and the chat session so it can be resumed
+type crashGuard struct {
+	Model
+	state *crashState
+}
+
+// crashState is shared by every copy of the guard
+type crashState struct {
+	mu     sync.Mutex
+	dir    string // Where crash reports and the session are written
+	events []string
+	latest Model // Model after the last update that didn't panic
+	quit   func()
+
+	report string // Path of the crash report once there was a crash
+	err    error  // What the crash was
+}
+
+func newCrashGuard(m Model, dir string) crashGuard {
+	return crashGuard{Model: m, state: &crashState{dir: dir, latest: m}}
+}
+
+// Update records the event and updates the model, quitting if it panics
+func (g crashGuard) Update(msg tea.Msg) (result tea.Model, cmd tea.Cmd) {
+	g.state.record(msg)
+	defer func() {
+		if r := recover(); r != nil {
+			g.state.crashed(r, debug.Stack())
+			result, cmd = g, tea.Quit
+		}
+	}()
+
+	updated, cmd := g.Model.Update(msg)
+	g.Model = updated.(Model)
+	g.state.mu.Lock()
+	g.state.latest = g.Model
+	g.state.mu.Unlock()
+	return g, cmd
+}
+
+// View renders the model. A panic while rendering quits the program
+func (g crashGuard) View() (view string) {
+	defer func() {
+		if r := recover(); r != nil {
+			g.state.crashed(r, debug.Stack())
+			view = ""
+			// The program can't be told to quit from inside View
+			go g.state.quit()
+		}
+	}()
+	return g.Model.View()
+}
+
+// record keeps a description of an event for the crash report. Typed text is left out
+// so drafts don't end up in reports
+func (s *crashState) record(msg tea.Msg) {
+	var event string
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		event = "key " + msg.String()
+		if msg.Type == tea.KeyRunes {
+			event = fmt.Sprintf("key (%d characters typed)", len(msg.Runes))
+		}
+	case tea.WindowSizeMsg:
+		event = fmt.Sprintf("resize %dx%d", msg.Width, msg.Height)
+	default:
+		event = fmt.Sprintf("%T", msg)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, time.Now().Format("15:04:05.000")+" "+event)
+	if len(s.events) > maxCrashEvents {
+		s.events = s.events[len(s.events)-maxCrashEvents:]
+	}
+}
+
+// crashed writes the crash report and saves the chat session of the last good model
+func (s *crashState) crashed(r any, stack []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	s.err = fmt.Errorf("%v", r)
+
+	now := time.Now()
+	var b strings.Builder
+	fmt.Fprintf(&b, "slop chat crashed at %s\n\npanic: %v\n\n%s\n", now.Format(time.RFC3339), r, stack)
+	fmt.Fprintf(&b, "Last %d events:\n", len(s.events))
+	for _, event := range s.events {
+		fmt.Fprintf(&b, "  %s\n", event)
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return
+	}
+	report := filepath.Join(s.dir, fmt.Sprintf("crash-%s.log", now.Format("20060102-150405")))
+	if err := os.WriteFile(report, []byte(b.String()), 0644); err != nil {
+		return
+	}
+	s.report = report
+
+	session := CrashedSession{Session: s.latest.chatScreen.Session(), Report: report, CrashedAt: now}
+	if session.ThreadID == uuid.Nil && session.Draft == "" {
+		return
+	}
+	if encoded, err := json.Marshal(session); err == nil {
+		_ = os.WriteFile(filepath.Join(s.dir, sessionFile), encoded, 0600)
+	}
+}
+
+// result describes how the program ended, nil if it didn't crash
+func (s *crashState) result() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		return nil
+	}
+	if s.report == "" {
+		return fmt.Errorf("the TUI crashed: %w", s.err)
+	}
+	return fmt.Errorf("the TUI crashed: %w. A report was written to %s, run slop chat again to resume where you were", s.err, s.report)
+}
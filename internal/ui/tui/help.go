@@ -1,35 +1,101 @@
 package tui
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/isaacphi/slop/internal/config"
 	"github.com/isaacphi/slop/internal/ui/tui/keymap"
 )
 
-// ShortHelp returns keybindings for the mini help view
-func (m Model) ShortHelp() []key.Binding {
-	km := m.GetKeyMap()
-	return km.Groups[keymap.SystemGroup]
+// helpHint is the line below the screens pointing to the help overlay
+func (m Model) helpHint() string {
+	keys := m.keyMap.GetKeys(config.KeyActionToggleHelp)
+	if m.mode == keymap.InputMode {
+		keys = m.keyMap.GetKeys(config.KeyActionExitInput)
+		if len(keys) == 0 {
+			return ""
+		}
+		return m.theme.MutedText().Render(keys[0] + " exit input mode")
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	return m.theme.MutedText().Render(keys[0] + " help")
 }
 
-// FullHelp returns keybindings organized by their groups
-func (m Model) FullHelp() [][]key.Binding {
+// screenName is the name of the screen or pane that keys currently go to
+func (m Model) screenName() string {
+	switch {
+	case m.currentScreen == HomeScreen:
+		return "Home"
+	case m.splitActive() && m.threadList.Focused():
+		return "Thread list"
+	}
+	return "Chat"
+}
+
+// helpOverlay lists the bindings of the current screen and mode by group. Custom
+// bindings are shown since the list is built from the same keymap as the key handling
+func (m Model) helpOverlay() string {
 	keyMap := m.GetKeyMap()
 
-	// Convert the groups map into a slice of columns
-	var result [][]key.Binding
+	mode := "normal mode"
+	if m.mode == keymap.InputMode {
+		mode = "input mode"
+	}
+	title := m.theme.Title().Render(fmt.Sprintf("Keys: %s, %s", m.screenName(), mode))
 
-	// Add groups in the order you want them displayed
-	// For each group that exists, add it as a column
-	for _, groupID := range []int{keymap.SystemGroup, keymap.NavigationGroup, keymap.ActionGroup} {
-		if bindings, exists := keyMap.Groups[groupID]; exists && len(bindings) > 0 {
-			result = append(result, bindings)
+	var columns []string
+	for _, group := range keymap.GroupOrder {
+		bindings := keyMap.Groups[group]
+		if len(bindings) == 0 {
+			continue
 		}
+		columns = append(columns, m.helpColumn(keymap.GroupName(group), bindings))
+	}
+
+	// Groups are side by side when they fit and stacked otherwise
+	body := lipgloss.JoinHorizontal(lipgloss.Top, spaced(columns, "    ")...)
+	if lipgloss.Width(body) > m.width-4 {
+		body = lipgloss.JoinVertical(lipgloss.Left, spaced(columns, "")...)
 	}
 
-	// If no groups had bindings, return empty result
-	if len(result) == 0 {
-		return [][]key.Binding{}
+	closeKeys := append(m.keyMap.GetKeys(config.KeyActionToggleHelp), "esc")
+	footer := m.theme.MutedText().Render(strings.Join(closeKeys, " or ") + " to close")
+
+	return m.theme.Panel().
+		Padding(0, 1).
+		Render(lipgloss.JoinVertical(lipgloss.Left, title, "", body, "", footer))
+}
+
+// helpColumn renders a group heading with a line for each binding
+func (m Model) helpColumn(heading string, bindings []key.Binding) string {
+	keys := make([]string, len(bindings))
+	width := 0
+	for i, binding := range bindings {
+		keys[i] = strings.Join(binding.Keys(), "/")
+		width = max(width, lipgloss.Width(keys[i]))
 	}
 
+	keyStyle := lipgloss.NewStyle().Foreground(m.theme.Accent).Width(width + 2)
+	lines := []string{lipgloss.NewStyle().Bold(true).Render(heading)}
+	for i, binding := range bindings {
+		lines = append(lines, keyStyle.Render(keys[i])+binding.Help().Desc)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+// spaced puts a gap between columns, or a blank line between stacked groups
+func spaced(columns []string, gap string) []string {
+	var result []string
+	for i, column := range columns {
+		if i > 0 {
+			result = append(result, gap)
+		}
+		result = append(result, column)
+	}
 	return result
 }
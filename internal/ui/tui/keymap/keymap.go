@@ -37,6 +37,22 @@ const (
 	ActionGroup
 )
 
+// GroupOrder is the order groups are listed in help
+var GroupOrder = []int{SystemGroup, NavigationGroup, ActionGroup}
+
+// GroupName is the heading of a group in help
+func GroupName(group int) string {
+	switch group {
+	case SystemGroup:
+		return "General"
+	case NavigationGroup:
+		return "Navigation"
+	case ActionGroup:
+		return "Actions"
+	}
+	return "Other"
+}
+
 // AddAction adds an action with its keys to the keymap
 func (k *KeyMap) AddAction(group int, actionName string, helpText string) {
 	keyList := k.userKeyMap.GetKeys(actionName)
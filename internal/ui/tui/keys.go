@@ -18,12 +18,22 @@ func (m Model) GetKeyMap() keymap.KeyMap {
 		keyMap.AddAction(keymap.NavigationGroup, config.KeyActionSwitchChat, "switch to chat")
 		keyMap.AddAction(keymap.NavigationGroup, config.KeyActionSwitchHome, "switch to home")
 
+		if m.splitActive() {
+			keyMap.AddAction(keymap.NavigationGroup, config.KeyActionFocusPane, "switch pane")
+			keyMap.AddAction(keymap.NavigationGroup, config.KeyActionGrowSplit, "widen thread list")
+			keyMap.AddAction(keymap.NavigationGroup, config.KeyActionShrinkSplit, "narrow thread list")
+		}
+
 		// Add keys from the current screen
 		switch m.currentScreen {
 		case HomeScreen:
 			keyMap.Merge(m.homeScreen.GetKeyMap())
 		case ChatScreen:
-			keyMap.Merge(m.chatScreen.GetKeyMap())
+			if m.splitActive() && m.threadList.Focused() {
+				keyMap.Merge(m.threadList.GetKeyMap())
+			} else {
+				keyMap.Merge(m.chatScreen.GetKeyMap())
+			}
 		}
 	} else if mode == keymap.InputMode {
 		// Handle input mode keys
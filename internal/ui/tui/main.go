@@ -3,25 +3,37 @@ package tui
 import (
 	"fmt"
 
-	"github.com/charmbracelet/bubbles/help"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/agent"
 	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/repository"
 	"github.com/isaacphi/slop/internal/ui/tui/keymap"
 	"github.com/isaacphi/slop/internal/ui/tui/screens/chat"
 	"github.com/isaacphi/slop/internal/ui/tui/screens/home"
+	"github.com/isaacphi/slop/internal/ui/tui/screens/threads"
+	"github.com/isaacphi/slop/internal/ui/tui/theme"
 )
 
 // Model represents the application state
 type Model struct {
 	currentScreen ScreenType
-	help          help.Model
+	showHelp      bool // Show the help overlay in place of the current screen
 	width         int
 	height        int
 	mode          keymap.AppMode
 	homeScreen    home.Model
 	chatScreen    chat.Model
+	threadList    threads.Model
+	splitWidth    int // Width of the thread list in split view
+	repo          repository.MessageRepository
+	agent         *agent.Agent // Sends the messages typed in the chat
 	keyMap        *config.KeyMap
+	theme         theme.Theme
+	warnings      []string
+	project       string        // Name of the project whose config is in use, empty outside of projects
+	resume        *chat.Session // Session the TUI was started to resume, if any
 }
 
 type ScreenType int
@@ -31,18 +43,58 @@ const (
 	ChatScreen
 )
 
-// StartTUI initializes and runs the TUI
-func StartTUI(keyMap *config.KeyMap) error {
-	p := tea.NewProgram(Model{
-		help:          help.New(),
+const (
+	// The chat is shown next to the thread list when the terminal is at least this wide
+	minSplitWidth = 100
+	// Starting width of the thread list in split view
+	defaultSplitWidth = 32
+	// Neither pane is made narrower than this when resizing the split
+	minPaneWidth = 20
+	// How much the split moves per key press
+	splitStep = 4
+	// Lines below the screens for the help hint
+	helpHeight = 1
+)
+
+// StartTUI initializes and runs the TUI. Config warnings are shown on the home
+// screen and counted in the status bar. Threads are read from repo for the home
+// screen and the split view, and messages typed in the chat are sent through
+// agentService. The chat estimates its token usage against preset, shows the preset
+// named presetName with its toolsets in the info panel, and edits its input with vim
+// keys when vimMode is set. LaTeX math in responses is shown as Unicode when
+// renderMath is set, and message times in timeFormat. The project whose config is in
+// use is named in the status bar. A crash is reported in crashDir along with the chat
+// session, which can be passed back as resume to open the chat where it was left
+func StartTUI(keyMap *config.KeyMap, t theme.Theme, warnings []string, repo repository.MessageRepository, agentService *agent.Agent, presetName string, preset config.Preset, toolsets map[string]config.Toolset, vimMode, renderMath bool, timeFormat string, project string, crashDir string, resume *chat.Session) error {
+	m := Model{
 		currentScreen: HomeScreen,
 		mode:          keymap.NormalMode,
-		homeScreen:    home.New(keyMap),
-		chatScreen:    chat.New(keyMap),
+		homeScreen:    home.New(keyMap, t, warnings),
+		chatScreen:    chat.New(keyMap, t, presetName, preset, toolsets, vimMode, renderMath, timeFormat),
+		threadList:    threads.New(keyMap, t),
+		splitWidth:    defaultSplitWidth,
+		repo:          repo,
+		agent:         agentService,
 		keyMap:        keyMap,
-	}, tea.WithAltScreen())
+		theme:         t,
+		warnings:      warnings,
+		project:       project,
+		resume:        resume,
+	}
+	if resume != nil {
+		m.currentScreen = ChatScreen
+		m.chatScreen.Restore(*resume)
+	}
+
+	guard := newCrashGuard(m, crashDir)
+	p := tea.NewProgram(guard, tea.WithAltScreen())
+	guard.state.quit = p.Quit
 
-	if _, err := p.Run(); err != nil {
+	_, err := p.Run()
+	if crashErr := guard.state.result(); crashErr != nil {
+		return crashErr
+	}
+	if err != nil {
 		return fmt.Errorf("error running TUI: %w", err)
 	}
 	return nil
@@ -50,7 +102,10 @@ func StartTUI(keyMap *config.KeyMap) error {
 
 // Init initializes the TUI
 func (m Model) Init() tea.Cmd {
-	return nil
+	if m.resume != nil && m.resume.ThreadID != uuid.Nil {
+		return tea.Batch(threads.Load(m.repo), chat.LoadThread(m.repo, m.resume.ThreadID))
+	}
+	return threads.Load(m.repo)
 }
 
 // Update handles updates to the TUI
@@ -61,6 +116,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		// Always handle ctrl-c
 		if msg.Type == tea.KeyCtrlC {
+			m.chatScreen.CancelTurn()
 			return m, tea.Quit
 		}
 
@@ -83,28 +139,59 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		keyMap := m.GetKeyMap()
 		keyStr := msg.String()
 
+		// The help overlay takes all keys until it is closed
+		if m.showHelp {
+			switch action := keyMap.KeyToActionMap[keyStr]; {
+			case action == config.KeyActionQuit:
+				return m, tea.Quit
+			case action == config.KeyActionToggleHelp || msg.Type == tea.KeyEsc:
+				m.showHelp = false
+			}
+			return m, nil
+		}
+
 		// Handle hotkeys
 		if action, exists := keyMap.KeyToActionMap[keyStr]; exists {
 			switch action {
 			case config.KeyActionQuit:
+				m.chatScreen.CancelTurn()
 				return m, tea.Quit
 
 			case config.KeyActionToggleHelp:
-				m.help.ShowAll = !m.help.ShowAll
-				return m, func() tea.Msg {
-					return tea.WindowSizeMsg{
-						Width:  m.width,
-						Height: m.height,
-					}
-				}
+				m.showHelp = true
+				return m, nil
 
 			case config.KeyActionSwitchChat:
 				m.currentScreen = ChatScreen
-				return m, nil
+				return m, m.resize()
 
 			case config.KeyActionSwitchHome:
+				// Leaving the chat stops the reply it is waiting for
+				m.chatScreen.CancelTurn()
 				m.currentScreen = HomeScreen
-				return m, nil
+				return m, m.resize()
+
+			case config.KeyActionFocusPane:
+				if m.splitActive() {
+					if m.threadList.Focused() {
+						m.threadList.Blur()
+					} else {
+						m.threadList.Focus()
+					}
+					return m, nil
+				}
+
+			case config.KeyActionGrowSplit:
+				if m.splitActive() {
+					m.splitWidth = m.listWidth() + splitStep
+					return m, m.resize()
+				}
+
+			case config.KeyActionShrinkSplit:
+				if m.splitActive() {
+					m.splitWidth = m.listWidth() - splitStep
+					return m, m.resize()
+				}
 			}
 		}
 
@@ -116,6 +203,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, cmd)
 
 		case ChatScreen:
+			if m.splitActive() && m.threadList.Focused() {
+				newList, cmd := m.threadList.Update(msg)
+				m.threadList = newList
+				cmds = append(cmds, cmd)
+				break
+			}
 			newChat, cmd := m.chatScreen.Update(msg)
 			m.chatScreen = newChat
 			cmds = append(cmds, cmd)
@@ -133,41 +226,122 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return m, nil
 
+	case threads.LoadedMsg:
+		m.threadList, _ = m.threadList.Update(msg)
+		m.homeScreen, _ = m.homeScreen.Update(msg)
+
+	case threads.SelectedMsg:
+		// Hand focus to the chat so the opened thread can be read right away
+		m.threadList.Blur()
+		m.currentScreen = ChatScreen
+		return m, tea.Batch(m.resize(), chat.LoadThread(m.repo, msg.ThreadID))
+
+	case threads.NewThreadMsg:
+		return m, threads.Create(m.repo)
+
+	case threads.CreatedMsg:
+		if msg.Err != nil {
+			m.threadList, _ = m.threadList.Update(msg)
+			m.homeScreen, _ = m.homeScreen.Update(msg)
+			return m, nil
+		}
+		m.threadList.Blur()
+		m.currentScreen = ChatScreen
+		return m, tea.Batch(m.resize(), threads.Load(m.repo), chat.LoadThread(m.repo, msg.ThreadID))
+
+	case threads.DeleteMsg:
+		return m, threads.Delete(m.repo, msg.ThreadID)
+
+	case threads.DeletedMsg:
+		m.threadList, _ = m.threadList.Update(msg)
+		m.homeScreen, _ = m.homeScreen.Update(msg)
+		if msg.Err != nil {
+			return m, nil
+		}
+		m.chatScreen.CloseThread(msg.ThreadID)
+		return m, threads.Load(m.repo)
+
+	case chat.ThreadLoadedMsg:
+		m.chatScreen, _ = m.chatScreen.Update(msg)
+		if msg.Err == nil {
+			// Opening the thread marked it as read
+			return m, threads.Load(m.repo)
+		}
+
+	case chat.SendMsg:
+		return m, chat.Send(m.repo, m.agent, msg)
+
+	case chat.TurnStartedMsg, chat.StreamChunkMsg, chat.StreamRetryMsg, chat.StreamApprovalMsg, chat.StreamErrorMsg:
+		newChat, cmd := m.chatScreen.Update(msg)
+		m.chatScreen = newChat
+		cmds = append(cmds, cmd)
+
+	case chat.StreamDoneMsg:
+		// A new thread shows up in the list once its reply is stored, and the reply may
+		// have titled the thread
+		newChat, cmd := m.chatScreen.Update(msg)
+		m.chatScreen = newChat
+		cmds = append(cmds, cmd, threads.Load(m.repo))
+
+	case chat.TurnFinishedMsg:
+		// Show the reply as it was stored, with its tool calls and times
+		return m, chat.LoadThread(m.repo, msg.ThreadID)
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		cmds = append(cmds, m.resize())
+	}
 
-		helpHeight := m.getHelpHeight()
+	return m, tea.Batch(cmds...)
+}
 
-		// Create a new WindowSizeMsg with adjusted height for the content area
-		contentSizeMsg := tea.WindowSizeMsg{
-			Width:  msg.Width - 2,
-			Height: msg.Height - helpHeight - 2,
-		}
+// splitActive reports whether the thread list is shown next to the chat
+func (m Model) splitActive() bool {
+	return m.currentScreen == ChatScreen && m.width >= minSplitWidth
+}
 
-		// Pass the adjusted size message to the screens
-		homeScreen, cmd1 := m.homeScreen.Update(contentSizeMsg)
-		m.homeScreen = homeScreen
-		cmds = append(cmds, cmd1)
+// listWidth is the width of the thread list in split view, keeping both panes
+// at least minPaneWidth wide
+func (m Model) listWidth() int {
+	return max(minPaneWidth, min(m.splitWidth, m.width-2-minPaneWidth-1))
+}
 
-		chatScreen, cmd2 := m.chatScreen.Update(contentSizeMsg)
-		m.chatScreen = chatScreen
-		cmds = append(cmds, cmd2)
+// resize passes the size of the content area to the screens, splitting it between
+// the thread list and the chat in split view
+func (m *Model) resize() tea.Cmd {
+	// Adjust the height for the content area
+	contentSizeMsg := tea.WindowSizeMsg{
+		Width:  m.width - 2,
+		Height: m.height - helpHeight - 2,
 	}
 
-	return m, tea.Batch(cmds...)
+	homeScreen, cmd1 := m.homeScreen.Update(contentSizeMsg)
+	m.homeScreen = homeScreen
+
+	chatSizeMsg := contentSizeMsg
+	if m.splitActive() {
+		listWidth := m.listWidth()
+		// One column is taken by the border between the panes
+		chatSizeMsg.Width = contentSizeMsg.Width - listWidth - 1
+		m.threadList, _ = m.threadList.Update(tea.WindowSizeMsg{Width: listWidth, Height: contentSizeMsg.Height})
+	} else {
+		// Fall back to the full screen chat
+		m.threadList.Blur()
+	}
+
+	chatScreen, cmd2 := m.chatScreen.Update(chatSizeMsg)
+	m.chatScreen = chatScreen
+
+	return tea.Batch(cmd1, cmd2)
 }
 
 // View renders the TUI
 func (m Model) View() string {
-	helpHeight := m.getHelpHeight()
-
 	bodyStyle := lipgloss.NewStyle().
 		Width(m.width).
 		Height(m.height - helpHeight)
 
-	helpStyle := lipgloss.NewStyle()
-
 	var body string
 
 	switch m.currentScreen {
@@ -175,25 +349,46 @@ func (m Model) View() string {
 		body = m.homeScreen.View()
 	case ChatScreen:
 		body = m.chatScreen.View()
+		if m.splitActive() {
+			list := lipgloss.NewStyle().
+				Border(lipgloss.NormalBorder(), false, true, false, false).
+				BorderForeground(m.theme.Border).
+				Render(m.threadList.View())
+			body = lipgloss.JoinHorizontal(lipgloss.Top, list, body)
+		}
+	}
+
+	if m.showHelp {
+		body = lipgloss.Place(m.width, m.height-helpHeight, lipgloss.Center, lipgloss.Center, m.helpOverlay())
 	}
 
 	return lipgloss.JoinVertical(
 		lipgloss.Top,
 		bodyStyle.Render(body),
-		helpStyle.Render(m.help.View(m)),
+		m.helpHint()+m.projectIndicator()+m.warningIndicator(),
 	)
 }
 
-func (m Model) getHelpHeight() int {
-	if !m.help.ShowAll {
-		return 1 // One line for short help
+// projectIndicator names the project whose config is in use next to the help
+func (m Model) projectIndicator() string {
+	if m.project == "" {
+		return ""
 	}
-	height := 1
-	// Get height of tallest column
-	for _, col := range m.FullHelp() {
-		if len(col) > height {
-			height = len(col)
-		}
+	return lipgloss.NewStyle().
+		Foreground(m.theme.Muted).
+		Render("  project " + m.project)
+}
+
+// warningIndicator shows the number of config warnings next to the help
+func (m Model) warningIndicator() string {
+	if len(m.warnings) == 0 {
+		return ""
+	}
+	label := "config warning"
+	if len(m.warnings) > 1 {
+		label += "s"
 	}
-	return height
+	return lipgloss.NewStyle().
+		Foreground(m.theme.Tool).
+		Render(fmt.Sprintf("  ! %d %s", len(m.warnings), label))
 }
@@ -0,0 +1,170 @@
+package chat
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/filepicker"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/isaacphi/slop/internal/artifact"
+	"github.com/isaacphi/slop/internal/config"
+)
+
+const (
+	// At most this many files can be attached to a message
+	maxAttachments = 5
+	// Files larger than this can't be attached
+	maxAttachmentSize = 10 * 1024 * 1024
+)
+
+// attachment is a file selected to be sent with the next message
+type attachment struct {
+	path     string
+	mimeType string
+	size     int
+}
+
+// describe shows the attachment's name, type and size
+func (a attachment) describe() string {
+	return fmt.Sprintf("[attached %s, %s, %s]", filepath.Base(a.path), a.mimeType, artifact.FormatSize(a.size))
+}
+
+// isImage reports whether the attachment is an image
+func (a attachment) isImage() bool {
+	return strings.HasPrefix(a.mimeType, "image/")
+}
+
+// pickerState tracks the file picker overlay and the files picked for the next message
+type pickerState struct {
+	picker      filepicker.Model
+	active      bool
+	attachments []attachment
+	notice      string // Why the last selection was refused
+}
+
+// openPicker shows the file picker over the chat
+func (m *Model) openPicker() tea.Cmd {
+	if len(m.files.attachments) >= maxAttachments {
+		m.files.notice = fmt.Sprintf("at most %d files can be attached", maxAttachments)
+		return nil
+	}
+
+	fp := filepicker.New()
+	fp.CurrentDirectory, _ = os.Getwd()
+	fp.AutoHeight = false
+	fp.Height = max(m.viewport.Height-2, 1)
+	fp.ShowPermissions = false
+	// Escape closes the picker instead of going up a directory
+	fp.KeyMap.Back = key.NewBinding(key.WithKeys("h", "backspace", "left"), key.WithHelp("h", "back"))
+	fp.Styles.Cursor = lipgloss.NewStyle().Foreground(m.theme.Accent)
+	fp.Styles.Selected = lipgloss.NewStyle().Foreground(m.theme.Accent).Bold(true)
+	fp.Styles.Directory = lipgloss.NewStyle().Foreground(m.theme.Human)
+	fp.Styles.FileSize = fp.Styles.FileSize.Foreground(m.theme.Muted)
+
+	m.files.picker = fp
+	m.files.active = true
+	m.files.notice = ""
+	return fp.Init()
+}
+
+// updatePicker passes messages to the open file picker. It reports whether the message
+// was used up by the picker
+func (m *Model) updatePicker(msg tea.Msg) (tea.Cmd, bool) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && (keyMsg.Type == tea.KeyEsc || m.GetKeyMap().KeyToActionMap[keyMsg.String()] == config.KeyActionAttachFile) {
+		m.files.active = false
+		return nil, true
+	}
+
+	var cmd tea.Cmd
+	m.files.picker, cmd = m.files.picker.Update(msg)
+	if selected, path := m.files.picker.DidSelectFile(msg); selected {
+		if err := m.attach(path); err != nil {
+			m.files.notice = err.Error()
+		} else {
+			m.files.active = false
+		}
+	}
+
+	_, isKey := msg.(tea.KeyMsg)
+	return cmd, isKey
+}
+
+// attach adds a file to the next message if it is within the attachment limits
+func (m *Model) attach(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("can't attach %s: %w", filepath.Base(path), err)
+	}
+	if info.Size() > maxAttachmentSize {
+		return fmt.Errorf("%s is %s, files up to %s can be attached",
+			filepath.Base(path), artifact.FormatSize(int(info.Size())), artifact.FormatSize(maxAttachmentSize))
+	}
+	for _, existing := range m.files.attachments {
+		if existing.path == path {
+			return fmt.Errorf("%s is already attached", filepath.Base(path))
+		}
+	}
+
+	a := attachment{path: path, mimeType: detectType(path), size: int(info.Size())}
+	if a.isImage() && !m.preset.Vision {
+		return fmt.Errorf("%s is an image and this preset does not accept images", filepath.Base(path))
+	}
+	m.files.attachments = append(m.files.attachments, a)
+	return nil
+}
+
+// detectType finds a file's mime type from its extension, or from its content when the
+// extension is unknown
+func detectType(path string) string {
+	if mimeType := mime.TypeByExtension(filepath.Ext(path)); mimeType != "" {
+		mimeType, _, _ = strings.Cut(mimeType, ";")
+		return mimeType
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := f.Read(head)
+	mimeType, _, _ := strings.Cut(http.DetectContentType(head[:n]), ";")
+	return mimeType
+}
+
+// takeAttachments returns the attached files and clears them for the next message
+func (m *Model) takeAttachments() []attachment {
+	attachments := m.files.attachments
+	m.files.attachments = nil
+	m.files.notice = ""
+	return attachments
+}
+
+// pickerView renders the file picker in place of the conversation
+func (m Model) pickerView() string {
+	header := m.theme.MutedText().Render(fmt.Sprintf("Attach a file from %s (esc to close, %d/%d attached, up to %s each)",
+		m.files.picker.CurrentDirectory, len(m.files.attachments), maxAttachments, artifact.FormatSize(maxAttachmentSize)))
+	return lipgloss.NewStyle().Height(m.viewport.Height).Render(header + "\n" + m.files.picker.View())
+}
+
+// attachmentStatus lists the files attached to the next message, or why the last one
+// was refused
+func (m Model) attachmentStatus() string {
+	if m.files.notice != "" {
+		return lipgloss.NewStyle().Foreground(m.theme.Danger).Render(m.files.notice)
+	}
+	if len(m.files.attachments) == 0 {
+		return ""
+	}
+	names := make([]string, len(m.files.attachments))
+	for i, a := range m.files.attachments {
+		names[i] = fmt.Sprintf("%s (%s, %s)", filepath.Base(a.path), a.mimeType, artifact.FormatSize(a.size))
+	}
+	return m.theme.MutedText().Render("attached: " + strings.Join(names, ", "))
+}
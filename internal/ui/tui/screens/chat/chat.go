@@ -2,48 +2,123 @@ package chat
 
 import (
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
 	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/timestamp"
+	"github.com/isaacphi/slop/internal/toolview"
 	"github.com/isaacphi/slop/internal/ui/tui/keymap"
+	"github.com/isaacphi/slop/internal/ui/tui/theme"
 )
 
 // Model represents the chat screen
 type Model struct {
-	width    int
-	height   int
-	textArea textarea.Model
-	messages []string
-	viewport viewport.Model
-	keyMap   *config.KeyMap
-	mode     keymap.AppMode
+	width     int
+	height    int
+	textArea  textarea.Model
+	messages  []chatMessage
+	viewport  viewport.Model
+	keyMap    *config.KeyMap
+	mode      keymap.AppMode
+	theme     theme.Theme
+	search    searchState
+	citations citationState
+	files     pickerState
+	stream    streamState
+	vim       vimState
+	threadID  uuid.UUID       // Thread opened from the thread list, if any
+	streamErr *StreamErrorMsg // Error from the last response, shown until the next message is sent
+	turn      *turn           // Reply the agent is sending, if any
+	approval  string          // Tool calls the last reply is waiting on, shown until the next message is sent
+	// Scroll position to go back to when the thread of a restored session is shown
+	restoreOffset *int
+
+	preset        config.Preset // Preset the chat is sent with
+	contextTokens int           // Estimated tokens of the conversation so far
+	expandTools   bool          // Show the full arguments and results of tool calls
+	showDiff      bool          // Show regenerated responses as changes from the responses they replaced
+	renderMath    bool          // Show LaTeX math in responses as Unicode
+	timeFormat    string        // How message times are shown, relative or absolute
+
+	// Shown in the info panel
+	presetName string                    // Name of the preset in the config
+	toolsets   map[string]config.Toolset // Configured toolsets, for the approval settings of the preset's
+	info       infoState
 }
 
-// New creates a new chat screen model
-func New(keyMap *config.KeyMap) Model {
+// chatMessage is a message displayed in the chat viewport
+type chatMessage struct {
+	id          uuid.UUID // Stored messages only, used to follow citations
+	role        domain.Role
+	content     string
+	attachments []attachment
+	tools       []toolview.Call // Calls of a tool result message, shown as summaries
+	footer      string          // Dimmed line shown below the message, such as the model's confidence
+	previous    *previousAnswer // Response this one was regenerated from, if any
+	createdAt   time.Time       // Zero for system notes and responses still streaming
+	duration    time.Duration   // Time the response took to generate, if known
+}
+
+// stamp describes when the message was written and how long it took to generate,
+// empty when the message has no time
+func (c chatMessage) stamp(format string) string {
+	if c.createdAt.IsZero() {
+		return ""
+	}
+	stamp := timestamp.Format(c.createdAt, format)
+	if c.duration > 0 {
+		stamp += " · " + timestamp.Elapsed(c.duration)
+	}
+	return stamp
+}
+
+// prefix is shown before the message content
+func (c chatMessage) prefix() string {
+	if c.role == domain.RoleHuman {
+		return "> "
+	}
+	return ""
+}
+
+// New creates a new chat screen model. The info panel shows the toolsets of the preset
+// from toolsets. LaTeX math in responses is shown as Unicode when renderMath is set.
+// Message times are shown in timeFormat, relative or absolute
+func New(keyMap *config.KeyMap, t theme.Theme, presetName string, preset config.Preset, toolsets map[string]config.Toolset, vimMode, renderMath bool, timeFormat string) Model {
 	ta := textarea.New()
 	ta.Placeholder = "Type your message here..."
 	ta.ShowLineNumbers = false
 	ta.MaxHeight = 5
 
-	messages := []string{
-		"Welcome to the chat screen!",
-		"Press 'i' to start typing, ESC to exit typing mode.",
-		"Press 'h' to return to home screen.",
+	messages := []chatMessage{
+		{role: domain.RoleSystem, content: "Welcome to the chat screen!"},
+		{role: domain.RoleSystem, content: "Press 'i' to start typing, ESC to exit typing mode."},
+		{role: domain.RoleSystem, content: "Press 'h' to return to home screen."},
 	}
 
-	vp := viewport.New(0, 0)
-	vp.SetContent(strings.Join(messages, "\n"))
-
-	return Model{
-		textArea: ta,
-		messages: messages,
-		viewport: vp,
-		keyMap:   keyMap,
+	m := Model{
+		textArea:   ta,
+		messages:   messages,
+		viewport:   viewport.New(0, 0),
+		keyMap:     keyMap,
+		theme:      t,
+		search:     newSearchState(),
+		stream:     newStreamState(),
+		presetName: presetName,
+		preset:     preset,
+		toolsets:   toolsets,
+		vim:        vimState{enabled: vimMode},
+		renderMath: renderMath,
+		timeFormat: timeFormat,
 	}
+	m.updateViewportContent()
+
+	return m
 }
 
 // Init initializes the chat screen
@@ -53,35 +128,119 @@ func (m Model) Init() tea.Cmd {
 
 // updateViewportContent updates the viewport content with current messages
 func (m *Model) updateViewportContent() {
-	m.viewport.SetContent(strings.Join(m.messages, "\n"))
+	m.findCitations()
+	lines := make([]string, len(m.messages))
+	for i, msg := range m.messages {
+		style := m.theme.Role(msg.role)
+		if msg.role == domain.RoleSystem {
+			style = m.theme.MutedText()
+		}
+		if m.showDiff && msg.previous != nil {
+			lines[i] = style.Render(msg.prefix()) + m.renderDiff(msg, style)
+		} else {
+			lines[i] = style.Render(msg.prefix()) + m.highlight(i, msg.content, style)
+		}
+		var footer []string
+		if stamp := msg.stamp(m.timeFormat); stamp != "" {
+			footer = append(footer, stamp)
+		}
+		if msg.footer != "" {
+			footer = append(footer, msg.footer)
+		}
+		if len(footer) > 0 {
+			lines[i] += "\n" + m.theme.MutedText().Faint(true).Render(strings.Join(footer, " · "))
+		}
+	}
+	m.viewport.SetContent(strings.Join(lines, "\n"))
+	m.updateContextTokens()
 }
 
 // Update handles updates to the chat screen
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	var cmds []tea.Cmd
 
+	// The file picker takes all keys while it is open
+	if m.files.active {
+		cmd, used := m.updatePicker(msg)
+		if used {
+			return m, cmd
+		}
+		cmds = append(cmds, cmd)
+	}
+
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 
 		// Calculate appropriate heights
-		inputHeight := 5                                           // Fixed input height
-		titleHeight := 1                                           // Title height
-		viewportHeight := m.height - inputHeight - titleHeight - 2 // Account for padding/gaps
+		inputHeight := 5                                                          // Fixed input height
+		titleHeight := 1                                                          // Title height
+		statusHeight := 1                                                         // Search and stream status height
+		viewportHeight := m.height - inputHeight - titleHeight - statusHeight - 2 // Account for padding/gaps
 
 		// Update textarea dimensions
 		m.textArea.SetWidth(msg.Width - 4) // Account for border
 		m.textArea.SetHeight(inputHeight)
 
-		// Update viewport dimensions
-		m.viewport.Width = msg.Width
+		// Update viewport dimensions, leaving room for the info panel
 		m.viewport.Height = viewportHeight
+		m.layout()
 
 		// Update the viewport content
 		m.updateViewportContent()
+		m.files.picker.Height = max(viewportHeight-2, 1)
 
 	case tea.KeyMsg:
+		// Route keys to the search input while a query is being typed
+		if m.search.active {
+			return m, m.updateSearchInput(msg)
+		}
+
+		if !m.textArea.Focused() {
+			switch m.GetKeyMap().KeyToActionMap[msg.String()] {
+			case config.KeyActionSearch:
+				return m, m.startSearch()
+			case config.KeyActionNextMatch:
+				m.nextMatch(1)
+				return m, nil
+			case config.KeyActionPrevMatch:
+				m.nextMatch(-1)
+				return m, nil
+			case config.KeyActionPauseStream:
+				return m, m.togglePause()
+			case config.KeyActionFollow:
+				m.toggleFollow()
+				return m, nil
+			case config.KeyActionNextCitation:
+				m.nextCitation(1)
+				return m, nil
+			case config.KeyActionPrevCitation:
+				m.nextCitation(-1)
+				return m, nil
+			case config.KeyActionFollowCitation:
+				m.followCitation()
+				return m, nil
+			case config.KeyActionToggleTools:
+				m.toggleTools()
+				return m, nil
+			case config.KeyActionToggleDiff:
+				m.toggleDiff()
+				return m, nil
+			case config.KeyActionToggleInfo:
+				m.toggleInfo()
+				return m, nil
+			}
+		}
+
+		if m.textArea.Focused() && m.GetKeyMap().KeyToActionMap[msg.String()] == config.KeyActionAttachFile {
+			return m, m.openPicker()
+		}
+
+		if cmd, used := m.updateVim(msg); used {
+			return m, cmd
+		}
+
 		switch msg.String() {
 		case "esc":
 			m.textArea.Blur()
@@ -92,25 +251,44 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		case "i":
 			// Enter input mode
 			m.textArea.Focus()
+			m.startVimInsert()
 			return m, tea.Cmd(func() tea.Msg {
 				return keymap.SetModeMsg{Mode: keymap.InputMode}
 			})
 
 		case "enter":
-			// If input mode, add message and clear textarea
+			// If input mode, add message, clear textarea and send it to the model
 			if m.textArea.Focused() {
 				content := m.textArea.Value()
-				if content != "" {
-					m.messages = append(m.messages, "> "+content)
+				if content != "" || len(m.files.attachments) > 0 {
+					if m.turn != nil {
+						m.files.notice = "wait for the reply to finish before sending"
+						return m, nil
+					}
+					send := SendMsg{ThreadID: m.threadID, Content: content}
+					attachments := m.takeAttachments()
+					for _, a := range attachments {
+						content += "\n" + a.describe()
+						send.Attachments = append(send.Attachments, a.path)
+					}
+					m.messages = append(m.messages, chatMessage{
+						role:        domain.RoleHuman,
+						content:     strings.TrimPrefix(content, "\n"),
+						attachments: attachments,
+						createdAt:   time.Now(),
+					})
 					m.textArea.Reset()
+					m.streamErr = nil
+					m.approval = ""
 
 					// Update viewport content with new messages
+					m.findMatches()
 					m.updateViewportContent()
 
 					// Scroll to the bottom of viewport
 					m.viewport.GotoBottom()
 
-					return m, nil
+					return m, func() tea.Msg { return send }
 				}
 			}
 
@@ -146,6 +324,48 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			cmds = append(cmds, cmd)
 		}
 
+	// Replies are only shown while their thread is open, the rest is read from the
+	// thread when it is opened again
+	case TurnStartedMsg:
+		cmds = append(cmds, m.startTurn(msg))
+
+	case StreamChunkMsg:
+		if msg.ThreadID == m.threadID {
+			m.receiveChunk(msg.Content)
+		}
+		cmds = append(cmds, m.turn.next())
+
+	case StreamRetryMsg:
+		if msg.ThreadID == m.threadID {
+			m.retrying(msg)
+		}
+		cmds = append(cmds, m.turn.next())
+
+	case StreamApprovalMsg:
+		if msg.ThreadID == m.threadID {
+			m.awaitApproval(msg)
+		}
+		cmds = append(cmds, m.turn.next())
+
+	case StreamErrorMsg:
+		if msg.ThreadID == m.threadID {
+			m.failStream(msg)
+		}
+		cmds = append(cmds, m.turn.next())
+
+	case StreamDoneMsg:
+		m.turn = nil
+		if msg.ThreadID != m.threadID {
+			break
+		}
+		m.finishStream()
+		if !m.stream.done {
+			cmds = append(cmds, m.endTurn())
+		}
+
+	case ThreadLoadedMsg:
+		m.showThread(msg)
+
 	case keymap.SetModeMsg:
 		m.mode = msg.Mode
 		// If we're switching to normal mode, blur the textarea
@@ -164,21 +384,26 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 
 // View renders the chat screen
 func (m Model) View() string {
-	title := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#FAFAFA")).
-		Background(lipgloss.Color("#7D56F4")).
-		Padding(0, 1).
-		Render("slop - Chat Screen")
+	titleText := "slop - Chat Screen"
+	if m.threadID != uuid.Nil {
+		titleText = "slop - Thread " + m.threadID.String()[:8]
+	}
+	title := m.theme.Title().Render(titleText)
 
 	// Style for input area (with border)
-	inputStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#7D56F4")).
-		Padding(0, 1)
+	inputStyle := m.theme.Panel().Padding(0, 1)
 
-	// Render viewport (no border)
+	// Render viewport (no border), or the file picker over it
 	viewportContent := m.viewport.View()
+	if m.files.active {
+		viewportContent = m.pickerView()
+	}
+
+	if m.infoShown() {
+		viewportContent = lipgloss.JoinHorizontal(lipgloss.Top,
+			lipgloss.NewStyle().Width(m.viewport.Width).Render(viewportContent),
+			m.infoView(m.viewport.Height))
+	}
 
 	// Render input area with border
 	inputArea := inputStyle.Render(m.textArea.View())
@@ -187,6 +412,28 @@ func (m Model) View() string {
 		lipgloss.Left,
 		title,
 		viewportContent,
+		m.statusLine(),
 		inputArea,
 	)
 }
+
+// statusLine combines the vim, search, citation, attachment and stream status into a single
+// line, with the token estimate on the right. An error banner replaces it after a
+// response fails
+func (m Model) statusLine() string {
+	if banner := m.errorBanner(); banner != "" {
+		return banner
+	}
+
+	var statuses []string
+	for _, status := range []string{m.vimStatus(), m.searchStatus(), m.citationStatus(), m.attachmentStatus(), m.streamStatus()} {
+		if status != "" {
+			statuses = append(statuses, status)
+		}
+	}
+	left := strings.Join(statuses, "  ")
+
+	right := m.tokenStatus()
+	gap := max(m.width-lipgloss.Width(left)-lipgloss.Width(right), 1)
+	return left + strings.Repeat(" ", gap) + right
+}
@@ -0,0 +1,84 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/citation"
+)
+
+// citationState tracks the citations in the chat and which one is selected
+type citationState struct {
+	refs     []citationRef
+	current  int
+	selected bool   // True once a citation was selected with the keyboard
+	notice   string // Result of following the last citation
+}
+
+// citationRef is a citation within a message's content
+type citationRef struct {
+	message int
+	citation.Ref
+}
+
+// findCitations finds the citations in all messages, keeping the selection when it still exists
+func (m *Model) findCitations() {
+	m.citations.refs = nil
+	for i, msg := range m.messages {
+		for _, ref := range citation.Find(msg.content) {
+			m.citations.refs = append(m.citations.refs, citationRef{message: i, Ref: ref})
+		}
+	}
+	if m.citations.current >= len(m.citations.refs) {
+		m.citations.current = 0
+		m.citations.selected = false
+	}
+}
+
+// nextCitation selects the next or previous citation, wrapping around
+func (m *Model) nextCitation(delta int) {
+	if len(m.citations.refs) == 0 {
+		return
+	}
+	n := len(m.citations.refs)
+	if m.citations.selected {
+		m.citations.current = ((m.citations.current+delta)%n + n) % n
+	} else if delta < 0 {
+		m.citations.current = n - 1
+	}
+	m.citations.selected = true
+	m.citations.notice = ""
+	m.updateViewportContent()
+
+	ref := m.citations.refs[m.citations.current]
+	m.scrollTo(ref.message, ref.Start)
+}
+
+// followCitation scrolls to the message the selected citation refers to
+func (m *Model) followCitation() {
+	if !m.citations.selected || len(m.citations.refs) == 0 {
+		return
+	}
+	ref := m.citations.refs[m.citations.current]
+	for i, msg := range m.messages {
+		if msg.id != uuid.Nil && strings.HasPrefix(msg.id.String(), ref.ID) && i != ref.message {
+			m.citations.notice = fmt.Sprintf("showing msg %s", msg.id.String()[:8])
+			m.scrollTo(i, 0)
+			return
+		}
+	}
+	m.citations.notice = fmt.Sprintf("msg %s is not in this thread", ref.ID)
+}
+
+// citationStatus shows the selected citation and where following it led
+func (m Model) citationStatus() string {
+	if !m.citations.selected || len(m.citations.refs) == 0 {
+		return ""
+	}
+	status := fmt.Sprintf("reference %d/%d", m.citations.current+1, len(m.citations.refs))
+	if m.citations.notice != "" {
+		status += ", " + m.citations.notice
+	}
+	return m.theme.MutedText().Render(status)
+}
@@ -0,0 +1,63 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/textdiff"
+)
+
+// previousAnswer is the response a regenerated response replaced
+type previousAnswer struct {
+	id      uuid.UUID
+	content string
+}
+
+// hasRegenerated reports whether any message was regenerated from an earlier response
+func (m Model) hasRegenerated() bool {
+	for _, msg := range m.messages {
+		if msg.previous != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// toggleDiff switches regenerated responses between their text and their changes from
+// the responses they replaced
+func (m *Model) toggleDiff() {
+	if !m.hasRegenerated() {
+		return
+	}
+	m.showDiff = !m.showDiff
+	m.updateViewportContent()
+}
+
+// renderDiff shows the words a regenerated response removed and added, followed by a
+// line counting them
+func (m Model) renderDiff(msg chatMessage, style lipgloss.Style) string {
+	ops := textdiff.Words(msg.previous.content, msg.content)
+	removedStyle := lipgloss.NewStyle().Foreground(m.theme.Danger).Strikethrough(true)
+	addedStyle := lipgloss.NewStyle().Foreground(m.theme.Accent).Underline(true)
+
+	var b strings.Builder
+	for _, op := range ops {
+		switch op.Kind {
+		case textdiff.Delete:
+			b.WriteString(removedStyle.Render(op.Text))
+		case textdiff.Insert:
+			b.WriteString(addedStyle.Render(op.Text))
+		default:
+			b.WriteString(style.Render(op.Text))
+		}
+	}
+
+	summary := fmt.Sprintf("Same as the previous response %s", msg.previous.id.String()[:8])
+	if textdiff.Changed(ops) {
+		removed, added := textdiff.Stats(ops)
+		summary = fmt.Sprintf("Changes from the previous response %s: %d words removed, %d added", msg.previous.id.String()[:8], removed, added)
+	}
+	return b.String() + "\n" + m.theme.MutedText().Render(summary)
+}
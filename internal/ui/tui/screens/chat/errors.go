@@ -0,0 +1,49 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/errkind"
+)
+
+// StreamErrorMsg reports that the response being streamed failed
+type StreamErrorMsg struct {
+	ThreadID uuid.UUID
+	Err      error
+	Kind     errkind.Kind
+}
+
+// failStream ends the current response and keeps the error to show in a banner
+// until the next message is sent
+func (m *Model) failStream(msg StreamErrorMsg) {
+	m.finishStream()
+	m.streamErr = &msg
+}
+
+// errorBanner renders the last stream error in place of the status line, with
+// advice for the kind of error when there is any
+func (m Model) errorBanner() string {
+	if m.streamErr == nil {
+		return ""
+	}
+	kind := m.streamErr.Kind
+	if kind == errkind.Unknown {
+		kind = errkind.Of(m.streamErr.Err)
+	}
+
+	text := fmt.Sprintf("%s: %s", kind.Title(), m.streamErr.Err)
+	if advice := kind.Advice(); advice != "" {
+		text += ". " + advice
+	}
+	text = strings.Join(strings.Fields(text), " ")
+
+	style := m.theme.ErrorBanner(kind)
+	width := max(m.width-style.GetHorizontalFrameSize(), 1)
+	if lipgloss.Width(text) > width {
+		text = string([]rune(text)[:max(width-3, 0)]) + "..."
+	}
+	return style.Width(m.width).Render(text)
+}
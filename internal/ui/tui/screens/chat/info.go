@@ -0,0 +1,167 @@
+package chat
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+const (
+	// Width of the info panel, including its border
+	infoWidth = 34
+	// The chat keeps at least this width when the panel is shown
+	minChatWidth = 30
+)
+
+// infoState is what the info panel shows about the thread besides its messages
+type infoState struct {
+	visible bool
+	thread  *domain.Thread // Thread opened in the chat, nil for a new chat
+	tags    []string
+}
+
+// infoShown reports whether the panel is open and the screen is wide enough for it
+func (m Model) infoShown() bool {
+	return m.info.visible && m.width-infoWidth >= minChatWidth
+}
+
+// toggleInfo shows or hides the info panel, giving its width to the chat while hidden
+func (m *Model) toggleInfo() {
+	m.info.visible = !m.info.visible
+	m.layout()
+	m.updateViewportContent()
+}
+
+// layout fits the viewport into the width left next to the info panel
+func (m *Model) layout() {
+	m.viewport.Width = m.width
+	if m.infoShown() {
+		m.viewport.Width = m.width - infoWidth
+	}
+}
+
+// infoView renders the info panel: the preset and its toolsets, how much of the context
+// window the conversation uses and the thread's title, tags and model pin
+func (m Model) infoView(height int) string {
+	heading := lipgloss.NewStyle().Bold(true).Foreground(m.theme.Accent)
+	muted := m.theme.MutedText()
+	// Space inside the border and padding
+	width := infoWidth - 4
+
+	var b strings.Builder
+	b.WriteString(heading.Render("Preset") + "\n")
+	b.WriteString(m.presetName + "\n")
+	b.WriteString(muted.Render(truncate(m.preset.Provider+"/"+m.preset.Name, width)) + "\n")
+
+	b.WriteString("\n" + heading.Render("Toolsets") + "\n")
+	if len(m.preset.Toolsets) == 0 {
+		b.WriteString(muted.Render("none") + "\n")
+	}
+	for _, name := range m.preset.Toolsets {
+		b.WriteString(truncate(name, width) + "\n")
+		toolset, ok := m.toolsets[name]
+		if !ok {
+			b.WriteString(lipgloss.NewStyle().Foreground(m.theme.Danger).Render("  not configured") + "\n")
+			continue
+		}
+		for _, server := range slices.Sorted(maps.Keys(toolset.Servers)) {
+			b.WriteString(m.approvalLine(server, toolset.Servers[server], width) + "\n")
+		}
+	}
+
+	b.WriteString("\n" + heading.Render("Context") + "\n")
+	b.WriteString(m.tokenBar(width) + "\n")
+
+	b.WriteString("\n" + heading.Render("Thread") + "\n")
+	if m.info.thread == nil {
+		b.WriteString(muted.Render("new chat") + "\n")
+	} else {
+		if label := m.info.thread.Label(); label != "" {
+			b.WriteString(lipgloss.NewStyle().Width(width).Render(label) + "\n")
+		}
+		b.WriteString(muted.Render(m.info.thread.ID.String()[:8]) + "\n")
+		if len(m.info.tags) > 0 {
+			b.WriteString(lipgloss.NewStyle().Width(width).Render("tags: "+strings.Join(m.info.tags, ", ")) + "\n")
+		}
+		if m.info.thread.PinModel {
+			b.WriteString("model pinned\n")
+		}
+		if m.info.thread.Language != "" {
+			b.WriteString("language: " + m.info.thread.Language + "\n")
+		}
+	}
+
+	// Cut what doesn't fit above the bottom border
+	inner := max(height-2, 0)
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) > inner {
+		lines = lines[:inner]
+	}
+	return m.theme.Panel().
+		Padding(0, 1).
+		Width(infoWidth - 2).
+		Height(inner).
+		Render(strings.Join(lines, "\n"))
+}
+
+// approvalLine shows whether the tools of a server in a toolset ask before they run
+func (m Model) approvalLine(server string, cfg config.MCPServerToolConfig, width int) string {
+	status, color := "auto", m.theme.Muted
+	switch {
+	case len(cfg.AllowedTools) == 0 && cfg.RequireApproval:
+		status, color = "approval", m.theme.Warning
+	case len(cfg.AllowedTools) > 0:
+		asking := 0
+		for _, tool := range cfg.AllowedTools {
+			if tool.RequireApproval {
+				asking++
+			}
+		}
+		if asking > 0 {
+			status, color = fmt.Sprintf("approval %d/%d", asking, len(cfg.AllowedTools)), m.theme.Warning
+		}
+	}
+	status = lipgloss.NewStyle().Foreground(color).Render(status)
+	name := truncate("  "+server, width-lipgloss.Width(status)-1)
+	return name + strings.Repeat(" ", max(width-lipgloss.Width(name)-lipgloss.Width(status), 1)) + status
+}
+
+// tokenBar draws the share of the context window the next request would use, colored
+// like the token counter in the status line
+func (m Model) tokenBar(width int) string {
+	total := m.requestTokens()
+	limit := m.preset.ContextWindow
+	if limit <= 0 {
+		return m.theme.MutedText().Render(fmt.Sprintf("~%s tokens, window unknown", formatTokens(total)))
+	}
+
+	ratio := float64(total) / float64(limit)
+	color := m.theme.Accent
+	switch {
+	case ratio >= tokenDangerRatio:
+		color = m.theme.Danger
+	case ratio >= tokenWarningRatio:
+		color = m.theme.Warning
+	}
+	filled := min(int(ratio*float64(width)), width)
+	bar := lipgloss.NewStyle().Foreground(color).Render(strings.Repeat("█", filled)) +
+		m.theme.MutedText().Render(strings.Repeat("░", width-filled))
+	return bar + "\n" + fmt.Sprintf("~%s / %s (%d%%)", formatTokens(total), formatTokens(limit), int(ratio*100))
+}
+
+// truncate shortens s to width columns, marking the cut with an ellipsis
+func truncate(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && lipgloss.Width(string(runes))+1 > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
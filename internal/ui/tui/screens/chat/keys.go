@@ -16,9 +16,31 @@ func (m Model) GetKeyMap() keymap.KeyMap {
 		km.AddAction(keymap.SystemGroup, config.KeyActionInputMode, "input mode")
 		km.AddAction(keymap.NavigationGroup, config.KeyActionScrollDown, "scroll down")
 		km.AddAction(keymap.NavigationGroup, config.KeyActionScrollUp, "scroll up")
-	} else if mode == keymap.InputMode {
+		km.AddAction(keymap.ActionGroup, config.KeyActionSearch, "search")
+		if m.stream.streaming {
+			km.AddAction(keymap.ActionGroup, config.KeyActionPauseStream, "pause/resume stream")
+		}
+		km.AddAction(keymap.NavigationGroup, config.KeyActionFollow, "toggle follow")
+		if len(m.citations.refs) > 0 {
+			km.AddAction(keymap.NavigationGroup, config.KeyActionNextCitation, "next reference")
+			km.AddAction(keymap.NavigationGroup, config.KeyActionPrevCitation, "previous reference")
+			km.AddAction(keymap.NavigationGroup, config.KeyActionFollowCitation, "go to referenced message")
+		}
+		if m.hasTools() {
+			km.AddAction(keymap.ActionGroup, config.KeyActionToggleTools, "expand/collapse tool calls")
+		}
+		if m.hasRegenerated() {
+			km.AddAction(keymap.ActionGroup, config.KeyActionToggleDiff, "show/hide changes from previous responses")
+		}
+		km.AddAction(keymap.ActionGroup, config.KeyActionToggleInfo, "show/hide thread info")
+		if m.search.query != "" {
+			km.AddAction(keymap.NavigationGroup, config.KeyActionNextMatch, "next match")
+			km.AddAction(keymap.NavigationGroup, config.KeyActionPrevMatch, "previous match")
+		}
+	} else if mode == keymap.InputMode && !m.search.active {
 		// No global key bindings in input mode
 		km.AddAction(keymap.SystemGroup, config.KeyActionSendMessage, "send message")
+		km.AddAction(keymap.ActionGroup, config.KeyActionAttachFile, "attach file")
 	}
 	return km
 }
@@ -0,0 +1,201 @@
+package chat
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/isaacphi/slop/internal/ui/tui/keymap"
+)
+
+// searchState tracks an in progress or completed search of the loaded thread
+type searchState struct {
+	input   textinput.Model
+	active  bool // True while the query is being typed
+	query   string
+	matches []searchMatch
+	current int
+}
+
+// searchMatch is the byte range of a match within a message's content
+type searchMatch struct {
+	message int
+	start   int
+	end     int
+}
+
+func newSearchState() searchState {
+	ti := textinput.New()
+	ti.Prompt = "/"
+	return searchState{input: ti}
+}
+
+// startSearch opens the search input
+func (m *Model) startSearch() tea.Cmd {
+	m.search.active = true
+	m.search.input.SetValue("")
+	m.search.input.Focus()
+	return func() tea.Msg {
+		return keymap.SetModeMsg{Mode: keymap.InputMode}
+	}
+}
+
+// updateSearchInput handles keys while the search query is being typed
+func (m *Model) updateSearchInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.search.active = false
+		m.search.input.Blur()
+		return func() tea.Msg {
+			return keymap.SetModeMsg{Mode: keymap.NormalMode}
+		}
+
+	case tea.KeyEnter:
+		m.search.active = false
+		m.search.input.Blur()
+		m.search.query = m.search.input.Value()
+		m.findMatches()
+		m.updateViewportContent()
+		m.scrollToMatch()
+		return func() tea.Msg {
+			return keymap.SetModeMsg{Mode: keymap.NormalMode}
+		}
+	}
+
+	var cmd tea.Cmd
+	m.search.input, cmd = m.search.input.Update(msg)
+	return cmd
+}
+
+// findMatches finds every case insensitive occurrence of the query in all messages
+func (m *Model) findMatches() {
+	m.search.matches = nil
+	m.search.current = 0
+	if m.search.query == "" {
+		return
+	}
+
+	re := regexp.MustCompile("(?i)" + regexp.QuoteMeta(m.search.query))
+	for i, msg := range m.messages {
+		for _, loc := range re.FindAllStringIndex(msg.content, -1) {
+			m.search.matches = append(m.search.matches, searchMatch{
+				message: i,
+				start:   loc[0],
+				end:     loc[1],
+			})
+		}
+	}
+}
+
+// nextMatch moves the current match forward or backward, wrapping around
+func (m *Model) nextMatch(delta int) {
+	if len(m.search.matches) == 0 {
+		return
+	}
+	n := len(m.search.matches)
+	m.search.current = ((m.search.current+delta)%n + n) % n
+	m.updateViewportContent()
+	m.scrollToMatch()
+}
+
+// scrollToMatch scrolls the viewport so the current match is in the middle
+func (m *Model) scrollToMatch() {
+	if len(m.search.matches) == 0 {
+		return
+	}
+	match := m.search.matches[m.search.current]
+	m.scrollTo(match.message, match.start)
+}
+
+// scrollTo scrolls the viewport so the given byte offset of a message is in the middle
+func (m *Model) scrollTo(message int, offset int) {
+	line := 0
+	for i := 0; i < message; i++ {
+		line += strings.Count(m.messages[i].prefix()+m.messages[i].content, "\n") + 1
+		if m.messages[i].footer != "" {
+			line++
+		}
+	}
+	line += strings.Count(m.messages[message].content[:offset], "\n")
+
+	m.viewport.SetYOffset(max(line-m.viewport.Height/2, 0))
+}
+
+// span is a styled byte range of a message's content
+type span struct {
+	start int
+	end   int
+	style lipgloss.Style
+}
+
+// highlight renders a message's content with search matches and citations highlighted
+func (m Model) highlight(index int, content string, style lipgloss.Style) string {
+	matchStyle := lipgloss.NewStyle().
+		Foreground(m.theme.AccentText).
+		Background(m.theme.Muted)
+	currentStyle := lipgloss.NewStyle().
+		Foreground(m.theme.AccentText).
+		Background(m.theme.Accent)
+	citationStyle := style.
+		Foreground(m.theme.Accent).
+		Underline(true)
+
+	var spans []span
+	for i, match := range m.search.matches {
+		if match.message != index {
+			continue
+		}
+		s := span{start: match.start, end: match.end, style: matchStyle}
+		if i == m.search.current {
+			s.style = currentStyle
+		}
+		spans = append(spans, s)
+	}
+	for i, ref := range m.citations.refs {
+		if ref.message != index {
+			continue
+		}
+		s := span{start: ref.Start, end: ref.End, style: citationStyle}
+		if m.citations.selected && i == m.citations.current {
+			s.style = currentStyle
+		}
+		spans = append(spans, s)
+	}
+	if len(spans) == 0 {
+		return style.Render(content)
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var b strings.Builder
+	pos := 0
+	for _, s := range spans {
+		// Skip spans overlapping an earlier one
+		if s.start < pos {
+			continue
+		}
+		b.WriteString(style.Render(content[pos:s.start]))
+		b.WriteString(s.style.Render(content[s.start:s.end]))
+		pos = s.end
+	}
+	b.WriteString(style.Render(content[pos:]))
+	return b.String()
+}
+
+// searchStatus renders the search input or the match count for the status bar
+func (m Model) searchStatus() string {
+	if m.search.active {
+		return m.search.input.View()
+	}
+	if m.search.query == "" {
+		return ""
+	}
+	if len(m.search.matches) == 0 {
+		return m.theme.MutedText().Render(fmt.Sprintf("no matches for %q", m.search.query))
+	}
+	return m.theme.MutedText().Render(fmt.Sprintf("match %d/%d for %q",
+		m.search.current+1, len(m.search.matches), m.search.query))
+}
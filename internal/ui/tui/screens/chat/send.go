@@ -0,0 +1,175 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/agent"
+	"github.com/isaacphi/slop/internal/artifact"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/events"
+	"github.com/isaacphi/slop/internal/llm"
+	"github.com/isaacphi/slop/internal/repository"
+)
+
+// SendMsg asks for a message typed in the chat to be sent to the model, in a new
+// thread when ThreadID is nil
+type SendMsg struct {
+	ThreadID    uuid.UUID
+	Content     string
+	Attachments []string // Paths of the files attached to the message
+}
+
+// TurnStartedMsg carries the stream of the agent's reply to a sent message
+type TurnStartedMsg struct {
+	ThreadID uuid.UUID
+	Stream   agent.AgentStream
+	Cancel   context.CancelFunc // Stops the reply
+	Err      error
+}
+
+// TurnFinishedMsg reports that the reply in a thread is complete and has been shown,
+// so the thread can be read again as it was stored
+type TurnFinishedMsg struct {
+	ThreadID uuid.UUID
+}
+
+// StreamApprovalMsg reports that the agent stopped to wait for tool calls to be approved
+type StreamApprovalMsg struct {
+	ThreadID uuid.UUID
+	Tools    int // Number of tool calls waiting
+}
+
+// Send stores a message in its thread, creating the thread if there is none, and starts
+// the agent's reply
+func Send(repo repository.MessageRepository, a *agent.Agent, msg SendMsg) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+
+		message := &domain.Message{ThreadID: msg.ThreadID, Role: domain.RoleHuman, Content: msg.Content}
+		if message.ThreadID == uuid.Nil {
+			thread := &domain.Thread{}
+			if err := repo.CreateThread(ctx, thread); err != nil {
+				return TurnStartedMsg{Err: fmt.Errorf("failed to create thread: %w", err)}
+			}
+			message.ThreadID = thread.ID
+		} else {
+			messages, err := repo.GetMessages(ctx, message.ThreadID, nil, false)
+			if err != nil {
+				return TurnStartedMsg{ThreadID: message.ThreadID, Err: fmt.Errorf("failed to get thread messages: %w", err)}
+			}
+			if len(messages) > 0 {
+				last := messages[len(messages)-1]
+				if last.Role == domain.RoleAssistant && last.ToolCalls != "" {
+					return TurnStartedMsg{ThreadID: message.ThreadID, Err: fmt.Errorf("thread has pending tool calls, approve or reject them with slop msg send -t %s --approve or --reject", message.ThreadID.String()[:8])}
+				}
+				message.ParentID = &last.ID
+			}
+		}
+
+		if len(msg.Attachments) > 0 {
+			if err := attachFiles(ctx, repo, message, msg.Attachments); err != nil {
+				return TurnStartedMsg{ThreadID: message.ThreadID, Err: err}
+			}
+		}
+
+		// The reply runs until it is complete or the chat cancels it
+		turnCtx, cancel := context.WithCancel(context.Background())
+		return TurnStartedMsg{ThreadID: message.ThreadID, Stream: a.SendMessageStream(turnCtx, message), Cancel: cancel}
+	}
+}
+
+// attachFiles adds the attached files to a message as parts of their own. Text files
+// are sent as they are, images are stored as artifacts of the thread and other files
+// are only described
+func attachFiles(ctx context.Context, repo repository.MessageRepository, message *domain.Message, paths []string) error {
+	var parts []domain.MessagePart
+	if message.Content != "" {
+		parts = append(parts, domain.MessagePart{Content: message.Content})
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+		a := attachment{path: path, mimeType: detectType(path), size: len(data)}
+
+		switch {
+		case a.isImage():
+			hash, err := storeImage(ctx, repo, message.ThreadID, path, data)
+			if err != nil {
+				return err
+			}
+			parts = append(parts, domain.MessagePart{
+				Source:   path,
+				Content:  fmt.Sprintf("Image %s", artifact.Reference(hash, filepath.Base(path), len(data))),
+				Artifact: hash,
+				MimeType: a.mimeType,
+			})
+		case bytes.IndexByte(data, 0) < 0:
+			parts = append(parts, domain.MessagePart{
+				Source:  path,
+				Content: fmt.Sprintf("--- File: %s ---\n%s\n--- End of %s ---", path, bytes.TrimRight(data, "\n"), path),
+			})
+			message.Attachments = append(message.Attachments, domain.Attachment{
+				Name: path,
+				Size: len(data),
+				Hash: artifact.Hash(string(data)),
+			})
+		default:
+			parts = append(parts, domain.MessagePart{Source: path, Content: a.describe()})
+		}
+	}
+	return message.SetParts(parts)
+}
+
+// storeImage keeps an attached image as an artifact of the thread and returns its hash
+func storeImage(ctx context.Context, repo repository.MessageRepository, threadID uuid.UUID, path string, data []byte) (string, error) {
+	stored, err := artifact.Store(ctx, repo, threadID, nil, filepath.Base(path), string(data))
+	if err != nil {
+		return "", err
+	}
+	return stored.Hash, nil
+}
+
+// nextEvent waits for the next event of a reply that the chat shows
+func nextEvent(threadID uuid.UUID, stream agent.AgentStream) tea.Cmd {
+	return func() tea.Msg {
+		for event := range stream.Events {
+			switch e := event.(type) {
+			case *llm.TextEvent:
+				return StreamChunkMsg{ThreadID: threadID, Content: e.Content}
+
+			case *llm.ToolCallStartEvent:
+				return StreamChunkMsg{ThreadID: threadID, Content: fmt.Sprintf("\n\n[Requesting function call: %s]\n", e.FunctionName)}
+
+			case *llm.RetryEvent:
+				return StreamRetryMsg{ThreadID: threadID, Attempt: e.Attempt, MaxAttempts: e.MaxAttempts}
+
+			case *agent.ToolResultEvent:
+				result := fmt.Sprintf("[%s returned %s]\n", e.Name, artifact.FormatSize(len(e.Result)))
+				if e.Error != nil {
+					result = fmt.Sprintf("[%s failed: %v]\n", e.Name, e.Error)
+				}
+				return StreamChunkMsg{ThreadID: threadID, Content: result}
+
+			case *agent.ToolApprovalRequestEvent:
+				return StreamApprovalMsg{ThreadID: threadID, Tools: len(e.ToolCalls)}
+
+			case *events.ErrorEvent:
+				// A reply cancelled by leaving the chat isn't an error
+				if errors.Is(e.Error, context.Canceled) {
+					continue
+				}
+				return StreamErrorMsg{ThreadID: threadID, Err: e.Error, Kind: e.Kind}
+			}
+		}
+		return StreamDoneMsg{ThreadID: threadID}
+	}
+}
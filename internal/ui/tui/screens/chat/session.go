@@ -0,0 +1,28 @@
+package chat
+
+import "github.com/google/uuid"
+
+// Session is what it takes to reopen the chat where it was left
+type Session struct {
+	ThreadID     uuid.UUID `json:"threadId"`
+	Draft        string    `json:"draft,omitempty"`        // Unsent text of the input
+	ScrollOffset int       `json:"scrollOffset,omitempty"` // First line of the chat in view
+}
+
+// Session describes the state of the chat
+func (m Model) Session() Session {
+	return Session{
+		ThreadID:     m.threadID,
+		Draft:        m.textArea.Value(),
+		ScrollOffset: m.viewport.YOffset,
+	}
+}
+
+// Restore puts back the draft of a session and scrolls to its position once its
+// thread is shown. The thread itself is opened with LoadThread
+func (m *Model) Restore(session Session) {
+	m.threadID = session.ThreadID
+	m.textArea.SetValue(session.Draft)
+	offset := session.ScrollOffset
+	m.restoreOffset = &offset
+}
@@ -0,0 +1,215 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/agent"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/mathtext"
+	"github.com/isaacphi/slop/internal/usage"
+)
+
+// StreamChunkMsg carries a chunk of a streaming assistant response
+type StreamChunkMsg struct {
+	ThreadID uuid.UUID
+	Content  string
+}
+
+// StreamDoneMsg marks the end of a streaming assistant response
+type StreamDoneMsg struct {
+	ThreadID uuid.UUID
+}
+
+// StreamRetryMsg reports that the request failed with a temporary error and is about
+// to be sent again
+type StreamRetryMsg struct {
+	ThreadID    uuid.UUID
+	Attempt     int
+	MaxAttempts int
+}
+
+// streamState tracks whether incoming chunks are shown and followed
+type streamState struct {
+	streaming bool     // True while a response is being received
+	paused    bool     // True while incoming chunks are buffered instead of shown
+	follow    bool     // Scroll to the bottom when new content is shown, like tail -f
+	pending   []string // Chunks received while paused
+	done      bool     // The stream finished while paused
+	retry     string   // Retry attempt shown until the response starts arriving
+
+	estimate *usage.Estimate // Size and cost of the response received so far
+}
+
+func newStreamState() streamState {
+	return streamState{follow: true}
+}
+
+// turn is a reply of the agent that is still arriving
+type turn struct {
+	threadID uuid.UUID
+	stream   agent.AgentStream
+	cancel   context.CancelFunc
+}
+
+// next waits for the next event of the reply
+func (t *turn) next() tea.Cmd {
+	if t == nil {
+		return nil
+	}
+	return nextEvent(t.threadID, t.stream)
+}
+
+// startTurn follows the reply to a message that was sent. A new chat becomes the
+// thread the message was sent in
+func (m *Model) startTurn(msg TurnStartedMsg) tea.Cmd {
+	if m.threadID == uuid.Nil {
+		m.threadID = msg.ThreadID
+	}
+	if msg.Err != nil {
+		m.failStream(StreamErrorMsg{ThreadID: msg.ThreadID, Err: msg.Err})
+		return nil
+	}
+	m.turn = &turn{threadID: msg.ThreadID, stream: msg.Stream, cancel: msg.Cancel}
+	return m.turn.next()
+}
+
+// endTurn lets the app know that the reply shown in the chat is complete
+func (m Model) endTurn() tea.Cmd {
+	threadID := m.threadID
+	return func() tea.Msg {
+		return TurnFinishedMsg{ThreadID: threadID}
+	}
+}
+
+// CancelTurn stops the reply the agent is sending, if any. What arrived so far is
+// kept in the thread
+func (m Model) CancelTurn() {
+	if m.turn != nil {
+		m.turn.cancel()
+	}
+}
+
+// receiveChunk shows a streamed chunk, or buffers it while paused
+func (m *Model) receiveChunk(content string) {
+	m.stream.retry = ""
+	if !m.stream.streaming {
+		m.stream.streaming = true
+		m.stream.estimate = usage.NewEstimate(m.preset)
+		m.messages = append(m.messages, chatMessage{role: domain.RoleAssistant})
+	}
+	m.stream.estimate.Add(content)
+	if m.stream.paused {
+		m.stream.pending = append(m.stream.pending, content)
+		return
+	}
+	m.messages[len(m.messages)-1].content += content
+	m.refreshStream()
+}
+
+// retrying shows the retry in the status line until the response starts arriving
+func (m *Model) retrying(msg StreamRetryMsg) {
+	m.stream.retry = fmt.Sprintf("retrying (%d/%d)…", msg.Attempt, msg.MaxAttempts)
+}
+
+// awaitApproval shows the tool calls the reply stopped for in the status line until the
+// next message is sent
+func (m *Model) awaitApproval(msg StreamApprovalMsg) {
+	label := "tool call"
+	if msg.Tools > 1 {
+		label += "s"
+	}
+	m.approval = fmt.Sprintf("%d %s waiting, approve with slop msg send -t %s --approve", msg.Tools, label, msg.ThreadID.String()[:8])
+}
+
+// finishStream ends the current response once every buffered chunk has been shown
+func (m *Model) finishStream() {
+	m.stream.retry = ""
+	if m.stream.paused {
+		m.stream.done = true
+		return
+	}
+	m.endStream()
+}
+
+// endStream marks the response as complete. Math is only converted once the whole
+// response has arrived, since a chunk can end partway through an expression
+func (m *Model) endStream() {
+	if m.renderMath && m.stream.streaming {
+		last := &m.messages[len(m.messages)-1]
+		last.content = mathtext.ToUnicode(last.content)
+		m.stream.streaming = false
+		m.refreshStream()
+		return
+	}
+	m.stream.streaming = false
+}
+
+// togglePause pauses the stream, or resumes it and catches up on buffered chunks. The
+// app is told when resuming shows the end of the reply
+func (m *Model) togglePause() tea.Cmd {
+	if !m.stream.paused {
+		// Only a response that is still arriving can be paused
+		m.stream.paused = m.stream.streaming
+		return nil
+	}
+
+	m.stream.paused = false
+	if len(m.stream.pending) > 0 {
+		m.messages[len(m.messages)-1].content += strings.Join(m.stream.pending, "")
+		m.stream.pending = nil
+	}
+	var cmd tea.Cmd
+	if m.stream.done {
+		m.stream.done = false
+		m.endStream()
+		cmd = m.endTurn()
+	}
+	m.refreshStream()
+	return cmd
+}
+
+// toggleFollow turns scrolling to new content on or off
+func (m *Model) toggleFollow() {
+	m.stream.follow = !m.stream.follow
+	if m.stream.follow {
+		m.viewport.GotoBottom()
+	}
+}
+
+// refreshStream redraws the viewport after streamed content changed
+func (m *Model) refreshStream() {
+	m.findMatches()
+	m.updateViewportContent()
+	if m.stream.follow {
+		m.viewport.GotoBottom()
+	}
+}
+
+// streamStatus renders the size and cost of the response while it streams and the
+// pause and follow state for the status bar
+func (m Model) streamStatus() string {
+	var parts []string
+	if m.stream.retry != "" {
+		parts = append(parts, m.stream.retry)
+	}
+	if m.approval != "" {
+		parts = append(parts, m.approval)
+	}
+	if m.stream.streaming && m.stream.estimate != nil {
+		parts = append(parts, m.stream.estimate.String())
+	}
+	if m.stream.paused {
+		parts = append(parts, fmt.Sprintf("paused (%d chunks buffered)", len(m.stream.pending)))
+	}
+	if !m.stream.follow {
+		parts = append(parts, "follow off")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return m.theme.MutedText().Render(strings.Join(parts, " | "))
+}
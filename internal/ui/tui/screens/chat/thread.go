@@ -0,0 +1,151 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/mathtext"
+	"github.com/isaacphi/slop/internal/repository"
+	"github.com/isaacphi/slop/internal/textdiff"
+	"github.com/isaacphi/slop/internal/toolview"
+)
+
+// ThreadLoadedMsg carries the messages of a thread opened in the chat
+type ThreadLoadedMsg struct {
+	ThreadID uuid.UUID
+	Thread   *domain.Thread
+	Tags     []string
+	Messages []domain.Message
+	// Responses that regenerated responses replaced, by the ID of the regenerated response
+	Previous map[uuid.UUID]domain.Message
+	Err      error
+}
+
+// LoadThread reads a thread with its tags and messages so they can be shown in the chat,
+// and marks the thread as read
+func LoadThread(repo repository.MessageRepository, threadID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		thread, err := repo.GetThread(ctx, threadID)
+		if err != nil {
+			return ThreadLoadedMsg{ThreadID: threadID, Err: fmt.Errorf("failed to get thread: %w", err)}
+		}
+		tags, err := repo.GetThreadTags(ctx, []uuid.UUID{threadID})
+		if err != nil {
+			return ThreadLoadedMsg{ThreadID: threadID, Err: fmt.Errorf("failed to get thread tags: %w", err)}
+		}
+		messages, err := repo.GetMessages(ctx, threadID, nil, false)
+		if err != nil {
+			return ThreadLoadedMsg{ThreadID: threadID, Err: fmt.Errorf("failed to get thread messages: %w", err)}
+		}
+		branches, err := repo.GetThreadsMessages(ctx, []uuid.UUID{threadID})
+		if err != nil {
+			return ThreadLoadedMsg{ThreadID: threadID, Err: fmt.Errorf("failed to get thread messages: %w", err)}
+		}
+		// The thread is read once it is shown
+		if err := repo.MarkThreadRead(ctx, threadID); err != nil {
+			slog.Warn("failed to mark thread as read", "thread", threadID, "error", err)
+		}
+		return ThreadLoadedMsg{
+			ThreadID: threadID,
+			Thread:   thread,
+			Tags:     tags[threadID],
+			Messages: messages,
+			Previous: textdiff.PreviousAnswers(branches),
+		}
+	}
+}
+
+// showThread replaces the chat with the messages of a thread
+func (m *Model) showThread(msg ThreadLoadedMsg) {
+	if msg.Err != nil {
+		m.messages = append(m.messages, chatMessage{role: domain.RoleSystem, content: msg.Err.Error()})
+		m.updateViewportContent()
+		return
+	}
+
+	m.threadID = msg.ThreadID
+	m.info.thread = msg.Thread
+	m.info.tags = msg.Tags
+	m.messages = make([]chatMessage, 0, len(msg.Messages))
+	byID := make(map[uuid.UUID]domain.Message, len(msg.Messages))
+	for _, message := range msg.Messages {
+		byID[message.ID] = message
+		chatMsg := chatMessage{id: message.ID, role: message.Role, content: message.Content, createdAt: message.CreatedAt}
+		if metadata, err := message.GetMetadata(); err == nil {
+			chatMsg.duration = metadata.Duration
+			if metadata.Confidence != nil {
+				chatMsg.footer = metadata.Confidence.Footer()
+			}
+		}
+		if message.Role == domain.RoleTool && message.ParentID != nil {
+			if calls, ok := toolview.Calls(byID[*message.ParentID], message); ok {
+				chatMsg.tools = calls
+				chatMsg.content = toolview.Render(calls, m.expandTools)
+			}
+		}
+		if previous, ok := msg.Previous[message.ID]; ok {
+			chatMsg.previous = &previousAnswer{id: previous.ID, content: previous.Content}
+		}
+		if message.Role == domain.RoleAssistant && m.renderMath {
+			chatMsg.content = mathtext.ToUnicode(chatMsg.content)
+			if chatMsg.previous != nil {
+				chatMsg.previous.content = mathtext.ToUnicode(chatMsg.previous.content)
+			}
+		}
+		m.messages = append(m.messages, chatMsg)
+	}
+	m.stream = newStreamState()
+	m.citations = citationState{}
+	m.findMatches()
+	m.updateViewportContent()
+	m.viewport.GotoBottom()
+	if m.restoreOffset != nil {
+		m.viewport.SetYOffset(*m.restoreOffset)
+		m.restoreOffset = nil
+	}
+}
+
+// CloseThread clears the chat of a thread that was deleted
+func (m *Model) CloseThread(threadID uuid.UUID) {
+	if m.threadID != threadID {
+		return
+	}
+	m.threadID = uuid.Nil
+	m.info.thread = nil
+	m.info.tags = nil
+	m.messages = []chatMessage{{role: domain.RoleSystem, content: fmt.Sprintf("Thread %s was deleted", threadID.String()[:8])}}
+	m.stream = newStreamState()
+	m.citations = citationState{}
+	m.findMatches()
+	m.updateViewportContent()
+}
+
+// hasTools reports whether any message shows tool calls
+func (m Model) hasTools() bool {
+	for _, msg := range m.messages {
+		if len(msg.tools) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// toggleTools expands or collapses every tool call in the chat
+func (m *Model) toggleTools() {
+	if !m.hasTools() {
+		return
+	}
+	m.expandTools = !m.expandTools
+	for i, msg := range m.messages {
+		if len(msg.tools) > 0 {
+			m.messages[i].content = toolview.Render(msg.tools, m.expandTools)
+		}
+	}
+	m.findMatches()
+	m.updateViewportContent()
+}
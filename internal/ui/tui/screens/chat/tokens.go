@@ -0,0 +1,83 @@
+package chat
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/tokens"
+)
+
+const (
+	// The token counter turns the warning color at this share of the context window
+	tokenWarningRatio = 0.7
+	// and the danger color at this share
+	tokenDangerRatio = 0.9
+)
+
+// updateContextTokens estimates the tokens of the conversation that is sent along with
+// the next message
+func (m *Model) updateContextTokens() {
+	tokenizer := m.tokenizer()
+	total := 0
+	if m.preset.SystemMessage != "" {
+		total += tokens.CountMessages(tokenizer, m.preset.SystemMessage)
+	}
+	for _, msg := range m.messages {
+		// System messages in the chat are notices from slop, not part of the conversation
+		if msg.role == domain.RoleSystem {
+			continue
+		}
+		total += tokens.CountMessages(tokenizer, msg.content)
+	}
+	m.contextTokens = total
+}
+
+// tokenizer counts tokens the way the preset's model does
+func (m Model) tokenizer() tokens.Tokenizer {
+	return tokens.For(m.preset.Tokenizer, m.preset.Provider, m.preset.Name)
+}
+
+// requestTokens estimates the size of the next request: the conversation and the
+// message being typed
+func (m Model) requestTokens() int {
+	total := m.contextTokens
+	if draft := m.textArea.Value(); draft != "" {
+		total += tokens.CountMessages(m.tokenizer(), draft)
+	}
+	return total
+}
+
+// tokenStatus shows the estimated size of the next request, colored as it approaches
+// the preset's context window
+func (m Model) tokenStatus() string {
+	total := m.requestTokens()
+
+	limit := m.preset.ContextWindow
+	if limit <= 0 {
+		return m.theme.MutedText().Render(fmt.Sprintf("~%s tokens", formatTokens(total)))
+	}
+
+	style := m.theme.MutedText()
+	ratio := float64(total) / float64(limit)
+	switch {
+	case ratio >= tokenDangerRatio:
+		style = lipgloss.NewStyle().Foreground(m.theme.Danger).Bold(true)
+	case ratio >= tokenWarningRatio:
+		style = lipgloss.NewStyle().Foreground(m.theme.Warning)
+	}
+	return style.Render(fmt.Sprintf("~%s / %s tokens", formatTokens(total), formatTokens(limit)))
+}
+
+// formatTokens shortens large token counts, such as 12.3k
+func formatTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 10_000:
+		return fmt.Sprintf("%dk", n/1000)
+	case n >= 1000:
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	}
+	return fmt.Sprint(n)
+}
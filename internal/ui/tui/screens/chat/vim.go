@@ -0,0 +1,371 @@
+package chat
+
+import (
+	"fmt"
+	"slices"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// vimMode is the editing mode of the input when vim keys are enabled
+type vimMode int
+
+const (
+	vimInsert vimMode = iota
+	vimNormal
+	vimVisual
+)
+
+// vimState tracks vim style editing of the input. It only applies while the chat is
+// in input mode, so the app's own navigation keys work as usual outside of it
+type vimState struct {
+	enabled  bool
+	mode     vimMode
+	pending  string // First key of a two key command such as dd
+	anchor   int    // Offset where the visual selection started
+	register []rune // Text last yanked or deleted
+	linewise bool   // The register holds whole lines
+}
+
+// updateVim handles a key while the input is focused. Keys that are not used, such as
+// escape in normal mode, are left to the chat's usual handling
+func (m *Model) updateVim(msg tea.KeyMsg) (tea.Cmd, bool) {
+	if !m.vim.enabled || !m.textArea.Focused() {
+		return nil, false
+	}
+
+	key := msg.String()
+	switch m.vim.mode {
+	case vimInsert:
+		if key != "esc" {
+			return nil, false
+		}
+		// Like vim, leaving insert mode puts the cursor on the last inserted character
+		m.vim.mode = vimNormal
+		text, off := m.vimCursor()
+		if off > 0 && text[off-1] != '\n' {
+			m.vimMoveTo(text, off-1)
+		}
+		return nil, true
+
+	case vimVisual:
+		if key == "esc" {
+			m.vim.mode = vimNormal
+			return nil, true
+		}
+
+	case vimNormal:
+		if key == "esc" && m.vim.pending == "" {
+			return nil, false
+		}
+		if key == "esc" {
+			m.vim.pending = ""
+			return nil, true
+		}
+		// Enter still sends the message
+		if key == "enter" {
+			return nil, false
+		}
+	}
+
+	m.vimCommand(key)
+	return nil, true
+}
+
+// startVimInsert is called when the chat enters input mode
+func (m *Model) startVimInsert() {
+	m.vim.mode = vimInsert
+	m.vim.pending = ""
+}
+
+// vimCommand runs a normal or visual mode command
+func (m *Model) vimCommand(key string) {
+	text, off := m.vimCursor()
+
+	if pending := m.vim.pending; pending != "" {
+		m.vim.pending = ""
+		switch pending + key {
+		case "dd":
+			start, end := lineRange(text, off)
+			m.yank(text[start:end], true)
+			from, to := start, min(end+1, len(text))
+			if end == len(text) && start > 0 {
+				// The last line has no newline after it, so the one before it goes
+				from = start - 1
+			}
+			edited := slices.Delete(slices.Clone(text), from, to)
+			lineStart, _ := lineRange(edited, from)
+			m.vimSetText(edited, lineStart)
+		case "yy":
+			start, end := lineRange(text, off)
+			m.yank(text[start:end], true)
+		case "gg":
+			m.vimMoveTo(text, 0)
+		}
+		return
+	}
+
+	start, end := lineRange(text, off)
+	switch key {
+	// Motions
+	case "h", "left":
+		if off > start {
+			m.vimMoveTo(text, off-1)
+		}
+	case "l", "right":
+		if off+1 < end {
+			m.vimMoveTo(text, off+1)
+		}
+	case "j", "down":
+		if end < len(text) {
+			_, nextEnd := lineRange(text, end+1)
+			m.vimMoveTo(text, min(end+1+off-start, max(nextEnd-1, end+1)))
+		}
+	case "k", "up":
+		if start > 0 {
+			prevStart, prevEnd := lineRange(text, start-1)
+			m.vimMoveTo(text, min(prevStart+off-start, max(prevEnd-1, prevStart)))
+		}
+	case "0", "home":
+		m.vimMoveTo(text, start)
+	case "$", "end":
+		m.vimMoveTo(text, max(end-1, start))
+	case "w":
+		m.vimMoveTo(text, nextWord(text, off))
+	case "b":
+		m.vimMoveTo(text, prevWord(text, off))
+	case "e":
+		m.vimMoveTo(text, wordEnd(text, off))
+	case "G":
+		lastStart, _ := lineRange(text, len(text))
+		m.vimMoveTo(text, lastStart)
+	case "g":
+		m.vim.pending = key
+
+	// Visual mode
+	case "v":
+		if m.vim.mode == vimVisual {
+			m.vim.mode = vimNormal
+			return
+		}
+		m.vim.mode = vimVisual
+		m.vim.anchor = off
+	case "y", "d", "x":
+		if m.vim.mode == vimVisual {
+			from, to := min(m.vim.anchor, off), min(max(m.vim.anchor, off)+1, len(text))
+			m.yank(text[from:to], false)
+			m.vim.mode = vimNormal
+			if key == "y" {
+				m.vimMoveTo(text, from)
+				return
+			}
+			m.vimSetText(slices.Delete(slices.Clone(text), from, to), from)
+			return
+		}
+		if key == "x" {
+			if off < end {
+				m.yank(text[off:off+1], false)
+				edited := slices.Delete(slices.Clone(text), off, off+1)
+				m.vimSetText(edited, min(off, max(end-2, start)))
+			}
+			return
+		}
+		m.vim.pending = key
+
+	// Editing
+	case "p", "P":
+		m.put(text, off, key == "P")
+	case "i":
+		m.vim.mode = vimInsert
+	case "a":
+		m.vim.mode = vimInsert
+		if off < end {
+			m.vimMoveTo(text, off+1)
+		}
+	case "I":
+		m.vim.mode = vimInsert
+		m.vimMoveTo(text, start)
+	case "A":
+		m.vim.mode = vimInsert
+		m.vimMoveTo(text, end)
+	case "o":
+		m.vim.mode = vimInsert
+		m.vimSetText(slices.Insert(slices.Clone(text), end, '\n'), end+1)
+	case "O":
+		m.vim.mode = vimInsert
+		m.vimSetText(slices.Insert(slices.Clone(text), start, '\n'), start)
+	}
+}
+
+// yank saves text to the register
+func (m *Model) yank(text []rune, linewise bool) {
+	m.vim.register = slices.Clone(text)
+	m.vim.linewise = linewise
+}
+
+// put pastes the register after the cursor, or before it when before is set. Lines
+// are pasted below or above the current line
+func (m *Model) put(text []rune, off int, before bool) {
+	if len(m.vim.register) == 0 {
+		return
+	}
+	start, end := lineRange(text, off)
+
+	if m.vim.linewise {
+		line := append(slices.Clone(m.vim.register), '\n')
+		switch {
+		case before:
+			m.vimSetText(slices.Insert(slices.Clone(text), start, line...), start)
+		case end == len(text):
+			// The last line has no newline to paste after
+			line = append([]rune{'\n'}, m.vim.register...)
+			m.vimSetText(slices.Insert(slices.Clone(text), end, line...), end+1)
+		default:
+			m.vimSetText(slices.Insert(slices.Clone(text), end+1, line...), end+1)
+		}
+		return
+	}
+
+	at := off
+	if !before && off < end {
+		at++
+	}
+	m.vimSetText(slices.Insert(slices.Clone(text), at, m.vim.register...), at+len(m.vim.register)-1)
+}
+
+// vimCursor returns the input text and the offset of the cursor in it
+func (m Model) vimCursor() ([]rune, int) {
+	text := []rune(m.textArea.Value())
+	row := m.textArea.Line()
+	info := m.textArea.LineInfo()
+
+	off := 0
+	for r := 0; r < row && off < len(text); r++ {
+		_, end := lineRange(text, off)
+		off = end + 1
+	}
+	return text, min(off+info.StartColumn+info.ColumnOffset, len(text))
+}
+
+// vimSetText replaces the input text and moves the cursor to off
+func (m *Model) vimSetText(text []rune, off int) {
+	m.textArea.SetValue(string(text))
+	m.vimMoveTo([]rune(m.textArea.Value()), off)
+}
+
+// vimMoveTo moves the cursor to the offset off in text. The textarea only moves
+// between lines relative to the cursor, so it is stepped there a line at a time
+func (m *Model) vimMoveTo(text []rune, off int) {
+	off = max(min(off, len(text)), 0)
+	row, col := 0, off
+	for i, r := range text[:off] {
+		if r == '\n' {
+			row++
+			col = off - i - 1
+		}
+	}
+
+	for line := m.textArea.Line(); line > row; line-- {
+		m.textArea.CursorStart()
+		m.textArea, _ = m.textArea.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	}
+	for line := m.textArea.Line(); line < row; line++ {
+		m.textArea.CursorEnd()
+		m.textArea, _ = m.textArea.Update(tea.KeyMsg{Type: tea.KeyRight})
+	}
+	m.textArea.SetCursor(col)
+}
+
+// lineRange returns the offsets of the start and end of the line containing off.
+// The end is the offset of the newline, or the length of text on the last line
+func lineRange(text []rune, off int) (int, int) {
+	start := off
+	for start > 0 && text[start-1] != '\n' {
+		start--
+	}
+	end := off
+	for end < len(text) && text[end] != '\n' {
+		end++
+	}
+	return start, end
+}
+
+// charClass groups characters the way vim does for word motions: blanks, word
+// characters and punctuation
+func charClass(r rune) int {
+	switch {
+	case unicode.IsSpace(r):
+		return 0
+	case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// nextWord returns the offset of the start of the next word after off
+func nextWord(text []rune, off int) int {
+	if off >= len(text) {
+		return off
+	}
+	i := off
+	if class := charClass(text[i]); class != 0 {
+		for i < len(text) && charClass(text[i]) == class {
+			i++
+		}
+	}
+	for i < len(text) && charClass(text[i]) == 0 {
+		i++
+	}
+	return min(i, max(len(text)-1, 0))
+}
+
+// prevWord returns the offset of the start of the word before off
+func prevWord(text []rune, off int) int {
+	i := off - 1
+	for i > 0 && charClass(text[i]) == 0 {
+		i--
+	}
+	if i <= 0 {
+		return 0
+	}
+	class := charClass(text[i])
+	for i > 0 && charClass(text[i-1]) == class {
+		i--
+	}
+	return i
+}
+
+// wordEnd returns the offset of the end of the word at or after off
+func wordEnd(text []rune, off int) int {
+	i := off + 1
+	for i < len(text) && charClass(text[i]) == 0 {
+		i++
+	}
+	if i >= len(text) {
+		return max(len(text)-1, 0)
+	}
+	class := charClass(text[i])
+	for i+1 < len(text) && charClass(text[i+1]) == class {
+		i++
+	}
+	return i
+}
+
+// vimStatus shows the editing mode in the status bar
+func (m Model) vimStatus() string {
+	if !m.vim.enabled || !m.textArea.Focused() {
+		return ""
+	}
+	switch m.vim.mode {
+	case vimNormal:
+		return m.theme.MutedText().Render("-- NORMAL --" + m.vim.pending)
+	case vimVisual:
+		text, off := m.vimCursor()
+		from, to := min(m.vim.anchor, off), min(max(m.vim.anchor, off)+1, len(text))
+		return m.theme.MutedText().Render(fmt.Sprintf("-- VISUAL -- (%d selected)", to-from))
+	default:
+		return m.theme.MutedText().Render("-- INSERT --")
+	}
+}
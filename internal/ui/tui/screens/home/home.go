@@ -1,32 +1,38 @@
 package home
 
 import (
-	"github.com/charmbracelet/bubbles/textarea"
+	"fmt"
+	"strings"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/isaacphi/slop/internal/config"
 	"github.com/isaacphi/slop/internal/ui/tui/keymap"
+	"github.com/isaacphi/slop/internal/ui/tui/screens/threads"
+	"github.com/isaacphi/slop/internal/ui/tui/theme"
 )
 
-// Model represents the home screen
+// Model represents the home screen, which lists the threads
 type Model struct {
 	width    int
 	height   int
-	textArea textarea.Model
+	threads  threads.Model
 	mode     keymap.AppMode
 	keyMap   *config.KeyMap
+	theme    theme.Theme
+	warnings []string
 }
 
 // New creates a new home screen model
-func New(keyMap *config.KeyMap) Model {
-	ta := textarea.New()
-	ta.Placeholder = "Type your message here..."
-	ta.ShowLineNumbers = false
-	ta.MaxHeight = 5
+func New(keyMap *config.KeyMap, t theme.Theme, warnings []string) Model {
+	list := threads.NewDetailed(keyMap, t)
+	list.Focus()
 
 	return Model{
 		keyMap:   keyMap,
-		textArea: ta,
+		threads:  list,
+		theme:    t,
+		warnings: warnings,
 	}
 }
 
@@ -37,42 +43,60 @@ func (m Model) Init() tea.Cmd {
 
 // Update handles updates to the home screen
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
-	var cmds []tea.Cmd
+	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		m.threads, cmd = m.threads.Update(tea.WindowSizeMsg{
+			Width:  msg.Width,
+			Height: max(msg.Height-m.bannerHeight(), 1),
+		})
 
-	case tea.KeyMsg:
-		// Pass keypress to text area if in input mode
-		if m.mode == keymap.InputMode {
-			var cmd tea.Cmd
-			m.textArea, cmd = m.textArea.Update(msg)
-			cmds = append(cmds, cmd)
-		}
+	case keymap.SetModeMsg:
+		m.mode = msg.Mode
+
+	case threads.LoadedMsg, tea.KeyMsg:
+		m.threads, cmd = m.threads.Update(msg)
 	}
 
-	return m, tea.Batch(cmds...)
+	return m, cmd
 }
 
 // View renders the home screen
 func (m Model) View() string {
+	if len(m.warnings) == 0 {
+		return m.threads.View()
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, m.threads.View(), m.warningBanner(m.width))
+}
 
-	centeredBody := lipgloss.NewStyle().
-		Width(m.width).
-		Align(lipgloss.Center).
-		Render("~~~")
-
-	inputAreaWidth := min(int(float64(m.width)*0.8), 80)
-	if m.width <= 60 {
-		inputAreaWidth = m.width - 2
+// bannerHeight is the number of lines taken by the warning banner
+func (m Model) bannerHeight() int {
+	if len(m.warnings) == 0 {
+		return 0
 	}
+	return lipgloss.Height(m.warningBanner(m.width))
+}
+
+// maxBannerWarnings limits how many config warnings are listed on the home screen
+const maxBannerWarnings = 5
 
-	inputArea := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		Width(inputAreaWidth).
-		Render(m.textArea.View())
+// warningBanner lists config warnings found at startup
+func (m Model) warningBanner(width int) string {
+	lines := []string{"Configuration warnings:"}
+	for i, w := range m.warnings {
+		if i == maxBannerWarnings {
+			lines = append(lines, fmt.Sprintf("...and %d more", len(m.warnings)-maxBannerWarnings))
+			break
+		}
+		lines = append(lines, "- "+w)
+	}
+	lines = append(lines, "Run `slop config warnings` to see them all")
 
-	return lipgloss.JoinVertical(lipgloss.Center, centeredBody, inputArea)
+	return lipgloss.NewStyle().
+		Width(width).
+		Foreground(m.theme.Tool).
+		Render(strings.Join(lines, "\n"))
 }
@@ -12,6 +12,7 @@ func (m Model) GetKeyMap() keymap.KeyMap {
 
 	if mode == keymap.NormalMode {
 		km.AddAction(keymap.NavigationGroup, config.KeyActionSwitchChat, "chat screen")
+		km.Merge(m.threads.GetKeyMap())
 	}
 	return km
 }
@@ -0,0 +1,19 @@
+package threads
+
+import (
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/ui/tui/keymap"
+)
+
+// GetKeyMap returns thread list specific keybindings
+func (m Model) GetKeyMap() keymap.KeyMap {
+	km := keymap.NewKeyMap(m.keyMap)
+	if m.focused {
+		km.AddAction(keymap.NavigationGroup, config.KeyActionScrollDown, "next thread")
+		km.AddAction(keymap.NavigationGroup, config.KeyActionScrollUp, "previous thread")
+		km.AddAction(keymap.ActionGroup, config.KeyActionOpenThread, "open thread")
+		km.AddAction(keymap.ActionGroup, config.KeyActionNewThread, "new thread")
+		km.AddAction(keymap.ActionGroup, config.KeyActionDeleteThread, "delete thread")
+	}
+	return km
+}
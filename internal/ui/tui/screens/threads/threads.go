@@ -0,0 +1,319 @@
+package threads
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
+	"github.com/isaacphi/slop/internal/ui/tui/theme"
+)
+
+// Model is the thread list shown next to the chat in split view and on the home screen
+type Model struct {
+	width    int
+	height   int
+	items    []item
+	cursor   int
+	offset   int // Index of the first visible item
+	focused  bool
+	detailed bool // Show when each thread was created and how many messages it has
+	deleting bool // Waiting for the deletion of the selected thread to be confirmed
+	err      error
+	keyMap   *config.KeyMap
+	theme    theme.Theme
+}
+
+// item is a thread shown in the list
+type item struct {
+	id        uuid.UUID
+	preview   string
+	createdAt time.Time
+	messages  int
+	unread    bool // A reply arrived since the thread was last opened
+}
+
+// LoadedMsg carries the threads read from the repository
+type LoadedMsg struct {
+	items []item
+	err   error
+}
+
+// SelectedMsg is sent when a thread is opened from the list
+type SelectedMsg struct {
+	ThreadID uuid.UUID
+}
+
+// DeleteMsg is sent once the deletion of a thread is confirmed
+type DeleteMsg struct {
+	ThreadID uuid.UUID
+}
+
+// DeletedMsg reports the deletion of a thread
+type DeletedMsg struct {
+	ThreadID uuid.UUID
+	Err      error
+}
+
+// NewThreadMsg is sent when a new thread is started from the list
+type NewThreadMsg struct{}
+
+// CreatedMsg reports the creation of a new thread
+type CreatedMsg struct {
+	ThreadID uuid.UUID
+	Err      error
+}
+
+// New creates a new thread list
+func New(keyMap *config.KeyMap, t theme.Theme) Model {
+	return Model{
+		keyMap: keyMap,
+		theme:  t,
+	}
+}
+
+// NewDetailed creates a thread list that also shows when each thread was created and
+// how many messages it has
+func NewDetailed(keyMap *config.KeyMap, t theme.Theme) Model {
+	m := New(keyMap, t)
+	m.detailed = true
+	return m
+}
+
+// Load reads the threads from the repository, most recent first, with whether a reply
+// arrived since each was last opened
+func Load(repo repository.MessageRepository) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		threads, err := repo.ListThreads(ctx, 0)
+		if err != nil {
+			return LoadedMsg{err: fmt.Errorf("failed to list threads: %w", err)}
+		}
+
+		ids := make([]uuid.UUID, len(threads))
+		for i, thread := range threads {
+			ids[i] = thread.ID
+		}
+		counts, err := repo.CountMessages(ctx, ids)
+		if err != nil {
+			return LoadedMsg{err: fmt.Errorf("failed to count messages: %w", err)}
+		}
+
+		items := make([]item, 0, len(threads))
+		for _, thread := range threads {
+			messages, err := repo.GetMessages(ctx, thread.ID, nil, false)
+			if err != nil {
+				return LoadedMsg{err: fmt.Errorf("failed to get messages: %w", err)}
+			}
+			preview := thread.Label()
+			if preview == "" {
+				preview = "[empty]"
+				for _, msg := range messages {
+					if msg.Role == domain.RoleHuman {
+						preview = msg.Content
+						break
+					}
+				}
+			}
+			items = append(items, item{
+				id:        thread.ID,
+				preview:   strings.Join(strings.Fields(preview), " "),
+				createdAt: thread.CreatedAt,
+				messages:  counts[thread.ID],
+				unread:    thread.IsUnread(messages),
+			})
+		}
+		return LoadedMsg{items: items}
+	}
+}
+
+// Create adds an empty thread to the repository
+func Create(repo repository.MessageRepository) tea.Cmd {
+	return func() tea.Msg {
+		thread := &domain.Thread{}
+		if err := repo.CreateThread(context.Background(), thread); err != nil {
+			return CreatedMsg{Err: fmt.Errorf("failed to create thread: %w", err)}
+		}
+		return CreatedMsg{ThreadID: thread.ID}
+	}
+}
+
+// Delete removes a thread from the repository
+func Delete(repo repository.MessageRepository, threadID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		err := repo.DeleteThread(context.Background(), threadID)
+		if err != nil {
+			err = fmt.Errorf("failed to delete thread: %w", err)
+		}
+		return DeletedMsg{ThreadID: threadID, Err: err}
+	}
+}
+
+// Focus lets the list receive keys
+func (m *Model) Focus() {
+	m.focused = true
+}
+
+// Blur stops the list from receiving keys
+func (m *Model) Blur() {
+	m.focused = false
+	m.deleting = false
+}
+
+// Focused reports whether the list receives keys
+func (m Model) Focused() bool {
+	return m.focused
+}
+
+// Init initializes the thread list
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles updates to the thread list
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.scrollToCursor()
+
+	case LoadedMsg:
+		m.items = msg.items
+		m.err = msg.err
+		m.cursor = min(m.cursor, max(len(m.items)-1, 0))
+		m.scrollToCursor()
+
+	case CreatedMsg:
+		m.err = msg.Err
+
+	case DeletedMsg:
+		m.err = msg.Err
+
+	case tea.KeyMsg:
+		if !m.focused {
+			return m, nil
+		}
+		if m.deleting {
+			// Any other key cancels the deletion
+			m.deleting = false
+			if msg.String() == "y" && len(m.items) > 0 {
+				id := m.items[m.cursor].id
+				return m, func() tea.Msg { return DeleteMsg{ThreadID: id} }
+			}
+			return m, nil
+		}
+		switch m.GetKeyMap().KeyToActionMap[msg.String()] {
+		case config.KeyActionScrollDown:
+			if m.cursor < len(m.items)-1 {
+				m.cursor++
+			}
+			m.scrollToCursor()
+		case config.KeyActionScrollUp:
+			if m.cursor > 0 {
+				m.cursor--
+			}
+			m.scrollToCursor()
+		case config.KeyActionOpenThread:
+			if len(m.items) > 0 {
+				id := m.items[m.cursor].id
+				return m, func() tea.Msg { return SelectedMsg{ThreadID: id} }
+			}
+		case config.KeyActionDeleteThread:
+			m.deleting = len(m.items) > 0
+		case config.KeyActionNewThread:
+			return m, func() tea.Msg { return NewThreadMsg{} }
+		}
+	}
+	return m, nil
+}
+
+// visibleItems is the number of threads that fit below the title and the prompt to
+// confirm deletions
+func (m Model) visibleItems() int {
+	return max(m.height-3, 1)
+}
+
+// scrollToCursor keeps the selected thread visible
+func (m *Model) scrollToCursor() {
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+m.visibleItems() {
+		m.offset = m.cursor - m.visibleItems() + 1
+	}
+}
+
+// View renders the thread list
+func (m Model) View() string {
+	title := m.theme.Title().Render("Threads")
+	if !m.focused {
+		title = m.theme.MutedText().Bold(true).Padding(0, 1).Render("Threads")
+	}
+
+	var lines []string
+	switch {
+	case m.err != nil:
+		lines = append(lines, m.theme.MutedText().Render(m.err.Error()))
+	case len(m.items) == 0:
+		lines = append(lines, m.theme.MutedText().Render("No threads yet"))
+	}
+
+	end := min(m.offset+m.visibleItems(), len(m.items))
+	for i := m.offset; i < end; i++ {
+		line := truncate(m.line(m.items[i]), m.width-2)
+		if i == m.cursor {
+			style := lipgloss.NewStyle().Foreground(m.theme.Accent)
+			if m.focused {
+				style = style.Bold(true)
+			}
+			lines = append(lines, style.Render("> "+line))
+			continue
+		}
+		lines = append(lines, "  "+line)
+	}
+
+	if m.deleting {
+		prompt := fmt.Sprintf("Delete thread %s? (y/n)", m.items[m.cursor].id.String()[:8])
+		lines = append(lines, lipgloss.NewStyle().Foreground(m.theme.Tool).Render(truncate(prompt, m.width)))
+	}
+
+	return lipgloss.NewStyle().
+		Width(m.width).
+		Height(m.height).
+		Render(lipgloss.JoinVertical(lipgloss.Left, title, strings.Join(lines, "\n")))
+}
+
+// line describes a thread in the list, marking unread threads with a * after their ID
+func (m Model) line(it item) string {
+	badge := " "
+	if it.unread {
+		badge = "*"
+	}
+	if !m.detailed {
+		return it.id.String()[:8] + badge + " " + it.preview
+	}
+	label := "messages"
+	if it.messages == 1 {
+		label = "message"
+	}
+	return fmt.Sprintf("%s%s %s  %3d %-8s  %s", it.id.String()[:8], badge, it.createdAt.Format("2006-01-02 15:04"), it.messages, label, it.preview)
+}
+
+// truncate shortens s to at most width characters
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	if width <= 3 {
+		return string(r[:max(width, 0)])
+	}
+	return string(r[:width-3]) + "..."
+}
@@ -0,0 +1,160 @@
+package theme
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/errkind"
+)
+
+// Theme holds the resolved colors used by every TUI screen
+type Theme struct {
+	Accent     lipgloss.Color
+	AccentText lipgloss.Color
+	Border     lipgloss.Color
+	Text       lipgloss.Color
+	Muted      lipgloss.Color
+	Human      lipgloss.Color
+	Assistant  lipgloss.Color
+	Tool       lipgloss.Color
+	System     lipgloss.Color
+	Warning    lipgloss.Color
+	Danger     lipgloss.Color
+}
+
+var builtins = map[string]Theme{
+	"dark": {
+		Accent:     "#7D56F4",
+		AccentText: "#FAFAFA",
+		Border:     "#7D56F4",
+		Text:       "#DDDDDD",
+		Muted:      "#777777",
+		Human:      "#8BE9FD",
+		Assistant:  "#F8F8F2",
+		Tool:       "#FFB86C",
+		System:     "#6272A4",
+		Warning:    "#F1FA8C",
+		Danger:     "#FF5555",
+	},
+	"light": {
+		Accent:     "#5A3FC0",
+		AccentText: "#FFFFFF",
+		Border:     "#5A3FC0",
+		Text:       "#1F1F1F",
+		Muted:      "#8A8A8A",
+		Human:      "#005F87",
+		Assistant:  "#1F1F1F",
+		Tool:       "#AF5F00",
+		System:     "#5F5F87",
+		Warning:    "#AF8700",
+		Danger:     "#D70000",
+	},
+	"solarized": {
+		Accent:     "#268BD2",
+		AccentText: "#FDF6E3",
+		Border:     "#586E75",
+		Text:       "#839496",
+		Muted:      "#586E75",
+		Human:      "#2AA198",
+		Assistant:  "#93A1A1",
+		Tool:       "#CB4B16",
+		System:     "#6C71C4",
+		Warning:    "#B58900",
+		Danger:     "#DC322F",
+	},
+}
+
+// Names returns the names of all built-in themes
+func Names() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New resolves a theme from configuration, starting from the named built-in
+// and applying any colors that are set explicitly
+func New(cfg config.Theme) (Theme, error) {
+	name := cfg.Name
+	if name == "" {
+		name = "dark"
+	}
+	t, ok := builtins[name]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown theme %q, must be one of: %v", name, Names())
+	}
+
+	override := func(target *lipgloss.Color, value string) {
+		if value != "" {
+			*target = lipgloss.Color(value)
+		}
+	}
+	override(&t.Accent, cfg.Accent)
+	override(&t.AccentText, cfg.AccentText)
+	override(&t.Border, cfg.Border)
+	override(&t.Text, cfg.Text)
+	override(&t.Muted, cfg.Muted)
+	override(&t.Human, cfg.Human)
+	override(&t.Assistant, cfg.Assistant)
+	override(&t.Tool, cfg.Tool)
+	override(&t.System, cfg.System)
+	override(&t.Warning, cfg.Warning)
+	override(&t.Danger, cfg.Danger)
+
+	return t, nil
+}
+
+// Title is the style for screen titles
+func (t Theme) Title() lipgloss.Style {
+	return lipgloss.NewStyle().
+		Bold(true).
+		Foreground(t.AccentText).
+		Background(t.Accent).
+		Padding(0, 1)
+}
+
+// Panel is the style for bordered areas such as text inputs
+func (t Theme) Panel() lipgloss.Style {
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.Border)
+}
+
+// MutedText is the style for secondary text
+func (t Theme) MutedText() lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(t.Muted)
+}
+
+// ErrorBanner is the style for an error of the given kind. Errors that go away by
+// waiting are shown as warnings, the rest need fixing and are shown as dangers
+func (t Theme) ErrorBanner(kind errkind.Kind) lipgloss.Style {
+	color := t.Danger
+	switch kind {
+	case errkind.RateLimit, errkind.DBBusy, errkind.ApprovalRequired:
+		color = t.Warning
+	}
+	return lipgloss.NewStyle().
+		Bold(true).
+		Foreground(color)
+}
+
+// Role is the style for a message from the given role
+func (t Theme) Role(role domain.Role) lipgloss.Style {
+	switch role {
+	case domain.RoleHuman:
+		return lipgloss.NewStyle().Foreground(t.Human)
+	case domain.RoleAssistant:
+		return lipgloss.NewStyle().Foreground(t.Assistant)
+	case domain.RoleTool:
+		return lipgloss.NewStyle().Foreground(t.Tool)
+	case domain.RoleSystem:
+		return lipgloss.NewStyle().Foreground(t.System)
+	default:
+		return lipgloss.NewStyle().Foreground(t.Text)
+	}
+}
@@ -0,0 +1,52 @@
+package usage
+
+import (
+	"fmt"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// Cost is the price of input and output tokens in US dollars
+func Cost(pricing config.Pricing, input, output int) float64 {
+	return (float64(input)*pricing.InputPerMillion + float64(output)*pricing.OutputPerMillion) / 1_000_000
+}
+
+// MessageCost prices the tokens of a message with the prices of the preset that
+// produced it. Messages of presets that are no longer configured cost 0
+func MessageCost(msg domain.Message, presets map[string]config.Preset) float64 {
+	preset, ok := presets[presetName(presets, msg.Provider, msg.ModelName)]
+	if !ok {
+		return 0
+	}
+	return Cost(preset.Pricing, msg.InputTokens, msg.OutputTokens)
+}
+
+// Tokens adds up the tokens and cost of messages
+type Tokens struct {
+	Messages     int     `json:"messages"`
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	Cost         float64 `json:"cost"`
+}
+
+// Add counts an assistant message, other messages are not sent by the provider
+func (t *Tokens) Add(msg domain.Message, presets map[string]config.Preset) {
+	if msg.Role != domain.RoleAssistant {
+		return
+	}
+	t.Messages++
+	t.InputTokens += msg.InputTokens
+	t.OutputTokens += msg.OutputTokens
+	t.Cost += MessageCost(msg, presets)
+}
+
+// String describes the totals, such as "1204 tokens in, 312 out, $0.0041". The cost is
+// left out when it is 0
+func (t Tokens) String() string {
+	s := fmt.Sprintf("%d tokens in, %d out", t.InputTokens, t.OutputTokens)
+	if t.Cost > 0 {
+		s += fmt.Sprintf(", $%.4f", t.Cost)
+	}
+	return s
+}
@@ -0,0 +1,51 @@
+package usage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/tokens"
+)
+
+// Estimate is a running estimate of the tokens generated for a response and what they
+// cost, shown while the response streams
+type Estimate struct {
+	pricing   config.Pricing
+	tokenizer tokens.Tokenizer
+	text      strings.Builder
+}
+
+// NewEstimate starts an estimate counted with the preset's tokenizer and priced with
+// its output price
+func NewEstimate(preset config.Preset) *Estimate {
+	return &Estimate{
+		pricing:   preset.Pricing,
+		tokenizer: tokens.For(preset.Tokenizer, preset.Provider, preset.Name),
+	}
+}
+
+// Add counts a streamed chunk of the response
+func (e *Estimate) Add(chunk string) {
+	e.text.WriteString(chunk)
+}
+
+// Tokens is the estimated number of tokens generated so far
+func (e *Estimate) Tokens() int {
+	return e.tokenizer.Count(e.text.String())
+}
+
+// Cost is the estimated price of the tokens generated so far in US dollars
+func (e *Estimate) Cost() float64 {
+	return float64(e.Tokens()) * e.pricing.OutputPerMillion / 1_000_000
+}
+
+// String describes the estimate, such as "~312 tokens, ~$0.0047". The cost is left
+// out when the preset has no prices
+func (e *Estimate) String() string {
+	s := fmt.Sprintf("~%d tokens", e.Tokens())
+	if e.pricing.OutputPerMillion > 0 {
+		s += fmt.Sprintf(", ~$%.4f", e.Cost())
+	}
+	return s
+}
@@ -0,0 +1,120 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// Row holds aggregated usage for a single preset, or for the whole report
+type Row struct {
+	Preset         string `json:"preset"`
+	Provider       string `json:"provider"`
+	Model          string `json:"model"`
+	Threads        int    `json:"threads"`
+	ToolExecutions int    `json:"toolExecutions"`
+	Tokens
+}
+
+// Report is a usage summary for a calendar month
+type Report struct {
+	Month   string `json:"month"`
+	Totals  Row    `json:"totals"`
+	Presets []Row  `json:"presets"`
+}
+
+// MonthRange parses a month in YYYY-MM format and returns the start of that
+// month and the start of the following month in local time
+func MonthRange(month string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid month %q, expected YYYY-MM: %w", month, err)
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// BuildReport aggregates assistant messages into per preset rows.
+// Messages only record the provider and model that produced them, so they are
+// matched back to preset names using the current configuration.
+func BuildReport(month string, messages []domain.Message, presets map[string]config.Preset) Report {
+	rows := make(map[string]*Row)
+	threads := make(map[string]map[uuid.UUID]struct{})
+	allThreads := make(map[uuid.UUID]struct{})
+
+	report := Report{Month: month}
+	report.Totals.Preset = "total"
+
+	for _, msg := range messages {
+		if msg.Role != domain.RoleAssistant {
+			continue
+		}
+
+		name := presetName(presets, msg.Provider, msg.ModelName)
+		row, ok := rows[name]
+		if !ok {
+			row = &Row{
+				Preset:   name,
+				Provider: msg.Provider,
+				Model:    msg.ModelName,
+			}
+			rows[name] = row
+			threads[name] = make(map[uuid.UUID]struct{})
+		}
+
+		toolCalls := countToolCalls(msg.ToolCalls)
+
+		row.Tokens.Add(msg, presets)
+		row.ToolExecutions += toolCalls
+		threads[name][msg.ThreadID] = struct{}{}
+
+		report.Totals.Tokens.Add(msg, presets)
+		report.Totals.ToolExecutions += toolCalls
+		allThreads[msg.ThreadID] = struct{}{}
+	}
+
+	report.Presets = make([]Row, 0, len(rows))
+	for name, row := range rows {
+		row.Threads = len(threads[name])
+		report.Presets = append(report.Presets, *row)
+	}
+	report.Totals.Threads = len(allThreads)
+
+	// Sort for consistent output
+	sort.Slice(report.Presets, func(i, j int) bool {
+		return report.Presets[i].Preset < report.Presets[j].Preset
+	})
+
+	return report
+}
+
+// presetName finds the configured preset matching a provider and model,
+// falling back to "provider/model" when no preset matches
+func presetName(presets map[string]config.Preset, provider, model string) string {
+	var matches []string
+	for name, preset := range presets {
+		if preset.Provider == provider && preset.Name == model {
+			matches = append(matches, name)
+		}
+	}
+	if len(matches) == 0 {
+		return fmt.Sprintf("%s/%s", provider, model)
+	}
+	sort.Strings(matches)
+	return matches[0]
+}
+
+func countToolCalls(toolCalls string) int {
+	if toolCalls == "" {
+		return 0
+	}
+	var calls []json.RawMessage
+	if err := json.Unmarshal([]byte(toolCalls), &calls); err != nil {
+		return 0
+	}
+	return len(calls)
+}
@@ -0,0 +1,122 @@
+// Package webhook turns inbound webhook requests into prompts and reports the results
+// of the runs they start to outbound webhooks
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// SignatureHeader carries the hex sha256 HMAC of a request body
+const SignatureHeader = "X-Slop-Signature"
+
+// githubSignatureHeader is accepted too so GitHub webhooks work without a proxy
+const githubSignatureHeader = "X-Hub-Signature-256"
+
+// Sign returns the signature of body in the form sent in SignatureHeader
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks that a request was sent by someone who knows the secret, either by
+// signing its body or by presenting the secret as a bearer token
+func Verify(secret string, header http.Header, body []byte) bool {
+	if secret == "" {
+		return false
+	}
+	expected := []byte(Sign(secret, body))
+	for _, name := range []string{SignatureHeader, githubSignatureHeader} {
+		if signature := header.Get(name); signature != "" {
+			return hmac.Equal([]byte(signature), expected)
+		}
+	}
+	if token, ok := strings.CutPrefix(header.Get("Authorization"), "Bearer "); ok {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+	}
+	return false
+}
+
+// Prompt is a parsed prompt template
+type Prompt struct {
+	tmpl *template.Template
+}
+
+// ParsePrompt parses the prompt template of a webhook
+func ParsePrompt(name string, text string) (*Prompt, error) {
+	tmpl, err := template.New(name).Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			encoded, err := json.MarshalIndent(v, "", "  ")
+			return string(encoded), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt for webhook %q: %w", name, err)
+	}
+	return &Prompt{tmpl: tmpl}, nil
+}
+
+// Render fills in the prompt with a payload. JSON payloads are decoded so their fields
+// can be used in the template, anything else is passed as a string
+func (p *Prompt) Render(payload []byte) (string, error) {
+	var data any = string(payload)
+	var decoded any
+	if json.Unmarshal(payload, &decoded) == nil {
+		data = decoded
+	}
+	var b strings.Builder
+	if err := p.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt: %w", err)
+	}
+	return b.String(), nil
+}
+
+// Result describes a run started by a webhook once it stops
+type Result struct {
+	Webhook  string `json:"webhook,omitempty"` // Name of the webhook that started the run
+	RunID    string `json:"runId"`
+	ThreadID string `json:"threadId"`
+	Status   string `json:"status"`
+	Response string `json:"response,omitempty"` // The last response of the model
+	Error    string `json:"error,omitempty"`
+}
+
+// Deliver POSTs a result to an outbound webhook, signed with the secret
+func Deliver(ctx context.Context, url string, secret string, result Result) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(SignatureHeader, Sign(secret, body))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver result to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to deliver result to %s: %s", url, resp.Status)
+	}
+	return nil
+}